@@ -0,0 +1,274 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagResume             = "resume"
+	flagAccountsCheckpoint = "accounts-checkpoint-interval"
+)
+
+// accountScanned is called as the account scan reaches the account at
+// index. Tests interrupt the scan with it.
+var accountScanned = func(index int) error { return nil }
+
+// accountScanOptions are the passes of scanAccounts and where it checkpoints.
+type accountScanOptions struct {
+	CheckDuplicates  bool
+	NormalizePubKeys bool
+	ClearPubKeys     bool
+	// Checkpoints, if set, stores the accounts scanned so far, and Resume
+	// continues the scan after the last one stored.
+	Checkpoints *accountCheckpoints
+	Resume      bool
+}
+
+// accountScanReport is the result of scanAccounts: the number of accounts,
+// the number of them restored from the checkpoints and the pubkeys found by
+// the normalization, in the order of the accounts.
+type accountScanReport struct {
+	Accounts int
+	Resumed  int
+	PubKeys  []accountPubKey
+}
+
+// scanAccounts runs the passes over the accounts of the auth genesis of
+// state in a single iteration, one account at a time: with
+// opts.CheckDuplicates the check that no address has two accounts and with
+// opts.NormalizePubKeys the pubkey normalization of normalizePubKeys.
+//
+// With opts.Checkpoints the accounts are stored as scanned every interval
+// accounts, so a scan of the same auth genesis with the same options that was
+// interrupted or failed resumes after the last checkpoint with opts.Resume.
+// The checkpoints of a complete scan are removed.
+func scanAccounts(cdc codec.JSONMarshaler, state types.AppMap, opts accountScanOptions) (accountScanReport, error) {
+	report := accountScanReport{PubKeys: []accountPubKey{}}
+	if state[auth.ModuleName] == nil {
+		return report, nil
+	}
+
+	var authGenesis map[string]json.RawMessage
+	if err := json.Unmarshal(state[auth.ModuleName], &authGenesis); err != nil {
+		return report, errors.Wrap(err, "failed to decode the auth genesis")
+	}
+	var accounts []json.RawMessage
+	if err := json.Unmarshal(authGenesis["accounts"], &accounts); err != nil {
+		return report, errors.Wrap(err, "failed to decode the auth genesis accounts")
+	}
+	report.Accounts = len(accounts)
+
+	seen := make(map[string]int, len(accounts))
+	addSeen := func(address string, i int) error {
+		if !opts.CheckDuplicates || address == "" {
+			return nil
+		}
+		if j, ok := seen[address]; ok {
+			return fmt.Errorf("address %s has accounts %d and %d", address, j, i)
+		}
+		seen[address] = i
+		return nil
+	}
+
+	changed := false
+	start := 0
+	if checkpoints := opts.Checkpoints; checkpoints != nil {
+		if !opts.Resume {
+			if err := checkpoints.Clear(); err != nil {
+				return report, err
+			}
+		}
+
+		chunks, err := checkpoints.Load(len(accounts))
+		if err != nil {
+			return report, err
+		}
+		for _, chunk := range chunks {
+			for j, address := range chunk.Addresses {
+				if err := addSeen(address, chunk.Start+j); err != nil {
+					return report, err
+				}
+			}
+			copy(accounts[chunk.Start:], chunk.Accounts)
+			report.PubKeys = append(report.PubKeys, chunk.PubKeys...)
+			changed = changed || chunk.Changed
+			start = chunk.Start + len(chunk.Accounts)
+		}
+		report.Resumed = start
+	}
+
+	chunk := newAccountChunk(start)
+	for i := start; i < len(accounts); i++ {
+		if err := accountScanned(i); err != nil {
+			return report, err
+		}
+
+		var account map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(accounts[i]))
+		dec.UseNumber()
+		if err := dec.Decode(&account); err != nil {
+			return report, errors.Wrapf(err, "failed to decode account %d", i)
+		}
+
+		base := baseAccountJSON(account)
+		var address string
+		if base != nil {
+			address, _ = base["address"].(string)
+		}
+		if err := addSeen(address, i); err != nil {
+			return report, err
+		}
+
+		if opts.NormalizePubKeys && base != nil && base["pub_key"] != nil {
+			found, rewrite, err := normalizeAccountPubKey(cdc, base, address, opts.ClearPubKeys)
+			if err != nil {
+				return report, err
+			}
+
+			if found != nil {
+				report.PubKeys = append(report.PubKeys, *found)
+				chunk.PubKeys = append(chunk.PubKeys, *found)
+			}
+			if rewrite {
+				if accounts[i], err = json.Marshal(account); err != nil {
+					return report, err
+				}
+				changed, chunk.Changed = true, true
+			}
+		}
+
+		chunk.Accounts = append(chunk.Accounts, accounts[i])
+		chunk.Addresses = append(chunk.Addresses, address)
+		if opts.Checkpoints != nil && len(chunk.Accounts) == opts.Checkpoints.interval {
+			if err := opts.Checkpoints.Save(chunk); err != nil {
+				return report, errors.Wrapf(err, "failed to checkpoint the accounts scanned up to %d", i)
+			}
+			chunk = newAccountChunk(i + 1)
+		}
+	}
+
+	if changed {
+		var err error
+		if authGenesis["accounts"], err = json.Marshal(accounts); err != nil {
+			return report, err
+		}
+		if state[auth.ModuleName], err = json.Marshal(authGenesis); err != nil {
+			return report, err
+		}
+	}
+
+	if opts.Checkpoints != nil {
+		if err := opts.Checkpoints.Clear(); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// accountChunk is a checkpoint of the account scan: the accounts from Start
+// on as scanned, their addresses, the pubkeys found in them and whether the
+// scan changed any of them.
+type accountChunk struct {
+	Start     int               `json:"start"`
+	Accounts  []json.RawMessage `json:"accounts"`
+	Addresses []string          `json:"addresses"`
+	PubKeys   []accountPubKey   `json:"pub_keys"`
+	Changed   bool              `json:"changed"`
+}
+
+func newAccountChunk(start int) accountChunk {
+	return accountChunk{Start: start, Accounts: []json.RawMessage{}, Addresses: []string{}, PubKeys: []accountPubKey{}}
+}
+
+// accountCheckpoints stores the checkpoints of the account scan of an auth
+// genesis with some options in a directory of the --cache-dir, a file per
+// interval accounts.
+type accountCheckpoints struct {
+	dir      string
+	interval int
+}
+
+// newAccountCheckpoints returns the checkpoints in cache of the scan, every
+// interval accounts, of the auth genesis authBz with options. The scans of
+// another auth genesis or with other options have other checkpoints.
+func newAccountCheckpoints(cache *stageCache, interval int, authBz []byte, options ...string) (*accountCheckpoints, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("--%s must be positive", flagAccountsCheckpoint)
+	}
+
+	sum := sha256.Sum256(authBz)
+	hash := sha256.New()
+	for _, part := range append([]string{hex.EncodeToString(sum[:]), strconv.Itoa(interval)}, options...) {
+		// length prefixed so the parts cannot run into each other
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return &accountCheckpoints{
+		dir:      filepath.Join(cache.dir, "accounts-"+hex.EncodeToString(hash.Sum(nil))),
+		interval: interval,
+	}, nil
+}
+
+func (c *accountCheckpoints) path(start int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%012d.json", start))
+}
+
+// Load returns the consecutive checkpoints from the first account of a scan
+// of accounts accounts, up to the first missing.
+func (c *accountCheckpoints) Load(accounts int) ([]accountChunk, error) {
+	var chunks []accountChunk
+	for start := 0; start+c.interval <= accounts; start += c.interval {
+		bz, err := ioutil.ReadFile(c.path(start))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var chunk accountChunk
+		if err := json.Unmarshal(bz, &chunk); err != nil {
+			return nil, errors.Wrapf(err, "invalid account checkpoint %s", c.path(start))
+		}
+		if chunk.Start != start || len(chunk.Accounts) != c.interval || len(chunk.Addresses) != c.interval {
+			return nil, fmt.Errorf("account checkpoint %s is not of the accounts %d to %d", c.path(start), start, start+c.interval-1)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
+
+// Save stores chunk. It is written to a temporary file first so an
+// interrupted scan never leaves a partial checkpoint.
+func (c *accountCheckpoints) Save(chunk accountChunk) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	bz, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.path(chunk.Start), bz)
+}
+
+// Clear removes the checkpoints.
+func (c *accountCheckpoints) Clear() error {
+	return os.RemoveAll(c.dir)
+}
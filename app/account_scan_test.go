@@ -0,0 +1,202 @@
+package gaia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/codec/legacy"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/stretchr/testify/require"
+)
+
+// accountScanGenesis returns a test auth genesis of n accounts, with a legacy
+// amino pubkey every other account and a pubkey of another address every
+// seventh.
+func accountScanGenesis(t *testing.T, n int) types.AppMap {
+	accounts := make([]string, n)
+	for i := range accounts {
+		pubKey := secp256k1.GenPrivKeyFromSecret([]byte(fmt.Sprintf("scan %d", i))).PubKey()
+		address := sdk.AccAddress(pubKey.Address()).String()
+
+		pubKeyJSON := []byte("null")
+		switch {
+		case i%7 == 3:
+			other := secp256k1.GenPrivKeyFromSecret([]byte(fmt.Sprintf("other %d", i))).PubKey()
+			bz, err := legacy.Cdc.MarshalJSON(other)
+			require.NoError(t, err)
+			pubKeyJSON = bz
+		case i%2 == 0:
+			bz, err := legacy.Cdc.MarshalJSON(pubKey)
+			require.NoError(t, err)
+			pubKeyJSON = bz
+		}
+		accounts[i] = fmt.Sprintf(`{"@type": "/cosmos.auth.v1beta1.BaseAccount", "address": %q, "pub_key": %s, "account_number": "%d", "sequence": "%d"}`,
+			address, pubKeyJSON, i, 3*i)
+	}
+
+	return types.AppMap{
+		auth.ModuleName: json.RawMessage(`{"params": {"max_memo_characters": "256"}, "accounts": [` + strings.Join(accounts, ",") + `]}`),
+	}
+}
+
+// copyAppMap returns a copy of state the scan can change without changing
+// state.
+func copyAppMap(state types.AppMap) types.AppMap {
+	copied := make(types.AppMap, len(state))
+	for module, bz := range state {
+		copied[module] = append(json.RawMessage(nil), bz...)
+	}
+	return copied
+}
+
+func TestScanAccountsResume(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	genesis := accountScanGenesis(t, 23)
+	opts := accountScanOptions{CheckDuplicates: true, NormalizePubKeys: true, ClearPubKeys: true}
+
+	// the uninterrupted scan
+	uninterrupted := copyAppMap(genesis)
+	want, err := scanAccounts(cdc, uninterrupted, opts)
+	require.NoError(t, err)
+	require.Equal(t, 23, want.Accounts)
+	require.Zero(t, want.Resumed)
+	require.NotEqual(t, genesis[auth.ModuleName], uninterrupted[auth.ModuleName])
+
+	cache, err := newStageCache(t.TempDir())
+	require.NoError(t, err)
+	opts.Checkpoints, err = newAccountCheckpoints(cache, 5, genesis[auth.ModuleName], "normalize", "clear")
+	require.NoError(t, err)
+
+	// killed at account 17, after the checkpoints of the accounts 0 to 14
+	var scanned []int
+	interrupted := fmt.Errorf("killed")
+	accountScanned = func(index int) error {
+		if index == 17 {
+			return interrupted
+		}
+		scanned = append(scanned, index)
+		return nil
+	}
+	t.Cleanup(func() { accountScanned = func(int) error { return nil } })
+
+	state := copyAppMap(genesis)
+	_, err = scanAccounts(cdc, state, opts)
+	require.True(t, errors.Is(err, interrupted), err)
+	require.Equal(t, genesis, state)
+	files, err := ioutil.ReadDir(opts.Checkpoints.dir)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	// the rerun resumes after the last checkpoint
+	scanned = nil
+	accountScanned = func(index int) error {
+		scanned = append(scanned, index)
+		return nil
+	}
+	opts.Resume = true
+	got, err := scanAccounts(cdc, state, opts)
+	require.NoError(t, err)
+	require.Equal(t, 15, got.Resumed)
+	require.Equal(t, []int{15, 16, 17, 18, 19, 20, 21, 22}, scanned)
+	require.Equal(t, want.PubKeys, got.PubKeys)
+	require.Equal(t, uninterrupted, state)
+
+	// a complete scan removes its checkpoints
+	_, err = os.Stat(opts.Checkpoints.dir)
+	require.True(t, os.IsNotExist(err), err)
+}
+
+func TestScanAccountsCheckpointsOptions(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	genesis := accountScanGenesis(t, 12)
+	cache, err := newStageCache(t.TempDir())
+	require.NoError(t, err)
+
+	interrupted := fmt.Errorf("killed")
+	accountScanned = func(index int) error {
+		if index == 10 {
+			return interrupted
+		}
+		return nil
+	}
+	t.Cleanup(func() { accountScanned = func(int) error { return nil } })
+
+	checkpoints, err := newAccountCheckpoints(cache, 4, genesis[auth.ModuleName], "normalize")
+	require.NoError(t, err)
+	_, err = scanAccounts(cdc, copyAppMap(genesis), accountScanOptions{NormalizePubKeys: true, Checkpoints: checkpoints})
+	require.True(t, errors.Is(err, interrupted), err)
+	accountScanned = func(int) error { return nil }
+
+	// other options and another auth genesis do not resume from them
+	other, err := newAccountCheckpoints(cache, 4, genesis[auth.ModuleName], "keep")
+	require.NoError(t, err)
+	report, err := scanAccounts(cdc, copyAppMap(genesis), accountScanOptions{Checkpoints: other, Resume: true})
+	require.NoError(t, err)
+	require.Zero(t, report.Resumed)
+
+	changed := accountScanGenesis(t, 13)
+	other, err = newAccountCheckpoints(cache, 4, changed[auth.ModuleName], "normalize")
+	require.NoError(t, err)
+	report, err = scanAccounts(cdc, changed, accountScanOptions{NormalizePubKeys: true, Checkpoints: other, Resume: true})
+	require.NoError(t, err)
+	require.Zero(t, report.Resumed)
+
+	// without resume the checkpoints are discarded
+	report, err = scanAccounts(cdc, copyAppMap(genesis), accountScanOptions{NormalizePubKeys: true, Checkpoints: checkpoints})
+	require.NoError(t, err)
+	require.Zero(t, report.Resumed)
+
+	_, err = newAccountCheckpoints(cache, 0, genesis[auth.ModuleName])
+	require.EqualError(t, err, "--accounts-checkpoint-interval must be positive")
+}
+
+func TestScanAccountsDuplicates(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	genesis, addresses := pubKeysGenesis(t)
+
+	_, err := scanAccounts(cdc, genesis, accountScanOptions{CheckDuplicates: true})
+	require.EqualError(t, err, fmt.Sprintf("address %s has accounts 3 and 5", addresses[3]))
+
+	report, err := scanAccounts(cdc, genesis, accountScanOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 7, report.Accounts)
+}
+
+func TestMigrateAccountsResume(t *testing.T) {
+	path := writeMutatedGenesis(t, func(_, _ map[string]interface{}) {})
+
+	_, err := executeMigrate(t, path, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--resume")
+	require.EqualError(t, err, "--resume needs the checkpoints of --cache-dir")
+
+	cacheDir := t.TempDir()
+	args := []string{path, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--cache-dir", cacheDir, "--accounts-checkpoint-interval", "1"}
+
+	want, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+
+	interrupted := fmt.Errorf("killed")
+	accountScanned = func(index int) error {
+		if index == 2 {
+			return interrupted
+		}
+		return nil
+	}
+	t.Cleanup(func() { accountScanned = func(int) error { return nil } })
+	_, err = executeMigrate(t, args...)
+	require.True(t, errors.Is(err, interrupted), err)
+	accountScanned = func(int) error { return nil }
+
+	var stderr strings.Builder
+	got, err := executeMigrateTo(t, &stderr, append(args, "--resume")...)
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "accounts: resumed the scan at account 2 of")
+	require.Equal(t, want, got)
+}
@@ -0,0 +1,277 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagSequenceReport = "sequence-report"
+
+	// legacyGenAccountsModule is the module holding the accounts of the
+	// genesis of releases before the v0.38 migration, like cosmoshub-3.
+	legacyGenAccountsModule = "accounts"
+)
+
+// accountSequence is what the sequence pass keeps of an account of the
+// source genesis: its sequence and a digest of its pubkey.
+type accountSequence struct {
+	Sequence uint64
+	PubKey   [sha256.Size]byte
+}
+
+// sequenceRegression is an account of both the source and the migrated
+// genesis whose sequence decreased or whose pubkey changed, which breaks the
+// transactions signed for it offline.
+type sequenceRegression struct {
+	Address           string `json:"address"`
+	SourceSequence    uint64 `json:"source_sequence"`
+	Sequence          uint64 `json:"sequence"`
+	SequenceDecreased bool   `json:"sequence_decreased,omitempty"`
+	PubKeyChanged     bool   `json:"pubkey_changed,omitempty"`
+}
+
+// sequenceReport is the result of compareAccountSequences. NewAccounts are
+// the accounts only the migrated genesis has, like prop29 destinations, which
+// are exempt. Regressions and NewAccounts are sorted by address.
+type sequenceReport struct {
+	Compared    int                  `json:"compared"`
+	Regressions []sequenceRegression `json:"regressions"`
+	NewAccounts []string             `json:"new_accounts"`
+}
+
+// sourceAccountsJSON returns the JSON holding the accounts of the source
+// genesis state and the path of their array in it: the genaccounts genesis if
+// it has one, else the auth genesis.
+func sourceAccountsJSON(state map[string]json.RawMessage) (json.RawMessage, []string) {
+	if accounts, ok := state[legacyGenAccountsModule]; ok {
+		return accounts, nil
+	}
+
+	return state[auth.ModuleName], []string{"accounts"}
+}
+
+// compareAccountSequences compares the accounts of the source genesis, the
+// array at sourcePath of the JSON read from source, with those of the
+// migrated auth genesis, in two passes over streams of their accounts: the
+// first indexes the sequence and pubkey digest by address of the source
+// accounts, the second checks every migrated account against the index. Only
+// the index is held in memory, the accounts are decoded one at a time. The
+// pubkeys are compared parsed, whether amino JSON, bech32 or proto Any
+// encoded.
+func compareAccountSequences(cdc codec.JSONMarshaler, source io.Reader, sourcePath []string, migrated io.Reader) (sequenceReport, error) {
+	report := sequenceReport{Regressions: []sequenceRegression{}, NewAccounts: []string{}}
+
+	index := make(map[string]accountSequence)
+	err := streamJSONArray(source, sourcePath, func(bz json.RawMessage) error {
+		address, sequence, err := decodeAccountSequence(cdc, bz)
+		if err != nil || address == "" {
+			return err
+		}
+
+		index[address] = sequence
+		return nil
+	})
+	if err != nil {
+		return report, errors.Wrap(err, "failed to read the source accounts")
+	}
+
+	err = streamJSONArray(migrated, []string{"accounts"}, func(bz json.RawMessage) error {
+		address, sequence, err := decodeAccountSequence(cdc, bz)
+		if err != nil || address == "" {
+			return err
+		}
+
+		source, ok := index[address]
+		if !ok {
+			report.NewAccounts = append(report.NewAccounts, address)
+			return nil
+		}
+
+		report.Compared++
+		regression := sequenceRegression{
+			Address:           address,
+			SourceSequence:    source.Sequence,
+			Sequence:          sequence.Sequence,
+			SequenceDecreased: sequence.Sequence < source.Sequence,
+			PubKeyChanged:     sequence.PubKey != source.PubKey,
+		}
+		if regression.SequenceDecreased || regression.PubKeyChanged {
+			report.Regressions = append(report.Regressions, regression)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, errors.Wrap(err, "failed to read the migrated accounts")
+	}
+
+	sort.Slice(report.Regressions, func(i, j int) bool { return report.Regressions[i].Address < report.Regressions[j].Address })
+	sort.Strings(report.NewAccounts)
+
+	return report, nil
+}
+
+// decodeAccountSequence returns the address, sequence and pubkey digest of
+// the JSON of an account of the genaccounts genesis, the amino JSON of the
+// legacy auth genesis or the proto JSON of the current one. A pubkey failing
+// to parse is digested as it is encoded, an account without one has the
+// digest of nothing.
+func decodeAccountSequence(cdc codec.JSONMarshaler, bz json.RawMessage) (string, accountSequence, error) {
+	var account map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	if err := dec.Decode(&account); err != nil {
+		return "", accountSequence{}, err
+	}
+
+	// the legacy accounts are wrapped in their amino type
+	if value, ok := account["value"].(map[string]interface{}); ok && account["type"] != nil {
+		account = value
+	}
+
+	base := baseAccountJSON(account)
+	if base == nil {
+		return "", accountSequence{}, nil
+	}
+	address, _ := base["address"].(string)
+
+	var sequence accountSequence
+	value := base["sequence"]
+	if value == nil {
+		value = base["sequence_number"]
+	}
+	if value != nil {
+		n, err := strconv.ParseUint(fmt.Sprint(value), 10, 64)
+		if err != nil {
+			return "", sequence, errors.Wrapf(err, "invalid sequence of account %s", address)
+		}
+		sequence.Sequence = n
+	}
+
+	pubKey := base["pub_key"]
+	if pubKey == nil {
+		pubKey = base["public_key"]
+	}
+	if pubKey == nil || pubKey == "" {
+		sequence.PubKey = sha256.Sum256(nil)
+		return address, sequence, nil
+	}
+
+	pubKeyBz, err := json.Marshal(pubKey)
+	if err != nil {
+		return "", sequence, err
+	}
+	if key, _, err := parsePubKeyJSON(cdc, pubKeyBz); err == nil {
+		sequence.PubKey = sha256.Sum256(append([]byte(proto.MessageName(key)+"/"), key.Bytes()...))
+	} else {
+		sequence.PubKey = sha256.Sum256(pubKeyBz)
+	}
+
+	return address, sequence, nil
+}
+
+// streamJSONArray calls fn with every element of the array at path in the
+// JSON object read from r, decoding one element at a time. The values before
+// it are skipped token by token. An empty input, or an object without the
+// path, has no elements.
+func streamJSONArray(r io.Reader, path []string, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	if !dec.More() {
+		return nil
+	}
+
+	for _, key := range path {
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+
+		found := false
+		for !found && dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			if token == key {
+				found = true
+			} else if err := skipJSONValue(dec); err != nil {
+				return err
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	return streamArrayElements(dec, fn)
+}
+
+// streamArrayElements calls fn with every element of the next value of dec,
+// an array or null, decoding one element at a time.
+func streamArrayElements(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("%v is not an array", token)
+	}
+
+	for dec.More() {
+		var element json.RawMessage
+		if err := dec.Decode(&element); err != nil {
+			return err
+		}
+		if err := fn(element); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %s, found %v", delim, token)
+	}
+
+	return nil
+}
+
+// skipJSONValue reads the next value of dec without decoding it.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
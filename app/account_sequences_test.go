@@ -0,0 +1,141 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAccountSequences(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	b := genesisbuilder.New()
+	key := func(name string) *secp256k1.PubKey {
+		return secp256k1.GenPrivKeyFromSecret([]byte(name)).PubKey().(*secp256k1.PubKey)
+	}
+	bech32Key := func(name string) string {
+		pubKey, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeAccPub, key(name))
+		require.NoError(t, err)
+		return pubKey
+	}
+
+	migrated := func(accounts ...*auth.BaseAccount) []byte {
+		genesisAccounts := make(auth.GenesisAccounts, len(accounts))
+		for i, acc := range accounts {
+			genesisAccounts[i] = acc
+		}
+		packed, err := auth.PackAccounts(genesisAccounts)
+		require.NoError(t, err)
+		return cdc.MustMarshalJSON(&auth.GenesisState{Params: auth.DefaultParams(), Accounts: packed})
+	}
+
+	t.Run("genaccounts source", func(t *testing.T) {
+		source := fmt.Sprintf(`[
+			{"address": %q, "coins": [], "sequence_number": "3", "account_number": "0", "module_name": ""},
+			{"address": %q, "coins": [], "sequence_number": "5", "account_number": "1", "module_name": ""},
+			{"address": %q, "coins": [], "sequence_number": "0", "account_number": "2", "module_name": ""}
+		]`, b.Address("alice").String(), b.Address("bob").String(), b.Address("carol").String())
+
+		report, err := compareAccountSequences(cdc, strings.NewReader(source), nil, bytes.NewReader(migrated(
+			auth.NewBaseAccount(b.Address("alice"), nil, 0, 3),
+			// a decreased sequence
+			auth.NewBaseAccount(b.Address("bob"), nil, 1, 2),
+			// a pubkey the source did not have
+			auth.NewBaseAccount(b.Address("carol"), key("carol"), 2, 0),
+			auth.NewBaseAccount(b.Address("dave"), nil, 3, 0),
+		)))
+		require.NoError(t, err)
+
+		require.Equal(t, 3, report.Compared)
+		require.Equal(t, []string{b.Address("dave").String()}, report.NewAccounts)
+		expected := []sequenceRegression{
+			{Address: b.Address("bob").String(), SourceSequence: 5, Sequence: 2, SequenceDecreased: true},
+			{Address: b.Address("carol").String(), SourceSequence: 0, Sequence: 0, PubKeyChanged: true},
+		}
+		if expected[0].Address > expected[1].Address {
+			expected[0], expected[1] = expected[1], expected[0]
+		}
+		require.Equal(t, expected, report.Regressions)
+	})
+
+	t.Run("legacy auth source", func(t *testing.T) {
+		source := fmt.Sprintf(`{"params": {}, "accounts": [
+			{"type": "cosmos-sdk/Account", "value": {"address": %q, "public_key": %q, "account_number": "0", "sequence": "7"}},
+			{"type": "cosmos-sdk/Account", "value": {"address": %q, "public_key": %q, "account_number": "1", "sequence": "1"}},
+			{"type": "cosmos-sdk/ModuleAccount", "value": {"address": %q, "public_key": "", "account_number": "2", "sequence": "0", "name": "distribution"}}
+		]}`, b.Address("alice").String(), bech32Key("alice"), b.Address("bob").String(), bech32Key("bob"), auth.NewModuleAddress("distribution").String())
+
+		report, err := compareAccountSequences(cdc, strings.NewReader(source), []string{"accounts"}, bytes.NewReader(migrated(
+			// the same pubkey, now a proto Any, and a later sequence
+			auth.NewBaseAccount(b.Address("alice"), key("alice"), 0, 8),
+			// a changed pubkey
+			auth.NewBaseAccount(b.Address("bob"), key("mallory"), 1, 1),
+			auth.NewBaseAccount(auth.NewModuleAddress("distribution"), nil, 2, 0),
+		)))
+		require.NoError(t, err)
+
+		require.Equal(t, 3, report.Compared)
+		require.Empty(t, report.NewAccounts)
+		require.Equal(t, []sequenceRegression{
+			{Address: b.Address("bob").String(), SourceSequence: 1, Sequence: 1, PubKeyChanged: true},
+		}, report.Regressions)
+	})
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	collect := func(input string, path ...string) ([]string, error) {
+		elements := []string{}
+		err := streamJSONArray(strings.NewReader(input), path, func(bz json.RawMessage) error {
+			elements = append(elements, string(bz))
+			return nil
+		})
+		return elements, err
+	}
+
+	elements, err := collect(`{"params": {"a": [1, {"accounts": [9]}]}, "other": "x", "accounts": [{"b": [1]}, 2, "c"], "after": 1}`, "accounts")
+	require.NoError(t, err)
+	require.Equal(t, []string{`{"b": [1]}`, "2", `"c"`}, elements)
+
+	elements, err = collect(`{"auth": {"accounts": [1, 2]}}`, "auth", "accounts")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, elements)
+
+	elements, err = collect(`[3]`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"3"}, elements)
+
+	for _, input := range []string{``, `{"params": {}}`, `{"accounts": null}`} {
+		elements, err = collect(input, "accounts")
+		require.NoError(t, err)
+		require.Empty(t, elements, input)
+	}
+
+	_, err = collect(`{"accounts": {}}`, "accounts")
+	require.EqualError(t, err, "{ is not an array")
+	_, err = collect(`[]`, "accounts")
+	require.EqualError(t, err, "expected {, found [")
+}
+
+func TestMigrateSequenceReport(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "sequences.json")
+	_, err := executeMigrate(t, filepath.Join(compatCorpus, "cosmoshub-3", "prop29-account", "genesis.json"),
+		"--chain-id", "cosmoshub-4", "--sequence-report", reportPath)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report sequenceReport
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Equal(t, 10, report.Compared)
+	require.Empty(t, report.Regressions)
+	require.Empty(t, report.NewAccounts)
+}
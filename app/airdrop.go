@@ -0,0 +1,259 @@
+package gaia
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+// airdropFormula is the content of the --airdrop file. Every account holding
+// at least MinBalance of SourceDenom, the bond denom when empty, is granted
+// Denom: either its holding times Ratio, rounded down, or the fixed Amount.
+// Holdings of the bond denom include delegated tokens. Module accounts and
+// the Exclude addresses are never granted anything.
+type airdropFormula struct {
+	Denom       string   `json:"denom"`
+	SourceDenom string   `json:"source_denom,omitempty"`
+	Ratio       *sdk.Dec `json:"ratio,omitempty"`
+	Amount      *sdk.Int `json:"amount,omitempty"`
+	MinBalance  *sdk.Int `json:"min_balance,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+}
+
+// airdropGrant records what an account was granted by applyAirdrop.
+type airdropGrant struct {
+	Address string  `json:"address"`
+	Holding sdk.Int `json:"holding"`
+	Granted sdk.Int `json:"granted"`
+}
+
+// airdropReport lists the grants of applyAirdrop, sorted by address, and the
+// total minted.
+type airdropReport struct {
+	Grants []airdropGrant `json:"grants"`
+	Total  sdk.Coin       `json:"total"`
+}
+
+// loadAirdropFormula reads and validates an --airdrop file.
+func loadAirdropFormula(path string) (airdropFormula, error) {
+	var formula airdropFormula
+
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return formula, errors.Wrap(err, "failed to read airdrop file")
+	}
+
+	// a misspelt min_balance or exclude would airdrop to every account
+	if err := decodeStrictJSON(bz, &formula); err != nil {
+		return formula, errors.Wrap(err, "failed to unmarshal airdrop file")
+	}
+
+	return formula, formula.Validate()
+}
+
+// Validate checks the denoms, that exactly one of Ratio and Amount is set and
+// positive, and the excluded addresses.
+func (f airdropFormula) Validate() error {
+	if err := sdk.ValidateDenom(f.Denom); err != nil {
+		return errors.Wrap(err, "invalid airdrop denom")
+	}
+
+	if f.SourceDenom != "" {
+		if err := sdk.ValidateDenom(f.SourceDenom); err != nil {
+			return errors.Wrap(err, "invalid airdrop source denom")
+		}
+	}
+
+	switch {
+	case (f.Ratio == nil) == (f.Amount == nil):
+		return fmt.Errorf("airdrop needs exactly one of ratio and amount")
+	case f.Ratio != nil && !f.Ratio.IsPositive():
+		return fmt.Errorf("airdrop ratio must be positive, got %s", f.Ratio)
+	case f.Amount != nil && !f.Amount.IsPositive():
+		return fmt.Errorf("airdrop amount must be positive, got %s", f.Amount)
+	case f.MinBalance != nil && f.MinBalance.IsNegative():
+		return fmt.Errorf("airdrop min balance cannot be negative, got %s", f.MinBalance)
+	}
+
+	for _, addr := range f.Exclude {
+		if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+			return errors.Wrapf(err, "invalid excluded airdrop address %s", addr)
+		}
+	}
+
+	return nil
+}
+
+// applyAirdrop mints the airdrop denom to the eligible accounts of the app
+// state and adds the total minted to the bank supply. Accounts are handled in
+// address order and every grant is rounded down, so the same state and
+// formula always produce the same balances. Accounts without any holding of
+// the source denom are not eligible, protected accounts are skipped. The
+// fractions the ratio grants round down are accounted in dust.
+func applyAirdrop(cdc codec.JSONMarshaler, state types.AppMap, formula airdropFormula, protected *protectedAddresses, dust *roundingDust) (airdropReport, error) {
+	var (
+		authGenesis    auth.GenesisState
+		bankGenesis    bank.GenesisState
+		stakingGenesis staking.GenesisState
+	)
+
+	report := airdropReport{Total: sdk.NewCoin(formula.Denom, sdk.ZeroInt())}
+
+	if err := cdc.UnmarshalJSON(state[auth.ModuleName], &authGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", auth.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", bank.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", staking.ModuleName)
+	}
+
+	holdings, err := airdropHoldings(authGenesis, bankGenesis, stakingGenesis, formula)
+	if err != nil {
+		return report, err
+	}
+
+	addresses := make([]string, 0, len(holdings))
+	for addr := range holdings {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	granted := make(map[string]sdk.Int)
+	for _, addr := range addresses {
+		holding := holdings[addr]
+		if !holding.IsPositive() || (formula.MinBalance != nil && holding.LT(*formula.MinBalance)) {
+			continue
+		}
+
+		// a protected account is granted nothing, not even dust
+		skip, err := protected.skip(flagAirdrop, addr)
+		if err != nil {
+			return report, err
+		}
+		if skip {
+			continue
+		}
+
+		amount := formula.Amount
+		if formula.Ratio != nil {
+			floor := dust.TruncateInt(flagAirdrop, formula.Denom, holding.ToDec().Mul(*formula.Ratio))
+			amount = &floor
+		}
+
+		if !amount.IsPositive() {
+			continue
+		}
+
+		granted[addr] = *amount
+		report.Grants = append(report.Grants, airdropGrant{Address: addr, Holding: holding, Granted: *amount})
+		report.Total.Amount = report.Total.Amount.Add(*amount)
+	}
+
+	for i, balance := range bankGenesis.Balances {
+		if amount, ok := granted[balance.Address]; ok {
+			bankGenesis.Balances[i].Coins = balance.Coins.Add(sdk.NewCoin(formula.Denom, amount))
+			delete(granted, balance.Address)
+		}
+	}
+
+	for addr, amount := range granted {
+		bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{Address: addr, Coins: sdk.NewCoins(sdk.NewCoin(formula.Denom, amount))})
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(bankGenesis.Balances)
+
+	if report.Total.IsPositive() {
+		bankGenesis.Supply = bankGenesis.Supply.Add(report.Total)
+	}
+
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	return report, nil
+}
+
+// airdropHoldings returns the source denom holdings of the accounts that may
+// be granted the airdrop, weighed like selectPrunedAccounts.
+func airdropHoldings(authGenesis auth.GenesisState, bankGenesis bank.GenesisState, stakingGenesis staking.GenesisState, formula airdropFormula) (map[string]sdk.Int, error) {
+	denom := formula.SourceDenom
+	if denom == "" {
+		denom = stakingGenesis.Params.BondDenom
+	}
+
+	excluded := make(map[string]bool, len(formula.Exclude)+len(maccPerms))
+	for _, addr := range formula.Exclude {
+		excluded[addr] = true
+	}
+	for name := range maccPerms {
+		excluded[auth.NewModuleAddress(name).String()] = true
+	}
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := make(map[string]sdk.Int, len(accounts))
+	for _, acc := range accounts {
+		addr := acc.GetAddress().String()
+		if _, ok := acc.(auth.ModuleAccountI); ok || excluded[addr] {
+			continue
+		}
+
+		holdings[addr] = sdk.ZeroInt()
+	}
+
+	for _, balance := range bankGenesis.Balances {
+		if holding, ok := holdings[balance.Address]; ok {
+			holdings[balance.Address] = holding.Add(balance.Coins.AmountOf(denom))
+		}
+	}
+
+	if denom == stakingGenesis.Params.BondDenom {
+		validators := make(map[string]staking.Validator, len(stakingGenesis.Validators))
+		for _, val := range stakingGenesis.Validators {
+			validators[val.OperatorAddress] = val
+		}
+
+		for _, del := range stakingGenesis.Delegations {
+			val, ok := validators[del.ValidatorAddress]
+			if !ok {
+				return nil, fmt.Errorf("delegation from %s to unknown validator %s", del.DelegatorAddress, del.ValidatorAddress)
+			}
+
+			if holding, ok := holdings[del.DelegatorAddress]; ok {
+				holdings[del.DelegatorAddress] = holding.Add(val.TokensFromShares(del.Shares).TruncateInt())
+			}
+		}
+	}
+
+	return holdings, nil
+}
+
+// writeAirdropCSV writes the grants of report as address,holding,granted
+// rows.
+func writeAirdropCSV(w io.Writer, report airdropReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"address", "holding", "granted"}); err != nil {
+		return err
+	}
+
+	for _, grant := range report.Grants {
+		if err := cw.Write([]string{grant.Address, grant.Holding.String(), grant.Granted.String()}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,160 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyAirdrop(t *testing.T) {
+	b := testGenesisBuilder().WithAccount("erin", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 3333333)))
+	cdc := MakeEncodingConfig().Marshaler
+
+	ratio := sdk.NewDecWithPrec(3, 1)
+	minBalance := sdk.NewInt(1000000)
+	amount := sdk.NewInt(100)
+	one := sdk.OneInt()
+
+	testCases := []struct {
+		name    string
+		formula airdropFormula
+		granted map[string]int64
+		total   int64
+	}{
+		{
+			"ratio with threshold and exclusion",
+			airdropFormula{Denom: "ufork", Ratio: &ratio, MinBalance: &minBalance, Exclude: []string{b.Address("carol").String()}},
+			map[string]int64{
+				"alice": 2100000, "dave": 2100000, "erin": 999999,
+				genesisbuilder.ValidatorName(0): 3000000, genesisbuilder.ValidatorName(1): 6000000, genesisbuilder.ValidatorName(2): 9000000,
+			},
+			2100000 + 2100000 + 999999 + 3000000 + 6000000 + 9000000,
+		},
+		{
+			"fixed amount",
+			airdropFormula{Denom: "ufork", Amount: &amount, MinBalance: &one},
+			map[string]int64{
+				"alice": 100, "bob": 100, "carol": 100, "dave": 100, "erin": 100,
+				genesisbuilder.ValidatorName(0): 100, genesisbuilder.ValidatorName(1): 100, genesisbuilder.ValidatorName(2): 100,
+			},
+			800,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.formula.Validate())
+
+			_, state := buildTestGenesis(t, b)
+
+			var bankBefore bank.GenesisState
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+
+			report, err := applyAirdrop(cdc, state, tc.formula, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, sdk.NewInt64Coin("ufork", tc.total), report.Total)
+			require.Len(t, report.Grants, len(tc.granted))
+			require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+			var bankGenesis bank.GenesisState
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+			require.Equal(t, bankBefore.Supply.Add(report.Total), bankGenesis.Supply)
+
+			minted := sdk.ZeroInt()
+			balances := make(map[string]sdk.Coins)
+			for _, balance := range bankGenesis.Balances {
+				minted = minted.Add(balance.Coins.AmountOf("ufork"))
+				balances[balance.Address] = balance.Coins
+			}
+			require.Equal(t, report.Total.Amount, minted)
+
+			for name, expected := range tc.granted {
+				require.Equal(t, sdk.NewInt(expected), balances[b.Address(name).String()].AmountOf("ufork"), name)
+			}
+		})
+	}
+}
+
+func TestApplyAirdropDeterministic(t *testing.T) {
+	b := testGenesisBuilder()
+	cdc := MakeEncodingConfig().Marshaler
+	ratio := sdk.MustNewDecFromStr("0.333333333333333333")
+	formula := airdropFormula{Denom: "ufork", Ratio: &ratio}
+
+	run := func() ([]byte, []byte) {
+		_, state := buildTestGenesis(t, b)
+
+		report, err := applyAirdrop(cdc, state, formula, nil, nil)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, writeAirdropCSV(&buf, report))
+
+		return state[bank.ModuleName], buf.Bytes()
+	}
+
+	bank1, csv1 := run()
+	bank2, csv2 := run()
+	require.Equal(t, bank1, bank2)
+	require.Equal(t, string(csv1), string(csv2))
+	require.Contains(t, string(csv1), "address,holding,granted\n")
+	require.Contains(t, string(csv1), b.Address("alice").String()+",7000000,2333333\n")
+}
+
+func TestAirdropFormulaValidate(t *testing.T) {
+	ratio := sdk.NewDecWithPrec(5, 1)
+	amount := sdk.NewInt(10)
+	negative := sdk.NewInt(-1)
+
+	testCases := []struct {
+		name    string
+		formula airdropFormula
+	}{
+		{"invalid denom", airdropFormula{Denom: "1", Ratio: &ratio}},
+		{"ratio and amount", airdropFormula{Denom: "ufork", Ratio: &ratio, Amount: &amount}},
+		{"neither ratio nor amount", airdropFormula{Denom: "ufork"}},
+		{"negative min balance", airdropFormula{Denom: "ufork", Amount: &amount, MinBalance: &negative}},
+		{"invalid exclusion", airdropFormula{Denom: "ufork", Amount: &amount, Exclude: []string{"cosmos1invalid"}}},
+	}
+
+	for _, tc := range testCases {
+		require.Error(t, tc.formula.Validate(), tc.name)
+	}
+}
+
+func TestLoadAirdropFormula(t *testing.T) {
+	dir := t.TempDir()
+	load := func(content string) (airdropFormula, error) {
+		path := filepath.Join(dir, "airdrop.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		return loadAirdropFormula(path)
+	}
+
+	formula, err := load(`{"denom": "ufork", "amount": "10", "min_balance": "1000000"}`)
+	require.NoError(t, err)
+	require.Equal(t, sdk.NewInt(1000000), *formula.MinBalance)
+
+	// a misspelt threshold or exclusion would grant every account
+	_, err = load(`{"denom": "ufork", "amount": "10", "min_balanse": "1000000"}`)
+	require.EqualError(t, err, `failed to unmarshal airdrop file: json: unknown field "min_balanse"`)
+	_, err = load(`{"denom": "ufork", "amount": "10", "excludes": ["cosmos1..."]}`)
+	require.EqualError(t, err, `failed to unmarshal airdrop file: json: unknown field "excludes"`)
+}
+
+func TestApplyAirdropMalformedState(t *testing.T) {
+	_, state := buildTestGenesis(t, testGenesisBuilder())
+	amount := sdk.NewInt(100)
+
+	state[bank.ModuleName] = json.RawMessage(`{"balances": 1}`)
+	_, err := applyAirdrop(MakeEncodingConfig().Marshaler, state, airdropFormula{Denom: "ufork", Amount: &amount}, nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to unmarshal bank genesis")
+}
@@ -0,0 +1,411 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	crisis "github.com/cosmos/cosmos-sdk/x/crisis/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	"github.com/pkg/errors"
+)
+
+// baselineMaxChanges is the number of record changes a baseline diff lists
+// per module, the others are only counted.
+const baselineMaxChanges = 50
+
+// Kinds of a baseline change.
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// baselineRecordKeys are the fields identifying the elements of an array in
+// a baseline diff, an element is identified by those it has. Elements with
+// none of them, even nested in an account, are identified by their content.
+var baselineRecordKeys = []string{
+	"address", "operator_address", "delegator_address", "validator_address", "validator_src_address",
+	"validator_dst_address", "proposal_id", "voter", "depositor", "denom", "client_id", "port_id", "channel_id",
+	"height", "period",
+}
+
+// baselineChange is a difference between the baseline and the migrated
+// genesis. A change is expected if the source genesis can explain it, a
+// change of a value set by an option of the migration, Cause, is not: the
+// baseline was migrated with another value.
+type baselineChange struct {
+	Path     string          `json:"path"`
+	Kind     string          `json:"kind"`
+	Expected bool            `json:"expected"`
+	Cause    string          `json:"cause"`
+	Baseline json.RawMessage `json:"baseline,omitempty"`
+	Output   json.RawMessage `json:"output,omitempty"`
+}
+
+// moduleBaselineDiff lists the changes of an app state module, the first
+// baselineMaxChanges of them, unexpected first, Omitted counting the others.
+type moduleBaselineDiff struct {
+	Module     string           `json:"module"`
+	Kind       string           `json:"kind"`
+	Changes    []baselineChange `json:"changes"`
+	Omitted    int              `json:"omitted"`
+	Unexpected int              `json:"unexpected"`
+}
+
+// baselineDiff is the difference between the baseline genesis, the output of
+// an earlier migration, and the migrated genesis: the changed genesis doc
+// fields and the changed modules, in alphabetical order.
+type baselineDiff struct {
+	Fields  []baselineChange     `json:"fields"`
+	Modules []moduleBaselineDiff `json:"modules"`
+}
+
+// Unexpected returns the number of changes the source genesis cannot explain.
+func (d baselineDiff) Unexpected() int {
+	n := 0
+	for _, change := range d.Fields {
+		if !change.Expected {
+			n++
+		}
+	}
+	for _, module := range d.Modules {
+		n += module.Unexpected
+	}
+
+	return n
+}
+
+// readBaselineGenesis reads the baseline genesis at path, JSON or YAML.
+func readBaselineGenesis(path string, stdin io.Reader) ([]byte, error) {
+	input, err := openGenesisInput(path, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	bz, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(bz) {
+		return yamlToJSON(bz)
+	}
+
+	return bz, nil
+}
+
+// optionPaths returns the genesis paths whose values the options set in opts
+// set, by path, with the option as cause. The paths of app state values start
+// with their module name.
+func optionPaths(opts *MigrateOptions) map[string]string {
+	options := map[string]string{
+		"chain_id":       flags.FlagChainID,
+		"genesis_time":   flagGenesisTime,
+		"initial_height": flagInitialHeight,
+		mint.ModuleName + ".params.blocks_per_year": flagMintBlocksPerYear,
+		mint.ModuleName + ".minter.inflation":       flagMintInflation,
+		crisis.ModuleName + ".constant_fee":         flagCrisisConstantFee,
+	}
+
+	paths := make(map[string]string)
+	for path, option := range options {
+		if opts.IsSet(option) {
+			paths[path] = "--" + option
+		}
+	}
+
+	return paths
+}
+
+// diffBaseline returns the difference between the baseline genesis and the
+// migrated one, both genesis JSON documents, classifying the changes of the
+// values at the paths of options as unexpected.
+func diffBaseline(baseline, output []byte, options map[string]string) (baselineDiff, error) {
+	var baselineDoc, outputDoc map[string]json.RawMessage
+	if err := json.Unmarshal(baseline, &baselineDoc); err != nil {
+		return baselineDiff{}, errors.Wrap(err, "invalid baseline genesis")
+	}
+	if err := json.Unmarshal(output, &outputDoc); err != nil {
+		return baselineDiff{}, errors.Wrap(err, "invalid migrated genesis")
+	}
+
+	var baselineState, outputState map[string]json.RawMessage
+	if err := unmarshalAppState(baselineDoc, &baselineState); err != nil {
+		return baselineDiff{}, errors.Wrap(err, "invalid baseline app state")
+	}
+	if err := unmarshalAppState(outputDoc, &outputState); err != nil {
+		return baselineDiff{}, errors.Wrap(err, "invalid migrated app state")
+	}
+	delete(baselineDoc, "app_state")
+	delete(outputDoc, "app_state")
+
+	var diff baselineDiff
+	diff.Fields = classifyChanges(diffObjects("", baselineDoc, outputDoc), "", options)
+
+	for _, module := range unionKeys(baselineState, outputState) {
+		before, inBaseline := baselineState[module]
+		after, inOutput := outputState[module]
+
+		moduleDiff := moduleBaselineDiff{Module: module, Kind: changeChanged}
+		switch {
+		case !inBaseline:
+			moduleDiff.Kind = changeAdded
+			moduleDiff.Changes = []baselineChange{{Kind: changeAdded, Output: after}}
+		case !inOutput:
+			moduleDiff.Kind = changeRemoved
+			moduleDiff.Changes = []baselineChange{{Kind: changeRemoved, Baseline: before}}
+		default:
+			moduleDiff.Changes = diffValues("", before, after)
+		}
+
+		if len(moduleDiff.Changes) == 0 {
+			continue
+		}
+
+		moduleDiff.Changes = classifyChanges(moduleDiff.Changes, module+".", options)
+		for _, change := range moduleDiff.Changes {
+			if !change.Expected {
+				moduleDiff.Unexpected++
+			}
+		}
+
+		if len(moduleDiff.Changes) > baselineMaxChanges {
+			moduleDiff.Omitted = len(moduleDiff.Changes) - baselineMaxChanges
+			moduleDiff.Changes = moduleDiff.Changes[:baselineMaxChanges]
+		}
+
+		diff.Modules = append(diff.Modules, moduleDiff)
+	}
+
+	return diff, nil
+}
+
+func unmarshalAppState(doc map[string]json.RawMessage, state *map[string]json.RawMessage) error {
+	bz, ok := doc["app_state"]
+	if !ok || bytes.Equal(bz, []byte("null")) {
+		*state = map[string]json.RawMessage{}
+		return nil
+	}
+
+	return json.Unmarshal(bz, state)
+}
+
+// classifyChanges marks the changes of the values at the paths of options,
+// prefix followed by their path, or of those containing them as unexpected,
+// and sorts them by path, the unexpected ones first.
+func classifyChanges(changes []baselineChange, prefix string, options map[string]string) []baselineChange {
+	for i := range changes {
+		changes[i].Expected, changes[i].Cause = true, "source"
+		path := strings.TrimSuffix(prefix+changes[i].Path, ".")
+		for optionPath, option := range options {
+			if path == optionPath || strings.HasPrefix(path, optionPath+".") || strings.HasPrefix(optionPath, path+".") {
+				changes[i].Expected, changes[i].Cause = false, option
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Expected != changes[j].Expected {
+			return !changes[i].Expected
+		}
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// diffValues returns the changes between two JSON values at path: objects
+// are compared field by field, arrays record by record and other values as
+// a whole.
+func diffValues(path string, before, after json.RawMessage) []baselineChange {
+	if jsonEqual(before, after) {
+		return nil
+	}
+
+	var beforeObject, afterObject map[string]json.RawMessage
+	if json.Unmarshal(before, &beforeObject) == nil && json.Unmarshal(after, &afterObject) == nil &&
+		beforeObject != nil && afterObject != nil {
+		return diffObjects(path, beforeObject, afterObject)
+	}
+
+	var beforeArray, afterArray []json.RawMessage
+	if json.Unmarshal(before, &beforeArray) == nil && json.Unmarshal(after, &afterArray) == nil &&
+		beforeArray != nil && afterArray != nil {
+		return diffArrays(path, beforeArray, afterArray)
+	}
+
+	return []baselineChange{{Path: path, Kind: changeChanged, Baseline: before, Output: after}}
+}
+
+func diffObjects(path string, before, after map[string]json.RawMessage) []baselineChange {
+	var changes []baselineChange
+	for _, key := range unionKeys(before, after) {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		beforeValue, inBefore := before[key]
+		afterValue, inAfter := after[key]
+		switch {
+		case !inBefore:
+			changes = append(changes, baselineChange{Path: keyPath, Kind: changeAdded, Output: afterValue})
+		case !inAfter:
+			changes = append(changes, baselineChange{Path: keyPath, Kind: changeRemoved, Baseline: beforeValue})
+		default:
+			changes = append(changes, diffValues(keyPath, beforeValue, afterValue)...)
+		}
+	}
+
+	return changes
+}
+
+// diffArrays compares the records of two arrays by their identifying fields,
+// or as a whole, without values, if they do not identify every record once.
+func diffArrays(path string, before, after []json.RawMessage) []baselineChange {
+	beforeRecords, beforeOk := keyRecords(before)
+	afterRecords, afterOk := keyRecords(after)
+	if !beforeOk || !afterOk {
+		return []baselineChange{{Path: path, Kind: changeChanged}}
+	}
+
+	var changes []baselineChange
+	for _, key := range unionKeys(beforeRecords, afterRecords) {
+		recordPath := fmt.Sprintf("%s[%s]", path, key)
+		beforeRecord, inBefore := beforeRecords[key]
+		afterRecord, inAfter := afterRecords[key]
+		switch {
+		case !inBefore:
+			changes = append(changes, baselineChange{Path: recordPath, Kind: changeAdded, Output: afterRecord})
+		case !inAfter:
+			changes = append(changes, baselineChange{Path: recordPath, Kind: changeRemoved, Baseline: beforeRecord})
+		case !jsonEqual(beforeRecord, afterRecord):
+			changes = append(changes, baselineChange{Path: recordPath, Kind: changeChanged, Baseline: beforeRecord, Output: afterRecord})
+		}
+	}
+
+	return changes
+}
+
+// keyRecords returns the records by key, false if a key is not unique.
+func keyRecords(records []json.RawMessage) (map[string]json.RawMessage, bool) {
+	keyed := make(map[string]json.RawMessage, len(records))
+	for _, record := range records {
+		key := recordKey(record, 2)
+		if key == "" {
+			sorted, err := sdk.SortJSON(record)
+			if err != nil {
+				sorted = record
+			}
+			key = string(sorted)
+		}
+
+		if _, ok := keyed[key]; ok {
+			return nil, false
+		}
+		keyed[key] = record
+	}
+
+	return keyed, true
+}
+
+// recordKey returns the identifying fields of record, searched in nested
+// objects up to depth levels down if it has none, empty if none is found.
+func recordKey(record json.RawMessage, depth int) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(record, &fields); err != nil || fields == nil {
+		return ""
+	}
+
+	var key []string
+	for _, name := range baselineRecordKeys {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+
+		var value string
+		if json.Unmarshal(raw, &value) != nil {
+			// numbers identify records as they are written
+			var number json.Number
+			if json.Unmarshal(raw, &number) != nil {
+				continue
+			}
+			value = number.String()
+		}
+		if value != "" {
+			key = append(key, name+"="+value)
+		}
+	}
+	if len(key) > 0 || depth == 0 {
+		return strings.Join(key, ",")
+	}
+
+	for _, name := range unionKeys(fields, nil) {
+		if key := recordKey(fields[name], depth-1); key != "" {
+			return key
+		}
+	}
+
+	return ""
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+
+	sortedA, errA := sdk.SortJSON(a)
+	sortedB, errB := sdk.SortJSON(b)
+	return errA == nil && errB == nil && bytes.Equal(sortedA, sortedB)
+}
+
+// unionKeys returns the keys of a and b, sorted.
+func unionKeys(a, b map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// printBaselineDiff prints a summary of diff to w, listing every module and
+// the unexpected changes.
+func printBaselineDiff(w io.Writer, baseline string, diff baselineDiff) {
+	if len(diff.Fields) == 0 && len(diff.Modules) == 0 {
+		fmt.Fprintf(w, "baseline: no changes from %s\n", baseline)
+		return
+	}
+
+	fmt.Fprintf(w, "baseline: %d genesis fields and %d modules changed from %s, %d changes unexpected\n",
+		len(diff.Fields), len(diff.Modules), baseline, diff.Unexpected())
+
+	for _, change := range diff.Fields {
+		fmt.Fprintf(w, "  %s %s (%s)\n", change.Path, change.Kind, change.Cause)
+	}
+
+	for _, module := range diff.Modules {
+		fmt.Fprintf(w, "  %s %s: %d changes, %d unexpected\n", module.Module, module.Kind, len(module.Changes)+module.Omitted, module.Unexpected)
+		for _, change := range module.Changes {
+			if !change.Expected {
+				fmt.Fprintf(w, "    %s %s (%s)\n", change.Path, change.Kind, change.Cause)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateBaseline(t *testing.T) {
+	args := []string{"--legacy-source", "cosmoshub-2", "--no-prop-29"}
+
+	rehearsal, err := executeMigrate(t, append([]string{"testdata/cosmoshub-2-genesis.json", "--chain-id", "cosmoshub-4"}, args...)...)
+	require.NoError(t, err)
+	baseline := filepath.Join(t.TempDir(), "rehearsal.json")
+	require.NoError(t, ioutil.WriteFile(baseline, rehearsal, 0644))
+
+	// the final export has one more transaction of an account
+	final := writeSourceGenesis(t, func(genesis map[string]interface{}) {
+		account := genesis["app_state"].(map[string]interface{})["accounts"].([]interface{})[1].(map[string]interface{})
+		account["sequence_number"] = "13"
+		account["coins"] = []interface{}{map[string]interface{}{"denom": "uatom", "amount": "49999000"}}
+	})
+
+	report := filepath.Join(t.TempDir(), "diff.json")
+	var stderr bytes.Buffer
+	_, err = executeMigrateTo(t, &stderr, append([]string{final, "--chain-id", "cosmoshub-4", "--baseline", baseline, "--baseline-report", report}, args...)...)
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "baseline: 0 genesis fields and 2 modules changed from "+baseline+", 0 changes unexpected\n")
+
+	var diff baselineDiff
+	bz, err := ioutil.ReadFile(report)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, &diff))
+	require.Empty(t, diff.Fields)
+	require.Len(t, diff.Modules, 2)
+
+	require.Equal(t, "auth", diff.Modules[0].Module)
+	require.Len(t, diff.Modules[0].Changes, 1)
+	require.Equal(t, "accounts[address=cosmos14e05nn094zxyx3nz898raf287w74zhdwx9af6r]", diff.Modules[0].Changes[0].Path)
+	require.Equal(t, changeChanged, diff.Modules[0].Changes[0].Kind)
+	require.True(t, diff.Modules[0].Changes[0].Expected)
+
+	require.Equal(t, "bank", diff.Modules[1].Module)
+	require.Len(t, diff.Modules[1].Changes, 1)
+	require.Equal(t, "balances[address=cosmos14e05nn094zxyx3nz898raf287w74zhdwx9af6r]", diff.Modules[1].Changes[0].Path)
+	require.JSONEq(t, `{"address":"cosmos14e05nn094zxyx3nz898raf287w74zhdwx9af6r","coins":[{"amount":"49999000","denom":"uatom"}]}`,
+		string(diff.Modules[1].Changes[0].Output))
+
+	// a value set by an option only changes if the rehearsal used another
+	stderr.Reset()
+	_, err = executeMigrateTo(t, &stderr, append([]string{"testdata/cosmoshub-2-genesis.json", "--chain-id", "cosmoshub-5", "--baseline", baseline, "--baseline-report", report}, args...)...)
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "baseline: 1 genesis fields and 0 modules changed from "+baseline+", 1 changes unexpected\n  chain_id changed (--chain-id)\n")
+
+	bz, err = ioutil.ReadFile(report)
+	require.NoError(t, err)
+	diff = baselineDiff{}
+	require.NoError(t, json.Unmarshal(bz, &diff))
+	require.Equal(t, []baselineChange{{
+		Path:     "chain_id",
+		Kind:     changeChanged,
+		Cause:    "--chain-id",
+		Baseline: json.RawMessage(`"cosmoshub-4"`),
+		Output:   json.RawMessage(`"cosmoshub-5"`),
+	}}, diff.Fields)
+}
+
+func TestDiffBaselineRecords(t *testing.T) {
+	baseline := []byte(`{"chain_id":"test","app_state":{"distribution":{"validator_historical_rewards":[
+		{"validator_address":"val1","period":"1","rewards":{"reference_count":1}},
+		{"validator_address":"val1","period":"2","rewards":{"reference_count":2}}
+	],"params":{"community_tax":"0.02"},"permissions":["a","b"]},"mint":{"params":{"blocks_per_year":"100"}}}}`)
+	output := []byte(`{"chain_id":"test","app_state":{"distribution":{"validator_historical_rewards":[
+		{"validator_address":"val1","period":"2","rewards":{"reference_count":1}},
+		{"validator_address":"val1","period":"3","rewards":{"reference_count":1}}
+	],"params":{"community_tax":"0.02"},"permissions":["b","c"]},"mint":{"params":{"blocks_per_year":"200"}}}}`)
+
+	diff, err := diffBaseline(baseline, output, map[string]string{"mint.params.blocks_per_year": "--mint-blocks-per-year"})
+	require.NoError(t, err)
+	require.Empty(t, diff.Fields)
+	require.Len(t, diff.Modules, 2)
+	require.Equal(t, 1, diff.Unexpected())
+
+	var paths []string
+	for _, change := range diff.Modules[0].Changes {
+		paths = append(paths, change.Kind+" "+change.Path)
+	}
+	require.Equal(t, []string{
+		`removed permissions["a"]`,
+		`added permissions["c"]`,
+		"removed validator_historical_rewards[validator_address=val1,period=1]",
+		"changed validator_historical_rewards[validator_address=val1,period=2]",
+		"added validator_historical_rewards[validator_address=val1,period=3]",
+	}, paths)
+
+	require.Equal(t, "mint", diff.Modules[1].Module)
+	require.Equal(t, 1, diff.Modules[1].Unexpected)
+	require.Equal(t, "--mint-blocks-per-year", diff.Modules[1].Changes[0].Cause)
+}
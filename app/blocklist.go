@@ -0,0 +1,323 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+// blockedCommunityPool is the --blocked-destination moving blocked funds to
+// the community pool.
+const blockedCommunityPool = "community-pool"
+
+// How the auth account of a blocked address is handled.
+const (
+	blockedAccountRemove = "remove"
+	blockedAccountZero   = "zero"
+)
+
+// blocklistOptions configures applyBlocklist. Destination is a bech32 account
+// address or blockedCommunityPool, AccountAction is blockedAccountRemove or
+// blockedAccountZero.
+type blocklistOptions struct {
+	Destination   string
+	AccountAction string
+}
+
+// blockedAddressReport records what applyBlocklist did to a blocked address.
+type blockedAddressReport struct {
+	Address   string    `json:"address"`
+	Found     bool      `json:"found"`
+	Account   string    `json:"account,omitempty"`
+	Balance   sdk.Coins `json:"balance"`
+	Unbonded  sdk.Int   `json:"unbonded"`
+	Unbonding sdk.Int   `json:"unbonding"`
+	Deposits  sdk.Coins `json:"deposits"`
+}
+
+// loadBlockedAddresses reads a JSON array of bech32 account addresses.
+func loadBlockedAddresses(path string) ([]string, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read blocked addresses file")
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(bz, &addresses); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal blocked addresses")
+	}
+
+	for _, addr := range addresses {
+		if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+			return nil, errors.Wrapf(err, "invalid blocked address %s", addr)
+		}
+	}
+
+	return addresses, nil
+}
+
+// applyBlocklist moves everything the blocked addresses own to the
+// destination: their bank balances, the tokens of their delegations and
+// unbonding delegations, which are force-unbonded, and their gov deposits.
+// Their redelegation records and distribution state are dropped, and their
+// auth accounts removed or zeroed. The total supply is unchanged. Force
+// unbonding lowers the power of the validators they delegated to, the last
+// validator powers are updated so the tendermint validator set has to be
+// regenerated from staking.
+//
+// Blocked addresses without an account or balance are reported with Found
+// unset and registered as warnings.
+func applyBlocklist(cdc codec.JSONMarshaler, state types.AppMap, addresses []string, opts blocklistOptions, warnings *warningCollector) ([]blockedAddressReport, error) {
+	if opts.AccountAction != blockedAccountRemove && opts.AccountAction != blockedAccountZero {
+		return nil, fmt.Errorf("unknown blocked account action %q, expected %s or %s", opts.AccountAction, blockedAccountRemove, blockedAccountZero)
+	}
+
+	var (
+		authGenesis         auth.GenesisState
+		bankGenesis         bank.GenesisState
+		stakingGenesis      staking.GenesisState
+		distributionGenesis distribution.GenesisState
+		govGenesis          gov.GenesisState
+	)
+
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+
+	destination := auth.NewModuleAddress(distribution.ModuleName).String()
+	if opts.Destination != blockedCommunityPool {
+		addr, err := sdk.AccAddressFromBech32(opts.Destination)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid blocked funds destination")
+		}
+		destination = addr.String()
+	}
+
+	blocked := make(map[string]bool, len(addresses))
+	reports := make(map[string]*blockedAddressReport, len(addresses))
+	for _, addr := range addresses {
+		if addr == destination {
+			return nil, fmt.Errorf("blocked funds destination %s is itself blocked", addr)
+		}
+
+		blocked[addr] = true
+		reports[addr] = &blockedAddressReport{Address: addr, Balance: sdk.NewCoins(), Unbonded: sdk.ZeroInt(), Unbonding: sdk.ZeroInt(), Deposits: sdk.NewCoins()}
+	}
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	hasDestination := false
+	var nextAccountNumber uint64
+	kept := accounts[:0]
+	for _, acc := range accounts {
+		addr := acc.GetAddress().String()
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+
+		if !blocked[addr] {
+			hasDestination = hasDestination || addr == destination
+			kept = append(kept, acc)
+			continue
+		}
+
+		if _, ok := acc.(auth.ModuleAccountI); ok {
+			return nil, fmt.Errorf("blocked address %s is a module account", addr)
+		}
+
+		reports[addr].Found = true
+		reports[addr].Account = opts.AccountAction
+		if opts.AccountAction == blockedAccountRemove {
+			continue
+		}
+
+		// a zeroed vesting account would keep vesting coins it no longer
+		// holds, keep only its base account
+		if vestingAcc, ok := acc.(vesting.VestingAccount); ok {
+			acc = auth.NewBaseAccount(vestingAcc.GetAddress(), vestingAcc.GetPubKey(), vestingAcc.GetAccountNumber(), vestingAcc.GetSequence())
+		}
+
+		kept = append(kept, acc)
+	}
+
+	if !hasDestination {
+		destinationAddr, _ := sdk.AccAddressFromBech32(destination)
+		kept = append(kept, auth.NewBaseAccount(destinationAddr, nil, nextAccountNumber, 0))
+	}
+
+	authGenesis.Accounts, err = auth.PackAccounts(kept)
+	if err != nil {
+		return nil, err
+	}
+
+	bondedRemoved, notBondedRemoved, err := forceUnbond(&stakingGenesis, &distributionGenesis, blocked, reports)
+	if err != nil {
+		return nil, err
+	}
+
+	dropWithdrawInfos(&distributionGenesis, blocked)
+
+	depositsRemoved := sdk.NewCoins()
+	for depositor, amount := range removeDeposits(&govGenesis, blocked) {
+		reports[depositor].Found = true
+		reports[depositor].Deposits = amount
+		depositsRemoved = depositsRemoved.Add(amount...)
+	}
+
+	bondDenom := stakingGenesis.Params.BondDenom
+	moved := sdk.NewCoins(sdk.NewCoin(bondDenom, bondedRemoved.Add(notBondedRemoved))).Add(depositsRemoved...)
+
+	poolAdjustments := map[string]sdk.Coins{
+		auth.NewModuleAddress(staking.BondedPoolName).String():    sdk.NewCoins(sdk.NewCoin(bondDenom, bondedRemoved)),
+		auth.NewModuleAddress(staking.NotBondedPoolName).String(): sdk.NewCoins(sdk.NewCoin(bondDenom, notBondedRemoved)),
+		auth.NewModuleAddress(gov.ModuleName).String():            depositsRemoved,
+	}
+
+	destinationCoins := sdk.NewCoins()
+	balances := bankGenesis.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		if blocked[balance.Address] {
+			reports[balance.Address].Found = true
+			reports[balance.Address].Balance = balance.Coins
+			moved = moved.Add(balance.Coins...)
+			continue
+		}
+
+		if adjustment, ok := poolAdjustments[balance.Address]; ok {
+			balance.Coins = balance.Coins.Sub(adjustment)
+		}
+
+		if balance.Address == destination {
+			destinationCoins = destinationCoins.Add(balance.Coins...)
+			continue
+		}
+
+		balances = append(balances, balance)
+	}
+	destinationCoins = destinationCoins.Add(moved...)
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(balances, bank.Balance{Address: destination, Coins: destinationCoins}))
+
+	if opts.Destination == blockedCommunityPool {
+		distributionGenesis.FeePool.CommunityPool = distributionGenesis.FeePool.CommunityPool.Add(sdk.NewDecCoinsFromCoins(moved...)...)
+	}
+
+	result := make([]blockedAddressReport, len(addresses))
+	for i, addr := range addresses {
+		result[i] = *reports[addr]
+		if !result[i].Found {
+			warnings.Add(warnAuthBlockedNotFound, severityLow, auth.ModuleName, "blocked address %s does not exist in genesis", addr)
+		}
+	}
+
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+
+	return result, nil
+}
+
+// forceUnbond removes the delegations, unbonding delegations and
+// redelegations of the blocked addresses. It returns the tokens removed from
+// the bonded and not bonded pools.
+func forceUnbond(stakingGenesis *staking.GenesisState, distributionGenesis *distribution.GenesisState, blocked map[string]bool, reports map[string]*blockedAddressReport) (sdk.Int, sdk.Int, error) {
+	bondedRemoved, notBondedRemoved := sdk.ZeroInt(), sdk.ZeroInt()
+
+	validators := make(map[string]int, len(stakingGenesis.Validators))
+	for i, val := range stakingGenesis.Validators {
+		validators[val.OperatorAddress] = i
+	}
+
+	dropped := make(map[startingInfoKey]bool)
+	changed := make(map[string]bool)
+
+	delegations := stakingGenesis.Delegations[:0]
+	for _, del := range stakingGenesis.Delegations {
+		if !blocked[del.DelegatorAddress] {
+			delegations = append(delegations, del)
+			continue
+		}
+
+		i, ok := validators[del.ValidatorAddress]
+		if !ok {
+			return bondedRemoved, notBondedRemoved, fmt.Errorf("delegation from %s to unknown validator %s", del.DelegatorAddress, del.ValidatorAddress)
+		}
+
+		val, tokens := stakingGenesis.Validators[i].RemoveDelShares(del.Shares)
+		stakingGenesis.Validators[i] = val
+
+		if val.IsBonded() {
+			bondedRemoved = bondedRemoved.Add(tokens)
+			changed[val.OperatorAddress] = true
+		} else {
+			notBondedRemoved = notBondedRemoved.Add(tokens)
+		}
+
+		reports[del.DelegatorAddress].Found = true
+		reports[del.DelegatorAddress].Unbonded = reports[del.DelegatorAddress].Unbonded.Add(tokens)
+		dropped[startingInfoKey{del.DelegatorAddress, del.ValidatorAddress}] = true
+	}
+	stakingGenesis.Delegations = delegations
+
+	unbondings := stakingGenesis.UnbondingDelegations[:0]
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		if !blocked[ubd.DelegatorAddress] {
+			unbondings = append(unbondings, ubd)
+			continue
+		}
+
+		for _, entry := range ubd.Entries {
+			notBondedRemoved = notBondedRemoved.Add(entry.Balance)
+			reports[ubd.DelegatorAddress].Unbonding = reports[ubd.DelegatorAddress].Unbonding.Add(entry.Balance)
+		}
+		reports[ubd.DelegatorAddress].Found = true
+	}
+	stakingGenesis.UnbondingDelegations = unbondings
+
+	redelegations := stakingGenesis.Redelegations[:0]
+	for _, red := range stakingGenesis.Redelegations {
+		if !blocked[red.DelegatorAddress] {
+			redelegations = append(redelegations, red)
+		}
+	}
+	stakingGenesis.Redelegations = redelegations
+
+	dropStartingInfos(distributionGenesis, dropped)
+
+	lastTotalPower := sdk.ZeroInt()
+	for i, lv := range stakingGenesis.LastValidatorPowers {
+		if changed[lv.Address] {
+			val := stakingGenesis.Validators[validators[lv.Address]]
+			if val.ConsensusPower() == 0 {
+				return bondedRemoved, notBondedRemoved, fmt.Errorf("force unbonding leaves bonded validator %s without power", lv.Address)
+			}
+
+			stakingGenesis.LastValidatorPowers[i].Power = val.ConsensusPower()
+		}
+
+		lastTotalPower = lastTotalPower.AddRaw(stakingGenesis.LastValidatorPowers[i].Power)
+	}
+
+	if len(changed) > 0 {
+		stakingGenesis.LastTotalPower = lastTotalPower
+	}
+
+	return bondedRemoved, notBondedRemoved, nil
+}
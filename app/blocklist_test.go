@@ -0,0 +1,147 @@
+package gaia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBlocklist(t *testing.T) {
+	b := testGenesisBuilder().WithDelegation("bob", 2, 4000)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	cdc := MakeEncodingConfig().Marshaler
+	addresses := []string{b.Address("alice").String(), b.Address("bob").String(), b.Address("depositor").String(), b.Address("nobody").String()}
+
+	testCases := []struct {
+		name string
+		opts blocklistOptions
+	}{
+		{"community pool and removed accounts", blocklistOptions{Destination: blockedCommunityPool, AccountAction: blockedAccountRemove}},
+		{"custody address and zeroed accounts", blocklistOptions{Destination: b.Address("custody").String(), AccountAction: blockedAccountZero}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			genDoc, state := exportTestGenesis(t, builtDoc)
+
+			var bankBefore bank.GenesisState
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+
+			var distributionBefore distribution.GenesisState
+			cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionBefore)
+
+			var warnings warningCollector
+			reports, err := applyBlocklist(cdc, state, addresses, tc.opts, &warnings)
+			require.NoError(t, err)
+			require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+			require.Len(t, reports, 4)
+			require.Equal(t, sdk.NewInt(2000000), reports[0].Unbonded)
+			require.Equal(t, sdk.NewInt(4000), reports[1].Unbonded)
+			require.Equal(t, sdk.NewInt(500000), reports[1].Unbonding)
+			require.Equal(t, sdk.NewInt(10000000), reports[2].Deposits.AmountOf(genesisbuilder.BondDenom))
+			require.False(t, reports[3].Found)
+			require.Len(t, warnings.Warnings(), 1)
+			require.Equal(t, warnAuthBlockedNotFound, warnings.Warnings()[0].Code)
+
+			moved := sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 2000000+4000+500000+10000000))
+			for _, report := range reports {
+				moved = moved.Add(report.Balance...)
+			}
+
+			var (
+				authGenesis         auth.GenesisState
+				bankGenesis         bank.GenesisState
+				stakingGenesis      staking.GenesisState
+				distributionGenesis distribution.GenesisState
+			)
+			cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+			cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+			cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+
+			require.Equal(t, bankBefore.Supply, bankGenesis.Supply)
+
+			balances := make(map[string]sdk.Coins)
+			for _, balance := range bankGenesis.Balances {
+				balances[balance.Address] = balance.Coins
+			}
+			for _, addr := range addresses {
+				require.True(t, balances[addr].IsZero(), addr)
+			}
+
+			if tc.opts.Destination == blockedCommunityPool {
+				require.Equal(t, distributionBefore.FeePool.CommunityPool.Add(sdk.NewDecCoinsFromCoins(moved...)...), distributionGenesis.FeePool.CommunityPool)
+			} else {
+				require.Equal(t, moved, balances[tc.opts.Destination])
+			}
+
+			accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+			require.NoError(t, err)
+
+			found := make(map[string]bool)
+			for _, acc := range accounts {
+				found[acc.GetAddress().String()] = true
+			}
+			require.Equal(t, tc.opts.AccountAction == blockedAccountZero, found[addresses[0]])
+
+			for _, del := range stakingGenesis.Delegations {
+				require.NotContains(t, addresses, del.DelegatorAddress)
+			}
+			require.Empty(t, stakingGenesis.UnbondingDelegations)
+
+			genDoc.AppState, err = json.Marshal(state)
+			require.NoError(t, err)
+
+			genDoc.Validators, err = tmValidatorsFromStaking(stakingGenesis)
+			require.NoError(t, err)
+			require.NoError(t, SmokeTestGenesis(genDoc))
+		})
+	}
+}
+
+func TestApplyBlocklistErrors(t *testing.T) {
+	b := testGenesisBuilder()
+	cdc := MakeEncodingConfig().Marshaler
+	opts := blocklistOptions{Destination: blockedCommunityPool, AccountAction: blockedAccountRemove}
+
+	_, state := buildTestGenesis(t, b)
+	_, err := applyBlocklist(cdc, state, []string{auth.NewModuleAddress(staking.BondedPoolName).String()}, opts, &warningCollector{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is a module account")
+
+	_, state = buildTestGenesis(t, b)
+	_, err = applyBlocklist(cdc, state, []string{b.Address("alice").String()}, blocklistOptions{Destination: b.Address("alice").String(), AccountAction: blockedAccountRemove}, &warningCollector{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is itself blocked")
+
+	_, state = buildTestGenesis(t, b)
+	_, err = applyBlocklist(cdc, state, nil, blocklistOptions{Destination: blockedCommunityPool, AccountAction: "burn"}, &warningCollector{})
+	require.Error(t, err)
+}
+
+func TestLoadBlockedAddresses(t *testing.T) {
+	b := genesisbuilder.New()
+	path := filepath.Join(t.TempDir(), "blocked.json")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`["`+b.Address("alice").String()+`"]`), 0600))
+	addresses, err := loadBlockedAddresses(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{b.Address("alice").String()}, addresses)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`["cosmos1bad"]`), 0600))
+	_, err = loadBlockedAddresses(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,289 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	capability "github.com/cosmos/cosmos-sdk/x/capability/types"
+	crisis "github.com/cosmos/cosmos-sdk/x/crisis/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	evidence "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	liquidity "github.com/gravity-devs/liquidity/x/liquidity/types"
+	"github.com/pkg/errors"
+
+	rotation "github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// typedGenesisStates are the genesis states of the modules canonicalAppState
+// re-encodes. The gentxs of genutil are raw JSON, which the proto JSON
+// encoding does not keep.
+var typedGenesisStates = map[string]func() codec.ProtoMarshaler{
+	auth.ModuleName:         func() codec.ProtoMarshaler { return &auth.GenesisState{} },
+	bank.ModuleName:         func() codec.ProtoMarshaler { return &bank.GenesisState{} },
+	capability.ModuleName:   func() codec.ProtoMarshaler { return &capability.GenesisState{} },
+	crisis.ModuleName:       func() codec.ProtoMarshaler { return &crisis.GenesisState{} },
+	distribution.ModuleName: func() codec.ProtoMarshaler { return &distribution.GenesisState{} },
+	evidence.ModuleName:     func() codec.ProtoMarshaler { return &evidence.GenesisState{} },
+	gov.ModuleName:          func() codec.ProtoMarshaler { return &gov.GenesisState{} },
+	host.ModuleName:         func() codec.ProtoMarshaler { return &ibccoretypes.GenesisState{} },
+	ibcxfertypes.ModuleName: func() codec.ProtoMarshaler { return &ibcxfertypes.GenesisState{} },
+	liquidity.ModuleName:    func() codec.ProtoMarshaler { return &liquidity.GenesisState{} },
+	mint.ModuleName:         func() codec.ProtoMarshaler { return &mint.GenesisState{} },
+	rotation.ModuleName:     func() codec.ProtoMarshaler { return &rotation.GenesisState{} },
+	slashing.ModuleName:     func() codec.ProtoMarshaler { return &slashing.GenesisState{} },
+	staking.ModuleName:      func() codec.ProtoMarshaler { return &staking.GenesisState{} },
+}
+
+// canonicalAppState re-encodes the genesis of every module of
+// typedGenesisStates in appState with cdc, so its fields are emitted as the
+// proto JSON encoding emits defaults: an empty or null repeated field, or one
+// left out, becomes [] whichever path produced the module genesis. Other
+// modules are passed through.
+func canonicalAppState(cdc codec.JSONMarshaler, appState json.RawMessage) (json.RawMessage, error) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(appState, &state); err != nil {
+		return nil, errors.Wrap(err, "failed to JSON unmarshal app state")
+	}
+
+	for module, bz := range state {
+		newState, ok := typedGenesisStates[module]
+		if !ok || bytes.Equal(bytes.TrimSpace(bz), []byte("null")) {
+			continue
+		}
+
+		genesisState := newState()
+		if err := cdc.UnmarshalJSON(bz, genesisState); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the %s genesis", module)
+		}
+
+		var err error
+		if state[module], err = cdc.MarshalJSON(genesisState); err != nil {
+			return nil, errors.Wrapf(err, "failed to encode the %s genesis", module)
+		}
+	}
+
+	return json.Marshal(state)
+}
+
+// canonicalJSON returns the JSON document bz with the keys of every object
+// sorted, arrays in their order, numbers as their literal tokens and strings
+// encoded as json.Marshal does, escaping <, > and & when escapeHTML is set.
+// It is the canonical form of the migrated genesis and, but for numbers,
+// which sdk.SortJSON rounds to float64, the output of sdk.SortJSON. Of
+// objects with duplicate keys the last value is kept, as json.Unmarshal does.
+func canonicalJSON(bz []byte, escapeHTML bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+
+	c := &canonicalWriter{dec: dec}
+	c.enc = json.NewEncoder(&c.scratch)
+	c.enc.SetEscapeHTML(escapeHTML)
+
+	root, err := c.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after the top-level JSON value")
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(bz))
+	root.writeTo(&out)
+
+	return out.Bytes(), nil
+}
+
+// checkCanonicalJSON fails unless canonical, the canonicalJSON of bz, is the
+// output of sdk.SortJSON of bz, which earlier releases shipped. A bump of the
+// SDK or Go changing either would otherwise fork the chain at genesis.
+func checkCanonicalJSON(bz, canonical []byte) error {
+	sorted, err := sdk.SortJSON(bz)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(sorted, canonical) {
+		return nil
+	}
+
+	i := 0
+	for i < len(sorted) && i < len(canonical) && sorted[i] == canonical[i] {
+		i++
+	}
+
+	return fmt.Errorf("the canonical JSON differs from sdk.SortJSON at byte %d: %q instead of %q",
+		i, excerpt(canonical, i), excerpt(sorted, i))
+}
+
+// excerpt returns up to 32 bytes of bz from offset i.
+func excerpt(bz []byte, i int) []byte {
+	end := i + 32
+	if end > len(bz) {
+		end = len(bz)
+	}
+
+	return bz[i:end]
+}
+
+type canonicalWriter struct {
+	dec     *json.Decoder
+	enc     *json.Encoder
+	scratch bytes.Buffer
+}
+
+// jsonNode is a decoded JSON value, raw holding the encoding of scalars.
+type jsonNode struct {
+	delim   json.Delim
+	raw     []byte
+	keys    [][]byte
+	members []*jsonNode
+}
+
+func (c *canonicalWriter) value() (*jsonNode, error) {
+	tok, err := c.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			return c.object()
+		case '[':
+			return c.array()
+		}
+		return nil, fmt.Errorf("unexpected %s in JSON", tok)
+	case string:
+		return &jsonNode{raw: c.encodeString(tok)}, nil
+	case json.Number:
+		return &jsonNode{raw: []byte(tok)}, nil
+	case bool:
+		if tok {
+			return &jsonNode{raw: []byte("true")}, nil
+		}
+		return &jsonNode{raw: []byte("false")}, nil
+	case nil:
+		return &jsonNode{raw: []byte("null")}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+func (c *canonicalWriter) object() (*jsonNode, error) {
+	node := &jsonNode{delim: '{'}
+	index := make(map[string]int)
+	for c.dec.More() {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		member, err := c.value()
+		if err != nil {
+			return nil, err
+		}
+
+		if i, ok := index[key]; ok {
+			node.members[i] = member
+			continue
+		}
+		index[key] = len(node.members)
+		node.keys = append(node.keys, c.encodeString(key))
+		node.members = append(node.members, member)
+	}
+
+	if _, err := c.dec.Token(); err != nil {
+		return nil, err
+	}
+
+	// json.Marshal sorts the keys of maps as strings, not as their encoding
+	names := make([]string, 0, len(index))
+	for key := range index {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	keys := make([][]byte, len(names))
+	members := make([]*jsonNode, len(names))
+	for i, name := range names {
+		keys[i] = node.keys[index[name]]
+		members[i] = node.members[index[name]]
+	}
+	node.keys, node.members = keys, members
+
+	return node, nil
+}
+
+func (c *canonicalWriter) array() (*jsonNode, error) {
+	node := &jsonNode{delim: '['}
+	for c.dec.More() {
+		member, err := c.value()
+		if err != nil {
+			return nil, err
+		}
+		node.members = append(node.members, member)
+	}
+
+	if _, err := c.dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// encodeString encodes s with the json.Encoder, so strings are escaped
+// exactly like json.Marshal of the same Go release escapes them.
+func (c *canonicalWriter) encodeString(s string) []byte {
+	c.scratch.Reset()
+	if err := c.enc.Encode(s); err != nil {
+		// encoding a string cannot fail
+		panic(err)
+	}
+
+	// drop the newline Encode appends
+	return append([]byte{}, c.scratch.Bytes()[:c.scratch.Len()-1]...)
+}
+
+func (n *jsonNode) writeTo(out *bytes.Buffer) {
+	switch n.delim {
+	case '{':
+		out.WriteByte('{')
+		for i, member := range n.members {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.Write(n.keys[i])
+			out.WriteByte(':')
+			member.writeTo(out)
+		}
+		out.WriteByte('}')
+	case '[':
+		out.WriteByte('[')
+		for i, member := range n.members {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			member.writeTo(out)
+		}
+		out.WriteByte(']')
+	default:
+		out.Write(n.raw)
+	}
+}
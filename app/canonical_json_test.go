@@ -0,0 +1,365 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"unicode"
+	"unicode/utf16"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cosmoshub3Snippet       = "testdata/cosmoshub-3-snippet.json"
+	cosmoshub3SnippetGolden = "testdata/cosmoshub-3-snippet.golden"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		in         string
+		out        string
+		escapeHTML bool
+	}{
+		{"keys sorted, arrays kept", `{"b":[3,1,2],"a":{"d":[{"z":1,"y":2}],"c":null}}`, `{"a":{"c":null,"d":[{"y":2,"z":1}]},"b":[3,1,2]}`, true},
+		{"whitespace dropped", " {\n  \"b\" : true ,\t\"a\" : false\n} ", `{"a":false,"b":true}`, true},
+		{"numbers literal", `{"big":123456789012345678901234567890,"float":1.50,"exp":1e3}`, `{"big":123456789012345678901234567890,"exp":1e3,"float":1.50}`, true},
+		{"escapes normalized", `{"s":"\u00e9\/\u0041\ud83d\ude00\n\u0001"}`, `{"s":"é/A😀\n\u0001"}`, true},
+		{"html escaped", `{"<a>":"x & y"}`, `{"\u003ca\u003e":"x \u0026 y"}`, true},
+		{"html kept", `{"<a>":"x & y"}`, `{"<a>":"x & y"}`, false},
+		{"line separators escaped", "{\"s\":\"a\u2028b\"}", `{"s":"a\u2028b"}`, false},
+		{"keys sorted as strings", `{"é":1,"z":2,"A":3}`, `{"A":3,"z":2,"é":1}`, true},
+		{"duplicate keys keep the last value", `{"a":1,"b":2,"a":3}`, `{"a":3,"b":2}`, true},
+		{"scalar document", `"x"`, `"x"`, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := canonicalJSON([]byte(tc.in), tc.escapeHTML)
+			require.NoError(t, err)
+			require.Equal(t, tc.out, string(out))
+		})
+	}
+
+	for _, in := range []string{``, `{"a":1`, `{"a":1}{}`, `{"a":1,}`, `[1 2]`} {
+		_, err := canonicalJSON([]byte(in), true)
+		require.Error(t, err, in)
+	}
+}
+
+// TestCanonicalJSONMatchesSortJSON compares canonicalJSON with sdk.SortJSON on
+// generated genesis-like documents, written with shuffled keys, random
+// whitespace and random string escapes.
+func TestCanonicalJSONMatchesSortJSON(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var doc bytes.Buffer
+		writeRandomJSON(r, &doc, randomGenesisValue(r, 0))
+
+		expected, err := sdk.SortJSON(doc.Bytes())
+		require.NoError(t, err, doc.String())
+
+		out, err := canonicalJSON(doc.Bytes(), true)
+		require.NoError(t, err, doc.String())
+		require.Equal(t, string(expected), string(out), doc.String())
+		require.NoError(t, checkCanonicalJSON(doc.Bytes(), out))
+
+		// the canonical form is canonical
+		again, err := canonicalJSON(out, true)
+		require.NoError(t, err)
+		require.Equal(t, out, again)
+	}
+}
+
+func TestCanonicalJSONGolden(t *testing.T) {
+	bz, err := ioutil.ReadFile(cosmoshub3Snippet)
+	require.NoError(t, err)
+
+	out, err := canonicalJSON(bz, true)
+	require.NoError(t, err)
+	require.NoError(t, checkCanonicalJSON(bz, out))
+
+	if *updateGolden {
+		require.NoError(t, ioutil.WriteFile(cosmoshub3SnippetGolden, append(out, '\n'), 0644))
+	}
+
+	golden, err := ioutil.ReadFile(cosmoshub3SnippetGolden)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(out)+"\n")
+}
+
+func TestCheckCanonicalJSON(t *testing.T) {
+	// sdk.SortJSON rounds numbers to float64
+	bz := []byte(`{"height":9007199254740993,"rate":1.0}`)
+	out, err := canonicalJSON(bz, true)
+	require.NoError(t, err)
+	require.EqualError(t, checkCanonicalJSON(bz, out),
+		`the canonical JSON differs from sdk.SortJSON at byte 25: "3,\"rate\":1.0}" instead of "2,\"rate\":1}"`)
+}
+
+// genesisKeys are keys of genesis documents, with some unusual ones.
+var genesisKeys = []string{
+	"accounts", "address", "amount", "app_state", "balances", "chain_id", "coins", "denom",
+	"delegator_shares", "description", "genesis_time", "height", "moniker", "params",
+	"pub_key", "@type", "validators", "<script>", "a&b", "ünïcödé", "键", "",
+}
+
+// randomGenesisValue returns a random genesis-like value: objects of genesis
+// keys, arrays of records, strings, integers exact in a float64, booleans and
+// nulls.
+func randomGenesisValue(r *rand.Rand, depth int) interface{} {
+	kind := r.Intn(10)
+	if depth == 0 {
+		kind = 0
+	} else if depth > 4 {
+		kind = 2 + r.Intn(8)
+	}
+
+	switch kind {
+	case 0, 1:
+		obj := make(map[string]interface{})
+		for i, n := 0, r.Intn(6); i < n; i++ {
+			obj[genesisKeys[r.Intn(len(genesisKeys))]] = randomGenesisValue(r, depth+1)
+		}
+		return obj
+	case 2:
+		arr := make([]interface{}, r.Intn(5))
+		for i := range arr {
+			arr[i] = randomGenesisValue(r, depth+1)
+		}
+		return arr
+	case 3, 4, 5:
+		return randomString(r)
+	case 6, 7:
+		return r.Int63n(1<<53) - 1<<52
+	case 8:
+		return r.Intn(2) == 0
+	default:
+		return nil
+	}
+}
+
+var stringRunes = []rune("abcXYZ019 \"\\/<>&\x00\x01\x1f\t\n\r\u007fé\u2028\u2029日\U0001F600\uFFFD")
+
+func randomString(r *rand.Rand) string {
+	runes := make([]rune, r.Intn(12))
+	for i := range runes {
+		runes[i] = stringRunes[r.Intn(len(stringRunes))]
+	}
+
+	return string(runes)
+}
+
+// writeRandomJSON writes v as JSON with shuffled object keys, random
+// whitespace and every rune of strings randomly escaped or not.
+func writeRandomJSON(r *rand.Rand, w *bytes.Buffer, v interface{}) {
+	space := func() {
+		w.WriteString([]string{"", "", " ", "\n  ", "\t"}[r.Intn(5)])
+	}
+
+	space()
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		r.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+
+		w.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeRandomString(r, w, key)
+			space()
+			w.WriteByte(':')
+			writeRandomJSON(r, w, v[key])
+		}
+		space()
+		w.WriteByte('}')
+	case []interface{}:
+		w.WriteByte('[')
+		for i, member := range v {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeRandomJSON(r, w, member)
+		}
+		space()
+		w.WriteByte(']')
+	case string:
+		writeRandomString(r, w, v)
+	case int64:
+		w.WriteString(strconv.FormatInt(v, 10))
+	case bool:
+		w.WriteString(strconv.FormatBool(v))
+	case nil:
+		w.WriteString("null")
+	}
+	space()
+}
+
+func writeRandomString(r *rand.Rand, w *bytes.Buffer, s string) {
+	w.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			w.WriteByte('\\')
+			w.WriteRune(c)
+		case c < 0x20 || r.Intn(4) == 0:
+			if c1, c2 := utf16.EncodeRune(c); c1 != unicode.ReplacementChar {
+				fmt.Fprintf(w, `\u%04x\u%04X`, c1, c2)
+			} else {
+				fmt.Fprintf(w, `\u%04x`, c)
+			}
+		case c == '/' && r.Intn(2) == 0:
+			w.WriteString(`\/`)
+		default:
+			w.WriteRune(c)
+		}
+	}
+	w.WriteByte('"')
+}
+
+// stripEmptyFields removes the empty arrays and objects of v, as encoders
+// leaving out empty fields do, and turns the empty arrays of even depths into
+// null.
+func stripEmptyFields(v interface{}, depth int) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, member := range v {
+			switch member := member.(type) {
+			case []interface{}:
+				if len(member) == 0 {
+					if depth%2 == 0 {
+						v[key] = nil
+					} else {
+						delete(v, key)
+					}
+					continue
+				}
+			case map[string]interface{}:
+				if len(member) == 0 {
+					delete(v, key)
+					continue
+				}
+			}
+			v[key] = stripEmptyFields(member, depth+1)
+		}
+	case []interface{}:
+		for i, member := range v {
+			v[i] = stripEmptyFields(member, depth+1)
+		}
+	}
+
+	return v
+}
+
+func TestCanonicalAppState(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	genDoc, state := buildTestGenesis(t, testGenesisBuilder())
+
+	appState, err := canonicalAppState(cdc, genDoc.AppState)
+	require.NoError(t, err)
+
+	stripped := make(map[string]interface{})
+	for module, bz := range state {
+		var v interface{}
+		require.NoError(t, json.Unmarshal(bz, &v))
+		stripped[module] = stripEmptyFields(v, 0)
+	}
+	stripped["unknown"] = map[string]interface{}{"records": nil}
+	bz, err := json.Marshal(stripped)
+	require.NoError(t, err)
+	require.NotContains(t, string(bz), `"redelegations":[]`)
+
+	canonical, err := canonicalAppState(cdc, bz)
+	require.NoError(t, err)
+
+	// the modules with typed genesis states emit their empty fields, the
+	// others are kept as they are
+	var expected, modules map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(appState, &expected))
+	require.NoError(t, json.Unmarshal(canonical, &modules))
+	for module, bz := range modules {
+		if _, ok := typedGenesisStates[module]; !ok {
+			strippedBz, err := json.Marshal(stripped[module])
+			require.NoError(t, err)
+			require.JSONEq(t, string(strippedBz), string(bz), module)
+			continue
+		}
+		require.Equal(t, string(expected[module]), string(bz), module)
+	}
+	require.Contains(t, string(modules[staking.ModuleName]), `"redelegations":[]`)
+}
+
+func TestMigrateEmptyFieldsDeterministic(t *testing.T) {
+	out, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+
+	var doc struct {
+		AppState map[string]json.RawMessage `json:"app_state"`
+	}
+	require.NoError(t, json.Unmarshal(out, &doc))
+
+	// the module genesis states of an encoder leaving out empty fields
+	stripped := make(map[string]interface{})
+	for module, bz := range doc.AppState {
+		var v interface{}
+		require.NoError(t, json.Unmarshal(bz, &v))
+		if _, ok := typedGenesisStates[module]; ok {
+			v = stripEmptyFields(v, 0)
+		}
+		stripped[module] = v
+	}
+	bz, err := json.Marshal(stripped)
+	require.NoError(t, err)
+
+	appState, err := canonicalAppState(MakeEncodingConfig().Marshaler, bz)
+	require.NoError(t, err)
+	canonical, err := canonicalJSON(appState, true)
+	require.NoError(t, err)
+
+	migrated, err := json.Marshal(doc.AppState)
+	require.NoError(t, err)
+	require.Equal(t, string(migrated), string(canonical))
+}
+
+func TestGenesisJoinEmptyFields(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	original := writeSortedTestGenesis(t, genesisPath)
+
+	// the module files are rewritten by an encoder leaving out empty fields
+	splitDir := filepath.Join(dir, "split")
+	require.NoError(t, runGenesisCmd(GenesisSplitCmd(), genesisPath, "--out-dir", splitDir))
+	for module := range typedGenesisStates {
+		path := filepath.Join(splitDir, module+".json")
+		bz, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		require.NoError(t, err)
+
+		var v interface{}
+		require.NoError(t, json.Unmarshal(bz, &v))
+		bz, err = json.Marshal(stripEmptyFields(v, 0))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+	}
+
+	joinedPath := filepath.Join(dir, "joined.json")
+	require.NoError(t, runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir, "--output", joinedPath))
+
+	joined, err := ioutil.ReadFile(joinedPath)
+	require.NoError(t, err)
+	require.Equal(t, string(original), string(joined))
+}
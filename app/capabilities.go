@@ -0,0 +1,215 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	captypes "github.com/cosmos/cosmos-sdk/x/capability/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	"github.com/pkg/errors"
+)
+
+// ibcCapabilities are the port and channel capabilities the IBC state of a
+// genesis needs, each owned by the module bound to its port and by ibc.
+type ibcCapabilities struct {
+	// owners maps the capability names to the modules that must own them.
+	owners map[string][]string
+	// names are the capability names, ports first, in the order
+	// repairCapabilities allocates their indexes.
+	names []string
+	// unbound are the problems no capability genesis can fix, channels on
+	// ports no module binds.
+	unbound []string
+}
+
+// neededCapabilities returns the capabilities of the IBC state of state. The
+// transfer port is needed when the transfer module has state, denom traces or
+// channels, or when the capability genesis already holds it; required adds it
+// whenever there is a transfer genesis.
+func neededCapabilities(cdc codec.JSONMarshaler, state types.AppMap, capGenesis *captypes.GenesisState, required bool) (*ibcCapabilities, error) {
+	caps := &ibcCapabilities{owners: make(map[string][]string)}
+
+	// portModules maps the ports to the modules binding them, only transfer
+	// is an IBC application of gaia.
+	portModules := make(map[string]string)
+	var transferGenesis *ibcxfertypes.GenesisState
+	if bz, ok := state[ibcxfertypes.ModuleName]; ok {
+		transferGenesis = new(ibcxfertypes.GenesisState)
+		if err := cdc.UnmarshalJSON(bz, transferGenesis); err != nil {
+			return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", ibcxfertypes.ModuleName)
+		}
+		portModules[transferGenesis.PortId] = ibcxfertypes.ModuleName
+	}
+
+	var channels []channeltypes.IdentifiedChannel
+	if bz, ok := state[host.ModuleName]; ok {
+		var ibcGenesis ibccoretypes.GenesisState
+		if err := cdc.UnmarshalJSON(bz, &ibcGenesis); err != nil {
+			return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", host.ModuleName)
+		}
+		channels = append(channels, ibcGenesis.ChannelGenesis.Channels...)
+	}
+
+	sort.SliceStable(channels, func(i, j int) bool {
+		if channels[i].PortId != channels[j].PortId {
+			return channels[i].PortId < channels[j].PortId
+		}
+		return channelLess(channels[i].ChannelId, channels[j].ChannelId)
+	})
+
+	if transferGenesis != nil {
+		portPath := host.PortPath(transferGenesis.PortId)
+		needed := required || len(transferGenesis.DenomTraces) > 0
+		for _, channel := range channels {
+			needed = needed || channel.PortId == transferGenesis.PortId
+		}
+		for _, owners := range capGenesis.Owners {
+			for _, owner := range owners.IndexOwners.Owners {
+				needed = needed || owner.Name == portPath
+			}
+		}
+
+		if needed {
+			caps.names = append(caps.names, portPath)
+			caps.owners[portPath] = []string{ibcxfertypes.ModuleName, host.ModuleName}
+		}
+	}
+
+	for _, channel := range channels {
+		module, ok := portModules[channel.PortId]
+		if !ok {
+			caps.unbound = append(caps.unbound, fmt.Sprintf("channel %s is on port %s, which no module binds", channel.ChannelId, channel.PortId))
+			continue
+		}
+
+		name := host.ChannelCapabilityPath(channel.PortId, channel.ChannelId)
+		caps.names = append(caps.names, name)
+		caps.owners[name] = []string{module, host.ModuleName}
+	}
+
+	return caps, nil
+}
+
+// channelLess orders the channel identifiers by sequence, those of another
+// format after them by name.
+func channelLess(a, b string) bool {
+	seqA, errA := channeltypes.ParseChannelSequence(a)
+	seqB, errB := channeltypes.ParseChannelSequence(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return seqA < seqB
+	case errA == nil || errB == nil:
+		return errA == nil
+	default:
+		return a < b
+	}
+}
+
+func capabilityGenesis(cdc codec.JSONMarshaler, state types.AppMap) (*captypes.GenesisState, error) {
+	capGenesis := captypes.DefaultGenesis()
+	if bz, ok := state[captypes.ModuleName]; ok {
+		if err := cdc.UnmarshalJSON(bz, capGenesis); err != nil {
+			return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", captypes.ModuleName)
+		}
+	}
+
+	return capGenesis, nil
+}
+
+// checkCapabilities returns the inconsistencies between the capability
+// genesis of state and its IBC state that break InitChain or the IBC
+// applications: needed capabilities missing an owner, owners of capabilities
+// no port or channel needs, channels on ports no module binds, and a
+// capability index not past every recorded index.
+func checkCapabilities(cdc codec.JSONMarshaler, state types.AppMap) ([]string, error) {
+	capGenesis, err := capabilityGenesis(cdc, state)
+	if err != nil {
+		return nil, err
+	}
+
+	caps, err := neededCapabilities(cdc, state, capGenesis, false)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := append([]string(nil), caps.unbound...)
+
+	// owned maps the capability names to their owning modules
+	owned := make(map[string]map[string]bool)
+	indexes := make(map[uint64]bool)
+	for _, owners := range capGenesis.Owners {
+		if indexes[owners.Index] {
+			problems = append(problems, fmt.Sprintf("capability index %d is recorded twice", owners.Index))
+		}
+		indexes[owners.Index] = true
+
+		if owners.Index >= capGenesis.Index {
+			problems = append(problems, fmt.Sprintf("capability index %d is recorded but the next index is %d", owners.Index, capGenesis.Index))
+		}
+
+		if len(owners.IndexOwners.Owners) == 0 {
+			problems = append(problems, fmt.Sprintf("capability index %d has no owners", owners.Index))
+		}
+
+		for _, owner := range owners.IndexOwners.Owners {
+			if _, ok := caps.owners[owner.Name]; !ok {
+				problems = append(problems, fmt.Sprintf("capability %s of index %d is owned by %s but no port or channel needs it", owner.Name, owners.Index, owner.Module))
+				continue
+			}
+
+			if owned[owner.Name] == nil {
+				owned[owner.Name] = make(map[string]bool)
+			}
+			owned[owner.Name][owner.Module] = true
+		}
+	}
+
+	for _, name := range caps.names {
+		for _, module := range caps.owners[name] {
+			if !owned[name][module] {
+				problems = append(problems, fmt.Sprintf("capability %s is not owned by %s", name, module))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// repairCapabilities regenerates the capability genesis of state from its IBC
+// state: the transfer port at index 1 when there is a transfer genesis, then
+// the channels ordered by port and sequence, each owned by the module bound
+// to its port and by ibc. Channels on ports no module binds cannot be
+// repaired.
+func repairCapabilities(cdc codec.JSONMarshaler, state types.AppMap) error {
+	caps, err := neededCapabilities(cdc, state, captypes.DefaultGenesis(), true)
+	if err != nil {
+		return err
+	}
+
+	if len(caps.unbound) > 0 {
+		return fmt.Errorf("%s", caps.unbound[0])
+	}
+
+	capGenesis := captypes.DefaultGenesis()
+	for _, name := range caps.names {
+		owners := captypes.NewCapabilityOwners()
+		for _, module := range caps.owners[name] {
+			if err := owners.Set(captypes.NewOwner(module, name)); err != nil {
+				return err
+			}
+		}
+
+		capGenesis.Owners = append(capGenesis.Owners, captypes.GenesisOwners{Index: capGenesis.Index, IndexOwners: *owners})
+		capGenesis.Index++
+	}
+
+	state[captypes.ModuleName] = cdc.MustMarshalJSON(capGenesis)
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package gaia
+
+import (
+	"encoding/json"
+	"testing"
+
+	captypes "github.com/cosmos/cosmos-sdk/x/capability/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	editCapabilities := func(edit func(*captypes.GenesisState)) func(types.AppMap) {
+		return func(state types.AppMap) {
+			var capGenesis captypes.GenesisState
+			cdc.MustUnmarshalJSON(state[captypes.ModuleName], &capGenesis)
+			edit(&capGenesis)
+			state[captypes.ModuleName] = cdc.MustMarshalJSON(&capGenesis)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		edit     func(types.AppMap)
+		problems []string
+	}{
+		{"consistent", func(types.AppMap) {}, nil},
+		{
+			"missing port owner",
+			editCapabilities(func(capGenesis *captypes.GenesisState) {
+				// only ibc owns the transfer port, so transfer binds it again
+				capGenesis.Owners[0].IndexOwners.Owners = capGenesis.Owners[0].IndexOwners.Owners[:1]
+			}),
+			[]string{"capability ports/transfer is not owned by transfer"},
+		},
+		{
+			"missing channel",
+			editCapabilities(func(capGenesis *captypes.GenesisState) {
+				capGenesis.Owners = capGenesis.Owners[:2]
+			}),
+			[]string{
+				"capability capabilities/ports/transfer/channels/channel-1 is not owned by transfer",
+				"capability capabilities/ports/transfer/channels/channel-1 is not owned by ibc",
+			},
+		},
+		{
+			"stale index",
+			editCapabilities(func(capGenesis *captypes.GenesisState) {
+				capGenesis.Index = 3
+			}),
+			[]string{"capability index 3 is recorded but the next index is 3"},
+		},
+		{
+			"stale port",
+			editCapabilities(func(capGenesis *captypes.GenesisState) {
+				owners := captypes.NewCapabilityOwners()
+				require.NoError(t, owners.Set(captypes.NewOwner("ica", host.PortPath("icahost"))))
+				capGenesis.Owners = append(capGenesis.Owners, captypes.GenesisOwners{Index: capGenesis.Index, IndexOwners: *owners})
+				capGenesis.Index++
+			}),
+			[]string{"capability ports/icahost of index 4 is owned by ica but no port or channel needs it"},
+		},
+		{
+			"unbound channel port",
+			func(state types.AppMap) {
+				var ibcGenesis ibccoretypes.GenesisState
+				cdc.MustUnmarshalJSON(state[host.ModuleName], &ibcGenesis)
+				ibcGenesis.ChannelGenesis.Channels[1].PortId = "icahost"
+				state[host.ModuleName] = cdc.MustMarshalJSON(&ibcGenesis)
+			},
+			[]string{
+				"channel channel-1 is on port icahost, which no module binds",
+				"capability capabilities/ports/transfer/channels/channel-1 of index 3 is owned by ibc but no port or channel needs it",
+				"capability capabilities/ports/transfer/channels/channel-1 of index 3 is owned by transfer but no port or channel needs it",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, state := buildTestGenesis(t, testGenesisBuilder().WithIBCChannel("juno-1"))
+			tc.edit(state)
+
+			problems, err := checkCapabilities(cdc, state)
+			require.NoError(t, err)
+			require.Equal(t, tc.problems, problems)
+		})
+	}
+}
+
+func TestCheckCapabilitiesTransferState(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	// without channels the builder leaves the capability genesis empty, which
+	// is fine while transfer has no state: it binds its port at InitChain
+	_, state := buildTestGenesis(t, genesisbuilder.New().WithValidators(1))
+	problems, err := checkCapabilities(cdc, state)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	var transferGenesis ibcxfertypes.GenesisState
+	cdc.MustUnmarshalJSON(state[ibcxfertypes.ModuleName], &transferGenesis)
+	transferGenesis.DenomTraces = ibcxfertypes.Traces{ibcxfertypes.ParseDenomTrace("transfer/channel-0/uosmo")}
+	state[ibcxfertypes.ModuleName] = cdc.MustMarshalJSON(&transferGenesis)
+
+	problems, err = checkCapabilities(cdc, state)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"capability ports/transfer is not owned by transfer",
+		"capability ports/transfer is not owned by ibc",
+	}, problems)
+}
+
+func TestRepairCapabilities(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	genDoc, state := buildTestGenesis(t, testGenesisBuilder().WithIBCChannel("juno-1"))
+	expected := state[captypes.ModuleName]
+
+	var capGenesis captypes.GenesisState
+	cdc.MustUnmarshalJSON(state[captypes.ModuleName], &capGenesis)
+	capGenesis.Owners[0].IndexOwners.Owners = capGenesis.Owners[0].IndexOwners.Owners[:1]
+	capGenesis.Owners[1], capGenesis.Owners[2] = capGenesis.Owners[2], capGenesis.Owners[1]
+	capGenesis.Index = 2
+	state[captypes.ModuleName] = cdc.MustMarshalJSON(&capGenesis)
+
+	require.NoError(t, repairCapabilities(cdc, state))
+	// the builder allocates the indexes as the repair does
+	require.JSONEq(t, string(expected), string(state[captypes.ModuleName]))
+
+	problems, err := checkCapabilities(cdc, state)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	bz, err := json.Marshal(state)
+	require.NoError(t, err)
+	genDoc.AppState = bz
+	require.NoError(t, SmokeTestGenesis(genDoc))
+
+	var ibcGenesis ibccoretypes.GenesisState
+	cdc.MustUnmarshalJSON(state[host.ModuleName], &ibcGenesis)
+	ibcGenesis.ChannelGenesis.Channels = append(ibcGenesis.ChannelGenesis.Channels,
+		channeltypes.IdentifiedChannel{PortId: "icahost", ChannelId: "channel-2"})
+	state[host.ModuleName] = cdc.MustMarshalJSON(&ibcGenesis)
+
+	require.EqualError(t, repairCapabilities(cdc, state), "channel channel-2 is on port icahost, which no module binds")
+}
+
+func TestMigrateRepairCapabilities(t *testing.T) {
+	bz, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--repair-capabilities")
+	require.NoError(t, err)
+
+	var genesis struct {
+		AppState types.AppMap `json:"app_state"`
+	}
+	require.NoError(t, json.Unmarshal(bz, &genesis))
+
+	var capGenesis captypes.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(genesis.AppState[captypes.ModuleName], &capGenesis)
+	require.Equal(t, uint64(2), capGenesis.Index)
+	require.Len(t, capGenesis.Owners, 1)
+	require.Equal(t, []captypes.Owner{
+		captypes.NewOwner(host.ModuleName, host.PortPath(ibcxfertypes.PortID)),
+		captypes.NewOwner(ibcxfertypes.ModuleName, host.PortPath(ibcxfertypes.PortID)),
+	}, capGenesis.Owners[0].IndexOwners.Owners)
+}
@@ -0,0 +1,124 @@
+package gaia
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagFundCommunityPool = "fund-community-pool"
+	flagFundFrom          = "from-account"
+)
+
+// communityPoolFunding is the --fund-community-pool move of Amount from the
+// balance of the account From to the community pool.
+type communityPoolFunding struct {
+	From   string
+	Amount sdk.Coins
+}
+
+// parseCommunityPoolFunding parses the --fund-community-pool coins and the
+// --from-account address, which cannot be a module account.
+func parseCommunityPoolFunding(amount, from string) (*communityPoolFunding, error) {
+	if from == "" {
+		return nil, fmt.Errorf("--%s needs --%s", flagFundCommunityPool, flagFundFrom)
+	}
+
+	coins, err := sdk.ParseCoinsNormalized(amount)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid --%s", flagFundCommunityPool)
+	}
+	if coins.IsZero() {
+		return nil, fmt.Errorf("--%s must be positive, got %q", flagFundCommunityPool, amount)
+	}
+
+	addr, err := sdk.AccAddressFromBech32(from)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid --%s", flagFundFrom)
+	}
+	if name, ok := moduleAccountName(addr); ok {
+		return nil, fmt.Errorf("--%s %s is the %s module account", flagFundFrom, from, name)
+	}
+
+	return &communityPoolFunding{From: addr.String(), Amount: coins}, nil
+}
+
+// fundCommunityPool moves the funding from the bank balance of its account to
+// the distribution module account and adds it to the community pool of the
+// distribution genesis of state, the supply is unchanged. Every denom must be
+// in the bank supply and the balance must cover the funding, which is checked
+// before state is changed. It reports false, changing nothing, when the
+// account is protected.
+func fundCommunityPool(cdc codec.JSONMarshaler, state types.AppMap, funding communityPoolFunding, protected *protectedAddresses) (bool, error) {
+	var (
+		bankGenesis         bank.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return false, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", bank.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[distribution.ModuleName], &distributionGenesis); err != nil {
+		return false, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", distribution.ModuleName)
+	}
+
+	// an empty supply is the sum of the balances, as InitGenesis sets it
+	supply := bankGenesis.Supply
+	if supply.Empty() {
+		for _, b := range bankGenesis.Balances {
+			supply = supply.Add(b.Coins...)
+		}
+	}
+	for _, coin := range funding.Amount {
+		if !supply.AmountOf(coin.Denom).IsPositive() {
+			return false, fmt.Errorf("denom %s of the community pool funding is not in the bank supply", coin.Denom)
+		}
+	}
+
+	balance := sdk.NewCoins()
+	for _, b := range bankGenesis.Balances {
+		if b.Address == funding.From {
+			balance = balance.Add(b.Coins...)
+		}
+	}
+	remaining, negative := balance.SafeSub(funding.Amount)
+	if negative {
+		return false, fmt.Errorf("account %s holds %s, less than the %s funding the community pool", funding.From, balance, funding.Amount)
+	}
+
+	skip, err := protected.skip(flagFundCommunityPool, funding.From)
+	if err != nil || skip {
+		return false, err
+	}
+
+	distributionAddr := auth.NewModuleAddress(distribution.ModuleName).String()
+	credited := funding.Amount
+	balances := bankGenesis.Balances[:0]
+	for _, b := range bankGenesis.Balances {
+		switch b.Address {
+		case funding.From:
+			continue
+		case distributionAddr:
+			credited = credited.Add(b.Coins...)
+			continue
+		}
+		balances = append(balances, b)
+	}
+	if !remaining.IsZero() {
+		balances = append(balances, bank.Balance{Address: funding.From, Coins: remaining})
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(balances, bank.Balance{Address: distributionAddr, Coins: credited}))
+
+	distributionGenesis.FeePool.CommunityPool = distributionGenesis.FeePool.CommunityPool.Add(sdk.NewDecCoinsFromCoins(funding.Amount...)...)
+
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+	return true, nil
+}
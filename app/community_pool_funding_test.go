@@ -0,0 +1,177 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestFundCommunityPool(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	b := genesisbuilder.New().
+		WithValidatorPowers(10).
+		WithAccount("treasury", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 5000), sdk.NewInt64Coin("ufoo", 300))).
+		WithAccount("other", sdk.NewCoins(sdk.NewInt64Coin("ubar", 10)))
+	treasury := b.Address("treasury").String()
+	distributionAddr := auth.NewModuleAddress(distribution.ModuleName).String()
+
+	fund := func(t *testing.T, amount string) (types.AppMap, types.AppMap, error) {
+		_, state := buildTestGenesis(t, b)
+		before := copyAppMap(state)
+
+		funding, err := parseCommunityPoolFunding(amount, treasury)
+		require.NoError(t, err)
+		funded, err := fundCommunityPool(cdc, state, *funding, nil)
+		require.Equal(t, err == nil, funded)
+		return before, state, err
+	}
+	balances := func(t *testing.T, state types.AppMap) (map[string]sdk.Coins, sdk.Coins, sdk.DecCoins) {
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		var distributionGenesis distribution.GenesisState
+		cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+
+		byAddress := make(map[string]sdk.Coins)
+		for _, balance := range bankGenesis.Balances {
+			byAddress[balance.Address] = balance.Coins
+		}
+		return byAddress, bankGenesis.Supply, distributionGenesis.FeePool.CommunityPool
+	}
+
+	t.Run("multi-denom", func(t *testing.T) {
+		before, state, err := fund(t, "2000uatom,100ufoo")
+		require.NoError(t, err)
+
+		balancesBefore, supplyBefore, poolBefore := balances(t, before)
+		after, supply, pool := balances(t, state)
+		require.Equal(t, "3000uatom,200ufoo", after[treasury].String())
+		require.Equal(t, balancesBefore[distributionAddr].Add(sdk.NewInt64Coin(genesisbuilder.BondDenom, 2000), sdk.NewInt64Coin("ufoo", 100)), after[distributionAddr])
+		require.Equal(t, poolBefore.Add(sdk.NewInt64DecCoin(genesisbuilder.BondDenom, 2000), sdk.NewInt64DecCoin("ufoo", 100)), pool)
+		require.Equal(t, supplyBefore, supply)
+
+		audit, err := auditModuleAccounts(cdc, state)
+		require.NoError(t, err)
+		require.True(t, moduleAccountAudit(t, audit, distribution.ModuleName).Balanced())
+	})
+
+	t.Run("exact balance", func(t *testing.T) {
+		_, state, err := fund(t, "5000uatom,300ufoo")
+		require.NoError(t, err)
+
+		after, _, _ := balances(t, state)
+		_, ok := after[treasury]
+		require.False(t, ok, "the emptied balance is removed")
+
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		require.NoError(t, bankGenesis.Validate())
+	})
+
+	t.Run("insufficient", func(t *testing.T) {
+		before, state, err := fund(t, "2000uatom,301ufoo")
+		require.EqualError(t, err, "account "+treasury+" holds 5000uatom,300ufoo, less than the 2000uatom,301ufoo funding the community pool")
+		require.Equal(t, before, state)
+
+		before, state, err = fund(t, "10ubar")
+		require.EqualError(t, err, "account "+treasury+" holds 5000uatom,300ufoo, less than the 10ubar funding the community pool")
+		require.Equal(t, before, state)
+	})
+
+	t.Run("unknown denom", func(t *testing.T) {
+		before, state, err := fund(t, "1uatom,1unknown")
+		require.EqualError(t, err, "denom unknown of the community pool funding is not in the bank supply")
+		require.Equal(t, before, state)
+	})
+
+	t.Run("protected", func(t *testing.T) {
+		_, state := buildTestGenesis(t, b)
+		before := copyAppMap(state)
+		protected := &protectedAddresses{reasons: map[string]string{treasury: "listed in protected.json"}}
+
+		funded, err := fundCommunityPool(cdc, state, communityPoolFunding{From: treasury, Amount: sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1))}, protected)
+		require.NoError(t, err)
+		require.False(t, funded)
+		require.Equal(t, before, state)
+		require.Len(t, protected.Skips, 1)
+	})
+}
+
+func TestParseCommunityPoolFunding(t *testing.T) {
+	from := genesisbuilder.New().Address("treasury").String()
+	govAddr := auth.NewModuleAddress(gov.ModuleName).String()
+
+	for _, tc := range []struct {
+		amount, from string
+		err          string
+	}{
+		{"1000uatom", "", "--fund-community-pool needs --from-account"},
+		{"1000", from, "invalid --fund-community-pool: invalid decimal coin expression: 1000"},
+		{"0uatom", from, `--fund-community-pool must be positive, got "0uatom"`},
+		{"1000uatom", "cosmos1invalid", "invalid --from-account: decoding bech32 failed: failed converting data to bytes: invalid character not part of charset: 105"},
+		{"1000uatom", govAddr, "--from-account " + govAddr + " is the gov module account"},
+	} {
+		_, err := parseCommunityPoolFunding(tc.amount, tc.from)
+		require.EqualError(t, err, tc.err, tc.amount+" "+tc.from)
+	}
+
+	funding, err := parseCommunityPoolFunding("10ufoo,1000uatom", from)
+	require.NoError(t, err)
+	require.Equal(t, &communityPoolFunding{From: from, Amount: sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000), sdk.NewInt64Coin("ufoo", 10))}, funding)
+}
+
+func TestMigrateFundCommunityPool(t *testing.T) {
+	const from = "cosmos14e05nn094zxyx3nz898raf287w74zhdwx9af6r"
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--strict-module-accounts"}
+
+	_, err := executeMigrate(t, append(args, "--"+flagFundFrom, from)...)
+	require.EqualError(t, err, "--from-account needs --fund-community-pool")
+
+	// fails before anything is changed
+	_, err = executeMigrate(t, append(args, "--"+flagFundCommunityPool, "50000001uatom", "--"+flagFundFrom, from)...)
+	require.EqualError(t, err, "failed to apply --fund-community-pool: account "+from+" holds 50000000uatom, less than the 50000001uatom funding the community pool")
+
+	reportPath := filepath.Join(t.TempDir(), "module-accounts.json")
+	var log bytes.Buffer
+	out, err := executeMigrateTo(t, &log, append(args, "--"+flagFundCommunityPool, "20000000uatom", "--"+flagFundFrom, from, "--"+flagModuleAcctsReport, reportPath)...)
+	require.NoError(t, err)
+	require.Contains(t, log.String(), "distribution: funded the community pool with 20000000uatom from "+from)
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report []moduleAccountBalance
+	require.NoError(t, json.Unmarshal(bz, &report))
+	distributionAudit := moduleAccountAudit(t, report, distribution.ModuleName)
+	require.True(t, distributionAudit.Balanced())
+	require.Equal(t, "20000000uatom", distributionAudit.Funded.String())
+	require.Equal(t, from, distributionAudit.FundedFrom)
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	cdc := MakeEncodingConfig().Marshaler
+	var distributionGenesis distribution.GenesisState
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+	require.True(t, distributionGenesis.FeePool.CommunityPool.AmountOf(genesisbuilder.BondDenom).GTE(sdk.NewDec(20000000)))
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	for _, balance := range bankGenesis.Balances {
+		if balance.Address == from {
+			require.Equal(t, "30000000uatom", balance.Coins.String())
+		}
+	}
+}
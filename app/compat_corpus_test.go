@@ -0,0 +1,174 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// compatCorpus is the directory of the compatibility corpus, one directory of
+// cases per exported era, see its README.md.
+const compatCorpus = "testdata/compat"
+
+// compatCase is a case of the compatibility corpus.
+type compatCase struct {
+	Era  string
+	Name string
+	// Dir holds the genesis.json of the case, its args.json and migrated.golden.
+	Dir string
+	// Args are the migrate arguments of the era followed by those of the case.
+	Args []string
+}
+
+// loadCompatCorpus returns the cases of every era of the corpus at root, by
+// era and name.
+func loadCompatCorpus(t *testing.T, root string) []compatCase {
+	eras, err := ioutil.ReadDir(root)
+	require.NoError(t, err)
+
+	var cases []compatCase
+	for _, era := range eras {
+		if !era.IsDir() {
+			continue
+		}
+
+		eraDir := filepath.Join(root, era.Name())
+		eraArgs := readCompatArgs(t, eraDir)
+
+		entries, err := ioutil.ReadDir(eraDir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			dir := filepath.Join(eraDir, entry.Name())
+			if !entry.IsDir() {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(dir, "genesis.json")); err != nil {
+				t.Fatalf("case %s of the compatibility corpus has no genesis.json", dir)
+			}
+
+			cases = append(cases, compatCase{
+				Era:  era.Name(),
+				Name: entry.Name(),
+				Dir:  dir,
+				Args: append(append([]string(nil), eraArgs...), readCompatArgs(t, dir)...),
+			})
+		}
+	}
+
+	sort.Slice(cases, func(i, j int) bool {
+		if cases[i].Era != cases[j].Era {
+			return cases[i].Era < cases[j].Era
+		}
+		return cases[i].Name < cases[j].Name
+	})
+
+	return cases
+}
+
+// readCompatArgs reads the JSON array of migrate arguments of the args.json in
+// dir, if any. Arguments starting with ./ are paths relative to dir.
+func readCompatArgs(t *testing.T, dir string) []string {
+	bz, err := ioutil.ReadFile(filepath.Join(dir, "args.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var args []string
+	require.NoError(t, json.Unmarshal(bz, &args), "args.json of %s", dir)
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "./") {
+			args[i] = filepath.Join(dir, arg)
+		}
+	}
+
+	return args
+}
+
+func TestCompatibilityCorpus(t *testing.T) {
+	cases := loadCompatCorpus(t, compatCorpus)
+	require.NotEmpty(t, cases)
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.Era+"/"+tc.Name, func(t *testing.T) {
+			out, err := executeMigrate(t, append([]string{filepath.Join(tc.Dir, "genesis.json")}, tc.Args...)...)
+			require.NoError(t, err)
+
+			// the golden output is indented for readable diffs, the order of
+			// the keys and the values being those of the output
+			var indented bytes.Buffer
+			require.NoError(t, json.Indent(&indented, out, "", "  "))
+
+			golden := filepath.Join(tc.Dir, "migrated.golden")
+			if *updateGolden {
+				require.NoError(t, ioutil.WriteFile(golden, indented.Bytes(), 0644))
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			require.NoError(t, err, "run the test with -update-golden to write the golden output")
+			require.Equal(t, string(expected), indented.String())
+		})
+	}
+}
+
+// TestCompatibilityCorpusConcurrent migrates every case of the corpus several
+// times over from 8 goroutines sharing one client context, codec and
+// interface registry included, so go test -race catches migration code that
+// mutates them, see make test-race-migrate. The outputs must be the golden
+// ones.
+func TestCompatibilityCorpusConcurrent(t *testing.T) {
+	const (
+		workers = 8
+		rounds  = 3
+	)
+
+	cases := loadCompatCorpus(t, compatCorpus)
+	require.NotEmpty(t, cases)
+	expected := make([][]byte, len(cases))
+	for i, tc := range cases {
+		golden, err := ioutil.ReadFile(filepath.Join(tc.Dir, "migrated.golden"))
+		require.NoError(t, err)
+		var compact bytes.Buffer
+		require.NoError(t, json.Compact(&compact, golden))
+		expected[i] = compact.Bytes()
+	}
+
+	clientCtx := migrateClientContext()
+	jobs := make(chan int)
+	errs := make([]error, len(cases)*rounds)
+	outs := make([][]byte, len(cases)*rounds)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				tc := cases[job%len(cases)]
+				outs[job], errs[job] = executeMigrateClient(context.Background(), &clientCtx, ioutil.Discard,
+					append([]string{filepath.Join(tc.Dir, "genesis.json")}, tc.Args...)...)
+			}
+		}()
+	}
+	for job := range outs {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for job, out := range outs {
+		tc := cases[job%len(cases)]
+		require.NoError(t, errs[job], "%s/%s", tc.Era, tc.Name)
+		require.Equal(t, string(expected[job%len(cases)]), strings.TrimSuffix(string(out), "\n"), "%s/%s", tc.Era, tc.Name)
+	}
+}
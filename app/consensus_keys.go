@@ -0,0 +1,157 @@
+package gaia
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+// consensusKeyCollision is a consensus pubkey shared by several validators of
+// the staking genesis, which the staking genesis rejects and whose slashing is
+// undefined.
+type consensusKeyCollision struct {
+	ConsAddress string               `json:"consensus_address"`
+	Validators  []collidingValidator `json:"validators"`
+}
+
+// collidingValidator is a validator sharing the consensus key of a collision.
+type collidingValidator struct {
+	OperatorAddress string `json:"operator_address"`
+	Status          string `json:"status"`
+	// StrippedTo is the consensus address of the placeholder key replacing
+	// the shared key of a stripped validator.
+	StrippedTo string `json:"stripped_to,omitempty"`
+}
+
+// Bonded returns the operator addresses of the bonded validators of the
+// collision, which no option can resolve.
+func (c consensusKeyCollision) Bonded() []string {
+	var bonded []string
+	for _, val := range c.Validators {
+		if val.Status == staking.Bonded.String() {
+			bonded = append(bonded, val.OperatorAddress)
+		}
+	}
+
+	return bonded
+}
+
+func (c consensusKeyCollision) String() string {
+	validators := make([]string, len(c.Validators))
+	for i, val := range c.Validators {
+		validators[i] = fmt.Sprintf("%s (%s)", val.OperatorAddress, strings.ToLower(strings.TrimPrefix(val.Status, "BOND_STATUS_")))
+	}
+
+	return fmt.Sprintf("consensus key %s is shared by validators %s", c.ConsAddress, strings.Join(validators, ", "))
+}
+
+// findConsensusKeyCollisions returns the consensus pubkeys shared by several
+// validators of the staking genesis of state, whatever their status, in the
+// genesis order of their first validator.
+func findConsensusKeyCollisions(cdc codec.JSONMarshaler, state types.AppMap) ([]consensusKeyCollision, error) {
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", staking.ModuleName)
+	}
+
+	var keys []string
+	byKey := make(map[string]*consensusKeyCollision)
+	for _, val := range stakingGenesis.Validators {
+		pk, err := val.ConsPubKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid consensus key of validator %s", val.OperatorAddress)
+		}
+
+		key := string(pk.Bytes())
+		c, ok := byKey[key]
+		if !ok {
+			c = &consensusKeyCollision{ConsAddress: sdk.ConsAddress(pk.Address()).String()}
+			byKey[key] = c
+			keys = append(keys, key)
+		}
+		c.Validators = append(c.Validators, collidingValidator{OperatorAddress: val.OperatorAddress, Status: val.Status.String()})
+	}
+
+	var collisions []consensusKeyCollision
+	for _, key := range keys {
+		if c := byKey[key]; len(c.Validators) > 1 {
+			collisions = append(collisions, *c)
+		}
+	}
+
+	return collisions, nil
+}
+
+// stripDuplicateConsensusKeys resolves collisions without more than one
+// bonded validator. The shared key stays with the bonded validator, or else
+// an unbonding one, which can still be slashed for past infractions, or else
+// the first one. The other validators get a placeholder key hashed from their
+// operator address, whose private key nobody knows, as the staking genesis
+// needs a key for every validator. The collisions are returned with the
+// placeholder consensus addresses.
+func stripDuplicateConsensusKeys(cdc codec.JSONMarshaler, state types.AppMap, collisions []consensusKeyCollision) ([]consensusKeyCollision, error) {
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", staking.ModuleName)
+	}
+
+	index := make(map[string]int, len(stakingGenesis.Validators))
+	for i, val := range stakingGenesis.Validators {
+		index[val.OperatorAddress] = i
+	}
+
+	stripped := make([]consensusKeyCollision, len(collisions))
+	for i, c := range collisions {
+		if bonded := c.Bonded(); len(bonded) > 1 {
+			return nil, fmt.Errorf("%s, %d of them bonded", c, len(bonded))
+		}
+
+		keep := 0
+		for j, val := range c.Validators {
+			if statusRank(val.Status) > statusRank(c.Validators[keep].Status) {
+				keep = j
+			}
+		}
+
+		stripped[i] = consensusKeyCollision{ConsAddress: c.ConsAddress, Validators: append([]collidingValidator{}, c.Validators...)}
+		for j, val := range c.Validators {
+			if j == keep {
+				continue
+			}
+
+			sum := sha256.Sum256([]byte("stripped consensus key " + val.OperatorAddress))
+			pk := &ed25519.PubKey{Key: sum[:ed25519.PubKeySize]}
+			any, err := codectypes.NewAnyWithValue(pk)
+			if err != nil {
+				return nil, err
+			}
+
+			stakingGenesis.Validators[index[val.OperatorAddress]].ConsensusPubkey = any
+			stripped[i].Validators[j].StrippedTo = sdk.ConsAddress(pk.Address()).String()
+		}
+	}
+
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	return stripped, nil
+}
+
+// statusRank orders the validator statuses by which keeps a shared key.
+func statusRank(status string) int {
+	switch status {
+	case staking.Bonded.String():
+		return 2
+	case staking.Unbonding.String():
+		return 1
+	default:
+		return 0
+	}
+}
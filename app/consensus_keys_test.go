@@ -0,0 +1,143 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestStripDuplicateConsensusKeys(t *testing.T) {
+	bonded, unbonding, unbonded := stakingtypes.Bonded, stakingtypes.Unbonding, stakingtypes.Unbonded
+
+	for _, tc := range []struct {
+		statuses [2]stakingtypes.BondStatus
+		keep     int
+		fatal    bool
+	}{
+		{statuses: [2]stakingtypes.BondStatus{bonded, bonded}, fatal: true},
+		{statuses: [2]stakingtypes.BondStatus{bonded, unbonding}, keep: 0},
+		{statuses: [2]stakingtypes.BondStatus{unbonded, bonded}, keep: 1},
+		{statuses: [2]stakingtypes.BondStatus{unbonded, unbonding}, keep: 1},
+		{statuses: [2]stakingtypes.BondStatus{unbonding, unbonding}, keep: 0},
+		{statuses: [2]stakingtypes.BondStatus{unbonded, unbonded}, keep: 0},
+	} {
+		t.Run(fmt.Sprintf("%s-%s", tc.statuses[0], tc.statuses[1]), func(t *testing.T) {
+			b := genesisbuilder.New().WithValidators(3)
+			_, state := buildTestGenesis(t, b)
+			cdc := MakeEncodingConfig().Marshaler
+
+			// validators 0 and 1 share the key of validator 0, 2 is unaffected
+			var stakingGenesis stakingtypes.GenesisState
+			cdc.MustUnmarshalJSON(state[stakingtypes.ModuleName], &stakingGenesis)
+			stakingGenesis.Validators[1].ConsensusPubkey = stakingGenesis.Validators[0].ConsensusPubkey
+			for i, status := range tc.statuses {
+				stakingGenesis.Validators[i].Status = status
+			}
+			state[stakingtypes.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+			require.Error(t, staking.ValidateGenesis(&stakingGenesis))
+
+			collisions, err := findConsensusKeyCollisions(cdc, state)
+			require.NoError(t, err)
+			require.Equal(t, []consensusKeyCollision{{
+				ConsAddress: b.ValidatorConsAddress(0).String(),
+				Validators: []collidingValidator{
+					{OperatorAddress: stakingGenesis.Validators[0].OperatorAddress, Status: tc.statuses[0].String()},
+					{OperatorAddress: stakingGenesis.Validators[1].OperatorAddress, Status: tc.statuses[1].String()},
+				},
+			}}, collisions)
+
+			stripped, err := stripDuplicateConsensusKeys(cdc, state, collisions)
+			if tc.fatal {
+				require.EqualError(t, err, fmt.Sprintf("consensus key %s is shared by validators %s (bonded), %s (bonded), 2 of them bonded",
+					b.ValidatorConsAddress(0), stakingGenesis.Validators[0].OperatorAddress, stakingGenesis.Validators[1].OperatorAddress))
+				return
+			}
+			require.NoError(t, err)
+
+			strip := 1 - tc.keep
+			require.Len(t, stripped, 1)
+			require.Empty(t, stripped[0].Validators[tc.keep].StrippedTo)
+			require.NotEmpty(t, stripped[0].Validators[strip].StrippedTo)
+
+			cdc.MustUnmarshalJSON(state[stakingtypes.ModuleName], &stakingGenesis)
+			require.NoError(t, staking.ValidateGenesis(&stakingGenesis))
+
+			consAddr := func(i int) string {
+				addr, err := stakingGenesis.Validators[i].GetConsAddr()
+				require.NoError(t, err)
+				return addr.String()
+			}
+			require.Equal(t, b.ValidatorConsAddress(0).String(), consAddr(tc.keep))
+			require.Equal(t, stripped[0].Validators[strip].StrippedTo, consAddr(strip))
+			require.Equal(t, b.ValidatorConsAddress(2).String(), consAddr(2))
+
+			collisions, err = findConsensusKeyCollisions(cdc, state)
+			require.NoError(t, err)
+			require.Empty(t, collisions)
+		})
+	}
+}
+
+func TestMigrateDuplicateConsensusKeys(t *testing.T) {
+	duplicateOperator := sdk.ValAddress(crypto.AddressHash([]byte("duplicate"))).String()
+	withDuplicate := func(status int) string {
+		return writeSourceGenesis(t, func(genesis map[string]interface{}) {
+			stakingGenesis := genesis["app_state"].(map[string]interface{})["staking"].(map[string]interface{})
+			validators := stakingGenesis["validators"].([]interface{})
+
+			duplicate := make(map[string]interface{})
+			for k, v := range validators[0].(map[string]interface{}) {
+				duplicate[k] = v
+			}
+			duplicate["operator_address"] = duplicateOperator
+			duplicate["status"] = status
+			duplicate["tokens"] = "0"
+			duplicate["delegator_shares"] = "0"
+			stakingGenesis["validators"] = append(validators, duplicate)
+		})
+	}
+	args := []string{"--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	// a shared key of an unbonding validator can be stripped
+	source := withDuplicate(1)
+	_, err := executeMigrate(t, append([]string{source}, args...)...)
+	require.EqualError(t, err, "1 consensus keys are shared by several validators, use --strip-duplicate-consensus-keys to strip them from the validators not bonded")
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	out, err := executeMigrate(t, append([]string{source, "--strip-duplicate-consensus-keys", "--duplicate-consensus-keys-report", reportPath}, args...)...)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report []consensusKeyCollision
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Len(t, report, 1)
+	require.Equal(t, "cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0", report[0].Validators[0].OperatorAddress)
+	require.Empty(t, report[0].Validators[0].StrippedTo)
+	require.Equal(t, duplicateOperator, report[0].Validators[1].OperatorAddress)
+	require.Equal(t, stakingtypes.Unbonding.String(), report[0].Validators[1].Status)
+	require.NotEmpty(t, report[0].Validators[1].StrippedTo)
+
+	// the migrated staking genesis validates
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+	var stakingGenesis stakingtypes.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(state[stakingtypes.ModuleName], &stakingGenesis)
+	require.NoError(t, staking.ValidateGenesis(&stakingGenesis))
+
+	// a key shared by bonded validators cannot
+	_, err = executeMigrate(t, append([]string{withDuplicate(2), "--strip-duplicate-consensus-keys"}, args...)...)
+	require.EqualError(t, err, "1 consensus keys are shared by several bonded validators")
+}
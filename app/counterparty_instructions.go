@@ -0,0 +1,277 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	commitmenttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/23-commitment/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	upgradetypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+const flagOutputDir = "output-dir"
+
+// recommendedTrustingPeriod is the trusting period recommended to the clients
+// of the migrated chain, the usual 2/3 of its unbonding period.
+func recommendedTrustingPeriod(unbondingPeriod time.Duration) time.Duration {
+	return unbondingPeriod * 2 / 3
+}
+
+// counterpartyInstructions are the parameters the relayer operators of a
+// counterparty chain need to update or upgrade their clients of the migrated
+// chain.
+type counterpartyInstructions struct {
+	CounterpartyChainID string `json:"counterparty_chain_id"`
+	ChainID             string `json:"chain_id"`
+	// RevisionNumber is the revision of the migrated chain ID, the revision
+	// of the heights of its clients.
+	RevisionNumber  uint64    `json:"revision_number"`
+	InitialHeight   int64     `json:"initial_height"`
+	GenesisTime     time.Time `json:"genesis_time"`
+	UnbondingPeriod string    `json:"unbonding_period"`
+	// TrustingPeriod is the recommended trusting period of the clients of
+	// the migrated chain.
+	TrustingPeriod string               `json:"recommended_trusting_period"`
+	Clients        []counterpartyClient `json:"clients"`
+	Upgrade        clientUpgrade        `json:"upgrade"`
+}
+
+// counterpartyClient is a client of the migrated chain tracking the
+// counterparty chain and, from its connections, the clients of the
+// counterparty chain tracking the migrated chain, those to update.
+type counterpartyClient struct {
+	ClientID              string   `json:"client_id"`
+	LatestHeight          string   `json:"latest_height"`
+	Connections           []string `json:"connections"`
+	CounterpartyClientIDs []string `json:"counterparty_client_ids"`
+}
+
+// clientUpgrade holds the values of a MsgUpgradeClient of the clients of the
+// migrated chain: the upgraded client state, its custom fields zeroed, and
+// the keys of the upgrade store its proofs are of. UpgradeHeight is the last
+// height of the source chain.
+type clientUpgrade struct {
+	UpgradePath           []string        `json:"upgrade_path"`
+	UpgradeHeight         int64           `json:"upgrade_height"`
+	UpgradedClientKey     string          `json:"upgraded_client_key"`
+	UpgradedConsStateKey  string          `json:"upgraded_consensus_state_key"`
+	UpgradedClientState   json.RawMessage `json:"upgraded_client_state"`
+	UpgradedLatestHeight  string          `json:"upgraded_latest_height"`
+	UpgradedUnbondingTime string          `json:"upgraded_unbonding_period"`
+}
+
+// GenesisCounterpartyInstructionsCmd returns a command writing the client
+// update instructions of the counterparty chains of a migrated genesis.
+func GenesisCounterpartyInstructionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "counterparty-instructions [migrated-genesis]",
+		Short: "Write the client update parameters of every IBC counterparty chain of a migrated genesis",
+		Long: fmt.Sprintf(`Write, for every counterparty chain of the tendermint clients of the IBC genesis
+of a migrated genesis, a JSON document for its relayer operators: the chain ID,
+revision, initial height, genesis time and unbonding period of the migrated
+chain, the recommended trusting period of its clients, 2/3 of the unbonding
+period, the clients of the counterparty chain tracking it from the
+connections, and the values of a MsgUpgradeClient: the upgrade path, the
+upgrade height, the last of the source chain, the upgrade store keys of the
+upgraded states and the upgraded client state.
+
+A migration restarting from an exported genesis commits no upgraded states,
+the counterparties then replace their clients, e.g. by a client update
+proposal, with clients of these parameters.
+
+The documents are printed as a JSON array sorted by counterparty chain ID, or
+written to --output-dir as <counterparty-chain-id>.json. Pass - as the genesis
+file to read it from STDIN.
+
+Example:
+$ %s genesis counterparty-instructions migrated.json --output-dir relayers/
+`, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			doc, err := genesis.Load(input)
+			if err != nil {
+				return err
+			}
+
+			instructions, err := newCounterpartyInstructions(doc)
+			if err != nil {
+				return err
+			}
+
+			outputDir, _ := cmd.Flags().GetString(flagOutputDir)
+			if outputDir == "" {
+				bz, err := json.MarshalIndent(instructions, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to JSON marshal counterparty instructions")
+				}
+				cmd.Println(string(bz))
+				return nil
+			}
+
+			files, err := outputFilesFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if err := files.prepareDir(outputDir); err != nil {
+				return err
+			}
+			for _, instruction := range instructions {
+				bz, err := json.MarshalIndent(instruction, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to JSON marshal counterparty instructions")
+				}
+
+				path := filepath.Join(outputDir, instruction.CounterpartyChainID+".json")
+				if err := files.WriteFile(path, append(bz, '\n'), outputFileMode); err != nil {
+					return err
+				}
+				cmd.PrintErrf("wrote the instructions of %s to %s\n", instruction.CounterpartyChainID, path)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagOutputDir, "", "Write the document of every counterparty chain to <counterparty-chain-id>.json in this directory instead")
+	addOutputFileFlags(cmd)
+
+	return cmd
+}
+
+// newCounterpartyInstructions returns the instructions of every counterparty
+// chain of the tendermint clients of doc, sorted by chain ID. The clients of
+// other types are left out.
+func newCounterpartyInstructions(doc *genesis.Document) ([]counterpartyInstructions, error) {
+	var ibcGenesis ibccoretypes.GenesisState
+	if err := doc.Module(host.ModuleName, &ibcGenesis); err != nil {
+		return nil, err
+	}
+	var stakingGenesis staking.GenesisState
+	if err := doc.Module(staking.ModuleName, &stakingGenesis); err != nil {
+		return nil, err
+	}
+
+	genDoc := doc.GenesisDoc()
+	unbondingPeriod := stakingGenesis.Params.UnbondingTime
+	trustingPeriod := recommendedTrustingPeriod(unbondingPeriod)
+	revision := clienttypes.ParseChainID(genDoc.ChainID)
+
+	upgrade, err := newClientUpgrade(genDoc.ChainID, revision, genDoc.InitialHeight, unbondingPeriod, trustingPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	connections := make(map[string][]counterpartyConnection)
+	for _, connection := range ibcGenesis.ConnectionGenesis.Connections {
+		connections[connection.ClientId] = append(connections[connection.ClientId], counterpartyConnection{
+			ID: connection.Id, CounterpartyClientID: connection.Counterparty.ClientId,
+		})
+	}
+
+	byChainID := make(map[string]*counterpartyInstructions)
+	for _, client := range ibcGenesis.ClientGenesis.Clients {
+		clientState, err := clienttypes.UnpackClientState(client.ClientState)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid client state of %s", client.ClientId)
+		}
+
+		tmClientState, ok := clientState.(*ibctmtypes.ClientState)
+		if !ok {
+			continue
+		}
+
+		instruction, ok := byChainID[tmClientState.ChainId]
+		if !ok {
+			instruction = &counterpartyInstructions{
+				CounterpartyChainID: tmClientState.ChainId,
+				ChainID:             genDoc.ChainID,
+				RevisionNumber:      revision,
+				InitialHeight:       genDoc.InitialHeight,
+				GenesisTime:         genDoc.GenesisTime,
+				UnbondingPeriod:     unbondingPeriod.String(),
+				TrustingPeriod:      trustingPeriod.String(),
+				Upgrade:             upgrade,
+			}
+			byChainID[tmClientState.ChainId] = instruction
+		}
+
+		entry := counterpartyClient{
+			ClientID:              client.ClientId,
+			LatestHeight:          tmClientState.LatestHeight.String(),
+			Connections:           []string{},
+			CounterpartyClientIDs: []string{},
+		}
+		seen := make(map[string]bool)
+		for _, connection := range connections[client.ClientId] {
+			entry.Connections = append(entry.Connections, connection.ID)
+			if !seen[connection.CounterpartyClientID] {
+				seen[connection.CounterpartyClientID] = true
+				entry.CounterpartyClientIDs = append(entry.CounterpartyClientIDs, connection.CounterpartyClientID)
+			}
+		}
+		sort.Strings(entry.Connections)
+		sort.Strings(entry.CounterpartyClientIDs)
+		instruction.Clients = append(instruction.Clients, entry)
+	}
+
+	instructions := make([]counterpartyInstructions, 0, len(byChainID))
+	for _, instruction := range byChainID {
+		sort.Slice(instruction.Clients, func(i, j int) bool { return instruction.Clients[i].ClientID < instruction.Clients[j].ClientID })
+		instructions = append(instructions, *instruction)
+	}
+	sort.Slice(instructions, func(i, j int) bool { return instructions[i].CounterpartyChainID < instructions[j].CounterpartyChainID })
+
+	return instructions, nil
+}
+
+type counterpartyConnection struct {
+	ID                   string
+	CounterpartyClientID string
+}
+
+// newClientUpgrade returns the MsgUpgradeClient values of the migrated chain
+// chainID starting at initialHeight.
+func newClientUpgrade(chainID string, revision uint64, initialHeight int64, unbondingPeriod, trustingPeriod time.Duration) (clientUpgrade, error) {
+	upgradeHeight := initialHeight - 1
+	latestHeight := clienttypes.NewHeight(revision, uint64(initialHeight))
+
+	clientState := ibctmtypes.NewClientState(chainID, ibctmtypes.DefaultTrustLevel, trustingPeriod, unbondingPeriod,
+		0, latestHeight, commitmenttypes.GetSDKSpecs(), standardUpgradePath, false, false).ZeroCustomFields()
+	any, err := codectypes.NewAnyWithValue(clientState)
+	if err != nil {
+		return clientUpgrade{}, err
+	}
+	bz, err := MakeEncodingConfig().Marshaler.MarshalJSON(any)
+	if err != nil {
+		return clientUpgrade{}, errors.Wrap(err, "failed to JSON marshal upgraded client state")
+	}
+
+	return clientUpgrade{
+		UpgradePath:           standardUpgradePath,
+		UpgradeHeight:         upgradeHeight,
+		UpgradedClientKey:     string(upgradetypes.UpgradedClientKey(upgradeHeight)),
+		UpgradedConsStateKey:  string(upgradetypes.UpgradedConsStateKey(upgradeHeight)),
+		UpgradedClientState:   bz,
+		UpgradedLatestHeight:  latestHeight.String(),
+		UpgradedUnbondingTime: unbondingPeriod.String(),
+	}, nil
+}
@@ -0,0 +1,99 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	"github.com/cosmos/cosmos-sdk/x/ibc/core/exported"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenesisCounterpartyInstructionsCmd(t *testing.T) {
+	genDoc, _ := buildTestGenesis(t, genesisbuilder.New().WithChainID("cosmoshub-4").WithValidators(1).
+		WithIBCChannel("osmosis-1").WithIBCChannel("juno-1").WithIBCChannel("osmosis-1"))
+	genDoc.InitialHeight = 7000000
+	genesisFile := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, genDoc.SaveAs(genesisFile))
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := GenesisCounterpartyInstructionsCmd()
+		cmd.SetOut(&out)
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run(genesisFile)
+	require.NoError(t, err)
+	var instructions []counterpartyInstructions
+	require.NoError(t, json.Unmarshal([]byte(out), &instructions))
+	require.Len(t, instructions, 2)
+
+	unbondingPeriod := 21 * 24 * time.Hour
+	for _, instruction := range instructions {
+		require.Equal(t, "cosmoshub-4", instruction.ChainID)
+		require.Equal(t, uint64(4), instruction.RevisionNumber)
+		require.Equal(t, int64(7000000), instruction.InitialHeight)
+		require.True(t, genesisbuilder.GenesisTime.Equal(instruction.GenesisTime))
+		require.Equal(t, unbondingPeriod.String(), instruction.UnbondingPeriod)
+		require.Equal(t, (14 * 24 * time.Hour).String(), instruction.TrustingPeriod)
+
+		upgrade := instruction.Upgrade
+		require.Equal(t, []string{"upgrade", "upgradedIBCState"}, upgrade.UpgradePath)
+		require.Equal(t, int64(6999999), upgrade.UpgradeHeight)
+		require.Equal(t, "upgradedIBCState/6999999/upgradedClient", upgrade.UpgradedClientKey)
+		require.Equal(t, "upgradedIBCState/6999999/upgradedConsState", upgrade.UpgradedConsStateKey)
+		require.Equal(t, "4-7000000", upgrade.UpgradedLatestHeight)
+
+		var clientState exported.ClientState
+		require.NoError(t, MakeEncodingConfig().Marshaler.UnmarshalInterfaceJSON(upgrade.UpgradedClientState, &clientState))
+		tmClientState := clientState.(*ibctmtypes.ClientState)
+		require.Equal(t, "cosmoshub-4", tmClientState.ChainId)
+		require.Equal(t, unbondingPeriod, tmClientState.UnbondingPeriod)
+		require.Equal(t, clienttypes.NewHeight(4, 7000000), tmClientState.LatestHeight)
+		require.Equal(t, standardUpgradePath, tmClientState.UpgradePath)
+		// the custom fields are zeroed, every counterparty sets its own
+		require.Zero(t, tmClientState.TrustingPeriod)
+		require.Zero(t, tmClientState.MaxClockDrift)
+	}
+
+	// the clients are grouped by counterparty chain, sorted by chain ID
+	require.Equal(t, "juno-1", instructions[0].CounterpartyChainID)
+	require.Equal(t, []counterpartyClient{
+		{ClientID: "07-tendermint-1", LatestHeight: "1-1000", Connections: []string{"connection-1"}, CounterpartyClientIDs: []string{"07-tendermint-1"}},
+	}, instructions[0].Clients)
+	require.Equal(t, "osmosis-1", instructions[1].CounterpartyChainID)
+	require.Equal(t, []counterpartyClient{
+		{ClientID: "07-tendermint-0", LatestHeight: "1-1000", Connections: []string{"connection-0"}, CounterpartyClientIDs: []string{"07-tendermint-0"}},
+		{ClientID: "07-tendermint-2", LatestHeight: "1-1000", Connections: []string{"connection-2"}, CounterpartyClientIDs: []string{"07-tendermint-2"}},
+	}, instructions[1].Clients)
+
+	// one document per counterparty chain
+	outputDir := filepath.Join(t.TempDir(), "relayers")
+	out, err = run(genesisFile, "--output-dir", outputDir)
+	require.NoError(t, err)
+	require.Empty(t, out)
+	for _, instruction := range instructions {
+		bz, err := ioutil.ReadFile(filepath.Join(outputDir, instruction.CounterpartyChainID+".json"))
+		require.NoError(t, err)
+		var written counterpartyInstructions
+		require.NoError(t, json.Unmarshal(bz, &written))
+		require.Equal(t, instruction.Clients, written.Clients)
+	}
+
+	// a genesis without IBC clients has no counterparties
+	genDoc, _ = buildTestGenesis(t, genesisbuilder.New().WithValidators(1))
+	require.NoError(t, genDoc.SaveAs(genesisFile))
+	out, err = run(genesisFile)
+	require.NoError(t, err)
+	require.Equal(t, "[]\n", out)
+}
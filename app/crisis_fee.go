@@ -0,0 +1,35 @@
+package gaia
+
+import (
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	crisis "github.com/cosmos/cosmos-sdk/x/crisis/types"
+)
+
+// checkCrisisConstantFee makes sure the crisis constant fee is payable on the
+// migrated chain. A non-nil override replaces the fee and must be in a denom of
+// the bank supply. Without an override a fee in a denom missing from the supply
+// is left as is and registered as a warning.
+func checkCrisisConstantFee(crisisGenesis *crisis.GenesisState, supply sdk.Coins, override *sdk.Coin, warnings *warningCollector) error {
+	if override != nil {
+		if err := override.Validate(); err != nil {
+			return fmt.Errorf("invalid crisis constant fee: %w", err)
+		}
+
+		if supply.AmountOf(override.Denom).IsZero() {
+			return fmt.Errorf("crisis constant fee denom %s is not in the bank supply", override.Denom)
+		}
+
+		crisisGenesis.ConstantFee = *override
+		return nil
+	}
+
+	if supply.AmountOf(crisisGenesis.ConstantFee.Denom).IsZero() {
+		warnings.Add(warnCrisisFeeDenom, severityMedium, crisis.ModuleName,
+			"constant fee %s is in a denom missing from the bank supply, MsgVerifyInvariant cannot be paid for, use --%s to override it",
+			crisisGenesis.ConstantFee, flagCrisisConstantFee)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package gaia
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	crisis "github.com/cosmos/cosmos-sdk/x/crisis/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCrisisConstantFee(t *testing.T) {
+	_, state := buildTestGenesis(t, testGenesisBuilder())
+	cdc := MakeEncodingConfig().Marshaler
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+
+	crisisGenesis := func() *crisis.GenesisState {
+		var crisisGenesis crisis.GenesisState
+		cdc.MustUnmarshalJSON(state[crisis.ModuleName], &crisisGenesis)
+		return &crisisGenesis
+	}
+
+	t.Run("correct denom is untouched", func(t *testing.T) {
+		genesis := crisisGenesis()
+		expected := genesis.ConstantFee
+		require.Equal(t, genesisbuilder.BondDenom, expected.Denom)
+
+		var warnings warningCollector
+		require.NoError(t, checkCrisisConstantFee(genesis, bankGenesis.Supply, nil, &warnings))
+		require.Empty(t, warnings.Warnings())
+		require.Equal(t, expected, genesis.ConstantFee)
+	})
+
+	t.Run("wrong denom warns", func(t *testing.T) {
+		genesis := crisisGenesis()
+		genesis.ConstantFee = sdk.NewInt64Coin("stake", 1000)
+
+		var warnings warningCollector
+		require.NoError(t, checkCrisisConstantFee(genesis, bankGenesis.Supply, nil, &warnings))
+		require.Len(t, warnings.Warnings(), 1)
+		require.Equal(t, warnCrisisFeeDenom, warnings.Warnings()[0].Code)
+		require.Contains(t, warnings.Warnings()[0].Message, "1000stake")
+		require.Equal(t, "stake", genesis.ConstantFee.Denom)
+	})
+
+	t.Run("wrong denom fixed by override", func(t *testing.T) {
+		genesis := crisisGenesis()
+		genesis.ConstantFee = sdk.NewInt64Coin("stake", 1000)
+
+		fee := sdk.NewInt64Coin(genesisbuilder.BondDenom, 1333000000)
+		var warnings warningCollector
+		require.NoError(t, checkCrisisConstantFee(genesis, bankGenesis.Supply, &fee, &warnings))
+		require.Empty(t, warnings.Warnings())
+		require.Equal(t, fee, genesis.ConstantFee)
+	})
+
+	t.Run("override denom not in supply", func(t *testing.T) {
+		fee := sdk.NewInt64Coin("stake", 1000)
+		err := checkCrisisConstantFee(crisisGenesis(), bankGenesis.Supply, &fee, &warningCollector{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not in the bank supply")
+	})
+}
@@ -0,0 +1,105 @@
+package gaia
+
+import (
+	"github.com/cosmos/cosmos-sdk/codec"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// emptyRecordsReport counts the records dropEmptyRecords removed.
+type emptyRecordsReport struct {
+	Balances            int `json:"balances"`
+	Delegations         int `json:"delegations"`
+	UnbondingEntries    int `json:"unbonding_entries"`
+	RedelegationEntries int `json:"redelegation_entries"`
+}
+
+// dropEmptyRecords removes the records of the app state that hold nothing:
+// bank balances without coins, delegations without shares, unbonding entries
+// without balance and redelegation entries without destination shares, and
+// the unbonding delegations and redelegations left without entries. Accounts
+// stay in auth, their number and sequence still matter. The starting infos of
+// the dropped delegations are removed as well, so the reference counts of the
+// distribution historical rewards stay consistent; supply, pools and
+// validator shares are unchanged.
+func dropEmptyRecords(cdc codec.JSONMarshaler, state types.AppMap) emptyRecordsReport {
+	var (
+		report              emptyRecordsReport
+		bankGenesis         bank.GenesisState
+		stakingGenesis      staking.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+
+	balances := bankGenesis.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		if balance.Coins.IsZero() {
+			report.Balances++
+			continue
+		}
+
+		balances = append(balances, balance)
+	}
+	bankGenesis.Balances = balances
+
+	dropped := make(map[startingInfoKey]bool)
+	delegations := stakingGenesis.Delegations[:0]
+	for _, del := range stakingGenesis.Delegations {
+		if del.Shares.IsZero() {
+			dropped[startingInfoKey{del.DelegatorAddress, del.ValidatorAddress}] = true
+			report.Delegations++
+			continue
+		}
+
+		delegations = append(delegations, del)
+	}
+	stakingGenesis.Delegations = delegations
+
+	unbondings := stakingGenesis.UnbondingDelegations[:0]
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		entries := ubd.Entries[:0]
+		for _, entry := range ubd.Entries {
+			if entry.Balance.IsZero() {
+				report.UnbondingEntries++
+				continue
+			}
+
+			entries = append(entries, entry)
+		}
+
+		if ubd.Entries = entries; len(entries) > 0 {
+			unbondings = append(unbondings, ubd)
+		}
+	}
+	stakingGenesis.UnbondingDelegations = unbondings
+
+	redelegations := stakingGenesis.Redelegations[:0]
+	for _, red := range stakingGenesis.Redelegations {
+		entries := red.Entries[:0]
+		for _, entry := range red.Entries {
+			if entry.SharesDst.IsZero() {
+				report.RedelegationEntries++
+				continue
+			}
+
+			entries = append(entries, entry)
+		}
+
+		if red.Entries = entries; len(entries) > 0 {
+			redelegations = append(redelegations, red)
+		}
+	}
+	stakingGenesis.Redelegations = redelegations
+
+	dropStartingInfos(&distributionGenesis, dropped)
+
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+	return report
+}
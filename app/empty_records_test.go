@@ -0,0 +1,126 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropEmptyRecords(t *testing.T) {
+	b := testGenesisBuilder()
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	cdc := MakeEncodingConfig().Marshaler
+	genDoc, state := exportTestGenesis(t, builtDoc)
+
+	expected := make(map[string]json.RawMessage)
+	for _, module := range []string{auth.ModuleName, bank.ModuleName, staking.ModuleName, distribution.ModuleName} {
+		expected[module] = state[module]
+	}
+
+	var (
+		bankGenesis         bank.GenesisState
+		stakingGenesis      staking.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+
+	// the export holds empty balances of its own
+	var nonEmpty bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &nonEmpty)
+	nonEmpty.Balances = nonEmpty.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		if !balance.Coins.IsZero() {
+			nonEmpty.Balances = append(nonEmpty.Balances, balance)
+		}
+	}
+	emptyBalances := len(bankGenesis.Balances) - len(nonEmpty.Balances)
+	expected[bank.ModuleName] = cdc.MustMarshalJSON(&nonEmpty)
+
+	eve := b.Address("eve").String()
+	validator := b.ValidatorAddress(0).String()
+
+	// the mint module account stays in auth without a balance
+	bankGenesis.Balances = append(bankGenesis.Balances,
+		bank.Balance{Address: auth.NewModuleAddress(mint.ModuleName).String(), Coins: sdk.NewCoins()},
+		bank.Balance{Address: eve, Coins: sdk.NewCoins()},
+	)
+
+	// a delegation left without shares, with the starting info and reference
+	// of a delegation started in the same period as alice's
+	stakingGenesis.Delegations = append(stakingGenesis.Delegations, staking.Delegation{
+		DelegatorAddress: eve,
+		ValidatorAddress: validator,
+		Shares:           sdk.ZeroDec(),
+	})
+	for _, info := range distributionGenesis.DelegatorStartingInfos {
+		if info.DelegatorAddress != b.Address("alice").String() {
+			continue
+		}
+
+		info.DelegatorAddress = eve
+		distributionGenesis.DelegatorStartingInfos = append(distributionGenesis.DelegatorStartingInfos, info)
+		for i, record := range distributionGenesis.ValidatorHistoricalRewards {
+			if record.ValidatorAddress == validator && record.Period == info.StartingInfo.PreviousPeriod {
+				distributionGenesis.ValidatorHistoricalRewards[i].Rewards.ReferenceCount++
+			}
+		}
+	}
+
+	require.Len(t, stakingGenesis.UnbondingDelegations, 1)
+	ubd := stakingGenesis.UnbondingDelegations[0]
+	emptyEntry := ubd.Entries[0]
+	emptyEntry.Balance = sdk.ZeroInt()
+	stakingGenesis.UnbondingDelegations[0].Entries = append(stakingGenesis.UnbondingDelegations[0].Entries, emptyEntry)
+	stakingGenesis.UnbondingDelegations = append(stakingGenesis.UnbondingDelegations, staking.UnbondingDelegation{
+		DelegatorAddress: eve,
+		ValidatorAddress: validator,
+		Entries:          []staking.UnbondingDelegationEntry{emptyEntry},
+	})
+
+	stakingGenesis.Redelegations = append(stakingGenesis.Redelegations, staking.Redelegation{
+		DelegatorAddress:    eve,
+		ValidatorSrcAddress: validator,
+		ValidatorDstAddress: b.ValidatorAddress(1).String(),
+		Entries: []staking.RedelegationEntry{
+			staking.NewRedelegationEntry(1, emptyEntry.CompletionTime, sdk.NewInt(100), sdk.ZeroDec()),
+		},
+	})
+
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+	report := dropEmptyRecords(cdc, state)
+	require.Equal(t, emptyRecordsReport{Balances: emptyBalances + 2, Delegations: 1, UnbondingEntries: 2, RedelegationEntries: 1}, report)
+
+	// supply, pools, validator shares and reference counts are those of before
+	for module, bz := range expected {
+		require.JSONEq(t, string(bz), string(state[module]), module)
+	}
+
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+	genDoc.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+	require.NoError(t, SmokeTestGenesis(genDoc))
+}
+
+func TestMigrateDropEmptyRecords(t *testing.T) {
+	var stderr bytes.Buffer
+	_, err := executeMigrateTo(t, &stderr, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2",
+		"--no-prop-29", "--chain-id", "cosmoshub-4", "--drop-empty-records")
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "dropped 5 empty balances, 0 delegations without shares, 0 empty unbonding entries and 0 empty redelegation entries")
+}
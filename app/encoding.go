@@ -1,16 +1,11 @@
 package gaia
 
 import (
-	"github.com/cosmos/cosmos-sdk/std"
 	"github.com/cosmos/gaia/v5/app/params"
+	"github.com/cosmos/gaia/v5/internal/modules"
 )
 
 // MakeEncodingConfig creates an EncodingConfig for testing
 func MakeEncodingConfig() params.EncodingConfig {
-	encodingConfig := params.MakeEncodingConfig()
-	std.RegisterLegacyAminoCodec(encodingConfig.Amino)
-	std.RegisterInterfaces(encodingConfig.InterfaceRegistry)
-	ModuleBasics.RegisterLegacyAminoCodec(encodingConfig.Amino)
-	ModuleBasics.RegisterInterfaces(encodingConfig.InterfaceRegistry)
-	return encodingConfig
+	return modules.MakeEncodingConfig()
 }
@@ -0,0 +1,144 @@
+package gaia
+
+import (
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	evtypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+const (
+	flagDropStaleEvidence = "drop-stale-evidence"
+	flagEvidenceReport    = "evidence-report"
+)
+
+// staleEvidence is an equivocation of the evidence genesis whose consensus
+// address the migration rewrote, that names no validator of the migrated
+// state or that is older than the evidence max age.
+type staleEvidence struct {
+	Height           int64     `json:"height"`
+	Time             time.Time `json:"time"`
+	ConsensusAddress string    `json:"consensus_address"`
+	// RewrittenTo is the consensus address of the replaced key of the
+	// validator.
+	RewrittenTo string `json:"rewritten_to,omitempty"`
+	// Unknown is set when no validator has the consensus address, e.g. it
+	// was stripped from the validator: the evidence can never be handled.
+	Unknown bool `json:"unknown,omitempty"`
+	Dropped bool `json:"dropped,omitempty"`
+	// Expired is set when the evidence is older than both max ages of the
+	// consensus params at the initial height and the genesis time.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// validatorConsAddresses returns the consensus addresses of the validators of
+// the staking genesis of state by operator address.
+func validatorConsAddresses(cdc codec.JSONMarshaler, state types.AppMap) (map[string]string, error) {
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the staking genesis")
+	}
+
+	addresses := make(map[string]string, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		consAddr, err := val.GetConsAddr()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the consensus address of validator %s", val.OperatorAddress)
+		}
+		addresses[val.OperatorAddress] = consAddr.String()
+	}
+
+	return addresses, nil
+}
+
+// migrateEvidence checks the equivocations of the evidence genesis of state
+// against the validators of its staking genesis. The consensus address of
+// the equivocations of validators whose key changed from before, validator
+// consensus addresses by operator address, is rewritten to the new address.
+// Those naming no validator are kept, or removed with drop. The evidence
+// older than params at height and genesisTime is only reported. Evidence of
+// other types is kept as it is.
+func migrateEvidence(cdc codec.JSONMarshaler, state types.AppMap, before map[string]string, drop bool,
+	height int64, genesisTime time.Time, params *tmproto.EvidenceParams) ([]staleEvidence, error) {
+	if state[evtypes.ModuleName] == nil {
+		return nil, nil
+	}
+
+	var evidenceGenesis evtypes.GenesisState
+	if err := cdc.UnmarshalJSON(state[evtypes.ModuleName], &evidenceGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the evidence genesis")
+	}
+	if len(evidenceGenesis.Evidence) == 0 {
+		return nil, nil
+	}
+
+	after, err := validatorConsAddresses(cdc, state)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(after))
+	for _, consAddr := range after {
+		known[consAddr] = true
+	}
+	rewritten := make(map[string]string)
+	for operator, consAddr := range before {
+		if after[operator] != "" && after[operator] != consAddr {
+			rewritten[consAddr] = after[operator]
+		}
+	}
+
+	var (
+		stale    []staleEvidence
+		evidence []*codectypes.Any
+		changed  bool
+	)
+	for _, any := range evidenceGenesis.Evidence {
+		equivocation, ok := any.GetCachedValue().(*evtypes.Equivocation)
+		if !ok {
+			evidence = append(evidence, any)
+			continue
+		}
+
+		entry := staleEvidence{Height: equivocation.Height, Time: equivocation.Time, ConsensusAddress: equivocation.ConsensusAddress}
+		if params != nil {
+			entry.Expired = height-equivocation.Height > params.MaxAgeNumBlocks && genesisTime.Sub(equivocation.Time) > params.MaxAgeDuration
+		}
+
+		switch consAddr, ok := rewritten[equivocation.ConsensusAddress]; {
+		case ok:
+			updated := *equivocation
+			updated.ConsensusAddress = consAddr
+			if any, err = codectypes.NewAnyWithValue(&updated); err != nil {
+				return nil, errors.Wrap(err, "failed to encode the rewritten evidence")
+			}
+			entry.RewrittenTo, changed = consAddr, true
+		case !known[equivocation.ConsensusAddress]:
+			entry.Unknown, entry.Dropped = true, drop
+		}
+
+		if entry.RewrittenTo != "" || entry.Unknown || entry.Expired {
+			stale = append(stale, entry)
+		}
+		if entry.Dropped {
+			changed = true
+			continue
+		}
+		evidence = append(evidence, any)
+	}
+
+	if !changed {
+		return stale, nil
+	}
+
+	evidenceGenesis.Evidence = evidence
+	if state[evtypes.ModuleName], err = cdc.MarshalJSON(&evidenceGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the evidence genesis")
+	}
+
+	return stale, nil
+}
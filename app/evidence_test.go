@@ -0,0 +1,99 @@
+package gaia
+
+import (
+	"testing"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/evidence/exported"
+	evtypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+// evidenceGenesis returns a test app state with equivocations of four
+// validators, the key of the second replaced and the fourth pruned after the
+// consensus addresses returned were taken, and the replacement address.
+func evidenceGenesis(t *testing.T) (*genesisbuilder.Builder, types.AppMap, map[string]string, sdk.ConsAddress) {
+	b := genesisbuilder.New().WithValidators(4)
+	_, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	evidenceTime := genesisbuilder.GenesisTime.Add(-time.Hour)
+	var evidence []exported.Evidence
+	for i, height := range []int64{990, 980, 10, 970} {
+		evidenceTime := evidenceTime
+		if height == 10 {
+			evidenceTime = genesisbuilder.GenesisTime.Add(-30 * 24 * time.Hour)
+		}
+		evidence = append(evidence, &evtypes.Equivocation{
+			Height: height, Time: evidenceTime, Power: 10, ConsensusAddress: b.ValidatorConsAddress(i).String(),
+		})
+	}
+	state[evtypes.ModuleName] = cdc.MustMarshalJSON(evtypes.NewGenesisState(evidence))
+
+	before, err := validatorConsAddresses(cdc, state)
+	require.NoError(t, err)
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	replacement := ed25519.GenPrivKeyFromSecret([]byte("replacement")).PubKey()
+	validators := stakingGenesis.Validators[:0]
+	for _, val := range stakingGenesis.Validators {
+		switch val.OperatorAddress {
+		case b.ValidatorAddress(1).String():
+			val.ConsensusPubkey, err = codectypes.NewAnyWithValue(replacement)
+			require.NoError(t, err)
+		case b.ValidatorAddress(3).String():
+			continue
+		}
+		validators = append(validators, val)
+	}
+	stakingGenesis.Validators = validators
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	return b, state, before, sdk.ConsAddress(replacement.Address())
+}
+
+func TestMigrateEvidence(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	params := &tmproto.EvidenceParams{MaxAgeNumBlocks: 100, MaxAgeDuration: 48 * time.Hour}
+
+	for _, drop := range []bool{false, true} {
+		b, state, before, replacement := evidenceGenesis(t)
+
+		stale, err := migrateEvidence(cdc, state, before, drop, 1001, genesisbuilder.GenesisTime, params)
+		require.NoError(t, err)
+		require.Equal(t, []staleEvidence{
+			{Height: 980, Time: genesisbuilder.GenesisTime.Add(-time.Hour), ConsensusAddress: b.ValidatorConsAddress(1).String(), RewrittenTo: replacement.String()},
+			{Height: 10, Time: genesisbuilder.GenesisTime.Add(-30 * 24 * time.Hour), ConsensusAddress: b.ValidatorConsAddress(2).String(), Expired: true},
+			{Height: 970, Time: genesisbuilder.GenesisTime.Add(-time.Hour), ConsensusAddress: b.ValidatorConsAddress(3).String(), Unknown: true, Dropped: drop},
+		}, stale)
+
+		var evidenceGenesis evtypes.GenesisState
+		cdc.MustUnmarshalJSON(state[evtypes.ModuleName], &evidenceGenesis)
+		var addresses []string
+		for _, any := range evidenceGenesis.Evidence {
+			addresses = append(addresses, any.GetCachedValue().(*evtypes.Equivocation).ConsensusAddress)
+		}
+		expected := []string{b.ValidatorConsAddress(0).String(), replacement.String(), b.ValidatorConsAddress(2).String()}
+		if !drop {
+			expected = append(expected, b.ValidatorConsAddress(3).String())
+		}
+		require.Equal(t, expected, addresses)
+		require.NoError(t, evidenceGenesis.Validate())
+	}
+
+	// without evidence nothing changes
+	_, state := buildTestGenesis(t, genesisbuilder.New().WithValidators(1))
+	evidenceBefore := state[evtypes.ModuleName]
+	stale, err := migrateEvidence(cdc, state, nil, true, 1, genesisbuilder.GenesisTime, params)
+	require.NoError(t, err)
+	require.Empty(t, stale)
+	require.Equal(t, evidenceBefore, state[evtypes.ModuleName])
+}
@@ -0,0 +1,231 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// GenesisBisectCmd returns a command finding the app state modules of a
+// genesis that make it fail the smoke test.
+func GenesisBisectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bisect [good-genesis-file] [bad-genesis-file]",
+		Short: "Find the modules whose state makes a genesis fail the smoke test",
+		Long: fmt.Sprintf(`Smoke test hybrids of a genesis passing the smoke test of migrate --smoke-test
+and one failing it, each taking the state of some modules from the failing
+genesis and the others from the passing one, until the smallest set of modules
+whose failing state still fails is found. The modules are first halved, then
+dropped one at a time. Every probe is printed with the error or panic it
+captured.
+
+The hybrids keep the genesis doc fields of the failing genesis but for the
+tendermint validators, which follow the %s state. Pass - as either genesis file
+to read it from STDIN.
+
+Example:
+$ %s genesis bisect exported.json migrated.json
+`, staking.ModuleName, version.AppName),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == stdinGenesis && args[1] == stdinGenesis {
+				return fmt.Errorf("only one genesis file can be read from STDIN")
+			}
+
+			good, err := readBisectGenesis(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrapf(err, "invalid good genesis %s", args[0])
+			}
+
+			bad, err := readBisectGenesis(args[1], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrapf(err, "invalid bad genesis %s", args[1])
+			}
+
+			n := 0
+			modules, err := bisectGenesis(good, bad, SmokeTestGenesis, func(p bisectProbe) {
+				n++
+				modules := "no modules"
+				if len(p.Modules) > 0 {
+					modules = strings.Join(p.Modules, ", ")
+				}
+
+				if p.Err == nil {
+					cmd.Printf("probe %d, %s from the bad genesis: passes\n", n, modules)
+				} else {
+					cmd.Printf("probe %d, %s from the bad genesis: fails: %s\n", n, modules, p.Err)
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("the smoke test fails with the state of %s from %s\n", strings.Join(modules, ", "), args[1])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func readBisectGenesis(path string, stdin io.Reader) (*tmtypes.GenesisDoc, error) {
+	input, err := openGenesisInput(path, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	doc, err := genesis.Load(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.GenesisDoc(), nil
+}
+
+// bisectProbe is the smoke test result of the hybrid genesis taking the state
+// of Modules from the bad genesis.
+type bisectProbe struct {
+	Modules []string
+	Err     error
+}
+
+// bisectGenesis returns the smallest set of app state modules found whose
+// state taken from bad makes the genesis good fail smokeTest, reporting every
+// probe to onProbe. Modules whose state is the same in both genesis docs are
+// left out. The set is 1-minimal: taking any one of its modules from good
+// again passes.
+func bisectGenesis(good, bad *tmtypes.GenesisDoc, smokeTest func(*tmtypes.GenesisDoc) error, onProbe func(bisectProbe)) ([]string, error) {
+	var goodState, badState types.AppMap
+	if err := json.Unmarshal(good.AppState, &goodState); err != nil {
+		return nil, errors.Wrap(err, "failed to JSON unmarshal good app state")
+	}
+	if err := json.Unmarshal(bad.AppState, &badState); err != nil {
+		return nil, errors.Wrap(err, "failed to JSON unmarshal bad app state")
+	}
+
+	var differing []string
+	for module, bz := range badState {
+		if !bytes.Equal(bz, goodState[module]) {
+			differing = append(differing, module)
+		}
+	}
+	for module := range goodState {
+		if _, ok := badState[module]; !ok {
+			differing = append(differing, module)
+		}
+	}
+	sort.Strings(differing)
+
+	probe := func(modules []string) (bool, error) {
+		genDoc, err := hybridGenesis(good, bad, goodState, badState, modules)
+		if err != nil {
+			return false, err
+		}
+
+		err = smokeTest(genDoc)
+		onProbe(bisectProbe{Modules: modules, Err: err})
+		return err != nil, nil
+	}
+
+	if fails, err := probe(nil); err != nil {
+		return nil, err
+	} else if fails {
+		return nil, fmt.Errorf("the good genesis fails the smoke test with the genesis doc fields of the bad one")
+	}
+
+	if len(differing) == 0 {
+		return nil, fmt.Errorf("the app states of both genesis docs are the same")
+	}
+
+	if fails, err := probe(differing); err != nil {
+		return nil, err
+	} else if !fails {
+		return nil, fmt.Errorf("the bad genesis passes the smoke test with the validators of its %s state", staking.ModuleName)
+	}
+
+	modules := differing
+	for len(modules) > 1 {
+		half := len(modules) / 2
+
+		fails, err := probe(modules[:half])
+		if err != nil {
+			return nil, err
+		}
+		if fails {
+			modules = modules[:half]
+			continue
+		}
+
+		if fails, err = probe(modules[half:]); err != nil {
+			return nil, err
+		}
+		if fails {
+			modules = modules[half:]
+			continue
+		}
+
+		// the failure needs modules of both halves
+		break
+	}
+
+	for i := 0; len(modules) > 1 && i < len(modules); {
+		rest := append(append([]string(nil), modules[:i]...), modules[i+1:]...)
+
+		fails, err := probe(rest)
+		if err != nil {
+			return nil, err
+		}
+		if fails {
+			modules = rest
+		} else {
+			i++
+		}
+	}
+
+	return modules, nil
+}
+
+// hybridGenesis returns the bad genesis doc with the app state of good but for
+// modules, and the tendermint validators of the genesis providing the staking
+// state.
+func hybridGenesis(good, bad *tmtypes.GenesisDoc, goodState, badState types.AppMap, modules []string) (*tmtypes.GenesisDoc, error) {
+	state := make(types.AppMap, len(goodState))
+	for module, bz := range goodState {
+		state[module] = bz
+	}
+
+	hybrid := *bad
+	hybrid.Validators = good.Validators
+	for _, module := range modules {
+		if bz, ok := badState[module]; ok {
+			state[module] = bz
+		} else {
+			delete(state, module)
+		}
+
+		if module == staking.ModuleName {
+			hybrid.Validators = bad.Validators
+		}
+	}
+
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to JSON marshal hybrid app state")
+	}
+	hybrid.AppState = bz
+
+	return &hybrid, nil
+}
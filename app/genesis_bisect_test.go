@@ -0,0 +1,110 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// corruptedTestGenesis returns the test genesis and a copy with harmless
+// changes to the gov and mint state and an invalid validator address in the
+// staking state.
+func corruptedTestGenesis(t *testing.T) (good, bad *tmtypes.GenesisDoc) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	good, _ = buildTestGenesis(t, testGenesisBuilder())
+	bad, state := buildTestGenesis(t, testGenesisBuilder())
+
+	var govGenesis gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	govGenesis.VotingParams.VotingPeriod *= 2
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+
+	var mintGenesis mint.GenesisState
+	cdc.MustUnmarshalJSON(state[mint.ModuleName], &mintGenesis)
+	mintGenesis.Params.InflationMax = sdk.NewDecWithPrec(25, 2)
+	state[mint.ModuleName] = cdc.MustMarshalJSON(&mintGenesis)
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	stakingGenesis.Validators[0].OperatorAddress = "cosmosvaloper1corrupted"
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	var err error
+	bad.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+
+	return good, bad
+}
+
+func TestBisectGenesis(t *testing.T) {
+	good, bad := corruptedTestGenesis(t)
+
+	var probes []bisectProbe
+	modules, err := bisectGenesis(good, bad, SmokeTestGenesis, func(p bisectProbe) {
+		probes = append(probes, p)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{staking.ModuleName}, modules)
+
+	// probes none, all three modules, gov, mint and staking, mint, staking
+	require.Len(t, probes, 6)
+	var failing [][]string
+	for _, p := range probes {
+		if p.Err != nil {
+			failing = append(failing, p.Modules)
+			require.Contains(t, p.Err.Error(), "smoke test InitChain panicked")
+		}
+	}
+	require.Equal(t, []string(nil), probes[0].Modules)
+	require.NoError(t, probes[0].Err)
+	require.Equal(t, [][]string{
+		{gov.ModuleName, mint.ModuleName, staking.ModuleName},
+		{mint.ModuleName, staking.ModuleName},
+		{staking.ModuleName},
+	}, failing)
+}
+
+func TestBisectGenesisErrors(t *testing.T) {
+	good, bad := corruptedTestGenesis(t)
+	ignore := func(bisectProbe) {}
+
+	_, err := bisectGenesis(good, good, SmokeTestGenesis, ignore)
+	require.EqualError(t, err, "the app states of both genesis docs are the same")
+
+	_, err = bisectGenesis(bad, bad, SmokeTestGenesis, ignore)
+	require.EqualError(t, err, "the good genesis fails the smoke test with the genesis doc fields of the bad one")
+
+	_, err = bisectGenesis(good, bad, func(*tmtypes.GenesisDoc) error { return nil }, ignore)
+	require.EqualError(t, err, "the bad genesis passes the smoke test with the validators of its staking state")
+}
+
+func TestGenesisBisectCmd(t *testing.T) {
+	good, bad := corruptedTestGenesis(t)
+	dir := t.TempDir()
+	goodFile, badFile := filepath.Join(dir, "good.json"), filepath.Join(dir, "bad.json")
+	require.NoError(t, good.SaveAs(goodFile))
+	require.NoError(t, bad.SaveAs(badFile))
+
+	var out bytes.Buffer
+	cmd := GenesisBisectCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs([]string{goodFile, badFile})
+	require.NoError(t, cmd.Execute())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, "probe 1, no modules from the bad genesis: passes", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "probe 2, gov, mint, staking from the bad genesis: fails: smoke test InitChain panicked: "))
+	require.Equal(t, "the smoke test fails with the state of staking from "+badFile, lines[len(lines)-1])
+}
@@ -0,0 +1,591 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distr "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const flagGenTxDir = "gentx-dir"
+
+// GenTxResult describes a genesis transaction collected onto a genesis.
+type GenTxResult struct {
+	File            string   `json:"file"`
+	Moniker         string   `json:"moniker,omitempty"`
+	OperatorAddress string   `json:"operator_address,omitempty"`
+	Power           int64    `json:"power,omitempty"`
+	Peer            string   `json:"peer,omitempty"`
+	Bonded          bool     `json:"bonded"`
+	Problems        []string `json:"problems,omitempty"`
+}
+
+// GenTxCollection is the outcome of collecting genesis transactions onto a
+// genesis: every transaction, and the validators moved to unbonding to keep
+// the bonded set within max_validators.
+type GenTxCollection struct {
+	GenTxs  []GenTxResult `json:"gentxs"`
+	Demoted []string      `json:"demoted,omitempty"`
+}
+
+// Problems returns the number of genesis transactions that cannot be collected.
+func (c GenTxCollection) Problems() int {
+	n := 0
+	for _, genTx := range c.GenTxs {
+		if len(genTx.Problems) > 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// GenesisCollectGenTxsOntoCmd returns a command applying genesis transactions
+// on top of the state of an existing genesis, such as a migrated one.
+func GenesisCollectGenTxsOntoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collect-gentxs-onto [genesis-file]",
+		Short: "Apply genesis transactions on top of the validators of an existing genesis",
+		Long: fmt.Sprintf(`Apply the MsgCreateValidator genesis transactions of the gentx directory to the
+state of a genesis that already has validators, such as a migrated one, and
+rewrite it in place. collect-gentxs cannot be used there: the staking module
+and the genesis transactions would both set the validators at InitChain.
+
+Each transaction is applied as delivering it at InitChain would: its signature
+is verified with account number 0 and the sequence of the delegator account,
+the self delegation is debited from the delegator and credited to the bonded
+pool, and the validator is appended with its delegation, distribution and
+slashing records. The bonded set is then kept within max_validators and the
+tendermint validators are regenerated if the genesis lists them.
+
+A transaction from an unknown or underfunded delegator, with an invalid
+signature, or reusing the operator address, consensus key or moniker of a
+validator is reported, and the genesis is left untouched.
+
+Example:
+$ %s genesis collect-gentxs-onto genesis.json --gentx-dir ~/.gaia/config/gentx
+`, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			genTxsDir, _ := cmd.Flags().GetString(flagGenTxDir)
+			if genTxsDir == "" {
+				return fmt.Errorf("--%s is required", flagGenTxDir)
+			}
+			files, err := outputFilesFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			genDoc, err := tmtypes.GenesisDocFromFile(args[0])
+			if err != nil {
+				return errors.Wrapf(err, "failed to read genesis file %s", args[0])
+			}
+
+			encCfg := MakeEncodingConfig()
+			collection, err := CollectGenTxsOnto(encCfg.TxConfig, encCfg.Marshaler, genDoc, genTxsDir)
+			if err != nil {
+				return err
+			}
+
+			for _, genTx := range collection.GenTxs {
+				if len(genTx.Problems) > 0 {
+					cmd.Printf("%s: %s\n", genTx.File, strings.Join(genTx.Problems, "; "))
+					continue
+				}
+
+				status := "bonded"
+				if !genTx.Bonded {
+					status = "unbonding, outside max_validators"
+				}
+				cmd.Printf("%s: %s %s with power %d, %s, peer %s\n", genTx.File, genTx.Moniker, genTx.OperatorAddress, genTx.Power, status, genTx.Peer)
+			}
+
+			if n := collection.Problems(); n > 0 {
+				return fmt.Errorf("%d of %d genesis transactions cannot be collected", n, len(collection.GenTxs))
+			}
+
+			for _, operator := range collection.Demoted {
+				cmd.Printf("validator %s moved to unbonding, outside max_validators\n", operator)
+			}
+
+			bz, err := tmjson.Marshal(genDoc)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal genesis doc")
+			}
+			if bz, err = canonicalJSON(bz, true); err != nil {
+				return errors.Wrap(err, "failed to sort JSON genesis doc")
+			}
+
+			return files.Write(args[0], outputFileMode, func(w io.Writer) error {
+				return writeGenesisOutput(w, bz)
+			}, nil)
+		},
+	}
+
+	cmd.Flags().String(flagGenTxDir, "", "Directory of the genesis transactions to collect")
+	addOutputFileFlags(cmd)
+
+	return cmd
+}
+
+// CollectGenTxsOnto applies the genesis transactions of the JSON files in
+// genTxsDir, in file name order, on top of the app state of genDoc. Every
+// transaction is checked against the state and the transactions before it;
+// only when none has problems are the app state and, if listed, the
+// validators of genDoc updated.
+func CollectGenTxsOnto(txConfig client.TxConfig, cdc codec.JSONMarshaler, genDoc *tmtypes.GenesisDoc, genTxsDir string) (GenTxCollection, error) {
+	var collection GenTxCollection
+
+	var state types.AppMap
+	if err := json.Unmarshal(genDoc.AppState, &state); err != nil {
+		return collection, errors.Wrap(err, "failed to JSON unmarshal app state")
+	}
+
+	files, err := ioutil.ReadDir(genTxsDir)
+	if err != nil {
+		return collection, errors.Wrap(err, "failed to read gentx directory")
+	}
+
+	c, err := newGenTxCollector(txConfig, cdc, genDoc, state)
+	if err != nil {
+		return collection, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		bz, err := ioutil.ReadFile(filepath.Join(genTxsDir, file.Name()))
+		if err != nil {
+			return collection, errors.Wrap(err, "failed to read gentx file")
+		}
+
+		collection.GenTxs = append(collection.GenTxs, c.collect(file.Name(), bz))
+	}
+
+	if len(collection.GenTxs) == 0 {
+		return collection, fmt.Errorf("no genesis transactions in %s", genTxsDir)
+	}
+	if collection.Problems() > 0 {
+		return collection, nil
+	}
+
+	if err := c.write(state); err != nil {
+		return collection, err
+	}
+
+	demotions, err := capBondedValidators(cdc, state, c.height, genDoc.GenesisTime)
+	if err != nil {
+		return collection, err
+	}
+
+	demoted := make(map[string]bool)
+	for _, d := range demotions {
+		demoted[d.OperatorAddress] = true
+	}
+	for i, genTx := range collection.GenTxs {
+		collection.GenTxs[i].Bonded = !demoted[genTx.OperatorAddress]
+		delete(demoted, genTx.OperatorAddress)
+	}
+	for _, d := range demotions {
+		if demoted[d.OperatorAddress] {
+			collection.Demoted = append(collection.Demoted, d.OperatorAddress)
+		}
+	}
+
+	if len(genDoc.Validators) > 0 {
+		var stakingGenesis staking.GenesisState
+		if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+			return collection, errors.Wrap(err, "failed to unmarshal staking genesis")
+		}
+		if genDoc.Validators, err = tmValidatorsFromStaking(stakingGenesis); err != nil {
+			return collection, err
+		}
+	}
+
+	if genDoc.AppState, err = json.Marshal(state); err != nil {
+		return collection, errors.Wrap(err, "failed to JSON marshal app state")
+	}
+
+	return collection, nil
+}
+
+// genTxCollector holds the decoded genesis state genesis transactions are
+// applied to.
+type genTxCollector struct {
+	txConfig client.TxConfig
+	cdc      codec.JSONMarshaler
+	genDoc   *tmtypes.GenesisDoc
+
+	// height is the block height of the InitChain context, at which the
+	// validators start.
+	height int64
+
+	accounts auth.GenesisAccounts
+	auth     auth.GenesisState
+	bank     bank.GenesisState
+	staking  staking.GenesisState
+	distr    *distr.GenesisState
+	slashing *slashing.GenesisState
+}
+
+func newGenTxCollector(txConfig client.TxConfig, cdc codec.JSONMarshaler, genDoc *tmtypes.GenesisDoc, state types.AppMap) (*genTxCollector, error) {
+	c := &genTxCollector{txConfig: txConfig, cdc: cdc, genDoc: genDoc}
+	if genDoc.InitialHeight > 1 {
+		c.height = genDoc.InitialHeight
+	}
+
+	if err := cdc.UnmarshalJSON(state[auth.ModuleName], &c.auth); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal auth genesis")
+	}
+	accounts, err := auth.UnpackAccounts(c.auth.Accounts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unpack genesis accounts")
+	}
+	c.accounts = accounts
+
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &c.bank); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal bank genesis")
+	}
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &c.staking); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal staking genesis")
+	}
+
+	if bz, ok := state[distr.ModuleName]; ok {
+		c.distr = &distr.GenesisState{}
+		if err := cdc.UnmarshalJSON(bz, c.distr); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal distribution genesis")
+		}
+	}
+	if bz, ok := state[slashing.ModuleName]; ok {
+		c.slashing = &slashing.GenesisState{}
+		if err := cdc.UnmarshalJSON(bz, c.slashing); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal slashing genesis")
+		}
+	}
+
+	return c, nil
+}
+
+// collect checks the genesis transaction bz of file and, if it has no
+// problems, applies it.
+func (c *genTxCollector) collect(file string, bz []byte) GenTxResult {
+	result := GenTxResult{File: file}
+	problem := func(format string, args ...interface{}) {
+		result.Problems = append(result.Problems, fmt.Sprintf(format, args...))
+	}
+
+	tx, err := c.txConfig.TxJSONDecoder()(bz)
+	if err != nil {
+		problem("cannot be decoded: %v", err)
+		return result
+	}
+
+	msgs := tx.GetMsgs()
+	if len(msgs) != 1 {
+		problem("has %d messages, a genesis transaction has a single MsgCreateValidator", len(msgs))
+		return result
+	}
+	msg, ok := msgs[0].(*staking.MsgCreateValidator)
+	if !ok {
+		problem("has a %T, a genesis transaction has a single MsgCreateValidator", msgs[0])
+		return result
+	}
+	if err := msg.ValidateBasic(); err != nil {
+		problem("invalid MsgCreateValidator: %v", err)
+		return result
+	}
+
+	delAddr, err := sdk.AccAddressFromBech32(msg.DelegatorAddress)
+	if err != nil {
+		problem("invalid delegator address: %v", err)
+		return result
+	}
+	valAddr, err := sdk.ValAddressFromBech32(msg.ValidatorAddress)
+	if err != nil {
+		problem("invalid validator address: %v", err)
+		return result
+	}
+
+	result.Moniker = msg.Description.Moniker
+	result.OperatorAddress = msg.ValidatorAddress
+	if memoTx, ok := tx.(sdk.TxWithMemo); ok {
+		result.Peer = memoTx.GetMemo()
+	}
+	if feeTx, ok := tx.(sdk.FeeTx); ok && !feeTx.GetFee().IsZero() {
+		problem("pays a fee of %s, genesis transactions are free", feeTx.GetFee())
+	}
+
+	pubKey, ok := msg.Pubkey.GetCachedValue().(cryptotypes.PubKey)
+	if !ok {
+		problem("has no consensus key")
+		return result
+	}
+	consAddr := sdk.ConsAddress(pubKey.Address()).String()
+
+	for _, val := range c.staking.Validators {
+		valConsAddr, err := val.GetConsAddr()
+		if err != nil {
+			problem("cannot be checked against validator %s: %v", val.OperatorAddress, err)
+			continue
+		}
+
+		if val.OperatorAddress == msg.ValidatorAddress {
+			problem("operator address %s is already a validator", msg.ValidatorAddress)
+		}
+		if valConsAddr.String() == consAddr {
+			problem("consensus key %s is already used by validator %s (%s)", consAddr, val.GetMoniker(), val.OperatorAddress)
+		}
+		if val.GetMoniker() == msg.Description.Moniker {
+			problem("moniker %q is already used by validator %s", msg.Description.Moniker, val.OperatorAddress)
+		}
+	}
+
+	if params := c.genDoc.ConsensusParams; params != nil && !tmtypes.IsValidPubkeyType(params.Validator, pubKey.Type()) {
+		problem("consensus key type %s is not allowed by the consensus params", pubKey.Type())
+	}
+
+	bondDenom := c.staking.Params.BondDenom
+	if msg.Value.Denom != bondDenom {
+		problem("delegates %s, not the bond denom %s", msg.Value, bondDenom)
+	}
+
+	val, err := staking.NewValidator(valAddr, pubKey, msg.Description)
+	if err != nil {
+		problem("invalid validator: %v", err)
+		return result
+	}
+	val, err = val.SetInitialCommission(staking.NewCommissionWithTime(
+		msg.Commission.Rate, msg.Commission.MaxRate, msg.Commission.MaxChangeRate, c.genDoc.GenesisTime))
+	if err != nil {
+		problem("invalid commission: %v", err)
+	}
+	val.MinSelfDelegation = msg.MinSelfDelegation
+
+	val, shares := val.AddTokensFromDel(msg.Value.Amount)
+	val = val.UpdateStatus(staking.Bonded)
+	result.Power = val.ConsensusPower()
+	if result.Power == 0 {
+		problem("self delegation of %s is below one consensus power", msg.Value)
+	}
+
+	accIndex := -1
+	for i, acc := range c.accounts {
+		if acc.GetAddress().Equals(delAddr) {
+			accIndex = i
+		}
+	}
+	if accIndex < 0 {
+		problem("delegator %s has no account", msg.DelegatorAddress)
+		return result
+	}
+	acc := c.accounts[accIndex]
+
+	if err := c.verifySignatures(tx, acc); err != nil {
+		problem("%v", err)
+	}
+
+	balIndex := -1
+	for i, balance := range c.bank.Balances {
+		if balance.Address == msg.DelegatorAddress {
+			balIndex = i
+		}
+	}
+	var balance sdk.Coins
+	if balIndex >= 0 {
+		balance = c.bank.Balances[balIndex].Coins
+	}
+	remaining, hasNeg := balance.SafeSub(sdk.NewCoins(msg.Value))
+	if hasNeg {
+		problem("delegator %s holds %s, less than the self delegation of %s", msg.DelegatorAddress, balance.AmountOf(msg.Value.Denom), msg.Value)
+	}
+
+	if len(result.Problems) > 0 {
+		return result
+	}
+
+	// the ante handler sets the public key and increments the sequence
+	if acc.GetPubKey() == nil {
+		if err := acc.SetPubKey(c.signerPubKey(tx)); err != nil {
+			problem("cannot set the public key of %s: %v", msg.DelegatorAddress, err)
+			return result
+		}
+	}
+	if err := acc.SetSequence(acc.GetSequence() + 1); err != nil {
+		problem("cannot increment the sequence of %s: %v", msg.DelegatorAddress, err)
+		return result
+	}
+	if vacc, ok := acc.(vestingexported.VestingAccount); ok {
+		vacc.TrackDelegation(c.genDoc.GenesisTime, balance, sdk.NewCoins(msg.Value))
+	}
+
+	c.bank.Balances[balIndex].Coins = remaining
+	c.credit(auth.NewModuleAddress(staking.BondedPoolName).String(), sdk.NewCoins(msg.Value))
+
+	c.staking.Validators = append(c.staking.Validators, val)
+	c.staking.Delegations = append(c.staking.Delegations, staking.NewDelegation(delAddr, valAddr, shares))
+	c.staking.LastValidatorPowers = append(c.staking.LastValidatorPowers, staking.LastValidatorPower{Address: val.OperatorAddress, Power: result.Power})
+	c.staking.LastTotalPower = c.staking.LastTotalPower.AddRaw(result.Power)
+
+	c.addDistributionRecords(msg.DelegatorAddress, val.OperatorAddress, val.TokensFromSharesTruncated(shares))
+	c.addSigningInfo(sdk.ConsAddress(pubKey.Address()))
+
+	return result
+}
+
+// verifySignatures verifies the signatures of the genesis transaction as the
+// ante handler does at InitChain, with account number 0.
+func (c *genTxCollector) verifySignatures(tx sdk.Tx, acc auth.GenesisAccount) error {
+	sigTx, ok := tx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return fmt.Errorf("is not a signed transaction")
+	}
+
+	sigs, err := sigTx.GetSignaturesV2()
+	if err != nil {
+		return fmt.Errorf("has invalid signatures: %v", err)
+	}
+	signers := sigTx.GetSigners()
+	if len(sigs) != len(signers) {
+		return fmt.Errorf("has %d signatures for %d signers", len(sigs), len(signers))
+	}
+
+	for i, sig := range sigs {
+		if !signers[i].Equals(acc.GetAddress()) {
+			return fmt.Errorf("is signed by %s, not the delegator", signers[i])
+		}
+
+		pubKey := sig.PubKey
+		if pubKey == nil {
+			pubKey = acc.GetPubKey()
+		}
+		if pubKey == nil {
+			return fmt.Errorf("has no public key for the signer %s", signers[i])
+		}
+		if !bytes.Equal(pubKey.Address(), signers[i]) {
+			return fmt.Errorf("is signed with a public key that is not of %s", signers[i])
+		}
+
+		signerData := authsigning.SignerData{ChainID: c.genDoc.ChainID, AccountNumber: 0, Sequence: acc.GetSequence()}
+		if err := authsigning.VerifySignature(pubKey, signerData, sig.Data, c.txConfig.SignModeHandler(), tx); err != nil {
+			return fmt.Errorf("has an invalid signature, please verify chain-id (%s), account number (0) and sequence (%d)", c.genDoc.ChainID, acc.GetSequence())
+		}
+	}
+
+	return nil
+}
+
+// signerPubKey returns the public key of the first signature of tx.
+func (c *genTxCollector) signerPubKey(tx sdk.Tx) cryptotypes.PubKey {
+	sigs, err := tx.(authsigning.SigVerifiableTx).GetSignaturesV2()
+	if err != nil || len(sigs) == 0 {
+		return nil
+	}
+
+	return sigs[0].PubKey
+}
+
+func (c *genTxCollector) credit(addr string, coins sdk.Coins) {
+	for i, balance := range c.bank.Balances {
+		if balance.Address == addr {
+			c.bank.Balances[i].Coins = balance.Coins.Add(coins...)
+			return
+		}
+	}
+
+	c.bank.Balances = append(c.bank.Balances, bank.Balance{Address: addr, Coins: coins})
+}
+
+// addDistributionRecords adds the records the distribution hooks create for a
+// new validator and its self delegation: period 1 of the historical rewards
+// referenced by the validator and the delegation, and period 2 current.
+func (c *genTxCollector) addDistributionRecords(delegator, operator string, stake sdk.Dec) {
+	if c.distr == nil {
+		return
+	}
+
+	c.distr.ValidatorHistoricalRewards = append(c.distr.ValidatorHistoricalRewards, distr.ValidatorHistoricalRewardsRecord{
+		ValidatorAddress: operator,
+		Period:           1,
+		Rewards:          distr.NewValidatorHistoricalRewards(sdk.DecCoins{}, 2),
+	})
+	c.distr.ValidatorCurrentRewards = append(c.distr.ValidatorCurrentRewards, distr.ValidatorCurrentRewardsRecord{
+		ValidatorAddress: operator,
+		Rewards:          distr.NewValidatorCurrentRewards(sdk.DecCoins{}, 2),
+	})
+	c.distr.ValidatorAccumulatedCommissions = append(c.distr.ValidatorAccumulatedCommissions, distr.ValidatorAccumulatedCommissionRecord{
+		ValidatorAddress: operator,
+		Accumulated:      distr.InitialValidatorAccumulatedCommission(),
+	})
+	c.distr.OutstandingRewards = append(c.distr.OutstandingRewards, distr.ValidatorOutstandingRewardsRecord{
+		ValidatorAddress:   operator,
+		OutstandingRewards: sdk.DecCoins{},
+	})
+	c.distr.DelegatorStartingInfos = append(c.distr.DelegatorStartingInfos, distr.DelegatorStartingInfoRecord{
+		DelegatorAddress: delegator,
+		ValidatorAddress: operator,
+		StartingInfo:     distr.NewDelegatorStartingInfo(1, stake, uint64(c.height)),
+	})
+}
+
+// addSigningInfo adds the signing info the slashing hooks create for a newly
+// bonded validator.
+func (c *genTxCollector) addSigningInfo(consAddr sdk.ConsAddress) {
+	if c.slashing == nil {
+		return
+	}
+
+	c.slashing.SigningInfos = append(c.slashing.SigningInfos, slashing.SigningInfo{
+		Address:              consAddr.String(),
+		ValidatorSigningInfo: slashing.NewValidatorSigningInfo(consAddr, c.height, 0, time.Unix(0, 0).UTC(), false, 0),
+	})
+	c.slashing.MissedBlocks = append(c.slashing.MissedBlocks, slashing.ValidatorMissedBlocks{
+		Address:      consAddr.String(),
+		MissedBlocks: []slashing.MissedBlock{},
+	})
+}
+
+// write marshals the decoded genesis back into state.
+func (c *genTxCollector) write(state types.AppMap) error {
+	packed, err := auth.PackAccounts(c.accounts)
+	if err != nil {
+		return errors.Wrap(err, "failed to pack genesis accounts")
+	}
+	c.auth.Accounts = packed
+	c.bank.Balances = bank.SanitizeGenesisBalances(c.bank.Balances)
+
+	state[auth.ModuleName] = c.cdc.MustMarshalJSON(&c.auth)
+	state[bank.ModuleName] = c.cdc.MustMarshalJSON(&c.bank)
+	state[staking.ModuleName] = c.cdc.MustMarshalJSON(&c.staking)
+	if c.distr != nil {
+		state[distr.ModuleName] = c.cdc.MustMarshalJSON(c.distr)
+	}
+	if c.slashing != nil {
+		state[slashing.ModuleName] = c.cdc.MustMarshalJSON(c.slashing)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distr "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// writeGenTx writes to dir a genesis transaction of the builder account name
+// creating a validator with moniker, consensus key and self delegation,
+// signed for chainID.
+func writeGenTx(t *testing.T, dir, name, moniker string, consKey cryptotypes.PubKey, tokens int64, chainID string) {
+	encCfg := MakeEncodingConfig()
+	priv := secp256k1.GenPrivKeyFromSecret([]byte(name))
+
+	msg, err := staking.NewMsgCreateValidator(sdk.ValAddress(priv.PubKey().Address()), consKey,
+		sdk.NewInt64Coin(genesisbuilder.BondDenom, tokens), staking.Description{Moniker: moniker},
+		staking.NewCommissionRates(sdk.NewDecWithPrec(1, 1), sdk.NewDecWithPrec(2, 1), sdk.NewDecWithPrec(1, 2)), sdk.OneInt())
+	require.NoError(t, err)
+
+	txBuilder := encCfg.TxConfig.NewTxBuilder()
+	require.NoError(t, txBuilder.SetMsgs(msg))
+	txBuilder.SetMemo(moniker + "@192.168.0.1:26656")
+	txBuilder.SetGasLimit(200000)
+
+	signMode := encCfg.TxConfig.SignModeHandler().DefaultMode()
+	require.NoError(t, txBuilder.SetSignatures(signing.SignatureV2{
+		PubKey: priv.PubKey(),
+		Data:   &signing.SingleSignatureData{SignMode: signMode},
+	}))
+	sig, err := tx.SignWithPrivKey(signMode, authsigning.SignerData{ChainID: chainID}, txBuilder, priv, encCfg.TxConfig, 0)
+	require.NoError(t, err)
+	require.NoError(t, txBuilder.SetSignatures(sig))
+
+	bz, err := encCfg.TxConfig.TxJSONEncoder()(txBuilder.GetTx())
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "gentx-"+name+".json"), bz, 0600))
+}
+
+func TestGenesisCollectGenTxsOnto(t *testing.T) {
+	b := testGenesisBuilder().
+		WithAccount("erin", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 3500000))).
+		WithAccount("frank", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 2000000)))
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+	genDoc, _ := exportTestGenesis(t, builtDoc)
+
+	dir := t.TempDir()
+	gentxDir := filepath.Join(dir, "gentx")
+	require.NoError(t, os.Mkdir(gentxDir, 0700))
+	writeGenTx(t, gentxDir, "erin", "erin", genesisbuilder.ValidatorConsKey(10).PubKey(), 3000000, genDoc.ChainID)
+	writeGenTx(t, gentxDir, "frank", "frank", genesisbuilder.ValidatorConsKey(11).PubKey(), 2000000, genDoc.ChainID)
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	cmd := GenesisCollectGenTxsOntoCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{path, "--" + flagGenTxDir, gentxDir})
+	require.NoError(t, cmd.Execute())
+	erinVal, frankVal := sdk.ValAddress(b.Address("erin")).String(), sdk.ValAddress(b.Address("frank")).String()
+	require.Equal(t, "gentx-erin.json: erin "+erinVal+" with power 3, bonded, peer erin@192.168.0.1:26656\n"+
+		"gentx-frank.json: frank "+frankVal+" with power 2, bonded, peer frank@192.168.0.1:26656\n", out.String())
+
+	collected, err := tmtypes.GenesisDocFromFile(path)
+	require.NoError(t, err)
+	require.NoError(t, SmokeTestGenesis(collected))
+
+	// the existing validators are kept and the new ones appended
+	require.Len(t, collected.Validators, 5)
+	require.Equal(t, genDoc.Validators, collected.Validators[:3])
+	require.Equal(t, "erin", collected.Validators[3].Name)
+	require.Equal(t, int64(2), collected.Validators[4].Power)
+
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(collected.AppState, &state))
+	encCfg := MakeEncodingConfig()
+	cdc := encCfg.Marshaler
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, encCfg.TxConfig, state))
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	balances := make(map[string]sdk.Coins)
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+	}
+	bondedPool := auth.NewModuleAddress(staking.BondedPoolName).String()
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 500000)), balances[b.Address("erin").String()])
+	require.True(t, balances[b.Address("frank").String()].IsZero())
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	bonded := sdk.ZeroInt()
+	for _, val := range stakingGenesis.Validators {
+		bonded = bonded.Add(val.Tokens)
+	}
+	require.Equal(t, bonded, balances[bondedPool].AmountOf(genesisbuilder.BondDenom))
+	require.Len(t, stakingGenesis.LastValidatorPowers, 5)
+
+	var distrGenesis distr.GenesisState
+	cdc.MustUnmarshalJSON(state[distr.ModuleName], &distrGenesis)
+	require.Len(t, distrGenesis.ValidatorCurrentRewards, 5)
+}
+
+func TestGenesisCollectGenTxsOntoConflicts(t *testing.T) {
+	b := testGenesisBuilder().
+		WithAccount("erin", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 3000000))).
+		WithAccount("frank", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 3000000))).
+		WithAccount("grace", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000)))
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+	genDoc, _ := exportTestGenesis(t, builtDoc)
+	appState := genDoc.AppState
+
+	gentxDir := t.TempDir()
+	writeGenTx(t, gentxDir, "erin", genesisbuilder.ValidatorName(0), genesisbuilder.ValidatorConsKey(1).PubKey(), 2000000, genDoc.ChainID)
+	writeGenTx(t, gentxDir, "frank", "frank", genesisbuilder.ValidatorConsKey(10).PubKey(), 2000000, "another-chain")
+	writeGenTx(t, gentxDir, "grace", "grace", genesisbuilder.ValidatorConsKey(11).PubKey(), 2000000, genDoc.ChainID)
+	writeGenTx(t, gentxDir, "heidi", "heidi", genesisbuilder.ValidatorConsKey(12).PubKey(), 2000000, genDoc.ChainID)
+
+	encCfg := MakeEncodingConfig()
+	collection, err := CollectGenTxsOnto(encCfg.TxConfig, encCfg.Marshaler, genDoc, gentxDir)
+	require.NoError(t, err)
+	require.Equal(t, 4, collection.Problems())
+
+	problems := make(map[string][]string)
+	for _, genTx := range collection.GenTxs {
+		problems[genTx.File] = genTx.Problems
+	}
+	require.Equal(t, []string{
+		"consensus key " + b.ValidatorConsAddress(1).String() + " is already used by validator validator1 (" + b.ValidatorAddress(1).String() + ")",
+		`moniker "validator0" is already used by validator ` + b.ValidatorAddress(0).String(),
+	}, problems["gentx-erin.json"])
+	require.Equal(t, []string{
+		"has an invalid signature, please verify chain-id (" + genDoc.ChainID + "), account number (0) and sequence (0)",
+	}, problems["gentx-frank.json"])
+	require.Equal(t, []string{
+		"delegator " + b.Address("grace").String() + " holds 1000, less than the self delegation of 2000000" + genesisbuilder.BondDenom,
+	}, problems["gentx-grace.json"])
+	require.Equal(t, []string{
+		"delegator " + sdk.AccAddress(secp256k1.GenPrivKeyFromSecret([]byte("heidi")).PubKey().Address()).String() + " has no account",
+	}, problems["gentx-heidi.json"])
+
+	// nothing is applied
+	require.Equal(t, appState, genDoc.AppState)
+}
@@ -0,0 +1,374 @@
+package gaia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// downloadBackoff is the wait before the first retry of a failed genesis
+// download, doubled for every further retry up to downloadMaxBackoff.
+var (
+	downloadBackoff    = time.Second
+	downloadMaxBackoff = time.Minute
+)
+
+// isGenesisURL reports whether the genesis file argument is an http(s) URL.
+func isGenesisURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// genesisDownload fetches a genesis from a URL into a cache directory,
+// resuming a partial download of the same URL and ETag with a range request
+// after a dropped connection or in a later run.
+type genesisDownload struct {
+	url string
+	dir string
+	// timeout aborts an attempt receiving no data for that long, unless 0
+	timeout time.Duration
+	retries int
+	client  *http.Client
+	// progress receives the bandwidth lines, at most one per progressInterval
+	progress io.Writer
+	now      func() time.Time
+}
+
+// downloadMeta is the cache file recording the ETag and size of the download
+// of a URL.
+type downloadMeta struct {
+	URL      string `json:"url"`
+	ETag     string `json:"etag"`
+	Size     int64  `json:"size"`
+	Complete bool   `json:"complete"`
+}
+
+// downloadFailure is a response a retry cannot fix.
+type downloadFailure struct {
+	status string
+}
+
+func (f downloadFailure) Error() string {
+	return f.status
+}
+
+func newGenesisDownload(url, dir string, timeout time.Duration, retries int, progress io.Writer) *genesisDownload {
+	return &genesisDownload{
+		url:      url,
+		dir:      dir,
+		timeout:  timeout,
+		retries:  retries,
+		client:   &http.Client{},
+		progress: progress,
+		now:      time.Now,
+	}
+}
+
+// defaultDownloadDir is the cache directory of genesis downloads without
+// --download-cache-dir, kept across runs so they can resume.
+func defaultDownloadDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "gaiad", "genesis-downloads")
+}
+
+func (d *genesisDownload) metaPath() string {
+	return filepath.Join(d.dir, downloadKey(d.url)+".json")
+}
+
+// filePath returns the cache file of the download of the URL with etag.
+func (d *genesisDownload) filePath(etag string) string {
+	return filepath.Join(d.dir, downloadKey(d.url+"\n"+etag)+".genesis")
+}
+
+func downloadKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Fetch downloads the genesis, retrying failed attempts with exponential
+// backoff, and returns the path of the complete file in the cache.
+func (d *genesisDownload) Fetch(ctx context.Context) (string, error) {
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create download cache directory")
+	}
+
+	// a download without ETag is only resumed within the same run, the file
+	// behind the URL may have changed since
+	if meta, err := d.readMeta(); err == nil && meta.ETag == "" {
+		d.Discard()
+	}
+
+	backoff := downloadBackoff
+	for attempt := 0; ; attempt++ {
+		path, err := d.attempt(ctx)
+		if err == nil {
+			return path, nil
+		}
+
+		var failure downloadFailure
+		if errors.As(err, &failure) || ctx.Err() != nil || attempt >= d.retries {
+			return "", errors.Wrapf(err, "failed to download %s", d.url)
+		}
+
+		fmt.Fprintf(d.progress, "download of %s failed, retrying in %s: %s\n", d.url, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", errors.Wrapf(ctx.Err(), "failed to download %s", d.url)
+		}
+
+		if backoff *= 2; backoff > downloadMaxBackoff {
+			backoff = downloadMaxBackoff
+		}
+	}
+}
+
+// Discard removes the cached download, e.g. once it failed verification.
+func (d *genesisDownload) Discard() {
+	if meta, err := d.readMeta(); err == nil {
+		os.Remove(d.filePath(meta.ETag))
+	}
+	os.Remove(d.metaPath())
+}
+
+func (d *genesisDownload) readMeta() (downloadMeta, error) {
+	var meta downloadMeta
+	bz, err := ioutil.ReadFile(d.metaPath())
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(bz, &meta); err != nil || meta.URL != d.url {
+		return downloadMeta{}, fmt.Errorf("invalid download cache entry %s", d.metaPath())
+	}
+
+	return meta, nil
+}
+
+func (d *genesisDownload) writeMeta(meta downloadMeta) error {
+	bz, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(d.metaPath(), bz, 0644)
+}
+
+// attempt requests the part of the genesis missing from the cache, the whole
+// file unless a partial download of the current ETag exists.
+func (d *genesisDownload) attempt(ctx context.Context) (string, error) {
+	meta, err := d.readMeta()
+	if err != nil {
+		meta = downloadMeta{URL: d.url, Size: -1}
+	}
+
+	var have int64
+	if info, err := os.Stat(d.filePath(meta.ETag)); err == nil {
+		have = info.Size()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return "", downloadFailure{err.Error()}
+	}
+	switch {
+	case meta.Complete && meta.ETag != "" && have == meta.Size:
+		req.Header.Set("If-None-Match", meta.ETag)
+	case have > 0 && !meta.Complete:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+		if meta.ETag != "" {
+			req.Header.Set("If-Range", meta.ETag)
+		}
+	}
+
+	// the request is canceled once no data arrived for the timeout
+	var stall *time.Timer
+	if d.timeout > 0 {
+		stall = time.AfterFunc(d.timeout, cancel)
+		defer stall.Stop()
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	offset := int64(0)
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return d.filePath(meta.ETag), nil
+	case http.StatusPartialContent:
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil || start != have {
+			return "", fmt.Errorf("unexpected Content-Range %q resuming at byte %d", resp.Header.Get("Content-Range"), have)
+		}
+		offset = have
+	case http.StatusOK:
+		meta.Size = -1
+	default:
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+			return "", fmt.Errorf("fetching %s: %s", d.url, resp.Status)
+		}
+		return "", downloadFailure{fmt.Sprintf("fetching %s: %s", d.url, resp.Status)}
+	}
+
+	if offset == 0 {
+		// a new download, possibly of a changed file, starts over
+		os.Remove(d.filePath(meta.ETag))
+		meta.ETag = resp.Header.Get("ETag")
+		meta.Complete = false
+		if resp.ContentLength >= 0 {
+			meta.Size = resp.ContentLength
+		}
+	}
+	if err := d.writeMeta(meta); err != nil {
+		return "", err
+	}
+
+	path := d.filePath(meta.ETag)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	meter := &bandwidthMeter{w: d.progress, url: d.url, offset: offset, total: meta.Size, now: d.now}
+	meter.start = d.now()
+	body := stallReader{Reader: resp.Body, stall: stall, timeout: d.timeout}
+	n, err := io.Copy(f, io.TeeReader(body, meter))
+	if err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if meta.Size >= 0 && offset+n != meta.Size {
+		return "", fmt.Errorf("received %d of %d bytes: %w", offset+n, meta.Size, io.ErrUnexpectedEOF)
+	}
+	meta.Size = offset + n
+	meta.Complete = true
+	if err := d.writeMeta(meta); err != nil {
+		return "", err
+	}
+
+	meter.Done()
+	return path, nil
+}
+
+// contentRangeStart returns the first byte of a "bytes start-end/size"
+// Content-Range header.
+func contentRangeStart(header string) (int64, error) {
+	spec := strings.TrimPrefix(header, "bytes ")
+	i := strings.IndexByte(spec, '-')
+	if spec == header || i < 0 {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+
+	return strconv.ParseInt(spec[:i], 10, 64)
+}
+
+// stallReader resets the stall timer of a download on every read.
+type stallReader struct {
+	io.Reader
+	stall   *time.Timer
+	timeout time.Duration
+}
+
+func (r stallReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 && r.stall != nil {
+		r.stall.Reset(r.timeout)
+	}
+
+	return n, err
+}
+
+// bandwidthMeter prints the progress and bandwidth of a download attempt.
+type bandwidthMeter struct {
+	w      io.Writer
+	url    string
+	offset int64
+	total  int64
+	now    func() time.Time
+
+	start     time.Time
+	received  int64
+	lastPrint time.Time
+}
+
+func (m *bandwidthMeter) Write(p []byte) (int, error) {
+	m.received += int64(len(p))
+	if now := m.now(); now.Sub(m.lastPrint) >= progressInterval {
+		m.lastPrint = now
+		fmt.Fprintf(m.w, "download: %s at %s\n", m.position(), m.rate())
+	}
+
+	return len(p), nil
+}
+
+// Done prints the summary of the completed download.
+func (m *bandwidthMeter) Done() {
+	fmt.Fprintf(m.w, "downloaded %s: %d bytes, %d resumed, at %s\n", m.url, m.offset+m.received, m.offset, m.rate())
+}
+
+func (m *bandwidthMeter) position() string {
+	done := m.offset + m.received
+	if m.total <= 0 {
+		return fmt.Sprintf("%d bytes", done)
+	}
+
+	return fmt.Sprintf("%d/%d bytes (%d%%)", done, m.total, done*100/m.total)
+}
+
+func (m *bandwidthMeter) rate() string {
+	elapsed := m.now().Sub(m.start).Seconds()
+	if elapsed <= 0 {
+		return "- MiB/s"
+	}
+
+	return fmt.Sprintf("%.1f MiB/s", float64(m.received)/elapsed/(1<<20))
+}
+
+// verifySourceSHA256 hashes the genesis file at path before it is parsed and
+// fails unless it matches the expected hex encoded SHA-256.
+func verifySourceSHA256(path, expected string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	digest := newDigestWriter()
+	if _, err := io.Copy(digest, f); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(digest.Sum(), expected) {
+		return fmt.Errorf("source genesis has sha256 %s, expected %s", digest.Sum(), expected)
+	}
+
+	return nil
+}
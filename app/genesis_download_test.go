@@ -0,0 +1,168 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// droppingWriter aborts the response once limit bytes of the body are sent.
+type droppingWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *droppingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		w.ResponseWriter.Write(p[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// genesisServer serves content with an ETag and range requests, dropping the
+// connection of the first response after drop bytes if drop is positive.
+type genesisServer struct {
+	content []byte
+	drop    int
+
+	mtx      sync.Mutex
+	requests []http.Header
+}
+
+func (s *genesisServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	s.requests = append(s.requests, r.Header.Clone())
+	first := len(s.requests) == 1
+	s.mtx.Unlock()
+
+	if first && s.drop > 0 {
+		w = &droppingWriter{ResponseWriter: w, limit: s.drop}
+	}
+
+	w.Header().Set("ETag", `"genesis-v1"`)
+	http.ServeContent(w, r, "genesis.json", time.Time{}, bytes.NewReader(s.content))
+}
+
+func withDownloadBackoff(t *testing.T, backoff time.Duration) {
+	saved := downloadBackoff
+	downloadBackoff = backoff
+	t.Cleanup(func() { downloadBackoff = saved })
+}
+
+func TestMigrateGenesisURLResumes(t *testing.T) {
+	withDownloadBackoff(t, time.Millisecond)
+
+	content, err := ioutil.ReadFile("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+	sum := sha256.Sum256(content)
+	sourceSHA256 := hex.EncodeToString(sum[:])
+
+	server := &genesisServer{content: content, drop: len(content) / 2}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cacheDir := t.TempDir()
+	migrateArgs := []string{"--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+	urlArgs := append([]string{ts.URL + "/genesis.json", "--source-sha256", sourceSHA256, "--download-cache-dir", cacheDir}, migrateArgs...)
+
+	expected, err := executeMigrate(t, append([]string{"testdata/cosmoshub-2-genesis.json"}, migrateArgs...)...)
+	require.NoError(t, err)
+
+	var stderr bytes.Buffer
+	out, err := executeMigrateTo(t, &stderr, urlArgs...)
+	require.NoError(t, err, stderr.String())
+	require.Equal(t, expected, out)
+
+	require.Len(t, server.requests, 2)
+	require.Empty(t, server.requests[0].Get("Range"))
+	require.True(t, strings.HasPrefix(server.requests[1].Get("Range"), "bytes="), server.requests[1].Get("Range"))
+	require.NotEqual(t, "bytes=0-", server.requests[1].Get("Range"))
+	require.Equal(t, `"genesis-v1"`, server.requests[1].Get("If-Range"))
+	require.Contains(t, stderr.String(), "retrying in 1ms")
+	require.Regexp(t, `downloaded .*/genesis.json: \d+ bytes, [1-9]\d* resumed`, stderr.String())
+
+	// a later run reuses the complete download while the ETag is unchanged
+	out, err = executeMigrate(t, urlArgs...)
+	require.NoError(t, err)
+	require.Equal(t, expected, out)
+	require.Len(t, server.requests, 3)
+	require.Equal(t, `"genesis-v1"`, server.requests[2].Get("If-None-Match"))
+}
+
+func TestMigrateGenesisURLVerifiesSHA256(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(&genesisServer{content: content})
+	defer ts.Close()
+
+	cacheDir := t.TempDir()
+	_, err = executeMigrate(t, ts.URL+"/genesis.json", "--source-sha256", strings.Repeat("0", 64), "--download-cache-dir", cacheDir,
+		"--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to verify "+ts.URL+"/genesis.json: source genesis has sha256")
+
+	// the corrupted download is not kept for the next run
+	files, err := ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	_, err = executeMigrate(t, "-", "--source-sha256", strings.Repeat("0", 64))
+	require.EqualError(t, err, "--source-sha256 cannot verify a genesis read from STDIN")
+}
+
+func TestGenesisDownloadFailures(t *testing.T) {
+	withDownloadBackoff(t, time.Millisecond)
+
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if strings.HasSuffix(r.URL.Path, "/missing.json") {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	// a missing file is not retried
+	_, err := newGenesisDownload(ts.URL+"/missing.json", t.TempDir(), time.Second, 3, ioutil.Discard).Fetch(context.Background())
+	require.EqualError(t, err, "failed to download "+ts.URL+"/missing.json: fetching "+ts.URL+"/missing.json: 404 Not Found")
+	require.Equal(t, 1, requests)
+
+	// server errors are, up to the retry count
+	requests = 0
+	_, err = newGenesisDownload(ts.URL+"/genesis.json", t.TempDir(), time.Second, 3, ioutil.Discard).Fetch(context.Background())
+	require.EqualError(t, err, "failed to download "+ts.URL+"/genesis.json: fetching "+ts.URL+"/genesis.json: 502 Bad Gateway")
+	require.Equal(t, 4, requests)
+}
+
+func TestGenesisDownloadStallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.Write([]byte("{"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	_, err := newGenesisDownload(ts.URL, t.TempDir(), 50*time.Millisecond, 0, ioutil.Discard).Fetch(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "context canceled")
+}
@@ -0,0 +1,88 @@
+package gaia
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// testGenesisBuilder returns a builder exercising every kind of fixture data.
+func testGenesisBuilder() *genesisbuilder.Builder {
+	return genesisbuilder.New().
+		WithValidators(3).
+		WithAccount("alice", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 5000000))).
+		WithAccount("bob", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000))).
+		WithDelegation("alice", 0, 2000000).
+		WithDelegation("carol", 2, 3000000).
+		WithUnbondingDelegation("bob", 1, 500000, 7*24*time.Hour).
+		WithVestingAccount("dave", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 7000000)), -24*time.Hour, 365*24*time.Hour).
+		WithProposal(govtypes.StatusVotingPeriod, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10000000))).
+		WithProposal(govtypes.StatusPassed, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10000000))).
+		WithIBCChannel("osmosis-1")
+}
+
+func buildTestGenesis(t *testing.T, b *genesisbuilder.Builder) (*tmtypes.GenesisDoc, types.AppMap) {
+	genDoc, err := b.Build()
+	require.NoError(t, err)
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	return genDoc, state
+}
+
+// exportTestGenesis starts an app from genDoc, runs a block and returns the
+// genesis exported from it, which carries the distribution and slashing state
+// a built genesis leaves to the InitChain hooks.
+func exportTestGenesis(t *testing.T, genDoc *tmtypes.GenesisDoc) (*tmtypes.GenesisDoc, types.AppMap) {
+	app := NewGaiaApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, map[int64]bool{}, t.TempDir(), 0, MakeEncodingConfig(), simapp.EmptyAppOptions{})
+
+	validators := make([]abci.ValidatorUpdate, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = tmtypes.TM2PB.NewValidatorUpdate(val.PubKey, val.Power)
+	}
+
+	app.InitChain(abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(genDoc.ConsensusParams),
+		Validators:      validators,
+		AppStateBytes:   genDoc.AppState,
+		InitialHeight:   genDoc.InitialHeight,
+	})
+
+	header := tmproto.Header{ChainID: genDoc.ChainID, Height: genDoc.InitialHeight, Time: genDoc.GenesisTime}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	app.EndBlock(abci.RequestEndBlock{Height: header.Height})
+	app.Commit()
+
+	exported, err := app.ExportAppStateAndValidators(false, nil)
+	require.NoError(t, err)
+
+	exportedDoc := &tmtypes.GenesisDoc{
+		GenesisTime:     genDoc.GenesisTime,
+		ChainID:         genDoc.ChainID,
+		InitialHeight:   exported.Height + 1,
+		ConsensusParams: genDoc.ConsensusParams,
+		Validators:      exported.Validators,
+		AppState:        exported.AppState,
+	}
+	require.NoError(t, exportedDoc.ValidateAndComplete())
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(exportedDoc.AppState, &state))
+
+	return exportedDoc, state
+}
@@ -0,0 +1,113 @@
+package gaia
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// genesisIOBufferSize is the buffer size of the genesis reader and writer,
+// large enough to keep syscalls rare on multi-GB genesis files.
+const genesisIOBufferSize = 1 << 20
+
+// stdinGenesis is the genesis file argument reading from STDIN.
+const stdinGenesis = "-"
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// openGenesisInput returns a buffered reader of the genesis file at path, or
+// of stdin for stdinGenesis, so it can be decoded without first copying the
+// whole file into memory.
+func openGenesisInput(path string, stdin io.Reader) (io.ReadCloser, error) {
+	if path == stdinGenesis {
+		return bufferedReadCloser{bufio.NewReaderSize(stdin, genesisIOBufferSize), ioutil.NopCloser(nil)}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return bufferedReadCloser{bufio.NewReaderSize(f, genesisIOBufferSize), f}, nil
+}
+
+// writeGenesisOutput writes the genesis followed by a newline through a
+// buffered writer, without converting it to a string first.
+func writeGenesisOutput(w io.Writer, bz []byte) error {
+	bw := bufio.NewWriterSize(w, genesisIOBufferSize)
+
+	if _, err := bw.Write(bz); err != nil {
+		return err
+	}
+
+	if err := bw.WriteByte('\n'); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// writeReviewGenesis writes the JSON genesis indented by two spaces, in the
+// key order of bz, for reviewers to read and diff. The indented copy is built
+// in memory.
+func writeReviewGenesis(w io.Writer, bz []byte) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bz, "", "  "); err != nil {
+		return err
+	}
+
+	return writeGenesisOutput(w, indented.Bytes())
+}
+
+// writeOutputFile writes the file at path with write, first to a temporary
+// file of the same directory that is renamed to path once complete, so the
+// rename never crosses filesystems. The temporary file is created private and
+// set to mode before the rename, whatever the umask. commit, if set, is
+// called before the rename and aborts it with its error, so a failed or
+// interrupted write never leaves a partial file at path. A symlink at path
+// is replaced, not followed.
+func writeOutputFile(path string, mode os.FileMode, write func(io.Writer) error, commit func() error) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package gaia
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// reportMaxRSS reports the peak resident set size of the test process.
+func reportMaxRSS(b *testing.B) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		b.Fatal(err)
+	}
+
+	maxRSS := float64(usage.Maxrss)
+	if runtime.GOOS == "darwin" {
+		// darwin reports bytes, linux kilobytes
+		maxRSS /= 1024
+	}
+
+	b.ReportMetric(maxRSS/1024, "maxrss-MB")
+}
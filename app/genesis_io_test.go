@@ -0,0 +1,176 @@
+package gaia
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const legacyTendermintGenesis = `{
+	"genesis_time": "2019-12-11T16:11:34Z",
+	"chain_id": "cosmoshub-3",
+	"consensus_params": {
+		"block": {"max_bytes": "200000", "max_gas": "2000000", "time_iota_ms": "1000"},
+		"evidence": {"max_age": "1000000"},
+		"validator": {"pub_key_types": ["ed25519"]}
+	},
+	"app_hash": "",
+	"app_state": {"bank": {"send_enabled": true}, "big": 18446744073709551615}
+}`
+
+func TestGenesisInputModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(legacyTendermintGenesis), 0600))
+
+	readMigrated := func(path string) []byte {
+		input, err := openGenesisInput(path, strings.NewReader(legacyTendermintGenesis))
+		require.NoError(t, err)
+		defer input.Close()
+
+		bz, err := migrateTendermintGenesis(input)
+		require.NoError(t, err)
+		return bz
+	}
+
+	fromFile := readMigrated(path)
+	require.Equal(t, fromFile, readMigrated(stdinGenesis))
+	require.Contains(t, string(fromFile), `"max_age_num_blocks":"1000000"`)
+	require.Contains(t, string(fromFile), `"big":18446744073709551615`)
+
+	_, err := openGenesisInput(filepath.Join(t.TempDir(), "missing.json"), nil)
+	require.Error(t, err)
+}
+
+func TestWriteGenesisOutput(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeGenesisOutput(&buf, []byte(`{"chain_id":"cosmoshub-4"}`)))
+	require.Equal(t, "{\"chain_id\":\"cosmoshub-4\"}\n", buf.String())
+}
+
+func TestWriteOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "genesis.json")
+	write := func(bz string) func(io.Writer) error {
+		return func(w io.Writer) error { return writeGenesisOutput(w, []byte(bz)) }
+	}
+
+	require.NoError(t, writeOutputFile(path, 0644, write(`{"chain_id":"cosmoshub-4"}`), nil))
+	bz, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{\"chain_id\":\"cosmoshub-4\"}\n", string(bz))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// a failed write or commit leaves the previous file and no temporary one
+	require.EqualError(t, writeOutputFile(path, 0644, func(w io.Writer) error {
+		_, err := w.Write([]byte(`{"chain_`))
+		require.NoError(t, err)
+		return fmt.Errorf("write failed")
+	}, nil), "write failed")
+	require.EqualError(t, writeOutputFile(path, 0644, write(`{"chain_id":"cosmoshub-5"}`), func() error {
+		return fmt.Errorf("canceled")
+	}), "canceled")
+
+	bz, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{\"chain_id\":\"cosmoshub-4\"}\n", string(bz))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+// BenchmarkGenesisIO compares the former ReadFile and Println genesis I/O with
+// the buffered one on a generated genesis of GAIA_BENCH_GENESIS_MB megabytes,
+// 16 by default. Peak RSS only grows within a process, compare it by running
+// each variant on its own, e.g.
+//
+//	GAIA_BENCH_GENESIS_MB=500 go test ./app -run none -bench 'GenesisIO/buffered' -benchtime 1x
+func BenchmarkGenesisIO(b *testing.B) {
+	size := 16
+	if env := os.Getenv("GAIA_BENCH_GENESIS_MB"); env != "" {
+		var err error
+		size, err = strconv.Atoi(env)
+		require.NoError(b, err)
+	}
+
+	dir := b.TempDir()
+	path := filepath.Join(dir, "genesis.json")
+	require.NoError(b, writeBenchGenesis(path, size<<20))
+
+	out, err := os.Create(filepath.Join(dir, "out.json"))
+	require.NoError(b, err)
+	defer out.Close()
+
+	b.Run("readfile", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			jsonBlob, err := ioutil.ReadFile(path)
+			require.NoError(b, err)
+
+			bz, err := migrateTendermintGenesis(bytes.NewReader(jsonBlob))
+			require.NoError(b, err)
+
+			_, err = fmt.Fprintln(out, string(bz))
+			require.NoError(b, err)
+		}
+		reportMaxRSS(b)
+	})
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			input, err := openGenesisInput(path, nil)
+			require.NoError(b, err)
+
+			bz, err := migrateTendermintGenesis(input)
+			require.NoError(b, err)
+			require.NoError(b, input.Close())
+
+			require.NoError(b, writeGenesisOutput(out, bz))
+		}
+		reportMaxRSS(b)
+	})
+}
+
+// writeBenchGenesis writes a legacy tendermint genesis whose app state is
+// padded with bank balances up to about size bytes.
+func writeBenchGenesis(path string, size int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := legacyTendermintGenesis[:strings.Index(legacyTendermintGenesis, `"app_state"`)]
+	if _, err := f.WriteString(head + `"app_state": {"bank": {"balances": [`); err != nil {
+		return err
+	}
+
+	written := len(head)
+	for i := 0; written < size; i++ {
+		entry := fmt.Sprintf(`{"address": "cosmos1%038d", "coins": [{"denom": "uatom", "amount": "%d"}]}`, i, i*1000)
+		if i > 0 {
+			entry = "," + entry
+		}
+
+		n, err := f.WriteString(entry)
+		if err != nil {
+			return err
+		}
+		written += n
+	}
+
+	_, err = f.WriteString(`]}}}`)
+	return err
+}
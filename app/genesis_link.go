@@ -0,0 +1,236 @@
+package gaia
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	connectiontypes "github.com/cosmos/cosmos-sdk/x/ibc/core/03-connection/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	commitmenttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/23-commitment/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	"github.com/cosmos/cosmos-sdk/x/ibc/core/exported"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// linkMaxClockDrift is the max clock drift of the injected clients.
+const linkMaxClockDrift = 10 * time.Second
+
+// GenesisLinkChainsCmd returns a command linking two genesis files over IBC, so
+// the chains start with an open transfer channel between them.
+func GenesisLinkChainsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "link-chains [genesis-a] [genesis-b]",
+		Short: "Inject an open IBC transfer channel between two genesis files",
+		Long: fmt.Sprintf(`Rewrite two genesis files so their chains start connected over IBC: each gets
+a tendermint client of the other chain with a consensus state at the other
+genesis, an OPEN connection end and an OPEN transfer channel, with the port and
+channel capabilities. The identifiers follow the IBC state already in each file.
+
+The consensus states cannot hold the app hash of the other genesis, which
+depends on the injected state itself, so they only trust the genesis
+validators of the other chain: no proof verifies at the genesis height, and
+the first client update must be to a header after height 1. Relayers update
+the clients before relaying the first packets.
+
+Example:
+$ %s genesis link-chains chain-a/config/genesis.json chain-b/config/genesis.json
+`, version.AppName),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := outputFilesFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			cdc := MakeEncodingConfig().Marshaler
+
+			docs := make([]*tmtypes.GenesisDoc, len(args))
+			states := make([]types.AppMap, len(args))
+			for i, path := range args {
+				genDoc, err := tmtypes.GenesisDocFromFile(path)
+				if err != nil {
+					return errors.Wrapf(err, "failed to read genesis file %s", path)
+				}
+				if err := json.Unmarshal(genDoc.AppState, &states[i]); err != nil {
+					return errors.Wrapf(err, "failed to JSON unmarshal app state of %s", path)
+				}
+				docs[i] = genDoc
+			}
+
+			ends, err := linkChains(cdc, docs, states)
+			if err != nil {
+				return err
+			}
+
+			for i, path := range args {
+				appState, err := json.Marshal(states[i])
+				if err != nil {
+					return errors.Wrap(err, "failed to JSON marshal app state")
+				}
+				docs[i].AppState = appState
+
+				bz, err := tmjson.Marshal(docs[i])
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal genesis doc")
+				}
+				if bz, err = canonicalJSON(bz, true); err != nil {
+					return errors.Wrap(err, "failed to sort JSON genesis doc")
+				}
+
+				if err := files.Write(path, outputFileMode, func(w io.Writer) error {
+					return writeGenesisOutput(w, bz)
+				}, nil); err != nil {
+					return err
+				}
+
+				cmd.Printf("%s: client %s of %s, %s, %s/%s\n", docs[i].ChainID,
+					ends[i].clientID, docs[1-i].ChainID, ends[i].connectionID, ibcxfertypes.PortID, ends[i].channelID)
+			}
+
+			return nil
+		},
+	}
+
+	addOutputFileFlags(cmd)
+
+	return cmd
+}
+
+// linkEnd are the identifiers of the IBC state injected in one genesis.
+type linkEnd struct {
+	clientID     string
+	connectionID string
+	channelID    string
+}
+
+// linkChains injects in the app states of the genesis docs a and b a client
+// of the other chain, an OPEN connection and an OPEN transfer channel of
+// counterparties of one another, and regenerates their capability genesis.
+// It returns the identifiers injected in each.
+func linkChains(cdc codec.JSONMarshaler, docs []*tmtypes.GenesisDoc, states []types.AppMap) ([2]linkEnd, error) {
+	var ends [2]linkEnd
+	if docs[0].ChainID == docs[1].ChainID {
+		return ends, fmt.Errorf("both genesis files are of chain %s", docs[0].ChainID)
+	}
+
+	ibcGeneses := make([]*ibccoretypes.GenesisState, 2)
+	for i, state := range states {
+		ibcGeneses[i] = ibccoretypes.DefaultGenesisState()
+		if bz, ok := state[host.ModuleName]; ok {
+			if err := cdc.UnmarshalJSON(bz, ibcGeneses[i]); err != nil {
+				return ends, errors.Wrapf(err, "failed to JSON unmarshal %s genesis of %s", host.ModuleName, docs[i].ChainID)
+			}
+		}
+
+		if _, ok := state[ibcxfertypes.ModuleName]; !ok {
+			state[ibcxfertypes.ModuleName] = cdc.MustMarshalJSON(ibcxfertypes.DefaultGenesisState())
+		}
+
+		ends[i] = linkEnd{
+			clientID:     clienttypes.FormatClientIdentifier(exported.Tendermint, ibcGeneses[i].ClientGenesis.NextClientSequence),
+			connectionID: connectiontypes.FormatConnectionIdentifier(ibcGeneses[i].ConnectionGenesis.NextConnectionSequence),
+			channelID:    channeltypes.FormatChannelIdentifier(ibcGeneses[i].ChannelGenesis.NextChannelSequence),
+		}
+	}
+
+	for i, ibcGenesis := range ibcGeneses {
+		end, counterparty := ends[i], ends[1-i]
+
+		clientState, consensusState, err := genesisClient(cdc, docs[1-i], states[1-i])
+		if err != nil {
+			return ends, err
+		}
+
+		ibcGenesis.ClientGenesis.Clients = append(ibcGenesis.ClientGenesis.Clients, clienttypes.NewIdentifiedClientState(end.clientID, clientState))
+		ibcGenesis.ClientGenesis.ClientsConsensus = append(ibcGenesis.ClientGenesis.ClientsConsensus, clienttypes.NewClientConsensusStates(
+			end.clientID, []clienttypes.ConsensusStateWithHeight{clienttypes.NewConsensusStateWithHeight(clientState.LatestHeight, consensusState)}))
+		ibcGenesis.ClientGenesis.NextClientSequence++
+
+		connection := connectiontypes.NewConnectionEnd(connectiontypes.OPEN, end.clientID,
+			connectiontypes.NewCounterparty(counterparty.clientID, counterparty.connectionID, commitmenttypes.NewMerklePrefix([]byte(host.StoreKey))),
+			connectiontypes.ExportedVersionsToProto(connectiontypes.GetCompatibleVersions()), 0)
+		ibcGenesis.ConnectionGenesis.Connections = append(ibcGenesis.ConnectionGenesis.Connections, connectiontypes.NewIdentifiedConnection(end.connectionID, connection))
+		ibcGenesis.ConnectionGenesis.ClientConnectionPaths = append(ibcGenesis.ConnectionGenesis.ClientConnectionPaths, connectiontypes.NewConnectionPaths(end.clientID, []string{end.connectionID}))
+		ibcGenesis.ConnectionGenesis.NextConnectionSequence++
+
+		channel := channeltypes.NewChannel(channeltypes.OPEN, channeltypes.UNORDERED,
+			channeltypes.NewCounterparty(ibcxfertypes.PortID, counterparty.channelID), []string{end.connectionID}, ibcxfertypes.Version)
+		ibcGenesis.ChannelGenesis.Channels = append(ibcGenesis.ChannelGenesis.Channels, channeltypes.NewIdentifiedChannel(ibcxfertypes.PortID, end.channelID, channel))
+		for _, seqs := range []*[]channeltypes.PacketSequence{
+			&ibcGenesis.ChannelGenesis.SendSequences, &ibcGenesis.ChannelGenesis.RecvSequences, &ibcGenesis.ChannelGenesis.AckSequences,
+		} {
+			*seqs = append(*seqs, channeltypes.NewPacketSequence(ibcxfertypes.PortID, end.channelID, 1))
+		}
+		ibcGenesis.ChannelGenesis.NextChannelSequence++
+
+		if err := ibcGenesis.Validate(); err != nil {
+			return ends, errors.Wrapf(err, "linked %s genesis of %s is invalid", host.ModuleName, docs[i].ChainID)
+		}
+
+		states[i][host.ModuleName] = cdc.MustMarshalJSON(ibcGenesis)
+		if err := repairCapabilities(cdc, states[i]); err != nil {
+			return ends, errors.Wrapf(err, "failed to regenerate the capabilities of %s", docs[i].ChainID)
+		}
+	}
+
+	return ends, nil
+}
+
+// genesisClient returns a tendermint client of the chain of genDoc at its
+// initial height. Its consensus state trusts the genesis validators of the
+// chain, but its root is no app hash of the chain: the app hash at InitChain
+// depends on the IBC state injected with the client.
+func genesisClient(cdc codec.JSONMarshaler, genDoc *tmtypes.GenesisDoc, state types.AppMap) (*ibctmtypes.ClientState, *ibctmtypes.ConsensusState, error) {
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis of %s", staking.ModuleName, genDoc.ChainID)
+	}
+
+	// without genesis validators tendermint takes those of the staking genesis
+	validators := genDoc.Validators
+	if len(validators) == 0 {
+		var err error
+		if validators, err = tmValidatorsFromStaking(stakingGenesis); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(validators) == 0 {
+		return nil, nil, fmt.Errorf("chain %s has no genesis validators", genDoc.ChainID)
+	}
+
+	vals := make([]*tmtypes.Validator, len(validators))
+	for i, val := range validators {
+		vals[i] = tmtypes.NewValidator(val.PubKey, val.Power)
+	}
+	valSet := tmtypes.NewValidatorSet(vals)
+
+	initialHeight := genDoc.InitialHeight
+	if initialHeight == 0 {
+		initialHeight = 1
+	}
+	height := clienttypes.NewHeight(clienttypes.ParseChainID(genDoc.ChainID), uint64(initialHeight))
+
+	unbondingPeriod := stakingGenesis.Params.UnbondingTime
+	clientState := ibctmtypes.NewClientState(genDoc.ChainID, ibctmtypes.DefaultTrustLevel,
+		unbondingPeriod*2/3, unbondingPeriod, linkMaxClockDrift, height, commitmenttypes.GetSDKSpecs(),
+		[]string{"upgrade", "upgradedIBCState"}, false, false)
+
+	// a root no app state hashes to, nothing verifies against it
+	root := sha256.Sum256([]byte("genesis of " + genDoc.ChainID))
+	consensusState := ibctmtypes.NewConsensusState(genDoc.GenesisTime, commitmenttypes.NewMerkleRoot(root[:]), valSet.Hash())
+
+	return clientState, consensusState, nil
+}
@@ -0,0 +1,250 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	commitmenttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/23-commitment/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmed25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// linkedChain is an in-memory GaiaApp started from a linked genesis, signing
+// its headers with the builder's validator keys.
+type linkedChain struct {
+	t       *testing.T
+	app     *GaiaApp
+	chainID string
+	header  tmproto.Header
+	valSet  *tmtypes.ValidatorSet
+	signers []tmtypes.PrivValidator
+}
+
+func startLinkedChain(t *testing.T, genDoc *tmtypes.GenesisDoc, validators int) *linkedChain {
+	app := NewGaiaApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, map[int64]bool{}, t.TempDir(), 0, MakeEncodingConfig(), smokeTestAppOptions{})
+
+	chain := &linkedChain{t: t, app: app, chainID: genDoc.ChainID}
+	vals := make([]*tmtypes.Validator, validators)
+	updates := make([]abci.ValidatorUpdate, validators)
+	for i := range vals {
+		pubKey := tmed25519.PrivKey(genesisbuilder.ValidatorConsKey(i).Key).PubKey()
+		vals[i] = tmtypes.NewValidator(pubKey, genDoc.Validators[i].Power)
+		updates[i] = tmtypes.TM2PB.NewValidatorUpdate(pubKey, genDoc.Validators[i].Power)
+	}
+	chain.valSet = tmtypes.NewValidatorSet(vals)
+
+	// the signers in the order of the validator set
+	chain.signers = make([]tmtypes.PrivValidator, validators)
+	for i := 0; i < validators; i++ {
+		privKey := tmed25519.PrivKey(genesisbuilder.ValidatorConsKey(i).Key)
+		idx, _ := chain.valSet.GetByAddress(privKey.PubKey().Address())
+		chain.signers[idx] = tmtypes.NewMockPVWithParams(privKey, false, false)
+	}
+
+	app.InitChain(abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(genDoc.ConsensusParams),
+		Validators:      updates,
+		AppStateBytes:   genDoc.AppState,
+		InitialHeight:   genDoc.InitialHeight,
+	})
+
+	chain.header = tmproto.Header{
+		ChainID:            genDoc.ChainID,
+		Height:             genDoc.InitialHeight,
+		Time:               genDoc.GenesisTime,
+		ValidatorsHash:     chain.valSet.Hash(),
+		NextValidatorsHash: chain.valSet.Hash(),
+	}
+	app.BeginBlock(abci.RequestBeginBlock{Header: chain.header})
+
+	return chain
+}
+
+// deliver executes the msg servers calls in the current block.
+func (c *linkedChain) deliver(calls ...func(ctx context.Context) error) {
+	ctx := sdk.WrapSDKContext(c.app.BaseApp.NewContext(false, c.header))
+	for _, call := range calls {
+		require.NoError(c.t, call(ctx))
+	}
+}
+
+// nextBlock commits the current block and begins the next, 5s later.
+func (c *linkedChain) nextBlock() {
+	c.app.EndBlock(abci.RequestEndBlock{Height: c.header.Height})
+	c.app.Commit()
+
+	c.header.Height++
+	c.header.Time = c.header.Time.Add(5 * time.Second)
+	c.header.AppHash = c.app.LastCommitID().Hash
+	c.app.BeginBlock(abci.RequestBeginBlock{Header: c.header})
+}
+
+func (c *linkedChain) height() clienttypes.Height {
+	return clienttypes.NewHeight(clienttypes.ParseChainID(c.chainID), uint64(c.header.Height))
+}
+
+// clientHeader returns the signed header of the current block, to update a
+// client trusting the genesis validators at trustedHeight.
+func (c *linkedChain) clientHeader(trustedHeight clienttypes.Height) *ibctmtypes.Header {
+	header := tmtypes.Header{
+		Version:            tmversion.Consensus{Block: version.BlockProtocol, App: 0},
+		ChainID:            c.chainID,
+		Height:             c.header.Height,
+		Time:               c.header.Time,
+		LastBlockID:        tmtypes.BlockID{Hash: make([]byte, tmhash.Size), PartSetHeader: tmtypes.PartSetHeader{Total: 1, Hash: make([]byte, tmhash.Size)}},
+		LastCommitHash:     tmhash.Sum([]byte("last_commit_hash")),
+		DataHash:           tmhash.Sum([]byte("data_hash")),
+		ValidatorsHash:     c.valSet.Hash(),
+		NextValidatorsHash: c.valSet.Hash(),
+		ConsensusHash:      tmhash.Sum([]byte("consensus_hash")),
+		AppHash:            c.header.AppHash,
+		LastResultsHash:    tmhash.Sum([]byte("last_results_hash")),
+		EvidenceHash:       tmhash.Sum([]byte("evidence_hash")),
+		ProposerAddress:    c.valSet.Proposer.Address,
+	}
+
+	blockID := tmtypes.BlockID{Hash: header.Hash(), PartSetHeader: tmtypes.PartSetHeader{Total: 1, Hash: tmhash.Sum([]byte("part_set"))}}
+	voteSet := tmtypes.NewVoteSet(c.chainID, header.Height, 1, tmproto.PrecommitType, c.valSet)
+	commit, err := tmtypes.MakeCommit(blockID, header.Height, 1, voteSet, c.signers, header.Time)
+	require.NoError(c.t, err)
+
+	valSet, err := c.valSet.ToProto()
+	require.NoError(c.t, err)
+
+	return &ibctmtypes.Header{
+		SignedHeader:      &tmproto.SignedHeader{Header: header.ToProto(), Commit: commit.ToProto()},
+		ValidatorSet:      valSet,
+		TrustedHeight:     trustedHeight,
+		TrustedValidators: valSet,
+	}
+}
+
+// proof returns the proof of key in the ibc store of the last committed
+// block, verified by the app hash of the current header.
+func (c *linkedChain) proof(key []byte) ([]byte, clienttypes.Height) {
+	res := c.app.Query(abci.RequestQuery{
+		Path:   "store/" + host.StoreKey + "/key",
+		Height: c.header.Height - 1,
+		Data:   key,
+		Prove:  true,
+	})
+	require.Zero(c.t, res.Code, res.Log)
+
+	merkleProof, err := commitmenttypes.ConvertProofs(res.ProofOps)
+	require.NoError(c.t, err)
+	proof, err := c.app.AppCodec().MarshalBinaryBare(&merkleProof)
+	require.NoError(c.t, err)
+
+	return proof, c.height()
+}
+
+func writeLinkGenesis(t *testing.T, b *genesisbuilder.Builder) string {
+	genDoc, _ := buildTestGenesis(t, b)
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+	return path
+}
+
+func TestGenesisLinkChains(t *testing.T) {
+	sender, receiver := "sender", "receiver"
+	builderA := genesisbuilder.New().WithChainID("gaia-a-1").WithValidators(2).
+		WithAccount(sender, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000)))
+	// chain b already has a channel, the injected one follows it
+	builderB := genesisbuilder.New().WithChainID("gaia-b-2").WithValidators(1).WithIBCChannel("gaia-c-1").
+		WithAccount(receiver, nil)
+	pathA, pathB := writeLinkGenesis(t, builderA), writeLinkGenesis(t, builderB)
+
+	cmd := GenesisLinkChainsCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{pathA, pathB})
+	require.NoError(t, cmd.Execute())
+	require.Equal(t, "gaia-a-1: client 07-tendermint-0 of gaia-b-2, connection-0, transfer/channel-0\n"+
+		"gaia-b-2: client 07-tendermint-1 of gaia-a-1, connection-1, transfer/channel-1\n", out.String())
+
+	genDocA, err := tmtypes.GenesisDocFromFile(pathA)
+	require.NoError(t, err)
+	genDocB, err := tmtypes.GenesisDocFromFile(pathB)
+	require.NoError(t, err)
+	for _, genDoc := range []*tmtypes.GenesisDoc{genDocA, genDocB} {
+		require.NoError(t, SmokeTestGenesis(genDoc))
+	}
+
+	chainA := startLinkedChain(t, genDocA, 2)
+	chainB := startLinkedChain(t, genDocB, 1)
+	genesisHeightA := chainA.height()
+
+	// transfer from a, in the block after genesis
+	chainA.nextBlock()
+	chainB.nextBlock()
+	timeout := clienttypes.NewHeight(2, 1000)
+	coin := sdk.NewInt64Coin(genesisbuilder.BondDenom, 100)
+	chainA.deliver(func(ctx context.Context) error {
+		_, err := chainA.app.TransferKeeper.Transfer(ctx, ibcxfertypes.NewMsgTransfer(ibcxfertypes.PortID, "channel-0", coin,
+			builderA.Address(sender), builderB.Address(receiver).String(), timeout, 0))
+		return err
+	})
+
+	packetData := ibcxfertypes.NewFungibleTokenPacketData(coin.Denom, coin.Amount.Uint64(), builderA.Address(sender).String(), builderB.Address(receiver).String())
+	packet := channeltypes.NewPacket(packetData.GetBytes(), 1, ibcxfertypes.PortID, "channel-0", ibcxfertypes.PortID, "channel-1", timeout, 0)
+
+	chainA.nextBlock()
+	chainB.nextBlock()
+	proof, proofHeight := chainA.proof(host.PacketCommitmentKey(packet.SourcePort, packet.SourceChannel, packet.Sequence))
+
+	// the client of a on b is updated from the genesis validators, then
+	// receives the packet
+	relayer := builderB.Address(receiver)
+	update, err := clienttypes.NewMsgUpdateClient("07-tendermint-1", chainA.clientHeader(genesisHeightA), relayer)
+	require.NoError(t, err)
+	chainB.deliver(func(ctx context.Context) error {
+		_, err := chainB.app.IBCKeeper.UpdateClient(ctx, update)
+		return err
+	}, func(ctx context.Context) error {
+		_, err := chainB.app.IBCKeeper.RecvPacket(ctx, channeltypes.NewMsgRecvPacket(packet, proof, proofHeight, relayer))
+		return err
+	})
+	chainB.nextBlock()
+
+	voucher := ibcxfertypes.ParseDenomTrace(ibcxfertypes.GetPrefixedDenom(ibcxfertypes.PortID, "channel-1", genesisbuilder.BondDenom)).IBCDenom()
+	ctx := chainB.app.BaseApp.NewContext(true, chainB.header)
+	require.Equal(t, sdk.NewInt64Coin(voucher, 100), chainB.app.BankKeeper.GetBalance(ctx, builderB.Address(receiver), voucher))
+
+	ctx = chainA.app.BaseApp.NewContext(true, chainA.header)
+	require.Equal(t, sdk.NewInt64Coin(genesisbuilder.BondDenom, 900), chainA.app.BankKeeper.GetBalance(ctx, builderA.Address(sender), genesisbuilder.BondDenom))
+}
+
+func TestLinkChainsSameChain(t *testing.T) {
+	path := writeLinkGenesis(t, genesisbuilder.New().WithValidators(1))
+
+	cmd := GenesisLinkChainsCmd()
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs([]string{path, path})
+	require.EqualError(t, cmd.Execute(), "both genesis files are of chain "+genesisbuilder.ChainID)
+}
@@ -0,0 +1,448 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagSetJSON = "json"
+
+	// genesisDocModule addresses the genesis doc itself instead of a module
+	// of its app state.
+	genesisDocModule = "."
+)
+
+// jsonPathStep is a step of a genesis query path: an object key, an array
+// index or the array element whose field has a value.
+type jsonPathStep struct {
+	Key   string
+	Index int
+	Field string
+	Value string
+}
+
+func (s jsonPathStep) isKey() bool      { return s.Key != "" }
+func (s jsonPathStep) isSelector() bool { return s.Field != "" }
+
+func (s jsonPathStep) String() string {
+	switch {
+	case s.isKey():
+		return s.Key
+	case s.isSelector():
+		return fmt.Sprintf("[%s=%s]", s.Field, s.Value)
+	default:
+		return fmt.Sprintf("[%d]", s.Index)
+	}
+}
+
+// parseGenesisPath parses a query path: the dot separated keys of the legacy
+// era paths, each followed by any number of [N] array indexes or
+// [field=value] selectors of the array element whose field has the value,
+// e.g. balances[address=cosmos1...].coins[0].amount. An empty path is the
+// whole module.
+func parseGenesisPath(path string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	if path == "" {
+		return steps, nil
+	}
+
+	for i := 0; i < len(path); {
+		end := strings.IndexAny(path[i:], ".[")
+		if end < 0 {
+			end = len(path)
+		} else {
+			end += i
+		}
+		// a module array is indexed by a path starting with a selector
+		if end > i {
+			steps = append(steps, jsonPathStep{Key: path[i:end]})
+		} else if i > 0 || path[i] != '[' {
+			return nil, fmt.Errorf("invalid path %s: empty key at %d", path, i)
+		}
+		i = end
+
+		for i < len(path) && path[i] == '[' {
+			closing := strings.IndexByte(path[i:], ']')
+			if closing < 0 {
+				return nil, fmt.Errorf("invalid path %s: unclosed [ at %d", path, i)
+			}
+			selector := path[i+1 : i+closing]
+			i += closing + 1
+
+			if eq := strings.IndexByte(selector, '='); eq >= 0 {
+				if eq == 0 {
+					return nil, fmt.Errorf("invalid path %s: selector [%s] has no field", path, selector)
+				}
+				steps = append(steps, jsonPathStep{Field: selector[:eq], Value: selector[eq+1:]})
+				continue
+			}
+
+			index, err := strconv.Atoi(selector)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %s: [%s] is neither an index nor a field=value selector", path, selector)
+			}
+			steps = append(steps, jsonPathStep{Index: index})
+		}
+
+		if i < len(path) {
+			if path[i] != '.' || i == len(path)-1 {
+				return nil, fmt.Errorf("invalid path %s: unexpected %q at %d", path, path[i], i)
+			}
+			i++
+		}
+	}
+
+	return steps, nil
+}
+
+// jsonPathString returns the path of steps.
+func jsonPathString(steps []jsonPathStep) string {
+	var b strings.Builder
+	for i, step := range steps {
+		if i > 0 && step.isKey() {
+			b.WriteByte('.')
+		}
+		b.WriteString(step.String())
+	}
+
+	return b.String()
+}
+
+// lookupGenesisPath returns the value at steps of value.
+func lookupGenesisPath(value interface{}, steps []jsonPathStep) (interface{}, error) {
+	for i, step := range steps {
+		next, err := stepInto(value, step, jsonPathString(steps[:i]))
+		if err != nil {
+			return nil, err
+		}
+		value = next
+	}
+
+	return value, nil
+}
+
+// setGenesisPath sets the existing value at steps of root to value, or adds
+// the last key of steps to its object.
+func setGenesisPath(root interface{}, steps []jsonPathStep, value interface{}) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("cannot replace a whole module, give a path")
+	}
+
+	last := len(steps) - 1
+	parent, err := lookupGenesisPath(root, steps[:last])
+	if err != nil {
+		return err
+	}
+
+	step, at := steps[last], jsonPathString(steps[:last])
+	if step.isKey() {
+		obj, ok := parent.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not an object", describePath(at))
+		}
+		obj[step.Key] = value
+		return nil
+	}
+
+	arr, ok := parent.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s is not an array", describePath(at))
+	}
+	i, err := arrayElement(arr, step, at)
+	if err != nil {
+		return err
+	}
+	arr[i] = value
+
+	return nil
+}
+
+func stepInto(value interface{}, step jsonPathStep, at string) (interface{}, error) {
+	if step.isKey() {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", describePath(at))
+		}
+		next, ok := obj[step.Key]
+		if !ok {
+			return nil, fmt.Errorf("%s has no key %s", describePath(at), step.Key)
+		}
+		return next, nil
+	}
+
+	arr, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an array", describePath(at))
+	}
+	i, err := arrayElement(arr, step, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return arr[i], nil
+}
+
+// arrayElement returns the index of the element of arr at the index or
+// selector step. A selector must match exactly one element.
+func arrayElement(arr []interface{}, step jsonPathStep, at string) (int, error) {
+	if !step.isSelector() {
+		if step.Index >= len(arr) {
+			return 0, fmt.Errorf("%s has %d elements, no index %d", describePath(at), len(arr), step.Index)
+		}
+		return step.Index, nil
+	}
+
+	match := -1
+	for i, element := range arr {
+		obj, ok := element.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		field, ok := obj[step.Field]
+		if !ok {
+			continue
+		}
+		if fmt.Sprint(field) != step.Value {
+			continue
+		}
+
+		if match >= 0 {
+			return 0, fmt.Errorf("several elements of %s have %s=%s", describePath(at), step.Field, step.Value)
+		}
+		match = i
+	}
+	if match < 0 {
+		return 0, fmt.Errorf("no element of %s has %s=%s", describePath(at), step.Field, step.Value)
+	}
+
+	return match, nil
+}
+
+func describePath(at string) string {
+	if at == "" {
+		return "the module"
+	}
+	return at
+}
+
+// decodeGenesisJSON decodes JSON keeping its numbers as json.Number, so
+// integers beyond the float64 precision, like uint64 values, are unchanged.
+func decodeGenesisJSON(r io.Reader) (interface{}, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after the top-level JSON value")
+	}
+
+	return value, nil
+}
+
+// genesisQueryRoot returns the app state module, or the genesis doc for
+// genesisDocModule, of the decoded genesis doc.
+func genesisQueryRoot(doc interface{}, module string) (interface{}, error) {
+	if module == genesisDocModule {
+		return doc, nil
+	}
+
+	appState, err := lookupGenesisPath(doc, []jsonPathStep{{Key: "app_state"}})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid genesis doc")
+	}
+	obj, ok := appState.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid genesis doc: app_state is not an object")
+	}
+	state, ok := obj[module]
+	if !ok {
+		return nil, fmt.Errorf("the app state has no module %s", module)
+	}
+
+	return state, nil
+}
+
+// rawJSONValue returns value as genesis get prints it: strings unquoted, any
+// other value as JSON.
+func rawJSONValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	bz, err := json.Marshal(value)
+	return string(bz), err
+}
+
+func readGenesisQueryDoc(path string, stdin io.Reader) (interface{}, error) {
+	input, err := openGenesisInput(path, stdin)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read genesis file")
+	}
+	defer input.Close()
+
+	doc, err := decodeGenesisJSON(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode genesis file")
+	}
+
+	return doc, nil
+}
+
+// GenesisGetCmd returns a command printing a value of a genesis file.
+func GenesisGetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get [genesis-file] [module] [path]",
+		Short: "Print a value of a module of a genesis file, keeping large integers exact",
+		Long: fmt.Sprintf(`Print the value at path of the genesis of module, or of the genesis doc for
+module %s. Strings are printed raw, other values as JSON. Numbers are never
+converted to floating point, unlike with jq, so uint64 values are printed as
+they are in the file.
+
+The path is a dot separated list of keys, each followed by any number of [N]
+array indexes or [field=value] selectors of the array element whose field has
+the value, e.g. balances[address=cosmos1...].coins[0].amount. An empty path
+prints the whole module. Pass - as the genesis file to read it from STDIN.
+
+Example:
+$ %s genesis get genesis.json bank 'supply[denom=uatom].amount'
+$ %s genesis get genesis.json . initial_height
+`, genesisDocModule, version.AppName, version.AppName),
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, err := parseGenesisPath(args[2])
+			if err != nil {
+				return err
+			}
+
+			doc, err := readGenesisQueryDoc(args[0], cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+
+			root, err := genesisQueryRoot(doc, args[1])
+			if err != nil {
+				return err
+			}
+
+			value, err := lookupGenesisPath(root, steps)
+			if err != nil {
+				return err
+			}
+
+			out, err := rawJSONValue(value)
+			if err != nil {
+				return err
+			}
+
+			cmd.Println(out)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// GenesisSetCmd returns a command setting a value of a genesis file.
+func GenesisSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set [genesis-file] [module] [path] [value]",
+		Short: "Set a value of a module of a genesis file, keeping large integers exact",
+		Long: fmt.Sprintf(`Set the value at path of the genesis of module, or of the genesis doc for
+module %s, with the path syntax of genesis get, and write the genesis, with
+sorted keys, to --output or STDOUT. The numbers of the file are never converted
+to floating point.
+
+A value replacing a string is set as a string, as the SDK encodes its 64-bit
+integers, any other value is parsed as JSON, numbers exactly. --json parses
+the value as JSON in every case. The last key of the path may be added to its
+object, every other step must exist.
+
+Example:
+$ %s genesis set genesis.json staking params.max_validators 150 --output genesis.json
+$ %s genesis set genesis.json auth 'accounts[address=cosmos1...].sequence' 18446744073709551615 --output genesis.json
+`, genesisDocModule, version.AppName, version.AppName),
+		Args: cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, err := parseGenesisPath(args[2])
+			if err != nil {
+				return err
+			}
+
+			files, err := outputFilesFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			doc, err := readGenesisQueryDoc(args[0], cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+
+			root, err := genesisQueryRoot(doc, args[1])
+			if err != nil {
+				return err
+			}
+
+			asJSON, _ := cmd.Flags().GetBool(flagSetJSON)
+			value, err := genesisSetValue(root, steps, args[3], asJSON)
+			if err != nil {
+				return err
+			}
+
+			if err := setGenesisPath(root, steps, value); err != nil {
+				return err
+			}
+
+			bz, err := json.Marshal(doc)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal genesis")
+			}
+
+			output, _ := cmd.Flags().GetString(flagOutputFile)
+			if output == "" {
+				return writeGenesisOutput(cmd.OutOrStdout(), bz)
+			}
+
+			return files.Write(output, outputFileMode, func(w io.Writer) error {
+				return writeGenesisOutput(w, bz)
+			}, nil)
+		},
+	}
+
+	cmd.Flags().Bool(flagSetJSON, false, "Parse the value as JSON even where it replaces a string")
+	cmd.Flags().String(flagOutputFile, "", "File to write the genesis to instead of STDOUT, which may be the genesis file")
+	addOutputFileFlags(cmd)
+
+	return cmd
+}
+
+// genesisSetValue returns the value genesis set sets at steps of root for
+// arg: a string if it replaces a string, unless asJSON, else the JSON of arg.
+func genesisSetValue(root interface{}, steps []jsonPathStep, arg string, asJSON bool) (interface{}, error) {
+	if !asJSON {
+		if current, err := lookupGenesisPath(root, steps); err == nil {
+			if _, ok := current.(string); ok {
+				return arg, nil
+			}
+		}
+	}
+
+	value, err := decodeGenesisJSON(bytes.NewReader([]byte(arg)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid JSON value %s", arg)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,125 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGenesisPath(t *testing.T) {
+	testCases := []struct {
+		path  string
+		steps []jsonPathStep
+		err   string
+	}{
+		{"", nil, ""},
+		{"params.max_validators", []jsonPathStep{{Key: "params"}, {Key: "max_validators"}}, ""},
+		{"balances[address=cosmos1abc].coins[0].amount", []jsonPathStep{
+			{Key: "balances"}, {Field: "address", Value: "cosmos1abc"}, {Key: "coins"}, {Index: 0}, {Key: "amount"},
+		}, ""},
+		{"supply[denom=ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2]", []jsonPathStep{
+			{Key: "supply"}, {Field: "denom", Value: "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"},
+		}, ""},
+		{"[1][2].a", []jsonPathStep{{Index: 1}, {Index: 2}, {Key: "a"}}, ""},
+		{"a[x=]", []jsonPathStep{{Key: "a"}, {Field: "x"}}, ""},
+		{"a..b", nil, "invalid path a..b: empty key at 2"},
+		{".a", nil, "invalid path .a: empty key at 0"},
+		{"a.", nil, `invalid path a.: unexpected '.' at 1`},
+		{"a[0]b", nil, `invalid path a[0]b: unexpected 'b' at 4`},
+		{"a[0", nil, "invalid path a[0: unclosed [ at 1"},
+		{"a[-1]", nil, "invalid path a[-1]: [-1] is neither an index nor a field=value selector"},
+		{"a[x]", nil, "invalid path a[x]: [x] is neither an index nor a field=value selector"},
+		{"a[=x]", nil, "invalid path a[=x]: selector [=x] has no field"},
+	}
+
+	for _, tc := range testCases {
+		steps, err := parseGenesisPath(tc.path)
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err, tc.path)
+			continue
+		}
+		require.NoError(t, err, tc.path)
+		require.Equal(t, tc.steps, steps, tc.path)
+		require.Equal(t, tc.path, jsonPathString(steps))
+	}
+}
+
+func TestGenesisGetSetCmd(t *testing.T) {
+	b := testGenesisBuilder()
+	genDoc, _ := buildTestGenesis(t, b)
+	// a JSON number float64 cannot hold, which jq rounds to 123456789012345680
+	genDoc.AppState = json.RawMessage(strings.Replace(string(genDoc.AppState), `{"auth":`, `{"custom":{"big":123456789012345678},"auth":`, 1))
+	genesisFile := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, genDoc.SaveAs(genesisFile))
+
+	run := func(newCmd func() *cobra.Command, args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := newCmd()
+		cmd.SetOut(&out)
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run(GenesisGetCmd, genesisFile, "custom", "big")
+	require.NoError(t, err)
+	require.Equal(t, "123456789012345678\n", out)
+
+	out, err = run(GenesisGetCmd, genesisFile, ".", "chain_id")
+	require.NoError(t, err)
+	require.Equal(t, genesisbuilder.ChainID+"\n", out)
+
+	alice := b.Address("alice").String()
+	out, err = run(GenesisGetCmd, genesisFile, "bank", "balances[address="+alice+"].coins[0]")
+	require.NoError(t, err)
+	require.Equal(t, `{"amount":"5000000","denom":"`+genesisbuilder.BondDenom+`"}`+"\n", out)
+
+	_, err = run(GenesisGetCmd, genesisFile, "bank", "balances[address=cosmos1nobody].coins")
+	require.EqualError(t, err, "no element of balances has address=cosmos1nobody")
+	_, err = run(GenesisGetCmd, genesisFile, "bank", "balances[9999]")
+	require.Error(t, err)
+	_, err = run(GenesisGetCmd, genesisFile, "bank", "params.missing")
+	require.EqualError(t, err, "params has no key missing")
+	_, err = run(GenesisGetCmd, genesisFile, "unknown", "")
+	require.EqualError(t, err, "the app state has no module unknown")
+
+	// an 18 digit sequence, a string as the SDK encodes uint64 values
+	output := filepath.Join(t.TempDir(), "set.json")
+	path := "accounts[address=" + alice + "].sequence"
+	_, err = run(GenesisSetCmd, genesisFile, "auth", path, "987654321987654321", "--output", output)
+	require.NoError(t, err)
+
+	out, err = run(GenesisGetCmd, output, "auth", path)
+	require.NoError(t, err)
+	require.Equal(t, "987654321987654321\n", out)
+	bz, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Contains(t, string(bz), `"sequence":"987654321987654321"`)
+	// the other numbers of the file are unchanged
+	require.Contains(t, string(bz), `"custom":{"big":123456789012345678}`)
+
+	// an 18 digit JSON number, added as a new key
+	_, err = run(GenesisSetCmd, output, "custom", "bigger", "876543210876543210", "--output", output)
+	require.NoError(t, err)
+	out, err = run(GenesisGetCmd, output, "custom", "")
+	require.NoError(t, err)
+	require.Equal(t, `{"big":123456789012345678,"bigger":876543210876543210}`+"\n", out)
+
+	// --json parses a value replacing a string
+	out, err = run(GenesisSetCmd, output, "auth", path, "12", "--json")
+	require.NoError(t, err)
+	require.Contains(t, out, `"sequence":12`)
+
+	_, err = run(GenesisSetCmd, output, "custom", "big", "{")
+	require.Error(t, err)
+	_, err = run(GenesisSetCmd, output, "custom", "", "1")
+	require.EqualError(t, err, "cannot replace a whole module, give a path")
+}
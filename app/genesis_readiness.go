@@ -0,0 +1,259 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"text/tabwriter"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+const flagBlockTime = "expected-block-time"
+
+// GenesisReadinessCmd returns a command reporting whether a genesis file is
+// ready for its chain to produce the first block.
+func GenesisReadinessCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "readiness [genesis-file]",
+		Short: "Report the launch readiness of a genesis file",
+		Long: fmt.Sprintf(`Report on one page what the launch of a genesis depends on: the time left until
+its genesis_time, whether the evidence params fit in the unbonding time at the
+expected block time and match the mint blocks_per_year, the smallest set of
+validators that must be online for more than 2/3 of the power to produce the
+first block, and the findings of genesis validate, which --bech32-prefix is
+passed to. The expected block time defaults to the one implied by the mint
+blocks_per_year. Nothing is written. Pass - as the genesis file to read it
+from STDIN.
+
+Example:
+$ %s genesis readiness genesis.json
+$ %s genesis readiness genesis.json --expected-block-time 6s --format json
+`, version.AppName, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString(flagFormat)
+			if format != formatText && format != formatJSON {
+				return fmt.Errorf("unknown format %q, expected %s or %s", format, formatText, formatJSON)
+			}
+
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			// the genesis is read once for the document and the power report
+			bz, err := ioutil.ReadAll(input)
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+
+			doc, err := genesis.Load(bytes.NewReader(bz))
+			if err != nil {
+				return err
+			}
+			prefix, _ := cmd.Flags().GetString(flagBech32Prefix)
+			doc = doc.WithBech32Prefix(prefix)
+
+			power, err := readPowerReport(bytes.NewReader(bz))
+			if err != nil {
+				return err
+			}
+
+			blockTime, _ := cmd.Flags().GetDuration(flagBlockTime)
+			report, err := newReadinessReport(doc, power, time.Now(), blockTime)
+			if err != nil {
+				return err
+			}
+
+			if format == formatJSON {
+				bz, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to JSON marshal readiness report")
+				}
+				cmd.Println(string(bz))
+				return nil
+			}
+
+			return report.write(cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().String(flagFormat, formatText, "Format of the report, text or json")
+	cmd.Flags().Duration(flagBlockTime, 0, "Expected time per block of the chain, defaults to the one implied by the mint blocks_per_year")
+	cmd.Flags().String(flagBech32Prefix, sdk.Bech32MainPrefix, "Bech32 prefix of the account addresses of the chain of the genesis")
+
+	return cmd
+}
+
+// readinessReport is the launch readiness of a genesis file.
+type readinessReport struct {
+	ChainID     string    `json:"chain_id"`
+	GenesisTime time.Time `json:"genesis_time"`
+	// TimeToGenesis is the duration left until the genesis time when the
+	// report was made, negative once it passed.
+	TimeToGenesis string `json:"time_to_genesis"`
+	BlockTime     string `json:"expected_block_time"`
+	// BlockTimeFromMint tells whether the block time is implied by the mint
+	// blocks_per_year rather than given.
+	BlockTimeFromMint bool             `json:"block_time_from_mint"`
+	Checks            []readinessCheck `json:"checks"`
+	TotalPower        int64            `json:"total_power"`
+	// OnlineValidators is the smallest set of validators controlling more
+	// than 2/3 of the power, by decreasing power.
+	OnlineValidators []readinessValidator `json:"online_validators"`
+	Findings         []genesis.Finding    `json:"findings"`
+	// Ready is set when every check passed, no finding is an error and the
+	// chain has bonded validators.
+	Ready bool `json:"ready"`
+}
+
+// readinessCheck is the outcome of a consistency check of the params.
+type readinessCheck struct {
+	Check  string `json:"check"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+type readinessValidator struct {
+	OperatorAddress string  `json:"operator_address"`
+	Moniker         string  `json:"moniker"`
+	Power           int64   `json:"power"`
+	CumulativeShare sdk.Dec `json:"cumulative_share"`
+}
+
+// newReadinessReport reports the readiness of doc at now. The block time is
+// implied by the mint blocks_per_year if blockTime is zero.
+func newReadinessReport(doc *genesis.Document, power *powerReport, now time.Time, blockTime time.Duration) (*readinessReport, error) {
+	genDoc := doc.GenesisDoc()
+	report := &readinessReport{
+		ChainID:          genDoc.ChainID,
+		GenesisTime:      genDoc.GenesisTime,
+		TimeToGenesis:    genDoc.GenesisTime.Sub(now).Round(time.Second).String(),
+		Checks:           []readinessCheck{},
+		TotalPower:       power.TotalPower,
+		OnlineValidators: []readinessValidator{},
+		Findings:         doc.Validate(),
+	}
+
+	var stakingGenesis staking.GenesisState
+	if err := doc.Module(staking.ModuleName, &stakingGenesis); err != nil {
+		return nil, err
+	}
+	var mintGenesis mint.GenesisState
+	if err := doc.Module(mint.ModuleName, &mintGenesis); err != nil {
+		return nil, err
+	}
+	blocksPerYearParam := mintGenesis.Params.BlocksPerYear
+
+	if blockTime <= 0 {
+		if blocksPerYearParam == 0 {
+			return nil, fmt.Errorf("mint blocks_per_year is 0, give the --%s", flagBlockTime)
+		}
+		blockTime = mintYear / time.Duration(blocksPerYearParam)
+		report.BlockTimeFromMint = true
+	}
+	report.BlockTime = blockTime.String()
+
+	unbondingTime := stakingGenesis.Params.UnbondingTime
+	evidence := genDoc.ConsensusParams.Evidence
+	evidenceBlocksAge := time.Duration(evidence.MaxAgeNumBlocks) * blockTime
+
+	// evidence older than the unbonding time can no longer slash the stake
+	// that committed the infraction
+	report.Checks = append(report.Checks,
+		readinessCheck{
+			Check:  "evidence max_age_duration within the unbonding time",
+			OK:     evidence.MaxAgeDuration <= unbondingTime,
+			Detail: fmt.Sprintf("max_age_duration %s, unbonding time %s", evidence.MaxAgeDuration, unbondingTime),
+		},
+		readinessCheck{
+			Check:  "evidence max_age_num_blocks within the unbonding time",
+			OK:     evidenceBlocksAge <= unbondingTime,
+			Detail: fmt.Sprintf("%d blocks of %s last %s, unbonding time %s", evidence.MaxAgeNumBlocks, blockTime, evidenceBlocksAge, unbondingTime),
+		},
+	)
+
+	if !report.BlockTimeFromMint {
+		expected := blocksPerYear(blockTime)
+		ok := blocksPerYearParam > 0
+		if ok {
+			deviation := sdk.NewDec(int64(blocksPerYearParam) - int64(expected)).Abs().QuoInt64(int64(expected))
+			ok = deviation.LTE(mintBlocksPerYearTolerance)
+		}
+
+		report.Checks = append(report.Checks, readinessCheck{
+			Check:  "mint blocks_per_year matches the block time",
+			OK:     ok,
+			Detail: fmt.Sprintf("blocks_per_year %d, %d blocks of %s per year", blocksPerYearParam, expected, blockTime),
+		})
+	}
+
+	for _, val := range power.Validators[:power.TwoThirdsValidators] {
+		report.OnlineValidators = append(report.OnlineValidators, readinessValidator{
+			OperatorAddress: val.OperatorAddress,
+			Moniker:         val.Moniker,
+			Power:           val.Power,
+			CumulativeShare: val.CumulativeShare,
+		})
+	}
+
+	report.Ready = power.TotalPower > 0 && len(genesis.Errors(report.Findings)) == 0
+	for _, check := range report.Checks {
+		report.Ready = report.Ready && check.OK
+	}
+
+	return report, nil
+}
+
+func (r *readinessReport) write(out io.Writer) error {
+	fmt.Fprintf(out, "chain %s, genesis time %s, in %s\n", r.ChainID, r.GenesisTime.Format(time.RFC3339), r.TimeToGenesis)
+
+	blockTime := r.BlockTime
+	if r.BlockTimeFromMint {
+		blockTime += " (from the mint blocks_per_year)"
+	}
+	fmt.Fprintf(out, "expected block time %s\n\n", blockTime)
+
+	for _, check := range r.Checks {
+		status := "ok"
+		if !check.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(out, "%-4s  %s: %s\n", status, check.Check, check.Detail)
+	}
+
+	fmt.Fprintf(out, "\n%d validators must be online for more than 2/3 of the total power %d:\n", len(r.OnlineValidators), r.TotalPower)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tVALIDATOR\tMONIKER\tPOWER\tCUMULATIVE")
+	for i, val := range r.OnlineValidators {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", i+1, val.OperatorAddress, val.Moniker, val.Power, formatPercent(val.CumulativeShare))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n%d findings of genesis validate\n", len(r.Findings))
+	for _, finding := range r.Findings {
+		fmt.Fprintf(out, "%s  %s  %s: %s\n", finding.Code, finding.Severity, finding.Module, finding.Message)
+	}
+
+	ready := "ready"
+	if !r.Ready {
+		ready = "NOT ready"
+	}
+	fmt.Fprintf(out, "\nthe genesis is %s for block one\n", ready)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+func readinessOf(t *testing.T, genDoc *tmtypes.GenesisDoc, now time.Time, blockTime time.Duration) *readinessReport {
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	doc, err := genesis.Load(bytes.NewReader(bz))
+	require.NoError(t, err)
+	power, err := readPowerReport(bytes.NewReader(bz))
+	require.NoError(t, err)
+
+	report, err := newReadinessReport(doc, power, now, blockTime)
+	require.NoError(t, err)
+	return report
+}
+
+func TestReadinessOnlineValidators(t *testing.T) {
+	for _, tc := range []struct {
+		powers []int64
+		online []int64
+	}{
+		{powers: []int64{5, 40, 10, 25, 5, 15}, online: []int64{40, 25, 15}},
+		// exactly 2/3 of the power does not make progress
+		{powers: []int64{10, 10, 30, 10}, online: []int64{30, 10, 10}},
+		{powers: []int64{10, 10, 10}, online: []int64{10, 10, 10}},
+		{powers: []int64{70, 10, 10, 10}, online: []int64{70}},
+		{powers: []int64{100}, online: []int64{100}},
+	} {
+		t.Run(fmt.Sprint(tc.powers), func(t *testing.T) {
+			genDoc, _ := buildTestGenesis(t, genesisbuilder.New().WithValidatorPowers(tc.powers...))
+			report := readinessOf(t, genDoc, genesisbuilder.GenesisTime, 0)
+
+			var total int64
+			for _, power := range tc.powers {
+				total += power
+			}
+			require.Equal(t, total, report.TotalPower)
+
+			online := make([]int64, len(report.OnlineValidators))
+			for i, val := range report.OnlineValidators {
+				online[i] = val.Power
+			}
+			require.Equal(t, tc.online, online)
+		})
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	genDoc, _ := buildTestGenesis(t, genesisbuilder.New().WithValidators(3))
+
+	report := readinessOf(t, genDoc, genesisbuilder.GenesisTime.Add(-90*time.Minute), 0)
+	require.Equal(t, "1h30m0s", report.TimeToGenesis)
+	require.Equal(t, "5s", report.BlockTime)
+	require.True(t, report.BlockTimeFromMint)
+	require.Len(t, report.Checks, 2)
+	for _, check := range report.Checks {
+		require.True(t, check.OK, check.Detail)
+	}
+	require.Empty(t, report.Findings)
+	require.True(t, report.Ready)
+
+	// a given block time is checked against the mint blocks_per_year
+	report = readinessOf(t, genDoc, genesisbuilder.GenesisTime, 4*time.Second)
+	require.Equal(t, readinessCheck{
+		Check:  "mint blocks_per_year matches the block time",
+		Detail: "blocks_per_year 6311520, 7889400 blocks of 4s per year",
+	}, report.Checks[2])
+	require.False(t, report.Ready)
+
+	// evidence older than the unbonding time cannot slash
+	genDoc.ConsensusParams.Evidence.MaxAgeDuration = 1000 * time.Hour
+	genDoc.ConsensusParams.Evidence.MaxAgeNumBlocks = 1000000
+	report = readinessOf(t, genDoc, genesisbuilder.GenesisTime.Add(time.Hour), 0)
+	require.Equal(t, "-1h0m0s", report.TimeToGenesis)
+	require.Equal(t, []readinessCheck{
+		{Check: "evidence max_age_duration within the unbonding time", Detail: "max_age_duration 1000h0m0s, unbonding time 504h0m0s"},
+		{Check: "evidence max_age_num_blocks within the unbonding time", Detail: "1000000 blocks of 5s last 1388h53m20s, unbonding time 504h0m0s"},
+	}, report.Checks)
+	require.False(t, report.Ready)
+}
+
+func TestGenesisReadinessCmd(t *testing.T) {
+	genDoc, _ := buildTestGenesis(t, genesisbuilder.New().WithValidatorPowers(60, 30, 10))
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	execute := func(cmd *cobra.Command, args ...string) string {
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	out := execute(GenesisReadinessCmd(), path)
+	require.Contains(t, out, "chain "+genesisbuilder.ChainID+", genesis time "+genesisbuilder.GenesisTime.Format(time.RFC3339))
+	require.Contains(t, out, "expected block time 5s (from the mint blocks_per_year)\n")
+	require.Contains(t, out, "ok    evidence max_age_duration within the unbonding time: max_age_duration 48h0m0s, unbonding time 504h0m0s\n")
+	require.Contains(t, out, "2 validators must be online for more than 2/3 of the total power 100:\n")
+	require.Contains(t, out, "0 findings of genesis validate\n")
+	require.Contains(t, out, "the genesis is ready for block one\n")
+
+	var report readinessReport
+	require.NoError(t, json.Unmarshal([]byte(execute(GenesisReadinessCmd(), path, "--format", "json", "--expected-block-time", "5s")), &report))
+	require.False(t, report.BlockTimeFromMint)
+	require.Len(t, report.Checks, 3)
+	require.Len(t, report.OnlineValidators, 2)
+	require.Equal(t, "0.900000000000000000", report.OnlineValidators[1].CumulativeShare.String())
+	require.True(t, report.Ready)
+}
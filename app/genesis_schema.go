@@ -0,0 +1,265 @@
+package gaia
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+const flagExample = "example"
+
+// schemaExamples holds an example file of every genesis schema, named
+// <name>.json.
+//
+//go:embed schemas/*.json
+var schemaExamples embed.FS
+
+// genesisSchema describes a file read or written by the genesis tooling.
+// Version is bumped on every incompatible change of its shape.
+type genesisSchema struct {
+	Name        string
+	Version     int
+	Description string
+	Value       interface{}
+	// Items describes the items of a top-level array without a struct to
+	// describe them.
+	Items string
+}
+
+var genesisSchemas = []genesisSchema{
+	{
+		Name:        "blocked-addresses",
+		Version:     1,
+		Description: fmt.Sprintf("Addresses whose funds migrate --%s moves away", flagBlockedAddresses),
+		Value:       []string{},
+		Items:       "Bech32 account address",
+	},
+	{
+		Name:        "manifest",
+		Version:     1,
+		Description: "Manifest of a migrated genesis file written by migrate --manifest and read by genesis verify-published",
+		Value:       migrationManifest{},
+	},
+	{
+		Name:        "prop29-data",
+		Version:     1,
+		Description: fmt.Sprintf("Fund recovery entries applied by migrate --%s", flagProp29Data),
+		Value:       []recoveryEntry{},
+	},
+	{
+		Name:        "replacement-cons-keys",
+		Version:     1,
+		Description: fmt.Sprintf("Validator consensus keys replaced by migrate --%s", flagReplacementKeys),
+		Value:       []replacementConfig{},
+	},
+	{
+		Name:        "warnings",
+		Version:     1,
+		Description: "Findings of the migration checks and of genesis validate",
+		Value:       []genesis.Finding{},
+	},
+}
+
+// jsonSchema is the subset of JSON Schema the genesis schemas use.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// GenesisSchemaCmd returns a command printing the JSON Schema of the files
+// read and written by the genesis tooling.
+func GenesisSchemaCmd() *cobra.Command {
+	names := make([]string, len(genesisSchemas))
+	for i, schema := range genesisSchemas {
+		names[i] = schema.Name
+	}
+
+	cmd := &cobra.Command{
+		Use:   "schema [name]",
+		Short: "Print the JSON Schema of a file read or written by the genesis tooling",
+		Long: fmt.Sprintf(`Print the JSON Schema of a file read or written by migrate or the genesis
+subcommands, generated from the types decoding it. The $id of the schema carries
+its version, bumped on every incompatible change. --%s prints an example file
+instead. The schemas are: %s.
+
+Example:
+$ %s genesis schema prop29-data
+`, flagExample, strings.Join(names, ", "), version.AppName),
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: names,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, ok := findGenesisSchema(args[0])
+			if !ok {
+				return fmt.Errorf("unknown schema %q, expected one of %s", args[0], strings.Join(names, ", "))
+			}
+
+			if example, _ := cmd.Flags().GetBool(flagExample); example {
+				bz, err := schemaExamples.ReadFile("schemas/" + schema.Name + ".json")
+				if err != nil {
+					return err
+				}
+
+				cmd.Print(string(bz))
+				return nil
+			}
+
+			s, err := schema.JSONSchema()
+			if err != nil {
+				return err
+			}
+
+			bz, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			cmd.Println(string(bz))
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool(flagExample, false, "Print an example file instead of the schema")
+
+	return cmd
+}
+
+func findGenesisSchema(name string) (genesisSchema, bool) {
+	for _, schema := range genesisSchemas {
+		if schema.Name == name {
+			return schema, true
+		}
+	}
+
+	return genesisSchema{}, false
+}
+
+// JSONSchema returns the JSON Schema of the file, generated from the json,
+// desc, enum and pattern tags of its type.
+func (s genesisSchema) JSONSchema() (*jsonSchema, error) {
+	root, err := typeSchema(reflect.TypeOf(s.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Items != nil && s.Items != "" {
+		root.Items.Description = s.Items
+	}
+
+	root.Schema = "http://json-schema.org/draft-07/schema#"
+	root.ID = fmt.Sprintf("urn:gaia:genesis:schema:%s:v%d", s.Name, s.Version)
+	root.Title = s.Name
+	root.Description = s.Description
+
+	return root, nil
+}
+
+var (
+	coinType = reflect.TypeOf(sdk.Coin{})
+	intType  = reflect.TypeOf(sdk.Int{})
+	decType  = reflect.TypeOf(sdk.Dec{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// typeSchema returns the schema of the JSON encoding of t.
+func typeSchema(t reflect.Type) (*jsonSchema, error) {
+	closed := false
+
+	switch t {
+	case coinType:
+		return &jsonSchema{
+			Type: "object",
+			Properties: map[string]*jsonSchema{
+				"denom":  {Type: "string", Pattern: "^[a-zA-Z][a-zA-Z0-9/]{2,127}$"},
+				"amount": {Type: "string", Pattern: "^[0-9]+$"},
+			},
+			Required:             []string{"amount", "denom"},
+			AdditionalProperties: &closed,
+		}, nil
+	case intType:
+		return &jsonSchema{Type: "string", Pattern: "^-?[0-9]+$"}, nil
+	case decType:
+		return &jsonSchema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]+)?$`}, nil
+	case timeType:
+		return &jsonSchema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return &jsonSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "array", Items: items}, nil
+	case reflect.Struct:
+		schema := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema), AdditionalProperties: &closed}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts := parseJSONTag(field.Tag.Get("json"))
+			if name == "-" || field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			property, err := typeSchema(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+			}
+			property.Description = field.Tag.Get("desc")
+			if pattern := field.Tag.Get("pattern"); pattern != "" {
+				property.Pattern = pattern
+			}
+			if enum := field.Tag.Get("enum"); enum != "" {
+				property.Enum = strings.Split(enum, ",")
+			}
+
+			schema.Properties[name] = property
+			if !strings.Contains(opts, "omitempty") {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		sort.Strings(schema.Required)
+
+		return schema, nil
+	default:
+		return nil, fmt.Errorf("type %s has no JSON Schema", t)
+	}
+}
+
+func parseJSONTag(tag string) (name, opts string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+
+	return tag, ""
+}
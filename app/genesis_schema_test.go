@@ -0,0 +1,196 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// validateJSONSchema returns where v, a decoded JSON value, does not match
+// schema. It covers the JSON Schema subset of jsonSchema.
+func validateJSONSchema(schema *jsonSchema, v interface{}, path string) []string {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("not an object")
+			return problems
+		}
+
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				fail("missing %s", name)
+			}
+		}
+
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					fail("unknown property %s", name)
+				}
+				continue
+			}
+			problems = append(problems, validateJSONSchema(property, obj[name], path+"."+name)...)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			fail("not an array")
+			return problems
+		}
+
+		for i, item := range items {
+			problems = append(problems, validateJSONSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			fail("not a string")
+			return problems
+		}
+
+		if schema.Pattern != "" && !regexp.MustCompile(schema.Pattern).MatchString(s) {
+			fail("%q does not match %s", s, schema.Pattern)
+		}
+		if len(schema.Enum) > 0 {
+			found := false
+			for _, value := range schema.Enum {
+				found = found || value == s
+			}
+			if !found {
+				fail("%q is not one of %v", s, schema.Enum)
+			}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			fail("not an integer")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("not a boolean")
+		}
+	}
+
+	return problems
+}
+
+func schemaExample(t *testing.T, name string) []byte {
+	bz, err := schemaExamples.ReadFile("schemas/" + name + ".json")
+	require.NoError(t, err)
+	return bz
+}
+
+func TestGenesisSchemaExamples(t *testing.T) {
+	for _, schema := range genesisSchemas {
+		schema := schema
+		t.Run(schema.Name, func(t *testing.T) {
+			s, err := schema.JSONSchema()
+			require.NoError(t, err)
+
+			var example interface{}
+			require.NoError(t, json.Unmarshal(schemaExample(t, schema.Name), &example))
+			require.Empty(t, validateJSONSchema(s, example, "$"))
+		})
+	}
+}
+
+func TestGenesisSchemaExamplesLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) string {
+		path := filepath.Join(dir, name+".json")
+		require.NoError(t, ioutil.WriteFile(path, schemaExample(t, name), 0644))
+		return path
+	}
+
+	addresses, err := loadBlockedAddresses(write("blocked-addresses"))
+	require.NoError(t, err)
+	require.Len(t, addresses, 2)
+
+	manifest, err := loadMigrationManifest(write("manifest"))
+	require.NoError(t, err)
+	require.Equal(t, "cosmoshub-4", manifest.ChainID)
+	require.NoError(t, verifyManifestStageChain(manifest))
+
+	entries, err := loadRecoveryEntries(write("prop29-data"), "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, recoveryVestingPeriodic, entries[1].Vesting.Type)
+
+	strict := func(name string, v interface{}) {
+		dec := json.NewDecoder(bytes.NewReader(schemaExample(t, name)))
+		dec.DisallowUnknownFields()
+		require.NoError(t, dec.Decode(v), name)
+	}
+
+	var replacements replacementConfigs
+	strict("replacement-cons-keys", &replacements)
+	require.Len(t, replacements, 1)
+
+	var warnings []migrationWarning
+	strict("warnings", &warnings)
+	require.Len(t, warnings, 2)
+}
+
+func TestGenesisSchemaRejects(t *testing.T) {
+	schema, ok := findGenesisSchema("prop29-data")
+	require.True(t, ok)
+	s, err := schema.JSONSchema()
+	require.NoError(t, err)
+
+	var entries interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[{"from": "a", "too": "b", "amount": [{"denom": "uatom", "amount": "-1"}], "vesting": {"type": "linear"}}]`), &entries))
+	require.Equal(t, []string{
+		"$[0]: missing to",
+		`$[0].amount[0].amount: "-1" does not match ^[0-9]+$`,
+		"$[0]: unknown property too",
+		`$[0].vesting.type: "linear" is not one of [delayed periodic]`,
+	}, validateJSONSchema(s, entries, "$"))
+}
+
+func TestGenesisSchemaCmd(t *testing.T) {
+	execute := func(args ...string) (string, error) {
+		cmd := GenesisSchemaCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := execute("manifest")
+	require.NoError(t, err)
+
+	var s jsonSchema
+	require.NoError(t, json.Unmarshal([]byte(out), &s))
+	require.Equal(t, "urn:gaia:genesis:schema:manifest:v1", s.ID)
+	require.Equal(t, "date-time", s.Properties["genesis_time"].Format)
+	require.Len(t, s.Required, 5)
+
+	out, err = execute("manifest", "--example")
+	require.NoError(t, err)
+	require.Equal(t, string(schemaExample(t, "manifest")), out)
+
+	_, err = execute("add-accounts")
+	require.EqualError(t, err, `unknown schema "add-accounts", expected one of blocked-addresses, manifest, prop29-data, replacement-cons-keys, warnings`)
+}
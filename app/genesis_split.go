@@ -0,0 +1,275 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+const (
+	flagOutDir     = "out-dir"
+	flagSplitDir   = "dir"
+	flagOutputFile = "output"
+)
+
+// splitHeaderFile is the file of a split genesis holding its chain-level
+// fields and the list of module files.
+const splitHeaderFile = "header.json"
+
+// splitHeader is the content of splitHeaderFile. Genesis holds every genesis
+// doc field but app_state, whose modules are stored in <module>.json files.
+type splitHeader struct {
+	Modules []string                   `json:"modules"`
+	Genesis map[string]json.RawMessage `json:"genesis"`
+}
+
+// GenesisSplitCmd returns a command writing the app state modules of a genesis
+// to separate files.
+func GenesisSplitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "split [genesis-file]",
+		Short: "Split a genesis file into a file per app state module",
+		Long: fmt.Sprintf(`Write the state of every app state module of the genesis file into an indented
+<module>.json file of --out-dir and the other genesis fields into %s, so
+modules can be reviewed and diffed on their own. Pass - as the genesis file to
+read it from STDIN.
+
+Example:
+$ %s genesis split genesis.json --out-dir genesis/
+`, splitHeaderFile, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outDir, _ := cmd.Flags().GetString(flagOutDir)
+			if outDir == "" {
+				return fmt.Errorf("--%s is required", flagOutDir)
+			}
+			files, err := outputFilesFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			bz, err := ioutil.ReadAll(input)
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+
+			header, modules, err := splitGenesis(bz)
+			if err != nil {
+				return err
+			}
+
+			if err := files.prepareDir(outDir); err != nil {
+				return err
+			}
+
+			headerBz, moduleFiles, err := splitFiles(header, modules)
+			if err != nil {
+				return err
+			}
+
+			if err := files.WriteFile(filepath.Join(outDir, splitHeaderFile), headerBz, outputFileMode); err != nil {
+				return err
+			}
+
+			for _, module := range header.Modules {
+				if err := files.WriteFile(filepath.Join(outDir, module+".json"), moduleFiles[module], outputFileMode); err != nil {
+					return err
+				}
+			}
+
+			cmd.PrintErrf("wrote %s and %d module files to %s\n", splitHeaderFile, len(header.Modules), outDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagOutDir, "", "Directory to write the header and module files to")
+	addOutputFileFlags(cmd)
+
+	return cmd
+}
+
+// GenesisJoinCmd returns a command reassembling a genesis written by
+// GenesisSplitCmd.
+func GenesisJoinCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "join",
+		Short: "Reassemble a genesis file split by genesis split",
+		Long: fmt.Sprintf(`Read %s and the module files of --dir, validate the reassembled genesis
+and write it, with sorted keys, to --output or STDOUT. Joining fails if a module
+listed in %s has no file or a file is not valid JSON. The genesis is validated
+as genesis validate does, with its --bech32-prefix.
+
+Example:
+$ %s genesis join --dir genesis/ --output genesis.json
+`, splitHeaderFile, splitHeaderFile, version.AppName),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString(flagSplitDir)
+			if dir == "" {
+				return fmt.Errorf("--%s is required", flagSplitDir)
+			}
+			files, err := outputFilesFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			bz, err := joinGenesis(dir)
+			if err != nil {
+				return err
+			}
+
+			doc, err := genesis.Load(bytes.NewReader(bz))
+			if err != nil {
+				return errors.Wrap(err, "joined genesis is invalid")
+			}
+			prefix, _ := cmd.Flags().GetString(flagBech32Prefix)
+			doc = doc.WithBech32Prefix(prefix)
+
+			var invalid []string
+			for _, finding := range genesis.Errors(doc.Validate()) {
+				invalid = append(invalid, fmt.Sprintf("%s: %s", finding.Module, finding.Message))
+			}
+			if len(invalid) > 0 {
+				return fmt.Errorf("joined app state is invalid: %s", strings.Join(invalid, "; "))
+			}
+
+			output, _ := cmd.Flags().GetString(flagOutputFile)
+			if output == "" {
+				return writeGenesisOutput(cmd.OutOrStdout(), bz)
+			}
+
+			return files.Write(output, outputFileMode, func(w io.Writer) error {
+				return writeGenesisOutput(w, bz)
+			}, nil)
+		},
+	}
+
+	cmd.Flags().String(flagSplitDir, "", "Directory written by genesis split")
+	cmd.Flags().String(flagOutputFile, "", "File to write the joined genesis to instead of STDOUT")
+	addOutputFileFlags(cmd)
+	cmd.Flags().String(flagBech32Prefix, sdk.Bech32MainPrefix, "Bech32 prefix of the account addresses of the chain of the genesis")
+
+	return cmd
+}
+
+// splitGenesis separates the app state modules of a genesis doc from its other
+// fields. The module names are sorted.
+func splitGenesis(bz []byte) (splitHeader, map[string]json.RawMessage, error) {
+	var header splitHeader
+	if err := json.Unmarshal(bz, &header.Genesis); err != nil {
+		return header, nil, errors.Wrap(err, "failed to decode genesis")
+	}
+
+	var modules map[string]json.RawMessage
+	if appState, ok := header.Genesis["app_state"]; ok {
+		if err := json.Unmarshal(appState, &modules); err != nil {
+			return header, nil, errors.Wrap(err, "failed to decode app_state")
+		}
+		delete(header.Genesis, "app_state")
+	}
+
+	for module := range modules {
+		if module == "" || module != filepath.Base(module) || strings.HasPrefix(module, ".") || module+".json" == splitHeaderFile {
+			return header, nil, fmt.Errorf("module %q cannot be used as a file name", module)
+		}
+		header.Modules = append(header.Modules, module)
+	}
+	sort.Strings(header.Modules)
+
+	return header, modules, nil
+}
+
+// splitFiles returns the content of the splitHeaderFile of a split genesis and
+// of its module files, by module.
+func splitFiles(header splitHeader, modules map[string]json.RawMessage) ([]byte, map[string][]byte, error) {
+	headerBz, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make(map[string][]byte, len(header.Modules))
+	for _, module := range header.Modules {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, modules[module], "", "  "); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to indent %s state", module)
+		}
+		buf.WriteByte('\n')
+		files[module] = buf.Bytes()
+	}
+
+	return append(headerBz, '\n'), files, nil
+}
+
+// joinGenesis reassembles the genesis doc split into dir and returns it with
+// sorted keys.
+func joinGenesis(dir string) ([]byte, error) {
+	headerBz, err := ioutil.ReadFile(filepath.Join(dir, splitHeaderFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var header splitHeader
+	if err := json.Unmarshal(headerBz, &header); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", splitHeaderFile)
+	}
+
+	if header.Genesis == nil {
+		return nil, fmt.Errorf("%s has no genesis fields", splitHeaderFile)
+	}
+
+	modules := make(map[string]json.RawMessage, len(header.Modules))
+	for _, module := range header.Modules {
+		if module != filepath.Base(module) {
+			return nil, fmt.Errorf("%s lists invalid module %q", splitHeaderFile, module)
+		}
+
+		path := filepath.Join(dir, module+".json")
+		bz, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("module %s is listed in %s but %s is missing", module, splitHeaderFile, path)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !json.Valid(bz) {
+			return nil, fmt.Errorf("%s is not valid JSON", path)
+		}
+
+		modules[module] = bytes.TrimSpace(bz)
+	}
+
+	appState, err := json.Marshal(modules)
+	if err != nil {
+		return nil, err
+	}
+	if appState, err = canonicalAppState(MakeEncodingConfig().Marshaler, appState); err != nil {
+		return nil, err
+	}
+	header.Genesis["app_state"] = appState
+
+	bz, err := json.Marshal(header.Genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonicalJSON(bz, true)
+}
@@ -0,0 +1,132 @@
+package gaia
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+)
+
+func runGenesisCmd(cmd *cobra.Command, args ...string) error {
+	cmd.SetArgs(args)
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+
+	return cmd.Execute()
+}
+
+// writeSortedTestGenesis writes the test genesis the way migrate prints it.
+func writeSortedTestGenesis(t *testing.T, path string) []byte {
+	genDoc, err := testGenesisBuilder().Build()
+	require.NoError(t, err)
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	sorted, err := sdk.SortJSON(bz)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeGenesisOutput(&buf, sorted))
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0600))
+
+	return buf.Bytes()
+}
+
+func TestGenesisSplitJoin(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	original := writeSortedTestGenesis(t, genesisPath)
+
+	splitDir := filepath.Join(dir, "split")
+	require.NoError(t, runGenesisCmd(GenesisSplitCmd(), genesisPath, "--out-dir", splitDir))
+
+	require.FileExists(t, filepath.Join(splitDir, splitHeaderFile))
+	bankBz, err := ioutil.ReadFile(filepath.Join(splitDir, bank.ModuleName+".json"))
+	require.NoError(t, err)
+	require.Contains(t, string(bankBz), "\n  \"balances\": [")
+
+	joinedPath := filepath.Join(dir, "joined.json")
+	require.NoError(t, runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir, "--output", joinedPath))
+
+	joined, err := ioutil.ReadFile(joinedPath)
+	require.NoError(t, err)
+	require.Equal(t, string(original), string(joined))
+
+	// the header and module files keep numbers sdk.SortJSON would round
+	header, modules, err := splitGenesis([]byte(`{"initial_height":"1","app_state":{"big":{"n":18446744073709551615}}}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"big"}, header.Modules)
+	require.Equal(t, `{"n":18446744073709551615}`, string(modules["big"]))
+}
+
+func TestGenesisSplitJoinFileMode(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	writeSortedTestGenesis(t, genesisPath)
+
+	splitDir := filepath.Join(dir, "split")
+	require.NoError(t, runGenesisCmd(GenesisSplitCmd(), genesisPath, "--out-dir", splitDir, "--file-mode", "0600"))
+	info, err := os.Stat(filepath.Join(splitDir, splitHeaderFile))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	linkedDir := filepath.Join(dir, "linked")
+	require.NoError(t, os.Symlink(t.TempDir(), linkedDir))
+	require.EqualError(t, runGenesisCmd(GenesisSplitCmd(), genesisPath, "--out-dir", linkedDir), "refusing to write "+linkedDir+": it is a symlink")
+
+	joinedPath := filepath.Join(dir, "launch", "joined.json")
+	require.Error(t, runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir, "--output", joinedPath))
+	require.NoError(t, runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir, "--output", joinedPath, "--create-dirs"))
+	info, err = os.Stat(joinedPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestGenesisJoinErrors(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	writeSortedTestGenesis(t, genesisPath)
+
+	split := func(t *testing.T) string {
+		splitDir := filepath.Join(t.TempDir(), "split")
+		require.NoError(t, runGenesisCmd(GenesisSplitCmd(), genesisPath, "--out-dir", splitDir))
+		return splitDir
+	}
+
+	t.Run("invalid module JSON", func(t *testing.T) {
+		splitDir := split(t)
+		path := filepath.Join(splitDir, bank.ModuleName+".json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(`{"balances": [`), 0600))
+
+		err := runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), path+" is not valid JSON")
+	})
+
+	t.Run("missing module", func(t *testing.T) {
+		splitDir := split(t)
+		require.NoError(t, os.Remove(filepath.Join(splitDir, bank.ModuleName+".json")))
+
+		err := runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "module bank is listed in header.json")
+	})
+
+	t.Run("invalid module state", func(t *testing.T) {
+		splitDir := split(t)
+		path := filepath.Join(splitDir, bank.ModuleName+".json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(`{"balances": [{"address": "invalid", "coins": []}]}`), 0600))
+
+		err := runGenesisCmd(GenesisJoinCmd(), "--dir", splitDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "joined app state is invalid")
+	})
+}
@@ -0,0 +1,269 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagUpgradeName    = "name"
+	flagHaltHeight     = "halt-height"
+	flagBinaries       = "binaries"
+	flagVerifyBinaries = "verify-binaries"
+)
+
+// binaryPlatformPattern matches the os/arch keys of the cosmovisor binaries,
+// any being the binary of every platform.
+var binaryPlatformPattern = regexp.MustCompile(`^(any|[a-z0-9]+/[a-z0-9]+)$`)
+
+// upgradeBinary is a binary of the --binaries file.
+type upgradeBinary struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// upgradeInfo is the upgrade-info.json cosmovisor reads when the chain halts,
+// the Info being the JSON of the binaries to download.
+type upgradeInfo struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height"`
+	Info   string `json:"info"`
+}
+
+// upgradeBinariesInfo is the Info of an upgradeInfo, the cosmovisor URLs of
+// the binaries by platform.
+type upgradeBinariesInfo struct {
+	Binaries map[string]string `json:"binaries"`
+}
+
+// GenesisUpgradeInfoCmd returns a command generating the upgrade-info.json of
+// a coordinated halt for cosmovisor.
+func GenesisUpgradeInfoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade-info",
+		Short: "Generate the cosmovisor upgrade-info.json of a coordinated halt",
+		Long: fmt.Sprintf(`Generate the upgrade-info.json cosmovisor reads to switch to the binary of the
+upgrade once the chain halts at --halt-height, for the export based migration.
+The --binaries file, or inline JSON, gives the URL and hex encoded SHA-256 of
+the binary of every os/arch platform, or of any:
+
+{"linux/amd64": {"url": "https://example.com/gaiad-v5-linux-amd64", "sha256": "..."}}
+
+The checksums are added to the URLs as cosmovisor verifies them. The binaries
+are downloaded and hashed with --verify-binaries.
+
+Example:
+$ %s genesis upgrade-info --halt-height 6910000 --name v5 --binaries binaries.json
+`, version.AppName),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString(flagUpgradeName)
+			height, _ := cmd.Flags().GetInt64(flagHaltHeight)
+			source, _ := cmd.Flags().GetString(flagBinaries)
+
+			binaries, err := loadUpgradeBinaries(source)
+			if err != nil {
+				return err
+			}
+
+			if verify, _ := cmd.Flags().GetBool(flagVerifyBinaries); verify {
+				timeout, _ := cmd.Flags().GetDuration(flagDownloadTimeout)
+				for _, platform := range sortedPlatforms(binaries) {
+					if err := verifyUpgradeBinary(cmd.Context(), binaries[platform], timeout); err != nil {
+						return errors.Wrapf(err, "binary of %s", platform)
+					}
+					cmd.PrintErrf("verified the binary of %s\n", platform)
+				}
+			}
+
+			info, err := newUpgradeInfo(name, height, binaries)
+			if err != nil {
+				return err
+			}
+
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(info); err != nil {
+				return errors.Wrap(err, "failed to JSON marshal upgrade info")
+			}
+
+			if output, _ := cmd.Flags().GetString(flagOutputFile); output != "" {
+				files, err := outputFilesFromFlags(cmd.Flags())
+				if err != nil {
+					return err
+				}
+				return files.WriteFile(output, buf.Bytes(), outputFileMode)
+			}
+
+			cmd.Print(buf.String())
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagUpgradeName, "", "Name of the upgrade, the cosmovisor upgrades directory of its binary")
+	cmd.Flags().Int64(flagHaltHeight, 0, "Height the chain halts at for the upgrade")
+	cmd.Flags().String(flagBinaries, "", "JSON file, or inline JSON, of the URL and sha256 of the binary of every platform")
+	cmd.Flags().Bool(flagVerifyBinaries, false, "Download every binary and check its sha256")
+	cmd.Flags().Duration(flagDownloadTimeout, time.Minute, "Abort a binary download once it received no data for this long, 0 to wait forever")
+	cmd.Flags().String(flagOutputFile, "", "Write the upgrade-info.json to this file instead of STDOUT")
+	addOutputFileFlags(cmd)
+
+	return cmd
+}
+
+// loadUpgradeBinaries reads the binaries of the --binaries file, or of the
+// inline JSON object, and validates them.
+func loadUpgradeBinaries(source string) (map[string]upgradeBinary, error) {
+	if source == "" {
+		return nil, fmt.Errorf("--%s is required", flagBinaries)
+	}
+
+	bz := []byte(source)
+	if !strings.HasPrefix(strings.TrimSpace(source), "{") {
+		var err error
+		if bz, err = ioutil.ReadFile(source); err != nil {
+			return nil, errors.Wrapf(err, "failed to read --%s", flagBinaries)
+		}
+	}
+
+	var binaries map[string]upgradeBinary
+	decoder := json.NewDecoder(bytes.NewReader(bz))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&binaries); err != nil {
+		return nil, errors.Wrapf(err, "invalid --%s", flagBinaries)
+	}
+	if len(binaries) == 0 {
+		return nil, fmt.Errorf("--%s lists no binaries", flagBinaries)
+	}
+
+	for _, platform := range sortedPlatforms(binaries) {
+		if err := validateUpgradeBinary(platform, binaries[platform]); err != nil {
+			return nil, errors.Wrapf(err, "invalid --%s", flagBinaries)
+		}
+	}
+
+	return binaries, nil
+}
+
+// validateUpgradeBinary checks the platform, URL and checksum of a binary.
+func validateUpgradeBinary(platform string, binary upgradeBinary) error {
+	if !binaryPlatformPattern.MatchString(platform) {
+		return fmt.Errorf("platform %q is neither os/arch nor any", platform)
+	}
+
+	u, err := url.Parse(binary.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("binary of %s has no absolute url", platform)
+	}
+	if u.Query().Get("checksum") != "" {
+		return fmt.Errorf("url of the binary of %s already has a checksum, give it as sha256", platform)
+	}
+
+	if sum, err := hex.DecodeString(binary.SHA256); err != nil || len(sum) != 32 {
+		return fmt.Errorf("sha256 %q of the binary of %s is not 64 hex digits", binary.SHA256, platform)
+	}
+
+	return nil
+}
+
+// newUpgradeInfo returns the upgrade info of the upgrade name at height, its
+// info listing the binaries URLs with their checksums.
+func newUpgradeInfo(name string, height int64, binaries map[string]upgradeBinary) (upgradeInfo, error) {
+	if strings.TrimSpace(name) == "" {
+		return upgradeInfo{}, fmt.Errorf("--%s is required", flagUpgradeName)
+	}
+	if height <= 0 {
+		return upgradeInfo{}, fmt.Errorf("--%s must be positive", flagHaltHeight)
+	}
+
+	info := upgradeBinariesInfo{Binaries: make(map[string]string, len(binaries))}
+	for platform, binary := range binaries {
+		u, err := url.Parse(binary.URL)
+		if err != nil {
+			return upgradeInfo{}, err
+		}
+		checksum := "checksum=sha256:" + strings.ToLower(binary.SHA256)
+		if u.RawQuery != "" {
+			checksum = "&" + checksum
+		}
+		u.RawQuery += checksum
+
+		info.Binaries[platform] = u.String()
+	}
+
+	// the info is read by operators too, the & of the URLs stay unescaped
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(info); err != nil {
+		return upgradeInfo{}, err
+	}
+
+	return upgradeInfo{Name: name, Height: height, Info: strings.TrimSpace(buf.String())}, nil
+}
+
+// verifyUpgradeBinary downloads binary and fails unless it has its sha256. A
+// download receiving no data for timeout is aborted.
+func verifyUpgradeBinary(ctx context.Context, binary upgradeBinary, timeout time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, binary.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s failed: %s", binary.URL, resp.Status)
+	}
+
+	var stall *time.Timer
+	if timeout > 0 {
+		stall = time.AfterFunc(timeout, cancel)
+		defer stall.Stop()
+	}
+
+	digest := newDigestWriter()
+	if _, err := io.Copy(digest, stallReader{Reader: resp.Body, stall: stall, timeout: timeout}); err != nil {
+		return errors.Wrapf(err, "download of %s failed", binary.URL)
+	}
+
+	if !strings.EqualFold(digest.Sum(), binary.SHA256) {
+		return fmt.Errorf("%s has sha256 %s, expected %s", binary.URL, digest.Sum(), binary.SHA256)
+	}
+
+	return nil
+}
+
+// sortedPlatforms returns the platforms of binaries in alphabetical order.
+func sortedPlatforms(binaries map[string]upgradeBinary) []string {
+	platforms := make([]string, 0, len(binaries))
+	for platform := range binaries {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	return platforms
+}
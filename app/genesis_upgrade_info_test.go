@@ -0,0 +1,140 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func executeUpgradeInfo(t *testing.T, args ...string) (string, error) {
+	cmd := GenesisUpgradeInfoCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestGenesisUpgradeInfo(t *testing.T) {
+	amd64 := bytes.Repeat([]byte{0xab}, 32)
+	arm64 := bytes.Repeat([]byte{0xcd}, 32)
+	binaries := filepath.Join(t.TempDir(), "binaries.json")
+	require.NoError(t, ioutil.WriteFile(binaries, []byte(fmt.Sprintf(`{
+  "linux/amd64": {"url": "https://example.com/gaiad-v5-linux-amd64", "sha256": "%s"},
+  "linux/arm64": {"url": "https://example.com/download?file=gaiad-v5-linux-arm64", "sha256": "%X"}
+}`, hex.EncodeToString(amd64), arm64)), 0644))
+
+	out, err := executeUpgradeInfo(t, "--halt-height", "6910000", "--name", "v5", "--binaries", binaries)
+	require.NoError(t, err)
+
+	// the shape of the upgrade-info.json cosmovisor reads
+	var info struct {
+		Name   string `json:"name"`
+		Height int64  `json:"height"`
+		Info   string `json:"info"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(out)))
+	decoder.DisallowUnknownFields()
+	require.NoError(t, decoder.Decode(&info))
+	require.Equal(t, "v5", info.Name)
+	require.Equal(t, int64(6910000), info.Height)
+
+	var plan struct {
+		Binaries map[string]string `json:"binaries"`
+	}
+	decoder = json.NewDecoder(bytes.NewReader([]byte(info.Info)))
+	decoder.DisallowUnknownFields()
+	require.NoError(t, decoder.Decode(&plan))
+	require.Equal(t, map[string]string{
+		"linux/amd64": "https://example.com/gaiad-v5-linux-amd64?checksum=sha256:" + hex.EncodeToString(amd64),
+		"linux/arm64": "https://example.com/download?file=gaiad-v5-linux-arm64&checksum=sha256:" + hex.EncodeToString(arm64),
+	}, plan.Binaries)
+
+	checksumPattern := regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+	for platform, binary := range plan.Binaries {
+		u, err := url.Parse(binary)
+		require.NoError(t, err)
+		require.Regexp(t, checksumPattern, u.Query().Get("checksum"), platform)
+	}
+
+	output := filepath.Join(t.TempDir(), "upgrade-info.json")
+	_, err = executeUpgradeInfo(t, "--halt-height", "6910000", "--name", "v5", "--binaries", binaries, "--output", output)
+	require.NoError(t, err)
+	bz, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, out, string(bz))
+}
+
+func TestGenesisUpgradeInfoInvalid(t *testing.T) {
+	sum := hex.EncodeToString(make([]byte, 32))
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		binaries string
+		err      string
+	}{
+		{"no name", []string{"--halt-height", "10"}, `{"any": {"url": "https://example.com/gaiad", "sha256": "` + sum + `"}}`, "--name is required"},
+		{"no height", []string{"--name", "v5"}, `{"any": {"url": "https://example.com/gaiad", "sha256": "` + sum + `"}}`, "--halt-height must be positive"},
+		{"no binaries", nil, `{}`, "--binaries lists no binaries"},
+		{"unknown field", nil, `{"any": {"url": "https://example.com/gaiad", "sha256": "` + sum + `", "md5": "00"}}`, `invalid --binaries: json: unknown field "md5"`},
+		{"platform", nil, `{"linux": {"url": "https://example.com/gaiad", "sha256": "` + sum + `"}}`, `invalid --binaries: platform "linux" is neither os/arch nor any`},
+		{"relative url", nil, `{"any": {"url": "gaiad", "sha256": "` + sum + `"}}`, "invalid --binaries: binary of any has no absolute url"},
+		{"url checksum", nil, `{"any": {"url": "https://example.com/gaiad?checksum=sha256:` + sum + `", "sha256": "` + sum + `"}}`, "invalid --binaries: url of the binary of any already has a checksum, give it as sha256"},
+		{"short sha256", nil, `{"any": {"url": "https://example.com/gaiad", "sha256": "abcd"}}`, `invalid --binaries: sha256 "abcd" of the binary of any is not 64 hex digits`},
+		{"hex sha256", nil, `{"any": {"url": "https://example.com/gaiad", "sha256": "` + sum[:63] + `g"}}`, "is not 64 hex digits"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			args := tc.args
+			if args == nil {
+				args = []string{"--name", "v5", "--halt-height", "10"}
+			}
+
+			_, err := executeUpgradeInfo(t, append(args, "--binaries", tc.binaries)...)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestGenesisUpgradeInfoVerifyBinaries(t *testing.T) {
+	binary := []byte("gaiad v5")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gaiad" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(binary)
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(binary)
+	binaries := func(path, sha256 string) string {
+		return fmt.Sprintf(`{"linux/amd64": {"url": "%s%s", "sha256": "%s"}}`, server.URL, path, sha256)
+	}
+	args := []string{"--name", "v5", "--halt-height", "10", "--verify-binaries", "--binaries"}
+
+	_, err := executeUpgradeInfo(t, append(args, binaries("/gaiad", hex.EncodeToString(sum[:])))...)
+	require.NoError(t, err)
+
+	_, err = executeUpgradeInfo(t, append(args, binaries("/gaiad", hex.EncodeToString(make([]byte, 32))))...)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "binary of linux/amd64: "+server.URL+"/gaiad has sha256 "+hex.EncodeToString(sum[:]))
+
+	_, err = executeUpgradeInfo(t, append(args, binaries("/missing", hex.EncodeToString(sum[:])))...)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404 Not Found")
+}
@@ -0,0 +1,77 @@
+package gaia
+
+import (
+	"fmt"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+const flagBech32Prefix = "bech32-prefix"
+
+// GenesisValidateCmd returns a command validating a genesis file against the
+// gaia modules.
+func GenesisValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [genesis-file]",
+		Short: "Validate a genesis file against the gaia modules",
+		Long: fmt.Sprintf(`Validate the genesis doc and the state of every gaia module of its app state. The
+migration info embedded by migrate --embed-migration-info is checked and printed,
+other app state keys naming no module are reported, InitChain ignores both. Every
+invalid module is reported, not only the first. Pass - as the genesis file to
+read it from STDIN.
+
+--bech32-prefix validates the genesis of another chain reusing these modules
+whose addresses have that prefix, e.g. osmo, and its valoper, valcons and pub
+variants. The bech32 strings of the %s prefixes are then reported, those of
+prefixes of other chains fail the module validation either way.
+
+Example:
+$ %s genesis validate genesis.json
+`, sdk.Bech32MainPrefix, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			doc, err := genesis.Load(input)
+			if err != nil {
+				return err
+			}
+			prefix, _ := cmd.Flags().GetString(flagBech32Prefix)
+			doc = doc.WithBech32Prefix(prefix)
+
+			if info, err := doc.MigrationInfo(); err == nil && info != nil {
+				cmd.Printf("migrated by %s\n", info)
+			}
+
+			var invalid []string
+			for _, finding := range doc.Validate() {
+				if finding.Severity == genesis.SeverityError {
+					invalid = append(invalid, fmt.Sprintf("%s: %s", finding.Module, finding.Message))
+				} else {
+					cmd.PrintErrln(finding.Message)
+				}
+			}
+
+			if len(invalid) > 0 {
+				return fmt.Errorf("invalid app state: %s", strings.Join(invalid, "; "))
+			}
+
+			cmd.Printf("%s is a valid genesis file\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagBech32Prefix, sdk.Bech32MainPrefix, "Bech32 prefix of the account addresses of the chain of the genesis")
+
+	return cmd
+}
@@ -0,0 +1,140 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+)
+
+func TestGenesisValidateModuleAccountAddresses(t *testing.T) {
+	b := testGenesisBuilder()
+	genDoc, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+	_, err = executeGenesisValidate(t, bz)
+	require.NoError(t, err)
+
+	// a fork tool rewrote the distribution module account address instead of
+	// deriving it from its name and made the fee collector a plain account
+	staleAddr := b.Address("stale-distribution")
+	distributionAddr := auth.NewModuleAddress(distribution.ModuleName)
+	feeCollectorAddr := auth.NewModuleAddress(auth.FeeCollectorName)
+
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	require.NoError(t, err)
+
+	var found int
+	for i, acc := range accounts {
+		switch acc.GetAddress().String() {
+		case distributionAddr.String():
+			macc := acc.(*auth.ModuleAccount)
+			macc.Address = staleAddr.String()
+			found++
+		case feeCollectorAddr.String():
+			accounts[i] = auth.NewBaseAccount(feeCollectorAddr, nil, acc.GetAccountNumber(), 0)
+			found++
+		}
+	}
+	require.Equal(t, 2, found, "the builder genesis has all module accounts")
+
+	authGenesis.Accounts, err = auth.PackAccounts(accounts)
+	require.NoError(t, err)
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{
+		Address: staleAddr.String(),
+		Coins:   sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10)),
+	})
+	bankGenesis.Supply = bankGenesis.Supply.Add(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10))
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	genDoc.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+	bz, err = tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	_, err = executeGenesisValidate(t, bz)
+	require.EqualError(t, err, fmt.Sprintf("invalid app state: "+
+		"auth: account %s at the address of the %s module is not a module account; "+
+		"auth: module account %s has address %s, its name derives %s, its balance of 10%s is orphaned; "+
+		"auth: invalid account found in genesis state; address: %s, error: address %s cannot be derived from the module name '%s'",
+		feeCollectorAddr, auth.FeeCollectorName, distribution.ModuleName, staleAddr, distributionAddr, genesisbuilder.BondDenom,
+		staleAddr, staleAddr, distribution.ModuleName))
+}
+
+// withOsmoPrefix returns genesis with its bech32 strings of the cosmos
+// prefixes re-encoded with the osmo ones.
+func withOsmoPrefix(t *testing.T, genesis []byte) []byte {
+	pattern := regexp.MustCompile(`"cosmos[a-z]*1[a-z0-9]+"`)
+	return pattern.ReplaceAllFunc(genesis, func(match []byte) []byte {
+		hrp, data, err := bech32.DecodeAndConvert(string(match[1 : len(match)-1]))
+		require.NoError(t, err)
+		s, err := bech32.ConvertAndEncode("osmo"+strings.TrimPrefix(hrp, "cosmos"), data)
+		require.NoError(t, err)
+		return []byte(`"` + s + `"`)
+	})
+}
+
+func TestGenesisValidateBech32Prefix(t *testing.T) {
+	b := testGenesisBuilder()
+	genDoc, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+	osmo := withOsmoPrefix(t, bz)
+	require.NotContains(t, string(osmo), `"cosmos1`)
+
+	_, err = executeGenesisValidate(t, osmo)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid Bech32 prefix; expected cosmos, got osmo")
+
+	out, err := executeGenesisValidate(t, osmo, "--bech32-prefix", "osmo")
+	require.NoError(t, err)
+	require.Contains(t, out, "is a valid genesis file")
+
+	alice := b.Address("alice")
+	osmoAlice, err := bech32.ConvertAndEncode("osmo", alice)
+	require.NoError(t, err)
+
+	// an address of the hub in the osmo genesis
+	mixed := strings.Replace(string(osmo), `"`+osmoAlice+`"`, `"`+alice.String()+`"`, 1)
+	_, err = executeGenesisValidate(t, []byte(mixed), "--bech32-prefix", "osmo")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bech32 strings of the cosmos prefixes the chain does not parse, e.g. "+alice.String())
+
+	// the findings name the osmo addresses
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	bankGenesis.Balances = append(bankGenesis.Balances, bankGenesis.Balances[0])
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	genDoc.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+	bz, err = tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	duplicate, err := sdk.AccAddressFromBech32(bankGenesis.Balances[0].Address)
+	require.NoError(t, err)
+	osmoDuplicate, err := bech32.ConvertAndEncode("osmo", duplicate)
+	require.NoError(t, err)
+
+	_, err = executeGenesisValidate(t, withOsmoPrefix(t, bz), "--bech32-prefix", "osmo")
+	require.EqualError(t, err, "invalid app state: bank: duplicate balance for address "+osmoDuplicate)
+}
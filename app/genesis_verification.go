@@ -0,0 +1,384 @@
+package gaia
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagListen    = "listen"
+	flagTLSCert   = "tls-cert"
+	flagTLSKey    = "tls-key"
+	flagTLSCA     = "tls-ca"
+	flagChunkSize = "chunk-size"
+)
+
+// verificationPath is the path the hashes of a served genesis are fetched from.
+const verificationPath = "/verification"
+
+// verificationFetchTimeout bounds fetching the hashes of a served genesis.
+const verificationFetchTimeout = time.Minute
+
+// genesisVerification are the hashes of a genesis file validators compare
+// their own genesis with, without downloading the file.
+type genesisVerification struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// HeaderSHA256 is the SHA-256 of the header.json of the split genesis,
+	// the genesis fields but app_state.
+	HeaderSHA256 string `json:"header_sha256"`
+	// Modules are the SHA-256 of the module files of the split genesis.
+	Modules map[string]string `json:"modules"`
+	// Chunks are the SHA-256 of the consecutive ChunkSize bytes of the file.
+	ChunkSize int64    `json:"chunk_size"`
+	Chunks    []string `json:"chunks"`
+}
+
+// newGenesisVerification returns the hashes of the genesis file bz, hashing
+// it in chunks of chunkSize bytes.
+func newGenesisVerification(bz []byte, chunkSize int64) (genesisVerification, error) {
+	if chunkSize <= 0 {
+		return genesisVerification{}, fmt.Errorf("the chunk size must be positive, got %d", chunkSize)
+	}
+
+	header, modules, err := splitGenesis(bz)
+	if err != nil {
+		return genesisVerification{}, err
+	}
+	headerBz, moduleFiles, err := splitFiles(header, modules)
+	if err != nil {
+		return genesisVerification{}, err
+	}
+
+	verification := genesisVerification{
+		SHA256:       sha256Hex(bz),
+		Size:         int64(len(bz)),
+		HeaderSHA256: sha256Hex(headerBz),
+		Modules:      make(map[string]string, len(moduleFiles)),
+		ChunkSize:    chunkSize,
+	}
+	for module, file := range moduleFiles {
+		verification.Modules[module] = sha256Hex(file)
+	}
+	for start := int64(0); start < int64(len(bz)); start += chunkSize {
+		end := start + chunkSize
+		if end > int64(len(bz)) {
+			end = int64(len(bz))
+		}
+		verification.Chunks = append(verification.Chunks, sha256Hex(bz[start:end]))
+	}
+
+	return verification, nil
+}
+
+// sha256Hex returns the hex encoded SHA-256 of bz.
+func sha256Hex(bz []byte) string {
+	sum := sha256.Sum256(bz)
+	return hex.EncodeToString(sum[:])
+}
+
+// GenesisServeVerificationCmd returns a command serving the hashes of a
+// genesis file over HTTP.
+func GenesisServeVerificationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve-verification [genesis-file]",
+		Short: "Serve the hashes of a genesis file for validators to verify their own against",
+		Long: fmt.Sprintf(`Serve the SHA-256 of the genesis file, of the header and module files of its
+split representation and of its --chunk-size chunks at %s of --listen, until
+interrupted. Validators compare their own genesis with genesis verify-against,
+which reports the modules and byte ranges that differ without downloading the
+file. Serve over TLS with --tls-cert and --tls-key. Pass - as the genesis file
+to read it from STDIN.
+
+Example:
+$ %s genesis serve-verification genesis.json --listen :9443 --tls-cert cert.pem --tls-key key.pem
+`, verificationPath, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			certFile, _ := cmd.Flags().GetString(flagTLSCert)
+			keyFile, _ := cmd.Flags().GetString(flagTLSKey)
+			if (certFile == "") != (keyFile == "") {
+				return fmt.Errorf("--%s and --%s must be given together", flagTLSCert, flagTLSKey)
+			}
+
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			bz, err := ioutil.ReadAll(input)
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+
+			chunkSize, _ := cmd.Flags().GetInt64(flagChunkSize)
+			verification, err := newGenesisVerification(bz, chunkSize)
+			if err != nil {
+				return err
+			}
+
+			listen, _ := cmd.Flags().GetString(flagListen)
+			listener, err := net.Listen("tcp", listen)
+			if err != nil {
+				return err
+			}
+
+			scheme := "http"
+			if certFile != "" {
+				scheme = "https"
+			}
+			cmd.PrintErrf("serving the verification of %s, sha256 %s, %d modules, %d chunks, at %s://%s%s\n",
+				args[0], verification.SHA256, len(verification.Modules), len(verification.Chunks), scheme, listener.Addr(), verificationPath)
+
+			return serveVerification(cmd.Context(), listener, verification, certFile, keyFile)
+		},
+	}
+
+	cmd.Flags().String(flagListen, ":9443", "Address to serve the verification at")
+	cmd.Flags().String(flagTLSCert, "", "PEM certificate file to serve over TLS with")
+	cmd.Flags().String(flagTLSKey, "", "PEM private key file of --tls-cert")
+	cmd.Flags().Int64(flagChunkSize, 1<<20, "Size in bytes of the hashed chunks of the genesis file")
+
+	return cmd
+}
+
+// serveVerification serves verification on listener, over TLS if certFile is
+// given, until ctx is done.
+func serveVerification(ctx context.Context, listener net.Listener, verification genesisVerification, certFile, keyFile string) error {
+	bz, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(verificationPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(bz)
+	})
+
+	// the hashes never change, slow clients are all a server has to bound
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       time.Minute,
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		case <-done:
+		}
+	}()
+
+	if certFile != "" {
+		err = server.ServeTLS(listener, certFile, keyFile)
+	} else {
+		err = server.Serve(listener)
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// GenesisVerifyAgainstCmd returns a command comparing a genesis file with the
+// hashes served by genesis serve-verification.
+func GenesisVerifyAgainstCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-against [url] [genesis-file]",
+		Short: "Verify a genesis file against the hashes served by genesis serve-verification",
+		Long: fmt.Sprintf(`Fetch the hashes served by genesis serve-verification at url, compute those of
+the genesis file and report the header, modules and chunks that differ. Pass
+the certificate of a coordinator serving TLS with a self-signed certificate as
+--tls-ca. Pass - as the genesis file to read it from STDIN.
+
+Example:
+$ %s genesis verify-against https://coordinator:9443 genesis.json
+`, version.AppName),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := &http.Client{Timeout: verificationFetchTimeout}
+			if caFile, _ := cmd.Flags().GetString(flagTLSCA); caFile != "" {
+				pem, err := ioutil.ReadFile(caFile)
+				if err != nil {
+					return errors.Wrapf(err, "failed to read --%s", flagTLSCA)
+				}
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(pem) {
+					return fmt.Errorf("--%s %s has no PEM certificates", flagTLSCA, caFile)
+				}
+				client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+			}
+
+			expected, err := fetchGenesisVerification(cmd.Context(), client, args[0])
+			if err != nil {
+				return err
+			}
+
+			input, err := openGenesisInput(args[1], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			bz, err := ioutil.ReadAll(input)
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+
+			actual, err := newGenesisVerification(bz, expected.ChunkSize)
+			if err != nil {
+				return err
+			}
+
+			if mismatches := compareGenesisVerification(expected, actual); len(mismatches) > 0 {
+				for _, mismatch := range mismatches {
+					cmd.PrintErrln(mismatch)
+				}
+
+				return fmt.Errorf("%s differs from the genesis served at %s", args[1], args[0])
+			}
+
+			cmd.Printf("%s matches the genesis served at %s: sha256 %s, %d bytes\n", args[1], args[0], actual.SHA256, actual.Size)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagTLSCA, "", "PEM certificate file to trust for the TLS of the server, e.g. its self-signed certificate")
+
+	return cmd
+}
+
+// fetchGenesisVerification fetches the hashes served at the base URL source.
+func fetchGenesisVerification(ctx context.Context, client *http.Client, source string) (genesisVerification, error) {
+	if !isGenesisURL(source) {
+		return genesisVerification{}, fmt.Errorf("%s is not an http(s) URL", source)
+	}
+	source = strings.TrimSuffix(source, "/") + verificationPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return genesisVerification{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return genesisVerification{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return genesisVerification{}, fmt.Errorf("fetching %s: %s", source, resp.Status)
+	}
+
+	var verification genesisVerification
+	if err := json.NewDecoder(resp.Body).Decode(&verification); err != nil {
+		return genesisVerification{}, fmt.Errorf("invalid verification %s: %w", source, err)
+	}
+	if verification.ChunkSize <= 0 {
+		return genesisVerification{}, fmt.Errorf("verification %s has no chunk_size", source)
+	}
+
+	return verification, nil
+}
+
+// compareGenesisVerification returns an expected vs actual line for the size,
+// SHA-256, header and every module that differ, and a line for every range of
+// consecutive chunks that differ.
+func compareGenesisVerification(expected, actual genesisVerification) []string {
+	var mismatches []string
+
+	if expected.Size != actual.Size {
+		mismatches = append(mismatches, fmt.Sprintf("size:   expected %d, actual %d", expected.Size, actual.Size))
+	}
+	if expected.SHA256 == actual.SHA256 {
+		return mismatches
+	}
+	mismatches = append(mismatches, fmt.Sprintf("sha256: expected %s, actual %s", expected.SHA256, actual.SHA256))
+
+	if expected.HeaderSHA256 != actual.HeaderSHA256 {
+		mismatches = append(mismatches, fmt.Sprintf("header: expected %s, actual %s", expected.HeaderSHA256, actual.HeaderSHA256))
+	}
+
+	modules := make(map[string]bool)
+	for module := range expected.Modules {
+		modules[module] = true
+	}
+	for module := range actual.Modules {
+		modules[module] = true
+	}
+	sorted := make([]string, 0, len(modules))
+	for module := range modules {
+		sorted = append(sorted, module)
+	}
+	sort.Strings(sorted)
+
+	for _, module := range sorted {
+		want, served := expected.Modules[module]
+		got, local := actual.Modules[module]
+		switch {
+		case !served:
+			mismatches = append(mismatches, fmt.Sprintf("module %s: not in the served genesis", module))
+		case !local:
+			mismatches = append(mismatches, fmt.Sprintf("module %s: missing", module))
+		case want != got:
+			mismatches = append(mismatches, fmt.Sprintf("module %s: expected %s, actual %s", module, want, got))
+		}
+	}
+
+	chunks := len(expected.Chunks)
+	if len(actual.Chunks) > chunks {
+		chunks = len(actual.Chunks)
+	}
+	size := expected.Size
+	if actual.Size > size {
+		size = actual.Size
+	}
+	differs := func(i int) bool {
+		return i >= len(expected.Chunks) || i >= len(actual.Chunks) || expected.Chunks[i] != actual.Chunks[i]
+	}
+	for i := 0; i < chunks; i++ {
+		if !differs(i) {
+			continue
+		}
+		j := i
+		for j+1 < chunks && differs(j+1) {
+			j++
+		}
+
+		end := int64(j+1) * expected.ChunkSize
+		if end > size {
+			end = size
+		}
+		mismatches = append(mismatches, fmt.Sprintf("chunks %d-%d: bytes %d-%d differ", i, j, int64(i)*expected.ChunkSize, end-1))
+		i = j
+	}
+
+	return mismatches
+}
@@ -0,0 +1,185 @@
+package gaia
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate writes a self-signed certificate of 127.0.0.1 and its
+// key to dir.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "coordinator"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyBz, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBz}), 0600))
+
+	return certFile, keyFile
+}
+
+// startVerificationServer runs genesis serve-verification of path until the
+// test ends and returns its URL.
+func startVerificationServer(t *testing.T, path string, args ...string) string {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stderr, stderrWriter := io.Pipe()
+	cmd := GenesisServeVerificationCmd()
+	cmd.SetArgs(append([]string{path, "--listen", "127.0.0.1:0"}, args...))
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(stderrWriter)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- cmd.ExecuteContext(ctx)
+		stderrWriter.Close()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-served)
+	})
+
+	line, err := bufio.NewReader(stderr).ReadString('\n')
+	require.NoError(t, err)
+	go io.Copy(ioutil.Discard, stderr)
+
+	match := regexp.MustCompile(`at (https?://\S+)` + verificationPath + `\n$`).FindStringSubmatch(line)
+	require.NotNil(t, match, line)
+
+	return match[1]
+}
+
+func executeVerifyAgainst(t *testing.T, args ...string) (string, string, error) {
+	cmd := GenesisVerifyAgainstCmd()
+	var out, stderr bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), stderr.String(), err
+}
+
+func TestGenesisVerifyAgainst(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	original := writeSortedTestGenesis(t, genesisPath)
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	url := startVerificationServer(t, genesisPath, "--tls-cert", certFile, "--tls-key", keyFile, "--chunk-size", "1024")
+	require.Regexp(t, `^https://`, url)
+
+	out, _, err := executeVerifyAgainst(t, url, genesisPath, "--tls-ca", certFile)
+	require.NoError(t, err)
+	require.Contains(t, out, genesisPath+" matches the genesis served at "+url)
+
+	// the certificate of the server is not trusted without --tls-ca
+	_, _, err = executeVerifyAgainst(t, url, genesisPath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "certificate")
+
+	// a digit of a bank balance changed, keeping the size of the file
+	bankStart := bytes.Index(original, []byte(`"bank":{`))
+	require.True(t, bankStart > 0)
+	amount := bankStart + bytes.Index(original[bankStart:], []byte(`"amount":"`)) + len(`"amount":"`)
+	changed := append([]byte(nil), original...)
+	if changed[amount] == '9' {
+		changed[amount] = '8'
+	} else {
+		changed[amount]++
+	}
+	changedPath := filepath.Join(dir, "changed.json")
+	require.NoError(t, ioutil.WriteFile(changedPath, changed, 0600))
+
+	_, stderr, err := executeVerifyAgainst(t, url, changedPath, "--tls-ca", certFile)
+	require.EqualError(t, err, changedPath+" differs from the genesis served at "+url)
+
+	verification, err := newGenesisVerification(original, 1024)
+	require.NoError(t, err)
+	chunk := amount / 1024
+	require.Regexp(t, `^sha256: expected `+verification.SHA256+`, actual [0-9a-f]{64}
+module bank: expected `+verification.Modules["bank"]+`, actual [0-9a-f]{64}
+chunks `+strconv.Itoa(chunk)+`-`+strconv.Itoa(chunk)+`: bytes `+strconv.Itoa(chunk*1024)+`-`+strconv.Itoa(chunk*1024+1023)+` differ
+Error: `, stderr)
+}
+
+func TestGenesisVerifyAgainstHTTP(t *testing.T) {
+	dir := t.TempDir()
+	genesisPath := filepath.Join(dir, "genesis.json")
+	writeSortedTestGenesis(t, genesisPath)
+
+	url := startVerificationServer(t, genesisPath)
+	require.Regexp(t, `^http://`, url)
+
+	_, _, err := executeVerifyAgainst(t, url+"/", genesisPath)
+	require.NoError(t, err)
+
+	require.Error(t, runGenesisCmd(GenesisServeVerificationCmd(), genesisPath, "--tls-cert", "cert.pem"))
+}
+
+func TestCompareGenesisVerification(t *testing.T) {
+	expected := genesisVerification{
+		SHA256:       "a",
+		Size:         95,
+		HeaderSHA256: "h",
+		Modules:      map[string]string{"auth": "1", "bank": "2", "gov": "3"},
+		ChunkSize:    10,
+		Chunks:       []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
+	}
+	actual := genesisVerification{
+		SHA256:       "b",
+		Size:         112,
+		HeaderSHA256: "h",
+		Modules:      map[string]string{"auth": "1", "bank": "x", "ibc": "4"},
+		ChunkSize:    10,
+		Chunks:       []string{"0", "x", "x", "3", "4", "5", "6", "7", "x", "x", "x", "x"},
+	}
+
+	require.Equal(t, []string{
+		"size:   expected 95, actual 112",
+		"sha256: expected a, actual b",
+		"module bank: expected 2, actual x",
+		"module gov: missing",
+		"module ibc: not in the served genesis",
+		"chunks 1-2: bytes 10-29 differ",
+		"chunks 8-11: bytes 80-111 differ",
+	}, compareGenesisVerification(expected, actual))
+
+	require.Empty(t, compareGenesisVerification(expected, expected))
+}
@@ -0,0 +1,477 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/cosmos/cosmos-sdk/version"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const flagWatchAddresses = "addresses"
+
+// Verdicts of a watched address.
+const (
+	watchUnchanged = "unchanged"
+	watchChanged   = "changed"
+	watchAdded     = "added"
+	watchRemoved   = "removed"
+	watchAbsent    = "absent"
+)
+
+// aminoAccountTypes are the proto type URLs of the amino JSON account types
+// of the v0.39 auth genesis.
+var aminoAccountTypes = map[string]string{
+	"cosmos-sdk/Account":                  "/" + proto.MessageName(&auth.BaseAccount{}),
+	"cosmos-sdk/ModuleAccount":            "/" + proto.MessageName(&auth.ModuleAccount{}),
+	"cosmos-sdk/ContinuousVestingAccount": "/" + proto.MessageName(&vesting.ContinuousVestingAccount{}),
+	"cosmos-sdk/DelayedVestingAccount":    "/" + proto.MessageName(&vesting.DelayedVestingAccount{}),
+	"cosmos-sdk/PeriodicVestingAccount":   "/" + proto.MessageName(&vesting.PeriodicVestingAccount{}),
+}
+
+// watchedAccount is what a genesis file holds for a watched address: its
+// auth record, if Account is set, and its bank balance. Type is the proto
+// type URL of the account, whatever the encoding of the file, so the same
+// account has the same type before and after the migration.
+type watchedAccount struct {
+	Account  bool      `json:"account"`
+	Type     string    `json:"type,omitempty"`
+	Sequence uint64    `json:"sequence"`
+	PubKey   bool      `json:"pubkey"`
+	Balance  sdk.Coins `json:"balance"`
+}
+
+// watchlistEntry compares a watched address in the source and the migrated
+// genesis, nil where the file has neither an account nor a balance of it.
+// Gained and Lost are the migrated balance above and below the source one.
+type watchlistEntry struct {
+	Address  string          `json:"address"`
+	Verdict  string          `json:"verdict"`
+	Source   *watchedAccount `json:"source"`
+	Migrated *watchedAccount `json:"migrated"`
+	Gained   sdk.Coins       `json:"gained"`
+	Lost     sdk.Coins       `json:"lost"`
+}
+
+// loadWatchlist reads a JSON array of bech32 account addresses of the prefix,
+// dropping duplicates.
+func loadWatchlist(path, prefix string) ([]string, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read addresses file")
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(bz, &addresses); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal addresses")
+	}
+
+	seen := make(map[string]bool, len(addresses))
+	watchlist := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		hrp, acc, err := bech32.DecodeAndConvert(addr)
+		if err == nil && hrp != prefix {
+			err = fmt.Errorf("expected the bech32 prefix %s, got %s", prefix, hrp)
+		}
+		if err == nil {
+			err = sdk.VerifyAddressFormat(acc)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid address %s", addr)
+		}
+
+		// the addresses of the genesis are lower case
+		address, err := bech32.ConvertAndEncode(prefix, acc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid address %s", addr)
+		}
+		if !seen[address] {
+			seen[address] = true
+			watchlist = append(watchlist, address)
+		}
+	}
+
+	return watchlist, nil
+}
+
+// readWatchedAccounts returns the accounts of the watched addresses of the
+// genesis read from r, streaming the accounts of its genaccounts or auth
+// genesis and the balances of its bank genesis. Only the watched accounts
+// are kept.
+func readWatchedAccounts(r io.Reader, watched map[string]bool) (map[string]*watchedAccount, error) {
+	accounts := make(map[string]*watchedAccount)
+	get := func(address string) *watchedAccount {
+		if accounts[address] == nil {
+			accounts[address] = &watchedAccount{Balance: sdk.NewCoins()}
+		}
+		return accounts[address]
+	}
+
+	onAccount := func(bz json.RawMessage) error {
+		address, account, coins, err := decodeWatchedAccount(bz)
+		if err != nil || !watched[address] {
+			return err
+		}
+
+		acc := get(address)
+		balance := acc.Balance
+		*acc = account
+		acc.Balance = balance.Add(coins...)
+		return nil
+	}
+
+	onBalance := func(bz json.RawMessage) error {
+		var balance struct {
+			Address string    `json:"address"`
+			Coins   sdk.Coins `json:"coins"`
+		}
+		if err := json.Unmarshal(bz, &balance); err != nil {
+			return errors.Wrap(err, "invalid bank balance")
+		}
+		if !watched[balance.Address] {
+			return nil
+		}
+
+		acc := get(balance.Address)
+		acc.Balance = acc.Balance.Add(balance.Coins.Sort()...)
+		return nil
+	}
+
+	if err := streamGenesisAccounts(r, onAccount, onBalance); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// decodeWatchedAccount returns the address, auth record and coins of the JSON
+// of an account of the genaccounts genesis, the amino JSON of the legacy auth
+// genesis or the proto JSON of the current one. Only the accounts before v0.40
+// hold coins.
+func decodeWatchedAccount(bz json.RawMessage) (string, watchedAccount, sdk.Coins, error) {
+	var account map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	if err := dec.Decode(&account); err != nil {
+		return "", watchedAccount{}, nil, err
+	}
+
+	watched := watchedAccount{Account: true}
+	if typeURL, ok := account["@type"].(string); ok {
+		watched.Type = typeURL
+	} else if aminoType, ok := account["type"].(string); ok {
+		watched.Type = aminoAccountTypes[aminoType]
+		if watched.Type == "" {
+			watched.Type = aminoType
+		}
+	}
+
+	if value, ok := account["value"].(map[string]interface{}); ok && account["type"] != nil {
+		account = value
+	}
+	if watched.Type == "" {
+		watched.Type = genAccountType(account)
+	}
+
+	base := baseAccountJSON(account)
+	if base == nil {
+		return "", watchedAccount{}, nil, nil
+	}
+	address, _ := base["address"].(string)
+
+	value := base["sequence"]
+	if value == nil {
+		value = base["sequence_number"]
+	}
+	if value != nil {
+		n, err := strconv.ParseUint(fmt.Sprint(value), 10, 64)
+		if err != nil {
+			return "", watchedAccount{}, nil, errors.Wrapf(err, "invalid sequence of account %s", address)
+		}
+		watched.Sequence = n
+	}
+
+	pubKey := base["pub_key"]
+	if pubKey == nil {
+		pubKey = base["public_key"]
+	}
+	watched.PubKey = pubKey != nil && pubKey != ""
+
+	var coins sdk.Coins
+	if value, ok := base["coins"]; ok && value != nil {
+		coinsBz, err := json.Marshal(value)
+		if err != nil {
+			return "", watchedAccount{}, nil, err
+		}
+		if err := json.Unmarshal(coinsBz, &coins); err != nil {
+			return "", watchedAccount{}, nil, errors.Wrapf(err, "invalid coins of account %s", address)
+		}
+	}
+
+	return address, watched, coins.Sort(), nil
+}
+
+// genAccountType returns the proto type URL of an account of the genaccounts
+// genesis, which has no type of its own: module accounts have a module name
+// and vesting accounts an original vesting, starting at once if delayed.
+func genAccountType(account map[string]interface{}) string {
+	if name, _ := account["module_name"].(string); name != "" {
+		return aminoAccountTypes["cosmos-sdk/ModuleAccount"]
+	}
+
+	if vesting, _ := account["original_vesting"].([]interface{}); len(vesting) > 0 {
+		if start := fmt.Sprint(account["start_time"]); start == "0" || start == "" {
+			return aminoAccountTypes["cosmos-sdk/DelayedVestingAccount"]
+		}
+		return aminoAccountTypes["cosmos-sdk/ContinuousVestingAccount"]
+	}
+
+	return aminoAccountTypes["cosmos-sdk/Account"]
+}
+
+// streamGenesisAccounts calls onAccount with every account of the
+// genaccounts or auth genesis and onBalance with every balance of the bank
+// genesis of the genesis read from r, in one pass decoding one element at a
+// time.
+func streamGenesisAccounts(r io.Reader, onAccount, onBalance func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "app_state" {
+			if err := skipJSONValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return errors.Wrap(err, "invalid app_state")
+		}
+		for dec.More() {
+			module, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			switch module {
+			case legacyGenAccountsModule:
+				err = streamArrayElements(dec, onAccount)
+			case auth.ModuleName:
+				err = streamObjectArray(dec, "accounts", onAccount)
+			case bank.ModuleName:
+				err = streamObjectArray(dec, "balances", onBalance)
+			default:
+				err = skipJSONValue(dec)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "invalid %s genesis", module)
+			}
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamObjectArray calls fn with every element of the array at key of the
+// next value of dec, an object or null, skipping its other values.
+func streamObjectArray(dec *json.Decoder, key string, fn func(json.RawMessage) error) error {
+	token, err := dec.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("%v is not an object", token)
+	}
+
+	for dec.More() {
+		name, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if name == key {
+			err = streamArrayElements(dec, fn)
+		} else {
+			err = skipJSONValue(dec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// compareWatchlist compares the accounts of every watched address in the
+// source and migrated genesis.
+func compareWatchlist(addresses []string, source, migrated map[string]*watchedAccount) []watchlistEntry {
+	entries := make([]watchlistEntry, 0, len(addresses))
+	for _, address := range addresses {
+		entry := watchlistEntry{Address: address, Source: source[address], Migrated: migrated[address], Gained: sdk.NewCoins(), Lost: sdk.NewCoins()}
+
+		switch {
+		case entry.Source == nil && entry.Migrated == nil:
+			entry.Verdict = watchAbsent
+		case entry.Source == nil:
+			entry.Verdict = watchAdded
+			entry.Gained = entry.Migrated.Balance
+		case entry.Migrated == nil:
+			entry.Verdict = watchRemoved
+			entry.Lost = entry.Source.Balance
+		default:
+			entry.Gained, entry.Lost = coinsDelta(entry.Migrated.Balance, entry.Source.Balance)
+			src, dst := entry.Source, entry.Migrated
+			entry.Verdict = watchUnchanged
+			if src.Account != dst.Account || src.Type != dst.Type || src.Sequence != dst.Sequence || src.PubKey != dst.PubKey ||
+				!entry.Gained.Empty() || !entry.Lost.Empty() {
+				entry.Verdict = watchChanged
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// writeWatchlistTable writes the entries as a table, a source and a migrated
+// value separated by an arrow where they differ.
+func writeWatchlistTable(w io.Writer, entries []watchlistEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tVERDICT\tTYPE\tSEQUENCE\tPUBKEY\tBALANCE\tDELTA")
+
+	for _, entry := range entries {
+		field := func(value func(*watchedAccount) string) string {
+			src, dst := "-", "-"
+			if entry.Source != nil {
+				src = value(entry.Source)
+			}
+			if entry.Migrated != nil {
+				dst = value(entry.Migrated)
+			}
+			if src == dst {
+				return src
+			}
+			return src + " -> " + dst
+		}
+
+		var delta []string
+		for _, coin := range entry.Gained {
+			delta = append(delta, "+"+coin.String())
+		}
+		for _, coin := range entry.Lost {
+			delta = append(delta, "-"+coin.String())
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Address, entry.Verdict,
+			field(func(a *watchedAccount) string {
+				if !a.Account {
+					return "none"
+				}
+				return strings.TrimPrefix(a.Type[strings.LastIndex(a.Type, ".")+1:], "/")
+			}),
+			field(func(a *watchedAccount) string { return strconv.FormatUint(a.Sequence, 10) }),
+			field(func(a *watchedAccount) string { return strconv.FormatBool(a.PubKey) }),
+			field(func(a *watchedAccount) string { return a.Balance.String() }),
+			strings.Join(delta, ","))
+	}
+
+	return tw.Flush()
+}
+
+// GenesisWatchlistDiffCmd returns a command comparing the accounts of a list
+// of addresses in a source and a migrated genesis file.
+func GenesisWatchlistDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watchlist-diff [source-genesis] [migrated-genesis]",
+		Short: "Compare the accounts and balances of a list of addresses before and after the migration",
+		Long: fmt.Sprintf(`Report, for every address of the --addresses JSON array, its auth account, its
+type, sequence and whether it has a pubkey, and its bank balance in the source
+and the migrated genesis, with the verdict unchanged or changed and the balance
+delta. An address only one file has is added or removed, one neither has is
+absent. The account types are compared as their proto type URLs, the types of
+the source accounts of genaccounts and amino JSON included. The addresses are
+of the --bech32-prefix of the chain. Both files are streamed, only the
+accounts of the listed addresses are kept in memory.
+
+Example:
+$ %s genesis watchlist-diff cosmoshub-3.json genesis.json --addresses exchange.json
+$ %s genesis watchlist-diff cosmoshub-3.json genesis.json --addresses exchange.json --format json
+`, version.AppName, version.AppName),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString(flagFormat)
+			if format != formatText && format != formatJSON {
+				return fmt.Errorf("unknown format %q, expected %s or %s", format, formatText, formatJSON)
+			}
+
+			path, _ := cmd.Flags().GetString(flagWatchAddresses)
+			if path == "" {
+				return fmt.Errorf("--%s is required", flagWatchAddresses)
+			}
+			prefix, _ := cmd.Flags().GetString(flagBech32Prefix)
+			addresses, err := loadWatchlist(path, prefix)
+			if err != nil {
+				return err
+			}
+			watched := make(map[string]bool, len(addresses))
+			for _, address := range addresses {
+				watched[address] = true
+			}
+
+			var files [2]map[string]*watchedAccount
+			for i, name := range args {
+				input, err := openGenesisInput(name, cmd.InOrStdin())
+				if err != nil {
+					return errors.Wrapf(err, "failed to read genesis file %s", name)
+				}
+
+				files[i], err = readWatchedAccounts(input, watched)
+				input.Close()
+				if err != nil {
+					return errors.Wrapf(err, "failed to read the accounts of %s", name)
+				}
+			}
+
+			entries := compareWatchlist(addresses, files[0], files[1])
+			if format == formatJSON {
+				bz, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to JSON marshal watchlist diff")
+				}
+				cmd.Println(string(bz))
+				return nil
+			}
+
+			return writeWatchlistTable(cmd.OutOrStdout(), entries)
+		},
+	}
+
+	cmd.Flags().String(flagWatchAddresses, "", "JSON array of the bech32 account addresses to compare")
+	cmd.Flags().String(flagFormat, formatText, "Format of the report, text or json")
+	cmd.Flags().String(flagBech32Prefix, sdk.Bech32MainPrefix, "Bech32 prefix of the account addresses of the chain of the genesis")
+
+	return cmd
+}
@@ -0,0 +1,157 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/stretchr/testify/require"
+)
+
+// executeWatchlistDiff runs genesis watchlist-diff with args and returns its
+// output.
+func executeWatchlistDiff(args ...string) (string, error) {
+	cmd := GenesisWatchlistDiffCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestGenesisWatchlistDiff(t *testing.T) {
+	const (
+		funder    = "cosmos1tlykl6rqlns2wv2nd5claxvdrnq2scsumduudu"
+		blocked   = "cosmos14e05nn094zxyx3nz898raf287w74zhdwx9af6r"
+		untouched = "cosmos1jqsn5gxyuz0vghn4vvupfd795uj2757lalyx3l"
+	)
+	distributionAddr := auth.NewModuleAddress(distribution.ModuleName).String()
+	absent := sdk.AccAddress([]byte("watchlist-absent-acc")).String()
+
+	dir := t.TempDir()
+	blocklist := filepath.Join(dir, "blocked.json")
+	require.NoError(t, ioutil.WriteFile(blocklist, []byte(`["`+blocked+`"]`), 0644))
+
+	migrated, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--blocked-addresses", blocklist, "--fund-community-pool", "1000uatom", "--from-account", funder)
+	require.NoError(t, err)
+	migratedPath := filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(migratedPath, migrated, 0644))
+
+	addresses := filepath.Join(dir, "addresses.json")
+	require.NoError(t, ioutil.WriteFile(addresses, []byte(`["`+funder+`","`+blocked+`","`+untouched+`","`+distributionAddr+`","`+absent+`","`+funder+`"]`), 0644))
+
+	out, err := executeWatchlistDiff("testdata/cosmoshub-2-genesis.json", migratedPath, "--addresses", addresses, "--format", "json")
+	require.NoError(t, err)
+	var entries []watchlistEntry
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+
+	baseAccount := "/cosmos.auth.v1beta1.BaseAccount"
+	byAddress := make(map[string]watchlistEntry)
+	var order []string
+	for _, entry := range entries {
+		byAddress[entry.Address] = entry
+		order = append(order, entry.Address)
+	}
+	require.Equal(t, []string{funder, blocked, untouched, distributionAddr, absent}, order)
+
+	changed := byAddress[funder]
+	require.Equal(t, watchChanged, changed.Verdict)
+	require.Equal(t, baseAccount, changed.Source.Type)
+	require.Equal(t, baseAccount, changed.Migrated.Type)
+	require.Equal(t, "100000000uatom", changed.Source.Balance.String())
+	require.Equal(t, "99999000uatom", changed.Migrated.Balance.String())
+	require.True(t, changed.Gained.Empty())
+	require.Equal(t, "1000uatom", changed.Lost.String())
+
+	removed := byAddress[blocked]
+	require.Equal(t, watchRemoved, removed.Verdict)
+	require.True(t, removed.Source.Account)
+	require.Nil(t, removed.Migrated)
+	require.Equal(t, "50000000uatom", removed.Lost.String())
+
+	unchanged := byAddress[untouched]
+	require.Equal(t, watchUnchanged, unchanged.Verdict)
+	require.Equal(t, *unchanged.Source, *unchanged.Migrated)
+	require.Equal(t, "1000uatom", unchanged.Migrated.Balance.String())
+	require.True(t, unchanged.Gained.Empty())
+	require.True(t, unchanged.Lost.Empty())
+
+	// the community pool gets the funding and the blocked balance
+	added := byAddress[distributionAddr]
+	require.Equal(t, watchAdded, added.Verdict)
+	require.Nil(t, added.Source)
+	require.Equal(t, "/cosmos.auth.v1beta1.ModuleAccount", added.Migrated.Type)
+	require.Equal(t, "50001000uatom", added.Gained.String())
+
+	require.Equal(t, watchAbsent, byAddress[absent].Verdict)
+	require.Nil(t, byAddress[absent].Source)
+	require.Nil(t, byAddress[absent].Migrated)
+
+	table, err := executeWatchlistDiff("testdata/cosmoshub-2-genesis.json", migratedPath, "--addresses", addresses)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	require.Len(t, lines, 6)
+	require.Equal(t, []string{"ADDRESS", "VERDICT", "TYPE", "SEQUENCE", "PUBKEY", "BALANCE", "DELTA"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{funder, "changed", "BaseAccount", "3", "false", "100000000uatom", "->", "99999000uatom", "-1000uatom"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{blocked, "removed", "BaseAccount", "->", "-", "12", "->", "-", "false", "->", "-", "50000000uatom", "->", "-", "-50000000uatom"}, strings.Fields(lines[2]))
+	require.Equal(t, []string{untouched, "unchanged", "BaseAccount", "0", "false", "1000uatom"}, strings.Fields(lines[3]))
+	require.Equal(t, []string{distributionAddr, "added", "-", "->", "ModuleAccount", "-", "->", "0", "-", "->", "false", "-", "->", "50001000uatom", "+50001000uatom"}, strings.Fields(lines[4]))
+	require.Equal(t, []string{absent, "absent", "-", "-", "-", "-"}, strings.Fields(lines[5]))
+}
+
+func TestGenesisWatchlistDiffErrors(t *testing.T) {
+	dir := t.TempDir()
+	addresses := filepath.Join(dir, "addresses.json")
+	require.NoError(t, ioutil.WriteFile(addresses, []byte(`["cosmos1invalid"]`), 0644))
+
+	_, err := executeWatchlistDiff("testdata/cosmoshub-2-genesis.json", "testdata/cosmoshub-2-genesis.json")
+	require.EqualError(t, err, "--addresses is required")
+
+	_, err = executeWatchlistDiff("testdata/cosmoshub-2-genesis.json", "testdata/cosmoshub-2-genesis.json", "--addresses", addresses)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid address cosmos1invalid")
+
+	_, err = executeWatchlistDiff("testdata/cosmoshub-2-genesis.json", "testdata/cosmoshub-2-genesis.json", "--addresses", addresses, "--format", "yaml")
+	require.EqualError(t, err, `unknown format "yaml", expected text or json`)
+}
+
+func TestGenesisWatchlistDiffBech32Prefix(t *testing.T) {
+	address, err := bech32.ConvertAndEncode("osmo", []byte("watchlist-osmosis-ac"))
+	require.NoError(t, err)
+	genesisJSON := func(amount string) []byte {
+		return []byte(`{"app_state":{"auth":{"accounts":[{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"` + address +
+			`","pub_key":null,"account_number":"0","sequence":"1"}]},"bank":{"balances":[{"address":"` + address +
+			`","coins":[{"denom":"uosmo","amount":"` + amount + `"}]}]}}}`)
+	}
+
+	dir := t.TempDir()
+	source, migrated := filepath.Join(dir, "source.json"), filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(source, genesisJSON("100"), 0644))
+	require.NoError(t, ioutil.WriteFile(migrated, genesisJSON("90"), 0644))
+	addresses := filepath.Join(dir, "addresses.json")
+	require.NoError(t, ioutil.WriteFile(addresses, []byte(`["`+strings.ToUpper(address)+`"]`), 0644))
+
+	// the addresses are of the cosmos prefix by default
+	_, err = executeWatchlistDiff(source, migrated, "--addresses", addresses)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected the bech32 prefix cosmos, got osmo")
+
+	out, err := executeWatchlistDiff(source, migrated, "--addresses", addresses, "--bech32-prefix", "osmo", "--format", "json")
+	require.NoError(t, err)
+	var entries []watchlistEntry
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	require.Len(t, entries, 1)
+	require.Equal(t, address, entries[0].Address)
+	require.Equal(t, watchChanged, entries[0].Verdict)
+	require.Equal(t, "10uosmo", entries[0].Lost.String())
+}
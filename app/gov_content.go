@@ -0,0 +1,298 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	flagDropUnmappable   = "drop-unmappable-proposals"
+	flagGovContentReport = "gov-content-report"
+)
+
+// legacyContent is the amino JSON of a proposal content.
+type legacyContent struct {
+	Type  string                     `json:"type"`
+	Value map[string]json.RawMessage `json:"value"`
+}
+
+// legacyContentMapping maps a content type of the cosmoshub-3 gov genesis to
+// one the SDK migrations decode. Transform, when set, rewrites the fields of
+// the content value, or fails when a field has no current equivalent.
+type legacyContentMapping struct {
+	Type      string
+	Transform func(value map[string]json.RawMessage) error
+}
+
+// legacyContentMappings are the content types migrated, by their amino name
+// in the source gov genesis, of data/gov-content-mappings.json. Types missing
+// there cannot be migrated.
+var legacyContentMappings = mustLoadLegacyContentMappings()
+
+// dropContentFields returns a transform removing the given fields, which the
+// current type does not have.
+func dropContentFields(fields ...string) func(map[string]json.RawMessage) error {
+	return func(value map[string]json.RawMessage) error {
+		for _, field := range fields {
+			delete(value, field)
+		}
+		return nil
+	}
+}
+
+// transformParamChanges encodes the change values given as JSON values into
+// the JSON strings of the current changes. The current changes have no
+// subkey, a change of a subkey cannot be migrated.
+func transformParamChanges(value map[string]json.RawMessage) error {
+	var changes []map[string]json.RawMessage
+	if err := json.Unmarshal(value["changes"], &changes); err != nil {
+		return errors.Wrap(err, "invalid changes")
+	}
+
+	for _, change := range changes {
+		var subspace, key, subkey string
+		_ = json.Unmarshal(change["subspace"], &subspace)
+		_ = json.Unmarshal(change["key"], &key)
+		if subkey, _ = rawString(change["subkey"]); subkey != "" {
+			return fmt.Errorf("the change of %s %s sets the subkey %s, current parameter changes have no subkeys", subspace, key, subkey)
+		}
+		delete(change, "subkey")
+
+		if raw := change["value"]; len(raw) > 0 {
+			if _, ok := rawString(raw); !ok {
+				bz, err := json.Marshal(string(raw))
+				if err != nil {
+					return err
+				}
+				change["value"] = bz
+			}
+		}
+	}
+
+	bz, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	value["changes"] = bz
+
+	return nil
+}
+
+// transformUpgradePlan encodes a plan height given as a JSON number into the
+// string amino decodes.
+func transformUpgradePlan(value map[string]json.RawMessage) error {
+	var plan map[string]json.RawMessage
+	if err := json.Unmarshal(value["plan"], &plan); err != nil {
+		return errors.Wrap(err, "invalid plan")
+	}
+
+	if raw := plan["height"]; len(raw) > 0 {
+		if _, ok := rawString(raw); !ok {
+			var height json.Number
+			if err := json.Unmarshal(raw, &height); err != nil {
+				return errors.Wrap(err, "invalid plan height")
+			}
+			plan["height"], _ = json.Marshal(height.String())
+		}
+	}
+
+	bz, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	value["plan"] = bz
+
+	return nil
+}
+
+// rawString returns the string of a JSON string.
+func rawString(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// mappedProposal is a proposal whose content migrateGovContents mapped to
+// another type.
+type mappedProposal struct {
+	ProposalID string `json:"proposal_id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// unmappableProposal is a proposal whose content migrateGovContents could not
+// map, with the summary of its tally.
+type unmappableProposal struct {
+	ProposalID       string          `json:"proposal_id"`
+	Type             string          `json:"type"`
+	Title            string          `json:"title,omitempty"`
+	Reason           string          `json:"reason"`
+	Status           string          `json:"status"`
+	FinalTallyResult json.RawMessage `json:"final_tally_result,omitempty"`
+	Votes            map[string]int  `json:"votes"`
+	TotalDeposit     json.RawMessage `json:"total_deposit,omitempty"`
+	Dropped          bool            `json:"dropped"`
+}
+
+// govContentReport lists the proposals migrateGovContents changed.
+type govContentReport struct {
+	Mapped     []mappedProposal     `json:"mapped"`
+	Unmappable []unmappableProposal `json:"unmappable"`
+}
+
+// legacyVote is a vote of the cosmoshub-3 gov genesis.
+type legacyVote struct {
+	ProposalID string `json:"proposal_id"`
+	Option     string `json:"option"`
+}
+
+// legacyDeposit is a deposit of the cosmoshub-3 gov genesis.
+type legacyDeposit struct {
+	ProposalID string `json:"proposal_id"`
+}
+
+// migrateGovContents maps the proposal contents of the cosmoshub-3 gov
+// genesis bz with legacyContentMappings. A proposal whose content cannot be
+// mapped fails the migration, unless drop is set: it is then removed with its
+// votes. A dropped proposal cannot hold deposits, their coins would be left
+// in the gov module account. The report is returned with the error too.
+func migrateGovContents(bz json.RawMessage, drop bool) (json.RawMessage, govContentReport, error) {
+	report := govContentReport{Mapped: []mappedProposal{}, Unmappable: []unmappableProposal{}}
+	if len(bz) == 0 {
+		return bz, report, nil
+	}
+
+	var govGenesis map[string]json.RawMessage
+	if err := json.Unmarshal(bz, &govGenesis); err != nil {
+		return bz, report, err
+	}
+
+	var proposals []map[string]json.RawMessage
+	var votes []json.RawMessage
+	var deposits []legacyDeposit
+	for field, v := range map[string]interface{}{"proposals": &proposals, "votes": &votes, "deposits": &deposits} {
+		if raw := govGenesis[field]; len(raw) > 0 {
+			if err := json.Unmarshal(raw, v); err != nil {
+				return bz, report, errors.Wrapf(err, "invalid %s", field)
+			}
+		}
+	}
+
+	parsedVotes := make([]legacyVote, len(votes))
+	for i, vote := range votes {
+		if err := json.Unmarshal(vote, &parsedVotes[i]); err != nil {
+			return bz, report, errors.Wrap(err, "invalid vote")
+		}
+	}
+
+	held := make(map[string]bool)
+	for _, deposit := range deposits {
+		held[deposit.ProposalID] = true
+	}
+
+	dropped := make(map[string]bool)
+	kept := proposals[:0]
+	for _, proposal := range proposals {
+		id, _ := rawString(proposal["id"])
+
+		var content *legacyContent
+		if err := json.Unmarshal(proposal["content"], &content); err != nil {
+			return bz, report, errors.Wrapf(err, "invalid content of proposal %s", id)
+		}
+
+		reason := "no content"
+		if content != nil {
+			mapping, ok := legacyContentMappings[content.Type]
+			reason = "no mapping of the type"
+			if ok {
+				reason = ""
+				if mapping.Transform != nil {
+					if err := mapping.Transform(content.Value); err != nil {
+						reason = err.Error()
+					}
+				}
+			}
+
+			if reason == "" {
+				if mapping.Type != content.Type {
+					report.Mapped = append(report.Mapped, mappedProposal{ProposalID: id, From: content.Type, To: mapping.Type})
+				}
+
+				content.Type = mapping.Type
+				bz, err := json.Marshal(content)
+				if err != nil {
+					return nil, report, err
+				}
+				proposal["content"] = bz
+				kept = append(kept, proposal)
+				continue
+			}
+		}
+
+		unmappable := unmappableProposal{
+			ProposalID:       id,
+			Reason:           reason,
+			FinalTallyResult: proposal["final_tally_result"],
+			Votes:            make(map[string]int),
+			TotalDeposit:     proposal["total_deposit"],
+			Dropped:          drop,
+		}
+		unmappable.Status, _ = rawString(proposal["proposal_status"])
+		if content != nil {
+			unmappable.Type = content.Type
+			unmappable.Title, _ = rawString(content.Value["title"])
+		}
+		for _, vote := range parsedVotes {
+			if vote.ProposalID == id {
+				unmappable.Votes[vote.Option]++
+			}
+		}
+		report.Unmappable = append(report.Unmappable, unmappable)
+
+		if drop && held[id] {
+			return bz, report, fmt.Errorf("proposal %s cannot be dropped, it holds deposits", id)
+		}
+		dropped[id] = true
+	}
+
+	if len(report.Unmappable) > 0 && !drop {
+		descriptions := make([]string, len(report.Unmappable))
+		for i, proposal := range report.Unmappable {
+			descriptions[i] = fmt.Sprintf("proposal %s (%s: %s)", proposal.ProposalID, proposal.Type, proposal.Reason)
+		}
+		sort.Strings(descriptions)
+
+		return bz, report, fmt.Errorf("the content of %d proposals cannot be migrated, use --%s to remove them: %s",
+			len(report.Unmappable), flagDropUnmappable, strings.Join(descriptions, ", "))
+	}
+
+	keptVotes := votes[:0]
+	for i, vote := range votes {
+		if !dropped[parsedVotes[i].ProposalID] {
+			keptVotes = append(keptVotes, vote)
+		}
+	}
+
+	var err error
+	if govGenesis["proposals"], err = json.Marshal(kept); err != nil {
+		return bz, report, err
+	}
+	if len(dropped) > 0 {
+		if govGenesis["votes"], err = json.Marshal(keptVotes); err != nil {
+			return bz, report, err
+		}
+	}
+
+	migrated, err := json.Marshal(govGenesis)
+	if err != nil {
+		return bz, report, err
+	}
+
+	return migrated, report, nil
+}
@@ -0,0 +1,171 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	params "github.com/cosmos/cosmos-sdk/x/params/types/proposal"
+	upgrade "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const cosmoshub3LegacyProposals = "testdata/cosmoshub-3-legacy-proposals.json"
+
+func TestMigrateGovContents(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		mapped  string
+		reason  string
+	}{
+		{
+			"canonical type",
+			`{"type":"cosmos-sdk/TextProposal","value":{"title":"t","description":"d"}}`,
+			`{"type":"cosmos-sdk/TextProposal","value":{"description":"d","title":"t"}}`,
+			"",
+		},
+		{
+			"v0.34 text proposal",
+			`{"type":"gov/TextProposal","value":{"title":"t","description":"d","proposal_type":"Text"}}`,
+			`{"type":"cosmos-sdk/TextProposal","value":{"description":"d","title":"t"}}`,
+			"",
+		},
+		{
+			"parameter change values",
+			`{"type":"params/ParameterChangeProposal","value":{"title":"t","description":"d","changes":[{"subspace":"staking","key":"MaxValidators","subkey":"","value":125},{"subspace":"mint","key":"MintDenom","value":"\"uatom\""}]}}`,
+			`{"type":"cosmos-sdk/ParameterChangeProposal","value":{"changes":[{"key":"MaxValidators","subspace":"staking","value":"125"},{"key":"MintDenom","subspace":"mint","value":"\"uatom\""}],"description":"d","title":"t"}}`,
+			"",
+		},
+		{
+			"upgrade plan height",
+			`{"type":"upgrade/SoftwareUpgradeProposal","value":{"title":"t","description":"d","plan":{"name":"v5","height":6910000}}}`,
+			`{"type":"cosmos-sdk/SoftwareUpgradeProposal","value":{"description":"d","plan":{"height":"6910000","name":"v5"},"title":"t"}}`,
+			"",
+		},
+		{
+			"parameter change subkey",
+			`{"type":"cosmos-sdk/ParameterChangeProposal","value":{"title":"t","description":"d","changes":[{"subspace":"baseapp","key":"BlockParams","subkey":"MaxGas","value":"\"100\""}]}}`,
+			"",
+			"the change of baseapp BlockParams sets the subkey MaxGas, current parameter changes have no subkeys",
+		},
+		{"unknown type", `{"type":"ibc/ClientUpdateProposal","value":{"title":"t"}}`, "", "no mapping of the type"},
+		{"no content", `null`, "", "no content"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			govGenesis := []byte(`{"starting_proposal_id":"2","deposits":null,"votes":[{"proposal_id":"1","voter":"cosmos1","option":"Yes"},{"proposal_id":"1","voter":"cosmos2","option":"No"}],` +
+				`"proposals":[{"content":` + tc.content + `,"id":"1","proposal_status":"Rejected","final_tally_result":{"yes":"1","no":"2"}}]}`)
+
+			migrated, report, err := migrateGovContents(govGenesis, false)
+			if tc.reason != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "use --"+flagDropUnmappable)
+				require.Len(t, report.Unmappable, 1)
+				require.Equal(t, tc.reason, report.Unmappable[0].Reason)
+				require.Equal(t, map[string]int{"Yes": 1, "No": 1}, report.Unmappable[0].Votes)
+				require.False(t, report.Unmappable[0].Dropped)
+
+				migrated, report, err = migrateGovContents(govGenesis, true)
+				require.NoError(t, err)
+				require.True(t, report.Unmappable[0].Dropped)
+				require.JSONEq(t, `{"starting_proposal_id":"2","deposits":null,"votes":[],"proposals":[]}`, string(migrated))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Empty(t, report.Unmappable)
+
+			var state struct {
+				Proposals []struct {
+					Content json.RawMessage `json:"content"`
+				} `json:"proposals"`
+			}
+			require.NoError(t, json.Unmarshal(migrated, &state))
+			require.JSONEq(t, tc.mapped, string(state.Proposals[0].Content))
+		})
+	}
+
+	// a dropped proposal cannot leave its deposits in the gov module account
+	govGenesis := []byte(`{"deposits":[{"proposal_id":"1","depositor":"cosmos1","amount":[]}],"proposals":[{"content":null,"id":"1","proposal_status":"VotingPeriod"}]}`)
+	_, _, err := migrateGovContents(govGenesis, true)
+	require.EqualError(t, err, "proposal 1 cannot be dropped, it holds deposits")
+}
+
+func TestMigrateLegacyProposals(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "gov-content.json")
+
+	_, err := executeMigrate(t, cosmoshub3LegacyProposals, "--chain-id", "cosmoshub-4", "--"+flagGovContentReport, reportPath)
+	require.EqualError(t, err, "failed to migrate gov proposal contents: the content of 1 proposals cannot be migrated, use --drop-unmappable-proposals to remove them: proposal 4 (ibc/ClientUpdateProposal: no mapping of the type)")
+
+	var stderr bytes.Buffer
+	out, err := executeMigrateTo(t, &stderr, cosmoshub3LegacyProposals, "--chain-id", "cosmoshub-4", "--"+flagDropUnmappable, "--"+flagGovContentReport, reportPath)
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "gov: mapped the content of 2 proposals of legacy types\n")
+	require.Contains(t, stderr.String(), "gov: dropped 1 proposals whose content cannot be migrated\n")
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report govContentReport
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Equal(t, []mappedProposal{
+		{ProposalID: "2", From: "params/ParameterChangeProposal", To: "cosmos-sdk/ParameterChangeProposal"},
+		{ProposalID: "3", From: "upgrade/SoftwareUpgradeProposal", To: "cosmos-sdk/SoftwareUpgradeProposal"},
+	}, report.Mapped)
+	require.Len(t, report.Unmappable, 1)
+	dropped := report.Unmappable[0]
+	require.Equal(t, "Revive the osmosis client", dropped.Title)
+	require.Equal(t, "Rejected", dropped.Status)
+	require.Equal(t, map[string]int{"Yes": 1, "No": 2}, dropped.Votes)
+	require.JSONEq(t, `{"yes":"200000000","abstain":"0","no":"600000000","no_with_veto":"0"}`, string(dropped.FinalTallyResult))
+	require.True(t, dropped.Dropped)
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	var appState map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &appState))
+	encCfg := MakeEncodingConfig()
+	var govGenesis gov.GenesisState
+	encCfg.Marshaler.MustUnmarshalJSON(appState[gov.ModuleName], &govGenesis)
+
+	require.Len(t, govGenesis.Proposals, 3)
+	change, ok := govGenesis.Proposals[1].GetContent().(*params.ParameterChangeProposal)
+	require.True(t, ok)
+	require.Equal(t, []params.ParamChange{{Subspace: "staking", Key: "MaxValidators", Value: "125"}}, change.Changes)
+	upgradeProposal, ok := govGenesis.Proposals[2].GetContent().(*upgrade.SoftwareUpgradeProposal)
+	require.True(t, ok)
+	require.Equal(t, int64(6910000), upgradeProposal.Plan.Height)
+	require.Len(t, govGenesis.Votes, 1)
+	require.Equal(t, uint64(2), govGenesis.Votes[0].ProposalId)
+}
+
+func TestMigrateLegacyProposalsCached(t *testing.T) {
+	cacheDir := t.TempDir()
+	migrate := func() (*migrationInfo, []byte, string) {
+		reportPath := filepath.Join(t.TempDir(), "gov-content.json")
+
+		var stderr bytes.Buffer
+		out, err := executeMigrateTo(t, &stderr, cosmoshub3LegacyProposals, "--chain-id", "cosmoshub-4", "--cache-dir", cacheDir,
+			"--"+flagDropUnmappable, "--"+flagGovContentReport, reportPath, "--embed-migration-info")
+		require.NoError(t, err)
+
+		report, err := ioutil.ReadFile(reportPath)
+		require.NoError(t, err)
+		return migrationInfoOf(t, out), report, stderr.String()
+	}
+
+	info, report, log := migrate()
+	require.NotContains(t, log, "reused the cached")
+	require.Contains(t, info.Steps, flagDropUnmappable)
+
+	// the cached stages replay the report and the step of the gov content
+	// mapping
+	cachedInfo, cachedReport, log := migrate()
+	require.Contains(t, log, "reused the cached v0.40 state")
+	require.Contains(t, log, "gov: dropped 1 proposals whose content cannot be migrated\n")
+	require.Equal(t, string(report), string(cachedReport))
+	require.Equal(t, info, cachedInfo)
+}
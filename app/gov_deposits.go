@@ -0,0 +1,125 @@
+package gaia
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/pkg/errors"
+)
+
+const flagTopUpGovAccount = "top-up-gov-account"
+
+// govDepositsCheck compares the bank balance of the gov module account with
+// the deposits of the proposals in deposit or voting period, which the new
+// chain refunds or burns from it. Surplus and Deficit are what the balance
+// holds above and below them per denom, ToppedUp the part of the deficit
+// --top-up-gov-account covered.
+type govDepositsCheck struct {
+	Deposits sdk.Coins `json:"deposits"`
+	Balance  sdk.Coins `json:"balance"`
+	Surplus  sdk.Coins `json:"surplus"`
+	Deficit  sdk.Coins `json:"deficit"`
+	ToppedUp sdk.Coins `json:"topped_up"`
+}
+
+// Backed tells whether the balance is the deposits once the deficit is
+// covered.
+func (c govDepositsCheck) Backed() bool {
+	return c.Surplus.IsZero() && c.Deficit.IsEqual(c.ToppedUp)
+}
+
+// checkGovDeposits returns the check of the gov module account of state
+// against the deposits of its active proposals.
+func checkGovDeposits(cdc codec.JSONMarshaler, state types.AppMap) (govDepositsCheck, error) {
+	var (
+		bankGenesis bank.GenesisState
+		govGenesis  gov.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return govDepositsCheck{}, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", bank.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[gov.ModuleName], &govGenesis); err != nil {
+		return govDepositsCheck{}, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", gov.ModuleName)
+	}
+
+	active := make(map[uint64]bool, len(govGenesis.Proposals))
+	for _, proposal := range govGenesis.Proposals {
+		active[proposal.ProposalId] = proposal.Status == gov.StatusDepositPeriod || proposal.Status == gov.StatusVotingPeriod
+	}
+
+	check := govDepositsCheck{Deposits: sdk.NewCoins(), Balance: sdk.NewCoins(), ToppedUp: sdk.NewCoins()}
+	for _, deposit := range govGenesis.Deposits {
+		if active[deposit.ProposalId] {
+			check.Deposits = check.Deposits.Add(deposit.Amount...)
+		}
+	}
+
+	govAddr := auth.NewModuleAddress(gov.ModuleName).String()
+	for _, balance := range bankGenesis.Balances {
+		if balance.Address == govAddr {
+			check.Balance = check.Balance.Add(balance.Coins...)
+		}
+	}
+
+	check.Surplus, check.Deficit = coinsDelta(check.Balance, check.Deposits)
+	return check, nil
+}
+
+// topUpGovAccount covers the deficit of check from the bank balance of the
+// account source, moving it to the gov module account of state. The supply
+// is unchanged. The balance must cover the whole deficit, which is checked
+// before state is changed, and source cannot be a module account.
+func topUpGovAccount(cdc codec.JSONMarshaler, state types.AppMap, check *govDepositsCheck, source string, protected *protectedAddresses) error {
+	if check.Deficit.IsZero() {
+		return nil
+	}
+
+	var bankGenesis bank.GenesisState
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return errors.Wrapf(err, "failed to JSON unmarshal %s genesis", bank.ModuleName)
+	}
+
+	balance := sdk.NewCoins()
+	for _, b := range bankGenesis.Balances {
+		if b.Address == source {
+			balance = balance.Add(b.Coins...)
+		}
+	}
+	remaining, negative := balance.SafeSub(check.Deficit)
+	if negative {
+		return fmt.Errorf("account %s holds %s, less than the %s the gov module account lacks", source, balance, check.Deficit)
+	}
+
+	skip, err := protected.skip(flagTopUpGovAccount, source)
+	if err != nil || skip {
+		return err
+	}
+
+	govAddr := auth.NewModuleAddress(gov.ModuleName).String()
+	credited := check.Deficit
+	balances := bankGenesis.Balances[:0]
+	for _, b := range bankGenesis.Balances {
+		switch b.Address {
+		case source:
+			continue
+		case govAddr:
+			credited = credited.Add(b.Coins...)
+			continue
+		}
+		balances = append(balances, b)
+	}
+	if !remaining.IsZero() {
+		balances = append(balances, bank.Balance{Address: source, Coins: remaining})
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(balances, bank.Balance{Address: govAddr, Coins: credited}))
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	check.ToppedUp = check.Deficit
+	check.Balance = check.Balance.Add(check.Deficit...)
+	return nil
+}
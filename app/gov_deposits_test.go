@@ -0,0 +1,140 @@
+package gaia
+
+import (
+	"bytes"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+func TestCheckGovDeposits(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	atoms := func(amount int64) sdk.Coins { return sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, amount)) }
+	b := genesisbuilder.New().
+		WithValidatorPowers(10).
+		WithAccount("depositor", atoms(3000000)).
+		WithAccount("treasury", atoms(4000000)).
+		WithProposal(govtypes.StatusVotingPeriod, atoms(10000000)).
+		WithProposal(govtypes.StatusDepositPeriod, atoms(5000000)).
+		WithProposal(govtypes.StatusPassed, atoms(10000000))
+	govAddr := auth.NewModuleAddress(govtypes.ModuleName).String()
+	treasury := b.Address("treasury").String()
+
+	_, state := buildTestGenesis(t, b)
+	check, err := checkGovDeposits(cdc, state)
+	require.NoError(t, err)
+	require.True(t, check.Backed())
+	require.Equal(t, atoms(15000000), check.Deposits)
+
+	t.Run("blocked depositor", func(t *testing.T) {
+		blocked := []string{b.Address("depositor").String()}
+
+		// the deposits leave the gov module account with the depositor
+		state := copyAppMap(state)
+		_, err := applyBlocklist(cdc, state, blocked, blocklistOptions{Destination: blockedCommunityPool, AccountAction: blockedAccountRemove}, &warningCollector{})
+		require.NoError(t, err)
+		check, err := checkGovDeposits(cdc, state)
+		require.NoError(t, err)
+		require.True(t, check.Backed())
+		require.True(t, check.Deposits.IsZero())
+
+		// but they come back when the gov module account is the destination
+		_, state = buildTestGenesis(t, b)
+		_, err = applyBlocklist(cdc, state, blocked, blocklistOptions{Destination: govAddr, AccountAction: blockedAccountRemove}, &warningCollector{})
+		require.NoError(t, err)
+		check, err = checkGovDeposits(cdc, state)
+		require.NoError(t, err)
+		require.False(t, check.Backed())
+		require.Equal(t, atoms(18000000), check.Surplus)
+		require.True(t, check.Deficit.IsZero())
+
+		// a surplus is not covered
+		before := copyAppMap(state)
+		require.NoError(t, topUpGovAccount(cdc, state, &check, treasury, nil))
+		require.Equal(t, before, state)
+		require.False(t, check.Backed())
+	})
+
+	t.Run("deficit", func(t *testing.T) {
+		// a prop29 entry sourced from the gov module account
+		state := copyAppMap(state)
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		_, err := applyRecoveries(&bankGenesis, []recoveryEntry{{From: govAddr, To: b.Address("other").String(), Amount: atoms(3500000)}})
+		require.NoError(t, err)
+		state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+		check, err := checkGovDeposits(cdc, state)
+		require.NoError(t, err)
+		require.False(t, check.Backed())
+		require.Equal(t, atoms(3500000), check.Deficit)
+		require.Equal(t, atoms(11500000), check.Balance)
+
+		// a source holding too little fails before anything changes
+		before := copyAppMap(state)
+		err = topUpGovAccount(cdc, state, &check, b.Address("depositor").String(), nil)
+		require.EqualError(t, err, "account "+b.Address("depositor").String()+" holds 3000000uatom, less than the 3500000uatom the gov module account lacks")
+		require.Equal(t, before, state)
+		require.True(t, check.ToppedUp.IsZero())
+
+		require.NoError(t, topUpGovAccount(cdc, state, &check, treasury, nil))
+		require.True(t, check.Backed())
+		require.Equal(t, atoms(3500000), check.ToppedUp)
+
+		again, err := checkGovDeposits(cdc, state)
+		require.NoError(t, err)
+		require.True(t, again.Backed())
+
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		for _, balance := range bankGenesis.Balances {
+			if balance.Address == treasury {
+				require.Equal(t, atoms(500000), balance.Coins)
+			}
+		}
+	})
+}
+
+func TestMigrateGovDeposits(t *testing.T) {
+	const depositor = "cosmos14e05nn094zxyx3nz898raf287w74zhdwx9af6r"
+	govAddr := auth.NewModuleAddress(govtypes.ModuleName).String()
+
+	// proposal 1 in voting period with a deposit of the depositor, held by
+	// the legacy deposits account
+	depositsAddr := sdk.AccAddress(crypto.AddressHash([]byte("govDepositedCoins"))).String()
+	source := writeMutatedGenesis(t, func(_, appState map[string]interface{}) {
+		appState["accounts"] = append(appState["accounts"].([]interface{}), map[string]interface{}{
+			"address": depositsAddr, "coins": []interface{}{map[string]interface{}{"denom": "uatom", "amount": "512000000"}},
+			"sequence_number": "0", "account_number": "3", "original_vesting": []interface{}{}, "delegated_free": []interface{}{},
+			"delegated_vesting": []interface{}{}, "start_time": "0", "end_time": "0",
+		})
+		gov := appState["gov"].(map[string]interface{})
+		proposal := gov["proposals"].([]interface{})[0].(map[string]interface{})
+		proposal["proposal_status"] = "VotingPeriod"
+		proposal["final_tally_result"] = map[string]interface{}{"yes": "0", "abstain": "0", "no": "0", "no_with_veto": "0"}
+		proposal["voting_end_time"] = "2100-01-01T00:00:00Z"
+		gov["deposits"] = []interface{}{map[string]interface{}{
+			"proposal_id": "1",
+			"deposit":     map[string]interface{}{"proposal_id": "1", "depositor": depositor, "amount": []interface{}{map[string]interface{}{"denom": "uatom", "amount": "512000000"}}},
+		}}
+	})
+	args := []string{source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--genesis-time", "2021-02-18T06:00:00Z"}
+
+	_, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+
+	// the blocklist drops the deposit of the depositor, and the gov module
+	// account keeps it along with the blocked funds it receives
+	blocked := writeConfig(t, "blocked.json", `["`+depositor+`"]`)
+	blockedArgs := append(args, "--blocked-addresses", blocked, "--blocked-destination", govAddr)
+	var log bytes.Buffer
+	_, err = executeMigrateTo(t, &log, blockedArgs...)
+	require.NoError(t, err)
+	require.Contains(t, log.String(), "W-GOV-004")
+	require.Contains(t, log.String(), "the gov module account holds 562000000uatom more than the deposits of the active proposals")
+}
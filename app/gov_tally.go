@@ -0,0 +1,219 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+// Projected outcomes of a proposal tally.
+const (
+	tallyPasses   = "passes"
+	tallyRejected = "rejected"
+	tallyNoQuorum = "no quorum"
+	tallyVetoed   = "vetoed"
+)
+
+// proposalTally is the tally a proposal in voting period would get if its
+// voting period ended on the genesis state. StaleVotes are the voters whose
+// vote carries no voting power, they neither are a bonded validator nor
+// delegate to one.
+type proposalTally struct {
+	ProposalID  uint64          `json:"proposal_id"`
+	Tally       gov.TallyResult `json:"tally"`
+	TotalBonded sdk.Int         `json:"total_bonded"`
+	Outcome     string          `json:"outcome"`
+	StaleVotes  []string        `json:"stale_votes"`
+}
+
+// String implements the fmt.Stringer interface.
+func (t proposalTally) String() string {
+	return fmt.Sprintf("yes %s, no %s, no with veto %s, abstain %s of %s bonded, %s",
+		t.Tally.Yes, t.Tally.No, t.Tally.NoWithVeto, t.Tally.Abstain, t.TotalBonded, t.Outcome)
+}
+
+// govTallyReport compares the projected tally of a proposal on the migrated
+// state, before the migration options changing stake ran, and after.
+type govTallyReport struct {
+	ProposalID   uint64         `json:"proposal_id"`
+	Before       *proposalTally `json:"before"`
+	After        proposalTally  `json:"after"`
+	DroppedVotes int            `json:"dropped_votes"`
+}
+
+// projectTallies returns the projected tally of every proposal of state in
+// voting period, by proposal ID in increasing order. The votes are tallied as
+// the gov end blocker does, against the bonded validators and the
+// delegations of the staking genesis.
+func projectTallies(cdc codec.JSONMarshaler, state types.AppMap) ([]proposalTally, error) {
+	var (
+		govGenesis     gov.GenesisState
+		stakingGenesis staking.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[gov.ModuleName], &govGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", gov.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", staking.ModuleName)
+	}
+
+	delegations := make(map[string][]staking.Delegation)
+	for _, del := range stakingGenesis.Delegations {
+		delegations[del.DelegatorAddress] = append(delegations[del.DelegatorAddress], del)
+	}
+
+	votes := make(map[uint64][]gov.Vote)
+	for _, vote := range govGenesis.Votes {
+		votes[vote.ProposalId] = append(votes[vote.ProposalId], vote)
+	}
+
+	var tallies []proposalTally
+	for _, proposal := range govGenesis.Proposals {
+		if proposal.Status != gov.StatusVotingPeriod {
+			continue
+		}
+
+		tally, err := tallyProposal(proposal.ProposalId, votes[proposal.ProposalId], stakingGenesis.Validators, delegations, govGenesis.TallyParams)
+		if err != nil {
+			return nil, err
+		}
+		tallies = append(tallies, tally)
+	}
+
+	sort.Slice(tallies, func(i, j int) bool { return tallies[i].ProposalID < tallies[j].ProposalID })
+
+	return tallies, nil
+}
+
+// tallyProposal tallies votes as the Tally of the gov keeper, a bonded
+// validator voting with the shares its voting delegators did not deduct.
+func tallyProposal(proposalID uint64, votes []gov.Vote, validators staking.Validators, delegations map[string][]staking.Delegation, params gov.TallyParams) (proposalTally, error) {
+	type validatorVote struct {
+		validator  staking.Validator
+		option     gov.VoteOption
+		deductions sdk.Dec
+	}
+
+	bonded := make(map[string]*validatorVote)
+	totalBonded := sdk.ZeroInt()
+	for _, val := range validators {
+		if val.IsBonded() {
+			bonded[val.OperatorAddress] = &validatorVote{validator: val, option: gov.OptionEmpty, deductions: sdk.ZeroDec()}
+			totalBonded = totalBonded.Add(val.BondedTokens())
+		}
+	}
+
+	results := map[gov.VoteOption]sdk.Dec{
+		gov.OptionYes:        sdk.ZeroDec(),
+		gov.OptionAbstain:    sdk.ZeroDec(),
+		gov.OptionNo:         sdk.ZeroDec(),
+		gov.OptionNoWithVeto: sdk.ZeroDec(),
+	}
+	totalVotingPower := sdk.ZeroDec()
+
+	tally := proposalTally{ProposalID: proposalID, TotalBonded: totalBonded}
+	for _, vote := range votes {
+		voter, err := sdk.AccAddressFromBech32(vote.Voter)
+		if err != nil {
+			return tally, errors.Wrapf(err, "invalid voter on proposal %d", proposalID)
+		}
+
+		stale := true
+		if val, ok := bonded[sdk.ValAddress(voter).String()]; ok {
+			val.option = vote.Option
+			stale = false
+		}
+
+		for _, del := range delegations[vote.Voter] {
+			val, ok := bonded[del.ValidatorAddress]
+			if !ok || val.validator.DelegatorShares.IsZero() {
+				continue
+			}
+
+			val.deductions = val.deductions.Add(del.Shares)
+			votingPower := del.Shares.MulInt(val.validator.BondedTokens()).Quo(val.validator.DelegatorShares)
+			results[vote.Option] = results[vote.Option].Add(votingPower)
+			totalVotingPower = totalVotingPower.Add(votingPower)
+			stale = stale && !del.Shares.IsPositive()
+		}
+
+		if stale {
+			tally.StaleVotes = append(tally.StaleVotes, vote.Voter)
+		}
+	}
+
+	for _, val := range bonded {
+		if val.option == gov.OptionEmpty || val.validator.DelegatorShares.IsZero() {
+			continue
+		}
+
+		sharesAfterDeductions := val.validator.DelegatorShares.Sub(val.deductions)
+		votingPower := sharesAfterDeductions.MulInt(val.validator.BondedTokens()).Quo(val.validator.DelegatorShares)
+		results[val.option] = results[val.option].Add(votingPower)
+		totalVotingPower = totalVotingPower.Add(votingPower)
+	}
+
+	tally.Tally = gov.NewTallyResultFromMap(results)
+
+	nonAbstaining := totalVotingPower.Sub(results[gov.OptionAbstain])
+	switch {
+	case totalBonded.IsZero():
+		tally.Outcome = tallyRejected
+	case totalVotingPower.Quo(totalBonded.ToDec()).LT(params.Quorum):
+		tally.Outcome = tallyNoQuorum
+	case nonAbstaining.IsZero():
+		tally.Outcome = tallyRejected
+	case results[gov.OptionNoWithVeto].Quo(totalVotingPower).GT(params.VetoThreshold):
+		tally.Outcome = tallyVetoed
+	case results[gov.OptionYes].Quo(nonAbstaining).GT(params.Threshold):
+		tally.Outcome = tallyPasses
+	default:
+		tally.Outcome = tallyRejected
+	}
+
+	return tally, nil
+}
+
+// dropStaleVotes removes the stale votes of tallies from the gov genesis of
+// state and returns how many it removed per proposal. The tallies are
+// unchanged, stale votes carry no voting power.
+func dropStaleVotes(cdc codec.JSONMarshaler, state types.AppMap, tallies []proposalTally) map[uint64]int {
+	type voteKey struct {
+		proposalID uint64
+		voter      string
+	}
+	stale := make(map[voteKey]bool)
+	for _, tally := range tallies {
+		for _, voter := range tally.StaleVotes {
+			stale[voteKey{tally.ProposalID, voter}] = true
+		}
+	}
+
+	dropped := make(map[uint64]int)
+	if len(stale) == 0 {
+		return dropped
+	}
+
+	var govGenesis gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+
+	votes := govGenesis.Votes[:0]
+	for _, vote := range govGenesis.Votes {
+		if stale[voteKey{vote.ProposalId, vote.Voter}] {
+			dropped[vote.ProposalId]++
+			continue
+		}
+
+		votes = append(votes, vote)
+	}
+	govGenesis.Votes = votes
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+
+	return dropped
+}
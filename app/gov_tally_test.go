@@ -0,0 +1,85 @@
+package gaia
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectTallies(t *testing.T) {
+	b := testGenesisBuilder()
+	genDoc, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	var govGenesis gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+
+	var proposalID uint64
+	for _, proposal := range govGenesis.Proposals {
+		if proposal.Status == gov.StatusVotingPeriod {
+			proposalID = proposal.ProposalId
+		}
+	}
+	require.NotZero(t, proposalID)
+
+	vote := func(voter sdk.AccAddress, option gov.VoteOption) gov.Vote {
+		return gov.NewVote(proposalID, voter, option)
+	}
+	govGenesis.Votes = append(govGenesis.Votes,
+		vote(b.Address(genesisbuilder.ValidatorName(0)), gov.OptionYes),
+		vote(b.Address("alice"), gov.OptionYes),
+		vote(b.Address(genesisbuilder.ValidatorName(1)), gov.OptionAbstain),
+		vote(b.Address("carol"), gov.OptionNo),
+		// bob only unbonds
+		vote(b.Address("bob"), gov.OptionNoWithVeto),
+	)
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+
+	before, err := projectTallies(cdc, state)
+	require.NoError(t, err)
+	require.Len(t, before, 1)
+	require.Equal(t, gov.NewTallyResult(sdk.NewInt(12000000), sdk.NewInt(20000000), sdk.NewInt(3000000), sdk.ZeroInt()), before[0].Tally)
+	require.Equal(t, sdk.NewInt(65000000), before[0].TotalBonded)
+	require.Equal(t, tallyPasses, before[0].Outcome)
+	require.Equal(t, []string{b.Address("bob").String()}, before[0].StaleVotes)
+
+	// keeping two validators demotes the first, its voters lose their power
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	stakingGenesis.Params.MaxValidators = 2
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	demotions, err := capBondedValidators(cdc, state, 1, genDoc.GenesisTime)
+	require.NoError(t, err)
+	require.Len(t, demotions, 1)
+	require.Equal(t, b.ValidatorAddress(0).String(), demotions[0].OperatorAddress)
+
+	after, err := projectTallies(cdc, state)
+	require.NoError(t, err)
+	require.Len(t, after, 1)
+	require.Equal(t, gov.NewTallyResult(sdk.ZeroInt(), sdk.NewInt(20000000), sdk.NewInt(3000000), sdk.ZeroInt()), after[0].Tally)
+	require.Equal(t, sdk.NewInt(53000000), after[0].TotalBonded)
+	require.Equal(t, tallyRejected, after[0].Outcome)
+	require.ElementsMatch(t, []string{
+		b.Address(genesisbuilder.ValidatorName(0)).String(),
+		b.Address("alice").String(),
+		b.Address("bob").String(),
+	}, after[0].StaleVotes)
+
+	dropped := dropStaleVotes(cdc, state, after)
+	require.Equal(t, map[uint64]int{proposalID: 3}, dropped)
+
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	require.Len(t, govGenesis.Votes, 2)
+
+	// stale votes carry no power, dropping them keeps the tally
+	kept, err := projectTallies(cdc, state)
+	require.NoError(t, err)
+	after[0].StaleVotes = nil
+	require.Equal(t, after, kept)
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+}
@@ -0,0 +1,587 @@
+// Package genesisbuilder builds small gaia genesis documents for the tests of
+// the migration and genesis tooling.
+package genesisbuilder
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	captypes "github.com/cosmos/cosmos-sdk/x/capability/types"
+	crisistypes "github.com/cosmos/cosmos-sdk/x/crisis/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	ibctransfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	connectiontypes "github.com/cosmos/cosmos-sdk/x/ibc/core/03-connection/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	commitmenttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/23-commitment/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	"github.com/cosmos/cosmos-sdk/x/ibc/core/exported"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	cryptocodec "github.com/tendermint/tendermint/crypto/encoding"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/internal/modules"
+)
+
+const (
+	// BondDenom is the staking denom of genesis documents built by Builder.
+	BondDenom = "uatom"
+	// ChainID is the default chain ID of genesis documents built by Builder.
+	ChainID = "gaia-test-1"
+)
+
+// GenesisTime is the default genesis time of genesis documents built by Builder.
+var GenesisTime = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+type builderAccount struct {
+	name  string
+	coins sdk.Coins
+}
+
+type builderDelegation struct {
+	delegator  string
+	validator  int
+	tokens     int64
+	completion time.Duration
+}
+
+type builderRedelegation struct {
+	delegator  string
+	src, dst   int
+	tokens     int64
+	completion time.Duration
+}
+
+type builderVesting struct {
+	name       string
+	coins      sdk.Coins
+	start, end time.Duration
+}
+
+type builderProposal struct {
+	status  govtypes.ProposalStatus
+	deposit sdk.Coins
+}
+
+// Builder builds small but structurally realistic gaia genesis documents
+// for tests. Keys, addresses and timestamps are all derived deterministically,
+// so the same builder calls always produce the same genesis.
+//
+// Validators are bonded and self-delegated by their operator account, extra
+// delegations add to their tokens. Accounts and validators are referred to by
+// name and index, see Address and ValidatorAddress.
+type Builder struct {
+	chainID     string
+	genesisTime time.Time
+	powers      []int64
+	accounts    []builderAccount
+	delegations []builderDelegation
+	unbondings  []builderDelegation
+	redels      []builderRedelegation
+	vesting     []builderVesting
+	proposals   []builderProposal
+	ibcChannels []string
+}
+
+// New returns a builder for an empty genesis with the test chain ID and
+// genesis time.
+func New() *Builder {
+	return &Builder{
+		chainID:     ChainID,
+		genesisTime: GenesisTime,
+	}
+}
+
+// WithChainID sets the chain ID of the genesis.
+func (b *Builder) WithChainID(chainID string) *Builder {
+	b.chainID = chainID
+	return b
+}
+
+// WithGenesisTime sets the genesis time, all other timestamps are relative to it.
+func (b *Builder) WithGenesisTime(t time.Time) *Builder {
+	b.genesisTime = t
+	return b
+}
+
+// WithValidators adds n validators with self-bonded powers 10, 20, 30, ...
+func (b *Builder) WithValidators(n int) *Builder {
+	powers := make([]int64, n)
+	for i := range powers {
+		powers[i] = int64(10 * (len(b.powers) + i + 1))
+	}
+
+	return b.WithValidatorPowers(powers...)
+}
+
+// WithValidatorPowers adds a validator for each of the given self-bonded powers.
+func (b *Builder) WithValidatorPowers(powers ...int64) *Builder {
+	b.powers = append(b.powers, powers...)
+	return b
+}
+
+// WithAccount adds a base account holding coins.
+func (b *Builder) WithAccount(name string, coins sdk.Coins) *Builder {
+	b.accounts = append(b.accounts, builderAccount{name: name, coins: coins})
+	return b
+}
+
+// WithDelegation adds a delegation of tokens bond denom from the named account
+// to the validator at index validator.
+func (b *Builder) WithDelegation(delegator string, validator int, tokens int64) *Builder {
+	b.delegations = append(b.delegations, builderDelegation{delegator: delegator, validator: validator, tokens: tokens})
+	return b
+}
+
+// WithUnbondingDelegation adds an unbonding delegation of tokens bond denom
+// from the named account and validator, completing completion after genesis.
+// Unbondings from the same validator add entries to the same unbonding
+// delegation.
+func (b *Builder) WithUnbondingDelegation(delegator string, validator int, tokens int64, completion time.Duration) *Builder {
+	b.unbondings = append(b.unbondings, builderDelegation{delegator: delegator, validator: validator, tokens: tokens, completion: completion})
+	return b
+}
+
+// WithRedelegation adds a delegation of tokens bond denom from the named
+// account to the validator at index dst, redelegated from the validator at
+// index src and completing completion after genesis. Redelegations between
+// the same validators add entries to the same redelegation.
+func (b *Builder) WithRedelegation(delegator string, src, dst int, tokens int64, completion time.Duration) *Builder {
+	b.redels = append(b.redels, builderRedelegation{delegator: delegator, src: src, dst: dst, tokens: tokens, completion: completion})
+	return b
+}
+
+// WithVestingAccount adds a continuous vesting account holding and vesting
+// coins between start and end, both relative to the genesis time.
+func (b *Builder) WithVestingAccount(name string, coins sdk.Coins, start, end time.Duration) *Builder {
+	b.vesting = append(b.vesting, builderVesting{name: name, coins: coins, start: start, end: end})
+	return b
+}
+
+// WithProposal adds a text proposal in the given status, with deposit made by
+// the "depositor" account.
+func (b *Builder) WithProposal(status govtypes.ProposalStatus, deposit sdk.Coins) *Builder {
+	b.proposals = append(b.proposals, builderProposal{status: status, deposit: deposit})
+	return b
+}
+
+// WithIBCChannel adds an open transfer channel to a counterparty chain, along
+// with its tendermint client and connection.
+func (b *Builder) WithIBCChannel(counterpartyChainID string) *Builder {
+	b.ibcChannels = append(b.ibcChannels, counterpartyChainID)
+	return b
+}
+
+// Address returns the address of the named account.
+func (b *Builder) Address(name string) sdk.AccAddress {
+	return sdk.AccAddress(secp256k1.GenPrivKeyFromSecret([]byte(name)).PubKey().Address())
+}
+
+// ValidatorAddress returns the operator address of the validator at index i.
+func (b *Builder) ValidatorAddress(i int) sdk.ValAddress {
+	return sdk.ValAddress(b.Address(ValidatorName(i)))
+}
+
+// ValidatorConsAddress returns the consensus address of the validator at index i.
+func (b *Builder) ValidatorConsAddress(i int) sdk.ConsAddress {
+	return sdk.ConsAddress(ValidatorConsKey(i).PubKey().Address())
+}
+
+// ValidatorName returns the name of the operator account of the validator at
+// index i.
+func ValidatorName(i int) string {
+	return fmt.Sprintf("validator%d", i)
+}
+
+// ValidatorConsKey returns the consensus key of the validator at index i.
+func ValidatorConsKey(i int) *ed25519.PrivKey {
+	return ed25519.GenPrivKeyFromSecret([]byte(ValidatorName(i) + "-consensus"))
+}
+
+// Build returns the genesis document. Its app state is checked against every
+// module's ValidateGenesis.
+func (b *Builder) Build() (*tmtypes.GenesisDoc, error) {
+	encCfg := modules.MakeEncodingConfig()
+	cdc := encCfg.Marshaler
+	state := modules.Basics.DefaultGenesis(cdc)
+
+	var (
+		authGenesis     authtypes.GenesisState
+		bankGenesis     banktypes.GenesisState
+		stakingGenesis  stakingtypes.GenesisState
+		slashingGenesis slashingtypes.GenesisState
+		mintGenesis     minttypes.GenesisState
+		crisisGenesis   crisistypes.GenesisState
+		govGenesis      govtypes.GenesisState
+	)
+
+	cdc.MustUnmarshalJSON(state[authtypes.ModuleName], &authGenesis)
+	cdc.MustUnmarshalJSON(state[banktypes.ModuleName], &bankGenesis)
+	cdc.MustUnmarshalJSON(state[stakingtypes.ModuleName], &stakingGenesis)
+	cdc.MustUnmarshalJSON(state[slashingtypes.ModuleName], &slashingGenesis)
+	cdc.MustUnmarshalJSON(state[minttypes.ModuleName], &mintGenesis)
+	cdc.MustUnmarshalJSON(state[crisistypes.ModuleName], &crisisGenesis)
+	cdc.MustUnmarshalJSON(state[govtypes.ModuleName], &govGenesis)
+
+	stakingGenesis.Params.BondDenom = BondDenom
+	mintGenesis.Params.MintDenom = BondDenom
+	crisisGenesis.ConstantFee.Denom = BondDenom
+	govGenesis.DepositParams.MinDeposit = sdk.NewCoins(sdk.NewInt64Coin(BondDenom, 10000000))
+
+	var accounts []authtypes.GenesisAccount
+	balances := make(map[string]sdk.Coins)
+	knownAccounts := make(map[string]bool)
+
+	addAccount := func(acc authtypes.GenesisAccount, coins sdk.Coins) {
+		if knownAccounts[acc.GetAddress().String()] {
+			return
+		}
+
+		if err := acc.SetAccountNumber(uint64(len(accounts))); err != nil {
+			panic(err)
+		}
+
+		accounts = append(accounts, acc)
+		knownAccounts[acc.GetAddress().String()] = true
+		balances[acc.GetAddress().String()] = balances[acc.GetAddress().String()].Add(coins...)
+	}
+
+	addBaseAccount := func(name string, coins sdk.Coins) {
+		pubKey := secp256k1.GenPrivKeyFromSecret([]byte(name)).PubKey()
+		addAccount(authtypes.NewBaseAccount(sdk.AccAddress(pubKey.Address()), pubKey, 0, 0), coins)
+	}
+
+	bondedTokens := sdk.ZeroInt()
+	notBondedTokens := sdk.ZeroInt()
+
+	for i, power := range b.powers {
+		addBaseAccount(ValidatorName(i), nil)
+
+		val, err := stakingtypes.NewValidator(b.ValidatorAddress(i), ValidatorConsKey(i).PubKey(), stakingtypes.Description{Moniker: ValidatorName(i)})
+		if err != nil {
+			return nil, err
+		}
+
+		tokens := sdk.TokensFromConsensusPower(power)
+		val.Status = stakingtypes.Bonded
+		val.Tokens = tokens
+		val.DelegatorShares = tokens.ToDec()
+		val.MinSelfDelegation = sdk.OneInt()
+		val.Commission = stakingtypes.NewCommission(sdk.NewDecWithPrec(1, 1), sdk.NewDecWithPrec(2, 1), sdk.NewDecWithPrec(1, 2))
+		val.Commission.UpdateTime = b.genesisTime.Add(-24 * time.Hour)
+
+		stakingGenesis.Validators = append(stakingGenesis.Validators, val)
+		stakingGenesis.Delegations = append(stakingGenesis.Delegations,
+			stakingtypes.NewDelegation(b.Address(ValidatorName(i)), b.ValidatorAddress(i), tokens.ToDec()))
+
+		slashingGenesis.SigningInfos = append(slashingGenesis.SigningInfos, slashingtypes.SigningInfo{
+			Address:              b.ValidatorConsAddress(i).String(),
+			ValidatorSigningInfo: slashingtypes.NewValidatorSigningInfo(b.ValidatorConsAddress(i), 0, 0, time.Unix(0, 0).UTC(), false, 0),
+		})
+		slashingGenesis.MissedBlocks = append(slashingGenesis.MissedBlocks, slashingtypes.ValidatorMissedBlocks{
+			Address:      b.ValidatorConsAddress(i).String(),
+			MissedBlocks: []slashingtypes.MissedBlock{},
+		})
+
+		bondedTokens = bondedTokens.Add(tokens)
+	}
+
+	for _, acc := range b.accounts {
+		addBaseAccount(acc.name, acc.coins)
+	}
+
+	for _, v := range b.vesting {
+		pubKey := secp256k1.GenPrivKeyFromSecret([]byte(v.name)).PubKey()
+		baseAcc := authtypes.NewBaseAccount(sdk.AccAddress(pubKey.Address()), pubKey, 0, 0)
+		addAccount(vestingtypes.NewContinuousVestingAccount(baseAcc, v.coins,
+			b.genesisTime.Add(v.start).Unix(), b.genesisTime.Add(v.end).Unix()), v.coins)
+	}
+
+	for _, del := range b.delegations {
+		if del.validator >= len(stakingGenesis.Validators) {
+			return nil, fmt.Errorf("delegation from %s to unknown validator %d", del.delegator, del.validator)
+		}
+
+		addBaseAccount(del.delegator, nil)
+
+		tokens := sdk.NewInt(del.tokens)
+		val := &stakingGenesis.Validators[del.validator]
+		val.Tokens = val.Tokens.Add(tokens)
+		val.DelegatorShares = val.DelegatorShares.Add(tokens.ToDec())
+
+		stakingGenesis.Delegations = append(stakingGenesis.Delegations,
+			stakingtypes.NewDelegation(b.Address(del.delegator), b.ValidatorAddress(del.validator), tokens.ToDec()))
+
+		bondedTokens = bondedTokens.Add(tokens)
+	}
+
+	ubdIndexes := make(map[string]int)
+	for _, ubd := range b.unbondings {
+		if ubd.validator >= len(stakingGenesis.Validators) {
+			return nil, fmt.Errorf("unbonding delegation from %s to unknown validator %d", ubd.delegator, ubd.validator)
+		}
+
+		addBaseAccount(ubd.delegator, nil)
+
+		completion := b.genesisTime.Add(ubd.completion)
+		key := fmt.Sprintf("%s/%d", ubd.delegator, ubd.validator)
+		if i, ok := ubdIndexes[key]; ok {
+			stakingGenesis.UnbondingDelegations[i].AddEntry(1, completion, sdk.NewInt(ubd.tokens))
+		} else {
+			ubdIndexes[key] = len(stakingGenesis.UnbondingDelegations)
+			stakingGenesis.UnbondingDelegations = append(stakingGenesis.UnbondingDelegations, stakingtypes.NewUnbondingDelegation(
+				b.Address(ubd.delegator), b.ValidatorAddress(ubd.validator), 1, completion, sdk.NewInt(ubd.tokens)))
+		}
+
+		notBondedTokens = notBondedTokens.Add(sdk.NewInt(ubd.tokens))
+	}
+
+	redIndexes := make(map[string]int)
+	for _, red := range b.redels {
+		if red.src >= len(stakingGenesis.Validators) || red.dst >= len(stakingGenesis.Validators) {
+			return nil, fmt.Errorf("redelegation from %s between unknown validators %d and %d", red.delegator, red.src, red.dst)
+		}
+
+		addBaseAccount(red.delegator, nil)
+
+		tokens := sdk.NewInt(red.tokens)
+		val := &stakingGenesis.Validators[red.dst]
+		val.Tokens = val.Tokens.Add(tokens)
+		val.DelegatorShares = val.DelegatorShares.Add(tokens.ToDec())
+
+		completion := b.genesisTime.Add(red.completion)
+		key := fmt.Sprintf("%s/%d/%d", red.delegator, red.src, red.dst)
+		if i, ok := redIndexes[key]; ok {
+			stakingGenesis.Redelegations[i].AddEntry(1, completion, tokens, tokens.ToDec())
+			for j, del := range stakingGenesis.Delegations {
+				if del.DelegatorAddress == b.Address(red.delegator).String() && del.ValidatorAddress == b.ValidatorAddress(red.dst).String() {
+					stakingGenesis.Delegations[j].Shares = del.Shares.Add(tokens.ToDec())
+				}
+			}
+		} else {
+			redIndexes[key] = len(stakingGenesis.Redelegations)
+			stakingGenesis.Redelegations = append(stakingGenesis.Redelegations, stakingtypes.NewRedelegation(
+				b.Address(red.delegator), b.ValidatorAddress(red.src), b.ValidatorAddress(red.dst), 1, completion, tokens, tokens.ToDec()))
+			stakingGenesis.Delegations = append(stakingGenesis.Delegations,
+				stakingtypes.NewDelegation(b.Address(red.delegator), b.ValidatorAddress(red.dst), tokens.ToDec()))
+		}
+
+		bondedTokens = bondedTokens.Add(tokens)
+	}
+
+	govDeposits := sdk.NewCoins()
+	if len(b.proposals) > 0 {
+		addBaseAccount("depositor", nil)
+	}
+
+	for i, p := range b.proposals {
+		id := uint64(i + 1)
+		submitTime := b.genesisTime.Add(-time.Hour)
+
+		proposal, err := govtypes.NewProposal(govtypes.NewTextProposal(fmt.Sprintf("Proposal %d", id), "A test proposal"),
+			id, submitTime, submitTime.Add(govGenesis.DepositParams.MaxDepositPeriod))
+		if err != nil {
+			return nil, err
+		}
+
+		proposal.Status = p.status
+		proposal.TotalDeposit = p.deposit
+		if p.status != govtypes.StatusDepositPeriod {
+			proposal.VotingStartTime = submitTime
+			proposal.VotingEndTime = submitTime.Add(govGenesis.VotingParams.VotingPeriod)
+		}
+
+		govGenesis.Proposals = append(govGenesis.Proposals, proposal)
+
+		if p.status == govtypes.StatusDepositPeriod || p.status == govtypes.StatusVotingPeriod {
+			govGenesis.Deposits = append(govGenesis.Deposits, govtypes.NewDeposit(id, b.Address("depositor"), p.deposit))
+			govDeposits = govDeposits.Add(p.deposit...)
+		}
+	}
+
+	govGenesis.StartingProposalId = uint64(len(b.proposals) + 1)
+
+	ibcGenesis, capGenesis := b.buildIBC(stakingGenesis.Params.UnbondingTime)
+
+	bondDenomCoins := func(amt sdk.Int) sdk.Coins {
+		return sdk.NewCoins(sdk.NewCoin(BondDenom, amt))
+	}
+
+	moduleBalances := map[string]sdk.Coins{
+		stakingtypes.BondedPoolName:    bondDenomCoins(bondedTokens),
+		stakingtypes.NotBondedPoolName: bondDenomCoins(notBondedTokens),
+		govtypes.ModuleName:            govDeposits,
+	}
+
+	moduleNames := make([]string, 0, len(modules.AccountPermissions))
+	for name := range modules.AccountPermissions {
+		moduleNames = append(moduleNames, name)
+	}
+	sort.Strings(moduleNames)
+
+	for _, name := range moduleNames {
+		addAccount(authtypes.NewEmptyModuleAccount(name, modules.AccountPermissions[name]...), moduleBalances[name])
+	}
+
+	packedAccounts, err := authtypes.PackAccounts(accounts)
+	if err != nil {
+		return nil, err
+	}
+	authGenesis.Accounts = packedAccounts
+
+	supply := sdk.NewCoins()
+	bankGenesis.Balances = nil
+	for _, acc := range accounts {
+		coins := balances[acc.GetAddress().String()]
+		bankGenesis.Balances = append(bankGenesis.Balances, banktypes.Balance{Address: acc.GetAddress().String(), Coins: coins})
+		supply = supply.Add(coins...)
+	}
+	bankGenesis.Supply = supply
+
+	state[authtypes.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	state[banktypes.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[stakingtypes.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[slashingtypes.ModuleName] = cdc.MustMarshalJSON(&slashingGenesis)
+	state[minttypes.ModuleName] = cdc.MustMarshalJSON(&mintGenesis)
+	state[crisistypes.ModuleName] = cdc.MustMarshalJSON(&crisisGenesis)
+	state[govtypes.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+	state[host.ModuleName] = cdc.MustMarshalJSON(ibcGenesis)
+	state[captypes.ModuleName] = cdc.MustMarshalJSON(capGenesis)
+
+	if err := modules.Basics.ValidateGenesis(cdc, encCfg.TxConfig, state); err != nil {
+		return nil, err
+	}
+
+	appState, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	validators, err := tmValidators(stakingGenesis)
+	if err != nil {
+		return nil, err
+	}
+
+	genDoc := &tmtypes.GenesisDoc{
+		GenesisTime:     b.genesisTime,
+		ChainID:         b.chainID,
+		InitialHeight:   1,
+		ConsensusParams: tmtypes.DefaultConsensusParams(),
+		Validators:      validators,
+		AppState:        appState,
+	}
+
+	return genDoc, genDoc.ValidateAndComplete()
+}
+
+// buildIBC returns the ibc and capability genesis for the configured channels.
+// Channel i uses client 07-tendermint-i, connection-i and transfer/channel-i.
+func (b *Builder) buildIBC(unbondingPeriod time.Duration) (*ibccoretypes.GenesisState, *captypes.GenesisState) {
+	ibcGenesis := ibccoretypes.DefaultGenesisState()
+	capGenesis := captypes.DefaultGenesis()
+
+	if len(b.ibcChannels) == 0 {
+		return ibcGenesis, capGenesis
+	}
+
+	// the transfer port capability is index 1, channel capabilities follow
+	portOwners := captypes.NewCapabilityOwners()
+	if err := portOwners.Set(captypes.NewOwner(ibctransfertypes.ModuleName, host.PortPath(ibctransfertypes.PortID))); err != nil {
+		panic(err)
+	}
+	if err := portOwners.Set(captypes.NewOwner(host.ModuleName, host.PortPath(ibctransfertypes.PortID))); err != nil {
+		panic(err)
+	}
+	capGenesis.Owners = append(capGenesis.Owners, captypes.GenesisOwners{Index: 1, IndexOwners: *portOwners})
+
+	for i, counterpartyChainID := range b.ibcChannels {
+		clientID := clienttypes.FormatClientIdentifier(exported.Tendermint, uint64(i))
+		connectionID := connectiontypes.FormatConnectionIdentifier(uint64(i))
+		channelID := channeltypes.FormatChannelIdentifier(uint64(i))
+		height := clienttypes.NewHeight(clienttypes.ParseChainID(counterpartyChainID), 1000)
+		root := sha256.Sum256([]byte(counterpartyChainID))
+
+		clientState := ibctmtypes.NewClientState(counterpartyChainID, ibctmtypes.DefaultTrustLevel,
+			unbondingPeriod*2/3, unbondingPeriod, 10*time.Second, height, commitmenttypes.GetSDKSpecs(),
+			[]string{"upgrade", "upgradedIBCState"}, false, false)
+		consensusState := ibctmtypes.NewConsensusState(b.genesisTime.Add(-time.Hour), commitmenttypes.NewMerkleRoot(root[:]), root[:])
+
+		ibcGenesis.ClientGenesis.Clients = append(ibcGenesis.ClientGenesis.Clients, clienttypes.NewIdentifiedClientState(clientID, clientState))
+		ibcGenesis.ClientGenesis.ClientsConsensus = append(ibcGenesis.ClientGenesis.ClientsConsensus, clienttypes.NewClientConsensusStates(
+			clientID, []clienttypes.ConsensusStateWithHeight{clienttypes.NewConsensusStateWithHeight(height, consensusState)}))
+
+		connection := connectiontypes.NewConnectionEnd(connectiontypes.OPEN, clientID,
+			connectiontypes.NewCounterparty(clientID, connectionID, commitmenttypes.NewMerklePrefix([]byte("ibc"))),
+			connectiontypes.ExportedVersionsToProto(connectiontypes.GetCompatibleVersions()), 0)
+		ibcGenesis.ConnectionGenesis.Connections = append(ibcGenesis.ConnectionGenesis.Connections, connectiontypes.NewIdentifiedConnection(connectionID, connection))
+		ibcGenesis.ConnectionGenesis.ClientConnectionPaths = append(ibcGenesis.ConnectionGenesis.ClientConnectionPaths, connectiontypes.NewConnectionPaths(clientID, []string{connectionID}))
+
+		channel := channeltypes.NewChannel(channeltypes.OPEN, channeltypes.UNORDERED,
+			channeltypes.NewCounterparty(ibctransfertypes.PortID, channelID), []string{connectionID}, ibctransfertypes.Version)
+		ibcGenesis.ChannelGenesis.Channels = append(ibcGenesis.ChannelGenesis.Channels, channeltypes.NewIdentifiedChannel(ibctransfertypes.PortID, channelID, channel))
+		for _, seqs := range []*[]channeltypes.PacketSequence{
+			&ibcGenesis.ChannelGenesis.SendSequences, &ibcGenesis.ChannelGenesis.RecvSequences, &ibcGenesis.ChannelGenesis.AckSequences,
+		} {
+			*seqs = append(*seqs, channeltypes.NewPacketSequence(ibctransfertypes.PortID, channelID, 1))
+		}
+
+		channelOwners := captypes.NewCapabilityOwners()
+		channelPath := host.ChannelCapabilityPath(ibctransfertypes.PortID, channelID)
+		if err := channelOwners.Set(captypes.NewOwner(ibctransfertypes.ModuleName, channelPath)); err != nil {
+			panic(err)
+		}
+		if err := channelOwners.Set(captypes.NewOwner(host.ModuleName, channelPath)); err != nil {
+			panic(err)
+		}
+		capGenesis.Owners = append(capGenesis.Owners, captypes.GenesisOwners{Index: uint64(i + 2), IndexOwners: *channelOwners})
+	}
+
+	ibcGenesis.ClientGenesis.NextClientSequence = uint64(len(b.ibcChannels))
+	ibcGenesis.ConnectionGenesis.NextConnectionSequence = uint64(len(b.ibcChannels))
+	ibcGenesis.ChannelGenesis.NextChannelSequence = uint64(len(b.ibcChannels))
+	capGenesis.Index = uint64(len(b.ibcChannels) + 2)
+
+	return ibcGenesis, capGenesis
+}
+
+// tmValidators returns the tendermint validator set of the bonded validators
+// of stakingGenesis.
+func tmValidators(stakingGenesis stakingtypes.GenesisState) ([]tmtypes.GenesisValidator, error) {
+	var validators []tmtypes.GenesisValidator
+	for _, val := range stakingGenesis.Validators {
+		if !val.IsBonded() {
+			continue
+		}
+
+		protoPubKey, err := val.TmConsPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := cryptocodec.PubKeyFromProto(protoPubKey)
+		if err != nil {
+			return nil, err
+		}
+
+		validators = append(validators, tmtypes.GenesisValidator{
+			Address: pubKey.Address(),
+			PubKey:  pubKey,
+			Power:   val.ConsensusPower(),
+			Name:    val.GetMoniker(),
+		})
+	}
+
+	return validators, nil
+}
@@ -0,0 +1,56 @@
+package genesisbuilder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/gaia/v5/internal/modules"
+)
+
+func testBuilder() *Builder {
+	return New().
+		WithValidators(3).
+		WithAccount("alice", sdk.NewCoins(sdk.NewInt64Coin(BondDenom, 5000000))).
+		WithAccount("bob", sdk.NewCoins(sdk.NewInt64Coin(BondDenom, 1000))).
+		WithDelegation("alice", 0, 2000000).
+		WithDelegation("carol", 2, 3000000).
+		WithUnbondingDelegation("bob", 1, 500000, 7*24*time.Hour).
+		WithVestingAccount("dave", sdk.NewCoins(sdk.NewInt64Coin(BondDenom, 7000000)), -24*time.Hour, 365*24*time.Hour).
+		WithProposal(govtypes.StatusVotingPeriod, sdk.NewCoins(sdk.NewInt64Coin(BondDenom, 10000000))).
+		WithProposal(govtypes.StatusPassed, sdk.NewCoins(sdk.NewInt64Coin(BondDenom, 10000000))).
+		WithIBCChannel("osmosis-1")
+}
+
+func TestBuilder(t *testing.T) {
+	b := testBuilder()
+	genDoc, err := b.Build()
+	require.NoError(t, err)
+
+	var state map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	cdc := modules.MakeEncodingConfig().Marshaler
+
+	var stakingGenesis stakingtypes.GenesisState
+	cdc.MustUnmarshalJSON(state[stakingtypes.ModuleName], &stakingGenesis)
+
+	require.Len(t, stakingGenesis.Validators, 3)
+	require.Equal(t, b.ValidatorAddress(0).String(), stakingGenesis.Validators[0].OperatorAddress)
+	require.Equal(t, int64(12), stakingGenesis.Validators[0].ConsensusPower())
+	require.Len(t, stakingGenesis.UnbondingDelegations, 1)
+
+	require.Len(t, genDoc.Validators, 3)
+	require.Equal(t, b.ValidatorConsAddress(2).Bytes(), genDoc.Validators[2].Address.Bytes())
+	require.Equal(t, int64(33), genDoc.Validators[2].Power)
+
+	again, err := testBuilder().Build()
+	require.NoError(t, err)
+	require.Equal(t, genDoc.AppState, again.AppState)
+	require.Equal(t, genDoc.Validators, again.Validators)
+}
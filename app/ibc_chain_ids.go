@@ -0,0 +1,96 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	"github.com/pkg/errors"
+)
+
+const flagRemapChainIDs = "remap-counterparty-chain-ids"
+
+// loadChainIDMapping reads a JSON object mapping the chain IDs of the
+// counterparties of the IBC clients to the chain IDs of the networks they
+// are forked to, e.g. {"osmosis-1": "osmosis-rehearsal-1"}.
+func loadChainIDMapping(path string) (map[string]string, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read counterparty chain ID mapping file")
+	}
+
+	var mapping map[string]string
+	if err := json.Unmarshal(bz, &mapping); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal counterparty chain ID mapping")
+	}
+
+	for from, to := range mapping {
+		if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return nil, fmt.Errorf("invalid counterparty chain ID mapping %q to %q, chain IDs cannot be blank", from, to)
+		}
+	}
+
+	return mapping, nil
+}
+
+// remappedClient is a tendermint client whose counterparty chain ID
+// remapCounterpartyChainIDs rewrote. RevisionChanged tells the revision
+// number of the new chain ID differs from the one of the client heights.
+type remappedClient struct {
+	ClientID        string
+	From, To        string
+	RevisionChanged bool
+}
+
+// unmappedClient is a tendermint client whose counterparty chain ID is not
+// in the mapping.
+type unmappedClient struct {
+	ClientID string
+	ChainID  string
+}
+
+// remapCounterpartyChainIDs rewrites the chain ID of every tendermint client
+// state of the IBC genesis of state by mapping, leaving their heights and
+// consensus states untouched. It returns the remapped clients and those whose
+// chain ID is not in the mapping, in genesis order.
+func remapCounterpartyChainIDs(cdc codec.JSONMarshaler, state types.AppMap, mapping map[string]string) ([]remappedClient, []unmappedClient, error) {
+	var (
+		remapped []remappedClient
+		unmapped []unmappedClient
+	)
+	err := walkTendermintClients(cdc, state, func(clientID string, clientState *ibctmtypes.ClientState) bool {
+		to, ok := mapping[clientState.ChainId]
+		if !ok {
+			unmapped = append(unmapped, unmappedClient{ClientID: clientID, ChainID: clientState.ChainId})
+			return false
+		}
+
+		remapped = append(remapped, remappedClient{
+			ClientID:        clientID,
+			From:            clientState.ChainId,
+			To:              to,
+			RevisionChanged: clienttypes.ParseChainID(to) != clientState.LatestHeight.RevisionNumber,
+		})
+		clientState.ChainId = to
+		return true
+	})
+
+	return remapped, unmapped, err
+}
+
+// chainIDMappingSummary describes mapping for the state change summary.
+func chainIDMappingSummary(mapping map[string]string) string {
+	pairs := make([]string, 0, len(mapping))
+	for from, to := range mapping {
+		pairs = append(pairs, fmt.Sprintf("%s to %s", from, to))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,77 @@
+package gaia
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemapCounterpartyChainIDs(t *testing.T) {
+	_, state := buildTestGenesis(t, genesisbuilder.New().WithValidators(1).WithIBCChannel("osmosis-1").WithIBCChannel("juno-1"))
+	cdc := MakeEncodingConfig().Marshaler
+
+	consensusStates := func() clienttypes.ClientsConsensusStates {
+		var ibcGenesis ibccoretypes.GenesisState
+		require.NoError(t, cdc.UnmarshalJSON(state[host.ModuleName], &ibcGenesis))
+		return ibcGenesis.ClientGenesis.ClientsConsensus
+	}
+	chainIDs := func() map[string]string {
+		ids := make(map[string]string)
+		editClientStates(t, state, func(clientID string, clientState *ibctmtypes.ClientState) {
+			ids[clientID] = clientState.ChainId
+		})
+		return ids
+	}
+	before := consensusStates()
+
+	remapped, unmapped, err := remapCounterpartyChainIDs(cdc, state, map[string]string{
+		"osmosis-1":    "osmosis-rehearsal-1",
+		"evmos_9001-2": "evmos_9001-3",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []remappedClient{
+		{ClientID: "07-tendermint-0", From: "osmosis-1", To: "osmosis-rehearsal-1"},
+	}, remapped)
+	require.Equal(t, []unmappedClient{{ClientID: "07-tendermint-1", ChainID: "juno-1"}}, unmapped)
+
+	require.Equal(t, map[string]string{
+		"07-tendermint-0": "osmosis-rehearsal-1",
+		"07-tendermint-1": "juno-1",
+	}, chainIDs())
+	require.NotEmpty(t, before)
+	require.Equal(t, before, consensusStates())
+
+	// a chain ID of another revision than the client heights is flagged
+	remapped, _, err = remapCounterpartyChainIDs(cdc, state, map[string]string{"juno-1": "juno-2"})
+	require.NoError(t, err)
+	require.Equal(t, []remappedClient{
+		{ClientID: "07-tendermint-1", From: "juno-1", To: "juno-2", RevisionChanged: true},
+	}, remapped)
+}
+
+func TestLoadChainIDMapping(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "mapping.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	mapping, err := loadChainIDMapping(write(`{"osmosis-1": "osmosis-rehearsal-1"}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"osmosis-1": "osmosis-rehearsal-1"}, mapping)
+
+	_, err = loadChainIDMapping(write(`{"osmosis-1": " "}`))
+	require.EqualError(t, err, `invalid counterparty chain ID mapping "osmosis-1" to " ", chain IDs cannot be blank`)
+	_, err = loadChainIDMapping(write(`["osmosis-1"]`))
+	require.Error(t, err)
+	_, err = loadChainIDMapping(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package gaia
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	connectiontypes "github.com/cosmos/cosmos-sdk/x/ibc/core/03-connection/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	"github.com/pkg/errors"
+)
+
+// The packet counters of a channel.
+const (
+	nextSequenceSend = "next_sequence_send"
+	nextSequenceRecv = "next_sequence_recv"
+	nextSequenceAck  = "next_sequence_ack"
+)
+
+// ibcChannelReport is the consistency of every channel of the migrated IBC
+// genesis.
+type ibcChannelReport struct {
+	Channels []ibcChannelCheck `json:"channels"`
+}
+
+// ibcChannelCheck is the consistency of a channel with its packets and its
+// connection. SequenceProblems are the counters below the sequences of its
+// packets, which --repair-channel-sequences bumps into Repairs, Problems the
+// rest: commitments of packets the channel acknowledged and a state its
+// connection does not allow.
+type ibcChannelCheck struct {
+	PortID           string                  `json:"port_id"`
+	ChannelID        string                  `json:"channel_id"`
+	State            string                  `json:"state"`
+	Ordering         string                  `json:"ordering"`
+	ConnectionID     string                  `json:"connection_id"`
+	ConnectionState  string                  `json:"connection_state,omitempty"`
+	NextSequenceSend uint64                  `json:"next_sequence_send"`
+	NextSequenceRecv uint64                  `json:"next_sequence_recv"`
+	NextSequenceAck  uint64                  `json:"next_sequence_ack"`
+	SequenceProblems []string                `json:"sequence_problems,omitempty"`
+	Problems         []string                `json:"problems,omitempty"`
+	Repairs          []channelSequenceRepair `json:"repairs,omitempty"`
+}
+
+// channelSequenceRepair is a counter --repair-channel-sequences bumped.
+type channelSequenceRepair struct {
+	Counter string `json:"counter"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+}
+
+// Consistent reports whether the channel has no problem left.
+func (c ibcChannelCheck) Consistent() bool {
+	return len(c.SequenceProblems) == 0 && len(c.Problems) == 0
+}
+
+// channelKey identifies a channel by its port and channel IDs.
+type channelKey struct {
+	portID, channelID string
+}
+
+// channelPackets are the highest sequences of the packets of a channel and
+// the sequences of its commitments.
+type channelPackets struct {
+	commitments                    []uint64
+	maxCommitment, maxReceived     uint64
+	send, recv, ack                uint64
+	hasSend, hasRecv, hasAck       bool
+	sendIndex, recvIndex, ackIndex int
+}
+
+// checkChannelSequences checks every channel of the IBC genesis of state, in
+// genesis order. Its next send sequence must be above the sequences of its
+// packet commitments and, of an ordered channel, its next receive sequence
+// above the sequences of its receipts and acknowledgements and its next
+// acknowledgement sequence at most its next send sequence, with no
+// commitment left below it. Only ordered channels count their receives and
+// acknowledgements, an unordered channel tracks them by packet. An open or
+// try-open channel needs an open connection, an init one an initialized
+// connection. With repair the counters below their minima are bumped to them
+// and the IBC genesis is stored back.
+func checkChannelSequences(cdc codec.JSONMarshaler, state types.AppMap, repair bool) ([]ibcChannelCheck, error) {
+	bz, ok := state[host.ModuleName]
+	if !ok {
+		return nil, nil
+	}
+
+	var ibcGenesis ibccoretypes.GenesisState
+	if err := cdc.UnmarshalJSON(bz, &ibcGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", host.ModuleName)
+	}
+	channelGenesis := &ibcGenesis.ChannelGenesis
+
+	connections := make(map[string]connectiontypes.State, len(ibcGenesis.ConnectionGenesis.Connections))
+	for _, connection := range ibcGenesis.ConnectionGenesis.Connections {
+		connections[connection.Id] = connection.State
+	}
+
+	packets := make(map[channelKey]*channelPackets)
+	get := func(portID, channelID string) *channelPackets {
+		key := channelKey{portID, channelID}
+		if packets[key] == nil {
+			packets[key] = &channelPackets{}
+		}
+		return packets[key]
+	}
+	for _, commitment := range channelGenesis.Commitments {
+		p := get(commitment.PortId, commitment.ChannelId)
+		p.commitments = append(p.commitments, commitment.Sequence)
+		if commitment.Sequence > p.maxCommitment {
+			p.maxCommitment = commitment.Sequence
+		}
+	}
+	for _, received := range append(append([]channeltypes.PacketState{}, channelGenesis.Receipts...), channelGenesis.Acknowledgements...) {
+		if p := get(received.PortId, received.ChannelId); received.Sequence > p.maxReceived {
+			p.maxReceived = received.Sequence
+		}
+	}
+	for i, seq := range channelGenesis.SendSequences {
+		p := get(seq.PortId, seq.ChannelId)
+		p.send, p.hasSend, p.sendIndex = seq.Sequence, true, i
+	}
+	for i, seq := range channelGenesis.RecvSequences {
+		p := get(seq.PortId, seq.ChannelId)
+		p.recv, p.hasRecv, p.recvIndex = seq.Sequence, true, i
+	}
+	for i, seq := range channelGenesis.AckSequences {
+		p := get(seq.PortId, seq.ChannelId)
+		p.ack, p.hasAck, p.ackIndex = seq.Sequence, true, i
+	}
+
+	changed := false
+	checks := make([]ibcChannelCheck, 0, len(channelGenesis.Channels))
+	for _, channel := range channelGenesis.Channels {
+		p := get(channel.PortId, channel.ChannelId)
+		check := ibcChannelCheck{
+			PortID:           channel.PortId,
+			ChannelID:        channel.ChannelId,
+			State:            channel.State.String(),
+			Ordering:         channel.Ordering.String(),
+			NextSequenceSend: p.send,
+			NextSequenceRecv: p.recv,
+			NextSequenceAck:  p.ack,
+		}
+		ordered := channel.Ordering == channeltypes.ORDERED
+
+		minSend, minRecv := p.maxCommitment+1, uint64(1)
+		if ordered {
+			minRecv = p.maxReceived + 1
+			if p.ack > minSend {
+				minSend = p.ack
+			}
+		}
+
+		// the counters are stored back in place, or appended when missing
+		bump := func(counter string, value *uint64, has bool, index int, min uint64, seqs *[]channeltypes.PacketSequence, why string) {
+			if *value >= min {
+				return
+			}
+			if !repair {
+				check.SequenceProblems = append(check.SequenceProblems, fmt.Sprintf("%s %d, %s", counter, *value, why))
+				return
+			}
+
+			check.Repairs = append(check.Repairs, channelSequenceRepair{Counter: counter, From: *value, To: min})
+			if has {
+				(*seqs)[index].Sequence = min
+			} else {
+				*seqs = append(*seqs, channeltypes.NewPacketSequence(channel.PortId, channel.ChannelId, min))
+			}
+			*value = min
+			changed = true
+		}
+
+		bump(nextSequenceSend, &check.NextSequenceSend, p.hasSend, p.sendIndex, minSend, &channelGenesis.SendSequences,
+			fmt.Sprintf("must be at least %d, above its packet commitments and not below %s", minSend, nextSequenceAck))
+		bump(nextSequenceRecv, &check.NextSequenceRecv, p.hasRecv, p.recvIndex, minRecv, &channelGenesis.RecvSequences,
+			fmt.Sprintf("must be at least %d, above its packet receipts and acknowledgements", minRecv))
+		bump(nextSequenceAck, &check.NextSequenceAck, p.hasAck, p.ackIndex, 1, &channelGenesis.AckSequences, "must be at least 1")
+
+		if ordered {
+			for _, seq := range p.commitments {
+				if seq < check.NextSequenceAck {
+					check.Problems = append(check.Problems, fmt.Sprintf("packet %d is acknowledged, %s is %d, but still has a commitment", seq, nextSequenceAck, check.NextSequenceAck))
+				}
+			}
+		}
+
+		if len(channel.ConnectionHops) != 1 {
+			check.Problems = append(check.Problems, fmt.Sprintf("%d connection hops, expected 1", len(channel.ConnectionHops)))
+		} else {
+			check.ConnectionID = channel.ConnectionHops[0]
+			connectionState, ok := connections[check.ConnectionID]
+			if ok {
+				check.ConnectionState = connectionState.String()
+			}
+
+			switch {
+			case !ok:
+				check.Problems = append(check.Problems, fmt.Sprintf("connection %s does not exist", check.ConnectionID))
+			case (channel.State == channeltypes.OPEN || channel.State == channeltypes.TRYOPEN) && connectionState != connectiontypes.OPEN,
+				channel.State == channeltypes.INIT && connectionState == connectiontypes.UNINITIALIZED:
+				check.Problems = append(check.Problems, fmt.Sprintf("channel is %s but connection %s is %s", channel.State, check.ConnectionID, connectionState))
+			}
+		}
+
+		checks = append(checks, check)
+	}
+
+	if changed {
+		state[host.ModuleName] = cdc.MustMarshalJSON(&ibcGenesis)
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,150 @@
+package gaia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	connectiontypes "github.com/cosmos/cosmos-sdk/x/ibc/core/03-connection/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// editIBCGenesis calls edit with the IBC genesis of state and stores it back.
+func editIBCGenesis(t *testing.T, state types.AppMap, edit func(ibcGenesis *ibccoretypes.GenesisState)) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	var ibcGenesis ibccoretypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(state[host.ModuleName], &ibcGenesis))
+	edit(&ibcGenesis)
+	state[host.ModuleName] = cdc.MustMarshalJSON(&ibcGenesis)
+}
+
+func TestChannelSequences(t *testing.T) {
+	// the test genesis has a channel, channel-0
+	_, state := buildTestGenesis(t, testGenesisBuilder().WithIBCChannel("juno-1").WithIBCChannel("stargaze-1").WithIBCChannel("osmosis-1"))
+	cdc := MakeEncodingConfig().Marshaler
+
+	checks, err := checkChannelSequences(cdc, state, false)
+	require.NoError(t, err)
+	require.Len(t, checks, 4)
+	for _, check := range checks {
+		require.True(t, check.Consistent(), check)
+		require.Equal(t, "STATE_OPEN", check.ConnectionState)
+	}
+
+	commitment := func(channelID string, seq uint64) channeltypes.PacketState {
+		return channeltypes.NewPacketState("transfer", channelID, seq, []byte("commitment"))
+	}
+	editIBCGenesis(t, state, func(ibcGenesis *ibccoretypes.GenesisState) {
+		channels := &ibcGenesis.ChannelGenesis
+
+		// channel-0 sends packets 3 and 5 with next_sequence_send 5
+		channels.Commitments = append(channels.Commitments, commitment("channel-0", 3), commitment("channel-0", 5))
+		channels.SendSequences[0].Sequence = 5
+
+		// the ordered channel-1 acknowledged packets up to 7 but expects 4,
+		// and keeps the commitment of packet 2 it got the acknowledgement of
+		channels.Channels[1].Ordering = channeltypes.ORDERED
+		channels.Acknowledgements = append(channels.Acknowledgements, channeltypes.NewPacketState("transfer", "channel-1", 7, []byte("ack")))
+		channels.RecvSequences[1].Sequence = 4
+		channels.Commitments = append(channels.Commitments, commitment("channel-1", 2))
+		channels.SendSequences[1].Sequence = 3
+		channels.AckSequences[1].Sequence = 3
+
+		// channel-2 is open on a connection still in init and has no
+		// next_sequence_recv
+		ibcGenesis.ConnectionGenesis.Connections[2].State = connectiontypes.INIT
+		channels.RecvSequences = append(channels.RecvSequences[:2], channels.RecvSequences[3:]...)
+
+		// channel-3 is consistent with packets in flight
+		channels.Commitments = append(channels.Commitments, commitment("channel-3", 1))
+		channels.Receipts = append(channels.Receipts, channeltypes.NewPacketState("transfer", "channel-3", 9, []byte{1}))
+		channels.SendSequences[3].Sequence = 2
+	})
+
+	channel := func(channelID, ordering, connectionState string, send, recv, ack uint64) ibcChannelCheck {
+		return ibcChannelCheck{
+			PortID: "transfer", ChannelID: channelID, State: "STATE_OPEN", Ordering: ordering,
+			ConnectionID: "connection-" + channelID[len("channel-"):], ConnectionState: connectionState,
+			NextSequenceSend: send, NextSequenceRecv: recv, NextSequenceAck: ack,
+		}
+	}
+	channel0 := channel("channel-0", "ORDER_UNORDERED", "STATE_OPEN", 5, 1, 1)
+	channel0.SequenceProblems = []string{"next_sequence_send 5, must be at least 6, above its packet commitments and not below next_sequence_ack"}
+	channel1 := channel("channel-1", "ORDER_ORDERED", "STATE_OPEN", 3, 4, 3)
+	channel1.SequenceProblems = []string{"next_sequence_recv 4, must be at least 8, above its packet receipts and acknowledgements"}
+	channel1.Problems = []string{"packet 2 is acknowledged, next_sequence_ack is 3, but still has a commitment"}
+	channel2 := channel("channel-2", "ORDER_UNORDERED", "STATE_INIT", 1, 0, 1)
+	channel2.SequenceProblems = []string{"next_sequence_recv 0, must be at least 1, above its packet receipts and acknowledgements"}
+	channel2.Problems = []string{"channel is STATE_OPEN but connection connection-2 is STATE_INIT"}
+	channel3 := channel("channel-3", "ORDER_UNORDERED", "STATE_OPEN", 2, 1, 1)
+
+	checks, err = checkChannelSequences(cdc, state, false)
+	require.NoError(t, err)
+	require.Equal(t, []ibcChannelCheck{channel0, channel1, channel2, channel3}, checks)
+
+	// the repair bumps the counters, the other problems stay
+	checks, err = checkChannelSequences(cdc, state, true)
+	require.NoError(t, err)
+	channel0.SequenceProblems, channel0.NextSequenceSend = nil, 6
+	channel0.Repairs = []channelSequenceRepair{{Counter: nextSequenceSend, From: 5, To: 6}}
+	channel1.SequenceProblems, channel1.NextSequenceRecv = nil, 8
+	channel1.Repairs = []channelSequenceRepair{{Counter: nextSequenceRecv, From: 4, To: 8}}
+	channel2.SequenceProblems, channel2.NextSequenceRecv = nil, 1
+	channel2.Repairs = []channelSequenceRepair{{Counter: nextSequenceRecv, From: 0, To: 1}}
+	require.Equal(t, []ibcChannelCheck{channel0, channel1, channel2, channel3}, checks)
+
+	channel0.Repairs, channel1.Repairs, channel2.Repairs = nil, nil, nil
+	checks, err = checkChannelSequences(cdc, state, false)
+	require.NoError(t, err)
+	require.Equal(t, []ibcChannelCheck{channel0, channel1, channel2, channel3}, checks)
+
+	var ibcGenesis ibccoretypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(state[host.ModuleName], &ibcGenesis))
+	require.NoError(t, ibcGenesis.Validate())
+}
+
+func TestChannelSequencesConnections(t *testing.T) {
+	_, state := buildTestGenesis(t, testGenesisBuilder().WithIBCChannel("juno-1").WithIBCChannel("stargaze-1"))
+	cdc := MakeEncodingConfig().Marshaler
+
+	editIBCGenesis(t, state, func(ibcGenesis *ibccoretypes.GenesisState) {
+		channels := ibcGenesis.ChannelGenesis.Channels
+		// a closed channel outlives its connection state, a try-open one
+		// needs it open
+		channels[0].State = channeltypes.CLOSED
+		ibcGenesis.ConnectionGenesis.Connections[0].State = connectiontypes.TRYOPEN
+		channels[1].State = channeltypes.TRYOPEN
+		ibcGenesis.ConnectionGenesis.Connections[1].State = connectiontypes.TRYOPEN
+		channels[2].ConnectionHops = []string{"connection-9"}
+	})
+
+	checks, err := checkChannelSequences(cdc, state, false)
+	require.NoError(t, err)
+	require.Len(t, checks, 3)
+	require.Empty(t, checks[0].Problems)
+	require.Equal(t, []string{"channel is STATE_TRYOPEN but connection connection-1 is STATE_TRYOPEN"}, checks[1].Problems)
+	require.Equal(t, []string{"connection connection-9 does not exist"}, checks[2].Problems)
+	require.Empty(t, checks[2].ConnectionState)
+}
+
+func TestMigrateIBCChannelReport(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "ibc-channels.json")
+
+	_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--repair-channel-sequences", "--ibc-channel-report", reportPath)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var report ibcChannelReport
+	require.NoError(t, json.Unmarshal(bz, &report))
+	// the migration starts IBC from its default genesis
+	require.Empty(t, report.Channels)
+}
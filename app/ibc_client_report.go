@@ -0,0 +1,114 @@
+package gaia
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	"github.com/pkg/errors"
+)
+
+// ibcClientReport tells, for every tendermint client of the migrated IBC
+// genesis, whether its latest consensus state is still trusted when the
+// migrated chain starts at its genesis time.
+type ibcClientReport struct {
+	GenesisTime   time.Time         `json:"genesis_time"`
+	InitialHeight int64             `json:"initial_height"`
+	Clients       []ibcClientExpiry `json:"clients"`
+}
+
+type ibcClientExpiry struct {
+	ClientID            string    `json:"client_id"`
+	CounterpartyChainID string    `json:"counterparty_chain_id"`
+	LatestHeight        string    `json:"latest_height"`
+	LatestTimestamp     time.Time `json:"latest_timestamp"`
+	TrustingPeriod      string    `json:"trusting_period"`
+	Frozen              bool      `json:"frozen"`
+	// WithinTrustingPeriod tells whether the latest consensus state is
+	// trusted at the genesis time, MustBeUpdatedWithin is the time left from
+	// the genesis time until it no longer is, negative once it expired.
+	WithinTrustingPeriod bool   `json:"within_trusting_period"`
+	MustBeUpdatedWithin  string `json:"must_be_updated_within"`
+}
+
+// newIBCClientReport computes the report of the IBC genesis of state for a
+// chain starting at genesisTime and initialHeight. Clients of other types
+// than tendermint have no trusting period and are left out.
+func newIBCClientReport(cdc codec.JSONMarshaler, state types.AppMap, genesisTime time.Time, initialHeight int64) (*ibcClientReport, error) {
+	report := &ibcClientReport{GenesisTime: genesisTime, InitialHeight: initialHeight, Clients: []ibcClientExpiry{}}
+
+	bz, ok := state[host.ModuleName]
+	if !ok {
+		return report, nil
+	}
+
+	var ibcGenesis ibccoretypes.GenesisState
+	if err := cdc.UnmarshalJSON(bz, &ibcGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", host.ModuleName)
+	}
+
+	consensusStates := make(map[string][]clienttypes.ConsensusStateWithHeight)
+	for _, states := range ibcGenesis.ClientGenesis.ClientsConsensus {
+		consensusStates[states.ClientId] = states.ConsensusStates
+	}
+
+	for _, client := range ibcGenesis.ClientGenesis.Clients {
+		clientState, err := clienttypes.UnpackClientState(client.ClientState)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid client state of %s", client.ClientId)
+		}
+
+		tmClientState, ok := clientState.(*ibctmtypes.ClientState)
+		if !ok {
+			continue
+		}
+
+		consensusState, err := latestConsensusState(consensusStates[client.ClientId], tmClientState.LatestHeight)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid consensus states of %s", client.ClientId)
+		}
+
+		left := consensusState.Timestamp.Add(tmClientState.TrustingPeriod).Sub(genesisTime)
+		report.Clients = append(report.Clients, ibcClientExpiry{
+			ClientID:             client.ClientId,
+			CounterpartyChainID:  tmClientState.ChainId,
+			LatestHeight:         tmClientState.LatestHeight.String(),
+			LatestTimestamp:      consensusState.Timestamp,
+			TrustingPeriod:       tmClientState.TrustingPeriod.String(),
+			Frozen:               tmClientState.IsFrozen(),
+			WithinTrustingPeriod: left > 0,
+			MustBeUpdatedWithin:  left.String(),
+		})
+	}
+
+	return report, nil
+}
+
+// latestConsensusState returns the tendermint consensus state of states at
+// the latest height of the client.
+func latestConsensusState(states []clienttypes.ConsensusStateWithHeight, latestHeight clienttypes.Height) (*ibctmtypes.ConsensusState, error) {
+	for _, state := range states {
+		if !state.Height.EQ(latestHeight) {
+			continue
+		}
+
+		consensusState, err := clienttypes.UnpackConsensusState(state.ConsensusState)
+		if err != nil {
+			return nil, err
+		}
+
+		tmConsensusState, ok := consensusState.(*ibctmtypes.ConsensusState)
+		if !ok {
+			return nil, fmt.Errorf("consensus state at %s is a %T, expected a tendermint consensus state", latestHeight, consensusState)
+		}
+
+		return tmConsensusState, nil
+	}
+
+	return nil, fmt.Errorf("no consensus state at the latest height %s", latestHeight)
+}
@@ -0,0 +1,74 @@
+package gaia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIBCClientReport(t *testing.T) {
+	_, state := buildTestGenesis(t, testGenesisBuilder().WithIBCChannel("juno-1"))
+	cdc := MakeEncodingConfig().Marshaler
+
+	// the builder clients trust their consensus states of an hour before the
+	// test genesis time for two thirds of the 21 days unbonding period
+	latest := genesisbuilder.GenesisTime.Add(-time.Hour)
+	trustingPeriod := 14 * 24 * time.Hour
+
+	testCases := []struct {
+		name        string
+		genesisTime time.Time
+		within      bool
+		left        time.Duration
+	}{
+		{"far from expiry", genesisbuilder.GenesisTime, true, trustingPeriod - time.Hour},
+		{"near expiry", latest.Add(trustingPeriod - time.Minute), true, time.Minute},
+		{"at expiry", latest.Add(trustingPeriod), false, 0},
+		{"expired", latest.Add(trustingPeriod + 36*time.Hour), false, -36 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := newIBCClientReport(cdc, state, tc.genesisTime, 7000000)
+			require.NoError(t, err)
+			require.Equal(t, int64(7000000), report.InitialHeight)
+			require.Len(t, report.Clients, 2)
+
+			for i, chainID := range []string{"osmosis-1", "juno-1"} {
+				client := report.Clients[i]
+				require.Equal(t, chainID, client.CounterpartyChainID)
+				require.Equal(t, "1-1000", client.LatestHeight)
+				require.True(t, latest.Equal(client.LatestTimestamp))
+				require.Equal(t, trustingPeriod.String(), client.TrustingPeriod)
+				require.False(t, client.Frozen)
+				require.Equal(t, tc.within, client.WithinTrustingPeriod)
+				require.Equal(t, tc.left.String(), client.MustBeUpdatedWithin)
+			}
+			require.Equal(t, "07-tendermint-0", report.Clients[0].ClientID)
+			require.Equal(t, "07-tendermint-1", report.Clients[1].ClientID)
+		})
+	}
+}
+
+func TestMigrateIBCClientReport(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "ibc-clients.json")
+
+	_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--initial-height", "5200791", "--ibc-client-report", reportPath)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var report ibcClientReport
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Equal(t, int64(5200791), report.InitialHeight)
+	// the migration starts IBC from its default genesis
+	require.Empty(t, report.Clients)
+}
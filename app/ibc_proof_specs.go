@@ -0,0 +1,141 @@
+package gaia
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	commitmenttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/23-commitment/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	upgradetypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// standardUpgradePath is the upgrade path of the tendermint clients of
+// counterparties running the SDK upgrade module.
+var standardUpgradePath = []string{upgradetypes.StoreKey, upgradetypes.KeyUpgradedIBCState}
+
+// clientProofSpecs lists how the proof specs and upgrade path of a
+// tendermint client differ from the SDK ones.
+type clientProofSpecs struct {
+	ClientID string
+	Problems []string
+}
+
+// checkClientProofSpecs returns the tendermint clients of the IBC genesis of
+// state, in genesis order, whose proof specs are not the ICS-23 specs of the
+// SDK stores or whose upgrade path is not standardUpgradePath. Verifying
+// proofs of such a counterparty fails.
+func checkClientProofSpecs(cdc codec.JSONMarshaler, state types.AppMap) ([]clientProofSpecs, error) {
+	var nonStandard []clientProofSpecs
+	err := walkTendermintClients(cdc, state, func(clientID string, clientState *ibctmtypes.ClientState) bool {
+		if problems := proofSpecsProblems(clientState); len(problems) > 0 {
+			nonStandard = append(nonStandard, clientProofSpecs{ClientID: clientID, Problems: problems})
+		}
+		return false
+	})
+
+	return nonStandard, err
+}
+
+// fixClientProofSpecs sets the proof specs and upgrade path of the tendermint
+// clients of the IBC genesis of state to the standard ones and returns the
+// IDs of the clients it changed.
+func fixClientProofSpecs(cdc codec.JSONMarshaler, state types.AppMap) ([]string, error) {
+	var fixed []string
+	err := walkTendermintClients(cdc, state, func(clientID string, clientState *ibctmtypes.ClientState) bool {
+		if len(proofSpecsProblems(clientState)) == 0 {
+			return false
+		}
+
+		clientState.ProofSpecs = commitmenttypes.GetSDKSpecs()
+		clientState.UpgradePath = append([]string{}, standardUpgradePath...)
+		fixed = append(fixed, clientID)
+		return true
+	})
+
+	return fixed, err
+}
+
+func proofSpecsProblems(clientState *ibctmtypes.ClientState) []string {
+	var problems []string
+
+	specs := commitmenttypes.GetSDKSpecs()
+	switch {
+	case len(clientState.ProofSpecs) == 0:
+		problems = append(problems, "no proof specs")
+	case len(clientState.ProofSpecs) != len(specs):
+		problems = append(problems, fmt.Sprintf("%d proof specs, the SDK has %d", len(clientState.ProofSpecs), len(specs)))
+	default:
+		for i, spec := range specs {
+			if !proto.Equal(clientState.ProofSpecs[i], spec) {
+				problems = append(problems, fmt.Sprintf("proof spec %d differs from the SDK one", i))
+			}
+		}
+	}
+
+	switch {
+	case len(clientState.UpgradePath) == 0:
+		problems = append(problems, "no upgrade path")
+	case !stringsEqual(clientState.UpgradePath, standardUpgradePath):
+		problems = append(problems, fmt.Sprintf("upgrade path %q, expected %q", clientState.UpgradePath, standardUpgradePath))
+	}
+
+	return problems
+}
+
+// walkTendermintClients calls visit with every tendermint client state of the
+// IBC genesis of state, storing the IBC genesis back if visit changed any.
+func walkTendermintClients(cdc codec.JSONMarshaler, state types.AppMap, visit func(clientID string, clientState *ibctmtypes.ClientState) bool) error {
+	bz, ok := state[host.ModuleName]
+	if !ok {
+		return nil
+	}
+
+	var ibcGenesis ibccoretypes.GenesisState
+	if err := cdc.UnmarshalJSON(bz, &ibcGenesis); err != nil {
+		return errors.Wrapf(err, "failed to JSON unmarshal %s genesis", host.ModuleName)
+	}
+
+	changed := false
+	for i, client := range ibcGenesis.ClientGenesis.Clients {
+		clientState, err := clienttypes.UnpackClientState(client.ClientState)
+		if err != nil {
+			return errors.Wrapf(err, "invalid client state of %s", client.ClientId)
+		}
+
+		tmClientState, ok := clientState.(*ibctmtypes.ClientState)
+		if !ok || !visit(client.ClientId, tmClientState) {
+			continue
+		}
+
+		ibcGenesis.ClientGenesis.Clients[i].ClientState, err = clienttypes.PackClientState(tmClientState)
+		if err != nil {
+			return errors.Wrapf(err, "failed to pack client state of %s", client.ClientId)
+		}
+		changed = true
+	}
+
+	if changed {
+		state[host.ModuleName] = cdc.MustMarshalJSON(&ibcGenesis)
+	}
+
+	return nil
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package gaia
+
+import (
+	"testing"
+
+	ics23 "github.com/confio/ics23/go"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	clienttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	commitmenttypes "github.com/cosmos/cosmos-sdk/x/ibc/core/23-commitment/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	ibctmtypes "github.com/cosmos/cosmos-sdk/x/ibc/light-clients/07-tendermint/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientProofSpecs(t *testing.T) {
+	_, state := buildTestGenesis(t, testGenesisBuilder().WithIBCChannel("juno-1").WithIBCChannel("stargaze-1"))
+	cdc := MakeEncodingConfig().Marshaler
+
+	nonStandard, err := checkClientProofSpecs(cdc, state)
+	require.NoError(t, err)
+	require.Empty(t, nonStandard)
+
+	editClientStates(t, state, func(clientID string, clientState *ibctmtypes.ClientState) {
+		switch clientID {
+		case "07-tendermint-0":
+			clientState.UpgradePath = nil
+		case "07-tendermint-1":
+			// the SDK specs are shared, alter a copy
+			spec := proto.Clone(commitmenttypes.GetSDKSpecs()[1]).(*ics23.ProofSpec)
+			spec.MaxDepth = 10
+			clientState.ProofSpecs[1] = spec
+		case "07-tendermint-2":
+			clientState.ProofSpecs = clientState.ProofSpecs[:1]
+			clientState.UpgradePath = []string{"upgrade", "upgradedClient"}
+		}
+	})
+
+	nonStandard, err = checkClientProofSpecs(cdc, state)
+	require.NoError(t, err)
+	require.Equal(t, []clientProofSpecs{
+		{ClientID: "07-tendermint-0", Problems: []string{"no upgrade path"}},
+		{ClientID: "07-tendermint-1", Problems: []string{"proof spec 1 differs from the SDK one"}},
+		{ClientID: "07-tendermint-2", Problems: []string{
+			"1 proof specs, the SDK has 2",
+			`upgrade path ["upgrade" "upgradedClient"], expected ["upgrade" "upgradedIBCState"]`,
+		}},
+	}, nonStandard)
+
+	fixed, err := fixClientProofSpecs(cdc, state)
+	require.NoError(t, err)
+	require.Equal(t, []string{"07-tendermint-0", "07-tendermint-1", "07-tendermint-2"}, fixed)
+
+	nonStandard, err = checkClientProofSpecs(cdc, state)
+	require.NoError(t, err)
+	require.Empty(t, nonStandard)
+
+	fixed, err = fixClientProofSpecs(cdc, state)
+	require.NoError(t, err)
+	require.Empty(t, fixed)
+}
+
+// editClientStates calls edit with every tendermint client state of the IBC
+// genesis of state and stores the edited states.
+func editClientStates(t *testing.T, state types.AppMap, edit func(clientID string, clientState *ibctmtypes.ClientState)) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	var ibcGenesis ibccoretypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(state[host.ModuleName], &ibcGenesis))
+
+	for i, client := range ibcGenesis.ClientGenesis.Clients {
+		clientState, err := clienttypes.UnpackClientState(client.ClientState)
+		require.NoError(t, err)
+
+		tmClientState := clientState.(*ibctmtypes.ClientState)
+		edit(client.ClientId, tmClientState)
+
+		ibcGenesis.ClientGenesis.Clients[i].ClientState, err = clienttypes.PackClientState(tmClientState)
+		require.NoError(t, err)
+	}
+
+	state[host.ModuleName] = cdc.MustMarshalJSON(&ibcGenesis)
+}
@@ -0,0 +1,221 @@
+package gaia
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+const (
+	flagSweepInactiveTo = "sweep-inactive-to"
+	flagInactiveBelow   = "inactive-below"
+	flagSweptReport     = "swept-accounts-report"
+)
+
+// inactiveSweepOptions selects the accounts removed by sweepInactiveAccounts.
+// An account is inactive when it never signed a transaction, holds less than
+// Below and takes no part in staking. Its balance goes to Destination.
+type inactiveSweepOptions struct {
+	Below       sdk.Coin
+	Destination sdk.AccAddress
+	Protected   *protectedAddresses
+}
+
+// sweptAccount is an account removed by sweepInactiveAccounts, with the
+// balance moved away from it.
+type sweptAccount struct {
+	Address       string    `json:"address"`
+	AccountNumber uint64    `json:"account_number"`
+	Balance       sdk.Coins `json:"balance"`
+}
+
+// inactiveSweepReport lists what sweepInactiveAccounts removed.
+type inactiveSweepReport struct {
+	Accounts []sweptAccount `json:"accounts"`
+	Total    sdk.Coins      `json:"total"`
+}
+
+// sweepInactiveAccounts removes the inactive accounts from the app state and
+// moves their balances to the destination account: accounts with a zero
+// sequence, holding less than the threshold in its denom and with no
+// delegation, unbonding delegation, redelegation or gov deposit. Module,
+// vesting and validator operator accounts and the destination itself are
+// never swept. The total supply is left unchanged.
+func sweepInactiveAccounts(cdc codec.JSONMarshaler, state types.AppMap, opts inactiveSweepOptions) (inactiveSweepReport, error) {
+	report := inactiveSweepReport{Total: sdk.NewCoins()}
+
+	if opts.Destination.Empty() {
+		return report, fmt.Errorf("a destination account is required to sweep inactive accounts")
+	}
+
+	var (
+		authGenesis    auth.GenesisState
+		bankGenesis    bank.GenesisState
+		stakingGenesis staking.GenesisState
+		govGenesis     gov.GenesisState
+	)
+
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return report, err
+	}
+
+	exempt, err := activeAccounts(stakingGenesis, govGenesis)
+	if err != nil {
+		return report, err
+	}
+	destination := opts.Destination.String()
+	exempt[destination] = true
+
+	balances := make(map[string]sdk.Coins, len(bankGenesis.Balances))
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+	}
+
+	swept := make(map[string]bool)
+	kept := accounts[:0]
+	hasDestination := false
+	var nextAccountNumber uint64
+	for _, acc := range accounts {
+		addr := acc.GetAddress().String()
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+
+		if addr == destination {
+			if _, ok := acc.(auth.ModuleAccountI); ok {
+				return report, fmt.Errorf("sweep destination %s is a module account", destination)
+			}
+
+			hasDestination = true
+		}
+
+		inactive, err := isInactiveAccount(acc, balances[addr], exempt, opts)
+		if err != nil {
+			return report, err
+		}
+		if !inactive {
+			kept = append(kept, acc)
+			continue
+		}
+
+		swept[addr] = true
+		report.Accounts = append(report.Accounts, sweptAccount{Address: addr, AccountNumber: acc.GetAccountNumber(), Balance: balances[addr]})
+		report.Total = report.Total.Add(balances[addr]...)
+	}
+
+	if !hasDestination {
+		kept = append(kept, auth.NewBaseAccount(opts.Destination, nil, nextAccountNumber, 0))
+	}
+
+	authGenesis.Accounts, err = auth.PackAccounts(kept)
+	if err != nil {
+		return report, err
+	}
+
+	destinationCoins := report.Total
+	filtered := bankGenesis.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		switch {
+		case swept[balance.Address]:
+			continue
+		case balance.Address == destination:
+			destinationCoins = destinationCoins.Add(balance.Coins...)
+			continue
+		}
+
+		filtered = append(filtered, balance)
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(filtered, bank.Balance{Address: destination, Coins: destinationCoins}))
+
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	return report, nil
+}
+
+// activeAccounts returns the addresses taking part in staking or gov, which
+// are never swept: validator operators, delegators, unbonding delegators,
+// redelegators and depositors.
+func activeAccounts(stakingGenesis staking.GenesisState, govGenesis gov.GenesisState) (map[string]bool, error) {
+	active := make(map[string]bool)
+
+	for _, val := range stakingGenesis.Validators {
+		valAddr, err := sdk.ValAddressFromBech32(val.OperatorAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		active[sdk.AccAddress(valAddr).String()] = true
+	}
+
+	for _, del := range stakingGenesis.Delegations {
+		active[del.DelegatorAddress] = true
+	}
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		active[ubd.DelegatorAddress] = true
+	}
+	for _, red := range stakingGenesis.Redelegations {
+		active[red.DelegatorAddress] = true
+	}
+	for _, deposit := range govGenesis.Deposits {
+		active[deposit.Depositor] = true
+	}
+
+	return active, nil
+}
+
+// isInactiveAccount tells whether acc, holding balance, is swept.
+func isInactiveAccount(acc auth.GenesisAccount, balance sdk.Coins, exempt map[string]bool, opts inactiveSweepOptions) (bool, error) {
+	addr := acc.GetAddress().String()
+
+	if _, ok := acc.(auth.ModuleAccountI); ok || exempt[addr] {
+		return false, nil
+	}
+	if _, ok := acc.(vesting.VestingAccount); ok {
+		return false, nil
+	}
+	if acc.GetSequence() != 0 || !balance.AmountOf(opts.Below.Denom).LT(opts.Below.Amount) {
+		return false, nil
+	}
+
+	skip, err := opts.Protected.skip(flagSweepInactiveTo, addr)
+	if err != nil {
+		return false, err
+	}
+
+	return !skip, nil
+}
+
+// writeSweptAccountsCSV writes the accounts of report as
+// address,account_number,balance rows, for a later claims process.
+func writeSweptAccountsCSV(w io.Writer, report inactiveSweepReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"address", "account_number", "balance"}); err != nil {
+		return err
+	}
+
+	for _, acc := range report.Accounts {
+		if err := cw.Write([]string{acc.Address, strconv.FormatUint(acc.AccountNumber, 10), acc.Balance.String()}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,142 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepInactiveAccounts(t *testing.T) {
+	b := testGenesisBuilder().
+		WithAccount("erin", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 999999), sdk.NewInt64Coin("uosmo", 5))).
+		WithAccount("frank", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000000))).
+		WithAccount("grace", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10))).
+		WithAccount("heidi", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 20))).
+		WithAccount("ivan", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 30))).
+		WithRedelegation("ivan", 0, 1, 1000, 24*time.Hour)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	cdc := MakeEncodingConfig().Marshaler
+	destination := b.Address("claims")
+	below := sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000000)
+	holdings := map[string]sdk.Coins{
+		"erin":  sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 999999), sdk.NewInt64Coin("uosmo", 5)),
+		"heidi": sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 20)),
+	}
+
+	for _, tc := range []struct {
+		name      string
+		protected []string
+		swept     []string
+	}{
+		{"inactive accounts", nil, []string{"erin", "heidi"}},
+		{"protected accounts", []string{b.Address("heidi").String()}, []string{"erin"}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			genDoc, state := exportTestGenesis(t, builtDoc)
+
+			// grace signed a transaction
+			var authGenesis auth.GenesisState
+			cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+			accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+			require.NoError(t, err)
+			for _, acc := range accounts {
+				if acc.GetAddress().Equals(b.Address("grace")) {
+					require.NoError(t, acc.SetSequence(3))
+				}
+			}
+			authGenesis.Accounts, err = auth.PackAccounts(accounts)
+			require.NoError(t, err)
+			state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+
+			var bankBefore bank.GenesisState
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+
+			protected, err := newProtectedAddresses("protected.json", tc.protected, false)
+			require.NoError(t, err)
+
+			report, err := sweepInactiveAccounts(cdc, state, inactiveSweepOptions{Below: below, Destination: destination, Protected: protected})
+			require.NoError(t, err)
+
+			var swept []string
+			for _, acc := range report.Accounts {
+				swept = append(swept, acc.Address)
+			}
+			var expected []string
+			total := sdk.NewCoins()
+			for _, name := range tc.swept {
+				expected = append(expected, b.Address(name).String())
+				total = total.Add(holdings[name]...)
+			}
+			require.ElementsMatch(t, expected, swept)
+			require.Equal(t, total, report.Total)
+			require.Len(t, protected.Skips, len(tc.protected))
+
+			require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+			var bankGenesis bank.GenesisState
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+			require.Equal(t, bankBefore.Supply, bankGenesis.Supply)
+
+			cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+			accounts, err = auth.UnpackAccounts(authGenesis.Accounts)
+			require.NoError(t, err)
+			addresses := make(map[string]bool)
+			for _, acc := range accounts {
+				addresses[acc.GetAddress().String()] = true
+			}
+			balances := make(map[string]sdk.Coins)
+			for _, balance := range bankGenesis.Balances {
+				balances[balance.Address] = balance.Coins
+			}
+
+			require.True(t, addresses[destination.String()])
+			require.Equal(t, total, balances[destination.String()])
+			for _, addr := range expected {
+				require.False(t, addresses[addr], addr)
+				require.Empty(t, balances[addr], addr)
+			}
+
+			// delegators, unbonding delegators, redelegators, depositors,
+			// validators, vesting and module accounts and active accounts are kept
+			for _, name := range []string{"alice", "bob", "carol", "dave", "depositor", genesisbuilder.ValidatorName(0), "frank", "grace", "ivan"} {
+				require.True(t, addresses[b.Address(name).String()], name)
+			}
+			require.True(t, addresses[auth.NewModuleAddress(gov.ModuleName).String()])
+			require.True(t, addresses[auth.NewModuleAddress(staking.NotBondedPoolName).String()])
+
+			genDoc.AppState, err = json.Marshal(state)
+			require.NoError(t, err)
+			require.NoError(t, SmokeTestGenesis(genDoc))
+
+			var buf bytes.Buffer
+			require.NoError(t, writeSweptAccountsCSV(&buf, report))
+			require.Contains(t, buf.String(), "address,account_number,balance\n")
+			require.Contains(t, buf.String(), "\n"+b.Address("erin").String()+",5,\"999999"+genesisbuilder.BondDenom+",5uosmo\"\n")
+		})
+	}
+}
+
+func TestSweepInactiveAccountsDestination(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	_, state := buildTestGenesis(t, testGenesisBuilder())
+	below := sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000000)
+
+	_, err := sweepInactiveAccounts(cdc, state, inactiveSweepOptions{Below: below})
+	require.Error(t, err)
+
+	_, err = sweepInactiveAccounts(cdc, state, inactiveSweepOptions{Below: below, Destination: auth.NewModuleAddress(gov.ModuleName)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is a module account")
+}
@@ -0,0 +1,124 @@
+//go:build go1.18
+// +build go1.18
+
+package gaia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+// The seeds of the fuzz targets are the example files, the inputs found by
+// fuzzing are kept in testdata/fuzz so go test runs them as regressions.
+
+// fuzzSeeds returns the files of paths, the schema examples by name.
+func fuzzSeeds(f *testing.F, schema string, paths ...string) [][]byte {
+	example, err := schemaExamples.ReadFile("schemas/" + schema + ".json")
+	require.NoError(f, err)
+
+	seeds := [][]byte{example}
+	for _, path := range paths {
+		bz, err := ioutil.ReadFile(path)
+		require.NoError(f, err)
+		seeds = append(seeds, bz)
+	}
+
+	return seeds
+}
+
+// jsonArrayLen returns the number of elements of the JSON array bz, -1 if
+// bz is not one.
+func jsonArrayLen(bz []byte) int {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(bz, &elements); err != nil {
+		return -1
+	}
+	return len(elements)
+}
+
+func FuzzParseRecoveryEntries(f *testing.F) {
+	for _, seed := range fuzzSeeds(f, "prop29-data", "testdata/compat/cosmoshub-3/prop29-account/prop29.json") {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	claims := sdk.AccAddress("prop29 claims account").String()
+	f.Fuzz(func(t *testing.T, bz []byte, divert bool) {
+		claimsAccount := ""
+		if divert {
+			claimsAccount = claims
+		}
+
+		entries, err := parseRecoveryEntries(bz, claimsAccount)
+		if err != nil {
+			require.Nil(t, entries)
+			return
+		}
+
+		// every element is an entry
+		require.Equal(t, jsonArrayLen(bz), len(entries))
+
+		for i, entry := range entries {
+			_, err := sdk.AccAddressFromBech32(entry.From)
+			require.NoError(t, err, i)
+
+			if entry.diversion != nil {
+				require.True(t, divert, i)
+				require.Equal(t, claims, entry.To, i)
+				require.Nil(t, entry.Vesting, i)
+				require.Equal(t, i, entry.diversion.Entry)
+			} else {
+				_, err := sdk.AccAddressFromBech32(entry.To)
+				require.NoError(t, err, i)
+			}
+
+			require.NoError(t, validateCoins(entry.Amount), i)
+			require.False(t, entry.Amount.IsZero(), i)
+			if entry.Vesting != nil {
+				require.NoError(t, entry.Vesting.Validate(entry.Amount), i)
+			}
+		}
+	})
+}
+
+func FuzzParseReplacementKeys(f *testing.F) {
+	for _, seed := range fuzzSeeds(f, "replacement-cons-keys") {
+		f.Add(seed)
+	}
+	f.Add([]byte(`{"pool": ["cosmosvalconspub1zcjduepqrtf672mn9qxh24c0v3ultsaxj0r2h76lj4nq9mraezsmhsqpxfdq7zw3js"], "select": "top-power"}`))
+
+	genDoc, err := genesisbuilder.New().WithValidators(2).Build()
+	require.NoError(f, err)
+	var state types.AppMap
+	require.NoError(f, json.Unmarshal(genDoc.AppState, &state))
+
+	cdc := MakeEncodingConfig().Marshaler
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+
+	f.Fuzz(func(t *testing.T, bz []byte) {
+		replacementKeys, err := parseReplacementKeys(cdc, bz, genDoc)
+		if err != nil {
+			require.Nil(t, replacementKeys)
+			return
+		}
+
+		// every entry of an array is kept, and replaces a distinct validator
+		// of the genesis with a distinct key
+		if n := jsonArrayLen(bz); n >= 0 {
+			require.Equal(t, n, len(replacementKeys))
+		}
+
+		require.NoError(t, validateReplacementKeys(replacementKeys, stakingGenesis.Validators))
+		require.LessOrEqual(t, len(replacementKeys), len(stakingGenesis.Validators))
+
+		_ = checkReplacementKeyTypes(replacementKeys, genDoc.ConsensusParams)
+	})
+}
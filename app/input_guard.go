@@ -0,0 +1,235 @@
+package gaia
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// defaultMaxInputSize is the default --max-input-size, above the size of any
+// genesis a migration has seen.
+const defaultMaxInputSize = 8 << 30
+
+// inputSniffSize is how much of the input is sniffed, enough for the magic
+// bytes of a tar header.
+const inputSniffSize = 512
+
+// archiveMagics are the magic bytes of the archive and compression formats
+// the genesis input is mistaken for, by name.
+var archiveMagics = []struct {
+	offset int
+	magic  []byte
+	format string
+}{
+	{257, []byte("ustar"), "a tar archive"},
+	{0, []byte("PK\x03\x04"), "a zip archive"},
+	{0, []byte("BZh"), "compressed with bzip2, only gzip is read"},
+	{0, []byte("\xfd7zXZ\x00"), "compressed with xz, only gzip is read"},
+	{0, []byte("\x28\xb5\x2f\xfd"), "compressed with zstd, only gzip is read"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "a 7z archive"},
+}
+
+var gzipMagic = []byte("\x1f\x8b")
+
+// inputGuard checks the genesis input before and while it is decoded, so an
+// archive, a truncated download or an oversized file fails with what was
+// detected rather than a JSON offset or an out of memory kill.
+type inputGuard struct {
+	maxSize  int64
+	read     int64
+	exceeded bool
+	scan     inputScanner
+}
+
+// Reader returns the input of r to decode: gunzipped when it starts with the
+// gzip magic bytes and failing once it exceeds the max size. It fails when
+// the input is empty, looks like an archive or, for JSON, does not start with
+// an object.
+func (g *inputGuard) Reader(r io.Reader, format string) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, inputSniffSize)
+	head, _ := br.Peek(inputSniffSize)
+	if len(head) == 0 {
+		return nil, fmt.Errorf("the input is empty")
+	}
+
+	var input io.Reader = br
+	compression := ""
+	if bytes.HasPrefix(head, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("the input appears to be gzip compressed but cannot be decompressed: %v", err)
+		}
+
+		br = bufio.NewReaderSize(gz, inputSniffSize)
+		head, _ = br.Peek(inputSniffSize)
+		if len(head) == 0 {
+			return nil, fmt.Errorf("the input is an empty gzip stream")
+		}
+		input, compression = br, ", compressed with gzip"
+	}
+
+	for _, archive := range archiveMagics {
+		if len(head) >= archive.offset+len(archive.magic) && bytes.Equal(head[archive.offset:archive.offset+len(archive.magic)], archive.magic) {
+			return nil, fmt.Errorf("the input appears to be %s%s", archive.format, compression)
+		}
+	}
+
+	if format == formatJSON {
+		trimmed := bytes.TrimLeft(head, " \t\r\n")
+		if len(trimmed) > 0 && trimmed[0] != '{' {
+			return nil, fmt.Errorf("the input does not start with a JSON object but with %q", excerpt(trimmed, 0))
+		}
+	}
+
+	return &guardedReader{r: input, g: g}, nil
+}
+
+// Scanner returns the writer scanning the JSON genesis being decoded.
+func (g *inputGuard) Scanner() io.Writer {
+	return &g.scan
+}
+
+// Err returns what is wrong with the input given the error of decoding it:
+// that it exceeds the max size, is truncated, or, once decoded, has no
+// app_state modules. It returns nil if nothing was detected.
+func (g *inputGuard) Err(decodeErr error) error {
+	switch {
+	case g.exceeded:
+		return fmt.Errorf("the input exceeds --%s of %d bytes", flagMaxInputSize, g.maxSize)
+	case decodeErr != nil:
+		if g.scan.truncated() {
+			return fmt.Errorf("the input is truncated after %d bytes, inside %s", g.scan.n, g.scan.location())
+		}
+		return nil
+	case !g.scan.appState:
+		return fmt.Errorf("the input has no app_state")
+	case g.scan.modules == 0:
+		return fmt.Errorf("the app_state of the input has no modules")
+	}
+
+	return nil
+}
+
+// guardedReader fails the reads past the max input size.
+type guardedReader struct {
+	r io.Reader
+	g *inputGuard
+}
+
+func (r *guardedReader) Read(p []byte) (int, error) {
+	if r.g.read >= r.g.maxSize {
+		// one more byte tells whether the input ends right at the max size
+		var b [1]byte
+		if n, _ := r.r.Read(b[:]); n == 0 {
+			return 0, io.EOF
+		}
+		r.g.exceeded = true
+		return 0, fmt.Errorf("the input exceeds --%s of %d bytes", flagMaxInputSize, r.g.maxSize)
+	}
+
+	if remaining := r.g.maxSize - r.g.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := r.r.Read(p)
+	r.g.read += int64(n)
+	return n, err
+}
+
+// inputScanner follows the structure of a JSON document as it is written,
+// without decoding it: the nesting, the top-level key and app_state module
+// being read, and the number of app_state modules.
+type inputScanner struct {
+	n         int64
+	stack     []byte
+	inString  bool
+	escaped   bool
+	expectKey bool
+	capturing bool
+	key       []byte
+
+	topKey   string
+	module   string
+	appState bool
+	modules  int
+}
+
+func (s *inputScanner) Write(p []byte) (int, error) {
+	for _, c := range p {
+		s.n++
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+				if s.capturing {
+					s.endKey()
+				}
+				continue
+			}
+			if s.capturing {
+				s.key = append(s.key, c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			s.inString = true
+			depth := len(s.stack)
+			s.capturing = s.expectKey && (depth == 1 || (depth == 2 && s.topKey == "app_state"))
+			s.key = s.key[:0]
+			s.expectKey = false
+		case '{':
+			s.stack = append(s.stack, c)
+			s.expectKey = true
+		case '[':
+			s.stack = append(s.stack, c)
+			s.expectKey = false
+		case '}', ']':
+			if len(s.stack) > 0 {
+				s.stack = s.stack[:len(s.stack)-1]
+			}
+			s.expectKey = false
+		case ',':
+			s.expectKey = len(s.stack) > 0 && s.stack[len(s.stack)-1] == '{'
+		}
+	}
+
+	return len(p), nil
+}
+
+func (s *inputScanner) endKey() {
+	s.capturing = false
+	if len(s.stack) == 1 {
+		s.topKey = string(s.key)
+		s.appState = s.appState || s.topKey == "app_state"
+		return
+	}
+
+	s.module = string(s.key)
+	s.modules++
+}
+
+// truncated tells whether the document ended inside a value.
+func (s *inputScanner) truncated() bool {
+	return len(s.stack) > 0 || s.inString
+}
+
+// location names where the document ended.
+func (s *inputScanner) location() string {
+	switch {
+	case s.topKey == "":
+		return "the top-level object"
+	case s.topKey == "app_state" && s.module != "" && len(s.stack) > 1:
+		return "app_state." + s.module
+	}
+
+	return s.topKey
+}
@@ -0,0 +1,75 @@
+package gaia
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateInputGuard(t *testing.T) {
+	source, err := ioutil.ReadFile("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	args := []string{"--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	write := func(name string, bz []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+		return path
+	}
+	gzipped := func(bz []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write(bz)
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "genesis.json", Mode: 0600, Size: int64(len(source))}))
+	_, err = tw.Write(source)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	for _, tc := range []struct {
+		name     string
+		input    []byte
+		args     []string
+		expected string
+	}{
+		{"empty", nil, nil, "the input is empty"},
+		{"tar", tarball.Bytes(), nil, "the input appears to be a tar archive"},
+		{"tar.gz", gzipped(tarball.Bytes()), nil, "the input appears to be a tar archive, compressed with gzip"},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00"), nil, "the input appears to be a zip archive"},
+		{"xz", []byte("\xfd7zXZ\x00\x00"), nil, "the input appears to be compressed with xz, only gzip is read"},
+		{"html", []byte("  <html><body>Not Found</body></html>"), nil, `the input does not start with a JSON object but with "<html><body>Not Found</body></ht"`},
+		{"truncated", source[:len(source)/2], nil, "the input is truncated after 4071 bytes, inside app_state.mint"},
+		{"truncated header", source[:40], nil, "the input is truncated after 40 bytes, inside genesis_time"},
+		{"no modules", []byte(`{"genesis_time":"2019-12-11T16:11:34Z","chain_id":"cosmoshub-3","consensus_params":{"evidence":{"max_age":"1000000"}},"app_state":{}}`), nil, "the app_state of the input has no modules"},
+		{"too large", source, []string{"--max-input-size", "1000"}, "the input exceeds --max-input-size of 1000 bytes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := write(tc.name, tc.input)
+			_, err := executeMigrate(t, append(append([]string{path}, args...), tc.args...)...)
+			require.EqualError(t, err, "invalid genesis input "+path+": "+tc.expected)
+		})
+	}
+
+	// a gzip compressed genesis migrates like the plain one
+	expected, err := executeMigrate(t, append([]string{"testdata/cosmoshub-2-genesis.json"}, args...)...)
+	require.NoError(t, err)
+	out, err := executeMigrate(t, append([]string{write("genesis.json.gz", gzipped(source))}, args...)...)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(out))
+
+	// exactly the max size is accepted
+	_, err = executeMigrate(t, append([]string{"testdata/cosmoshub-2-genesis.json", "--max-input-size", "8143"}, args...)...)
+	require.NoError(t, err)
+}
@@ -0,0 +1,39 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// decodeStrictJSON decodes the JSON value bz into v, failing on the fields v
+// does not have and on anything following the value, which json.Unmarshal
+// would ignore or report after a partial decode.
+func decodeStrictJSON(bz []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(bz))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the JSON value")
+	}
+
+	return nil
+}
+
+// validateCoins validates coins decoded from a user supplied file. A coin
+// without an amount decodes to a nil Int, on which Coins.Validate panics.
+func validateCoins(coins sdk.Coins) error {
+	for _, coin := range coins {
+		if coin.Amount.IsNil() {
+			return fmt.Errorf("coin %s has no amount", coin.Denom)
+		}
+	}
+
+	return coins.Validate()
+}
@@ -0,0 +1,149 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// legacyRename moves the value at the dot separated path From to To.
+type legacyRename struct {
+	From, To string
+}
+
+// legacyDefault sets the dot separated Path to the JSON Value when missing.
+type legacyDefault struct {
+	Path  string
+	Value string
+}
+
+// legacyEra describes how exports of a chain older than cosmoshub-3 are
+// normalized into the cosmoshub-3 shape migrate starts from. Renames and
+// Defaults apply to the whole genesis doc, Migrations are the SDK genesis
+// migrations run on the app state before v0.38.
+type legacyEra struct {
+	Renames    []legacyRename
+	Defaults   []legacyDefault
+	Migrations []string
+}
+
+// legacyEras are the --legacy-source eras of data/legacy-eras.json.
+// Supporting another era only takes adding it there.
+var legacyEras = mustLoadLegacyEras()
+
+// legacyEraNames returns the supported --legacy-source values.
+func legacyEraNames() []string {
+	names := make([]string, 0, len(legacyEras))
+	for name := range legacyEras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// normalizeLegacyGenesis applies the renames and defaults of era to a genesis
+// doc JSON and returns it with the number of changed fields.
+func normalizeLegacyGenesis(bz []byte, era legacyEra) ([]byte, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, 0, err
+	}
+
+	changed := 0
+	for _, rename := range era.Renames {
+		value, ok := lookupJSONPath(doc, rename.From)
+		if !ok {
+			continue
+		}
+
+		if _, exists := lookupJSONPath(doc, rename.To); exists {
+			return nil, 0, fmt.Errorf("cannot rename %s, %s already exists", rename.From, rename.To)
+		}
+
+		if err := setJSONPath(doc, rename.To, value); err != nil {
+			return nil, 0, err
+		}
+		deleteJSONPath(doc, rename.From)
+		changed++
+	}
+
+	for _, def := range era.Defaults {
+		if _, ok := lookupJSONPath(doc, def.Path); ok {
+			continue
+		}
+
+		valueDec := json.NewDecoder(strings.NewReader(def.Value))
+		valueDec.UseNumber()
+
+		var value interface{}
+		if err := valueDec.Decode(&value); err != nil {
+			return nil, 0, fmt.Errorf("invalid default for %s: %w", def.Path, err)
+		}
+
+		if err := setJSONPath(doc, def.Path, value); err != nil {
+			return nil, 0, err
+		}
+		changed++
+	}
+
+	out, err := json.Marshal(doc)
+	return out, changed, err
+}
+
+func lookupJSONPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+
+	var value interface{} = doc
+	for _, key := range keys {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if value, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func setJSONPath(doc map[string]interface{}, path string, value interface{}) error {
+	keys := strings.Split(path, ".")
+
+	obj := doc
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key]
+		if !ok {
+			next = make(map[string]interface{})
+			obj[key] = next
+		}
+
+		if obj, ok = next.(map[string]interface{}); !ok {
+			return fmt.Errorf("cannot set %s, %s is not an object", path, key)
+		}
+	}
+
+	obj[keys[len(keys)-1]] = value
+	return nil
+}
+
+func deleteJSONPath(doc map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	last := len(keys) - 1
+
+	var parent interface{} = doc
+	if last > 0 {
+		parent, _ = lookupJSONPath(doc, strings.Join(keys[:last], "."))
+	}
+
+	if obj, ok := parent.(map[string]interface{}); ok {
+		delete(obj, keys[last])
+	}
+}
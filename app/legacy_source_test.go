@@ -0,0 +1,113 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// executeMigrate runs MigrateGenesisCmd with args and returns its output.
+func executeMigrate(t *testing.T, args ...string) ([]byte, error) {
+	return executeMigrateTo(t, ioutil.Discard, args...)
+}
+
+// executeMigrateTo is executeMigrate writing the stderr of the command to
+// stderr.
+func executeMigrateTo(t *testing.T, stderr io.Writer, args ...string) ([]byte, error) {
+	return executeMigrateContext(context.Background(), t, stderr, args...)
+}
+
+// executeMigrateContext is executeMigrateTo running the command with ctx.
+func executeMigrateContext(ctx context.Context, t *testing.T, stderr io.Writer, args ...string) ([]byte, error) {
+	clientCtx := migrateClientContext()
+	return executeMigrateClient(ctx, &clientCtx, stderr, args...)
+}
+
+// migrateClientContext returns the client context of executeMigrate, with a
+// new encoding config.
+func migrateClientContext() client.Context {
+	encodingConfig := MakeEncodingConfig()
+	return client.Context{}.
+		WithJSONMarshaler(encodingConfig.Marshaler).
+		WithInterfaceRegistry(encodingConfig.InterfaceRegistry).
+		WithTxConfig(encodingConfig.TxConfig).
+		WithLegacyAmino(encodingConfig.Amino)
+}
+
+// executeMigrateClient is executeMigrateContext running the command with
+// clientCtx, which concurrent runs may share.
+func executeMigrateClient(ctx context.Context, clientCtx *client.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	cmd := MigrateGenesisCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(stderr)
+	cmd.SetArgs(args)
+
+	err := cmd.ExecuteContext(context.WithValue(ctx, client.ClientContextKey, clientCtx))
+	return out.Bytes(), err
+}
+
+func TestNormalizeLegacyGenesis(t *testing.T) {
+	era := legacyEra{
+		Renames: []legacyRename{
+			{"a.old", "a.new"},
+			{"b", "c.d"},
+			{"missing", "ignored"},
+		},
+		Defaults: []legacyDefault{
+			{"a.number", "18446744073709551615"},
+			{"a.new", `"unused"`},
+		},
+	}
+
+	bz, changed, err := normalizeLegacyGenesis([]byte(`{"a":{"old":"x"},"b":[1,2]}`), era)
+	require.NoError(t, err)
+	require.Equal(t, 3, changed)
+	require.JSONEq(t, `{"a":{"new":"x","number":18446744073709551615},"c":{"d":[1,2]}}`, string(bz))
+	require.Contains(t, string(bz), "18446744073709551615")
+
+	_, _, err = normalizeLegacyGenesis([]byte(`{"a":{"old":"x","new":"y"}}`), era)
+	require.Error(t, err)
+}
+
+func TestMigrateCosmoshub2(t *testing.T) {
+	_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--no-prop-29")
+	require.Error(t, err, "a cosmoshub-2 export cannot be migrated without --legacy-source")
+
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-1")
+	require.Error(t, err)
+
+	out, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--initial-height", "2")
+	require.NoError(t, err)
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), genDoc.ConsensusParams.Block.TimeIotaMs)
+	require.Len(t, genDoc.Validators, 1)
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	encodingConfig := MakeEncodingConfig()
+	// migrate does not create the liquidity genesis, validate the migrated modules
+	for name, basic := range ModuleBasics {
+		if bz, ok := state[name]; ok {
+			require.NoError(t, basic.ValidateGenesis(encodingConfig.Marshaler, encodingConfig.TxConfig, bz), name)
+		}
+	}
+
+	var stakingGenesis staking.GenesisState
+	encodingConfig.Marshaler.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	require.Len(t, stakingGenesis.Validators, 1)
+	require.Equal(t, "validator", stakingGenesis.Validators[0].GetMoniker())
+}
@@ -0,0 +1,183 @@
+package gaia
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	ibcclient "github.com/cosmos/cosmos-sdk/x/ibc/core/02-client/types"
+	params "github.com/cosmos/cosmos-sdk/x/params/types/proposal"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	upgrade "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagTruncateLongStrings = "truncate-long-strings"
+	flagLongStringsReport   = "long-strings-report"
+)
+
+// longString is a string of the migrated state longer than the limit the
+// target version enforces when it is edited.
+type longString struct {
+	Module string `json:"module"`
+	// Record is the operator address of a validator or the id of a proposal.
+	Record string `json:"record"`
+	Field  string `json:"field"`
+	// Length and Limit are in bytes, as the limits are checked.
+	Length    int  `json:"length"`
+	Limit     int  `json:"limit"`
+	Truncated bool `json:"truncated"`
+	// Exempt is set on the contents of the proposals past voting, which are
+	// kept as they are: they cannot be edited and are history.
+	Exempt bool `json:"exempt,omitempty"`
+}
+
+// checkLongStrings returns the validator description fields and proposal
+// titles and descriptions of state longer than the staking and gov limits
+// of the target version, by module, record and field. With truncate they are
+// cut to the limit, but those of the proposals past voting.
+func checkLongStrings(cdc codec.JSONMarshaler, state types.AppMap, truncate bool) ([]longString, error) {
+	var (
+		long           []longString
+		stakingGenesis staking.GenesisState
+		govGenesis     gov.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the staking genesis")
+	}
+	if err := cdc.UnmarshalJSON(state[gov.ModuleName], &govGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the gov genesis")
+	}
+
+	for i, val := range stakingGenesis.Validators {
+		description := &stakingGenesis.Validators[i].Description
+		for _, field := range []struct {
+			name  string
+			value *string
+			limit int
+		}{
+			{"moniker", &description.Moniker, staking.MaxMonikerLength},
+			{"identity", &description.Identity, staking.MaxIdentityLength},
+			{"website", &description.Website, staking.MaxWebsiteLength},
+			{"security_contact", &description.SecurityContact, staking.MaxSecurityContactLength},
+			{"details", &description.Details, staking.MaxDetailsLength},
+		} {
+			if len(*field.value) <= field.limit {
+				continue
+			}
+
+			long = append(long, longString{
+				Module: staking.ModuleName, Record: val.OperatorAddress, Field: field.name,
+				Length: len(*field.value), Limit: field.limit, Truncated: truncate,
+			})
+			if truncate {
+				*field.value = truncateUTF8(*field.value, field.limit)
+			}
+		}
+	}
+
+	for i, proposal := range govGenesis.Proposals {
+		content := proposal.GetContent()
+		if content == nil {
+			continue
+		}
+
+		title, description := content.GetTitle(), content.GetDescription()
+		exempt := proposal.Status != gov.StatusDepositPeriod && proposal.Status != gov.StatusVotingPeriod
+		var found []longString
+		if len(title) > gov.MaxTitleLength {
+			found = append(found, longString{Field: "title", Length: len(title), Limit: gov.MaxTitleLength})
+			title = truncateUTF8(title, gov.MaxTitleLength)
+		}
+		if len(description) > gov.MaxDescriptionLength {
+			found = append(found, longString{Field: "description", Length: len(description), Limit: gov.MaxDescriptionLength})
+			description = truncateUTF8(description, gov.MaxDescriptionLength)
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		truncated := truncate && !exempt
+		if truncated {
+			any, err := truncatedContent(content, title, description)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to truncate the content of proposal %d", proposal.ProposalId)
+			}
+			govGenesis.Proposals[i].Content = any
+		}
+
+		for _, entry := range found {
+			entry.Module, entry.Record = gov.ModuleName, fmt.Sprint(proposal.ProposalId)
+			entry.Truncated, entry.Exempt = truncated, exempt
+			long = append(long, entry)
+		}
+	}
+
+	if !truncate {
+		return long, nil
+	}
+
+	var err error
+	if state[staking.ModuleName], err = cdc.MarshalJSON(&stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the staking genesis")
+	}
+	if state[gov.ModuleName], err = cdc.MarshalJSON(&govGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the gov genesis")
+	}
+
+	return long, nil
+}
+
+// truncatedContent returns a packed copy of the proposal content with the
+// title and description. The content is the cached value of the decoded
+// genesis and is left unchanged.
+func truncatedContent(content gov.Content, title, description string) (*codectypes.Any, error) {
+	switch c := content.(type) {
+	case *gov.TextProposal:
+		truncated := *c
+		truncated.Title, truncated.Description = title, description
+		return codectypes.NewAnyWithValue(&truncated)
+	case *params.ParameterChangeProposal:
+		truncated := *c
+		truncated.Title, truncated.Description = title, description
+		return codectypes.NewAnyWithValue(&truncated)
+	case *upgrade.SoftwareUpgradeProposal:
+		truncated := *c
+		truncated.Title, truncated.Description = title, description
+		return codectypes.NewAnyWithValue(&truncated)
+	case *upgrade.CancelSoftwareUpgradeProposal:
+		truncated := *c
+		truncated.Title, truncated.Description = title, description
+		return codectypes.NewAnyWithValue(&truncated)
+	case *distribution.CommunityPoolSpendProposal:
+		truncated := *c
+		truncated.Title, truncated.Description = title, description
+		return codectypes.NewAnyWithValue(&truncated)
+	case *ibcclient.ClientUpdateProposal:
+		truncated := *c
+		truncated.Title, truncated.Description = title, description
+		return codectypes.NewAnyWithValue(&truncated)
+	default:
+		return nil, fmt.Errorf("unknown content type %T", content)
+	}
+}
+
+// truncateUTF8 cuts s to at most limit bytes without splitting a UTF-8
+// encoded rune.
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	end := limit
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	return s[:end]
+}
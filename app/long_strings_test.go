@@ -0,0 +1,197 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		in    string
+		limit int
+		out   string
+	}{
+		{"shorter", "abc", 5, "abc"},
+		{"exact", "abcde", 5, "abcde"},
+		{"ascii", "abcdefgh", 5, "abcde"},
+		{"two byte rune at the limit", "abcdé", 6, "abcdé"},
+		{"two byte rune across the limit", "abcdé", 5, "abcd"},
+		{"three byte runes", "日本語", 7, "日本"},
+		{"four byte rune", "ab😀cd", 5, "ab"},
+		{"rune at the start", "😀", 3, ""},
+		{"invalid bytes", "ab\xff\xffcd", 3, "ab\xff"},
+		{"zero", "abc", 0, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := truncateUTF8(tc.in, tc.limit)
+			require.Equal(t, tc.out, out)
+			require.LessOrEqual(t, len(out), tc.limit)
+			if utf8.ValidString(tc.in) {
+				require.True(t, utf8.ValidString(out))
+			}
+		})
+	}
+}
+
+// longStringsGenesis returns a test app state with a validator whose details
+// and moniker are too long and proposals in voting period and passed with a
+// too long description and title.
+func longStringsGenesis(t *testing.T) (*genesisbuilder.Builder, map[string]json.RawMessage) {
+	b := genesisbuilder.New().
+		WithValidators(2).
+		WithProposal(gov.StatusVotingPeriod, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10000000))).
+		WithProposal(gov.StatusPassed, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10000000)))
+	_, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	for i, val := range stakingGenesis.Validators {
+		if val.OperatorAddress == b.ValidatorAddress(1).String() {
+			stakingGenesis.Validators[i].Description.Details = strings.Repeat("#", 30000)
+			stakingGenesis.Validators[i].Description.Moniker = strings.Repeat("é", 40)
+		}
+	}
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	var govGenesis gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	voting, err := gov.NewProposal(gov.NewTextProposal("voting", strings.Repeat("€", 2000)), govGenesis.Proposals[0].ProposalId,
+		govGenesis.Proposals[0].SubmitTime, govGenesis.Proposals[0].DepositEndTime)
+	require.NoError(t, err)
+	voting.Status, voting.TotalDeposit = gov.StatusVotingPeriod, govGenesis.Proposals[0].TotalDeposit
+	voting.VotingStartTime, voting.VotingEndTime = govGenesis.Proposals[0].VotingStartTime, govGenesis.Proposals[0].VotingEndTime
+	passed := govGenesis.Proposals[1]
+	passedContent := gov.NewTextProposal(strings.Repeat("t", 200), "passed")
+	passed, err = gov.NewProposal(passedContent, passed.ProposalId, passed.SubmitTime, passed.DepositEndTime)
+	require.NoError(t, err)
+	passed.Status = gov.StatusPassed
+	govGenesis.Proposals = gov.Proposals{voting, passed}
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+
+	return b, state
+}
+
+func TestCheckLongStrings(t *testing.T) {
+	b, state := longStringsGenesis(t)
+	cdc := MakeEncodingConfig().Marshaler
+	operator := b.ValidatorAddress(1).String()
+
+	var govGenesis gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	voting := strconv.FormatUint(govGenesis.Proposals[0].ProposalId, 10)
+	passed := strconv.FormatUint(govGenesis.Proposals[1].ProposalId, 10)
+
+	expected := func(truncate bool) []longString {
+		return []longString{
+			{Module: staking.ModuleName, Record: operator, Field: "moniker", Length: 80, Limit: 70, Truncated: truncate},
+			{Module: staking.ModuleName, Record: operator, Field: "details", Length: 30000, Limit: 280, Truncated: truncate},
+			{Module: gov.ModuleName, Record: voting, Field: "description", Length: 6000, Limit: 5000, Truncated: truncate},
+			{Module: gov.ModuleName, Record: passed, Field: "title", Length: 200, Limit: 140, Exempt: true},
+		}
+	}
+
+	// without truncating the state is unchanged
+	before := make(map[string]json.RawMessage, len(state))
+	for module, bz := range state {
+		before[module] = bz
+	}
+	long, err := checkLongStrings(cdc, state, false)
+	require.NoError(t, err)
+	require.Equal(t, expected(false), long)
+	require.Equal(t, before, map[string]json.RawMessage(state))
+
+	long, err = checkLongStrings(cdc, state, true)
+	require.NoError(t, err)
+	require.Equal(t, expected(true), long)
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	for _, val := range stakingGenesis.Validators {
+		if val.OperatorAddress != operator {
+			continue
+		}
+		// 35 two byte runes, the limit of 70 bytes falls on a rune boundary
+		require.Equal(t, strings.Repeat("é", 35), val.Description.Moniker)
+		require.Equal(t, strings.Repeat("#", 280), val.Description.Details)
+		_, err := val.Description.EnsureLength()
+		require.NoError(t, err)
+	}
+
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	// 5000 bytes cut a three byte rune, 1666 runes are left
+	description := govGenesis.Proposals[0].GetContent().GetDescription()
+	require.Equal(t, strings.Repeat("€", 1666), description)
+	require.True(t, utf8.ValidString(description))
+	require.Equal(t, "voting", govGenesis.Proposals[0].GetContent().GetTitle())
+	require.NoError(t, gov.ValidateAbstract(govGenesis.Proposals[0].GetContent()))
+	// the passed proposal is kept as it is
+	require.Equal(t, strings.Repeat("t", 200), govGenesis.Proposals[1].GetContent().GetTitle())
+
+	// once truncated nothing is left to report but the exempt title
+	long, err = checkLongStrings(cdc, state, true)
+	require.NoError(t, err)
+	require.Equal(t, expected(true)[3:], long)
+}
+
+func TestMigrateLongStrings(t *testing.T) {
+	bz, err := ioutil.ReadFile(filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json"))
+	require.NoError(t, err)
+	var genesis map[string]interface{}
+	require.NoError(t, json.Unmarshal(bz, &genesis))
+	stakingState := genesis["app_state"].(map[string]interface{})["staking"].(map[string]interface{})
+	validator := stakingState["validators"].([]interface{})[0].(map[string]interface{})
+	validator["description"].(map[string]interface{})["details"] = strings.Repeat("ascii art ", 3000)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "genesis.json")
+	bz, err = json.Marshal(genesis)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	var stderr bytes.Buffer
+	_, err = executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "W-STAKING-005 [low] the details of validator cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0 is 30000 bytes, longer than the limit of 280, use --truncate-long-strings to cut it")
+
+	_, err = executeMigrate(t, path, "--chain-id", "cosmoshub-4", "--warnings-as-errors=W-STAKING-*")
+	require.Error(t, err)
+
+	reportPath := filepath.Join(dir, "long-strings.json")
+	stderr.Reset()
+	out, err := executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4", "--"+flagTruncateLongStrings, "--"+flagLongStringsReport, reportPath)
+	require.NoError(t, err)
+	require.NotContains(t, stderr.String(), "W-STAKING-005")
+	require.Contains(t, stderr.String(), "truncated 1 validator description and proposal content strings to their limit\n")
+
+	bz, err = ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report []longString
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Equal(t, []longString{{
+		Module: staking.ModuleName, Record: "cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0", Field: "details",
+		Length: 30000, Limit: staking.MaxDetailsLength, Truncated: true,
+	}}, report)
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	var appState map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &appState))
+	var stakingGenesis staking.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(appState[staking.ModuleName], &stakingGenesis)
+	require.Len(t, stakingGenesis.Validators[0].Description.Details, staking.MaxDetailsLength)
+}
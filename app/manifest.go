@@ -0,0 +1,156 @@
+package gaia
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// manifestFetchTimeout bounds the download of a manifest given as a URL.
+const manifestFetchTimeout = 30 * time.Second
+
+// migrationManifest describes a migrated genesis file so it can be verified
+// once published, written by migrate --manifest.
+type migrationManifest = genesis.Manifest
+
+// newMigrationManifest returns the manifest of the genesis genDoc written
+// through digest, migrated from the source read through sourceDigest by the
+// running binary with the migrate args.
+func newMigrationManifest(genDoc *tmtypes.GenesisDoc, digest, sourceDigest *digestWriter, args []string) migrationManifest {
+	versions := newBuildVersions()
+
+	return migrationManifest{
+		ChainID:             genDoc.ChainID,
+		GenesisTime:         genDoc.GenesisTime,
+		InitialHeight:       genDoc.InitialHeight,
+		GenesisSHA256:       digest.Sum(),
+		GenesisSize:         digest.Size(),
+		GaiaVersion:         versions.Gaia,
+		CosmosSDKVersion:    versions.CosmosSDK,
+		IBCVersion:          versions.IBC,
+		GoVersion:           versions.Go,
+		SourceGenesisSHA256: sourceDigest.Sum(),
+		MigrateArgs:         args,
+		MigrationData:       migrationDataHashes(),
+	}
+}
+
+// buildVersions are the versions a migration output depends on.
+type buildVersions struct {
+	Gaia      string
+	CosmosSDK string
+	IBC       string
+	Go        string
+}
+
+// newBuildVersions returns the versions of the running binary. IBC is part of
+// the cosmos-sdk until the binary depends on ibc-go.
+func newBuildVersions() buildVersions {
+	info := version.NewInfo()
+	versions := buildVersions{
+		Gaia:      info.Version,
+		CosmosSDK: info.CosmosSdkVersion,
+		IBC:       info.CosmosSdkVersion,
+		Go:        runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range buildInfo.Deps {
+			if strings.HasPrefix(dep.Path, "github.com/cosmos/ibc-go") {
+				versions.IBC = dep.Version
+			}
+		}
+	}
+
+	return versions
+}
+
+// manifestVersions returns the versions recorded by manifest.
+func manifestVersions(manifest migrationManifest) buildVersions {
+	return buildVersions{
+		Gaia:      manifest.GaiaVersion,
+		CosmosSDK: manifest.CosmosSDKVersion,
+		IBC:       manifest.IBCVersion,
+		Go:        manifest.GoVersion,
+	}
+}
+
+// String implements the fmt.Stringer interface.
+func (v buildVersions) String() string {
+	return fmt.Sprintf("gaia %s, cosmos-sdk %s, ibc %s, %s", v.Gaia, v.CosmosSDK, v.IBC, v.Go)
+}
+
+// loadMigrationManifest reads a manifest from a file or an http(s) URL.
+func loadMigrationManifest(source string) (migrationManifest, error) {
+	var bz []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := http.Client{Timeout: manifestFetchTimeout}
+		resp, err := client.Get(source)
+		if err != nil {
+			return migrationManifest{}, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return migrationManifest{}, fmt.Errorf("fetching %s: %s", source, resp.Status)
+		}
+
+		if bz, err = ioutil.ReadAll(resp.Body); err != nil {
+			return migrationManifest{}, err
+		}
+	} else {
+		var err error
+		if bz, err = ioutil.ReadFile(source); err != nil {
+			return migrationManifest{}, err
+		}
+	}
+
+	var manifest migrationManifest
+	if err := json.Unmarshal(bz, &manifest); err != nil {
+		return migrationManifest{}, fmt.Errorf("invalid manifest %s: %w", source, err)
+	}
+
+	if manifest.GenesisSHA256 == "" {
+		return migrationManifest{}, fmt.Errorf("manifest %s has no genesis_sha256", source)
+	}
+
+	return manifest, nil
+}
+
+// digestWriter computes the SHA-256 and size of everything written to it.
+type digestWriter struct {
+	hash hash.Hash
+	size int64
+}
+
+func newDigestWriter() *digestWriter {
+	return &digestWriter{hash: sha256.New()}
+}
+
+func (d *digestWriter) Write(p []byte) (int, error) {
+	d.size += int64(len(p))
+	return d.hash.Write(p)
+}
+
+// Sum returns the hex encoded SHA-256 of the written bytes.
+func (d *digestWriter) Sum() string {
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
+
+// Size returns the number of written bytes.
+func (d *digestWriter) Size() int64 {
+	return d.size
+}
@@ -0,0 +1,218 @@
+package gaia
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec/legacy"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagSignManifest = "sign-manifest"
+	flagPubKey       = "pubkey"
+
+	// manifestSignatureExt is appended to the path of a signed manifest to
+	// get the path of its signature.
+	manifestSignatureExt = ".sig"
+)
+
+// manifestSignature is the detached signature of a migration manifest,
+// written by migrate --sign-manifest. Signature signs the manifestSignBytes
+// of the manifest by Signer, PubKey is the amino JSON of the key of Signer.
+type manifestSignature struct {
+	Signer    string          `json:"signer"`
+	PubKey    json.RawMessage `json:"pub_key"`
+	Signature []byte          `json:"signature"`
+}
+
+// manifestSignBytes returns the bytes signing the manifest bz by the account
+// address signer: the amino JSON sign doc of ADR-36 of a sign/MsgSignData of
+// the canonical JSON of bz, without chain ID, account number, sequence and
+// fee. A signature thus verifies however the manifest is indented.
+func manifestSignBytes(signer string, bz []byte) ([]byte, error) {
+	canonical, err := canonicalJSON(bz, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+
+	// json.Marshal sorts the keys of maps, as amino JSON sign docs are
+	return json.Marshal(map[string]interface{}{
+		"account_number": "0",
+		"chain_id":       "",
+		"fee":            map[string]interface{}{"amount": []interface{}{}, "gas": "0"},
+		"memo":           "",
+		"msgs": []interface{}{map[string]interface{}{
+			"type":  "sign/MsgSignData",
+			"value": map[string]interface{}{"data": base64.StdEncoding.EncodeToString(canonical), "signer": signer},
+		}},
+		"sequence": "0",
+	})
+}
+
+// manifestSigner signs manifests with the key uid of a keyring.
+type manifestSigner struct {
+	keyring keyring.Keyring
+	uid     string
+	address sdk.AccAddress
+}
+
+// newManifestSigner returns the signer of the key --from of the keyring of
+// the migrate options, or nil without --sign-manifest. The key must exist,
+// a ledger key is only reached when signing.
+func newManifestSigner(cmd *cobra.Command, clientCtx client.Context, opts *MigrateOptions) (*manifestSigner, error) {
+	if !opts.SignManifest {
+		return nil, nil
+	}
+
+	if opts.Manifest == "" && opts.BundleDir == "" {
+		return nil, fmt.Errorf("--%s needs --%s or --%s", flagSignManifest, flagManifest, flagBundleDir)
+	}
+
+	uid := opts.From
+	if uid == "" {
+		return nil, fmt.Errorf("--%s needs the key name of --%s", flagSignManifest, flags.FlagFrom)
+	}
+
+	dir := opts.KeyringDir
+	if dir == "" {
+		dir = clientCtx.HomeDir
+	}
+
+	kr, err := keyring.New(sdk.KeyringServiceName(), opts.KeyringBackend, dir, cmd.InOrStdin())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the %s keyring", opts.KeyringBackend)
+	}
+
+	info, err := kr.Key(uid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find key %s of --%s", uid, flags.FlagFrom)
+	}
+
+	return &manifestSigner{keyring: kr, uid: uid, address: info.GetAddress()}, nil
+}
+
+// Sign returns the signature of the manifest bz.
+func (s *manifestSigner) Sign(bz []byte) (manifestSignature, error) {
+	signBytes, err := manifestSignBytes(s.address.String(), bz)
+	if err != nil {
+		return manifestSignature{}, err
+	}
+
+	sig, pubKey, err := s.keyring.Sign(s.uid, signBytes)
+	if err != nil {
+		return manifestSignature{}, errors.Wrapf(err, "failed to sign the manifest with key %s", s.uid)
+	}
+
+	pubKeyBz, err := legacy.Cdc.MarshalJSON(pubKey)
+	if err != nil {
+		return manifestSignature{}, err
+	}
+
+	return manifestSignature{Signer: s.address.String(), PubKey: pubKeyBz, Signature: sig}, nil
+}
+
+// verifyManifestSignature checks sig signs the manifest bz by its signer, and
+// that the signer is signer, a bech32 account address or account pubkey,
+// if set.
+func verifyManifestSignature(bz []byte, sig manifestSignature, signer string) error {
+	var pubKey cryptotypes.PubKey
+	if err := legacy.Cdc.UnmarshalJSON(sig.PubKey, &pubKey); err != nil {
+		return errors.Wrap(err, "invalid signature pubkey")
+	}
+
+	if addr := sdk.AccAddress(pubKey.Address()).String(); addr != sig.Signer {
+		return fmt.Errorf("the signature pubkey is the key of %s, not of its signer %s", addr, sig.Signer)
+	}
+
+	if signer != "" {
+		expected, err := manifestSignerAddress(signer)
+		if err != nil {
+			return err
+		}
+		if expected != sig.Signer {
+			return fmt.Errorf("the manifest is signed by %s, not %s", sig.Signer, expected)
+		}
+	}
+
+	signBytes, err := manifestSignBytes(sig.Signer, bz)
+	if err != nil {
+		return err
+	}
+	if !pubKey.VerifySignature(signBytes, sig.Signature) {
+		return fmt.Errorf("the signature of %s does not match the manifest", sig.Signer)
+	}
+
+	return nil
+}
+
+// manifestSignerAddress returns the account address of a --pubkey, an
+// account address or pubkey in bech32.
+func manifestSignerAddress(s string) (string, error) {
+	if addr, err := sdk.AccAddressFromBech32(s); err == nil {
+		return addr.String(), nil
+	}
+
+	pubKey, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeAccPub, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid --%s %s, neither an account address nor an account pubkey", flagPubKey, s)
+	}
+
+	return sdk.AccAddress(pubKey.Address()).String(), nil
+}
+
+// GenesisVerifyManifestCmd returns a command checking the signature of a
+// migration manifest.
+func GenesisVerifyManifestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-manifest [manifest-file] [signature-file]",
+		Short: "Verify the signature of a migration manifest",
+		Long: fmt.Sprintf(`Verify offline the signature written by migrate --sign-manifest, by default to
+the manifest path with %s appended, of a migration manifest. The signature
+signs the ADR-36 sign doc of the canonical JSON of the manifest by a keyring
+key, so the manifest may be re-indented but changing any of its values breaks
+it. --pubkey, the account address or bech32 account pubkey of the expected
+signer, fails a signature by any other key; without it the signer is printed.
+
+Example:
+$ %s genesis verify-manifest manifest.json manifest.json.sig --pubkey cosmos1...
+`, manifestSignatureExt, version.AppName),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bz, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return errors.Wrap(err, "failed to read manifest")
+			}
+
+			sigBz, err := ioutil.ReadFile(args[1])
+			if err != nil {
+				return errors.Wrap(err, "failed to read signature")
+			}
+			var sig manifestSignature
+			if err := json.Unmarshal(sigBz, &sig); err != nil {
+				return fmt.Errorf("invalid signature %s: %w", args[1], err)
+			}
+
+			signer, _ := cmd.Flags().GetString(flagPubKey)
+			if err := verifyManifestSignature(bz, sig, signer); err != nil {
+				return err
+			}
+
+			cmd.Printf("%s is signed by %s\n", args[0], sig.Signer)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagPubKey, "", "Account address or bech32 account pubkey of the expected signer")
+
+	return cmd
+}
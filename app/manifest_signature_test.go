@@ -0,0 +1,132 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+// executeVerifyManifest runs genesis verify-manifest with args and returns
+// its output.
+func executeVerifyManifest(args ...string) (string, error) {
+	cmd := GenesisVerifyManifestCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// executeMigrateSigning runs migrate with args reading the keyring
+// passphrases from stdin.
+func executeMigrateSigning(stdin io.Reader, args ...string) error {
+	cmd := MigrateGenesisCmd()
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetIn(stdin)
+	cmd.SetArgs(args)
+
+	clientCtx := migrateClientContext()
+	return cmd.ExecuteContext(context.WithValue(context.Background(), client.ClientContextKey, &clientCtx))
+}
+
+func TestMigrateSignManifest(t *testing.T) {
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	for _, tc := range []struct {
+		backend, passphrase string
+	}{
+		{keyring.BackendTest, ""},
+		{keyring.BackendFile, "coordinator passphrase\n"},
+	} {
+		t.Run(tc.backend, func(t *testing.T) {
+			dir := t.TempDir()
+			kr, err := keyring.New(sdk.KeyringServiceName(), tc.backend, dir, strings.NewReader(strings.Repeat(tc.passphrase, 2)))
+			require.NoError(t, err)
+			info, _, err := kr.NewMnemonic("coordinator", keyring.English, sdk.FullFundraiserPath, hd.Secp256k1)
+			require.NoError(t, err)
+			other, _, err := kr.NewMnemonic("other", keyring.English, sdk.FullFundraiserPath, hd.Secp256k1)
+			require.NoError(t, err)
+
+			manifest := filepath.Join(t.TempDir(), "manifest.json")
+			signArgs := append(args, "--output", filepath.Join(t.TempDir(), "genesis.json"), "--manifest", manifest,
+				"--sign-manifest", "--from", "coordinator", "--keyring-backend", tc.backend, "--keyring-dir", dir)
+			require.NoError(t, executeMigrateSigning(strings.NewReader(tc.passphrase), signArgs...))
+			sig := manifest + manifestSignatureExt
+
+			out, err := executeVerifyManifest(manifest, sig, "--pubkey", info.GetAddress().String())
+			require.NoError(t, err)
+			require.Equal(t, manifest+" is signed by "+info.GetAddress().String()+"\n", out)
+
+			pubKey, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeAccPub, info.GetPubKey())
+			require.NoError(t, err)
+			_, err = executeVerifyManifest(manifest, sig, "--pubkey", pubKey)
+			require.NoError(t, err)
+
+			_, err = executeVerifyManifest(manifest, sig, "--pubkey", other.GetAddress().String())
+			require.EqualError(t, err, "the manifest is signed by "+info.GetAddress().String()+", not "+other.GetAddress().String())
+
+			// the signature is of the values, not the indentation
+			bz, err := ioutil.ReadFile(manifest)
+			require.NoError(t, err)
+			var values map[string]interface{}
+			require.NoError(t, json.Unmarshal(bz, &values))
+			compact, err := json.Marshal(values)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(manifest, compact, 0644))
+			_, err = executeVerifyManifest(manifest, sig)
+			require.NoError(t, err)
+
+			values["chain_id"] = "cosmoshub-5"
+			tampered, err := json.Marshal(values)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(manifest, tampered, 0644))
+			_, err = executeVerifyManifest(manifest, sig, "--pubkey", info.GetAddress().String())
+			require.EqualError(t, err, "the signature of "+info.GetAddress().String()+" does not match the manifest")
+		})
+	}
+}
+
+func TestMigrateSignManifestErrors(t *testing.T) {
+	dir := t.TempDir()
+	kr, err := keyring.New(sdk.KeyringServiceName(), keyring.BackendTest, dir, nil)
+	require.NoError(t, err)
+	_, _, err = kr.NewMnemonic("coordinator", keyring.English, sdk.FullFundraiserPath, hd.Secp256k1)
+	require.NoError(t, err)
+	// a key that cannot sign, as a ledger key without its device
+	_, err = kr.SavePubKey("offline", secp256k1.GenPrivKey().PubKey(), hd.Secp256k1Type)
+	require.NoError(t, err)
+
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4",
+		"--output", filepath.Join(t.TempDir(), "genesis.json"), "--sign-manifest", "--keyring-backend", keyring.BackendTest, "--keyring-dir", dir}
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+
+	err = executeMigrateSigning(nil, append(args, "--from", "coordinator")...)
+	require.EqualError(t, err, "--sign-manifest needs --manifest or --bundle-dir")
+
+	err = executeMigrateSigning(nil, append(args, "--manifest", manifest)...)
+	require.EqualError(t, err, "--sign-manifest needs the key name of --from")
+
+	err = executeMigrateSigning(nil, append(args, "--manifest", manifest, "--from", "missing")...)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to find key missing of --from")
+
+	err = executeMigrateSigning(nil, append(args, "--manifest", manifest, "--from", "offline")...)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to sign the manifest with key offline")
+	require.NoFileExists(t, manifest+manifestSignatureExt)
+}
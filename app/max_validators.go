@@ -0,0 +1,137 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// validatorDemotion describes a bonded validator moved to unbonding because
+// the bonded set exceeded the max_validators staking param.
+type validatorDemotion struct {
+	OperatorAddress string          `json:"operator_address"`
+	ConsAddress     sdk.ConsAddress `json:"cons_address"`
+	Moniker         string          `json:"moniker"`
+	Power           int64           `json:"power"`
+	Tokens          sdk.Int         `json:"tokens"`
+}
+
+// capBondedValidators demotes the lowest power bonded validators beyond the
+// max_validators staking param to unbonding, as the staking end blocker would,
+// starting their unbonding at height and genesisTime. Validators of equal power
+// are kept in operator address order, so the greater addresses are demoted
+// first. Their tokens move from the bonded to the not bonded pool and they are
+// removed from the last validator powers, the tendermint genesis validators
+// must be updated by the caller.
+func capBondedValidators(cdc codec.JSONMarshaler, state types.AppMap, height int64, genesisTime time.Time) ([]validatorDemotion, error) {
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal staking genesis: %w", err)
+	}
+
+	var bonded []int
+	for i, val := range stakingGenesis.Validators {
+		if val.IsBonded() {
+			bonded = append(bonded, i)
+		}
+	}
+
+	maxValidators := int(stakingGenesis.Params.MaxValidators)
+	if len(bonded) <= maxValidators {
+		return nil, nil
+	}
+
+	sort.SliceStable(bonded, func(i, j int) bool {
+		vi, vj := stakingGenesis.Validators[bonded[i]], stakingGenesis.Validators[bonded[j]]
+		if vi.ConsensusPower() != vj.ConsensusPower() {
+			return vi.ConsensusPower() > vj.ConsensusPower()
+		}
+		return vi.OperatorAddress < vj.OperatorAddress
+	})
+
+	var bankGenesis bank.GenesisState
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bank genesis: %w", err)
+	}
+
+	unbondingTime := genesisTime.Add(stakingGenesis.Params.UnbondingTime)
+	demotions := make([]validatorDemotion, 0, len(bonded)-maxValidators)
+	demoted := make(map[string]bool)
+	moved := sdk.ZeroInt()
+
+	for _, i := range bonded[maxValidators:] {
+		val := stakingGenesis.Validators[i]
+
+		consAddr, err := val.GetConsAddr()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consensus address of validator %s: %w", val.OperatorAddress, err)
+		}
+
+		demotions = append(demotions, validatorDemotion{
+			OperatorAddress: val.OperatorAddress,
+			ConsAddress:     consAddr,
+			Moniker:         val.GetMoniker(),
+			Power:           val.ConsensusPower(),
+			Tokens:          val.Tokens,
+		})
+
+		val = val.UpdateStatus(staking.Unbonding)
+		val.UnbondingHeight = height
+		val.UnbondingTime = unbondingTime
+		stakingGenesis.Validators[i] = val
+
+		demoted[val.OperatorAddress] = true
+		moved = moved.Add(val.Tokens)
+	}
+
+	lastTotalPower := sdk.ZeroInt()
+	powers := stakingGenesis.LastValidatorPowers[:0]
+	for _, lv := range stakingGenesis.LastValidatorPowers {
+		if demoted[lv.Address] {
+			continue
+		}
+
+		powers = append(powers, lv)
+		lastTotalPower = lastTotalPower.AddRaw(lv.Power)
+	}
+	stakingGenesis.LastValidatorPowers = powers
+	stakingGenesis.LastTotalPower = lastTotalPower
+
+	bondDenom := stakingGenesis.Params.BondDenom
+	bondedPool := auth.NewModuleAddress(staking.BondedPoolName).String()
+	notBondedPool := auth.NewModuleAddress(staking.NotBondedPoolName).String()
+	movedCoins := sdk.NewCoins(sdk.NewCoin(bondDenom, moved))
+
+	hasNotBondedPool := false
+	for i, balance := range bankGenesis.Balances {
+		switch balance.Address {
+		case bondedPool:
+			coins, hasNeg := balance.Coins.SafeSub(movedCoins)
+			if hasNeg {
+				return nil, fmt.Errorf("bonded pool holds %s, less than the %s bonded to demoted validators", balance.Coins, movedCoins)
+			}
+			bankGenesis.Balances[i].Coins = coins
+
+		case notBondedPool:
+			bankGenesis.Balances[i].Coins = balance.Coins.Add(movedCoins...)
+			hasNotBondedPool = true
+		}
+	}
+
+	if !hasNotBondedPool {
+		bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{Address: notBondedPool, Coins: movedCoins})
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(bankGenesis.Balances)
+
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	return demotions, nil
+}
@@ -0,0 +1,119 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func setMaxValidators(t *testing.T, state types.AppMap, maxValidators uint32) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	var stakingGenesis staking.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis))
+	stakingGenesis.Params.MaxValidators = maxValidators
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+}
+
+func TestCapBondedValidators(t *testing.T) {
+	b := genesisbuilder.New().WithValidatorPowers(30, 10, 20, 10)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	cdc := MakeEncodingConfig().Marshaler
+
+	t.Run("at cap", func(t *testing.T) {
+		_, state := exportTestGenesis(t, builtDoc)
+		setMaxValidators(t, state, 4)
+
+		before, err := json.Marshal(state)
+		require.NoError(t, err)
+
+		demotions, err := capBondedValidators(cdc, state, 2, genesisbuilder.GenesisTime)
+		require.NoError(t, err)
+		require.Empty(t, demotions)
+
+		after, err := json.Marshal(state)
+		require.NoError(t, err)
+		require.Equal(t, before, after)
+	})
+
+	t.Run("over cap", func(t *testing.T) {
+		genDoc, state := exportTestGenesis(t, builtDoc)
+		setMaxValidators(t, state, 3)
+
+		// validators 1 and 3 share the lowest power, the one with the greater
+		// operator address is demoted
+		demotedIndex := 3
+		if b.ValidatorAddress(1).String() > b.ValidatorAddress(3).String() {
+			demotedIndex = 1
+		}
+
+		demotions, err := capBondedValidators(cdc, state, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.NoError(t, err)
+		require.Equal(t, []validatorDemotion{{
+			OperatorAddress: b.ValidatorAddress(demotedIndex).String(),
+			ConsAddress:     b.ValidatorConsAddress(demotedIndex),
+			Moniker:         genesisbuilder.ValidatorName(demotedIndex),
+			Power:           10,
+			Tokens:          sdk.TokensFromConsensusPower(10),
+		}}, demotions)
+
+		var (
+			stakingGenesis staking.GenesisState
+			bankGenesis    bank.GenesisState
+		)
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+
+		bondedTokens, notBondedTokens := sdk.ZeroInt(), sdk.ZeroInt()
+		for _, val := range stakingGenesis.Validators {
+			switch val.OperatorAddress {
+			case demotions[0].OperatorAddress:
+				require.Equal(t, staking.Unbonding, val.Status)
+				require.Equal(t, genDoc.InitialHeight, val.UnbondingHeight)
+				require.Equal(t, genDoc.GenesisTime.Add(stakingGenesis.Params.UnbondingTime), val.UnbondingTime)
+				notBondedTokens = notBondedTokens.Add(val.Tokens)
+			default:
+				require.Equal(t, staking.Bonded, val.Status)
+				bondedTokens = bondedTokens.Add(val.Tokens)
+			}
+		}
+		for _, ubd := range stakingGenesis.UnbondingDelegations {
+			for _, entry := range ubd.Entries {
+				notBondedTokens = notBondedTokens.Add(entry.Balance)
+			}
+		}
+
+		require.Len(t, stakingGenesis.LastValidatorPowers, 3)
+		require.Equal(t, sdk.NewInt(60), stakingGenesis.LastTotalPower)
+
+		balances := make(map[string]sdk.Coins)
+		for _, balance := range bankGenesis.Balances {
+			balances[balance.Address] = balance.Coins
+		}
+		require.Equal(t, bondedTokens, balances[auth.NewModuleAddress(staking.BondedPoolName).String()].AmountOf(genesisbuilder.BondDenom))
+		require.Equal(t, notBondedTokens, balances[auth.NewModuleAddress(staking.NotBondedPoolName).String()].AmountOf(genesisbuilder.BondDenom))
+
+		var tmValidators []tmtypes.GenesisValidator
+		for _, val := range genDoc.Validators {
+			if !bytes.Equal(val.Address, demotions[0].ConsAddress) {
+				tmValidators = append(tmValidators, val)
+			}
+		}
+		genDoc.Validators = tmValidators
+
+		genDoc.AppState, err = json.Marshal(state)
+		require.NoError(t, err)
+		require.NoError(t, SmokeTestGenesis(genDoc))
+	})
+}
@@ -0,0 +1,141 @@
+package gaia
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "gaia_migrate"
+
+// migrationMetrics exposes the migration stages, sizes, warnings and results
+// as Prometheus metrics. It is only created for --metrics-listen, so migrations
+// without it do not pay for any instrumentation.
+type migrationMetrics struct {
+	registry *prometheus.Registry
+
+	stageDuration *prometheus.HistogramVec
+	stageProgress *prometheus.GaugeVec
+	inputBytes    prometheus.Gauge
+	outputBytes   prometheus.Gauge
+	warnings      *prometheus.CounterVec
+	runs          *prometheus.CounterVec
+
+	stage string
+
+	// status is served on /status, and /cancel if allowed, along with the
+	// metrics
+	status *migrationStatus
+}
+
+func newMigrationMetrics() *migrationMetrics {
+	m := &migrationMetrics{
+		registry: prometheus.NewRegistry(),
+		status:   newMigrationStatus(),
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "stage_duration_seconds",
+			Help:      "Duration of the migration stages.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"stage"}),
+		stageProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "stage_progress_ratio",
+			Help:      "Fraction of the bytes processed by the migration stages reporting progress.",
+		}, []string{"stage"}),
+		inputBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "input_bytes",
+			Help:      "Size of the genesis read by the migration.",
+		}),
+		outputBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "output_bytes",
+			Help:      "Size of the migrated genesis.",
+		}),
+		warnings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "warnings_total",
+			Help:      "Migration warnings by code.",
+		}, []string{"code"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "runs_total",
+			Help:      "Finished migrations by result, success or failure.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(m.stageDuration, m.stageProgress, m.inputBytes, m.outputBytes, m.warnings, m.runs)
+
+	// expose both results from the start so rates work before the first failure
+	m.runs.WithLabelValues("success")
+	m.runs.WithLabelValues("failure")
+
+	return m
+}
+
+func (m *migrationMetrics) StageStarted(_, _ int, name string) {
+	m.stage = name
+}
+
+func (m *migrationMetrics) StageProgress(done, total int64) {
+	if total > 0 {
+		m.stageProgress.WithLabelValues(m.stage).Set(float64(done) / float64(total))
+	}
+}
+
+func (m *migrationMetrics) StageFinished(name string, elapsed time.Duration) {
+	m.stageDuration.WithLabelValues(name).Observe(elapsed.Seconds())
+}
+
+// ObserveWarnings counts the warnings by code.
+func (m *migrationMetrics) ObserveWarnings(warnings []migrationWarning) {
+	for _, warning := range warnings {
+		m.warnings.WithLabelValues(warning.Code).Inc()
+	}
+}
+
+// ObserveResult counts a finished migration, failed if err is not nil.
+func (m *migrationMetrics) ObserveResult(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	m.runs.WithLabelValues(result).Inc()
+}
+
+// Serve exposes the metrics on /metrics, and the status of the migration on
+// /status and with allowCancel /cancel, at the listen address. It returns the
+// address listened on and a function stopping the server.
+func (m *migrationMetrics) Serve(listen string, allowCancel bool) (net.Addr, func() error, error) {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	m.status.register(mux, allowCancel)
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener) // nolint: errcheck
+
+	return listener.Addr(), server.Close, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,75 @@
+package gaia
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationMetrics(t *testing.T) {
+	metrics := newMigrationMetrics()
+
+	addr, stop, err := metrics.Serve("127.0.0.1:0", false)
+	require.NoError(t, err)
+	defer stop() // nolint: errcheck
+
+	scrape := func() string {
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", addr))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		bz, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(bz)
+	}
+
+	stages := newStageTracker([]string{"read", "modules", "output"}, metrics)
+	stages.Start("read")
+	metrics.inputBytes.Set(1024)
+	stages.Start("modules")
+	stages.Progress(50, 200)
+
+	// scraped while the migration is running
+	out := scrape()
+	require.Contains(t, out, `gaia_migrate_stage_duration_seconds_count{stage="read"} 1`)
+	require.Contains(t, out, `gaia_migrate_stage_progress_ratio{stage="modules"} 0.25`)
+	require.Contains(t, out, "gaia_migrate_input_bytes 1024")
+	require.Contains(t, out, `gaia_migrate_runs_total{result="success"} 0`)
+
+	warnings := &warningCollector{}
+	warnings.Add(warnMintInflationBounds, severityHigh, "mint", "out of bounds")
+	warnings.Add(warnStakingDemoted, severityMedium, "staking", "demoted")
+	warnings.Add(warnStakingDemoted, severityMedium, "staking", "demoted")
+	metrics.ObserveWarnings(warnings.Warnings())
+
+	stages.Start("output")
+	stages.Done()
+	metrics.outputBytes.Set(2048)
+	metrics.ObserveResult(nil)
+	metrics.ObserveResult(errors.New("failed"))
+
+	out = scrape()
+	require.Contains(t, out, `gaia_migrate_stage_duration_seconds_count{stage="modules"} 1`)
+	require.Contains(t, out, `gaia_migrate_stage_duration_seconds_count{stage="output"} 1`)
+	require.Contains(t, out, "gaia_migrate_output_bytes 2048")
+	require.Contains(t, out, `gaia_migrate_warnings_total{code="W-MINT-001"} 1`)
+	require.Contains(t, out, `gaia_migrate_warnings_total{code="W-STAKING-002"} 2`)
+	require.Contains(t, out, `gaia_migrate_runs_total{result="success"} 1`)
+	require.Contains(t, out, `gaia_migrate_runs_total{result="failure"} 1`)
+}
+
+func TestMigrateMetricsListen(t *testing.T) {
+	cmd := MigrateGenesisCmd()
+	cmd.SetArgs([]string{"missing.json", "--metrics-listen", "256.0.0.1:0"})
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to listen on --metrics-listen")
+}
@@ -4,207 +4,2402 @@ package gaia
 //This file also implements setting an initial height from an upgrade.
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/version"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
 	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
 	captypes "github.com/cosmos/cosmos-sdk/x/capability/types"
+	crisis "github.com/cosmos/cosmos-sdk/x/crisis/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
 	evtypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
 	"github.com/cosmos/cosmos-sdk/x/genutil/client/cli"
 	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
 	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
 	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
 	"github.com/cosmos/cosmos-sdk/x/ibc/core/exported"
 	ibccoretypes "github.com/cosmos/cosmos-sdk/x/ibc/core/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/params"
+	"github.com/cosmos/gaia/v5/pkg/genesis"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
 	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 const (
-	flagGenesisTime     = "genesis-time"
-	flagInitialHeight   = "initial-height"
-	flagReplacementKeys = "replacement-cons-keys"
-	flagNoProp29        = "no-prop-29"
+	flagGenesisTime       = "genesis-time"
+	flagInitialHeight     = "initial-height"
+	flagReplacementKeys   = "replacement-cons-keys"
+	flagNoProp29          = "no-prop-29"
+	flagSyncTmValidators  = "sync-tm-validators"
+	flagUpgradeProposal   = "upgrade-proposal"
+	flagProp29Data        = "prop-29-data"
+	flagProp29Report      = "prop-29-report"
+	flagSmokeTest         = "smoke-test"
+	flagCrisisConstantFee = "crisis-constant-fee"
+	flagExpectedBlockTime = "ibc-expected-block-time"
+	flagMintBlocksPerYear = "mint-blocks-per-year"
+	flagMintInflation     = "mint-inflation"
+	flagPruneBelow        = "prune-accounts-below"
+	flagKeepTopAccounts   = "keep-top-accounts"
+	flagPruneSink         = "prune-sink"
+	flagWarningsAsErrors  = "warnings-as-errors"
+	flagWarningsReport    = "warnings-report"
+	flagMaxWarnExamples   = "max-warning-examples"
+	flagProgress          = "progress"
+	flagBlockedAddresses  = "blocked-addresses"
+	flagBlockedDest       = "blocked-destination"
+	flagBlockedAccount    = "blocked-account-action"
+	flagBlockedReport     = "blocked-addresses-report"
+	flagInputFormat       = "input-format"
+	flagOutputFormat      = "output-format"
+	flagVerbose           = "verbose"
+	flagMetricsListen     = "metrics-listen"
+	flagMetricsCancel     = "metrics-allow-cancel"
+	flagShiftAllTimes     = "shift-all-times"
+	flagLegacySource      = "legacy-source"
+	flagAirdrop           = "airdrop"
+	flagAirdropReport     = "airdrop-report"
+	flagNoNormalizeOrder  = "no-normalize-order"
+	flagEmbedMigration    = "embed-migration-info"
+	flagPreserveAppHash   = "preserve-app-hash"
+	flagIBCClientReport   = "ibc-client-report"
+	flagCacheDir          = "cache-dir"
+	flagRepairCaps        = "repair-capabilities"
+	flagFixProofSpecs     = "fix-client-proof-specs"
+	flagRepairChannelSeqs = "repair-channel-sequences"
+	flagIBCChannelReport  = "ibc-channel-report"
+	flagTimeout           = "timeout"
+	flagSweepModuleDust   = "sweep-module-dust"
+	flagStrictModuleAccts = "strict-module-accounts"
+	flagModuleAcctsReport = "module-accounts-report"
+	flagDropEmptyRecords  = "drop-empty-records"
+	flagDropStaleVotes    = "drop-stale-votes"
+	flagGovTallyReport    = "gov-tally-report"
+	flagBundleDir         = "bundle-dir"
+	flagBaseline          = "baseline"
+	flagBaselineReport    = "baseline-report"
+	flagReviewOutput      = "review-output"
+	flagCompleteMatured   = "complete-matured-entries"
+	flagSourceSHA256      = "source-sha256"
+	flagStripDupConsKeys  = "strip-duplicate-consensus-keys"
+	flagConsKeysReport    = "duplicate-consensus-keys-report"
+	flagDownloadDir       = "download-cache-dir"
+	flagDownloadTimeout   = "download-timeout"
+	flagDownloadRetries   = "download-retries"
+	flagProtectedAddrs    = "protected-addresses"
+	flagMaxInputSize      = "max-input-size"
+	flagFailOnProtected   = "fail-on-protected-conflict"
 )
 
-// MigrateGenesisCmd returns a command to execute genesis state migration.
-func MigrateGenesisCmd() *cobra.Command {
+// Migrate migrates the genesis source, a file, - for the input of clientCtx
+// or an http(s) URL, with opts as the migrate command does, decoding it with
+// the codecs of clientCtx. The migrated genesis is written to the output of
+// clientCtx unless opts write it to a file or a bundle, the logs and the
+// status line to errOut. Canceling ctx stops the migration like SIGINT does.
+func Migrate(ctx context.Context, clientCtx client.Context, source string, opts *MigrateOptions, errOut io.Writer) error {
 	cmd := &cobra.Command{
-		Use:   "migrate [genesis-file]",
-		Short: "Migrate genesis to a specified target version",
-		Long: fmt.Sprintf(`Migrate the source genesis into the target version and print to STDOUT.
+		Use:  "migrate",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceErrors, cmd.SilenceUsage = true, true
+			return runMigration(cmd, nil, &migrateRun{start: time.Now()}, source, opts)
+		},
+	}
+	cmd.SetArgs([]string{})
+	if clientCtx.Input != nil {
+		cmd.SetIn(clientCtx.Input)
+	}
+	if clientCtx.Output != nil {
+		cmd.SetOut(clientCtx.Output)
+	}
+	cmd.SetErr(errOut)
+
+	return cmd.ExecuteContext(context.WithValue(ctx, client.ClientContextKey, &clientCtx))
+}
+
+// runMigration migrates source with opts, the run of cmd, serving the
+// metrics of --metrics-listen, and prints the status line of run. The codecs
+// of encodingConfig replace those of the client context of cmd when it is
+// set.
+func runMigration(cmd *cobra.Command, encodingConfig *params.EncodingConfig, run *migrateRun, source string, opts *MigrateOptions) error {
+	// metrics is only set when --metrics-listen is given
+	var metrics *migrationMetrics
+
+	migrate := func() (err error) {
+		// a panic fails the migration with the stage and module it was at
+		position := &migrationPosition{}
+		defer dumpFailingModule(position, &err)
+		defer recoverMigrationPanic(position, &err)
+		run.position = position
+
+		clientCtx := client.GetClientContextFromCmd(cmd)
+		if encodingConfig != nil {
+			clientCtx = clientCtx.
+				WithJSONMarshaler(encodingConfig.Marshaler).
+				WithInterfaceRegistry(encodingConfig.InterfaceRegistry).
+				WithTxConfig(encodingConfig.TxConfig).
+				WithLegacyAmino(encodingConfig.Amino)
+		}
+
+		if err := validateMigrateClientContext(clientCtx); err != nil {
+			return err
+		}
+
+		if opts.RequireVersion != "" {
+			if err := checkRequiredVersion(opts.RequireVersion, version.Version); err != nil {
+				return err
+			}
+		}
+
+		strict := opts.Strict
+		if strict {
+			if err := strictModeOptionsOf(opts, source).Validate(); err != nil {
+				return err
+			}
+		}
+
+		files, err := newOutputFiles(opts.FileMode, opts.CreateDirs)
+		if err != nil {
+			return err
+		}
+
+		// the key is looked up before the migration runs
+		signer, err := newManifestSigner(cmd, clientCtx, opts)
+		if err != nil {
+			return err
+		}
+
+		// a launch run dumps nothing it is not asked to
+		position.dump, position.files = !strict, files
+		if opts.IsSet(flagDebugDumpDir) {
+			position.dumpDir = opts.DebugDumpDir
+			position.dump = position.dumpDir != ""
+		}
+
+		// the data tables must be the reviewed ones the manifest records
+		if err := verifyMigrationData(migrationDataFiles); err != nil {
+			return err
+		}
+
+		timeout := opts.Timeout
+		ctx, cancel := migrationContext(cmd, timeout)
+		defer cancel()
+
+		warnings := &warningCollector{}
+		if metrics != nil {
+			metrics.status.Running(cancel)
+			warnings.observe = metrics.status.ObserveWarning
+		}
+		run.warnings = warnings
+
+		var legacy *legacyEra
+		if legacySource := opts.LegacySource; legacySource != "" {
+			era, ok := legacyEras[legacySource]
+			if !ok {
+				return fmt.Errorf("unknown --%s %s, expected one of %s", flagLegacySource, legacySource, strings.Join(legacyEraNames(), ", "))
+			}
+			legacy = &era
+		}
+
+		stateChanges, err := stateChangeOptionsOf(opts)
+		if err != nil {
+			return err
+		}
+
+		if err := confirmStateChanges(cmd, stateChanges, opts.SkipConfirmation); err != nil {
+			return err
+		}
+
+		// bundle is only set with --bundle-dir, removed unless committed
+		var bundle *migrationBundle
+		if bundleDir := opts.BundleDir; bundleDir != "" {
+			if opts.Output != "" {
+				return fmt.Errorf("--%s writes the genesis to the bundle, it cannot be combined with --%s", flagBundleDir, flagOutputFile)
+			}
+
+			bundle, err = newMigrationBundle(bundleDir, files)
+			if err != nil {
+				return errors.Wrap(err, "failed to create bundle")
+			}
+			defer bundle.Remove()
+		}
+
+		baselinePath := opts.Baseline
+		var baseline []byte
+		if baselinePath != "" {
+			if baselinePath == stdinGenesis && source == stdinGenesis {
+				return fmt.Errorf("only one genesis file can be read from STDIN")
+			}
+
+			baseline, err = readBaselineGenesis(baselinePath, cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrapf(err, "failed to read baseline genesis %s", baselinePath)
+			}
+		}
+
+		stageNames := migrateStageNames(opts.enabled)
+
+		var observers []stageObserver
+		if opts.Verbose {
+			observers = append(observers, verboseObserver{cmd.ErrOrStderr()})
+		}
+		if opts.Progress {
+			observers = append(observers, newProgressObserver(cmd.ErrOrStderr()))
+		}
+		if metrics != nil {
+			observers = append(observers, metrics, metrics.status)
+		}
+
+		stages := newStageTracker(stageNames, observers...)
+		defer stages.Done()
+
+		// the migration stops between stages and modules once canceled
+		startStage := func(name string) error {
+			if err := migrationCanceled(ctx, timeout); err != nil {
+				return err
+			}
+
+			stages.Start(name)
+			position.stage, position.module = name, ""
+			position.version, position.input, position.rerun = "", nil, nil
+			migrateStageStarted(ctx, name)
+			return nil
+		}
+
+		firstMigration := "v0.38"
+		importGenesis := source
+
+		if err := startStage("read"); err != nil {
+			return err
+		}
+
+		// a URL source is read from its download in the cache
+		genesisPath := importGenesis
+		var download *genesisDownload
+		if isGenesisURL(importGenesis) {
+			dir := opts.DownloadDir
+			if dir == "" {
+				dir = defaultDownloadDir()
+			}
+			downloadTimeout := opts.DownloadTimeout
+			retries := opts.DownloadRetries
+
+			download = newGenesisDownload(importGenesis, dir, downloadTimeout, retries, cmd.ErrOrStderr())
+			if genesisPath, err = download.Fetch(ctx); err != nil {
+				return err
+			}
+		}
+
+		if sourceSHA256 := opts.SourceSHA256; sourceSHA256 != "" {
+			if genesisPath == stdinGenesis {
+				return fmt.Errorf("--%s cannot verify a genesis read from STDIN", flagSourceSHA256)
+			}
+
+			if err := verifySourceSHA256(genesisPath, sourceSHA256); err != nil {
+				if download != nil {
+					// a corrupted download is fetched again by the next run
+					download.Discard()
+				}
+				return errors.Wrapf(err, "failed to verify %s", importGenesis)
+			}
+		}
+
+		input, err := openGenesisInput(genesisPath, cmd.InOrStdin())
+		if err != nil {
+			return errors.Wrap(err, "failed to read provided genesis file")
+		}
+		defer input.Close()
+
+		var genesisReader io.Reader = input
+
+		var inputCounter *countingReader
+		if metrics != nil {
+			inputCounter = &countingReader{Reader: input}
+			genesisReader = inputCounter
+		}
+
+		// the source genesis is hashed as it is read for the migration info
+		// and the manifest
+		embedInfo := opts.EmbedMigration
+		manifestPath := opts.Manifest
+		var sourceDigest *digestWriter
+		var sourceReader io.Reader
+		if embedInfo || manifestPath != "" || bundle != nil {
+			sourceDigest = newDigestWriter()
+			sourceReader = io.TeeReader(genesisReader, sourceDigest)
+			genesisReader = sourceReader
+		}
+
+		inputFormat := opts.InputFormat
+		maxInputSize := opts.MaxInputSize
+		guard := &inputGuard{maxSize: maxInputSize}
+		if genesisReader, err = guard.Reader(genesisReader, inputFormat); err != nil {
+			return errors.Wrapf(err, "invalid genesis input %s", importGenesis)
+		}
+
+		switch inputFormat {
+		case formatJSON:
+		case formatYAML:
+			yamlBlob, err := ioutil.ReadAll(genesisReader)
+			if err != nil {
+				return errors.Wrap(err, "failed to read provided genesis file")
+			}
+
+			jsonBlob, err := yamlToJSON(yamlBlob)
+			if err != nil {
+				return errors.Wrap(err, "failed to convert YAML genesis to JSON")
+			}
+
+			genesisReader = bytes.NewReader(jsonBlob)
+		default:
+			return fmt.Errorf("unknown --%s %s", flagInputFormat, inputFormat)
+		}
+
+		jsonBlob, err := migrateTendermintGenesis(io.TeeReader(genesisReader, guard.Scanner()))
+		if inputErr := guard.Err(err); inputErr != nil {
+			return errors.Wrapf(inputErr, "invalid genesis input %s", importGenesis)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to migration from 0.32 Tendermint params to 0.34 parms")
+		}
+
+		if sourceReader != nil {
+			// hash what follows the decoded JSON too, such as a trailing newline
+			if _, err := io.Copy(ioutil.Discard, sourceReader); err != nil {
+				return errors.Wrap(err, "failed to read provided genesis file")
+			}
+		}
+
+		if inputCounter != nil {
+			metrics.inputBytes.Set(float64(inputCounter.n))
+		}
+
+		if legacy != nil {
+			var changed int
+			jsonBlob, changed, err = normalizeLegacyGenesis(jsonBlob, *legacy)
+			if err != nil {
+				return errors.Wrap(err, "failed to normalize legacy genesis")
+			}
+
+			cmd.PrintErrf("normalized %d legacy genesis fields\n", changed)
+		}
+
+		genDoc, err := tmtypes.GenesisDocFromJSON(jsonBlob)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read genesis document from file %s", importGenesis)
+		}
+
+		sourceGenesisTime := genDoc.GenesisTime
+
+		var initialState types.AppMap
+		if err := json.Unmarshal(genDoc.AppState, &initialState); err != nil {
+			return errors.Wrap(err, "failed to JSON unmarshal initial genesis state")
+		}
+
+		if !opts.ForceRemigrate {
+			if err := checkNotMigrated(initialState); err != nil {
+				return err
+			}
+		} else if signs, err := migratedGenesisSigns(initialState); err != nil {
+			return err
+		} else if len(signs) > 0 {
+			cmd.PrintErrf("migrating again a source genesis migrated already: %s\n", strings.Join(signs, "; "))
+		}
+
+		// the source accounts, compared with the migrated ones at the end
+		sourceAccounts, sourceAccountsPath := sourceAccountsJSON(initialState)
+
+		if err := checkSourceValidators(genDoc, initialState); err != nil {
+			return err
+		}
+
+		// the migrated chain computes its own app hash, an exported one
+		// only fails InitChain
+		if len(genDoc.AppHash) > 0 {
+			if opts.PreserveAppHash {
+				cmd.PrintErrf("preserving the app_hash %X of the source genesis\n", genDoc.AppHash)
+			} else {
+				cmd.PrintErrf("cleared the app_hash %X of the source genesis, use --%s to keep it\n", genDoc.AppHash, flagPreserveAppHash)
+				genDoc.AppHash = nil
+			}
+		}
+
+		// steps lists the migrations and state changes applied, in order
+		var steps []string
+
+		// the stages running the SDK migration callbacks, each cached
+		// by the key of its input and the versions it migrates through
+		type migrationStage struct {
+			name     string
+			versions []string
+		}
+
+		var migrationStages []migrationStage
+		if legacy != nil {
+			migrationStages = append(migrationStages, migrationStage{"legacy", legacy.Migrations})
+		}
+		thirdMigration := "v0.40"
+		migrationStages = append(migrationStages,
+			migrationStage{firstMigration, []string{firstMigration}},
+			migrationStage{"v0.39", []string{"v0.39"}},
+			migrationStage{thirdMigration, []string{thirdMigration}},
+		)
+
+		var cache *stageCache
+		if cacheDir := opts.CacheDir; cacheDir != "" {
+			if cache, err = newStageCache(cacheDir); err != nil {
+				return err
+			}
+		}
+		resume := opts.Resume
+		if resume && cache == nil {
+			return fmt.Errorf("--%s needs the checkpoints of --%s", flagResume, flagCacheDir)
+		}
+
+		cacheKeys := make([]string, len(migrationStages))
+		migrationNames := make([]string, len(migrationStages))
+		key := stageCacheSourceKey(jsonBlob)
+		for i, stage := range migrationStages {
+			options := stage.versions
+			if stage.name == firstMigration && stateChanges.DropUnmappable {
+				options = append([]string{flagDropUnmappable}, options...)
+			}
+
+			key = stageCacheKey(stage.name, key, options...)
+			cacheKeys[i] = key
+			migrationNames[i] = stage.name
+		}
+
+		// the source, the output of every stage and the migrated genesis
+		// are hash chained for the manifest and the cache entries
+		chainStages := cache != nil || manifestPath != "" || bundle != nil
+		stageChain := []genesis.StageLink{genesis.NewStageLink(genesis.StageLink{}, genesis.StageSource, stageCacheSourceKey(jsonBlob))}
+
+		newGenState := initialState
+		cached := 0
+		for i := len(migrationStages) - 1; cache != nil && i >= 0; i-- {
+			state, ok, err := cache.Get(cacheKeys[i])
+			if err != nil {
+				cmd.PrintErrf("ignoring the cached %s state: %s\n", migrationStages[i].name, err)
+				continue
+			}
+
+			if ok {
+				newGenState = state
+				cached = i + 1
+				break
+			}
+		}
+
+		// the cached states are only reused as far as they chain from
+		// the source, the stages from the first broken link on run again
+		if cached > 0 {
+			links, err := cache.Chain(stageChain[0], migrationNames[:cached], cacheKeys[:cached])
+			if err != nil {
+				cmd.PrintErrf("ignoring the cached states from the %s stage on: %s\n", migrationNames[len(links)-1], err)
+
+				newGenState = initialState
+				if cached = len(links) - 1; cached > 0 {
+					state, _, err := cache.Get(cacheKeys[cached-1])
+					if err != nil {
+						return errors.Wrapf(err, "failed to read the cached %s state", migrationNames[cached-1])
+					}
+					newGenState = state
+				}
+			}
+			stageChain = links
+
+			if cached > 0 {
+				cmd.PrintErrf("reused the cached %s state %s\n", migrationNames[cached-1], cacheKeys[cached-1])
+			}
+		}
+
+		// the gov content mapping runs with the first SDK stage, whose
+		// cache entry keeps its report for the runs reusing it
+		writeGovContentReport := func(report govContentReport) (json.RawMessage, error) {
+			bz, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal gov content report")
+			}
+
+			if reportPath := opts.GovContentReport; reportPath != "" {
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return nil, errors.Wrap(err, "failed to write gov content report")
+				}
+			}
+
+			return bz, nil
+		}
+		logGovContents := func(report govContentReport) {
+			if len(report.Mapped) > 0 {
+				cmd.PrintErrf("gov: mapped the content of %d proposals of legacy types\n", len(report.Mapped))
+			}
+			if len(report.Unmappable) > 0 {
+				cmd.PrintErrf("gov: dropped %d proposals whose content cannot be migrated\n", len(report.Unmappable))
+				steps = append(steps, flagDropUnmappable)
+			}
+		}
+
+		for i, stage := range migrationStages {
+			if i < cached {
+				if stage.name == firstMigration {
+					bz, err := cache.Report(cacheKeys[i])
+					if err != nil {
+						return errors.Wrapf(err, "failed to read the cached %s report", stage.name)
+					}
+					if bz == nil {
+						return fmt.Errorf("the cached %s state has no gov content report, clear --%s", stage.name, flagCacheDir)
+					}
+
+					var report govContentReport
+					if err := json.Unmarshal(bz, &report); err != nil {
+						return errors.Wrapf(err, "invalid cached %s report", stage.name)
+					}
+					if _, err := writeGovContentReport(report); err != nil {
+						return err
+					}
+					logGovContents(report)
+				}
+
+				steps = append(steps, stage.versions...)
+				continue
+			}
+
+			if err := startStage(stage.name); err != nil {
+				return err
+			}
+
+			// the SDK migrations from here on decode the proposal
+			// contents of the cosmoshub-3 gov genesis
+			var reportBz json.RawMessage
+			if stage.name == firstMigration {
+				govGenesis, report, err := migrateGovContents(newGenState[gov.ModuleName], stateChanges.DropUnmappable)
+				bz, reportErr := writeGovContentReport(report)
+				if reportErr != nil {
+					return reportErr
+				}
+				if err != nil {
+					return errors.Wrap(err, "failed to migrate gov proposal contents")
+				}
+
+				newGenState[gov.ModuleName] = govGenesis
+				logGovContents(report)
+				reportBz = bz
+			}
+
+			for _, version := range stage.versions {
+				if err := migrationCanceled(ctx, timeout); err != nil {
+					return err
+				}
+
+				if version != stage.name {
+					position.stage = fmt.Sprintf("%s (%s)", stage.name, version)
+				}
+
+				migrationFunc := cli.GetMigrationCallback(version)
+				if migrationFunc == nil {
+					return fmt.Errorf("unknown migration function for version: %s", version)
+				}
+
+				// the migration writes the migrated modules to its input
+				position.version = version
+				position.input = make(types.AppMap, len(newGenState))
+				for module, bz := range newGenState {
+					position.input[module] = bz
+				}
+				position.rerun = func(state types.AppMap) types.AppMap {
+					return migrationFunc(state, clientCtx)
+				}
+
+				// TODO: handler error from migrationFunc call
+				newGenState = migrationFunc(newGenState, clientCtx)
+				steps = append(steps, version)
+			}
+
+			if chainStages {
+				link, stateBz, err := stageLink(stageChain[len(stageChain)-1], stage.name, newGenState)
+				if err != nil {
+					return errors.Wrapf(err, "failed to hash the %s state", stage.name)
+				}
+				stageChain = append(stageChain, link)
+
+				if cache != nil {
+					if err := cache.Put(cacheKeys[i], link, stateBz, reportBz); err != nil {
+						return errors.Wrapf(err, "failed to cache the %s state", stage.name)
+					}
+				}
+			}
+		}
+
+		if err := startStage("modules"); err != nil {
+			return err
+		}
+		position.input = newGenState
+
+		normalizeKeys := opts.NormalizePubKeys
+		scanOpts := accountScanOptions{
+			CheckDuplicates:  true,
+			NormalizePubKeys: normalizeKeys || stateChanges.ClearPubKeys,
+			ClearPubKeys:     stateChanges.ClearPubKeys,
+			Resume:           resume,
+		}
+		if cache != nil {
+			interval := opts.AccountsCheckpoint
+			scanOpts.Checkpoints, err = newAccountCheckpoints(cache, interval, newGenState[auth.ModuleName],
+				strconv.FormatBool(scanOpts.NormalizePubKeys), strconv.FormatBool(scanOpts.ClearPubKeys))
+			if err != nil {
+				return err
+			}
+		}
+
+		position.module = auth.ModuleName
+		scan, err := scanAccounts(clientCtx.JSONMarshaler, newGenState, scanOpts)
+		if err != nil {
+			return errors.Wrap(err, "failed to scan the auth accounts")
+		}
+		if scan.Resumed > 0 {
+			cmd.PrintErrf("accounts: resumed the scan at account %d of %d from the checkpoints\n", scan.Resumed, scan.Accounts)
+		}
+		position.module = ""
+
+		if scanOpts.NormalizePubKeys {
+			position.module = auth.ModuleName
+			pubKeys := scan.PubKeys
+			var reencoded, cleared int
+			for _, entry := range pubKeys {
+				switch {
+				case !entry.Invalid():
+					reencoded++
+				case entry.Cleared:
+					cleared++
+				default:
+					warnings.Add(warnAuthInvalidPubKey, severityHigh, auth.ModuleName, "the pubkey of account %s is invalid: %s, use --%s to set it to null",
+						entry.Address, entry.Error, flagClearInvalidPubKeys)
+				}
+			}
+
+			if reencoded > 0 {
+				cmd.PrintErrf("pubkeys: re-encoded %d legacy account pubkeys as proto Any\n", reencoded)
+			}
+			if cleared > 0 {
+				cmd.PrintErrf("pubkeys: cleared %d invalid account pubkeys\n", cleared)
+				steps = append(steps, flagClearInvalidPubKeys)
+			}
+
+			if reportPath := opts.PubKeyReport; reportPath != "" {
+				bz, err := json.MarshalIndent(pubKeys, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal pubkey report")
+				}
+
+				if err := files.WriteFile(reportPath, bz, keyReportFileMode); err != nil {
+					return errors.Wrap(err, "failed to write pubkey report")
+				}
+			}
+			position.module = ""
+		}
+
+		// module progress is accounted in bytes of the migrated module
+		// genesis states as each of them is done
+		moduleSizes := make(map[string]int64, len(newGenState))
+		var moduleBytesTotal, moduleBytesDone int64
+		for module, bz := range newGenState {
+			moduleSizes[module] = int64(len(bz))
+			moduleBytesTotal += int64(len(bz))
+		}
+
+		moduleDone := func(modules ...string) error {
+			position.module = ""
+			for _, module := range modules {
+				moduleBytesDone += moduleSizes[module]
+				delete(moduleSizes, module)
+			}
+
+			stages.Progress(moduleBytesDone, moduleBytesTotal)
+			return migrationCanceled(ctx, timeout)
+		}
+
+		position.module = bank.ModuleName
+		var bankGenesis bank.GenesisState
+
+		clientCtx.JSONMarshaler.MustUnmarshalJSON(newGenState[bank.ModuleName], &bankGenesis)
+
+		bankGenesis.DenomMetadata = []bank.Metadata{
+			{
+				Description: "The native staking token of the Cosmos Hub.",
+				DenomUnits: []*bank.DenomUnit{
+					{Denom: "uatom", Exponent: uint32(0), Aliases: []string{"microatom"}},
+					{Denom: "matom", Exponent: uint32(3), Aliases: []string{"milliatom"}},
+					{Denom: "atom", Exponent: uint32(6), Aliases: []string{}},
+				},
+				Base:    "uatom",
+				Display: "atom",
+			},
+		}
+
+		if stateChanges.Prop29 != nil {
+			report, err := applyRecoveries(&bankGenesis, stateChanges.Prop29)
+			if err != nil {
+				return errors.Wrap(err, "failed to apply prop29 recovery")
+			}
+
+			steps = append(steps, "prop29")
+
+			for _, coin := range report.Totals {
+				cmd.PrintErrf("prop29: recovered %s%s across %d entries\n", coin.Amount, coin.Denom, len(report.Entries))
+			}
+
+			claims := newProp29Claims(stateChanges.Prop29Claims, stateChanges.Prop29)
+			if len(claims.Entries) > 0 {
+				if claims.ModuleAccount != "" {
+					if err := addClaimsModuleAccount(clientCtx.JSONMarshaler, newGenState); err != nil {
+						return errors.Wrap(err, "failed to add the prop29 claims module account")
+					}
+				}
+
+				steps = append(steps, flagProp29Claims)
+				cmd.PrintErrf("prop29: diverted %s of %d entries of invalid recipients to %s\n", claims.Totals, len(claims.Entries), claims.ClaimsAccount)
+			}
+
+			if reportPath := opts.Prop29ClaimsReport; reportPath != "" {
+				bz, err := json.MarshalIndent(claims, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal prop29 claims")
+				}
+
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write prop29 claims")
+				}
+			}
+
+			if bundle != nil && len(claims.Entries) > 0 {
+				if err := bundle.WriteJSON(bundleClaimsFile, claims); err != nil {
+					return errors.Wrap(err, "failed to write prop29 claims")
+				}
+			}
+
+			if reportPath := opts.Prop29Report; reportPath != "" {
+				bz, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal prop29 report")
+				}
+
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write prop29 report")
+				}
+			}
+
+			if bundle != nil {
+				if err := bundle.WriteJSON(bundleProp29File, report); err != nil {
+					return errors.Wrap(err, "failed to write prop29 report")
+				}
+			}
+		}
+
+		newGenState[bank.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(&bankGenesis)
+		if err := moduleDone(bank.ModuleName); err != nil {
+			return err
+		}
+
+		position.module = crisis.ModuleName
+		var crisisGenesis crisis.GenesisState
+
+		clientCtx.JSONMarshaler.MustUnmarshalJSON(newGenState[crisis.ModuleName], &crisisGenesis)
+
+		var constantFee *sdk.Coin
+		if fee := opts.CrisisConstantFee; fee != "" {
+			coin, err := sdk.ParseCoinNormalized(fee)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse --%s", flagCrisisConstantFee)
+			}
+
+			constantFee = &coin
+			steps = append(steps, flagCrisisConstantFee)
+		}
+
+		if err := checkCrisisConstantFee(&crisisGenesis, bankGenesis.Supply, constantFee, warnings); err != nil {
+			return err
+		}
+
+		cmd.PrintErrf("crisis: constant fee is %s, invariants are only checked on MsgVerifyInvariant unless nodes start with --%s\n",
+			crisisGenesis.ConstantFee, server.FlagInvCheckPeriod)
+
+		newGenState[crisis.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(&crisisGenesis)
+		if err := moduleDone(crisis.ModuleName); err != nil {
+			return err
+		}
+
+		position.module = mint.ModuleName
+		var mintGenesis mint.GenesisState
+
+		clientCtx.JSONMarshaler.MustUnmarshalJSON(newGenState[mint.ModuleName], &mintGenesis)
+
+		var overrides mintOverrides
+		if opts.IsSet(flagMintBlocksPerYear) {
+			blocks := opts.MintBlocksPerYear
+			overrides.BlocksPerYear = &blocks
+		}
+
+		if inflation := opts.MintInflation; inflation != "" {
+			dec, err := sdk.NewDecFromStr(inflation)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse --%s", flagMintInflation)
+			}
+
+			overrides.Inflation = &dec
+		}
+
+		if err := applyMintOverrides(&mintGenesis, overrides); err != nil {
+			return err
+		}
+		if overrides.BlocksPerYear != nil || overrides.Inflation != nil {
+			steps = append(steps, "mint-overrides")
+		}
+
+		expectedBlockTime := opts.ExpectedBlockTime
+		checkMintParams(mintGenesis, expectedBlockTime, warnings)
+
+		newGenState[mint.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(&mintGenesis)
+		if err := moduleDone(mint.ModuleName); err != nil {
+			return err
+		}
+
+		position.module = staking.ModuleName
+		var stakingGenesis staking.GenesisState
+
+		clientCtx.JSONMarshaler.MustUnmarshalJSON(newGenState[staking.ModuleName], &stakingGenesis)
+
+		ibcTransferGenesis := ibcxfertypes.DefaultGenesisState()
+		ibcCoreGenesis := ibccoretypes.DefaultGenesisState()
+		capGenesis := captypes.DefaultGenesis()
+
+		ibcTransferGenesis.Params.ReceiveEnabled = false
+		ibcTransferGenesis.Params.SendEnabled = false
+
+		ibcCoreGenesis.ClientGenesis.Params.AllowedClients = []string{exported.Tendermint}
+		stakingGenesis.Params.HistoricalEntries = 10000
+
+		newGenState[ibcxfertypes.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(ibcTransferGenesis)
+		newGenState[host.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(ibcCoreGenesis)
+		newGenState[captypes.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(capGenesis)
+		// pending evidence the migrations carried is checked against the
+		// validators once their keys are final
+		if newGenState[evtypes.ModuleName] == nil {
+			newGenState[evtypes.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(evtypes.DefaultGenesisState())
+		}
+		newGenState[staking.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(&stakingGenesis)
+
+		if opts.RepairCaps {
+			if err := repairCapabilities(clientCtx.JSONMarshaler, newGenState); err != nil {
+				return errors.Wrapf(err, "failed to repair %s genesis", captypes.ModuleName)
+			}
+			steps = append(steps, flagRepairCaps)
+		}
+
+		capProblems, err := checkCapabilities(clientCtx.JSONMarshaler, newGenState)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check %s genesis", captypes.ModuleName)
+		}
+		if len(capProblems) > 0 {
+			return fmt.Errorf("%s genesis does not match the %s genesis, InitChain would fail, use --%s to regenerate it:\n%s",
+				captypes.ModuleName, host.ModuleName, flagRepairCaps, strings.Join(capProblems, "\n"))
+		}
+		if opts.FixProofSpecs {
+			fixed, err := fixClientProofSpecs(clientCtx.JSONMarshaler, newGenState)
+			if err != nil {
+				return errors.Wrap(err, "failed to fix IBC client proof specs")
+			}
+			steps = append(steps, flagFixProofSpecs)
+
+			if len(fixed) > 0 {
+				cmd.PrintErrf("%s: set the standard proof specs and upgrade path of %s\n", host.ModuleName, strings.Join(fixed, ", "))
+			}
+		}
+
+		if stateChanges.RemapChainIDs != nil {
+			remapped, unmapped, err := remapCounterpartyChainIDs(clientCtx.JSONMarshaler, newGenState, stateChanges.RemapChainIDs)
+			if err != nil {
+				return errors.Wrap(err, "failed to remap IBC counterparty chain IDs")
+			}
+			steps = append(steps, flagRemapChainIDs)
+
+			for _, client := range remapped {
+				if client.RevisionChanged {
+					warnings.Add(warnIBCClientRevision, severityMedium, host.ModuleName, "client %s remapped from %s to %s keeps heights of another revision than the one of %s",
+						client.ClientID, client.From, client.To, client.To)
+				}
+			}
+			for _, client := range unmapped {
+				warnings.Add(warnIBCClientUnmapped, severityMedium, host.ModuleName, "client %s of %s is not in the --%s mapping",
+					client.ClientID, client.ChainID, flagRemapChainIDs)
+			}
+
+			cmd.PrintErrf("%s: remapped the counterparty chain IDs of %d clients\n", host.ModuleName, len(remapped))
+		}
+
+		nonStandardClients, err := checkClientProofSpecs(clientCtx.JSONMarshaler, newGenState)
+		if err != nil {
+			return errors.Wrap(err, "failed to check IBC client proof specs")
+		}
+		for _, client := range nonStandardClients {
+			warnings.Add(warnIBCClientProofSpecs, severityHigh, host.ModuleName, "client %s does not carry the standard proof specs and upgrade path, %s, use --%s to normalize them",
+				client.ClientID, strings.Join(client.Problems, ", "), flagFixProofSpecs)
+		}
+
+		repairChannelSeqs := opts.RepairChannelSeqs
+		channelChecks, err := checkChannelSequences(clientCtx.JSONMarshaler, newGenState, repairChannelSeqs)
+		if err != nil {
+			return errors.Wrap(err, "failed to check IBC channel sequences")
+		}
+		if repairChannelSeqs {
+			steps = append(steps, flagRepairChannelSeqs)
+		}
+		for _, channel := range channelChecks {
+			for _, repair := range channel.Repairs {
+				cmd.PrintErrf("%s: bumped %s of %s/%s from %d to %d\n", host.ModuleName, repair.Counter, channel.PortID, channel.ChannelID, repair.From, repair.To)
+			}
+			if len(channel.SequenceProblems) > 0 {
+				warnings.Add(warnIBCChannelSequences, severityHigh, host.ModuleName, "channel %s/%s: %s, use --%s to bump them",
+					channel.PortID, channel.ChannelID, strings.Join(channel.SequenceProblems, "; "), flagRepairChannelSeqs)
+			}
+			if len(channel.Problems) > 0 {
+				warnings.Add(warnIBCChannelState, severityHigh, host.ModuleName, "channel %s/%s: %s",
+					channel.PortID, channel.ChannelID, strings.Join(channel.Problems, "; "))
+			}
+		}
+
+		if reportPath := opts.IBCChannelReport; reportPath != "" {
+			bz, err := json.MarshalIndent(ibcChannelReport{Channels: channelChecks}, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal IBC channel report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write IBC channel report")
+			}
+		}
+
+		if err := moduleDone(ibcxfertypes.ModuleName, host.ModuleName, captypes.ModuleName, evtypes.ModuleName, staking.ModuleName); err != nil {
+			return err
+		}
+
+		tallyBefore, err := projectTallies(clientCtx.JSONMarshaler, newGenState)
+		if err != nil {
+			return errors.Wrap(err, "failed to project gov tallies")
+		}
+
+		// the rewards are paid out first, so the options moving balances
+		// move them with the rest
+		if stateChanges.WithdrawRewards {
+			report, err := withdrawAllRewards(clientCtx.JSONMarshaler, newGenState)
+			if err != nil {
+				return errors.Wrapf(err, "failed to apply --%s", flagWithdrawAllRewards)
+			}
+
+			cmd.PrintErrf("withdrew %s of rewards and %s of commission to %d addresses, %s of dust to the community pool\n",
+				report.Rewards, report.Commission, report.Recipients, report.Dust)
+			steps = append(steps, flagWithdrawAllRewards)
+
+			if reportPath := opts.RewardsReport; reportPath != "" {
+				bz, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal rewards withdrawal report")
+				}
+
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write rewards withdrawal report")
+				}
+			}
+		}
+
+		var funded bool
+		if stateChanges.Funding != nil {
+			if funded, err = fundCommunityPool(clientCtx.JSONMarshaler, newGenState, *stateChanges.Funding, stateChanges.Protected); err != nil {
+				return errors.Wrapf(err, "failed to apply --%s", flagFundCommunityPool)
+			}
+
+			if funded {
+				cmd.PrintErrf("%s: funded the community pool with %s from %s\n", distribution.ModuleName, stateChanges.Funding.Amount, stateChanges.Funding.From)
+				steps = append(steps, flagFundCommunityPool)
+			}
+		}
+
+		if stateChanges.BlockedSource != "" {
+			blocklist := stateChanges.Blocklist
+
+			reports, err := applyBlocklist(clientCtx.JSONMarshaler, newGenState, stateChanges.Blocked, blocklist, warnings)
+			if err != nil {
+				return errors.Wrap(err, "failed to apply blocked addresses")
+			}
+
+			cmd.PrintErrf("moved the funds of %d blocked addresses to %s\n", len(reports), blocklist.Destination)
+			steps = append(steps, flagBlockedAddresses)
+
+			if reportPath := opts.BlockedReport; reportPath != "" {
+				bz, err := json.MarshalIndent(reports, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal blocked addresses report")
+				}
+
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write blocked addresses report")
+				}
+			}
+		}
+
+		if stateChanges.Prune != nil {
+			report, err := pruneAccounts(clientCtx.JSONMarshaler, newGenState, *stateChanges.Prune)
+			if err != nil {
+				return errors.Wrap(err, "failed to prune accounts")
+			}
+
+			steps = append(steps, "prune-accounts")
+
+			cmd.PrintErrf("pruned %d accounts holding %s, transferred %s delegated and %s unbonding tokens, %s deposits and dropped %d votes\n",
+				report.Accounts, report.Balances, report.Stake, report.Unbonding, report.Deposits, report.Votes)
+		}
+
+		if stateChanges.SweepInactive != nil {
+			report, err := sweepInactiveAccounts(clientCtx.JSONMarshaler, newGenState, *stateChanges.SweepInactive)
+			if err != nil {
+				return errors.Wrap(err, "failed to sweep inactive accounts")
+			}
+
+			cmd.PrintErrf("swept %d inactive accounts holding %s to %s\n", len(report.Accounts), report.Total, stateChanges.SweepInactive.Destination)
+			steps = append(steps, flagSweepInactiveTo)
+
+			if reportPath := opts.SweptReport; reportPath != "" {
+				var buf bytes.Buffer
+				if err := writeSweptAccountsCSV(&buf, report); err != nil {
+					return errors.Wrap(err, "failed to write swept accounts report")
+				}
+
+				if err := files.WriteFile(reportPath, buf.Bytes(), outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write swept accounts report")
+				}
+			}
+		}
+
+		if opts.DropEmptyRecords {
+			report := dropEmptyRecords(clientCtx.JSONMarshaler, newGenState)
+			steps = append(steps, flagDropEmptyRecords)
+
+			cmd.PrintErrf("dropped %d empty balances, %d delegations without shares, %d empty unbonding entries and %d empty redelegation entries\n",
+				report.Balances, report.Delegations, report.UnbondingEntries, report.RedelegationEntries)
+		}
+
+		dust := newRoundingDust()
+		if stateChanges.Airdrop != nil {
+			report, err := applyAirdrop(clientCtx.JSONMarshaler, newGenState, *stateChanges.Airdrop, stateChanges.Protected, dust)
+			if err != nil {
+				return errors.Wrap(err, "failed to apply airdrop")
+			}
+
+			cmd.PrintErrf("airdrop: minted %s to %d accounts\n", report.Total, len(report.Grants))
+			steps = append(steps, flagAirdrop)
+
+			if reportPath := opts.AirdropReport; reportPath != "" {
+				var buf bytes.Buffer
+				if err := writeAirdropCSV(&buf, report); err != nil {
+					return errors.Wrap(err, "failed to write airdrop report")
+				}
+
+				if err := files.WriteFile(reportPath, buf.Bytes(), outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write airdrop report")
+				}
+			}
+		}
+
+		dustReport, err := creditRoundingDust(clientCtx.JSONMarshaler, newGenState, dust, stateChanges.RoundingDustTo)
+		if err != nil {
+			return errors.Wrap(err, "failed to credit rounding dust")
+		}
+		if !dustReport.Credited.IsZero() {
+			cmd.PrintErrf("rounding: minted %s of dust rounded down to %s\n", dustReport.Credited, stateChanges.RoundingDustTo)
+		}
+
+		if reportPath := opts.RoundingDustReport; reportPath != "" {
+			bz, err := json.MarshalIndent(dustReport, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal rounding dust report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write rounding dust report")
+			}
+		}
+
+		longStrings, err := checkLongStrings(clientCtx.JSONMarshaler, newGenState, stateChanges.TruncateStrings)
+		if err != nil {
+			return errors.Wrap(err, "failed to check string lengths")
+		}
+		if stateChanges.TruncateStrings {
+			steps = append(steps, flagTruncateLongStrings)
+		}
+
+		var truncated, exempt int
+		for _, long := range longStrings {
+			switch {
+			case long.Truncated:
+				truncated++
+			case long.Exempt:
+				exempt++
+			case long.Module == staking.ModuleName:
+				warnings.Add(warnStakingLongString, severityLow, staking.ModuleName, "the %s of validator %s is %d bytes, longer than the limit of %d, use --%s to cut it",
+					long.Field, long.Record, long.Length, long.Limit, flagTruncateLongStrings)
+			default:
+				warnings.Add(warnGovLongContent, severityLow, gov.ModuleName, "the %s of proposal %s is %d bytes, longer than the limit of %d, use --%s to cut it",
+					long.Field, long.Record, long.Length, long.Limit, flagTruncateLongStrings)
+			}
+		}
+		if truncated > 0 {
+			cmd.PrintErrf("truncated %d validator description and proposal content strings to their limit\n", truncated)
+		}
+		if exempt > 0 {
+			cmd.PrintErrf("gov: kept %d titles and descriptions of proposals past voting beyond their limit\n", exempt)
+		}
+
+		if reportPath := opts.LongStringsReport; reportPath != "" {
+			bz, err := json.MarshalIndent(append([]longString{}, longStrings...), "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal long strings report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write long strings report")
+			}
+		}
+
+		nonNFC, err := checkUnicodeNFC(clientCtx.JSONMarshaler, newGenState, stateChanges.NormalizeNFC)
+		if err != nil {
+			return errors.Wrap(err, "failed to check unicode normalization")
+		}
+		if stateChanges.NormalizeNFC {
+			steps = append(steps, flagNormalizeUnicode)
+			if len(nonNFC) > 0 {
+				cmd.PrintErrf("normalized %d validator description and proposal content strings to NFC\n", len(nonNFC))
+			}
+		} else {
+			for _, s := range nonNFC {
+				if s.Module == staking.ModuleName {
+					warnings.Add(warnStakingNonNFC, severityMedium, staking.ModuleName, "the %s of validator %s, %+q, is not in Unicode NFC, %+q, use --%s to normalize it",
+						s.Field, s.Record, s.Value, s.NFC, flagNormalizeUnicode)
+				} else {
+					warnings.Add(warnGovNonNFC, severityMedium, gov.ModuleName, "the %s of proposal %s, %+q, is not in Unicode NFC, %+q, use --%s to normalize it",
+						s.Field, s.Record, s.Value, s.NFC, flagNormalizeUnicode)
+				}
+			}
+		}
+
+		if reportPath := opts.UnicodeReport; reportPath != "" {
+			bz, err := json.MarshalIndent(append([]nonNFCString{}, nonNFC...), "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal unicode report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write unicode report")
+			}
+		}
+
+		moduleAccounts, err := auditModuleAccounts(clientCtx.JSONMarshaler, newGenState)
+		if err != nil {
+			return errors.Wrap(err, "failed to audit module accounts")
+		}
+
+		if funded {
+			for i := range moduleAccounts {
+				if moduleAccounts[i].Name == distribution.ModuleName {
+					moduleAccounts[i].Funded = stateChanges.Funding.Amount
+					moduleAccounts[i].FundedFrom = stateChanges.Funding.From
+				}
+			}
+		}
+
+		if stateChanges.SweepDustTo != "" {
+			if err := sweepModuleDust(clientCtx.JSONMarshaler, newGenState, moduleAccounts, stateChanges.SweepDustTo, stateChanges.Protected); err != nil {
+				return errors.Wrap(err, "failed to sweep module account dust")
+			}
+			steps = append(steps, flagSweepModuleDust)
+		}
+
+		stateChanges.Protected.warn(warnings)
+
+		var unbalanced []string
+		for _, acc := range moduleAccounts {
+			if acc.Balanced() {
+				continue
+			}
+
+			if surplus := acc.Surplus.Sub(acc.Swept); !surplus.IsZero() {
+				warnings.Add(warnBankModuleAccount, severityHigh, acc.Name, "module account %s holds %s more than its module genesis accounts for", acc.Name, surplus)
+			}
+			if !acc.Deficit.IsZero() {
+				warnings.Add(warnBankModuleAccount, severityHigh, acc.Name, "module account %s holds %s less than its module genesis accounts for", acc.Name, acc.Deficit)
+			}
+			unbalanced = append(unbalanced, acc.Name)
+		}
+
+		if reportPath := opts.ModuleAcctsReport; reportPath != "" {
+			bz, err := json.MarshalIndent(moduleAccounts, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal module accounts report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write module accounts report")
+			}
+		}
+
+		if (strict || opts.StrictModuleAccts) && len(unbalanced) > 0 {
+			return fmt.Errorf("the balances of the module accounts %s do not match their module genesis", strings.Join(unbalanced, ", "))
+		}
+
+		for module := range moduleSizes {
+			if err := moduleDone(module); err != nil {
+				return err
+			}
+		}
+
+		if err := startStage("genesis"); err != nil {
+			return err
+		}
+
+		genDoc.AppState, err = json.Marshal(newGenState)
+		if err != nil {
+			return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
+		}
+
+		var bases relativeBases
+		bases.HaltHeight = opts.SourceHaltHeight
+		bases.UpgradeInfo = opts.UpgradeInfo
+		bases.GenesisTime = sourceGenesisTime
+		if opts.SourceHaltTime != "" {
+			if err := bases.HaltTime.UnmarshalText([]byte(opts.SourceHaltTime)); err != nil {
+				return errors.Wrapf(err, "invalid --%s", flagSourceHaltTime)
+			}
+		}
+
+		// relative values are printed as resolved and replaced by them,
+		// so the manifest records the absolute ones
+		if opts.GenesisTime != "" {
+			t, resolved, err := resolveGenesisTime(opts.GenesisTime, bases)
+			if err != nil {
+				return err
+			}
+
+			if resolved != "" {
+				cmd.PrintErrf("==> %s\n", resolved)
+				opts.GenesisTime = t.Format(time.RFC3339Nano)
+			}
+
+			genDoc.GenesisTime = t
+		}
+
+		if opts.ChainID != "" {
+			genDoc.ChainID = opts.ChainID
+		}
+
+		genDoc.InitialHeight = 0
+		if opts.InitialHeight != "" {
+			height, resolved, err := resolveInitialHeight(opts.InitialHeight, bases)
+			if err != nil {
+				return err
+			}
+
+			if resolved != "" {
+				cmd.PrintErrf("==> %s\n", resolved)
+				opts.InitialHeight = strconv.FormatInt(height, 10)
+			}
+
+			genDoc.InitialHeight = height
+		}
+
+		if opts.UpgradeProposal != "" {
+			if opts.Node != "" {
+				rpcClient, err := client.NewClientFromNode(opts.Node)
+				if err != nil {
+					return errors.Wrap(err, "failed to create node client")
+				}
+
+				clientCtx = clientCtx.WithNodeURI(opts.Node).WithClient(rpcClient)
+			}
+
+			plan, err := loadUpgradePlan(clientCtx, opts.UpgradeProposal)
+			if err != nil {
+				return err
+			}
+
+			err = applyUpgradePlan(genDoc, plan, opts.IsSet(flagInitialHeight), opts.IsSet(flagGenesisTime))
+			if err != nil {
+				return err
+			}
+			steps = append(steps, flagUpgradeProposal)
+		}
+
+		if hasRecoveryVesting(stateChanges.Prop29) {
+			var appState types.AppMap
+			if err := json.Unmarshal(genDoc.AppState, &appState); err != nil {
+				return errors.Wrap(err, "failed to JSON unmarshal migrated genesis state")
+			}
+
+			// vesting schedules are relative to the final genesis time
+			if err := applyRecoveryVesting(clientCtx.JSONMarshaler, appState, stateChanges.Prop29, genDoc.GenesisTime); err != nil {
+				return errors.Wrap(err, "failed to apply prop29 vesting")
+			}
+
+			genDoc.AppState, err = json.Marshal(appState)
+			if err != nil {
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
+			}
+
+			steps = append(steps, "prop29-vesting")
+		}
+
+		if stateChanges.ShiftAllTimes && !genDoc.GenesisTime.Equal(sourceGenesisTime) {
+			delta := genDoc.GenesisTime.Sub(sourceGenesisTime)
+
+			var appState types.AppMap
+			if err := json.Unmarshal(genDoc.AppState, &appState); err != nil {
+				return errors.Wrap(err, "failed to JSON unmarshal migrated genesis state")
+			}
+
+			shifted, err := shiftGenesisTimes(clientCtx.JSONMarshaler, appState, delta)
+			if err != nil {
+				return errors.Wrap(err, "failed to shift genesis times")
+			}
+
+			genDoc.AppState, err = json.Marshal(appState)
+			if err != nil {
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
+			}
+
+			steps = append(steps, flagShiftAllTimes)
+
+			for _, module := range []string{staking.ModuleName, gov.ModuleName, slashing.ModuleName, evtypes.ModuleName} {
+				cmd.PrintErrf("%s: shifted %d timestamps by %s\n", module, shifted[module], delta)
+			}
+			cmd.PrintErrln(timeShiftExcluded)
+		}
+
+		// the evidence of the validators whose keys are replaced or
+		// stripped follows them to their new consensus address
+		var stateBefore types.AppMap
+		if err := json.Unmarshal(genDoc.AppState, &stateBefore); err != nil {
+			return errors.Wrap(err, "failed to JSON unmarshal migrated genesis state")
+		}
+		consAddrsBefore, err := validatorConsAddresses(clientCtx.JSONMarshaler, stateBefore)
+		if err != nil {
+			return err
+		}
+
+		if replacementPath := stateChanges.ReplacementKeys; replacementPath != "" {
+			replacementKeys, err := readReplacementKeys(clientCtx.JSONMarshaler, replacementPath, genDoc)
+			if err != nil {
+				return err
+			}
+
+			if err := checkReplacementKeyTypes(replacementKeys, genDoc.ConsensusParams); err != nil {
+				return err
+			}
 
-Example:
-$ %s migrate /path/to/genesis.json --chain-id=cosmoshub-4 --genesis-time=2019-04-22T17:00:00Z --initial-height=5000
-`, version.AppName),
-		Args: cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			clientCtx := client.GetClientContextFromCmd(cmd)
+			proposerBefore, err := firstProposer(genDoc.Validators)
+			if err != nil {
+				return errors.Wrap(err, "failed to compute first proposer")
+			}
+
+			validatorsBefore := append([]tmtypes.GenesisValidator{}, genDoc.Validators...)
+			genDoc = replaceConsensusKeys(clientCtx, replacementKeys, genDoc)
+			steps = append(steps, flagReplacementKeys)
 
-			var err error
+			replaced := replacedKeys(validatorsBefore, genDoc.Validators, replacementKeys)
+			cmd.PrintErrf("replaced the consensus keys of %d validators\n", len(replaced))
 
-			firstMigration := "v0.38"
-			importGenesis := args[0]
+			if bundle != nil {
+				if err := bundle.WriteJSON(bundleReplacementFile, replaced); err != nil {
+					return errors.Wrap(err, "failed to write replacement report")
+				}
+			}
+
+			if reportPath := opts.ReplacementReport; reportPath != "" {
+				bz, err := json.MarshalIndent(replaced, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal replacement report")
+				}
 
-			jsonBlob, err := ioutil.ReadFile(importGenesis)
+				if err := files.WriteFile(reportPath, bz, keyReportFileMode); err != nil {
+					return errors.Wrap(err, "failed to write replacement report")
+				}
+			}
 
+			proposerAfter, err := firstProposer(genDoc.Validators)
 			if err != nil {
-				return errors.Wrap(err, "failed to read provided genesis file")
+				return errors.Wrap(err, "failed to compute first proposer after key replacement")
 			}
 
-			jsonBlob, err = migrateTendermintGenesis(jsonBlob)
+			if proposerBefore != proposerAfter {
+				warnings.Add(warnStakingProposer, severityLow, staking.ModuleName, "replacement keys changed the first proposer from %s to %s",
+					genDoc.Validators[proposerBefore].Name, genDoc.Validators[proposerAfter].Name)
+			}
+		}
+
+		if err := startStage("validators"); err != nil {
+			return err
+		}
+
+		var appState types.AppMap
+		if err := json.Unmarshal(genDoc.AppState, &appState); err != nil {
+			return errors.Wrap(err, "failed to JSON unmarshal migrated genesis state")
+		}
+
+		demotions, err := capBondedValidators(clientCtx.JSONMarshaler, appState, genDoc.InitialHeight, genDoc.GenesisTime)
+		if err != nil {
+			return errors.Wrap(err, "failed to enforce max_validators")
+		}
+
+		if len(demotions) > 0 {
+			demoted := make(map[string]bool, len(demotions))
+			for _, d := range demotions {
+				demoted[d.ConsAddress.String()] = true
+				warnings.Add(warnStakingDemoted, severityMedium, staking.ModuleName, "validator %s (%s) with power %d exceeds max_validators and was demoted to unbonding",
+					d.OperatorAddress, d.Moniker, d.Power)
+			}
+
+			tmValidators := genDoc.Validators[:0]
+			for _, val := range genDoc.Validators {
+				if !demoted[sdk.ConsAddress(val.Address).String()] {
+					tmValidators = append(tmValidators, val)
+				}
+			}
+			genDoc.Validators = tmValidators
+			steps = append(steps, "max-validators")
+
+			genDoc.AppState, err = json.Marshal(appState)
+			if err != nil {
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
+			}
+		}
+
+		if stateChanges.PowerCap != nil {
+			report, err := capValidatorPower(clientCtx.JSONMarshaler, appState, *stateChanges.PowerCap, stateChanges.Protected, genDoc.InitialHeight, genDoc.GenesisTime)
+			if err != nil {
+				return errors.Wrapf(err, "failed to apply --%s", flagCapValidatorPower)
+			}
 
+			genDoc.AppState, err = json.Marshal(appState)
 			if err != nil {
-				return errors.Wrap(err, "failed to migration from 0.32 Tendermint params to 0.34 parms")
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
 			}
 
-			genDoc, err := tmtypes.GenesisDocFromJSON(jsonBlob)
+			// the capped validators keep their tendermint genesis entries
+			// at their new power, the jailed ones are removed
+			capped, err := tmValidatorsFromAppState(clientCtx, genDoc.AppState)
 			if err != nil {
-				return errors.Wrapf(err, "failed to read genesis document from file %s", importGenesis)
+				return err
+			}
+			powers := make(map[string]int64, len(capped))
+			for _, val := range capped {
+				powers[sdk.ConsAddress(val.Address).String()] = val.Power
+			}
+
+			tmValidators := genDoc.Validators[:0]
+			for _, val := range genDoc.Validators {
+				if power, ok := powers[sdk.ConsAddress(val.Address).String()]; ok {
+					val.Power = power
+					tmValidators = append(tmValidators, val)
+				}
+			}
+			genDoc.Validators = tmValidators
+
+			jailed := 0
+			for _, val := range report.Validators {
+				if val.Jailed {
+					jailed++
+					warnings.Add(warnStakingDemoted, severityMedium, staking.ModuleName, "validator %s (%s) with power %d exceeds --%s but %s, it was jailed",
+						val.OperatorAddress, val.Moniker, val.PowerBefore, flagCapValidatorPower, val.Reason)
+				}
 			}
+			if len(report.Validators) > 0 {
+				steps = append(steps, flagCapValidatorPower)
+			}
+			cmd.PrintErrf("capped %d validators at %s%% of the bonded power in %d rounds, returning %s to their delegators and jailing %d\n",
+				len(report.Validators)-jailed, stateChanges.PowerCapPercent, report.Rounds, report.Moved, jailed)
+
+			if reportPath := opts.PowerCapReport; reportPath != "" {
+				bz, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal power cap report")
+				}
 
-			var initialState types.AppMap
-			if err := json.Unmarshal(genDoc.AppState, &initialState); err != nil {
-				return errors.Wrap(err, "failed to JSON unmarshal initial genesis state")
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write power cap report")
+				}
 			}
+		}
+
+		collisions, err := findConsensusKeyCollisions(clientCtx.JSONMarshaler, appState)
+		if err != nil {
+			return errors.Wrap(err, "failed to check validator consensus keys")
+		}
 
-			migrationFunc := cli.GetMigrationCallback(firstMigration)
-			if migrationFunc == nil {
-				return fmt.Errorf("unknown migration function for version: %s", firstMigration)
+		if len(collisions) > 0 {
+			bondedCollisions := 0
+			for _, c := range collisions {
+				cmd.PrintErrln(c)
+				if len(c.Bonded()) > 1 {
+					bondedCollisions++
+				}
 			}
 
-			// TODO: handler error from migrationFunc call
-			newGenState := migrationFunc(initialState, clientCtx)
+			switch {
+			case bondedCollisions > 0:
+				return fmt.Errorf("%d consensus keys are shared by several bonded validators", bondedCollisions)
+			case !stateChanges.StripDupKeys:
+				return fmt.Errorf("%d consensus keys are shared by several validators, use --%s to strip them from the validators not bonded", len(collisions), flagStripDupConsKeys)
+			}
 
-			secondMigration := "v0.39"
+			stripped, err := stripDuplicateConsensusKeys(clientCtx.JSONMarshaler, appState, collisions)
+			if err != nil {
+				return errors.Wrap(err, "failed to strip duplicate consensus keys")
+			}
+			steps = append(steps, flagStripDupConsKeys)
 
-			migrationFunc = cli.GetMigrationCallback(secondMigration)
-			if migrationFunc == nil {
-				return fmt.Errorf("unknown migration function for version: %s", secondMigration)
+			genDoc.AppState, err = json.Marshal(appState)
+			if err != nil {
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
 			}
 
-			// TODO: handler error from migrationFunc call
-			newGenState = migrationFunc(newGenState, clientCtx)
+			for _, c := range stripped {
+				for _, val := range c.Validators {
+					if val.StrippedTo != "" {
+						cmd.PrintErrf("staking: stripped consensus key %s from validator %s, it now has the unusable key %s\n", c.ConsAddress, val.OperatorAddress, val.StrippedTo)
+					}
+				}
+			}
 
-			thirdMigration := "v0.40"
+			if reportPath := opts.ConsKeysReport; reportPath != "" {
+				bz, err := json.MarshalIndent(stripped, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal consensus keys report")
+				}
 
-			migrationFunc = cli.GetMigrationCallback(thirdMigration)
-			if migrationFunc == nil {
-				return fmt.Errorf("unknown migration function for version: %s", thirdMigration)
+				if err := files.WriteFile(reportPath, bz, keyReportFileMode); err != nil {
+					return errors.Wrap(err, "failed to write consensus keys report")
+				}
 			}
 
-			// TODO: handler error from migrationFunc call
-			newGenState = migrationFunc(newGenState, clientCtx)
+			if bundle != nil {
+				if err := bundle.WriteJSON(bundleConsKeysFile, stripped); err != nil {
+					return errors.Wrap(err, "failed to write consensus keys report")
+				}
+			}
+		}
 
-			var bankGenesis bank.GenesisState
+		var evidenceParams *tmproto.EvidenceParams
+		if genDoc.ConsensusParams != nil {
+			evidenceParams = &genDoc.ConsensusParams.Evidence
+		}
+		evidence, err := migrateEvidence(clientCtx.JSONMarshaler, appState, consAddrsBefore, stateChanges.DropEvidence,
+			genDoc.InitialHeight, genDoc.GenesisTime, evidenceParams)
+		if err != nil {
+			return errors.Wrap(err, "failed to migrate the evidence genesis")
+		}
 
-			clientCtx.JSONMarshaler.MustUnmarshalJSON(newGenState[bank.ModuleName], &bankGenesis)
+		if len(evidence) > 0 {
+			var rewritten, dropped int
+			for _, entry := range evidence {
+				switch {
+				case entry.RewrittenTo != "":
+					rewritten++
+				case entry.Dropped:
+					dropped++
+				case entry.Unknown:
+					warnings.Add(warnEvidenceUnknown, severityMedium, evtypes.ModuleName, "the evidence of height %d names %s, which is no validator, use --%s to drop it",
+						entry.Height, entry.ConsensusAddress, flagDropStaleEvidence)
+				}
+				if entry.Expired && !entry.Dropped {
+					warnings.Add(warnEvidenceExpired, severityLow, evtypes.ModuleName, "the evidence of height %d of %s is older than the evidence max age at the initial height and genesis time",
+						entry.Height, entry.ConsensusAddress)
+				}
+			}
 
-			bankGenesis.DenomMetadata = []bank.Metadata{
-				{
-					Description: "The native staking token of the Cosmos Hub.",
-					DenomUnits: []*bank.DenomUnit{
-						{Denom: "uatom", Exponent: uint32(0), Aliases: []string{"microatom"}},
-						{Denom: "matom", Exponent: uint32(3), Aliases: []string{"milliatom"}},
-						{Denom: "atom", Exponent: uint32(6), Aliases: []string{}},
-					},
-					Base:    "uatom",
-					Display: "atom",
-				},
+			if rewritten > 0 {
+				cmd.PrintErrf("evidence: rewrote the consensus address of %d equivocations of validators whose keys changed\n", rewritten)
+			}
+			if dropped > 0 {
+				cmd.PrintErrf("evidence: dropped %d equivocations naming no validator\n", dropped)
+				steps = append(steps, flagDropStaleEvidence)
 			}
-			newGenState[bank.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(&bankGenesis)
 
-			var stakingGenesis staking.GenesisState
+			if reportPath := opts.EvidenceReport; reportPath != "" {
+				bz, err := json.MarshalIndent(evidence, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal evidence report")
+				}
+
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write evidence report")
+				}
+			}
+
+			genDoc.AppState, err = json.Marshal(appState)
+			if err != nil {
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
+			}
+		}
+
+		if opts.CompleteMatured {
+			report, err := completeMaturedEntries(clientCtx.JSONMarshaler, appState, genDoc.GenesisTime)
+			if err != nil {
+				return errors.Wrap(err, "failed to complete matured staking entries")
+			}
+			steps = append(steps, flagCompleteMatured)
 
-			clientCtx.JSONMarshaler.MustUnmarshalJSON(newGenState[staking.ModuleName], &stakingGenesis)
+			genDoc.AppState, err = json.Marshal(appState)
+			if err != nil {
+				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
+			}
 
-			ibcTransferGenesis := ibcxfertypes.DefaultGenesisState()
-			ibcCoreGenesis := ibccoretypes.DefaultGenesisState()
-			capGenesis := captypes.DefaultGenesis()
-			evGenesis := evtypes.DefaultGenesisState()
+			cmd.PrintErrf("staking: completed %d matured unbonding entries returning %s to %d delegators and %d matured redelegation entries\n",
+				report.UnbondingEntries, report.Returned, report.Delegators, report.RedelegationEntries)
+		}
 
-			ibcTransferGenesis.Params.ReceiveEnabled = false
-			ibcTransferGenesis.Params.SendEnabled = false
+		entriesCheck, err := checkStakingEntries(clientCtx.JSONMarshaler, appState, genDoc.GenesisTime)
+		if err != nil {
+			return errors.Wrap(err, "failed to check staking entries")
+		}
+		for _, overLimit := range entriesCheck.OverLimit {
+			warnings.Add(warnStakingMaxEntries, severityMedium, staking.ModuleName, "%s, the delegator cannot add entries to it until some complete", overLimit)
+		}
+		if entriesCheck.MaturedUnbondings > 0 || entriesCheck.MaturedRedelegations > 0 {
+			warnings.Add(warnStakingMatured, severityMedium, staking.ModuleName,
+				"%d unbonding and %d redelegation entries completed before the genesis time and all complete in the first block, use --%s to complete them at genesis",
+				entriesCheck.MaturedUnbondings, entriesCheck.MaturedRedelegations, flagCompleteMatured)
+		}
 
-			ibcCoreGenesis.ClientGenesis.Params.AllowedClients = []string{exported.Tendermint}
-			stakingGenesis.Params.HistoricalEntries = 10000
+		tallyAfter, err := projectTallies(clientCtx.JSONMarshaler, appState)
+		if err != nil {
+			return errors.Wrap(err, "failed to project gov tallies")
+		}
 
-			newGenState[ibcxfertypes.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(ibcTransferGenesis)
-			newGenState[host.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(ibcCoreGenesis)
-			newGenState[captypes.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(capGenesis)
-			newGenState[evtypes.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(evGenesis)
-			newGenState[staking.ModuleName] = clientCtx.JSONMarshaler.MustMarshalJSON(&stakingGenesis)
+		var droppedVotes map[uint64]int
+		if opts.DropStaleVotes {
+			droppedVotes = dropStaleVotes(clientCtx.JSONMarshaler, appState, tallyAfter)
+			steps = append(steps, flagDropStaleVotes)
 
-			genDoc.AppState, err = json.Marshal(newGenState)
+			genDoc.AppState, err = json.Marshal(appState)
 			if err != nil {
 				return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
 			}
+		}
+
+		talliesBefore := make(map[uint64]proposalTally, len(tallyBefore))
+		for _, tally := range tallyBefore {
+			talliesBefore[tally.ProposalID] = tally
+		}
+
+		tallyReports := make([]govTallyReport, 0, len(tallyAfter))
+		for _, after := range tallyAfter {
+			report := govTallyReport{ProposalID: after.ProposalID, After: after, DroppedVotes: droppedVotes[after.ProposalID]}
+			if before, ok := talliesBefore[after.ProposalID]; ok {
+				report.Before = &before
+				cmd.PrintErrf("proposal %d: projected tally %s before the migration options, %s after\n", after.ProposalID, before, after)
+
+				if before.Outcome != after.Outcome {
+					warnings.Add(warnGovTallyOutcome, severityMedium, gov.ModuleName, "the projected outcome of proposal %d changed from %s to %s",
+						after.ProposalID, before.Outcome, after.Outcome)
+				}
+			}
+
+			if report.DroppedVotes == 0 {
+				for _, voter := range after.StaleVotes {
+					warnings.Add(warnGovStaleVote, severityLow, gov.ModuleName, "the vote of %s on proposal %d carries no voting power, use --%s to drop it",
+						voter, after.ProposalID, flagDropStaleVotes)
+				}
+			}
+
+			tallyReports = append(tallyReports, report)
+		}
+
+		if reportPath := opts.GovTallyReport; reportPath != "" {
+			bz, err := json.MarshalIndent(tallyReports, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal gov tally report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write gov tally report")
+			}
+		}
+
+		// checked after every option moving balances, the new chain
+		// refunds and burns the deposits from the gov module account
+		govDeposits, err := checkGovDeposits(clientCtx.JSONMarshaler, appState)
+		if err != nil {
+			return errors.Wrap(err, "failed to check gov deposits")
+		}
+
+		if stateChanges.TopUpGovFrom != "" {
+			if err := topUpGovAccount(clientCtx.JSONMarshaler, appState, &govDeposits, stateChanges.TopUpGovFrom, stateChanges.Protected); err != nil {
+				return errors.Wrapf(err, "failed to apply --%s", flagTopUpGovAccount)
+			}
 
-			genesisTime, _ := cmd.Flags().GetString(flagGenesisTime)
-			if genesisTime != "" {
-				var t time.Time
+			if !govDeposits.ToppedUp.IsZero() {
+				cmd.PrintErrf("gov: covered %s of the deposits from %s\n", govDeposits.ToppedUp, stateChanges.TopUpGovFrom)
+				steps = append(steps, flagTopUpGovAccount)
 
-				err := t.UnmarshalText([]byte(genesisTime))
+				genDoc.AppState, err = json.Marshal(appState)
 				if err != nil {
-					return errors.Wrap(err, "failed to unmarshal genesis time")
+					return errors.Wrap(err, "failed to JSON marshal migrated genesis state")
 				}
+			}
+		}
+
+		if !govDeposits.Backed() {
+			if !govDeposits.Surplus.IsZero() {
+				warnings.Add(warnGovDepositsBalance, severityHigh, gov.ModuleName, "the gov module account holds %s more than the deposits of the active proposals", govDeposits.Surplus)
+			}
+			if deficit := govDeposits.Deficit.Sub(govDeposits.ToppedUp); !deficit.IsZero() {
+				warnings.Add(warnGovDepositsBalance, severityHigh, gov.ModuleName, "the gov module account holds %s less than the deposits of the active proposals, use --%s to cover it",
+					deficit, flagTopUpGovAccount)
+			}
 
-				genDoc.GenesisTime = t
+			if strict || opts.StrictModuleAccts {
+				return fmt.Errorf("the gov module account holds %s, not the %s of deposits of the active proposals", govDeposits.Balance, govDeposits.Deposits)
 			}
+		}
 
-			chainID, _ := cmd.Flags().GetString(flags.FlagChainID)
-			if chainID != "" {
-				genDoc.ChainID = chainID
+		stakingValidators, err := tmValidatorsFromAppState(clientCtx, genDoc.AppState)
+		if err != nil {
+			return errors.Wrap(err, "failed to compute validator set from staking genesis")
+		}
+
+		if stateChanges.SyncValidators {
+			genDoc.Validators = stakingValidators
+			steps = append(steps, flagSyncTmValidators)
+		} else if discrepancies := validatorSetDiscrepancies(stakingValidators, genDoc.Validators); len(discrepancies) > 0 {
+			for _, d := range discrepancies {
+				cmd.PrintErrln(d)
 			}
 
-			initialHeight, _ := cmd.Flags().GetInt(flagInitialHeight)
+			return fmt.Errorf("tendermint genesis validators do not match the staking bonded set (%d discrepancies), use --%s to regenerate them from staking", len(discrepancies), flagSyncTmValidators)
+		}
 
-			genDoc.InitialHeight = int64(initialHeight)
+		if problems := genesisValidatorProblems(genDoc.Validators); len(problems) > 0 {
+			for _, problem := range problems {
+				cmd.PrintErrln(problem)
+			}
 
-			replacementKeys, _ := cmd.Flags().GetString(flagReplacementKeys)
+			return fmt.Errorf("tendermint genesis validators exceed the limits of Tendermint (%d problems)", len(problems))
+		}
 
-			if replacementKeys != "" {
-				genDoc = loadKeydataFromFile(clientCtx, replacementKeys, genDoc)
+		if findings := genesis.ValidatorKeyTypes(genDoc); len(findings) > 0 {
+			for _, finding := range findings {
+				cmd.PrintErrln(finding.Message)
 			}
 
-			bz, err := tmjson.Marshal(genDoc)
+			return fmt.Errorf("%d tendermint genesis validators have consensus key types the consensus params do not allow", len(findings))
+		}
+
+		if stateChanges.ReplacementKeys != "" {
+			proposer, err := firstProposer(genDoc.Validators)
 			if err != nil {
-				return errors.Wrap(err, "failed to marshal genesis doc")
+				return errors.Wrap(err, "failed to compute first proposer")
+			}
+
+			if proposer >= 0 {
+				cmd.PrintErrf("first proposer at height %d: %s (%s)\n",
+					genDoc.InitialHeight, genDoc.Validators[proposer].Address, genDoc.Validators[proposer].Name)
+			}
+		}
+
+		if err := json.Unmarshal(genDoc.AppState, &appState); err != nil {
+			return errors.Wrap(err, "failed to JSON unmarshal migrated genesis state")
+		}
+
+		clientReport, err := newIBCClientReport(clientCtx.JSONMarshaler, appState, genDoc.GenesisTime, genDoc.InitialHeight)
+		if err != nil {
+			return errors.Wrap(err, "failed to check the IBC clients")
+		}
+
+		for _, c := range clientReport.Clients {
+			if !c.WithinTrustingPeriod {
+				warnings.Add(warnIBCClientExpired, severityHigh, host.ModuleName, "client %s of %s expired %s before the genesis time",
+					c.ClientID, c.CounterpartyChainID, strings.TrimPrefix(c.MustBeUpdatedWithin, "-"))
 			}
+		}
 
-			sortedBz, err := sdk.SortJSON(bz)
+		if reportPath := opts.IBCClientReport; reportPath != "" {
+			bz, err := json.MarshalIndent(clientReport, "", "  ")
 			if err != nil {
-				return errors.Wrap(err, "failed to sort JSON genesis doc")
+				return errors.Wrap(err, "failed to marshal IBC client report")
 			}
 
-			fmt.Println(string(sortedBz))
-			return nil
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write IBC client report")
+			}
+		}
+
+		sequences, err := compareAccountSequences(clientCtx.JSONMarshaler, bytes.NewReader(sourceAccounts), sourceAccountsPath, bytes.NewReader(appState[auth.ModuleName]))
+		if err != nil {
+			return errors.Wrap(err, "failed to compare the account sequences")
+		}
+
+		for _, regression := range sequences.Regressions {
+			if regression.SequenceDecreased {
+				warnings.Add(warnAuthSeqDecreased, severityHigh, auth.ModuleName, "the sequence of account %s decreased from %d to %d, the transactions signed for it offline replay or fail",
+					regression.Address, regression.SourceSequence, regression.Sequence)
+			}
+			if regression.PubKeyChanged {
+				warnings.Add(warnAuthPubKeyChanged, severityHigh, auth.ModuleName, "the pubkey of account %s changed", regression.Address)
+			}
+		}
+
+		if len(sequences.NewAccounts) > 0 {
+			cmd.PrintErrf("sequences: %d accounts are new to the migrated genesis\n", len(sequences.NewAccounts))
+		}
+
+		if reportPath := opts.SequenceReport; reportPath != "" {
+			bz, err := json.MarshalIndent(sequences, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal sequence report")
+			}
+
+			if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write sequence report")
+			}
+		}
+
+		noNormalizeOrder := opts.NoNormalizeOrder
+
+		if embedInfo {
+			if !noNormalizeOrder {
+				steps = append(steps, "normalize-order")
+			}
+
+			if err := embedMigrationInfo(genDoc, newMigrationInfo(thirdMigration, steps, sourceDigest.Sum())); err != nil {
+				return errors.Wrap(err, "failed to embed migration info")
+			}
+		}
+
+		if opts.SmokeTest {
+			if err := startStage("smoke-test"); err != nil {
+				return err
+			}
+
+			if len(genDoc.AppState) > smokeTestWarnSize {
+				warnings.Add(warnGenesisSmokeTestSize, severityLow, "genesis", "smoke testing a %d MB app state needs several times that much memory", len(genDoc.AppState)>>20)
+			}
+
+			if err := SmokeTestGenesis(genDoc); err != nil {
+				return errors.Wrap(err, "migrated genesis failed the smoke test")
+			}
+
+			cmd.PrintErrln("smoke test passed: InitChain, one block and all invariants succeeded")
+		}
+
+		maxOutputSize := opts.MaxOutputSize
+		if maxOutputSize > 0 {
+			sizes, err := measureModules(genDoc.AppState)
+			if err != nil {
+				return errors.Wrap(err, "failed to measure the module genesis")
+			}
+			checkModuleBudgets(warnings, sizes, maxOutputSize)
+		}
+
+		maxExamples := opts.MaxWarnExamples
+		warnings.Print(cmd.ErrOrStderr(), maxExamples)
+
+		if reportPath := opts.WarningsReport; reportPath != "" {
+			if err := writeWarningsReport(files, reportPath, warnings.Warnings()); err != nil {
+				return errors.Wrap(err, "failed to write warnings report")
+			}
+		}
+
+		if bundle != nil {
+			if err := bundle.WriteJSON(bundleWarningsFile, append([]migrationWarning{}, warnings.Warnings()...)); err != nil {
+				return errors.Wrap(err, "failed to write warnings")
+			}
+		}
+
+		if metrics != nil {
+			metrics.ObserveWarnings(warnings.Warnings())
+		}
+
+		patterns := opts.WarningsAsErrors
+		if strict {
+			patterns = []string{"*"}
+		}
+		if len(patterns) > 0 {
+			failed, err := warnings.Matching(patterns)
+			if err != nil {
+				return err
+			}
+
+			if len(failed) > 0 {
+				return &WarningsAsErrorsError{Warnings: failed}
+			}
+		}
+
+		if err := startStage("output"); err != nil {
+			return err
+		}
+
+		if !noNormalizeOrder {
+			if err := normalizeGenesisOrder(clientCtx.JSONMarshaler, genDoc); err != nil {
+				return errors.Wrap(err, "failed to normalize genesis order")
+			}
+		}
+
+		// empty fields are emitted alike whichever path produced a module
+		if genDoc.AppState, err = canonicalAppState(clientCtx.JSONMarshaler, genDoc.AppState); err != nil {
+			return err
+		}
+
+		bz, err := tmjson.Marshal(genDoc)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal genesis doc")
+		}
+
+		sortedBz, err := canonicalJSON(bz, true)
+		if err != nil {
+			return errors.Wrap(err, "failed to sort JSON genesis doc")
+		}
+
+		// the output of this release must be the one sdk.SortJSON produced
+		if err := checkCanonicalJSON(bz, sortedBz); err != nil {
+			return errors.Wrap(err, "failed to check the canonical genesis")
+		}
+
+		if opts.SelfCheck || strict {
+			if err := selfCheckOutput(clientCtx.JSONMarshaler, sortedBz, noNormalizeOrder); err != nil {
+				return errors.Wrap(err, "migrated genesis failed the self-check")
+			}
+		}
+
+		if baseline != nil {
+			diff, err := diffBaseline(baseline, sortedBz, optionPaths(opts))
+			if err != nil {
+				return errors.Wrap(err, "failed to compare with the baseline")
+			}
+
+			printBaselineDiff(cmd.ErrOrStderr(), baselinePath, diff)
+
+			if reportPath := opts.BaselineReport; reportPath != "" {
+				bz, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal baseline diff")
+				}
+
+				if err := files.WriteFile(reportPath, bz, outputFileMode); err != nil {
+					return errors.Wrap(err, "failed to write baseline diff")
+				}
+			}
+
+			if bundle != nil {
+				if err := bundle.WriteJSON(bundleBaselineFile, diff); err != nil {
+					return errors.Wrap(err, "failed to write baseline diff")
+				}
+			}
+		}
+
+		// the review copy is indented from the canonical JSON whatever the
+		// output format
+		canonicalJSON := sortedBz
+
+		if outputFormat := opts.OutputFormat; outputFormat == formatYAML {
+			sortedBz, err = jsonToYAML(sortedBz)
+			if err != nil {
+				return errors.Wrap(err, "failed to convert genesis to YAML")
+			}
+		} else if outputFormat != formatJSON {
+			return fmt.Errorf("unknown --%s %s", flagOutputFormat, outputFormat)
+		}
+
+		// the sizes of the canonical module genesis whatever the output format
+		sizes, err := measureModules(genDoc.AppState)
+		if err != nil {
+			return errors.Wrap(err, "failed to measure the module genesis")
+		}
+		// with the newline writeGenesisOutput ends the genesis with
+		outputSize := int64(len(sortedBz)) + 1
+		if err := checkOutputSize(outputSize, maxOutputSize, sizes); err != nil {
+			return err
+		}
+
+		// finish the progress output before the genesis goes to stdout
+		stages.Done()
+
+		if opts.Verbose {
+			printModuleSizes(cmd.ErrOrStderr(), sizes, outputSize)
+		}
+
+		digest := newDigestWriter()
+		write := func(w io.Writer) error {
+			return writeGenesisOutput(io.MultiWriter(w, digest), sortedBz)
+		}
+		canceled := func() error {
+			return migrationCanceled(ctx, timeout)
+		}
+
+		if output := opts.Output; output != "" {
+			// a canceled run removes the written file instead of renaming it
+			if err := files.Write(output, outputFileMode, write, canceled); err != nil {
+				return err
+			}
+		} else if bundle != nil {
+			if err := files.Write(bundle.Path(bundleGenesisFile), outputFileMode, write, nil); err != nil {
+				return err
+			}
+		} else {
+			if err := canceled(); err != nil {
+				return err
+			}
+			if err := write(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+		}
+
+		if reviewOutput := opts.ReviewOutput; reviewOutput != "" {
+			writeReview := func(w io.Writer) error {
+				return writeReviewGenesis(w, canonicalJSON)
+			}
+			if err := files.Write(reviewOutput, outputFileMode, writeReview, canceled); err != nil {
+				return errors.Wrap(err, "failed to write review output")
+			}
+		}
+
+		if metrics != nil {
+			metrics.outputBytes.Set(float64(digest.Size()))
+		}
+		run.outputSHA256 = digest.Sum()
+
+		var manifest migrationManifest
+		if manifestPath != "" || bundle != nil {
+			manifest = newMigrationManifest(genDoc, digest, sourceDigest, opts.reproducibleArgs())
+			// without the duration the manifest of a rerun is the same
+			result := run.result(nil)
+			result.Duration = 0
+			manifest.MigrateResult = result.String()
+			manifest.StageChain = append(stageChain, genesis.NewStageLink(stageChain[len(stageChain)-1], genesis.StageOutput, digest.Sum()))
+		}
+
+		// the manifest is signed as it is written
+		var signature *manifestSignature
+		if signer != nil {
+			bz, err := json.MarshalIndent(manifest, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal manifest")
+			}
+
+			sig, err := signer.Sign(bz)
+			if err != nil {
+				return err
+			}
+			signature = &sig
+			cmd.PrintErrf("signed the manifest with %s\n", sig.Signer)
+		}
+
+		if manifestPath != "" {
+			manifestBz, err := json.MarshalIndent(manifest, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal manifest")
+			}
+
+			if err := files.WriteFile(manifestPath, manifestBz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write manifest")
+			}
+		}
+
+		if manifestPath != "" && signature != nil {
+			sigBz, err := json.MarshalIndent(signature, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal manifest signature")
+			}
+
+			if err := files.WriteFile(manifestPath+manifestSignatureExt, sigBz, outputFileMode); err != nil {
+				return errors.Wrap(err, "failed to write manifest signature")
+			}
+		}
+
+		if bundle != nil {
+			if err := bundle.WriteJSON(bundleManifestFile, manifest); err != nil {
+				return errors.Wrap(err, "failed to write manifest")
+			}
+
+			if signature != nil {
+				if err := bundle.WriteJSON(bundleManifestFile+manifestSignatureExt, signature); err != nil {
+					return errors.Wrap(err, "failed to write manifest signature")
+				}
+			}
+
+			// a canceled run removes the bundle instead of renaming it
+			if err := bundle.Commit(canceled); err != nil {
+				return errors.Wrap(err, "failed to commit bundle")
+			}
+
+			cmd.PrintErrf("wrote the migration bundle to %s\n", bundle.dir)
+		}
+
+		return nil
+	}
+
+	serveMetrics := func() error {
+		if opts.MetricsListen == "" {
+			return migrate()
+		}
+
+		metrics = newMigrationMetrics()
+		addr, stop, err := metrics.Serve(opts.MetricsListen, opts.MetricsAllowCancel)
+		if err != nil {
+			return errors.Wrapf(err, "failed to listen on --%s", flagMetricsListen)
+		}
+		defer stop() // nolint: errcheck
+
+		cmd.PrintErrf("serving migration metrics on http://%s/metrics\n", addr)
+
+		err = migrate()
+		metrics.ObserveResult(err)
+		metrics.status.Finish(err)
+		return err
+	}
+
+	return printMigrateResult(cmd, run, serveMetrics())
+}
+
+// printMigrateResult prints err and the status line of run, the last line
+// written by a migration, and returns err.
+func printMigrateResult(cmd *cobra.Command, run *migrateRun, err error) error {
+	if err != nil {
+		cmd.PrintErrln("Error:", err.Error())
+	}
+	cmd.PrintErrln(run.result(err).String())
+
+	return err
+}
+
+// MigrateGenesisCmd returns a command to execute genesis state migration.
+func MigrateGenesisCmd() *cobra.Command {
+	return newMigrateGenesisCmd(nil)
+}
+
+// NewMigrateGenesisCmd returns MigrateGenesisCmd using the codecs of
+// encodingConfig instead of those of the command's client context, for
+// binaries that embed the command without the gaia client context.
+func NewMigrateGenesisCmd(encodingConfig params.EncodingConfig) *cobra.Command {
+	return newMigrateGenesisCmd(&encodingConfig)
+}
+
+// newMigrateGenesisCmd returns the migrate command, using the codecs of
+// encodingConfig when it is set.
+func newMigrateGenesisCmd(encodingConfig *params.EncodingConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate [genesis-file]",
+		Short: "Migrate genesis to a specified target version",
+		Long: fmt.Sprintf(`Migrate the source genesis into the target version and print it to STDOUT, or
+write it to --output or a --bundle-dir. The source is a file, - for STDIN or an
+http(s) URL, optionally gzip compressed, and --legacy-source migrates an export
+older than cosmoshub-3 first. A source that is the output of a migration
+already is refused unless --force-remigrate.
+
+After the legacy and SDK migrations, the state-altering options like
+--prop-29-data, --airdrop or --blocked-addresses run, skipping the
+--protected-addresses. The migrated genesis is then checked for consistency,
+the findings are warnings or, with --warnings-as-errors, errors. The --*-report flags write what each option and
+check did to a file.
+
+--config reads the options from a TOML or JSON file of flag values by flag
+name, the flags given override it. migrate print-config prints the resolved
+options, migrate capabilities the stages, checks and options as JSON and
+migrate show-data the embedded data tables.
+
+--strict is the mode of a launch run: it requires a verified local source and
+explicit chain parameters, forbids the flags repairing or overriding the source
+state and fails on every warning. --manifest records what genesis reproduce
+needs to re-run the migration.
+
+SIGINT, SIGTERM and --timeout stop the migration at the next stage or module,
+leaving an existing output untouched. The last line written to stderr is the
+status line of the run, e.g.
+
+migrate-result status=ok output_sha256=7a5f... warnings=12 duration=1m33s
+
+docs/migration/migrate.md details every option.
+
+Example:
+$ %s migrate /path/to/genesis.json --chain-id=cosmoshub-4 --genesis-time=2019-04-22T17:00:00Z --initial-height=5000
+`, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the status line is the last line written, cobra must not
+			// print the error and the usage after it
+			cmd.SilenceErrors, cmd.SilenceUsage = true, true
+
+			run := &migrateRun{start: time.Now()}
+			if err := applyMigrateConfig(cmd); err != nil {
+				return printMigrateResult(cmd, run, err)
+			}
+			opts, err := migrateOptionsFromFlags(cmd.Flags())
+			if err != nil {
+				return printMigrateResult(cmd, run, err)
+			}
+
+			return runMigration(cmd, encodingConfig, run, args[0], opts)
 		},
 	}
 
-	cmd.Flags().String(flagGenesisTime, "", "override genesis_time with this flag")
-	cmd.Flags().Int(flagInitialHeight, 0, "Set the starting height for the chain")
-	cmd.Flags().String(flagReplacementKeys, "", "Proviide a JSON file to replace the consensus keys of validators")
+	cmd.AddCommand(MigrateShowDataCmd())
+
+	cmd.Flags().String(flagGenesisTime, "", "override genesis_time with this flag, an RFC 3339 time or +duration after --source-halt-time or the source genesis time, e.g. +45m")
+	cmd.Flags().String(flagInitialHeight, "", "Set the starting height for the chain, a height or +N blocks after --source-halt-height or the --upgrade-info height, e.g. +1")
+	cmd.Flags().Int64(flagSourceHaltHeight, 0, "Height the source chain halted at, the base of a relative --initial-height")
+	cmd.Flags().String(flagUpgradeInfo, "", "upgrade-info.json written by the upgrade module when it halted the source chain, whose height is the base of a relative --initial-height")
+	cmd.Flags().String(flagSourceHaltTime, "", "Time the source chain halted at, the base of a relative --genesis-time instead of the source genesis time")
+	cmd.Flags().String(flagLegacySource, "", fmt.Sprintf("Normalize and migrate an export older than cosmoshub-3 first, one of %s", strings.Join(legacyEraNames(), ", ")))
+	cmd.Flags().Bool(flagShiftAllTimes, false, "Shift the staking, gov, slashing and evidence timestamps by the change of the genesis time, IBC timestamps are never shifted")
+	cmd.Flags().String(flagReplacementKeys, "", "Provide a JSON file to replace the consensus keys of validators, or a pool of keys assigned to the validators selected by top-power, all-bonded or an array of operator addresses")
+	cmd.Flags().String(flagReplacementReport, "", "Write a JSON report of the validators whose consensus keys --"+flagReplacementKeys+" replaced to this file")
 	cmd.Flags().String(flags.FlagChainID, "", "override chain_id with this flag")
+	cmd.Flags().Bool(flagPreserveAppHash, false, "Keep the app_hash of the source genesis instead of clearing it, for replaying the source chain")
+	cmd.Flags().Bool(flagSmokeTest, false, "Start an in-memory app from the migrated genesis, run one block and all invariants before printing it")
 	cmd.Flags().Bool(flagNoProp29, false, "Do not implement fund recovery from prop29")
+	cmd.Flags().String(flagProp29Data, "", "Provide a JSON file of prop29 recovery entries to apply to the migrated balances")
+	cmd.Flags().Bool(flagForceRemigrate, false, fmt.Sprintf("Migrate a source genesis that is the output of a migration already, skipping --%s and --%s", flagProp29Data, flagAirdrop))
+	cmd.Flags().Bool(flagReapplyStateChanges, false, fmt.Sprintf("Apply --%s and --%s to the source genesis --%s migrates again", flagProp29Data, flagAirdrop, flagForceRemigrate))
+	cmd.Flags().String(flagProp29Report, "", "Write a JSON report of the applied prop29 recovery entries to this file")
+	cmd.Flags().String(flagProp29Claims, "", fmt.Sprintf("Divert the prop29 entries of invalid recipients to this account address, or to the %s module account with %q, instead of failing", prop29ClaimsModuleName, prop29ClaimsModule))
+	cmd.Flags().String(flagProp29ClaimsReport, "", "Write the JSON record of the prop29 entries diverted to the claims account to this file")
+	cmd.Flags().String(flagUpgradeProposal, "", "Derive the initial height (and genesis time) from a software upgrade proposal, given as a proposal ID queried from --node or a JSON file of the proposal content")
+	cmd.Flags().String(flags.FlagNode, "", "<host>:<port> to Tendermint RPC interface used to query --upgrade-proposal by ID")
+	cmd.Flags().String(flagCrisisConstantFee, "", "Override the crisis module constant fee, e.g. 1333000000uatom, its denom must be in the bank supply")
+	cmd.Flags().Bool(flagStripDupConsKeys, false, "Replace a consensus key shared by several validators with an unusable key on all but the bonded, or else unbonding, one, a key shared by bonded validators always fails the migration")
+	cmd.Flags().String(flagConsKeysReport, "", "Write a JSON report of the consensus keys stripped by --"+flagStripDupConsKeys+" to this file")
+	cmd.Flags().Bool(flagDropStaleEvidence, false, "Remove the equivocations of the evidence genesis whose consensus address names no validator of the migrated state")
+	cmd.Flags().Bool(flagNormalizePubKeys, false, "Re-encode the account pubkeys of the auth genesis, legacy amino JSON and bech32 ones included, as proto Any")
+	cmd.Flags().Bool(flagClearInvalidPubKeys, false, "Set the account pubkeys that fail to parse or do not match the account address to null, implies --"+flagNormalizePubKeys)
+	cmd.Flags().String(flagPubKeyReport, "", "Write a JSON report of the account pubkeys re-encoded or failing to parse to this file")
+	cmd.Flags().String(flagEvidenceReport, "", "Write a JSON report of the equivocations whose consensus address was rewritten, names no validator or is older than the evidence max age to this file")
+	cmd.Flags().Bool(flagWithdrawAllRewards, false, "Pay out every pending delegation reward and validator commission of the distribution genesis to the withdraw addresses, leaving only the community pool, which gets the rounding dust")
+	cmd.Flags().String(flagRewardsReport, "", "Write a JSON report of the rewards, commission and dust --"+flagWithdrawAllRewards+" paid out, by validator, to this file")
+	cmd.Flags().String(flagTopUpGovAccount, "", "Cover what the gov module account lacks of the deposits of the proposals in deposit or voting period from the balance of this account address")
+	cmd.Flags().String(flagFundCommunityPool, "", "Move these coins, e.g. 1000000uatom, from the balance of --"+flagFundFrom+" to the community pool")
+	cmd.Flags().String(flagFundFrom, "", "The bech32 address of the account --"+flagFundCommunityPool+" debits")
+	cmd.Flags().String(flagCapValidatorPower, "", "Reduce the delegations of every bonded validator above this percent of the bonded power in proportion until none is, returning the excess tokens to the delegators' balances, e.g. 10")
+	cmd.Flags().String(flagPowerCapReport, "", "Write a JSON report of the tokens --"+flagCapValidatorPower+" moved and the validators it jailed, by validator, to this file")
+	cmd.Flags().Bool(flagCompleteMatured, false, "Complete the unbonding and redelegation entries that matured before the genesis time, returning the unbonded tokens to their delegators")
+	cmd.Flags().Bool(flagFixProofSpecs, false, "Set the proof specs and upgrade path of the IBC tendermint clients to the standard SDK ones")
+	cmd.Flags().String(flagRemapChainIDs, "", "Provide a JSON object mapping counterparty chain IDs to new ones, e.g. of a rehearsal network, to rewrite the chain IDs of the IBC tendermint client states with, consensus states are untouched")
+	cmd.Flags().Bool(flagRepairChannelSeqs, false, "Bump the next send, receive and acknowledgement sequences of the IBC channels below the sequences of their packets to the lowest valid ones")
+	cmd.Flags().Bool(flagRepairCaps, false, "Regenerate the capability genesis from the IBC port and channel genesis instead of failing when they do not match")
+	cmd.Flags().String(flagOutputFile, "", "File to write the migrated genesis to instead of STDOUT, only created once the migration completed")
+	cmd.Flags().String(flagBundleDir, "", "Directory to create with the migrated genesis, manifest, warnings, reports and their SHA256SUMS instead of writing the genesis to STDOUT, only created once the migration completed")
+	addOutputFileFlags(cmd)
+	cmd.Flags().Duration(flagTimeout, 0, fmt.Sprintf("Abort the migration once it runs longer than this, exiting with code %d, e.g. 30m", MigrationTimeoutExitCode))
+	cmd.Flags().String(flagSweepModuleDust, "", fmt.Sprintf("Move what the module accounts hold beyond their module genesis to this account address, or to the community pool with %s", blockedCommunityPool))
+	cmd.Flags().Bool(flagStrictModuleAccts, false, "Fail when a module account balance does not match its module genesis, after --"+flagSweepModuleDust+", or the gov module account the deposits of the active proposals, after --"+flagTopUpGovAccount)
+	cmd.Flags().String(flagModuleAcctsReport, "", "Write a JSON report of the expected and actual balance of every module account to this file")
+	cmd.Flags().Bool(flagDropStaleVotes, false, "Drop the votes on proposals in voting period that carry no voting power on the migrated staking state")
+	cmd.Flags().String(flagReviewOutput, "", "Also write an indented JSON copy of the migrated genesis for review to this file, the output and its manifest are unchanged")
+	cmd.Flags().String(flagBaseline, "", "Compare the migrated genesis with this earlier migration output and print the changed modules and unexpected changes")
+	cmd.Flags().String(flagBaselineReport, "", "Write a JSON report of the changes from --baseline by module and record to this file")
+	cmd.Flags().Bool(flagDropUnmappable, false, "Remove the proposals whose content type cannot be migrated, with their votes, instead of failing")
+	cmd.Flags().String(flagGovContentReport, "", "Write a JSON report of the proposal contents mapped from legacy types and of those that cannot be migrated, with their tallies, to this file")
+	cmd.Flags().String(flagGovTallyReport, "", "Write a JSON report of the projected tally of every proposal in voting period before and after the migration options to this file")
+	cmd.Flags().Bool(flagNormalizeUnicode, false, "Normalize the validator description fields and the titles and descriptions of proposals not in Unicode NFC to NFC, instead of warning")
+	cmd.Flags().String(flagUnicodeReport, "", "Write a JSON report of the description and proposal content strings not in Unicode NFC, with their NFC forms, to this file")
+	cmd.Flags().Bool(flagTruncateLongStrings, false, "Cut the validator description fields and the titles and descriptions of proposals in deposit or voting period longer than the staking and gov limits to the limit, instead of warning")
+	cmd.Flags().String(flagLongStringsReport, "", "Write a JSON report of the description and proposal content strings longer than their limit, with their original lengths, to this file")
+	cmd.Flags().Bool(flagDropEmptyRecords, false, "Drop the bank balances without coins, delegations without shares and unbonding and redelegation entries without balance, accounts are kept in auth")
+	cmd.Flags().String(flagDebugDumpDir, "", "Dump the module genesis a panicked migration started from, and the records around the one failing, to this directory (default a new temporary directory, none with --strict)")
+	cmd.Flags().String(flagSequenceReport, "", "Write a JSON report of the accounts whose sequence decreased or whose pubkey changed from the source genesis, and of the new accounts, to this file")
+	cmd.Flags().String(flagIBCClientReport, "", "Write a JSON report of the trusting period left at the genesis time to every IBC client of the migrated genesis to this file")
+	cmd.Flags().String(flagIBCChannelReport, "", "Write a JSON report of the consistency of every IBC channel of the migrated genesis with its packets and connection, and of the --"+flagRepairChannelSeqs+" repairs, to this file")
+	cmd.Flags().Duration(flagExpectedBlockTime, 0, "Expected time per block of the migrated chain, used to check the mint blocks_per_year")
+	cmd.Flags().Uint64(flagMintBlocksPerYear, 0, "Override the mint blocks_per_year param")
+	cmd.Flags().String(flagMintInflation, "", "Override the current mint inflation, it must be within the inflation_min and inflation_max params")
+	cmd.Flags().String(flagProtectedAddrs, "", "Provide a JSON array of addresses, such as exchange cold wallets, that together with the module accounts no state-altering option modifies, the options skip them and report the skips as warnings")
+	cmd.Flags().Bool(flagFailOnProtected, false, "Fail instead of skipping when a state-altering option would modify a --"+flagProtectedAddrs+" address")
+	cmd.Flags().String(flagBlockedAddresses, "", "Provide a JSON array of addresses whose balances, delegations and deposits are moved to --blocked-destination, force unbonding changes validator powers and needs --sync-tm-validators")
+	cmd.Flags().String(flagBlockedDest, blockedCommunityPool, "Address receiving the funds of blocked addresses, or community-pool")
+	cmd.Flags().String(flagBlockedAccount, blockedAccountRemove, "What to do with the accounts of blocked addresses, remove or zero")
+	cmd.Flags().String(flagBlockedReport, "", "Write a JSON report of the handled blocked addresses to this file")
+	cmd.Flags().String(flagPruneBelow, "", "Prune accounts holding less than this amount, e.g. 1000000uatom, delegations in the bond denom count towards it")
+	cmd.Flags().Int(flagKeepTopAccounts, 0, "Prune all but this many of the largest accounts by bond denom holdings")
+	cmd.Flags().String(flagPruneSink, "", "Account receiving the balances, delegations and deposits of pruned accounts")
+	cmd.Flags().String(flagSweepInactiveTo, "", "Remove the accounts with a zero sequence, holding less than --"+flagInactiveBelow+" and not staking, moving their balances to this account address")
+	cmd.Flags().String(flagInactiveBelow, "1000000uatom", "Balance below which an account with a zero sequence and no delegations is inactive")
+	cmd.Flags().String(flagSweptReport, "", "Write the accounts removed by --"+flagSweepInactiveTo+" as CSV to this file")
+	cmd.Flags().String(flagAirdrop, "", "Provide a JSON airdrop formula minting a denom to the holders of another, applied after blocked addresses and pruning")
+	cmd.Flags().String(flagRoundingDustTo, blockedCommunityPool, "Mint the fractions the steps minting decimal amounts, like an --"+flagAirdrop+" ratio, round down to this address or "+blockedCommunityPool+", so the supply is what they meant to mint to the base unit")
+	cmd.Flags().String(flagRoundingDustReport, "", "Write a JSON report of the rounding dust by step and denom and what was minted for it to this file")
+	cmd.Flags().String(flagAirdropReport, "", "Write a CSV of the address, holding and granted amount of every airdrop recipient to this file")
+	cmd.Flags().Bool(flagSyncTmValidators, false, "Regenerate the tendermint genesis validators from the staking bonded set instead of failing on a mismatch")
+	cmd.Flags().StringSlice(flagWarningsAsErrors, nil, "Fail on warnings, optionally only those with codes matching the given patterns, e.g. --warnings-as-errors=W-IBC-*")
+	cmd.Flags().Lookup(flagWarningsAsErrors).NoOptDefVal = "*"
+	cmd.Flags().Int(flagMaxWarnExamples, 10, "Print at most this many warnings of a code, followed by the count of the others, 0 prints all")
+	cmd.Flags().String(flagWarningsReport, "", "Write every warning as a JSON array to this file")
+	cmd.Flags().String(flagSourceSHA256, "", "Fail unless the source genesis file, or its download, has this hex encoded SHA-256, checked before it is parsed")
+	cmd.Flags().String(flagDownloadDir, "", "Directory caching the downloads of URL sources so an interrupted download resumes, defaults to gaiad/genesis-downloads in the user cache directory")
+	cmd.Flags().Duration(flagDownloadTimeout, time.Minute, "Abort a download attempt of a URL source once it received no data for this long, 0 to wait forever")
+	cmd.Flags().Int(flagDownloadRetries, 5, "Retry a failed download of a URL source this many times, with exponential backoff, resuming where it stopped")
+	cmd.Flags().String(flagInputFormat, formatJSON, "Format of the genesis file to migrate, json or yaml, either optionally gzip compressed")
+	cmd.Flags().Int64(flagMaxInputSize, defaultMaxInputSize, "Fail on a genesis input larger than this many bytes, after gzip decompression")
+	cmd.Flags().Int64(flagMaxOutputSize, 0, "Fail before writing a migrated genesis larger than this many bytes, naming its largest modules, and warn about a module taking over half of it; 0 for no limit")
+	cmd.Flags().Bool(flagNoNormalizeOrder, false, "Keep the order the migrations produce instead of sorting the validators and the auth, bank, staking and slashing arrays like an SDK export, the output of releases before this flag was added")
+	cmd.Flags().String(flagOutputFormat, formatJSON, "Format of the migrated genesis, json or yaml")
+	cmd.Flags().Bool(flagProgress, false, "Render the migration progress on stderr")
+	cmd.Flags().Bool(flagEmbedMigration, false, fmt.Sprintf("Record the gaia and SDK versions, the migration steps and the source genesis SHA-256 in app_state.%s, which InitChain ignores but strict parsers may reject", migrationInfoKey))
+	cmd.Flags().String(flagManifest, "", "Write a JSON manifest with the SHA-256 and size of the migrated genesis, the versions of this binary and the migration flags to this file, checked by genesis verify-published and genesis reproduce")
+	cmd.Flags().Bool(flagSignManifest, false, "Sign the --manifest, and the manifest of --bundle-dir, with the keyring key --from, writing the signature next to it with "+manifestSignatureExt+" appended, checked by genesis verify-manifest")
+	cmd.Flags().String(flags.FlagFrom, "", "Name of the keyring key signing the manifest with --"+flagSignManifest)
+	cmd.Flags().String(flags.FlagKeyringBackend, flags.DefaultKeyringBackend, "Keyring backend of the --"+flags.FlagFrom+" key (os|file|test)")
+	cmd.Flags().String(flags.FlagKeyringDir, "", "Directory of the keyring of the --"+flags.FlagFrom+" key, the home directory by default")
+	cmd.Flags().String(flagRequireVersion, "", "Refuse to run unless this binary is this gaia version, e.g. v5.0.2")
+	cmd.Flags().BoolP(flags.FlagSkipConfirmation, "y", false, "Skip confirming the state-altering options when running in a terminal")
+	cmd.Flags().String(flagCacheDir, "", "Cache the state migrated by the legacy and SDK migration stages in this directory and resume a later migration of the same genesis after the last cached stage")
+	cmd.Flags().Bool(flagResume, false, "Continue the scan of the auth accounts from the last --cache-dir checkpoint of the same auth genesis and options")
+	cmd.Flags().Int(flagAccountsCheckpoint, 100000, "Checkpoint the scan of the auth accounts in the --cache-dir every this many accounts")
+	cmd.Flags().Bool(flagSelfCheck, false, "Fail unless the output stage reproduces the migrated genesis byte for byte from itself")
+	cmd.Flags().Bool(flagStrict, false, "Launch mode: require a local source of a given --source-sha256, explicit --chain-id, --genesis-time and --initial-height and --output, forbid the repair and override flags, fail on every warning and module account mismatch and run --self-check")
+	cmd.Flags().Bool(flagVerbose, false, "Log every migration stage with its duration, and the module sizes of the output, on stderr")
+	cmd.Flags().String(flagConfig, "", "Read migrate options from this TOML or JSON file of flag values by flag name, the flags given override it")
+	cmd.Flags().String(flagMetricsListen, "", "Expose Prometheus metrics of the migration stages, sizes, warnings and results on /metrics at this address, e.g. 127.0.0.1:9091, and the JSON status of the run on GET /status; :9091 listens on every interface")
+	cmd.Flags().Bool(flagMetricsCancel, false, "Also cancel the run on POST /cancel of --"+flagMetricsListen+", only accepted from loopback clients")
+
+	// print-config and capabilities share the flags, they must be added
+	// after all of them
+	cmd.AddCommand(migratePrintConfigCmd(cmd.Flags()), migrateCapabilitiesCmd(cmd.Flags()))
 
 	return cmd
 }
 
 // MigrateTendermintGenesis makes sure a later version of Tendermint can parse
 // a JSON blob exported by an older version of Tendermint.
-func migrateTendermintGenesis(jsonBlob []byte) ([]byte, error) {
+func migrateTendermintGenesis(r io.Reader) ([]byte, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
 	var jsonObj map[string]interface{}
-	err := json.Unmarshal(jsonBlob, &jsonObj)
+	err := dec.Decode(&jsonObj)
 	if err != nil {
 		return nil, err
 	}
@@ -219,13 +2414,16 @@ func migrateTendermintGenesis(jsonBlob []byte) ([]byte, error) {
 
 	}
 
-	evidenceParams["max_age_num_blocks"] = evidenceParams["max_age"]
-	delete(evidenceParams, "max_age")
+	// the params of a genesis migrated already are left as they are
+	if maxAge, ok := evidenceParams["max_age"]; ok {
+		evidenceParams["max_age_num_blocks"] = maxAge
+		delete(evidenceParams, "max_age")
 
-	evidenceParams["max_age_duration"] = "172800000000000"
-	evidenceParams["max_bytes"] = "50000"
+		evidenceParams["max_age_duration"] = "172800000000000"
+		evidenceParams["max_bytes"] = "50000"
+	}
 
-	jsonBlob, err = json.Marshal(jsonObj)
+	jsonBlob, err := json.Marshal(jsonObj)
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error resserializing JSON blob after tendermint migrations")
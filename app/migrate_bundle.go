@@ -0,0 +1,137 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files of a migrate --bundle-dir bundle. The reports are only written when
+// the migration produces them.
+const (
+	bundleGenesisFile     = "genesis.json"
+	bundleManifestFile    = "manifest.json"
+	bundleWarningsFile    = "warnings.json"
+	bundleProp29File      = "prop29-report.json"
+	bundleClaimsFile      = "prop29-claims.json"
+	bundleReplacementFile = "replacement-report.json"
+	bundleBaselineFile    = "baseline-diff.json"
+	bundleConsKeysFile    = "consensus-keys-report.json"
+	bundleChecksumsFile   = "SHA256SUMS"
+)
+
+// migrationBundle collects the files of a migrate --bundle-dir run in a
+// temporary directory next to the bundle directory, which Commit renames to
+// it, so a failed run leaves no bundle directory behind.
+type migrationBundle struct {
+	dir   string
+	tmp   string
+	files outputFiles
+}
+
+// newMigrationBundle returns the bundle of dir, which must not exist yet,
+// whose files are written as files writes them.
+func newMigrationBundle(dir string, files outputFiles) (*migrationBundle, error) {
+	dir = filepath.Clean(dir)
+	if _, err := os.Lstat(dir); err == nil {
+		return nil, fmt.Errorf("bundle directory %s already exists", dir)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if files.createDirs {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), "."+filepath.Base(dir)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrationBundle{dir: dir, tmp: tmp, files: files}, nil
+}
+
+// Path returns the path of the bundle file name until the bundle is
+// committed.
+func (b *migrationBundle) Path(name string) string {
+	return filepath.Join(b.tmp, name)
+}
+
+// WriteJSON writes v as indented JSON to the bundle file name, the
+// reports of consensus keys private by default.
+func (b *migrationBundle) WriteJSON(name string, v interface{}) error {
+	bz, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	mode := outputFileMode
+	if name == bundleReplacementFile || name == bundleConsKeysFile {
+		mode = keyReportFileMode
+	}
+	return b.files.WriteFile(b.Path(name), bz, mode)
+}
+
+// Commit writes the SHA-256 of every bundle file to bundleChecksumsFile, in
+// the format of sha256sum, and renames the bundle to its directory. commit,
+// if set, is called before the rename and aborts it with its error.
+func (b *migrationBundle) Commit(commit func() error) error {
+	entries, err := ioutil.ReadDir(b.tmp)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var sums strings.Builder
+	for _, entry := range entries {
+		sum, err := fileSHA256(b.Path(entry.Name()))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", sum, entry.Name())
+	}
+
+	if err := b.files.WriteFile(b.Path(bundleChecksumsFile), []byte(sums.String()), outputFileMode); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(b.tmp, 0755); err != nil {
+		return err
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(b.tmp, b.dir)
+}
+
+// Remove deletes the bundle unless it was committed.
+func (b *migrationBundle) Remove() error {
+	return os.RemoveAll(b.tmp)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	digest := newDigestWriter()
+	if _, err := io.Copy(digest, f); err != nil {
+		return "", err
+	}
+
+	return digest.Sum(), nil
+}
@@ -0,0 +1,98 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateBundle(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "bundle")
+
+	consPubKey, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeConsPub, genesisbuilder.ValidatorConsKey(7).PubKey())
+	require.NoError(t, err)
+	replacementKeys := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, ioutil.WriteFile(replacementKeys, []byte(fmt.Sprintf(`[{
+		"validator_name": "validator",
+		"validator_address": "cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0",
+		"stargate_consensus_public_key": %q
+	}]`, consPubKey)), 0644))
+
+	out, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--chain-id", "cosmoshub-4",
+		"--no-prop-29", "--replacement-cons-keys", replacementKeys, "--bundle-dir", dir)
+	require.NoError(t, err)
+	require.Empty(t, out)
+
+	entries, err := ioutil.ReadDir(parent)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	entries, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.Equal(t, []string{bundleChecksumsFile, bundleGenesisFile, bundleManifestFile, bundleReplacementFile, bundleWarningsFile}, names)
+
+	sums, err := ioutil.ReadFile(filepath.Join(dir, bundleChecksumsFile))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(sums), "\n"), "\n")
+	require.Len(t, lines, len(names)-1)
+	for i, name := range names[1:] {
+		sum, err := fileSHA256(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, sum+"  "+name, lines[i])
+	}
+
+	var manifest migrationManifest
+	bz, err := ioutil.ReadFile(filepath.Join(dir, bundleManifestFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, &manifest))
+	require.Equal(t, "cosmoshub-4", manifest.ChainID)
+	require.Contains(t, string(sums), manifest.GenesisSHA256+"  "+bundleGenesisFile)
+
+	var replaced []keyReplacement
+	bz, err = ioutil.ReadFile(filepath.Join(dir, bundleReplacementFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, &replaced))
+	require.Len(t, replaced, 1)
+	require.Equal(t, sdk.ConsAddress(genesisbuilder.ValidatorConsKey(7).PubKey().Address()).String(), replaced[0].NewConsAddress)
+
+	var warnings []migrationWarning
+	bz, err = ioutil.ReadFile(filepath.Join(dir, bundleWarningsFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, &warnings))
+	require.NotEmpty(t, warnings)
+
+	// an existing bundle is never overwritten
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--bundle-dir", dir)
+	require.EqualError(t, err, fmt.Sprintf("failed to create bundle: bundle directory %s already exists", dir))
+}
+
+func TestMigrateBundleFailure(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "bundle")
+
+	// the fixture has a crisis fee warning, failing the run after the
+	// genesis and warnings are written
+	_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--bundle-dir", dir, "--warnings-as-errors=W-CRISIS-*")
+	require.EqualError(t, err, "1 warnings are treated as errors by --warnings-as-errors")
+
+	entries, err := ioutil.ReadDir(parent)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--bundle-dir", dir, "--output", filepath.Join(parent, "genesis.json"))
+	require.EqualError(t, err, "--bundle-dir writes the genesis to the bundle, it cannot be combined with --output")
+}
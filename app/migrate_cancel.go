@@ -0,0 +1,78 @@
+package gaia
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// MigrationTimeoutExitCode is the exit code of gaiad when migrate exceeds
+// its --timeout, the exit code of timeout(1).
+const MigrationTimeoutExitCode = 124
+
+// MigrationTimeoutError is the error of a migration aborted by --timeout.
+type MigrationTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *MigrationTimeoutError) Error() string {
+	return fmt.Sprintf("migration canceled, --%s %s exceeded", flagTimeout, e.Timeout)
+}
+
+// migrateStageStarted is called as every stage of migrate starts, with the
+// context of the run. Tests slow stages down with it.
+var migrateStageStarted = func(ctx context.Context, stage string) {}
+
+// migrationContext returns the context of a migrate run, the context of cmd
+// canceled on SIGINT or SIGTERM and, if timeout is positive, once it
+// elapses. Only the first signal cancels the context, a second one kills the
+// process as usual.
+func migrationContext(cmd *cobra.Command, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cancelTimeout := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+		cancelTimeout()
+	}
+}
+
+// migrationCanceled returns the error of a migration whose context ctx is
+// done, a MigrationTimeoutError once timeout elapsed, nil while it is not.
+func migrationCanceled(ctx context.Context, timeout time.Duration) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+	case err == context.DeadlineExceeded && timeout > 0:
+		return &MigrationTimeoutError{Timeout: timeout}
+	default:
+		return errors.Wrap(err, "migration canceled")
+	}
+}
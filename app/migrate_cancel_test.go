@@ -0,0 +1,127 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowMigrateStage makes stage of migrate call slow with the context of the
+// run until the test ends.
+func slowMigrateStage(t *testing.T, stage string, slow func(ctx context.Context)) {
+	started := migrateStageStarted
+	t.Cleanup(func() { migrateStageStarted = started })
+
+	migrateStageStarted = func(ctx context.Context, name string) {
+		if name == stage {
+			slow(ctx)
+		}
+	}
+}
+
+func TestMigrateOutputFile(t *testing.T) {
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+	expected, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+
+	output := filepath.Join(t.TempDir(), "genesis.json")
+	out, err := executeMigrate(t, append(args, "--output", output)...)
+	require.NoError(t, err)
+	require.Empty(t, out)
+
+	bz, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, expected, bz)
+}
+
+func TestMigrateCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the cancellation is noticed as the next module is done
+	slowMigrateStage(t, "modules", func(context.Context) { cancel() })
+
+	dir := t.TempDir()
+	_, err := executeMigrateContext(ctx, t, ioutil.Discard, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2",
+		"--no-prop-29", "--chain-id", "cosmoshub-4", "--output", filepath.Join(dir, "genesis.json"))
+	require.EqualError(t, err, "migration canceled: context canceled")
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestMigrateTimeout(t *testing.T) {
+	slowMigrateStage(t, "validators", func(ctx context.Context) { <-ctx.Done() })
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(output, []byte("previous"), 0644))
+
+	_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2",
+		"--no-prop-29", "--chain-id", "cosmoshub-4", "--output", output, "--timeout", "2s")
+	require.EqualError(t, err, "migration canceled, --timeout 2s exceeded")
+
+	var timeoutErr *MigrationTimeoutError
+	require.True(t, errors.As(err, &timeoutErr))
+	require.Equal(t, 2*time.Second, timeoutErr.Timeout)
+
+	bz, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "previous", string(bz))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestMigrateTimeoutBeforeOutput(t *testing.T) {
+	// the timeout elapses while the output is written, which is not renamed
+	slowMigrateStage(t, "output", func(ctx context.Context) { <-ctx.Done() })
+
+	dir := t.TempDir()
+	_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2",
+		"--no-prop-29", "--chain-id", "cosmoshub-4", "--output", filepath.Join(dir, "genesis.json"), "--timeout", "2s")
+	require.EqualError(t, err, "migration canceled, --timeout 2s exceeded")
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestMigrateReviewOutput(t *testing.T) {
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+	dir := t.TempDir()
+
+	single := filepath.Join(dir, "single.json")
+	_, err := executeMigrate(t, append(args, "--output", single, "--manifest", filepath.Join(dir, "single-manifest.json"))...)
+	require.NoError(t, err)
+
+	output, review := filepath.Join(dir, "genesis.json"), filepath.Join(dir, "review.json")
+	_, err = executeMigrate(t, append(args, "--output", output, "--review-output", review, "--manifest", filepath.Join(dir, "manifest.json"))...)
+	require.NoError(t, err)
+
+	expected, err := ioutil.ReadFile(single)
+	require.NoError(t, err)
+	canonical, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, expected, canonical)
+
+	singleManifest, err := ioutil.ReadFile(filepath.Join(dir, "single-manifest.json"))
+	require.NoError(t, err)
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	require.NoError(t, err)
+	require.Equal(t, singleManifest, manifest)
+
+	bz, err := ioutil.ReadFile(review)
+	require.NoError(t, err)
+	require.JSONEq(t, string(canonical), string(bz))
+	require.True(t, bytes.HasPrefix(bz, []byte("{\n  \"app_hash\": ")))
+	require.Greater(t, bytes.Count(bz, []byte("\n")), bytes.Count(canonical, []byte("\n")))
+}
@@ -0,0 +1,112 @@
+package gaia
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// migrateStage is a stage of the migration, run only when its When flag is
+// set if any.
+type migrateStage struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	When        string `json:"when,omitempty"`
+}
+
+// migrateStages are the stages of the migration in the order they run.
+var migrateStages = []migrateStage{
+	{Name: "read", Description: "Read and check the source genesis"},
+	{Name: "legacy", Description: "Normalize the export of an era older than cosmoshub-3 and migrate it to the v0.36 state", When: flagLegacySource},
+	{Name: "v0.38", Description: "Run the SDK v0.38 genesis migration"},
+	{Name: "v0.39", Description: "Run the SDK v0.39 genesis migration"},
+	{Name: "v0.40", Description: "Run the SDK v0.40 genesis migration and add the genesis of the new modules"},
+	{Name: "modules", Description: "Apply the state changes and check the module genesis states"},
+	{Name: "genesis", Description: "Set the chain ID, genesis time, initial height and consensus params"},
+	{Name: "validators", Description: "Compute the validator set from the staking genesis and check the migrated state"},
+	{Name: "smoke-test", Description: "Initialize a gaia app from the migrated genesis", When: flagSmokeTest},
+	{Name: "output", Description: "Write the migrated genesis, the reports and the manifest"},
+}
+
+// migrateStageNames returns the names of the stages a migration runs, set
+// tells whether the flag of a conditional stage is set.
+func migrateStageNames(set func(flag string) bool) []string {
+	var names []string
+	for _, stage := range migrateStages {
+		if stage.When == "" || set(stage.When) {
+			names = append(names, stage.Name)
+		}
+	}
+	return names
+}
+
+// migrateOption is a migrate flag. StrictAllowed tells whether --strict
+// allows it, Reproduced whether the manifest records it.
+type migrateOption struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Default       string `json:"default"`
+	Usage         string `json:"usage"`
+	StrictAllowed bool   `json:"strict_allowed"`
+	Reproduced    bool   `json:"reproduced"`
+}
+
+// migrateCapabilities is the catalog migrate capabilities prints.
+type migrateCapabilities struct {
+	Stages  []migrateStage  `json:"stages"`
+	Checks  []warningCheck  `json:"checks"`
+	Options []migrateOption `json:"options"`
+}
+
+// migrateCapabilitiesOf returns the catalog of the migrate flags fs.
+func migrateCapabilitiesOf(fs *pflag.FlagSet) migrateCapabilities {
+	forbidden := make(map[string]bool, len(strictForbiddenFlags))
+	for _, name := range strictForbiddenFlags {
+		forbidden[name] = true
+	}
+
+	capabilities := migrateCapabilities{Stages: migrateStages, Checks: warningChecks}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+
+		capabilities.Options = append(capabilities.Options, migrateOption{
+			Name:          flag.Name,
+			Type:          flag.Value.Type(),
+			Default:       flag.DefValue,
+			Usage:         flag.Usage,
+			StrictAllowed: !forbidden[flag.Name],
+			Reproduced:    !unreproducedFlags[flag.Name],
+		})
+	})
+
+	return capabilities
+}
+
+// migrateCapabilitiesCmd returns the migrate capabilities command printing
+// the catalog of the migrate flags fs.
+func migrateCapabilitiesCmd(fs *pflag.FlagSet) *cobra.Command {
+	return &cobra.Command{
+		Use:   "capabilities",
+		Short: "Print the migration stages, checks and options as JSON",
+		Long: `Print a JSON catalog of the migration to STDOUT, for tools driving migrate:
+the stages in the order they run, with the flag a conditional one runs for,
+the checks by warning code, with their severity and the flag repairing what
+they find if any, and the migrate flags, with their type, default, whether
+--strict allows them and whether the manifest records them. The catalog is
+built from the tables the migration runs on, it follows this binary.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			bz, err := json.MarshalIndent(migrateCapabilitiesOf(fs), "", "  ")
+			if err != nil {
+				return err
+			}
+
+			_, err = cmd.OutOrStdout().Write(append(bz, '\n'))
+			return err
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateCapabilities(t *testing.T) {
+	cmd := MigrateGenesisCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs([]string{"capabilities"})
+	require.NoError(t, cmd.Execute())
+
+	var capabilities migrateCapabilities
+	require.NoError(t, json.Unmarshal(out.Bytes(), &capabilities))
+
+	var stages []string
+	for _, stage := range capabilities.Stages {
+		stages = append(stages, stage.Name)
+	}
+	require.Equal(t, []string{"read", "legacy", "v0.38", "v0.39", "v0.40", "modules", "genesis", "validators", "smoke-test", "output"}, stages)
+	require.Equal(t, []string{"read", "v0.38", "v0.39", "v0.40", "modules", "genesis", "validators", "output"}, migrateStageNames(func(string) bool { return false }))
+
+	// every migrate flag is an option, with its strict and manifest rules
+	options := make(map[string]migrateOption)
+	for _, option := range capabilities.Options {
+		options[option.Name] = option
+	}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+		require.Contains(t, options, flag.Name)
+		require.Equal(t, flag.DefValue, options[flag.Name].Default, flag.Name)
+		require.Equal(t, flag.Value.Type(), options[flag.Name].Type, flag.Name)
+	})
+	require.Len(t, options, len(capabilities.Options))
+	for _, name := range strictForbiddenFlags {
+		require.False(t, options[name].StrictAllowed, name)
+	}
+	require.True(t, options[flagPruneBelow].StrictAllowed)
+	require.False(t, options[flagOutputFile].Reproduced)
+	require.True(t, options[flagFundCommunityPool].Reproduced)
+	require.Equal(t, migrateOption{
+		Name: flagAccountsCheckpoint, Type: "int", Default: "100000",
+		Usage:         "Checkpoint the scan of the auth accounts in the --cache-dir every this many accounts",
+		StrictAllowed: true,
+	}, options[flagAccountsCheckpoint])
+
+	checks := make(map[string]warningCheck)
+	for _, check := range capabilities.Checks {
+		require.NotContains(t, checks, check.Code)
+		checks[check.Code] = check
+		if check.Repair != "" {
+			require.Contains(t, options, check.Repair, check.Code)
+		}
+	}
+
+	// every warning code is a check, and is reported with its severity
+	codes, severities := warningCodesOf(t)
+	require.Len(t, checks, len(codes))
+	for name, code := range codes {
+		require.Contains(t, checks, code, name)
+	}
+	for _, added := range severities {
+		require.Equal(t, checks[codes[added.code]].Severity, added.severity, "%s at %s", added.code, added.pos)
+	}
+}
+
+// warningAdd is a warningCollector.Add call of the package sources.
+type warningAdd struct {
+	code     string
+	severity warningSeverity
+	pos      string
+}
+
+// warningCodesOf returns the warning codes declared in warnings.go by
+// constant name, and the calls adding warnings of a constant code in the
+// package sources.
+func warningCodesOf(t *testing.T) (map[string]string, []warningAdd) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool { return !strings.HasSuffix(info.Name(), "_test.go") }, 0)
+	require.NoError(t, err)
+
+	codes := make(map[string]string)
+	for _, spec := range pkgs["gaia"].Files["warnings.go"].Decls {
+		decl, ok := spec.(*ast.GenDecl)
+		if !ok || decl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok || !strings.HasPrefix(name.Name, "warn") {
+					continue
+				}
+				code, err := strconv.Unquote(lit.Value)
+				require.NoError(t, err)
+				codes[name.Name] = code
+			}
+		}
+	}
+	require.NotEmpty(t, codes)
+
+	severities := map[string]warningSeverity{"severityLow": severityLow, "severityMedium": severityMedium, "severityHigh": severityHigh}
+	var adds []warningAdd
+	for _, file := range pkgs["gaia"].Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Add" {
+				return true
+			}
+			code, ok := call.Args[0].(*ast.Ident)
+			if !ok || codes[code.Name] == "" {
+				return true
+			}
+			severity, ok := call.Args[1].(*ast.Ident)
+			require.True(t, ok, "%s adds %s with a computed severity", fset.Position(call.Pos()), code.Name)
+			adds = append(adds, warningAdd{code: code.Name, severity: severities[severity.Name], pos: fset.Position(call.Pos()).String()})
+			return true
+		})
+	}
+	require.NotEmpty(t, adds)
+
+	return codes, adds
+}
@@ -0,0 +1,215 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagConfig = "config"
+	formatTOML = "toml"
+)
+
+// migrateConfig is the content of a migrate --config file: the values of
+// migrate flags by flag name, e.g. chain-id = "cosmoshub-4" in TOML.
+type migrateConfig map[string]interface{}
+
+// loadMigrateConfig reads a --config file, TOML or JSON by its extension.
+func loadMigrateConfig(path string) (migrateConfig, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config")
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case "." + formatTOML:
+		tree, err := toml.LoadBytes(bz)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid TOML config %s", path)
+		}
+		return tree.ToMap(), nil
+
+	case "." + formatJSON:
+		var config migrateConfig
+		decoder := json.NewDecoder(bytes.NewReader(bz))
+		decoder.UseNumber()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, errors.Wrapf(err, "invalid JSON config %s", path)
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, fmt.Errorf("invalid JSON config %s: unexpected data after the JSON object", path)
+		}
+		return config, nil
+
+	default:
+		return nil, fmt.Errorf("config %s is neither .%s nor .%s", path, formatTOML, formatJSON)
+	}
+}
+
+// configurableFlag returns the flag of fs a config key may set: the migrate
+// flags other than --config itself and the flags of the parent commands.
+func configurableFlag(cmd *cobra.Command, fs *pflag.FlagSet, name string) *pflag.Flag {
+	if name == flagConfig || name == "help" || cmd.InheritedFlags().Lookup(name) != nil {
+		return nil
+	}
+	return fs.Lookup(name)
+}
+
+// applyMigrateConfig sets the flags of cmd from the --config file, if any.
+// A flag given on the command line overrides the config. Every key must name
+// a migrate flag and its value must parse as one, the errors name the key.
+func applyMigrateConfig(cmd *cobra.Command) error {
+	fs := cmd.Flags()
+	path, _ := fs.GetString(flagConfig)
+	if path == "" {
+		return nil
+	}
+
+	config, err := loadMigrateConfig(path)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := configurableFlag(cmd, fs, key)
+		if flag == nil {
+			return fmt.Errorf("unknown key %q in config %s, not a migrate flag", key, path)
+		}
+		if flag.Changed {
+			continue
+		}
+
+		if err := setConfigFlag(fs, flag, config[key]); err != nil {
+			return errors.Wrapf(err, "invalid config key %q", key)
+		}
+	}
+
+	return nil
+}
+
+// setConfigFlag sets flag of fs to a config value, an array setting a flag
+// that may be given several times once per element.
+func setConfigFlag(fs *pflag.FlagSet, flag *pflag.Flag, value interface{}) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	} else if _, ok := flag.Value.(pflag.SliceValue); !ok {
+		return fmt.Errorf("--%s takes a single value, not an array", flag.Name)
+	}
+
+	for _, v := range values {
+		s, err := configScalar(v)
+		if err != nil {
+			return err
+		}
+		if err := fs.Set(flag.Name, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configScalar returns the flag value of a scalar config value.
+func configScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case nil:
+		return "", fmt.Errorf("null is not a flag value")
+	default:
+		return "", fmt.Errorf("a %T is not a flag value", value)
+	}
+}
+
+// migratePrintConfigCmd returns the migrate print-config command, resolving
+// the migrate flags fs with its --config file like migrate does.
+func migratePrintConfigCmd(fs *pflag.FlagSet) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "print-config",
+		Short: "Print the migrate options resolved from --config and the flags",
+		Long: fmt.Sprintf(`Print the migrate options set by the --config file and the flags, the flags
+overriding the config, as a config file to STDOUT, in TOML or with --format
+json. The options left out have their defaults. The output read back with
+--config resolves to the same options, so it can be reviewed and kept with
+the manifest of the migration.
+
+Example:
+$ %s migrate print-config --config launch.toml --chain-id cosmoshub-4
+`, version.AppName),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := applyMigrateConfig(cmd); err != nil {
+				return err
+			}
+			opts, err := migrateOptionsFromFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			config := opts.Config()
+
+			var bz []byte
+			switch format, _ := cmd.Flags().GetString(flagFormat); format {
+			case formatTOML:
+				tree, err := toml.TreeFromMap(config)
+				if err != nil {
+					return err
+				}
+				s, err := tree.ToTomlString()
+				if err != nil {
+					return err
+				}
+				bz = []byte(s)
+
+			case formatJSON:
+				if bz, err = json.MarshalIndent(config, "", "  "); err != nil {
+					return err
+				}
+				bz = append(bz, '\n')
+
+			default:
+				return fmt.Errorf("unknown --%s %q, must be %s or %s", flagFormat, format, formatTOML, formatJSON)
+			}
+
+			_, err = cmd.OutOrStdout().Write(bz)
+			return err
+		},
+	}
+
+	cmd.Flags().AddFlagSet(fs)
+	cmd.Flags().String(flagFormat, formatTOML, "Format of the printed config, toml or json")
+
+	return cmd
+}
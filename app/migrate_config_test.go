@@ -0,0 +1,117 @@
+package gaia
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// executePrintConfig runs migrate print-config with args and returns its
+// output.
+func executePrintConfig(t *testing.T, args ...string) (string, error) {
+	cmd := MigrateGenesisCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs(append([]string{"print-config"}, args...))
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestMigratePrintConfig(t *testing.T) {
+	config := writeConfig(t, "launch.toml", `
+legacy-source = "cosmoshub-2"
+no-prop-29 = true
+chain-id = "cosmoshub-4"
+genesis-time = 2021-02-18T06:00:00Z
+accounts-checkpoint-interval = 5000
+mint-blocks-per-year = 4360000
+timeout = "30m"
+warnings-as-errors = ["W-IBC-*", "W-AUTH-004"]
+`)
+
+	out, err := executePrintConfig(t, "--config", config)
+	require.NoError(t, err)
+	require.Equal(t, `accounts-checkpoint-interval = 5000
+chain-id = "cosmoshub-4"
+genesis-time = "2021-02-18T06:00:00Z"
+legacy-source = "cosmoshub-2"
+mint-blocks-per-year = 4360000
+no-prop-29 = true
+timeout = "30m0s"
+warnings-as-errors = ["W-IBC-*", "W-AUTH-004"]
+`, out)
+
+	// the flags override the config
+	overridden, err := executePrintConfig(t, "--config", config, "--chain-id", "cosmoshub-rehearsal", "--format", "json")
+	require.NoError(t, err)
+	require.Equal(t, `{
+  "accounts-checkpoint-interval": 5000,
+  "chain-id": "cosmoshub-rehearsal",
+  "genesis-time": "2021-02-18T06:00:00Z",
+  "legacy-source": "cosmoshub-2",
+  "mint-blocks-per-year": 4360000,
+  "no-prop-29": true,
+  "timeout": "30m0s",
+  "warnings-as-errors": [
+    "W-IBC-*",
+    "W-AUTH-004"
+  ]
+}
+`, overridden)
+
+	// the output read back resolves to the same options
+	again, err := executePrintConfig(t, "--config", writeConfig(t, "printed.toml", out))
+	require.NoError(t, err)
+	require.Equal(t, out, again)
+
+	again, err = executePrintConfig(t, "--config", writeConfig(t, "printed.json", overridden), "--format", "json")
+	require.NoError(t, err)
+	require.Equal(t, overridden, again)
+}
+
+func TestMigrateConfigErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		file, content, err string
+	}{
+		"unknown key": {"c.toml", "chain-id = \"cosmoshub-4\"\nchain_id = \"cosmoshub-4\"\n", `unknown key "chain_id" in config %s, not a migrate flag`},
+		"config key":  {"c.json", `{"config": "other.json"}`, `unknown key "config" in config %s, not a migrate flag`},
+		"table":       {"c.toml", "[chain-id]\nname = \"cosmoshub-4\"\n", `invalid config key "chain-id": a map[string]interface {} is not a flag value`},
+		"bool":        {"c.toml", "no-prop-29 = \"yes\"\n", `invalid config key "no-prop-29": invalid argument "yes" for "--no-prop-29" flag: strconv.ParseBool: parsing "yes": invalid syntax`},
+		"int":         {"c.json", `{"accounts-checkpoint-interval": 1.5}`, `invalid config key "accounts-checkpoint-interval": invalid argument "1.5" for "--accounts-checkpoint-interval" flag: strconv.ParseInt: parsing "1.5": invalid syntax`},
+		"array":       {"c.json", `{"chain-id": ["a", "b"]}`, `invalid config key "chain-id": --chain-id takes a single value, not an array`},
+		"trailing":    {"c.json", `{"chain-id": "a"} {}`, `invalid JSON config %s: unexpected data after the JSON object`},
+		"extension":   {"c.yaml", "chain-id: a\n", `config %s is neither .toml nor .json`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfig(t, tc.file, tc.content)
+			_, err := executePrintConfig(t, "--config", path)
+			require.EqualError(t, err, strings.ReplaceAll(tc.err, "%s", path))
+		})
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	want, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+
+	config := writeConfig(t, "launch.toml", "legacy-source = \"cosmoshub-2\"\nno-prop-29 = true\nchain-id = \"test\"\n")
+	got, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--config", config, "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--config", writeConfig(t, "bad.toml", "prop-29 = false\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown key "prop-29"`)
+}
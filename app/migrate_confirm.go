@@ -0,0 +1,396 @@
+package gaia
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// stateChangeOptions are the migrate options materially altering the migrated
+// state. They are parsed from the flags before the migration starts and the
+// migration applies them from here, so the summary confirmed before it runs
+// is what it does.
+type stateChangeOptions struct {
+	Prop29Data      string
+	Prop29          []recoveryEntry
+	Prop29Claims    string
+	BlockedSource   string
+	Blocked         []string
+	Blocklist       blocklistOptions
+	Prune           *pruneOptions
+	SweepInactive   *inactiveSweepOptions
+	AirdropSource   string
+	Airdrop         *airdropFormula
+	SweepDustTo     string
+	TopUpGovFrom    string
+	RoundingDustTo  string
+	DropUnmappable  bool
+	WithdrawRewards bool
+	Funding         *communityPoolFunding
+	ReplacementKeys string
+	ShiftAllTimes   bool
+	SyncValidators  bool
+	StripDupKeys    bool
+	TruncateStrings bool
+	NormalizeNFC    bool
+	DropEvidence    bool
+	ClearPubKeys    bool
+	RemapSource     string
+	RemapChainIDs   map[string]string
+	PowerCapPercent string
+	PowerCap        *sdk.Dec
+	Protected       *protectedAddresses
+	// Skipped are the flags of the state changes --force-remigrate leaves
+	// out, which a migrated genesis may have had applied already.
+	Skipped []string
+}
+
+// stateChangeOptionsOf parses and loads the state-altering options of the
+// migrate options.
+func stateChangeOptionsOf(migrate *MigrateOptions) (stateChangeOptions, error) {
+	var opts stateChangeOptions
+	var err error
+
+	if migrate.ProtectedAddrs != "" {
+		if opts.Protected, err = loadProtectedAddresses(migrate.ProtectedAddrs, migrate.FailOnProtected); err != nil {
+			return opts, err
+		}
+	} else if migrate.FailOnProtected {
+		return opts, fmt.Errorf("--%s needs --%s", flagFailOnProtected, flagProtectedAddrs)
+	}
+
+	// the state changes applied twice to a genesis migrated twice are left
+	// out of a forced migration, unless applied again on purpose
+	if migrate.ReapplyStateChanges && !migrate.ForceRemigrate {
+		return opts, fmt.Errorf("--%s needs --%s", flagReapplyStateChanges, flagForceRemigrate)
+	}
+	skip := func(flag, value string) bool {
+		if value == "" || !migrate.ForceRemigrate || migrate.ReapplyStateChanges {
+			return false
+		}
+
+		opts.Skipped = append(opts.Skipped, flag)
+		return true
+	}
+
+	if opts.Prop29Data = migrate.Prop29Data; opts.Prop29Data != "" && !migrate.NoProp29 && !skip(flagProp29Data, opts.Prop29Data) {
+		claims := ""
+		if opts.Prop29Claims = migrate.Prop29Claims; opts.Prop29Claims != "" {
+			if claims, err = prop29ClaimsAddress(opts.Prop29Claims); err != nil {
+				return opts, err
+			}
+		}
+
+		if opts.Prop29, err = loadRecoveryEntries(opts.Prop29Data, claims); err != nil {
+			return opts, err
+		}
+		if opts.Prop29, err = opts.Protected.filterRecoveries(opts.Prop29); err != nil {
+			return opts, err
+		}
+	}
+
+	if funding := migrate.FundCommunityPool; funding != "" && !skip(flagFundCommunityPool, funding) {
+		if opts.Funding, err = parseCommunityPoolFunding(funding, migrate.FundFrom); err != nil {
+			return opts, err
+		}
+	} else if funding == "" && migrate.FundFrom != "" {
+		return opts, fmt.Errorf("--%s needs --%s", flagFundFrom, flagFundCommunityPool)
+	}
+
+	if opts.BlockedSource = migrate.BlockedAddresses; opts.BlockedSource != "" {
+		if opts.Blocked, err = loadBlockedAddresses(opts.BlockedSource); err != nil {
+			return opts, err
+		}
+		if opts.Blocked, err = opts.Protected.filter(flagBlockedAddresses, opts.Blocked); err != nil {
+			return opts, err
+		}
+
+		opts.Blocklist.Destination = migrate.BlockedDest
+		opts.Blocklist.AccountAction = migrate.BlockedAccount
+	}
+
+	if migrate.PruneBelow != "" || migrate.KeepTopAccounts > 0 {
+		opts.Prune = &pruneOptions{KeepTop: migrate.KeepTopAccounts, Protected: opts.Protected}
+
+		if migrate.PruneBelow != "" {
+			coin, err := sdk.ParseCoinNormalized(migrate.PruneBelow)
+			if err != nil {
+				return opts, errors.Wrapf(err, "failed to parse --%s", flagPruneBelow)
+			}
+
+			opts.Prune.Below = &coin
+		}
+
+		if opts.Prune.Sink, err = sdk.AccAddressFromBech32(migrate.PruneSink); err != nil {
+			return opts, errors.Wrapf(err, "invalid --%s", flagPruneSink)
+		}
+	}
+
+	if migrate.SweepInactiveTo != "" {
+		opts.SweepInactive = &inactiveSweepOptions{Protected: opts.Protected}
+		if opts.SweepInactive.Destination, err = sdk.AccAddressFromBech32(migrate.SweepInactiveTo); err != nil {
+			return opts, errors.Wrapf(err, "invalid --%s", flagSweepInactiveTo)
+		}
+
+		if opts.SweepInactive.Below, err = sdk.ParseCoinNormalized(migrate.InactiveBelow); err != nil {
+			return opts, errors.Wrapf(err, "failed to parse --%s", flagInactiveBelow)
+		}
+	}
+
+	if opts.AirdropSource = migrate.Airdrop; opts.AirdropSource != "" && !skip(flagAirdrop, opts.AirdropSource) {
+		formula, err := loadAirdropFormula(opts.AirdropSource)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.Airdrop = &formula
+	}
+
+	if opts.SweepDustTo = migrate.SweepModuleDust; opts.SweepDustTo != "" && opts.SweepDustTo != blockedCommunityPool {
+		if _, err := sdk.AccAddressFromBech32(opts.SweepDustTo); err != nil {
+			return opts, errors.Wrapf(err, "invalid --%s", flagSweepModuleDust)
+		}
+	}
+
+	if opts.TopUpGovFrom = migrate.TopUpGovAccount; opts.TopUpGovFrom != "" {
+		addr, err := sdk.AccAddressFromBech32(opts.TopUpGovFrom)
+		if err != nil {
+			return opts, errors.Wrapf(err, "invalid --%s", flagTopUpGovAccount)
+		}
+		if name, ok := moduleAccountName(addr); ok {
+			return opts, fmt.Errorf("--%s %s is the %s module account", flagTopUpGovAccount, opts.TopUpGovFrom, name)
+		}
+		opts.TopUpGovFrom = addr.String()
+	}
+
+	if opts.RoundingDustTo = migrate.RoundingDustTo; opts.RoundingDustTo != blockedCommunityPool {
+		if _, err := sdk.AccAddressFromBech32(opts.RoundingDustTo); err != nil {
+			return opts, errors.Wrapf(err, "invalid --%s", flagRoundingDustTo)
+		}
+	}
+
+	if opts.RemapSource = migrate.RemapChainIDs; opts.RemapSource != "" {
+		if opts.RemapChainIDs, err = loadChainIDMapping(opts.RemapSource); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.PowerCapPercent = migrate.CapValidatorPower; opts.PowerCapPercent != "" {
+		capRatio, err := parsePowerCap(opts.PowerCapPercent)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.PowerCap = &capRatio
+	}
+
+	opts.DropUnmappable = migrate.DropUnmappable
+	opts.WithdrawRewards = migrate.WithdrawRewards
+	opts.ReplacementKeys = migrate.ReplacementKeys
+	opts.ShiftAllTimes = migrate.ShiftAllTimes
+	opts.SyncValidators = migrate.SyncTmValidators
+	opts.StripDupKeys = migrate.StripDupConsKeys
+	opts.TruncateStrings = migrate.TruncateStrings
+	opts.NormalizeNFC = migrate.NormalizeUnicode
+	opts.DropEvidence = migrate.DropStaleEvidence
+	opts.ClearPubKeys = migrate.ClearPubKeys
+
+	return opts, nil
+}
+
+// Summary describes every state change in effect, one per line.
+func (opts stateChangeOptions) Summary() []string {
+	var lines []string
+
+	if opts.Prop29 != nil {
+		line := fmt.Sprintf("--%s: apply %d prop29 recovery entries from %s", flagProp29Data, len(opts.Prop29), opts.Prop29Data)
+		if hasRecoveryVesting(opts.Prop29) {
+			line += ", vesting some to their recipients"
+		}
+		if claims := newProp29Claims(opts.Prop29Claims, opts.Prop29); len(claims.Entries) > 0 {
+			line += fmt.Sprintf(", diverting the %d of invalid recipients to %s", len(claims.Entries), claims.ClaimsAccount)
+			if claims.ModuleAccount != "" {
+				line += fmt.Sprintf(" (module account %s)", claims.ModuleAccount)
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	if opts.WithdrawRewards {
+		lines = append(lines, fmt.Sprintf("--%s: pay out every pending delegation reward and validator commission to the withdraw addresses, leaving the dust to the community pool and starting the reward periods over", flagWithdrawAllRewards))
+	}
+
+	if opts.Funding != nil {
+		lines = append(lines, fmt.Sprintf("--%s: move %s from the balance of %s to the community pool", flagFundCommunityPool, opts.Funding.Amount, opts.Funding.From))
+	}
+
+	if opts.BlockedSource != "" {
+		lines = append(lines, fmt.Sprintf("--%s: move the funds of the addresses listed in %s (%d) to %s and %s their accounts",
+			flagBlockedAddresses, opts.BlockedSource, len(opts.Blocked), opts.Blocklist.Destination, opts.Blocklist.AccountAction))
+	}
+
+	if opts.Prune != nil {
+		var criteria []string
+		if opts.Prune.Below != nil {
+			criteria = append(criteria, fmt.Sprintf("holding less than %s", opts.Prune.Below))
+		}
+		if opts.Prune.KeepTop > 0 {
+			criteria = append(criteria, fmt.Sprintf("outside the top %d", opts.Prune.KeepTop))
+		}
+
+		lines = append(lines, fmt.Sprintf("--%s: prune the accounts %s, handing what they own to %s",
+			flagPruneBelow, strings.Join(criteria, " or "), opts.Prune.Sink))
+	}
+
+	if opts.SweepInactive != nil {
+		lines = append(lines, fmt.Sprintf("--%s: remove the accounts that never signed a transaction, hold less than %s and do not stake, moving their balances to %s",
+			flagSweepInactiveTo, opts.SweepInactive.Below, opts.SweepInactive.Destination))
+	}
+
+	if opts.Airdrop != nil {
+		source := opts.Airdrop.SourceDenom
+		if source == "" {
+			source = "bond denom"
+		}
+
+		grant := fmt.Sprintf("%s per %s", opts.Airdrop.Ratio, source)
+		if opts.Airdrop.Amount != nil {
+			grant = fmt.Sprintf("%s to every %s holder", opts.Airdrop.Amount, source)
+		}
+
+		lines = append(lines, fmt.Sprintf("--%s: mint %s %s from %s, excluding %d addresses",
+			flagAirdrop, opts.Airdrop.Denom, grant, opts.AirdropSource, len(opts.Airdrop.Exclude)))
+
+		if opts.Airdrop.Ratio != nil {
+			lines = append(lines, fmt.Sprintf("--%s: mint the fractions of %s the airdrop grants round down to %s", flagRoundingDustTo, opts.Airdrop.Denom, opts.RoundingDustTo))
+		}
+	}
+
+	if opts.SweepDustTo != "" {
+		lines = append(lines, fmt.Sprintf("--%s: move what the module accounts hold beyond their module genesis to %s", flagSweepModuleDust, opts.SweepDustTo))
+	}
+
+	if opts.TopUpGovFrom != "" {
+		lines = append(lines, fmt.Sprintf("--%s: cover what the gov module account lacks of the deposits of the active proposals from the balance of %s", flagTopUpGovAccount, opts.TopUpGovFrom))
+	}
+
+	if opts.DropUnmappable {
+		lines = append(lines, fmt.Sprintf("--%s: remove the proposals whose content type cannot be migrated, with their votes", flagDropUnmappable))
+	}
+
+	if opts.ReplacementKeys != "" {
+		lines = append(lines, fmt.Sprintf("--%s: replace validator consensus keys from %s", flagReplacementKeys, opts.ReplacementKeys))
+	}
+
+	if opts.ShiftAllTimes {
+		lines = append(lines, fmt.Sprintf("--%s: shift the staking, gov, slashing and evidence timestamps by the genesis time change", flagShiftAllTimes))
+	}
+
+	if opts.SyncValidators {
+		lines = append(lines, fmt.Sprintf("--%s: regenerate the tendermint validators from the staking bonded set", flagSyncTmValidators))
+	}
+
+	if opts.StripDupKeys {
+		lines = append(lines, fmt.Sprintf("--%s: replace consensus keys shared with another validator by unusable keys on the validators not bonded", flagStripDupConsKeys))
+	}
+
+	if opts.TruncateStrings {
+		lines = append(lines, fmt.Sprintf("--%s: cut the validator descriptions and the contents of proposals in deposit or voting period to the staking and gov limits", flagTruncateLongStrings))
+	}
+
+	if opts.NormalizeNFC {
+		lines = append(lines, fmt.Sprintf("--%s: normalize the validator descriptions and proposal titles and descriptions to Unicode NFC", flagNormalizeUnicode))
+	}
+
+	if opts.DropEvidence {
+		lines = append(lines, fmt.Sprintf("--%s: remove the equivocations naming no validator from the evidence genesis", flagDropStaleEvidence))
+	}
+
+	if opts.ClearPubKeys {
+		lines = append(lines, fmt.Sprintf("--%s: set the account pubkeys that fail to parse or do not match their address to null", flagClearInvalidPubKeys))
+	}
+
+	if opts.RemapChainIDs != nil {
+		lines = append(lines, fmt.Sprintf("--%s: rewrite the counterparty chain IDs of the IBC tendermint clients from %s: %s",
+			flagRemapChainIDs, opts.RemapSource, chainIDMappingSummary(opts.RemapChainIDs)))
+	}
+
+	if opts.PowerCap != nil {
+		lines = append(lines, fmt.Sprintf("--%s: reduce the delegations of the validators above %s%% of the bonded power in proportion, returning the excess to their delegators, and jail those whose self-delegation would fall below its min_self_delegation",
+			flagCapValidatorPower, opts.PowerCapPercent))
+	}
+
+	if len(opts.Skipped) > 0 {
+		skipped := make([]string, len(opts.Skipped))
+		for i, flag := range opts.Skipped {
+			skipped[i] = "--" + flag
+		}
+		lines = append(lines, fmt.Sprintf("--%s: skip %s, which the migrated source may have applied already, --%s applies them again",
+			flagForceRemigrate, strings.Join(skipped, " and "), flagReapplyStateChanges))
+	}
+
+	if opts.Protected != nil && len(lines) > 0 {
+		conflict := "skipping"
+		if opts.Protected.Strict {
+			conflict = "failing on"
+		}
+		lines = append(lines, fmt.Sprintf("--%s: leave the %d addresses listed in %s and the module accounts untouched, %s conflicts",
+			flagProtectedAddrs, opts.Protected.listed, opts.Protected.Source, conflict))
+	}
+
+	return lines
+}
+
+// migrateIsInteractive tells whether the migrate command runs in a terminal,
+// where the state changes must be confirmed. The genesis usually goes to a
+// redirected stdout, so the prompt is written to stderr and read from stdin.
+var migrateIsInteractive = func(cmd *cobra.Command) bool {
+	return isTerminal(cmd.InOrStdin()) && isTerminal(cmd.ErrOrStderr())
+}
+
+func isTerminal(v interface{}) bool {
+	f, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmStateChanges prints the summary of opts on stderr and, in a
+// terminal unless skip, as with --yes, requires typing "yes" to proceed.
+func confirmStateChanges(cmd *cobra.Command, opts stateChangeOptions, skip bool) error {
+	summary := opts.Summary()
+	if len(summary) == 0 {
+		return nil
+	}
+
+	cmd.PrintErrln("the migration applies these state changes:")
+	for _, line := range summary {
+		cmd.PrintErrf("  %s\n", line)
+	}
+
+	if skip || !migrateIsInteractive(cmd) {
+		return nil
+	}
+
+	cmd.PrintErr(`type "yes" to proceed: `)
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "failed to read confirmation")
+	}
+
+	if strings.TrimSpace(answer) != "yes" {
+		return fmt.Errorf("migration aborted, the state changes were not confirmed")
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateChangeOptionsSummary(t *testing.T) {
+	blocked := filepath.Join(t.TempDir(), "blocked.json")
+	require.NoError(t, ioutil.WriteFile(blocked, []byte(`["cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"]`), 0644))
+
+	sink := genesisbuilder.New().Address("sink").String()
+
+	remap := filepath.Join(t.TempDir(), "chain-ids.json")
+	require.NoError(t, ioutil.WriteFile(remap, []byte(`{"osmosis-1": "osmosis-rehearsal-1", "juno-1": "juno-rehearsal-1"}`), 0644))
+
+	opts, err := stateChangeOptionsOf(parseMigrateOptions(t,
+		"--"+flagBlockedAddresses, blocked,
+		"--"+flagPruneBelow, "1000uatom", "--"+flagKeepTopAccounts, "10", "--"+flagPruneSink, sink,
+		"--"+flagSweepInactiveTo, sink,
+		"--"+flagSweepModuleDust, blockedCommunityPool,
+		"--"+flagTopUpGovAccount, sink,
+		"--"+flagDropUnmappable,
+		"--"+flagShiftAllTimes,
+		"--"+flagTruncateLongStrings,
+		"--"+flagNormalizeUnicode,
+		"--"+flagDropStaleEvidence,
+		"--"+flagClearInvalidPubKeys,
+		"--"+flagRemapChainIDs, remap,
+		"--"+flagCapValidatorPower, "33.3",
+		"--"+flagWithdrawAllRewards,
+		"--"+flagFundCommunityPool, "1000000uatom", "--"+flagFundFrom, sink,
+	))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"--withdraw-all-rewards: pay out every pending delegation reward and validator commission to the withdraw addresses, leaving the dust to the community pool and starting the reward periods over",
+		"--fund-community-pool: move 1000000uatom from the balance of " + sink + " to the community pool",
+		"--blocked-addresses: move the funds of the addresses listed in " + blocked + " (1) to community-pool and remove their accounts",
+		"--prune-accounts-below: prune the accounts holding less than 1000uatom or outside the top 10, handing what they own to " + sink,
+		"--sweep-inactive-to: remove the accounts that never signed a transaction, hold less than 1000000uatom and do not stake, moving their balances to " + sink,
+		"--sweep-module-dust: move what the module accounts hold beyond their module genesis to community-pool",
+		"--top-up-gov-account: cover what the gov module account lacks of the deposits of the active proposals from the balance of " + sink,
+		"--drop-unmappable-proposals: remove the proposals whose content type cannot be migrated, with their votes",
+		"--shift-all-times: shift the staking, gov, slashing and evidence timestamps by the genesis time change",
+		"--truncate-long-strings: cut the validator descriptions and the contents of proposals in deposit or voting period to the staking and gov limits",
+		"--normalize-unicode-nfc: normalize the validator descriptions and proposal titles and descriptions to Unicode NFC",
+		"--drop-stale-evidence: remove the equivocations naming no validator from the evidence genesis",
+		"--clear-invalid-pubkeys: set the account pubkeys that fail to parse or do not match their address to null",
+		"--remap-counterparty-chain-ids: rewrite the counterparty chain IDs of the IBC tendermint clients from " + remap + ": juno-1 to juno-rehearsal-1, osmosis-1 to osmosis-rehearsal-1",
+		"--cap-validator-power: reduce the delegations of the validators above 33.3% of the bonded power in proportion, returning the excess to their delegators, and jail those whose self-delegation would fall below its min_self_delegation",
+	}, opts.Summary())
+
+	_, err = stateChangeOptionsOf(parseMigrateOptions(t, "--"+flagPruneBelow, "1000uatom"))
+	require.Error(t, err, "pruning needs a sink")
+}
+
+func TestConfirmStateChanges(t *testing.T) {
+	opts := stateChangeOptions{ShiftAllTimes: true}
+
+	run := func(interactive bool, stdin string, args ...string) (string, error) {
+		defer func(isInteractive func(*cobra.Command) bool) { migrateIsInteractive = isInteractive }(migrateIsInteractive)
+		migrateIsInteractive = func(*cobra.Command) bool { return interactive }
+
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolP(flags.FlagSkipConfirmation, "y", false, "")
+		require.NoError(t, cmd.ParseFlags(args))
+
+		var stderr bytes.Buffer
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetErr(&stderr)
+
+		skip, _ := cmd.Flags().GetBool(flags.FlagSkipConfirmation)
+		err := confirmStateChanges(cmd, opts, skip)
+		return stderr.String(), err
+	}
+
+	out, err := run(true, "yes\n")
+	require.NoError(t, err)
+	require.Contains(t, out, "--shift-all-times")
+	require.Contains(t, out, `type "yes" to proceed`)
+
+	_, err = run(true, "y\n")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "migration aborted")
+
+	_, err = run(true, "")
+	require.Error(t, err)
+
+	out, err = run(true, "", "--yes")
+	require.NoError(t, err)
+	require.Contains(t, out, "--shift-all-times")
+	require.NotContains(t, out, "proceed")
+
+	out, err = run(false, "")
+	require.NoError(t, err)
+	require.Contains(t, out, "--shift-all-times")
+	require.NotContains(t, out, "proceed")
+
+	opts = stateChangeOptions{}
+	out, err = run(true, "")
+	require.NoError(t, err)
+	require.Empty(t, out)
+}
+
+func TestMigrateNonInteractiveSummary(t *testing.T) {
+	encodingConfig := MakeEncodingConfig()
+	clientCtx := client.Context{}.
+		WithJSONMarshaler(encodingConfig.Marshaler).
+		WithInterfaceRegistry(encodingConfig.InterfaceRegistry).
+		WithTxConfig(encodingConfig.TxConfig).
+		WithLegacyAmino(encodingConfig.Amino)
+
+	cmd := MigrateGenesisCmd()
+	var stderr bytes.Buffer
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(&stderr)
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetArgs([]string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--sync-tm-validators"})
+
+	require.NoError(t, cmd.ExecuteContext(context.WithValue(context.Background(), client.ClientContextKey, &clientCtx)))
+	require.Contains(t, stderr.String(), "the migration applies these state changes:\n  --sync-tm-validators")
+}
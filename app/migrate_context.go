@@ -0,0 +1,43 @@
+package gaia
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/client"
+)
+
+// migrateTypeURLs are types the migration callbacks unpack from the migrated
+// state, so the interface registry of a client context must resolve them.
+var migrateTypeURLs = []string{
+	"/cosmos.auth.v1beta1.BaseAccount",
+	"/cosmos.auth.v1beta1.ModuleAccount",
+	"/cosmos.vesting.v1beta1.ContinuousVestingAccount",
+	"/cosmos.crypto.secp256k1.PubKey",
+	"/cosmos.crypto.ed25519.PubKey",
+	"/cosmos.gov.v1beta1.TextProposal",
+}
+
+// migrateContextHint tells how to give the migrate command its codecs.
+const migrateContextHint = "set the client context of the command from gaia.MakeEncodingConfig(), or build it with gaia.NewMigrateGenesisCmd"
+
+// validateMigrateClientContext checks that clientCtx carries the codecs the
+// migration callbacks use, so a command embedded without them fails before
+// the callbacks do.
+func validateMigrateClientContext(clientCtx client.Context) error {
+	switch {
+	case clientCtx.JSONMarshaler == nil:
+		return fmt.Errorf("client context has no JSON codec, %s", migrateContextHint)
+	case clientCtx.InterfaceRegistry == nil:
+		return fmt.Errorf("client context has no interface registry, %s", migrateContextHint)
+	case clientCtx.LegacyAmino == nil:
+		return fmt.Errorf("client context has no legacy amino codec, %s", migrateContextHint)
+	}
+
+	for _, typeURL := range migrateTypeURLs {
+		if _, err := clientCtx.InterfaceRegistry.Resolve(typeURL); err != nil {
+			return fmt.Errorf("client context interface registry does not register %s, %s", typeURL, migrateContextHint)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package gaia
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/gaia/v5/app/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateClientContext(t *testing.T) {
+	bare := params.MakeEncodingConfig()
+
+	testCases := []struct {
+		name      string
+		clientCtx client.Context
+		err       string
+	}{
+		{"empty context", client.Context{}, "client context has no JSON codec"},
+		{
+			"no legacy amino",
+			client.Context{}.WithJSONMarshaler(bare.Marshaler).WithInterfaceRegistry(bare.InterfaceRegistry),
+			"client context has no legacy amino codec",
+		},
+		{
+			"unregistered interfaces",
+			client.Context{}.WithJSONMarshaler(bare.Marshaler).WithInterfaceRegistry(bare.InterfaceRegistry).WithLegacyAmino(bare.Amino),
+			"interface registry does not register /cosmos.auth.v1beta1.BaseAccount",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := MigrateGenesisCmd()
+			cmd.SetOut(ioutil.Discard)
+			cmd.SetErr(ioutil.Discard)
+			cmd.SetArgs([]string{"testdata/cosmoshub-2-genesis.json"})
+
+			err := cmd.ExecuteContext(context.WithValue(context.Background(), client.ClientContextKey, &tc.clientCtx))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+			require.Contains(t, err.Error(), "gaia.NewMigrateGenesisCmd")
+		})
+	}
+}
+
+func TestNewMigrateGenesisCmd(t *testing.T) {
+	cmd := NewMigrateGenesisCmd(MakeEncodingConfig())
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs([]string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"})
+
+	// without any client context on the command
+	require.NoError(t, cmd.Execute())
+}
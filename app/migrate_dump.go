@@ -0,0 +1,234 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+)
+
+const flagDebugDumpDir = "debug-dump-dir"
+
+// excerptRadius is the number of records kept on each side of the record
+// failing to migrate in the excerpt of a dump.
+const excerptRadius = 3
+
+// moduleDump is where the genesis of the module a panicked migration failed
+// on, as the migration started from it, and, when the record failing is
+// found, the excerpt around it are written.
+type moduleDump struct {
+	Module      string
+	Path        string
+	ExcerptPath string
+	Excerpt     *moduleExcerpt
+}
+
+// moduleExcerpt are the records of a module genesis around the one failing
+// to migrate, record Index of its top-level array Field.
+type moduleExcerpt struct {
+	Module  string            `json:"module"`
+	Field   string            `json:"field"`
+	Index   int               `json:"index"`
+	First   int               `json:"first"`
+	Records []json.RawMessage `json:"records"`
+}
+
+// dumpFailingModule dumps the genesis of the module a migration panicked at
+// when *err is a MigrationPanicError, and records the dump in it. A panic of
+// an SDK migration outside the modules is isolated by running it again on
+// every module alone. It is deferred before recoverMigrationPanic so that it
+// runs after it.
+func dumpFailingModule(pos *migrationPosition, err *error) {
+	var panicErr *MigrationPanicError
+	if !pos.dump || pos.input == nil || !errors.As(*err, &panicErr) {
+		return
+	}
+
+	var fails func(types.AppMap) bool
+	if pos.rerun != nil {
+		fails = func(state types.AppMap) (failed bool) {
+			defer func() {
+				if r := recover(); r != nil {
+					failed = true
+				}
+			}()
+
+			pos.rerun(state)
+			return false
+		}
+	}
+
+	module := panicErr.Module
+	var excerpt *moduleExcerpt
+	switch {
+	case fails == nil:
+	case module == "":
+		module, excerpt = isolateFailingModule(pos.input, fails)
+	default:
+		excerpt = findFailingRecord(module, pos.input[module], fails)
+	}
+
+	raw := pos.input[module]
+	if raw == nil {
+		return
+	}
+
+	name := pos.version
+	if name == "" {
+		name = pos.stage
+	}
+
+	dump, dumpErr := writeModuleDump(pos.files, pos.dumpDir, module, name, raw, excerpt)
+	if dumpErr != nil {
+		*err = fmt.Errorf("%w\nfailed to dump the %s genesis: %s", panicErr, module, dumpErr)
+		return
+	}
+
+	panicErr.Dump = dump
+}
+
+// writeModuleDump writes the raw genesis of module, which the migration name
+// failed on, and excerpt if any to dir, a new temporary directory when empty.
+func writeModuleDump(files outputFiles, dir, module, name string, raw json.RawMessage, excerpt *moduleExcerpt) (*moduleDump, error) {
+	var err error
+	if dir == "" {
+		if dir, err = ioutil.TempDir("", "gaiad-migrate-dump-"); err != nil {
+			return nil, err
+		}
+	} else if err = files.prepareDir(dir); err != nil {
+		return nil, err
+	}
+
+	dump := &moduleDump{Module: module, Path: filepath.Join(dir, fmt.Sprintf("%s-%s.json", module, name))}
+	if err := files.WriteFile(dump.Path, raw, outputFileMode); err != nil {
+		return nil, err
+	}
+
+	if excerpt == nil {
+		return dump, nil
+	}
+
+	bz, err := json.MarshalIndent(excerpt, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	dump.Excerpt, dump.ExcerptPath = excerpt, filepath.Join(dir, fmt.Sprintf("%s-%s-excerpt.json", module, name))
+	if err := files.WriteFile(dump.ExcerptPath, bz, outputFileMode); err != nil {
+		return nil, err
+	}
+
+	return dump, nil
+}
+
+// isolateFailingModule returns the module of state whose migration alone
+// fails on one of its records, with the records around it. Without one, it is
+// the only module failing alone, if any: modules failing alone may only miss
+// the others they are migrated with.
+func isolateFailingModule(state types.AppMap, fails func(types.AppMap) bool) (string, *moduleExcerpt) {
+	modules := make([]string, 0, len(state))
+	for module := range state {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	var failing []string
+	for _, module := range modules {
+		if !fails(types.AppMap{module: state[module]}) {
+			continue
+		}
+
+		if excerpt := findFailingRecord(module, state[module], fails); excerpt != nil {
+			return module, excerpt
+		}
+		failing = append(failing, module)
+	}
+
+	if len(failing) != 1 {
+		return "", nil
+	}
+
+	return failing[0], nil
+}
+
+// findFailingRecord returns the records around the first one of a top-level
+// array of the module genesis raw that fails to migrate, nil when raw does
+// not fail alone or still fails with its arrays empty. Every array is tried
+// with the others empty, by halving its records.
+func findFailingRecord(module string, raw json.RawMessage, fails func(types.AppMap) bool) *moduleExcerpt {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || !fails(types.AppMap{module: raw}) {
+		return nil
+	}
+
+	arrays := make(map[string][]json.RawMessage)
+	for field, value := range fields {
+		var records []json.RawMessage
+		if err := json.Unmarshal(value, &records); err == nil && len(records) > 0 {
+			arrays[field] = records
+		}
+	}
+
+	// probe fails with all arrays but field emptied and its first n records
+	probe := func(field string, n int) bool {
+		state := make(map[string]json.RawMessage, len(fields))
+		for name, value := range fields {
+			state[name] = value
+		}
+		for name, records := range arrays {
+			if name != field {
+				state[name] = json.RawMessage("[]")
+			} else {
+				state[name], _ = json.Marshal(records[:n])
+			}
+		}
+
+		bz, _ := json.Marshal(state)
+		return fails(types.AppMap{module: bz})
+	}
+
+	if len(arrays) == 0 || probe("", 0) {
+		return nil
+	}
+
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, field := range names {
+		records := arrays[field]
+
+		// the longest passing and the shortest failing prefixes
+		good, bad := 0, len(records)
+		if !probe(field, bad) {
+			continue
+		}
+		for bad-good > 1 {
+			mid := (good + bad) / 2
+			if probe(field, mid) {
+				bad = mid
+			} else {
+				good = mid
+			}
+		}
+
+		index := bad - 1
+		first, last := index-excerptRadius, index+excerptRadius+1
+		if first < 0 {
+			first = 0
+		}
+		if last > len(records) {
+			last = len(records)
+		}
+
+		return &moduleExcerpt{Module: module, Field: field, Index: index, First: first, Records: records[first:last]}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package gaia
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateDumpsFailingModule(t *testing.T) {
+	// the one delegation of the genesis repeated, with a malformed one
+	const delegations, malformed = 2000, 1234
+	source := writeMutatedGenesis(t, func(_, appState map[string]interface{}) {
+		staking := appState["staking"].(map[string]interface{})
+		delegation := staking["delegations"].([]interface{})[0].(map[string]interface{})
+
+		records := make([]interface{}, delegations)
+		for i := range records {
+			record := make(map[string]interface{}, len(delegation))
+			for k, v := range delegation {
+				record[k] = v
+			}
+			record["shares"] = fmt.Sprintf("%d.000000000000000000", i+1)
+			records[i] = record
+		}
+		records[malformed].(map[string]interface{})["shares"] = "not-a-dec"
+		staking["delegations"] = records
+	})
+
+	dir := filepath.Join(t.TempDir(), "dump")
+	args := []string{source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+	_, err := executeMigrate(t, append(args, "--debug-dump-dir", dir)...)
+	require.Error(t, err)
+
+	var panicErr *MigrationPanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Equal(t, "legacy (v0.36)", panicErr.Stage)
+	require.NotNil(t, panicErr.Dump)
+	require.Equal(t, "staking", panicErr.Dump.Module)
+
+	dumpPath := filepath.Join(dir, "staking-v0.36.json")
+	excerptPath := filepath.Join(dir, "staking-v0.36-excerpt.json")
+	require.Contains(t, err.Error(), "the staking genesis the stage started from is dumped to "+dumpPath)
+	require.Contains(t, err.Error(), fmt.Sprintf("record %d of its delegations fails to migrate, the records around it are in %s", malformed, excerptPath))
+
+	bz, err := ioutil.ReadFile(dumpPath)
+	require.NoError(t, err)
+	var staking struct {
+		Delegations []json.RawMessage `json:"delegations"`
+	}
+	require.NoError(t, json.Unmarshal(bz, &staking))
+	require.Len(t, staking.Delegations, delegations)
+
+	bz, err = ioutil.ReadFile(excerptPath)
+	require.NoError(t, err)
+	var excerpt moduleExcerpt
+	require.NoError(t, json.Unmarshal(bz, &excerpt))
+	require.Equal(t, "staking", excerpt.Module)
+	require.Equal(t, "delegations", excerpt.Field)
+	require.Equal(t, malformed, excerpt.Index)
+	require.Equal(t, malformed-excerptRadius, excerpt.First)
+	require.Len(t, excerpt.Records, 2*excerptRadius+1)
+	require.Contains(t, string(excerpt.Records[excerptRadius]), `"not-a-dec"`)
+	for i, record := range excerpt.Records {
+		if i != excerptRadius {
+			require.Contains(t, string(record), fmt.Sprintf(`"%d.000000000000000000"`, excerpt.First+i+1))
+		}
+	}
+
+	// an empty --debug-dump-dir dumps nothing
+	_, err = executeMigrate(t, append(args, "--debug-dump-dir=")...)
+	require.True(t, errors.As(err, &panicErr))
+	require.Nil(t, panicErr.Dump)
+	require.NotContains(t, err.Error(), "dumped")
+
+	// nor does --strict by default
+	bz, err = ioutil.ReadFile(source)
+	require.NoError(t, err)
+	sum := sha256.Sum256(bz)
+	_, err = executeMigrate(t, append(args, "--strict", "--source-sha256", hex.EncodeToString(sum[:]),
+		"--genesis-time", "2021-02-18T17:00:00Z", "--initial-height", "5200791", "--output", filepath.Join(t.TempDir(), "genesis.json"))...)
+	require.True(t, errors.As(err, &panicErr))
+	require.Nil(t, panicErr.Dump)
+	require.NotContains(t, err.Error(), "dumped")
+}
+
+func TestFindFailingRecord(t *testing.T) {
+	// a migration failing on the records with a bad field and without params
+	fails := func(state types.AppMap) bool {
+		var genesis struct {
+			Params  *json.RawMessage
+			Records []map[string]interface{}
+			Others  []map[string]interface{}
+		}
+		if err := json.Unmarshal(state["module"], &genesis); err != nil {
+			return true
+		}
+		for _, record := range append(genesis.Records, genesis.Others...) {
+			if record["bad"] != nil {
+				return true
+			}
+		}
+		return genesis.Params == nil
+	}
+
+	excerpt := findFailingRecord("module", json.RawMessage(`{"params":{},"others":[{},{}],"records":[{"a":0},{"a":1},{"bad":1},{"a":3},{"bad":2}]}`), fails)
+	require.Equal(t, &moduleExcerpt{
+		Module: "module", Field: "records", Index: 2, First: 0,
+		Records: []json.RawMessage{
+			json.RawMessage(`{"a":0}`), json.RawMessage(`{"a":1}`), json.RawMessage(`{"bad":1}`), json.RawMessage(`{"a":3}`), json.RawMessage(`{"bad":2}`),
+		},
+	}, excerpt)
+
+	// no record fails, the genesis fails with its arrays empty
+	require.Nil(t, findFailingRecord("module", json.RawMessage(`{"records":[{"a":0}]}`), fails))
+	// the genesis does not fail alone
+	require.Nil(t, findFailingRecord("module", json.RawMessage(`{"params":{},"records":[{"a":0}]}`), fails))
+}
@@ -0,0 +1,339 @@
+package gaia
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// MigrateOptions are the options of a migrate run, resolved once from the
+// --config file and the flags overriding it, or built by NewMigrateOptions
+// for Migrate. Every field holds the value of the migrate flag its flag tag
+// names.
+type MigrateOptions struct {
+	// the source genesis and how it is read
+	LegacySource        string        `flag:"legacy-source"`
+	InputFormat         string        `flag:"input-format"`
+	MaxInputSize        int64         `flag:"max-input-size"`
+	SourceSHA256        string        `flag:"source-sha256"`
+	DownloadDir         string        `flag:"download-cache-dir"`
+	DownloadTimeout     time.Duration `flag:"download-timeout"`
+	DownloadRetries     int           `flag:"download-retries"`
+	ForceRemigrate      bool          `flag:"force-remigrate"`
+	ReapplyStateChanges bool          `flag:"reapply-state-changes"`
+	CacheDir            string        `flag:"cache-dir"`
+	Resume              bool          `flag:"resume"`
+	AccountsCheckpoint  int           `flag:"accounts-checkpoint-interval"`
+
+	// the chain parameters
+	ChainID          string `flag:"chain-id"`
+	GenesisTime      string `flag:"genesis-time"`
+	InitialHeight    string `flag:"initial-height"`
+	SourceHaltHeight int64  `flag:"source-halt-height"`
+	SourceHaltTime   string `flag:"source-halt-time"`
+	UpgradeInfo      string `flag:"upgrade-info"`
+	UpgradeProposal  string `flag:"upgrade-proposal"`
+	Node             string `flag:"node"`
+	PreserveAppHash  bool   `flag:"preserve-app-hash"`
+	ShiftAllTimes    bool   `flag:"shift-all-times"`
+
+	// the state-altering options
+	NoProp29          bool   `flag:"no-prop-29"`
+	Prop29Data        string `flag:"prop-29-data"`
+	Prop29Claims      string `flag:"prop-29-claims-account"`
+	ProtectedAddrs    string `flag:"protected-addresses"`
+	FailOnProtected   bool   `flag:"fail-on-protected-conflict"`
+	BlockedAddresses  string `flag:"blocked-addresses"`
+	BlockedDest       string `flag:"blocked-destination"`
+	BlockedAccount    string `flag:"blocked-account-action"`
+	PruneBelow        string `flag:"prune-accounts-below"`
+	KeepTopAccounts   int    `flag:"keep-top-accounts"`
+	PruneSink         string `flag:"prune-sink"`
+	SweepInactiveTo   string `flag:"sweep-inactive-to"`
+	InactiveBelow     string `flag:"inactive-below"`
+	Airdrop           string `flag:"airdrop"`
+	RoundingDustTo    string `flag:"rounding-dust-to"`
+	SweepModuleDust   string `flag:"sweep-module-dust"`
+	TopUpGovAccount   string `flag:"top-up-gov-account"`
+	FundCommunityPool string `flag:"fund-community-pool"`
+	FundFrom          string `flag:"from-account"`
+	WithdrawRewards   bool   `flag:"withdraw-all-rewards"`
+	CapValidatorPower string `flag:"cap-validator-power"`
+	ReplacementKeys   string `flag:"replacement-cons-keys"`
+	StripDupConsKeys  bool   `flag:"strip-duplicate-consensus-keys"`
+	SyncTmValidators  bool   `flag:"sync-tm-validators"`
+	DropUnmappable    bool   `flag:"drop-unmappable-proposals"`
+	TruncateStrings   bool   `flag:"truncate-long-strings"`
+	NormalizeUnicode  bool   `flag:"normalize-unicode-nfc"`
+	DropStaleEvidence bool   `flag:"drop-stale-evidence"`
+	NormalizePubKeys  bool   `flag:"normalize-pubkeys"`
+	ClearPubKeys      bool   `flag:"clear-invalid-pubkeys"`
+	RemapChainIDs     string `flag:"remap-counterparty-chain-ids"`
+
+	// the repairs and overrides of the migrated state
+	CrisisConstantFee string        `flag:"crisis-constant-fee"`
+	MintBlocksPerYear uint64        `flag:"mint-blocks-per-year"`
+	MintInflation     string        `flag:"mint-inflation"`
+	ExpectedBlockTime time.Duration `flag:"ibc-expected-block-time"`
+	RepairCaps        bool          `flag:"repair-capabilities"`
+	FixProofSpecs     bool          `flag:"fix-client-proof-specs"`
+	RepairChannelSeqs bool          `flag:"repair-channel-sequences"`
+	DropEmptyRecords  bool          `flag:"drop-empty-records"`
+	CompleteMatured   bool          `flag:"complete-matured-entries"`
+	DropStaleVotes    bool          `flag:"drop-stale-votes"`
+	NoNormalizeOrder  bool          `flag:"no-normalize-order"`
+	EmbedMigration    bool          `flag:"embed-migration-info"`
+
+	// the checks of the migrated genesis
+	StrictModuleAccts bool     `flag:"strict-module-accounts"`
+	SmokeTest         bool     `flag:"smoke-test"`
+	SelfCheck         bool     `flag:"self-check"`
+	WarningsAsErrors  []string `flag:"warnings-as-errors"`
+	MaxWarnExamples   int      `flag:"max-warning-examples"`
+	MaxOutputSize     int64    `flag:"max-output-size"`
+	Baseline          string   `flag:"baseline"`
+
+	// the output files
+	Output         string `flag:"output"`
+	BundleDir      string `flag:"bundle-dir"`
+	OutputFormat   string `flag:"output-format"`
+	ReviewOutput   string `flag:"review-output"`
+	FileMode       string `flag:"file-mode"`
+	CreateDirs     bool   `flag:"create-dirs"`
+	Manifest       string `flag:"manifest"`
+	SignManifest   bool   `flag:"sign-manifest"`
+	From           string `flag:"from"`
+	KeyringBackend string `flag:"keyring-backend"`
+	KeyringDir     string `flag:"keyring-dir"`
+
+	// the reports
+	WarningsReport     string `flag:"warnings-report"`
+	Prop29Report       string `flag:"prop-29-report"`
+	Prop29ClaimsReport string `flag:"prop-29-claims-report"`
+	ReplacementReport  string `flag:"replacement-keys-report"`
+	ConsKeysReport     string `flag:"duplicate-consensus-keys-report"`
+	PubKeyReport       string `flag:"pubkey-report"`
+	EvidenceReport     string `flag:"evidence-report"`
+	RewardsReport      string `flag:"withdraw-rewards-report"`
+	PowerCapReport     string `flag:"power-cap-report"`
+	ModuleAcctsReport  string `flag:"module-accounts-report"`
+	BlockedReport      string `flag:"blocked-addresses-report"`
+	SweptReport        string `flag:"swept-accounts-report"`
+	AirdropReport      string `flag:"airdrop-report"`
+	RoundingDustReport string `flag:"rounding-dust-report"`
+	GovContentReport   string `flag:"gov-content-report"`
+	GovTallyReport     string `flag:"gov-tally-report"`
+	UnicodeReport      string `flag:"unicode-report"`
+	LongStringsReport  string `flag:"long-strings-report"`
+	SequenceReport     string `flag:"sequence-report"`
+	IBCClientReport    string `flag:"ibc-client-report"`
+	IBCChannelReport   string `flag:"ibc-channel-report"`
+	BaselineReport     string `flag:"baseline-report"`
+
+	// the run itself
+	RequireVersion     string        `flag:"require-version"`
+	Strict             bool          `flag:"strict"`
+	SkipConfirmation   bool          `flag:"yes"`
+	Timeout            time.Duration `flag:"timeout"`
+	DebugDumpDir       string        `flag:"debug-dump-dir"`
+	Verbose            bool          `flag:"verbose"`
+	Progress           bool          `flag:"progress"`
+	MetricsListen      string        `flag:"metrics-listen"`
+	MetricsAllowCancel bool          `flag:"metrics-allow-cancel"`
+
+	// set are the names of the flags given, on the command line or by the
+	// config.
+	set map[string]bool
+}
+
+var (
+	migrateDefaultsOnce sync.Once
+	migrateDefaults     *MigrateOptions
+)
+
+// defaultMigrateOptions returns the options of the migrate flag defaults.
+func defaultMigrateOptions() *MigrateOptions {
+	migrateDefaultsOnce.Do(func() {
+		var err error
+		if migrateDefaults, err = migrateOptionsFromFlags(MigrateGenesisCmd().Flags()); err != nil {
+			panic(err)
+		}
+	})
+	return migrateDefaults
+}
+
+// NewMigrateOptions returns the MigrateOptions of the migrate flag defaults,
+// for Migrate. The options changed from their defaults count as set.
+func NewMigrateOptions() *MigrateOptions {
+	opts := *defaultMigrateOptions()
+	opts.WarningsAsErrors = append([]string(nil), opts.WarningsAsErrors...)
+	opts.set = make(map[string]bool)
+	return &opts
+}
+
+// migrateOptionsFromFlags returns the MigrateOptions of the migrate flags fs,
+// once applyMigrateConfig set those of the --config file.
+func migrateOptionsFromFlags(fs *pflag.FlagSet) (*MigrateOptions, error) {
+	opts := &MigrateOptions{set: make(map[string]bool)}
+
+	fields := opts.fields()
+	for name, field := range fields {
+		if fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("no --%s flag of the migrate option", name)
+		}
+
+		var err error
+		switch v := field.Addr().Interface().(type) {
+		case *string:
+			*v, err = fs.GetString(name)
+		case *bool:
+			*v, err = fs.GetBool(name)
+		case *int:
+			*v, err = fs.GetInt(name)
+		case *int64:
+			*v, err = fs.GetInt64(name)
+		case *uint64:
+			*v, err = fs.GetUint64(name)
+		case *time.Duration:
+			*v, err = fs.GetDuration(name)
+		case *[]string:
+			*v, err = fs.GetStringSlice(name)
+		default:
+			err = fmt.Errorf("unsupported option type %T", v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fs.Visit(func(flag *pflag.Flag) {
+		if _, ok := fields[flag.Name]; ok {
+			opts.set[flag.Name] = true
+		}
+	})
+
+	return opts, nil
+}
+
+// fields returns the fields of opts by flag name.
+func (opts *MigrateOptions) fields() map[string]reflect.Value {
+	v := reflect.ValueOf(opts).Elem()
+	fields := make(map[string]reflect.Value, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if name := v.Type().Field(i).Tag.Get("flag"); name != "" {
+			fields[name] = v.Field(i)
+		}
+	}
+	return fields
+}
+
+// IsSet tells whether the flag name was given, on the command line or by
+// the config, or its option was changed from the default.
+func (opts *MigrateOptions) IsSet(name string) bool {
+	if opts.set[name] {
+		return true
+	}
+
+	field, ok := opts.fields()[name]
+	if !ok {
+		return false
+	}
+	value, defaultValue := field.Interface(), defaultMigrateOptions().fields()[name].Interface()
+	if field.Kind() == reflect.Slice {
+		// a nil list is the empty default
+		if field.Len() == 0 && reflect.ValueOf(defaultValue).Len() == 0 {
+			return false
+		}
+	}
+
+	return !reflect.DeepEqual(value, defaultValue)
+}
+
+// setFlags returns the names of the flags whose options are set.
+func (opts *MigrateOptions) setFlags() map[string]bool {
+	set := make(map[string]bool)
+	for name := range opts.fields() {
+		if opts.IsSet(name) {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// enabled tells whether the boolean or string option of the flag name is
+// set to true or to a value.
+func (opts *MigrateOptions) enabled(name string) bool {
+	field, ok := opts.fields()[name]
+	if !ok {
+		return false
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		return field.Bool()
+	case reflect.String:
+		return field.String() != ""
+	default:
+		return false
+	}
+}
+
+// visit calls fn with the name and value of every option set, in flag name
+// order.
+func (opts *MigrateOptions) visit(fn func(name string, value interface{})) {
+	fields := opts.fields()
+	set := opts.setFlags()
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fn(name, fields[name].Interface())
+	}
+}
+
+// Config returns the options set, as a config setting them. The others have
+// the defaults of this binary.
+func (opts *MigrateOptions) Config() migrateConfig {
+	config := migrateConfig{}
+	opts.visit(func(name string, value interface{}) {
+		switch v := value.(type) {
+		case int:
+			config[name] = int64(v)
+		case time.Duration:
+			config[name] = v.String()
+		default:
+			config[name] = v
+		}
+	})
+
+	return config
+}
+
+// reproducibleArgs returns the flags of the options set that determine the
+// migrated genesis, in flag name order.
+func (opts *MigrateOptions) reproducibleArgs() []string {
+	args := []string{}
+	opts.visit(func(name string, value interface{}) {
+		if unreproducedFlags[name] {
+			return
+		}
+
+		if values, ok := value.([]string); ok {
+			for _, v := range values {
+				args = append(args, fmt.Sprintf("--%s=%s", name, v))
+			}
+			return
+		}
+
+		args = append(args, fmt.Sprintf("--%s=%v", name, value))
+	})
+
+	return args
+}
@@ -0,0 +1,123 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// parseMigrateOptions returns the MigrateOptions of the migrate flags args,
+// with those of their --config file.
+func parseMigrateOptions(t *testing.T, args ...string) *MigrateOptions {
+	cmd := MigrateGenesisCmd()
+	require.NoError(t, cmd.ParseFlags(args))
+	require.NoError(t, applyMigrateConfig(cmd))
+
+	opts, err := migrateOptionsFromFlags(cmd.Flags())
+	require.NoError(t, err)
+	return opts
+}
+
+func TestMigrateOptionsCoverFlags(t *testing.T) {
+	cmd := MigrateGenesisCmd()
+	fields := (&MigrateOptions{}).fields()
+
+	// every flag a config may set is an option
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if configurableFlag(cmd, cmd.Flags(), flag.Name) == nil {
+			return
+		}
+		_, ok := fields[flag.Name]
+		require.True(t, ok, "no option of --%s", flag.Name)
+	})
+}
+
+func TestMigrateOptionsFromFlags(t *testing.T) {
+	config := writeConfig(t, "launch.toml", `
+chain-id = "cosmoshub-4"
+genesis-time = "+45m"
+timeout = "30m"
+mint-blocks-per-year = 4360000
+warnings-as-errors = ["W-IBC-*"]
+output = "genesis.json"
+`)
+
+	opts := parseMigrateOptions(t, "--config", config, "--chain-id", "cosmoshub-rehearsal", "--yes")
+	require.Equal(t, "cosmoshub-rehearsal", opts.ChainID)
+	require.Equal(t, "+45m", opts.GenesisTime)
+	require.Equal(t, 30*time.Minute, opts.Timeout)
+	require.Equal(t, uint64(4360000), opts.MintBlocksPerYear)
+	require.Equal(t, []string{"W-IBC-*"}, opts.WarningsAsErrors)
+	require.True(t, opts.SkipConfirmation)
+
+	// the options left out have their defaults and are not set
+	require.Equal(t, 10, opts.MaxWarnExamples)
+	require.Equal(t, formatJSON, opts.OutputFormat)
+	require.Equal(t, time.Minute, opts.DownloadTimeout)
+	require.False(t, opts.IsSet(flagMaxWarnExamples))
+	require.True(t, opts.IsSet(flagTimeout))
+
+	require.True(t, opts.enabled(flags.FlagSkipConfirmation))
+	require.True(t, opts.enabled(flagOutputFile))
+	require.False(t, opts.enabled(flagSmokeTest))
+	require.False(t, opts.enabled(flagLegacySource))
+
+	// the manifest records the options set that change the genesis
+	require.Equal(t, []string{
+		"--chain-id=cosmoshub-rehearsal",
+		"--genesis-time=+45m",
+		"--mint-blocks-per-year=4360000",
+	}, opts.reproducibleArgs())
+
+	require.Equal(t, migrateConfig{
+		"chain-id":             "cosmoshub-rehearsal",
+		"genesis-time":         "+45m",
+		"mint-blocks-per-year": uint64(4360000),
+		"output":               "genesis.json",
+		"timeout":              "30m0s",
+		"warnings-as-errors":   []string{"W-IBC-*"},
+		"yes":                  true,
+	}, opts.Config())
+}
+
+func TestNewMigrateOptions(t *testing.T) {
+	opts := NewMigrateOptions()
+	require.Equal(t, parseMigrateOptions(t).Config(), opts.Config())
+	require.Equal(t, 10, opts.MaxWarnExamples)
+	require.Empty(t, opts.reproducibleArgs())
+
+	// the options changed count as set
+	opts.ChainID = "cosmoshub-4"
+	opts.WarningsAsErrors = nil
+	require.True(t, opts.IsSet(flags.FlagChainID))
+	require.False(t, opts.IsSet(flagWarningsAsErrors))
+	require.Equal(t, []string{"--chain-id=cosmoshub-4"}, opts.reproducibleArgs())
+}
+
+func TestMigrate(t *testing.T) {
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+	want, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+
+	opts := NewMigrateOptions()
+	opts.LegacySource = "cosmoshub-2"
+	opts.NoProp29 = true
+	opts.ChainID = "cosmoshub-4"
+
+	var out, stderr bytes.Buffer
+	clientCtx := migrateClientContext().WithOutput(&out)
+	require.NoError(t, Migrate(context.Background(), clientCtx, "testdata/cosmoshub-2-genesis.json", opts, &stderr))
+	require.Equal(t, string(want), out.String())
+	require.Contains(t, stderr.String(), "migrate-result status=ok")
+
+	// the options decide the run as the flags do
+	opts.Strict = true
+	err = Migrate(context.Background(), clientCtx, "testdata/cosmoshub-2-genesis.json", opts, &stderr)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--"+flagSourceSHA256)
+}
@@ -0,0 +1,142 @@
+package gaia
+
+import (
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+)
+
+// maxPanicFrames is the number of stack frames from the panic kept in a
+// MigrationPanicError.
+const maxPanicFrames = 8
+
+// panicModulePattern matches the functions of the SDK and gaia modules in a
+// stack trace. The genutil migrations only dispatch to the other modules.
+var panicModulePattern = regexp.MustCompile(`^github\.com/cosmos/(?:cosmos-sdk|gaia/v5)/x/([a-z]+)/`)
+
+// frameArgsPattern matches the arguments of a function in a stack trace.
+var frameArgsPattern = regexp.MustCompile(`\([^()]*\)$`)
+
+// MigrationPanicError is the error of a migration that panicked, with the
+// stage and, when known, the module it was migrating.
+type MigrationPanicError struct {
+	Stage  string
+	Module string
+	Value  interface{}
+	// Stack are the first frames from the panic.
+	Stack string
+	// Dump is where the genesis of the module failing is dumped, nil when it
+	// is not.
+	Dump *moduleDump
+}
+
+func (e *MigrationPanicError) Error() string {
+	where := "before its first stage"
+	if e.Stage != "" {
+		where = "in the " + e.Stage + " stage"
+	}
+	if e.Module != "" {
+		where += ", migrating the " + e.Module + " module"
+	}
+
+	msg := fmt.Sprintf("the migration panicked %s: %v\n%s", where, e.Value, e.Stack)
+	if e.Dump != nil {
+		msg += fmt.Sprintf("\nthe %s genesis the stage started from is dumped to %s", e.Dump.Module, e.Dump.Path)
+	}
+	if e.Dump != nil && e.Dump.Excerpt != nil {
+		msg += fmt.Sprintf("\nrecord %d of its %s fails to migrate, the records around it are in %s",
+			e.Dump.Excerpt.Index, e.Dump.Excerpt.Field, e.Dump.ExcerptPath)
+	}
+
+	return msg
+}
+
+// migrationPosition is the stage and the module the migration is at, for
+// the error of a panic.
+type migrationPosition struct {
+	stage  string
+	module string
+	// version is the SDK migration of the stage running, if any.
+	version string
+	// input is the app state the stage or SDK migration started from, nil
+	// when the modules failing in it are not dumped, and rerun runs the SDK
+	// migration again.
+	input types.AppMap
+	rerun func(types.AppMap) types.AppMap
+	// dump tells whether the module genesis of a panic is dumped, to
+	// dumpDir or a new temporary directory, as files writes.
+	dump    bool
+	dumpDir string
+	files   outputFiles
+}
+
+// recoverMigrationPanic sets *err to a MigrationPanicError at pos when the
+// migration panics. It must be deferred by the function running it.
+func recoverMigrationPanic(pos *migrationPosition, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	frames := panicFrames(string(debug.Stack()))
+
+	module := pos.module
+	if module == "" {
+		module = panicModule(frames)
+	}
+
+	stack := frames
+	if len(stack) > maxPanicFrames {
+		stack = append(stack[:maxPanicFrames:maxPanicFrames], "...")
+	}
+
+	*err = &MigrationPanicError{Stage: pos.stage, Module: module, Value: r, Stack: "  " + strings.Join(stack, "\n  ")}
+}
+
+// panicFrames returns the frames of a debug.Stack trace from the function
+// that panicked outwards, each as its function and its file:line.
+func panicFrames(stack string) []string {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+
+	// the frames start after the panic call, the runtime frames raising
+	// runtime errors are skipped too
+	start := len(lines)
+	for i := 1; i+1 < len(lines); i += 2 {
+		if strings.HasPrefix(lines[i], "panic(") {
+			start = i + 2
+			break
+		}
+	}
+
+	var frames []string
+	for i := start; i+1 < len(lines); i += 2 {
+		function := frameArgsPattern.ReplaceAllString(lines[i], "")
+		if strings.HasPrefix(function, "runtime.") && len(frames) == 0 {
+			continue
+		}
+
+		file := strings.TrimSpace(lines[i+1])
+		if j := strings.LastIndex(file, " +0x"); j >= 0 {
+			file = file[:j]
+		}
+
+		frames = append(frames, function+" "+file)
+	}
+
+	return frames
+}
+
+// panicModule returns the innermost module of the frames of a panic, empty
+// when it did not panic in a module.
+func panicModule(frames []string) string {
+	for _, frame := range frames {
+		if m := panicModulePattern.FindStringSubmatch(frame); m != nil && m[1] != "genutil" {
+			return m[1]
+		}
+	}
+
+	return ""
+}
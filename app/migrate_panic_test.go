@@ -0,0 +1,155 @@
+package gaia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMutatedGenesis writes the cosmoshub-2 test genesis changed by mutate,
+// which gets the genesis doc and its app_state.
+func writeMutatedGenesis(t *testing.T, mutate func(doc, appState map[string]interface{})) string {
+	bz, err := ioutil.ReadFile("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(bz, &doc))
+	mutate(doc, doc["app_state"].(map[string]interface{}))
+
+	bz, err = json.Marshal(doc)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	return path
+}
+
+func TestMigratePanics(t *testing.T) {
+	proposal := func(appState map[string]interface{}) map[string]interface{} {
+		return appState["gov"].(map[string]interface{})["proposals"].([]interface{})[0].(map[string]interface{})
+	}
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(doc, appState map[string]interface{})
+		// panicked is the start of the error of a migration that panicked
+		panicked string
+		// err is the error of a migration that fails without panicking
+		err string
+	}{
+		{
+			"nil gov proposal content",
+			func(_, appState map[string]interface{}) { proposal(appState)["proposal_content"] = nil },
+			"the migration panicked in the legacy (v0.36) stage, migrating the gov module: runtime error: invalid memory address or nil pointer dereference",
+			"",
+		},
+		{
+			"unknown gov proposal type",
+			func(_, appState map[string]interface{}) {
+				proposal(appState)["proposal_content"] = map[string]interface{}{"type": "gov/UnknownProposal", "value": map[string]interface{}{}}
+			},
+			"the migration panicked in the legacy (v0.36) stage: unrecognized concrete type name gov/UnknownProposal",
+			"",
+		},
+		{
+			"invalid account address",
+			func(_, appState map[string]interface{}) {
+				appState["accounts"] = []interface{}{map[string]interface{}{"address": "cosmos1"}}
+			},
+			"the migration panicked in the legacy (v0.36) stage: decoding bech32 failed",
+			"",
+		},
+		{
+			"missing gov genesis",
+			func(_, appState map[string]interface{}) { delete(appState, "gov") },
+			"the migration panicked in the legacy (v0.36) stage: UnmarshalJSON cannot decode empty bytes",
+			"",
+		},
+		{
+			"missing bank genesis",
+			func(_, appState map[string]interface{}) { delete(appState, "bank") },
+			"the migration panicked in the modules stage, migrating the bank module: EOF",
+			"",
+		},
+		{
+			"missing crisis genesis",
+			func(_, appState map[string]interface{}) { delete(appState, "crisis") },
+			"the migration panicked in the modules stage, migrating the crisis module: EOF",
+			"",
+		},
+		{
+			"empty staking validators",
+			func(_, appState map[string]interface{}) {
+				appState["staking"].(map[string]interface{})["validators"] = []interface{}{}
+			},
+			"",
+			"tendermint genesis validators do not match the staking bonded set (1 discrepancies), use --sync-tm-validators to regenerate them from staking",
+		},
+		{
+			"empty validator sets",
+			func(doc, appState map[string]interface{}) {
+				staking := appState["staking"].(map[string]interface{})
+				staking["validators"] = []interface{}{}
+				staking["last_validator_powers"] = []interface{}{}
+				doc["validators"] = []interface{}{}
+			},
+			"",
+			"genesis has no validators and its staking genesis has no bonded validators with power, the migrated chain could not start",
+		},
+		{
+			// the source IBC state is replaced by the default one
+			"nil IBC client state",
+			func(_, appState map[string]interface{}) {
+				appState["ibc"] = map[string]interface{}{"client_genesis": map[string]interface{}{
+					"clients": []interface{}{map[string]interface{}{"client_id": "07-tendermint-0", "client_state": nil}},
+				}}
+			},
+			"",
+			"",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeMutatedGenesis(t, tc.mutate)
+
+			// a source with IBC state is refused as migrated already otherwise
+			_, err := executeMigrate(t, path, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--debug-dump-dir", t.TempDir(), "--force-remigrate")
+			switch {
+			case tc.panicked != "":
+				require.Error(t, err)
+				require.True(t, strings.HasPrefix(err.Error(), tc.panicked), err.Error())
+
+				var panicErr *MigrationPanicError
+				require.True(t, errors.As(err, &panicErr))
+				require.NotEmpty(t, panicErr.Stack)
+				require.LessOrEqual(t, strings.Count(panicErr.Stack, "\n"), maxPanicFrames)
+				require.NotContains(t, panicErr.Stack, "runtime/debug")
+				require.NotContains(t, panicErr.Stack, "recoverMigrationPanic")
+			case tc.err != "":
+				require.EqualError(t, err, tc.err)
+			default:
+				require.NoError(t, err)
+			}
+
+			if err != nil {
+				require.NotContains(t, err.Error(), "goroutine ")
+			}
+		})
+	}
+}
+
+func TestPanicModule(t *testing.T) {
+	require.Equal(t, "gov", panicModule([]string{
+		"github.com/cosmos/cosmos-sdk/x/gov/legacy/v036.migrateContent /go/x/gov/legacy/v036/migrate.go:43",
+		"github.com/cosmos/cosmos-sdk/x/genutil/legacy/v036.Migrate /go/x/genutil/legacy/v036/migrate.go:76",
+	}))
+	require.Equal(t, "rotation", panicModule([]string{"github.com/cosmos/gaia/v5/x/rotation/keeper.Keeper.InitGenesis /gaia/x/rotation/keeper/genesis.go:12"}))
+	require.Empty(t, panicModule([]string{
+		"github.com/cosmos/cosmos-sdk/codec.(*LegacyAmino).MustUnmarshalJSON /go/codec/amino.go:171",
+		"github.com/cosmos/cosmos-sdk/x/genutil/legacy/v036.Migrate /go/x/genutil/legacy/v036/migrate.go:43",
+	}))
+}
@@ -0,0 +1,245 @@
+package gaia
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+const flagRequireVersion = "require-version"
+
+// semverPattern matches a semantic version, with or without the v prefix.
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// unreproducedFlags are the migrate flags that do not change the migrated
+// genesis: where it and the reports are written, how the source is fetched
+// and cached, what is logged and what is checked. They are not recorded in
+// the manifest, nor are the bases of the relative --initial-height and
+// --genesis-time, which are recorded resolved.
+var unreproducedFlags = map[string]bool{
+	flagOutputFile:             true,
+	flagBundleDir:              true,
+	flagManifest:               true,
+	flagReviewOutput:           true,
+	flagFileMode:               true,
+	flagCreateDirs:             true,
+	flagProp29Report:           true,
+	flagProp29ClaimsReport:     true,
+	flagConsKeysReport:         true,
+	flagReplacementReport:      true,
+	flagModuleAcctsReport:      true,
+	flagBlockedReport:          true,
+	flagSweptReport:            true,
+	flagAirdropReport:          true,
+	flagGovTallyReport:         true,
+	flagGovContentReport:       true,
+	flagIBCClientReport:        true,
+	flagLongStringsReport:      true,
+	flagEvidenceReport:         true,
+	flagPubKeyReport:           true,
+	flagRoundingDustReport:     true,
+	flagSequenceReport:         true,
+	flagPowerCapReport:         true,
+	flagRewardsReport:          true,
+	flagDebugDumpDir:           true,
+	flagBaseline:               true,
+	flagBaselineReport:         true,
+	flagTimeout:                true,
+	flagDownloadDir:            true,
+	flagDownloadTimeout:        true,
+	flagDownloadRetries:        true,
+	flagCacheDir:               true,
+	flagSignManifest:           true,
+	flags.FlagFrom:             true,
+	flags.FlagKeyringBackend:   true,
+	flags.FlagKeyringDir:       true,
+	flagConfig:                 true,
+	flagResume:                 true,
+	flagAccountsCheckpoint:     true,
+	flagVerbose:                true,
+	flagProgress:               true,
+	flagMetricsListen:          true,
+	flagMetricsCancel:          true,
+	flagSmokeTest:              true,
+	flagWarningsAsErrors:       true,
+	flagWarningsReport:         true,
+	flagMaxWarnExamples:        true,
+	flagMaxOutputSize:          true,
+	flagSourceSHA256:           true,
+	flagRequireVersion:         true,
+	flagStrict:                 true,
+	flagSelfCheck:              true,
+	flagSourceHaltHeight:       true,
+	flagSourceHaltTime:         true,
+	flagUpgradeInfo:            true,
+	flags.FlagSkipConfirmation: true,
+}
+
+// checkRequiredVersion fails unless the binary version is the required
+// semantic version, either with or without the v prefix.
+func checkRequiredVersion(required, binary string) error {
+	if !semverPattern.MatchString(required) {
+		return fmt.Errorf("--%s %s is not a semantic version", flagRequireVersion, required)
+	}
+
+	if binary == "" {
+		return fmt.Errorf("--%s %s: this binary was built without a version", flagRequireVersion, required)
+	}
+
+	if strings.TrimPrefix(required, "v") != strings.TrimPrefix(binary, "v") {
+		return fmt.Errorf("--%s %s: this binary is version %s", flagRequireVersion, required, binary)
+	}
+
+	return nil
+}
+
+// GenesisReproduceCmd returns a command re-running the migration recorded in a
+// manifest and checking it produces the same genesis.
+func GenesisReproduceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reproduce [source-genesis]",
+		Short: "Re-run the migration recorded in a manifest and verify its genesis hash",
+		Long: fmt.Sprintf(`Migrate the source genesis again with the flags recorded in the manifest written
+by migrate --manifest, a file or an http(s) URL, and check the migrated genesis
+has the SHA-256 and size of the manifest. The source must have the SHA-256 the
+manifest records and the files given to the recorded flags must be at the same
+paths. A mismatch fails with the manifest fields that differ and the versions
+of the binary that wrote the manifest and of this one.
+
+The stage chain of the manifest, hashing the source, the output of each
+migration stage and the migrated genesis in turn, must be unbroken, and the
+re-run must reproduce it: the first stage whose output differs is reported.
+
+Example:
+$ %s genesis reproduce --manifest manifest.json exported-genesis.json
+`, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, _ := cmd.Flags().GetString(flagManifest)
+			if source == "" {
+				return fmt.Errorf("--%s is required", flagManifest)
+			}
+
+			manifest, err := loadMigrationManifest(source)
+			if err != nil {
+				return errors.Wrap(err, "failed to load manifest")
+			}
+			if manifest.CosmosSDKVersion == "" {
+				return fmt.Errorf("manifest %s records no versions and flags, it was written by a migrate that did not record them", source)
+			}
+
+			if len(manifest.StageChain) > 0 {
+				if err := verifyManifestStageChain(manifest); err != nil {
+					return errors.Wrapf(err, "the stage chain of %s is broken", source)
+				}
+			}
+
+			recorded, running := manifestVersions(manifest), newBuildVersions()
+			if recorded != running {
+				cmd.PrintErrf("the manifest was written by %s, this binary is %s\n", recorded, running)
+			}
+
+			if manifest.SourceGenesisSHA256 != "" {
+				if err := verifySourceSHA256(args[0], manifest.SourceGenesisSHA256); err != nil {
+					return errors.Wrapf(err, "%s is not the source of the manifest", args[0])
+				}
+			}
+
+			dir, err := ioutil.TempDir("", "gaiad-reproduce-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+
+			reproducedPath := filepath.Join(dir, "manifest.json")
+			migrateArgs := append([]string{args[0]}, manifest.MigrateArgs...)
+			migrateArgs = append(migrateArgs, "--"+flagManifest, reproducedPath, "--"+flags.FlagSkipConfirmation)
+
+			migrate := NewMigrateGenesisCmd(MakeEncodingConfig())
+			migrate.SetOut(ioutil.Discard)
+			migrate.SetErr(cmd.ErrOrStderr())
+			migrate.SetArgs(migrateArgs)
+			if err := migrate.ExecuteContext(cmd.Context()); err != nil {
+				return errors.Wrap(err, "failed to re-run the migration")
+			}
+
+			reproduced, err := loadMigrationManifest(reproducedPath)
+			if err != nil {
+				return errors.Wrap(err, "failed to load the reproduced manifest")
+			}
+
+			if differences := manifestDifferences(manifest, reproduced); len(differences) > 0 {
+				if recorded != running {
+					differences = append(differences, fmt.Sprintf("versions: manifest %s, this binary %s", recorded, running))
+				}
+				return fmt.Errorf("the migration does not reproduce %s:\n  %s", source, strings.Join(differences, "\n  "))
+			}
+
+			cmd.Printf("reproduced the genesis of %s: sha256 %s, %d bytes\n", source, reproduced.GenesisSHA256, reproduced.GenesisSize)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagManifest, "", "Manifest written by migrate --manifest, a file or an http(s) URL")
+
+	return cmd
+}
+
+// manifestDifferences describes the genesis fields of the reproduced manifest
+// that differ from the expected one.
+func manifestDifferences(expected, reproduced migrationManifest) []string {
+	var differences []string
+	differ := func(field string, expected, reproduced interface{}) {
+		if expected != reproduced {
+			differences = append(differences, fmt.Sprintf("%s: manifest %v, reproduced %v", field, expected, reproduced))
+		}
+	}
+
+	differ("chain_id", expected.ChainID, reproduced.ChainID)
+	differ("genesis_time", expected.GenesisTime.UTC().Format(time.RFC3339Nano), reproduced.GenesisTime.UTC().Format(time.RFC3339Nano))
+	differ("initial_height", expected.InitialHeight, reproduced.InitialHeight)
+	differ("genesis_size", expected.GenesisSize, reproduced.GenesisSize)
+	differ("genesis_sha256", strings.ToLower(expected.GenesisSHA256), reproduced.GenesisSHA256)
+
+	// the first stage whose output differs, of a manifest recording them
+	if len(expected.StageChain) > 0 {
+		if err := genesis.CompareStageChains(expected.StageChain, reproduced.StageChain); err != nil {
+			differences = append(differences, "stage_chain: "+err.Error())
+		}
+	}
+
+	return differences
+}
+
+// verifyManifestStageChain checks the stage chain of manifest is unbroken from
+// the source genesis to the migrated genesis of the manifest SHA-256.
+func verifyManifestStageChain(manifest migrationManifest) error {
+	if err := genesis.VerifyStageChain(manifest.StageChain); err != nil {
+		return err
+	}
+
+	first, last := manifest.StageChain[0], manifest.StageChain[len(manifest.StageChain)-1]
+	if first.Stage != genesis.StageSource {
+		return &genesis.StageChainError{Index: 0, Stage: first.Stage, Reason: fmt.Sprintf("the chain does not start at the %s", genesis.StageSource)}
+	}
+	if last.Stage != genesis.StageOutput || last.SHA256 != strings.ToLower(manifest.GenesisSHA256) {
+		return &genesis.StageChainError{
+			Index:  len(manifest.StageChain) - 1,
+			Stage:  last.Stage,
+			Reason: fmt.Sprintf("the chain does not end at the %s of the genesis_sha256 %s", genesis.StageOutput, manifest.GenesisSHA256),
+		}
+	}
+
+	return nil
+}
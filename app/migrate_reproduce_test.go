@@ -0,0 +1,141 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+func TestCheckRequiredVersion(t *testing.T) {
+	for _, tc := range []struct {
+		required string
+		binary   string
+		expected string
+	}{
+		{"v5.0.2", "5.0.2", ""},
+		{"5.0.2", "v5.0.2", ""},
+		{"v5.0.2-rc1", "5.0.2-rc1", ""},
+		{"v5.0.3", "5.0.2", "--require-version v5.0.3: this binary is version 5.0.2"},
+		{"v5.0.2", "5.0.2-rc1", "--require-version v5.0.2: this binary is version 5.0.2-rc1"},
+		{"v5.0.2", "", "--require-version v5.0.2: this binary was built without a version"},
+		{"latest", "5.0.2", "--require-version latest is not a semantic version"},
+	} {
+		err := checkRequiredVersion(tc.required, tc.binary)
+		if tc.expected == "" {
+			require.NoError(t, err, tc.required)
+		} else {
+			require.EqualError(t, err, tc.expected)
+		}
+	}
+
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+	defer func(v string) { version.Version = v }(version.Version)
+	version.Version = "5.0.2"
+
+	_, err := executeMigrate(t, append(args, "--"+flagRequireVersion, "v5.0.3")...)
+	require.EqualError(t, err, "--require-version v5.0.3: this binary is version 5.0.2")
+
+	_, err = executeMigrate(t, append(args, "--"+flagRequireVersion, "v5.0.2")...)
+	require.NoError(t, err)
+}
+
+func TestGenesisReproduce(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.json")
+	source := "testdata/cosmoshub-2-genesis.json"
+
+	_, err := executeMigrate(t, source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4",
+		"--initial-height", "42", "--output", filepath.Join(dir, "genesis.json"), "--manifest", manifestPath, "--verbose")
+	require.NoError(t, err)
+
+	manifest, err := loadMigrationManifest(manifestPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{"--chain-id=cosmoshub-4", "--initial-height=42", "--legacy-source=cosmoshub-2", "--no-prop-29=true"}, manifest.MigrateArgs)
+	require.Equal(t, newBuildVersions(), manifestVersions(manifest))
+	require.NotEmpty(t, manifest.CosmosSDKVersion)
+	require.NotEmpty(t, manifest.GoVersion)
+	sourceSum, err := fileSHA256(source)
+	require.NoError(t, err)
+	require.Equal(t, sourceSum, manifest.SourceGenesisSHA256)
+
+	reproduce := func(manifestPath, source string) (string, error) {
+		cmd := GenesisReproduceCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs([]string{source, "--manifest", manifestPath})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := reproduce(manifestPath, source)
+	require.NoError(t, err)
+	require.Equal(t, "reproduced the genesis of "+manifestPath+": sha256 "+manifest.GenesisSHA256+", 8402 bytes\n", out)
+
+	// a manifest recording other flags does not reproduce
+	tampered := manifest
+	tampered.MigrateArgs = []string{"--chain-id=cosmoshub-4", "--initial-height=43", "--legacy-source=cosmoshub-2", "--no-prop-29=true"}
+	tampered.GaiaVersion = "4.2.1"
+	tamperedPath := filepath.Join(dir, "tampered.json")
+	bz, err := json.Marshal(tampered)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(tamperedPath, bz, 0600))
+
+	_, err = reproduce(tamperedPath, source)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the migration does not reproduce "+tamperedPath+":\n  initial_height: manifest 42, reproduced 43\n  genesis_sha256: manifest "+manifest.GenesisSHA256)
+	require.Contains(t, err.Error(), "\n  versions: manifest gaia 4.2.1, cosmos-sdk ")
+	// the stages before the output reproduce
+	require.Contains(t, err.Error(), "\n  stage_chain: stage chain broken at link 5, stage output: output ")
+
+	// the stage chain runs from the source through every stage to the output
+	stages := make([]string, len(manifest.StageChain))
+	for i, link := range manifest.StageChain {
+		stages[i] = link.Stage
+	}
+	require.Equal(t, []string{genesis.StageSource, "legacy", "v0.38", "v0.39", "v0.40", genesis.StageOutput}, stages)
+	require.Equal(t, manifest.GenesisSHA256, manifest.StageChain[5].SHA256)
+
+	// a stage output changed in the manifest breaks its chain
+	tampered = manifest
+	tampered.StageChain = append([]genesis.StageLink(nil), manifest.StageChain...)
+	tampered.StageChain[3].SHA256 = manifest.StageChain[2].SHA256
+	bz, err = json.Marshal(tampered)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(tamperedPath, bz, 0600))
+
+	_, err = reproduce(tamperedPath, source)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the stage chain of "+tamperedPath+" is broken: stage chain broken at link 3, stage v0.39")
+
+	// and chained again from there on, it does not reproduce from that stage
+	for i := 3; i < len(tampered.StageChain); i++ {
+		tampered.StageChain[i] = genesis.NewStageLink(tampered.StageChain[i-1], tampered.StageChain[i].Stage, tampered.StageChain[i].SHA256)
+	}
+	bz, err = json.Marshal(tampered)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(tamperedPath, bz, 0600))
+
+	_, err = reproduce(tamperedPath, source)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "stage_chain: stage chain broken at link 3, stage v0.39: output "+manifest.StageChain[3].SHA256+", expected "+manifest.StageChain[2].SHA256)
+
+	// another source is refused before migrating
+	_, err = reproduce(manifestPath, filepath.Join(dir, "genesis.json"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not the source of the manifest")
+
+	// a manifest of an earlier migrate records nothing to reproduce
+	bz, err = json.Marshal(migrationManifest{ChainID: "cosmoshub-4", GenesisSHA256: manifest.GenesisSHA256})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(tamperedPath, bz, 0600))
+	_, err = reproduce(tamperedPath, source)
+	require.EqualError(t, err, "manifest "+tamperedPath+" records no versions and flags, it was written by a migrate that did not record them")
+}
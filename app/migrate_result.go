@@ -0,0 +1,70 @@
+package gaia
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// WarningsAsErrorsError is the error of a migration failing on the warnings
+// --warnings-as-errors treats as errors.
+type WarningsAsErrorsError struct {
+	Warnings []migrationWarning
+}
+
+func (e *WarningsAsErrorsError) Error() string {
+	return fmt.Sprintf("%d warnings are treated as errors by --%s", len(e.Warnings), flagWarningsAsErrors)
+}
+
+// Codes returns the distinct codes of the warnings in registration order.
+func (e *WarningsAsErrorsError) Codes() []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, warning := range e.Warnings {
+		if !seen[warning.Code] {
+			seen[warning.Code] = true
+			codes = append(codes, warning.Code)
+		}
+	}
+
+	return codes
+}
+
+// migrateRun is what the status line of a migrate run is made of, filled in
+// as the run goes.
+type migrateRun struct {
+	start        time.Time
+	position     *migrationPosition
+	warnings     *warningCollector
+	outputSHA256 string
+}
+
+// result returns the result of the run ending with err.
+func (r *migrateRun) result(err error) genesis.MigrateResult {
+	result := genesis.MigrateResult{
+		Status:   genesis.MigrateStatusOK,
+		Duration: time.Since(r.start),
+	}
+	if r.warnings != nil {
+		result.Warnings = len(r.warnings.Warnings())
+	}
+	if err == nil {
+		result.OutputSHA256 = r.outputSHA256
+		return result
+	}
+
+	result.Status, result.Error = genesis.MigrateStatusError, err.Error()
+	if r.position != nil {
+		result.Stage, result.Module = r.position.stage, r.position.module
+	}
+	if panicErr, ok := err.(*MigrationPanicError); ok {
+		result.Module = panicErr.Module
+	}
+	if warningsErr, ok := err.(*WarningsAsErrorsError); ok {
+		result.Code = strings.Join(warningsErr.Codes(), ",")
+	}
+
+	return result
+}
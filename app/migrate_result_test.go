@@ -0,0 +1,202 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// lastMigrateResult parses the last line of the stderr of a migrate run,
+// which must be its status line.
+func lastMigrateResult(t *testing.T, stderr string) genesis.MigrateResult {
+	require.True(t, strings.HasSuffix(stderr, "\n"), stderr)
+	lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+	result, err := genesis.ParseMigrateResult(lines[len(lines)-1])
+	require.NoError(t, err, stderr)
+	require.Equal(t, 1, strings.Count(stderr, genesis.MigrateResultPrefix+" "), stderr)
+
+	return result
+}
+
+func TestMigrateResultString(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		result genesis.MigrateResult
+		line   string
+	}{
+		{
+			"ok",
+			genesis.MigrateResult{Status: genesis.MigrateStatusOK, OutputSHA256: "ab01", Warnings: 12, Duration: 93 * time.Second},
+			"migrate-result status=ok output_sha256=ab01 warnings=12 duration=1m33s",
+		},
+		{
+			"without duration",
+			genesis.MigrateResult{Status: genesis.MigrateStatusOK, OutputSHA256: "ab01"},
+			"migrate-result status=ok output_sha256=ab01 warnings=0",
+		},
+		{
+			"error",
+			genesis.MigrateResult{
+				Status: genesis.MigrateStatusError, Stage: "v0.40 (v0.39)", Module: "ibc", Code: "W-IBC-003,W-IBC-004",
+				Warnings: 2, Duration: 2500 * time.Millisecond, Error: "failed: \"x=y\"\n  at main.go",
+			},
+			`migrate-result status=error stage="v0.40 (v0.39)" module=ibc code=W-IBC-003,W-IBC-004 warnings=2 duration=2.5s error="failed: \"x=y\"\n  at main.go"`,
+		},
+		{
+			"empty error",
+			genesis.MigrateResult{Status: genesis.MigrateStatusError, Stage: "read", Duration: time.Millisecond},
+			"migrate-result status=error stage=read warnings=0 duration=1ms",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.line, tc.result.String())
+
+			parsed, err := genesis.ParseMigrateResult(tc.line + "\n")
+			require.NoError(t, err)
+			require.Equal(t, tc.result, parsed)
+		})
+	}
+
+	// later versions may add keys
+	result, err := genesis.ParseMigrateResult("migrate-result status=ok attempts=3 warnings=1")
+	require.NoError(t, err)
+	require.Equal(t, genesis.MigrateResult{Status: genesis.MigrateStatusOK, Warnings: 1}, result)
+
+	for _, line := range []string{
+		"",
+		"migrate-results status=ok",
+		"migrate-result warnings=1",
+		"migrate-result status=ok warnings=many",
+		"migrate-result status=ok duration=93",
+		`migrate-result status=error error="unterminated`,
+		"migrate-result status=ok =1",
+	} {
+		_, err := genesis.ParseMigrateResult(line)
+		require.Error(t, err, line)
+	}
+}
+
+func TestMigrateResult(t *testing.T) {
+	path := filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json")
+	dir := t.TempDir()
+
+	var stderr bytes.Buffer
+	manifestPath := filepath.Join(dir, "manifest.json")
+	out, err := executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4", "--manifest", manifestPath)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(out)
+	result := lastMigrateResult(t, stderr.String())
+	require.Equal(t, genesis.MigrateStatusOK, result.Status)
+	require.True(t, result.OK())
+	require.Equal(t, hex.EncodeToString(sum[:]), result.OutputSHA256)
+	require.Positive(t, int64(result.Duration))
+	require.Empty(t, result.Stage)
+	require.Empty(t, result.Error)
+
+	// the manifest has the result without the duration
+	bz, err := ioutil.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var manifest migrationManifest
+	require.NoError(t, json.Unmarshal(bz, &manifest))
+	manifestResult, err := genesis.ParseMigrateResult(manifest.MigrateResult)
+	require.NoError(t, err)
+	result.Duration = 0
+	require.Equal(t, result, manifestResult)
+
+	// a missing genesis fails reading it
+	stderr.Reset()
+	_, err = executeMigrateTo(t, &stderr, filepath.Join(dir, "missing.json"), "--chain-id", "cosmoshub-4")
+	require.Error(t, err)
+	result = lastMigrateResult(t, stderr.String())
+	require.Equal(t, genesis.MigrateResult{
+		Status: genesis.MigrateStatusError, Stage: "read", Duration: result.Duration, Error: err.Error(),
+	}, result)
+	require.Contains(t, stderr.String(), "Error: "+err.Error()+"\n")
+}
+
+func TestMigrateResultFailedStage(t *testing.T) {
+	started := migrateStageStarted
+	t.Cleanup(func() { migrateStageStarted = started })
+
+	path := filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json")
+	for _, stage := range []string{"read", "v0.38", "v0.39", "v0.40", "modules", "genesis", "validators", "output"} {
+		t.Run(stage, func(t *testing.T) {
+			migrateStageStarted = func(ctx context.Context, name string) {
+				if name == stage {
+					panic("failing " + name)
+				}
+			}
+
+			var stderr bytes.Buffer
+			_, err := executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4")
+			require.Error(t, err)
+
+			result := lastMigrateResult(t, stderr.String())
+			require.Equal(t, genesis.MigrateStatusError, result.Status)
+			require.Equal(t, stage, result.Stage)
+			require.Empty(t, result.OutputSHA256)
+			require.Contains(t, result.Error, "failing "+stage)
+		})
+	}
+
+	// a timeout fails with the stage it stopped at
+	migrateStageStarted = func(ctx context.Context, name string) {
+		if name == "modules" {
+			<-ctx.Done()
+		}
+	}
+	var stderr bytes.Buffer
+	_, err := executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4", "--timeout", "100ms")
+	require.IsType(t, &MigrationTimeoutError{}, err)
+	result := lastMigrateResult(t, stderr.String())
+	require.Equal(t, "modules", result.Stage)
+	require.Equal(t, err.Error(), result.Error)
+}
+
+func TestMigrateResultWarningsAsErrors(t *testing.T) {
+	bz, err := ioutil.ReadFile(filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json"))
+	require.NoError(t, err)
+	var genesisJSON map[string]interface{}
+	require.NoError(t, json.Unmarshal(bz, &genesisJSON))
+	stakingState := genesisJSON["app_state"].(map[string]interface{})["staking"].(map[string]interface{})
+	validator := stakingState["validators"].([]interface{})[0].(map[string]interface{})
+	validator["description"].(map[string]interface{})["details"] = strings.Repeat("ascii art ", 3000)
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	bz, err = json.Marshal(genesisJSON)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	var stderr bytes.Buffer
+	_, err = executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+	warnings := lastMigrateResult(t, stderr.String()).Warnings
+	require.Positive(t, warnings)
+
+	stderr.Reset()
+	_, err = executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4", "--warnings-as-errors=W-STAKING-*")
+	require.EqualError(t, err, "1 warnings are treated as errors by --warnings-as-errors")
+	require.IsType(t, &WarningsAsErrorsError{}, err)
+
+	result := lastMigrateResult(t, stderr.String())
+	require.Equal(t, genesis.MigrateResult{
+		Status:   genesis.MigrateStatusError,
+		Stage:    "validators",
+		Code:     warnStakingLongString,
+		Warnings: warnings,
+		Duration: result.Duration,
+		Error:    err.Error(),
+	}, result)
+}
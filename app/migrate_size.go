@@ -0,0 +1,149 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distr "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	evidence "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+const flagMaxOutputSize = "max-output-size"
+
+// moduleBudgetShare is the share of --max-output-size above which the
+// genesis of a single module is warned about.
+const moduleBudgetShare = 0.5
+
+// oversizedModules is how many of the largest modules a --max-output-size
+// failure names.
+const oversizedModules = 3
+
+// moduleSizeAdvice are the migrate options shrinking the genesis of a module,
+// suggested for the modules taking most of an oversized output.
+var moduleSizeAdvice = map[string]string{
+	auth.ModuleName:     fmt.Sprintf("--%s or --%s", flagPruneBelow, flagSweepInactiveTo),
+	bank.ModuleName:     fmt.Sprintf("--%s or --%s", flagPruneBelow, flagDropEmptyRecords),
+	distr.ModuleName:    fmt.Sprintf("--%s", flagPruneBelow),
+	evidence.ModuleName: fmt.Sprintf("--%s", flagDropStaleEvidence),
+	gov.ModuleName:      fmt.Sprintf("--%s or --%s", flagDropStaleVotes, flagDropUnmappable),
+	staking.ModuleName:  fmt.Sprintf("--%s or --%s", flagCompleteMatured, flagDropEmptyRecords),
+}
+
+// moduleSize is the size in bytes of the JSON genesis of a module.
+type moduleSize struct {
+	Module string
+	Bytes  int64
+}
+
+// measureModules returns the sizes of the module genesis of the JSON app state,
+// largest first and then by module name.
+func measureModules(appState json.RawMessage) ([]moduleSize, error) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(appState, &state); err != nil {
+		return nil, err
+	}
+
+	sizes := make([]moduleSize, 0, len(state))
+	for module, raw := range state {
+		sizes = append(sizes, moduleSize{Module: module, Bytes: int64(len(raw))})
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Bytes != sizes[j].Bytes {
+			return sizes[i].Bytes > sizes[j].Bytes
+		}
+		return sizes[i].Module < sizes[j].Module
+	})
+
+	return sizes, nil
+}
+
+// formatByteSize returns n bytes in the largest binary unit it reaches, e.g.
+// 2.3 GiB.
+func formatByteSize(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// printModuleSizes writes the table of the module sizes of an output of
+// total bytes to w.
+func printModuleSizes(w io.Writer, sizes []moduleSize, total int64) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "module\tsize\tshare\t")
+	for _, size := range sizes {
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t\n", size.Module, formatByteSize(size.Bytes), 100*float64(size.Bytes)/float64(total))
+	}
+	fmt.Fprintf(tw, "total\t%s\t100.0%%\t\n", formatByteSize(total))
+	tw.Flush()
+}
+
+// checkModuleBudgets warns about every module whose genesis alone takes more
+// than moduleBudgetShare of the max output size.
+func checkModuleBudgets(warnings *warningCollector, sizes []moduleSize, maxSize int64) {
+	for _, size := range sizes {
+		if float64(size.Bytes) <= moduleBudgetShare*float64(maxSize) {
+			continue
+		}
+
+		advice := ""
+		if options, ok := moduleSizeAdvice[size.Module]; ok {
+			advice = ", consider " + options
+		}
+		warnings.Add(warnGenesisModuleSize, severityMedium, size.Module, "%s genesis is %s, %.0f%% of --%s %s%s",
+			size.Module, formatByteSize(size.Bytes), 100*float64(size.Bytes)/float64(maxSize), flagMaxOutputSize, formatByteSize(maxSize), advice)
+	}
+}
+
+// OutputSizeError is the error of a migration whose output exceeds
+// --max-output-size.
+type OutputSizeError struct {
+	Size    int64
+	MaxSize int64
+	// Modules are the largest modules of the output, largest first.
+	Modules []moduleSize
+}
+
+func (e *OutputSizeError) Error() string {
+	largest := make([]string, 0, len(e.Modules))
+	for _, size := range e.Modules {
+		s := fmt.Sprintf("%s %s (%.0f%%", size.Module, formatByteSize(size.Bytes), 100*float64(size.Bytes)/float64(e.Size))
+		if options, ok := moduleSizeAdvice[size.Module]; ok {
+			s += ", consider " + options
+		}
+		largest = append(largest, s+")")
+	}
+
+	return fmt.Sprintf("the migrated genesis of %s exceeds --%s %s, the largest modules are %s",
+		formatByteSize(e.Size), flagMaxOutputSize, formatByteSize(e.MaxSize), strings.Join(largest, "; "))
+}
+
+// checkOutputSize fails with an OutputSizeError when the output of size bytes
+// exceeds maxSize, unless maxSize is 0.
+func checkOutputSize(size, maxSize int64, sizes []moduleSize) error {
+	if maxSize == 0 || size <= maxSize {
+		return nil
+	}
+
+	if len(sizes) > oversizedModules {
+		sizes = sizes[:oversizedModules]
+	}
+
+	return &OutputSizeError{Size: size, MaxSize: maxSize, Modules: sizes}
+}
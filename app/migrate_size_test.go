@@ -0,0 +1,83 @@
+package gaia
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateMaxOutputSize(t *testing.T) {
+	// the historical rewards of the one validator of the genesis over many
+	// periods
+	const periods = 3000
+	source := writeMutatedGenesis(t, func(_, appState map[string]interface{}) {
+		distr := appState["distr"].(map[string]interface{})
+		rewards := distr["validator_historical_rewards"].([]interface{})
+		record := rewards[0].(map[string]interface{})
+
+		for i := 1; i <= periods; i++ {
+			rewards = append(rewards, map[string]interface{}{
+				"validator_address": record["validator_address"],
+				"period":            fmt.Sprint(i),
+				"rewards":           map[string]interface{}{"cumulative_reward_ratio": []interface{}{}, "reference_count": 1},
+			})
+		}
+		distr["validator_historical_rewards"] = rewards
+	})
+
+	args := []string{source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	var log bytes.Buffer
+	out, err := executeMigrateTo(t, &log, append(args, "--verbose")...)
+	require.NoError(t, err)
+	require.Regexp(t, `(?m)^\s+module\s+size\s+share$`, log.String())
+	require.Regexp(t, `(?m)^\s+distribution\s+[0-9.]+ KiB\s+9[0-9]\.[0-9]%$`, log.String())
+	require.Regexp(t, fmt.Sprintf(`(?m)^\s+total\s+%s\s+100.0%%$`, formatByteSize(int64(len(out)))), log.String())
+
+	maxSize := int64(len(out)) / 2
+	log.Reset()
+	_, err = executeMigrateTo(t, &log, append(args, "--max-output-size", fmt.Sprint(maxSize))...)
+	require.Error(t, err)
+
+	var sizeErr *OutputSizeError
+	require.True(t, errors.As(err, &sizeErr))
+	require.Equal(t, int64(len(out)), sizeErr.Size)
+	require.Len(t, sizeErr.Modules, oversizedModules)
+	require.Equal(t, "distribution", sizeErr.Modules[0].Module)
+	require.Contains(t, err.Error(), fmt.Sprintf("exceeds --%s %s, the largest modules are distribution", flagMaxOutputSize, formatByteSize(maxSize)))
+	require.Contains(t, err.Error(), "consider --"+flagPruneBelow)
+
+	// the module over half of the limit is warned about before
+	require.Contains(t, log.String(), warnGenesisModuleSize)
+	require.Contains(t, log.String(), fmt.Sprintf("distribution genesis is %s", formatByteSize(sizeErr.Modules[0].Bytes)))
+
+	// at the limit, the run succeeds and only warns about the distribution
+	log.Reset()
+	_, err = executeMigrateTo(t, &log, append(args, "--max-output-size", fmt.Sprint(len(out)))...)
+	require.NoError(t, err)
+	require.Contains(t, log.String(), warnGenesisModuleSize)
+
+	// well under it, nothing warns
+	log.Reset()
+	_, err = executeMigrateTo(t, &log, append(args, "--max-output-size", fmt.Sprint(4*len(out)))...)
+	require.NoError(t, err)
+	require.NotContains(t, log.String(), warnGenesisModuleSize)
+}
+
+func TestFormatByteSize(t *testing.T) {
+	for n, expected := range map[int64]string{
+		0:                 "0 B",
+		1023:              "1023 B",
+		1024:              "1.0 KiB",
+		1536:              "1.5 KiB",
+		5 << 20:           "5.0 MiB",
+		2469606195:        "2.3 GiB",
+		3 << 40:           "3.0 TiB",
+		(2 << 50) + 1<<49: "2560.0 TiB",
+	} {
+		require.Equal(t, expected, formatByteSize(n), n)
+	}
+}
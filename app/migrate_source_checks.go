@@ -0,0 +1,151 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const (
+	flagForceRemigrate      = "force-remigrate"
+	flagReapplyStateChanges = "reapply-state-changes"
+)
+
+// checkSourceValidators fails when the source genesis has no tendermint
+// validators and its staking genesis returns none from InitChain either, so
+// the migrated chain could not produce a block.
+func checkSourceValidators(genDoc *tmtypes.GenesisDoc, state types.AppMap) error {
+	if len(genDoc.Validators) > 0 {
+		return nil
+	}
+
+	ok, err := stakingGenesisHasValidators(state)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("genesis has no validators and its %s genesis has no bonded validators with power, the migrated chain could not start", staking.ModuleName)
+	}
+
+	return nil
+}
+
+// stakingGenesisHasValidators tells whether the staking genesis of state
+// has a last validator power or a bonded, unjailed validator with power, in
+// the JSON of any supported source version.
+func stakingGenesisHasValidators(state types.AppMap) (bool, error) {
+	bz, ok := state[staking.ModuleName]
+	if !ok {
+		return false, nil
+	}
+
+	var stakingGenesis struct {
+		LastValidatorPowers []struct {
+			Power json.Number `json:"power"`
+		} `json:"last_validator_powers"`
+		Validators []struct {
+			Jailed bool            `json:"jailed"`
+			Status json.RawMessage `json:"status"`
+			Tokens sdk.Int         `json:"tokens"`
+		} `json:"validators"`
+	}
+	if err := json.Unmarshal(bz, &stakingGenesis); err != nil {
+		return false, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", staking.ModuleName)
+	}
+
+	for _, power := range stakingGenesis.LastValidatorPowers {
+		if n, err := power.Power.Int64(); err == nil && n > 0 {
+			return true, nil
+		}
+	}
+
+	for _, val := range stakingGenesis.Validators {
+		if !val.Jailed && isBondedStatus(val.Status) && !val.Tokens.IsNil() && sdk.TokensToConsensusPower(val.Tokens) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RemigrationError is the error of a migration refusing a source genesis that
+// is the output of a migration already, for the Signs of it.
+type RemigrationError struct {
+	Signs []string
+}
+
+func (e *RemigrationError) Error() string {
+	return fmt.Sprintf("the source genesis is migrated already: %s; migrating it again applies the migrations and state changes twice, use --%s to migrate it anyway",
+		strings.Join(e.Signs, "; "), flagForceRemigrate)
+}
+
+// checkNotMigrated fails with a RemigrationError when state shows signs of
+// being the output of a migration.
+func checkNotMigrated(state types.AppMap) error {
+	signs, err := migratedGenesisSigns(state)
+	if err != nil {
+		return err
+	}
+	if len(signs) > 0 {
+		return &RemigrationError{Signs: signs}
+	}
+
+	return nil
+}
+
+// migratedGenesisSigns returns the signs that state is the output of a
+// migration: its migration info, and the module genesis only the target
+// version has or already in the form of the target version.
+func migratedGenesisSigns(state types.AppMap) ([]string, error) {
+	var signs []string
+
+	info, err := readMigrationInfo(state)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		signs = append(signs, fmt.Sprintf("app_state.%s records a migration to %s by gaia %s", migrationInfoKey, info.MigrationTarget, info.GaiaVersion))
+	}
+
+	if _, ok := state[host.ModuleName]; ok {
+		signs = append(signs, fmt.Sprintf("it has an %s genesis, which no source version has", host.ModuleName))
+	}
+
+	if bz, ok := state[bank.ModuleName]; ok {
+		var bankGenesis struct {
+			Balances json.RawMessage `json:"balances"`
+		}
+		if err := json.Unmarshal(bz, &bankGenesis); err != nil {
+			return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", bank.ModuleName)
+		}
+		if bankGenesis.Balances != nil {
+			signs = append(signs, fmt.Sprintf("its %s genesis has the balances the v0.40 %s module holds", bank.ModuleName, bank.ModuleName))
+		}
+	}
+
+	if bz, ok := state[auth.ModuleName]; ok {
+		var authGenesis struct {
+			Accounts []map[string]json.RawMessage `json:"accounts"`
+		}
+		if err := json.Unmarshal(bz, &authGenesis); err != nil {
+			return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", auth.ModuleName)
+		}
+		for _, acc := range authGenesis.Accounts {
+			if _, ok := acc["@type"]; ok {
+				signs = append(signs, fmt.Sprintf("its %s accounts are the proto Any of v0.40", auth.ModuleName))
+				break
+			}
+		}
+	}
+
+	return signs, nil
+}
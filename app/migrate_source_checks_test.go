@@ -0,0 +1,172 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// writeSourceGenesis writes the cosmoshub-2 test genesis, changed by edit, to
+// a temporary file.
+func writeSourceGenesis(t *testing.T, edit func(genesis map[string]interface{})) string {
+	bz, err := ioutil.ReadFile("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+
+	var genesis map[string]interface{}
+	require.NoError(t, json.Unmarshal(bz, &genesis))
+	edit(genesis)
+
+	bz, err = json.Marshal(genesis)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	return path
+}
+
+func TestMigrateAppHash(t *testing.T) {
+	source := writeSourceGenesis(t, func(genesis map[string]interface{}) {
+		genesis["app_hash"] = "0A1B2C3D"
+	})
+	args := []string{source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	out, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	require.Empty(t, genDoc.AppHash)
+
+	out, err = executeMigrate(t, append(args, "--preserve-app-hash")...)
+	require.NoError(t, err)
+	genDoc, err = tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	require.Equal(t, "0A1B2C3D", genDoc.AppHash.String())
+}
+
+func TestMigrateSourceValidators(t *testing.T) {
+	stakingGenesis := func(genesis map[string]interface{}) map[string]interface{} {
+		return genesis["app_state"].(map[string]interface{})["staking"].(map[string]interface{})
+	}
+	args := []string{"--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--sync-tm-validators"}
+
+	testCases := []struct {
+		name string
+		edit func(genesis map[string]interface{})
+		err  string
+	}{
+		{
+			"staking last validator powers",
+			func(genesis map[string]interface{}) {
+				genesis["validators"] = []interface{}{}
+			},
+			"",
+		},
+		{
+			"staking bonded validators",
+			func(genesis map[string]interface{}) {
+				genesis["validators"] = []interface{}{}
+				stakingGenesis(genesis)["last_validator_powers"] = []interface{}{}
+			},
+			"",
+		},
+		{
+			"no validators",
+			func(genesis map[string]interface{}) {
+				genesis["validators"] = []interface{}{}
+				stakingGenesis(genesis)["last_validator_powers"] = []interface{}{}
+				stakingGenesis(genesis)["validators"].([]interface{})[0].(map[string]interface{})["status"] = 1
+			},
+			"genesis has no validators and its staking genesis has no bonded validators with power, the migrated chain could not start",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			source := writeSourceGenesis(t, tc.edit)
+
+			_, err := executeMigrate(t, append([]string{source}, args...)...)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestMigrateRemigration(t *testing.T) {
+	args := []string{"--chain-id", "cosmoshub-4", "--debug-dump-dir="}
+	migrated := func(extra ...string) string {
+		out, err := executeMigrate(t, append([]string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29"}, append(args, extra...)...)...)
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "migrated.json")
+		require.NoError(t, ioutil.WriteFile(path, out, 0600))
+		return path
+	}
+
+	// a previous output with its migration info
+	source := migrated("--" + flagEmbedMigration)
+	_, err := executeMigrate(t, append([]string{source}, args...)...)
+	var remigrationErr *RemigrationError
+	require.True(t, errors.As(err, &remigrationErr), err)
+	require.Len(t, remigrationErr.Signs, 4)
+	require.Contains(t, remigrationErr.Signs[0], "app_state.migration_info records a migration to v0.40")
+	require.Contains(t, err.Error(), "--"+flagForceRemigrate)
+
+	// and without, by the shape of its module genesis
+	source = migrated()
+	_, err = executeMigrate(t, append([]string{source}, args...)...)
+	require.True(t, errors.As(err, &remigrationErr), err)
+	require.Equal(t, []string{
+		"it has an ibc genesis, which no source version has",
+		"its bank genesis has the balances the v0.40 bank module holds",
+		"its auth accounts are the proto Any of v0.40",
+	}, remigrationErr.Signs)
+
+	// --force-remigrate migrates it anyway, without the prop29 entries
+	b := genesisbuilder.New()
+	prop29 := filepath.Join(t.TempDir(), "prop29.json")
+	require.NoError(t, ioutil.WriteFile(prop29, []byte(`[{"from": "`+b.Address("from").String()+`", "to": "`+b.Address("to").String()+`", "amount": [{"denom": "uatom", "amount": "1"}]}]`), 0600))
+
+	var log bytes.Buffer
+	_, err = executeMigrateTo(t, &log, append([]string{source, "--" + flagForceRemigrate, "--" + flagProp29Data, prop29}, args...)...)
+	require.False(t, errors.As(err, &remigrationErr))
+	require.Contains(t, log.String(), "migrating again a source genesis migrated already: it has an ibc genesis")
+	require.Contains(t, log.String(), "--force-remigrate: skip --prop-29-data, which the migrated source may have applied already, --reapply-state-changes applies them again")
+	// the source migrated twice does not make it through the SDK migrations
+	require.Contains(t, log.String(), "migrate-result status=error stage=v0.38")
+}
+
+func TestRemigrationStateChanges(t *testing.T) {
+	b := genesisbuilder.New()
+	prop29 := filepath.Join(t.TempDir(), "prop29.json")
+	require.NoError(t, ioutil.WriteFile(prop29, []byte(`[{"from": "`+b.Address("from").String()+`", "to": "`+b.Address("to").String()+`", "amount": [{"denom": "uatom", "amount": "1"}]}]`), 0600))
+
+	options := func(args ...string) (stateChangeOptions, error) {
+		return stateChangeOptionsOf(parseMigrateOptions(t, append([]string{"--" + flagProp29Data, prop29}, args...)...))
+	}
+
+	opts, err := options("--" + flagForceRemigrate)
+	require.NoError(t, err)
+	require.Nil(t, opts.Prop29)
+	require.Equal(t, []string{flagProp29Data}, opts.Skipped)
+
+	opts, err = options("--"+flagForceRemigrate, "--"+flagReapplyStateChanges)
+	require.NoError(t, err)
+	require.Len(t, opts.Prop29, 1)
+	require.Empty(t, opts.Skipped)
+
+	_, err = options("--" + flagReapplyStateChanges)
+	require.EqualError(t, err, "--reapply-state-changes needs --force-remigrate")
+}
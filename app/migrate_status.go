@@ -0,0 +1,192 @@
+package gaia
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Run states of migrationStatusSnapshot.
+const (
+	migrationStarting  = "starting"
+	migrationRunning   = "running"
+	migrationSucceeded = "succeeded"
+	migrationFailed    = "failed"
+)
+
+// migrationStatus tracks the run of a migration for /status and cancels it
+// on /cancel. The pipeline updates it as a stage observer and through the
+// warning collector while the HTTP handlers read it, all under mtx.
+type migrationStatus struct {
+	mtx      sync.Mutex
+	now      func() time.Time
+	start    time.Time
+	state    string
+	stage    string
+	index    int
+	stages   int
+	progress float64
+	warnings map[string]int
+	err      string
+	cancel   context.CancelFunc
+}
+
+// migrationStatusSnapshot is the /status report of a migration. Percent
+// counts every stage as the same share of the run.
+type migrationStatusSnapshot struct {
+	State          string         `json:"state"`
+	Stage          string         `json:"stage,omitempty"`
+	StageIndex     int            `json:"stage_index"`
+	Stages         int            `json:"stages"`
+	Percent        float64        `json:"percent"`
+	ElapsedSeconds float64        `json:"elapsed_seconds"`
+	Warnings       int            `json:"warnings"`
+	WarningCodes   map[string]int `json:"warning_codes"`
+	Error          string         `json:"error,omitempty"`
+}
+
+func newMigrationStatus() *migrationStatus {
+	return &migrationStatus{now: time.Now, start: time.Now(), state: migrationStarting, warnings: make(map[string]int)}
+}
+
+// Running records the migration as running, canceled by cancel.
+func (s *migrationStatus) Running(cancel context.CancelFunc) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.state, s.cancel = migrationRunning, cancel
+}
+
+// Finish records the result of the migration, failed if err is not nil.
+func (s *migrationStatus) Finish(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.state, s.cancel = migrationSucceeded, nil
+	if err != nil {
+		s.state, s.err = migrationFailed, err.Error()
+	}
+}
+
+// Cancel cancels the running migration, it reports false if none is.
+func (s *migrationStatus) Cancel() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	return true
+}
+
+func (s *migrationStatus) StageStarted(index, total int, name string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stage, s.index, s.stages, s.progress = name, index, total, 0
+}
+
+func (s *migrationStatus) StageProgress(done, total int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if total > 0 {
+		s.progress = float64(done) / float64(total)
+	}
+}
+
+func (s *migrationStatus) StageFinished(string, time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.progress = 1
+}
+
+// ObserveWarning counts a warning as the pipeline reports it.
+func (s *migrationStatus) ObserveWarning(warning migrationWarning) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.warnings[warning.Code]++
+}
+
+// Snapshot returns the status of the migration.
+func (s *migrationStatus) Snapshot() migrationStatusSnapshot {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	snapshot := migrationStatusSnapshot{
+		State:          s.state,
+		Stage:          s.stage,
+		StageIndex:     s.index,
+		Stages:         s.stages,
+		ElapsedSeconds: s.now().Sub(s.start).Seconds(),
+		WarningCodes:   make(map[string]int, len(s.warnings)),
+		Error:          s.err,
+	}
+	for code, n := range s.warnings {
+		snapshot.WarningCodes[code] = n
+		snapshot.Warnings += n
+	}
+
+	switch {
+	case s.state == migrationSucceeded:
+		snapshot.Percent = 100
+	case s.stages > 0 && s.index < s.stages:
+		snapshot.Percent = 100 * (float64(s.index) + s.progress) / float64(s.stages)
+	}
+
+	return snapshot
+}
+
+// register adds the /status handler to mux and, with allowCancel, the /cancel
+// one. /cancel only accepts requests from a loopback client: whoever can
+// reach the metrics port may scrape it, not abort the migration.
+func (s *migrationStatus) register(mux *http.ServeMux, allowCancel bool) {
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "GET only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Snapshot()) // nolint: errcheck
+	})
+
+	if !allowCancel {
+		return
+	}
+
+	mux.HandleFunc("/cancel", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !isLoopbackClient(r) {
+			http.Error(w, "cancel is only accepted from loopback clients", http.StatusForbidden)
+			return
+		}
+
+		if !s.Cancel() {
+			http.Error(w, "no migration is running", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// isLoopbackClient tells whether the request r comes from a loopback address.
+func isLoopbackClient(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
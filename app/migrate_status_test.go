@@ -0,0 +1,185 @@
+package gaia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// getMigrationStatus polls the /status of the migration served at url.
+func getMigrationStatus(t *testing.T, url string) migrationStatusSnapshot {
+	resp, err := http.Get(url + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var snapshot migrationStatusSnapshot
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+	return snapshot
+}
+
+// postCancel posts to the /cancel of the migration served at url and returns
+// the status code.
+func postCancel(t *testing.T, url string) int {
+	resp, err := http.Post(url+"/cancel", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestMigrationStatus(t *testing.T) {
+	status := newMigrationStatus()
+	mux := http.NewServeMux()
+	status.register(mux, true)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	require.Equal(t, migrationStarting, getMigrationStatus(t, server.URL).State)
+	require.Equal(t, http.StatusConflict, postCancel(t, server.URL))
+
+	// a client beyond the loopback interface cannot cancel
+	remote := httptest.NewRequest(http.MethodPost, "/cancel", nil)
+	remote.RemoteAddr = "203.0.113.7:40312"
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, remote)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	resp, err := http.Post(server.URL+"/status", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	status.Running(cancel)
+	stages := newStageTracker([]string{"read", "modules", "genesis", "output"}, status)
+	warnings := &warningCollector{observe: status.ObserveWarning}
+
+	// the pipeline advances while the status is polled
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stages.Start("read")
+		for i := int64(0); i <= 100; i++ {
+			stages.Progress(i, 100)
+			warnings.Add(warnStakingDemoted, severityMedium, "staking", "demoted %d", i)
+		}
+		stages.Start("modules")
+		stages.Progress(1, 2)
+	}()
+	for i := 0; i < 20; i++ {
+		snapshot := getMigrationStatus(t, server.URL)
+		require.Equal(t, migrationRunning, snapshot.State)
+		require.Equal(t, snapshot.Warnings, snapshot.WarningCodes[warnStakingDemoted])
+	}
+	wg.Wait()
+
+	snapshot := getMigrationStatus(t, server.URL)
+	require.Equal(t, "modules", snapshot.Stage)
+	require.Equal(t, 1, snapshot.StageIndex)
+	require.Equal(t, 4, snapshot.Stages)
+	require.Equal(t, 37.5, snapshot.Percent)
+	require.Equal(t, 101, snapshot.Warnings)
+
+	require.Equal(t, http.StatusAccepted, postCancel(t, server.URL))
+	require.Error(t, ctx.Err())
+
+	stages.Done()
+	status.Finish(errors.New("migration canceled: context canceled"))
+	snapshot = getMigrationStatus(t, server.URL)
+	require.Equal(t, migrationFailed, snapshot.State)
+	require.Equal(t, "migration canceled: context canceled", snapshot.Error)
+	require.Equal(t, http.StatusConflict, postCancel(t, server.URL))
+}
+
+// listenWriter sends the address migrate serves its metrics on, read from
+// the stderr written to it, to addr.
+type listenWriter struct {
+	mtx  sync.Mutex
+	buf  []byte
+	sent bool
+	addr chan string
+}
+
+var metricsListenLine = regexp.MustCompile(`serving migration metrics on http://(\S+)/metrics\n`)
+
+func (w *listenWriter) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if !w.sent {
+		w.buf = append(w.buf, p...)
+		if m := metricsListenLine.FindSubmatch(w.buf); m != nil {
+			w.addr <- string(m[1])
+			w.sent = true
+		}
+	}
+	return len(p), nil
+}
+
+func TestMigrateStatusCancel(t *testing.T) {
+	// the run waits in the modules stage until it is canceled
+	inModules := make(chan struct{})
+	slowMigrateStage(t, "modules", func(ctx context.Context) {
+		close(inModules)
+		<-ctx.Done()
+	})
+
+	stderr := &listenWriter{addr: make(chan string, 1)}
+	output := filepath.Join(t.TempDir(), "genesis.json")
+	done := make(chan error, 1)
+	go func() {
+		_, err := executeMigrateTo(t, stderr, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2",
+			"--no-prop-29", "--chain-id", "cosmoshub-4", "--output", output, "--metrics-listen", "127.0.0.1:0", "--metrics-allow-cancel")
+		done <- err
+	}()
+
+	var url string
+	select {
+	case addr := <-stderr.addr:
+		url = fmt.Sprintf("http://%s", addr)
+	case err := <-done:
+		t.Fatalf("migrate returned before serving: %v", err)
+	case <-time.After(time.Minute):
+		t.Fatal("migrate did not serve its metrics")
+	}
+	<-inModules
+
+	snapshot := getMigrationStatus(t, url)
+	require.Equal(t, migrationRunning, snapshot.State)
+	require.Equal(t, "modules", snapshot.Stage)
+	require.Equal(t, 5, snapshot.StageIndex)
+	require.Greater(t, snapshot.Percent, 0.0)
+	require.Less(t, snapshot.Percent, 100.0)
+	require.Greater(t, snapshot.ElapsedSeconds, 0.0)
+
+	require.Equal(t, http.StatusAccepted, postCancel(t, url))
+	require.EqualError(t, <-done, "migration canceled: context canceled")
+	require.NoFileExists(t, output)
+}
+
+func TestMigrationStatusCancelOptIn(t *testing.T) {
+	status := newMigrationStatus()
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	status.Running(cancel)
+
+	// without --metrics-allow-cancel there is no /cancel to post to
+	mux := http.NewServeMux()
+	status.register(mux, false)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	require.Equal(t, http.StatusNotFound, postCancel(t, server.URL))
+	require.Equal(t, migrationRunning, getMigrationStatus(t, server.URL).State)
+}
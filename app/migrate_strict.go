@@ -0,0 +1,169 @@
+package gaia
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/pkg/errors"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const (
+	flagStrict    = "strict"
+	flagSelfCheck = "self-check"
+)
+
+// strictForbiddenFlags are the migrate flags --strict forbids: the repairs of
+// a source state that does not migrate as it is, the overrides of its params
+// and output format, and the flags reading state from outside the source
+// genesis. The deliberate state changes, like --prop29-data, are allowed.
+var strictForbiddenFlags = []string{
+	flagRepairCaps,
+	flagFixProofSpecs,
+	flagRepairChannelSeqs,
+	flagSyncTmValidators,
+	flagStripDupConsKeys,
+	flagDropStaleEvidence,
+	flagClearInvalidPubKeys,
+	flagTruncateLongStrings,
+	flagNormalizeUnicode,
+	flagDropUnmappable,
+	flagDropEmptyRecords,
+	flagDropStaleVotes,
+	flagCompleteMatured,
+	flagSweepModuleDust,
+	flagTopUpGovAccount,
+	flagRemapChainIDs,
+	flagCrisisConstantFee,
+	flagMintBlocksPerYear,
+	flagMintInflation,
+	flagPreserveAppHash,
+	flagNoNormalizeOrder,
+	flagCacheDir,
+	flagResume,
+	flagForceRemigrate,
+	flags.FlagNode,
+}
+
+// strictModeOptions are the migrate options --strict requires or forbids.
+type strictModeOptions struct {
+	Source        string
+	SourceSHA256  string
+	ChainID       string
+	GenesisTime   string
+	InitialHeight string
+	Output        string
+	BundleDir     string
+	// Changed are the names of the flags given, on the command line or by
+	// the config.
+	Changed map[string]bool
+}
+
+// strictModeOptionsOf returns the strict mode options of the migrate options
+// opts of the source genesis.
+func strictModeOptionsOf(opts *MigrateOptions, source string) strictModeOptions {
+	return strictModeOptions{
+		Source:        source,
+		SourceSHA256:  opts.SourceSHA256,
+		ChainID:       opts.ChainID,
+		GenesisTime:   opts.GenesisTime,
+		InitialHeight: opts.InitialHeight,
+		Output:        opts.Output,
+		BundleDir:     opts.BundleDir,
+		Changed:       opts.setFlags(),
+	}
+}
+
+// Validate fails with every requirement of --strict opts does not meet: a
+// local source file of a given SHA-256, an explicit and absolute chain ID,
+// genesis time and initial height, a genesis written to a file and none of
+// strictForbiddenFlags.
+func (opts strictModeOptions) Validate() error {
+	var problems []string
+
+	switch {
+	case isGenesisURL(opts.Source):
+		problems = append(problems, "the source is a URL, download and verify it first")
+	case opts.Source == stdinGenesis:
+		problems = append(problems, "the source is read from STDIN, pass the file")
+	}
+	if opts.SourceSHA256 == "" {
+		problems = append(problems, fmt.Sprintf("--%s of the source is required", flagSourceSHA256))
+	}
+
+	if opts.ChainID == "" {
+		problems = append(problems, fmt.Sprintf("--%s is required", flags.FlagChainID))
+	}
+	for _, flag := range []struct{ name, value, absolute string }{
+		{flagGenesisTime, opts.GenesisTime, "an RFC 3339 time"},
+		{flagInitialHeight, opts.InitialHeight, "a height"},
+	} {
+		if flag.value == "" {
+			problems = append(problems, fmt.Sprintf("--%s is required", flag.name))
+		} else if strings.HasPrefix(flag.value, "+") {
+			problems = append(problems, fmt.Sprintf("--%s %s is relative, give %s", flag.name, flag.value, flag.absolute))
+		}
+	}
+
+	if opts.Output == "" && opts.BundleDir == "" {
+		problems = append(problems, fmt.Sprintf("--%s or --%s is required, the genesis is not written to STDOUT", flagOutputFile, flagBundleDir))
+	}
+
+	for _, name := range strictForbiddenFlags {
+		if opts.Changed[name] {
+			problems = append(problems, fmt.Sprintf("--%s is forbidden", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("--%s: %s", flagStrict, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// selfCheckOutput fails unless the canonical JSON genesis bz is what the
+// output stage produces from bz again: decoding it, normalizing its order
+// unless noNormalizeOrder, re-encoding the module genesis and sorting it must
+// give back the same bytes. An output depending on map order, or on anything
+// but the genesis, would otherwise differ between the validators migrating it.
+func selfCheckOutput(cdc codec.JSONMarshaler, bz []byte, noNormalizeOrder bool) error {
+	genDoc, err := tmtypes.GenesisDocFromJSON(bz)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the migrated genesis")
+	}
+
+	if !noNormalizeOrder {
+		if err := normalizeGenesisOrder(cdc, genDoc); err != nil {
+			return errors.Wrap(err, "failed to normalize genesis order")
+		}
+	}
+
+	if genDoc.AppState, err = canonicalAppState(cdc, genDoc.AppState); err != nil {
+		return err
+	}
+
+	again, err := tmjson.Marshal(genDoc)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal genesis doc")
+	}
+	if again, err = canonicalJSON(again, true); err != nil {
+		return errors.Wrap(err, "failed to sort JSON genesis doc")
+	}
+
+	if !bytes.Equal(again, bz) {
+		i := 0
+		for i < len(again) && i < len(bz) && again[i] == bz[i] {
+			i++
+		}
+
+		return fmt.Errorf("the migrated genesis is not reproduced from itself at byte %d: %q instead of %q",
+			i, excerpt(again, i), excerpt(bz, i))
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package gaia
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictModeOptionsValidate(t *testing.T) {
+	valid := func() strictModeOptions {
+		return strictModeOptions{
+			Source:        "exported.json",
+			SourceSHA256:  strings.Repeat("0", 64),
+			ChainID:       "cosmoshub-4",
+			GenesisTime:   "2021-02-18T17:00:00Z",
+			InitialHeight: "5200791",
+			Output:        "genesis.json",
+			Changed:       map[string]bool{},
+		}
+	}
+	require.NoError(t, valid().Validate())
+
+	testCases := []struct {
+		name   string
+		modify func(opts *strictModeOptions)
+		err    string
+	}{
+		{"bundle instead of output", func(opts *strictModeOptions) { opts.Output, opts.BundleDir = "", "bundle" }, ""},
+		{"deliberate state change", func(opts *strictModeOptions) { opts.Changed[flagProp29Data] = true }, ""},
+		{"URL source", func(opts *strictModeOptions) { opts.Source = "https://example.com/genesis.json" }, "the source is a URL, download and verify it first"},
+		{"STDIN source", func(opts *strictModeOptions) { opts.Source = stdinGenesis }, "the source is read from STDIN, pass the file"},
+		{"no source SHA-256", func(opts *strictModeOptions) { opts.SourceSHA256 = "" }, "--source-sha256 of the source is required"},
+		{"no chain ID", func(opts *strictModeOptions) { opts.ChainID = "" }, "--chain-id is required"},
+		{"no genesis time", func(opts *strictModeOptions) { opts.GenesisTime = "" }, "--genesis-time is required"},
+		{"relative genesis time", func(opts *strictModeOptions) { opts.GenesisTime = "+45m" }, "--genesis-time +45m is relative, give an RFC 3339 time"},
+		{"no initial height", func(opts *strictModeOptions) { opts.InitialHeight = "" }, "--initial-height is required"},
+		{"relative initial height", func(opts *strictModeOptions) { opts.InitialHeight = "+1" }, "--initial-height +1 is relative, give a height"},
+		{"STDOUT output", func(opts *strictModeOptions) { opts.Output = "" }, "--output or --bundle-dir is required, the genesis is not written to STDOUT"},
+	}
+	for _, name := range strictForbiddenFlags {
+		name := name
+		testCases = append(testCases, struct {
+			name   string
+			modify func(opts *strictModeOptions)
+			err    string
+		}{"--" + name, func(opts *strictModeOptions) { opts.Changed[name] = true }, "--" + name + " is forbidden"})
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := valid()
+			tc.modify(&opts)
+
+			err := opts.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, "--strict: "+tc.err)
+		})
+	}
+
+	// every problem is reported at once
+	opts := valid()
+	opts.GenesisTime, opts.InitialHeight, opts.Output = "", "+1", ""
+	opts.Changed[flagRepairCaps] = true
+	require.EqualError(t, opts.Validate(), "--strict: --genesis-time is required; --initial-height +1 is relative, give a height; "+
+		"--output or --bundle-dir is required, the genesis is not written to STDOUT; --repair-capabilities is forbidden")
+}
+
+func TestSelfCheckOutput(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	source := filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json")
+	out, err := executeMigrate(t, source, "--chain-id", "cosmoshub-4", "--initial-height", "5200791")
+	require.NoError(t, err)
+	out = []byte(strings.TrimSuffix(string(out), "\n"))
+
+	require.NoError(t, selfCheckOutput(cdc, out, false))
+
+	// a genesis the output stage does not produce
+	unsorted := strings.Replace(string(out), `{"app_hash":"",`, `{"app_hash":"","z":1,`, 1)
+	require.NotEqual(t, string(out), unsorted)
+	require.Regexp(t, `^the migrated genesis is not reproduced from itself at byte \d+`, selfCheckOutput(cdc, []byte(unsorted), false).Error())
+}
+
+func TestMigrateStrict(t *testing.T) {
+	source := filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json")
+	bz, err := ioutil.ReadFile(source)
+	require.NoError(t, err)
+	sum := sha256.Sum256(bz)
+	output := filepath.Join(t.TempDir(), "genesis.json")
+
+	args := []string{source, "--strict", "--source-sha256", hex.EncodeToString(sum[:]), "--chain-id", "cosmoshub-4",
+		"--genesis-time", "2021-02-18T17:00:00Z", "--initial-height", "5200791", "--output", output}
+
+	_, err = executeMigrate(t, append(args, "--repair-capabilities", "--initial-height", "+1")...)
+	require.EqualError(t, err, "--strict: --initial-height +1 is relative, give a height; --repair-capabilities is forbidden")
+	require.NoFileExists(t, output)
+
+	out, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+	require.Empty(t, out)
+
+	// the strict run writes the genesis of the same migration without it
+	expected, err := executeMigrate(t, append([]string{source}, args[2:len(args)-2]...)...)
+	require.NoError(t, err)
+	migrated, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(migrated))
+}
@@ -0,0 +1,261 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// migrationDataFiles holds the data tables of the migration as JSON files,
+// named data/<name>.json, so they are reviewed as data rather than as Go
+// source.
+//
+//go:embed data/*.json
+var migrationDataFiles embed.FS
+
+// migrationDataAsset is an embedded data table with the SHA-256 of its
+// reviewed file compiled in. A binary built with a changed file fails to
+// migrate until the hash is updated too.
+type migrationDataAsset struct {
+	Name        string
+	SHA256      string
+	Description string
+}
+
+var migrationData = []migrationDataAsset{
+	{
+		Name:        "gov-content-mappings",
+		SHA256:      "d15999239bc075522d16fa4c69cfada27113e9088c30274a3c502279315e8ca6",
+		Description: "The proposal content types of the cosmoshub-3 gov genesis that are migrated, by their amino name",
+	},
+	{
+		Name:        "legacy-eras",
+		SHA256:      "3dbbdc2c5f2faa8da1d277a21c27f9a31be907669d0f55b2683e768ab964799c",
+		Description: fmt.Sprintf("The renames, defaults and SDK migrations normalizing the exports of the --%s eras", flagLegacySource),
+	},
+}
+
+// migrationDataHashes returns the compiled-in SHA-256 of every data table, as
+// recorded in the manifest.
+func migrationDataHashes() []genesis.MigrationData {
+	hashes := make([]genesis.MigrationData, len(migrationData))
+	for i, asset := range migrationData {
+		hashes[i] = genesis.MigrationData{Name: asset.Name, SHA256: asset.SHA256}
+	}
+
+	return hashes
+}
+
+// readMigrationData returns the file of the data table name in files and
+// fails unless it has the compiled-in SHA-256.
+func readMigrationData(files fs.FS, name string) ([]byte, error) {
+	for _, asset := range migrationData {
+		if asset.Name != name {
+			continue
+		}
+
+		bz, err := fs.ReadFile(files, "data/"+name+".json")
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(bz)
+		if actual := hex.EncodeToString(sum[:]); actual != asset.SHA256 {
+			return nil, fmt.Errorf("migration data %s has SHA-256 %s instead of %s", name, actual, asset.SHA256)
+		}
+
+		return bz, nil
+	}
+
+	return nil, fmt.Errorf("unknown migration data %s, one of %s", name, strings.Join(migrationDataNames(), ", "))
+}
+
+// verifyMigrationData checks every data table of files against its
+// compiled-in SHA-256.
+func verifyMigrationData(files fs.FS) error {
+	for _, asset := range migrationData {
+		if _, err := readMigrationData(files, asset.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migrationDataNames() []string {
+	names := make([]string, len(migrationData))
+	for i, asset := range migrationData {
+		names[i] = asset.Name
+	}
+
+	return names
+}
+
+// decodeMigrationData strictly decodes the data table name into v. Its hash
+// is checked by verifyMigrationData as migrate starts, the other commands
+// run with a changed table.
+func decodeMigrationData(files fs.FS, name string, v interface{}) error {
+	bz, err := fs.ReadFile(files, "data/"+name+".json")
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid migration data %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// legacyEraData is the JSON encoding of a legacy era in data/legacy-eras.json.
+// The notes are for the reviewers.
+type legacyEraData struct {
+	Note    string `json:"note"`
+	Renames []struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Note string `json:"note"`
+	} `json:"renames"`
+	Defaults []struct {
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+		Note  string          `json:"note"`
+	} `json:"defaults"`
+	Migrations []string `json:"migrations"`
+}
+
+// loadLegacyEras decodes the legacy eras of files.
+func loadLegacyEras(files fs.FS) (map[string]legacyEra, error) {
+	var data map[string]legacyEraData
+	if err := decodeMigrationData(files, "legacy-eras", &data); err != nil {
+		return nil, err
+	}
+
+	eras := make(map[string]legacyEra, len(data))
+	for name, eraData := range data {
+		var era legacyEra
+		for _, rename := range eraData.Renames {
+			era.Renames = append(era.Renames, legacyRename{rename.From, rename.To})
+		}
+		for _, def := range eraData.Defaults {
+			era.Defaults = append(era.Defaults, legacyDefault{def.Path, string(def.Value)})
+		}
+		era.Migrations = eraData.Migrations
+		eras[name] = era
+	}
+
+	return eras, nil
+}
+
+// legacyContentMappingData is the JSON encoding of a content mapping in
+// data/gov-content-mappings.json. Transform names one of contentTransforms,
+// DropFields are removed from the content.
+type legacyContentMappingData struct {
+	Type       string   `json:"type"`
+	Transform  string   `json:"transform"`
+	DropFields []string `json:"drop_fields"`
+	Note       string   `json:"note"`
+}
+
+// contentTransforms are the transforms of the content fields the content
+// mappings name.
+var contentTransforms = map[string]func(map[string]json.RawMessage) error{
+	"param-changes": transformParamChanges,
+	"upgrade-plan":  transformUpgradePlan,
+}
+
+// loadLegacyContentMappings decodes the gov content mappings of files.
+func loadLegacyContentMappings(files fs.FS) (map[string]legacyContentMapping, error) {
+	var data map[string]legacyContentMappingData
+	if err := decodeMigrationData(files, "gov-content-mappings", &data); err != nil {
+		return nil, err
+	}
+
+	mappings := make(map[string]legacyContentMapping, len(data))
+	for name, mappingData := range data {
+		mapping := legacyContentMapping{Type: mappingData.Type}
+		switch {
+		case mappingData.Transform != "" && len(mappingData.DropFields) > 0:
+			return nil, fmt.Errorf("gov content mapping %s both transforms and drops fields", name)
+		case mappingData.Transform != "":
+			transform, ok := contentTransforms[mappingData.Transform]
+			if !ok {
+				return nil, fmt.Errorf("gov content mapping %s has the unknown transform %s", name, mappingData.Transform)
+			}
+			mapping.Transform = transform
+		case len(mappingData.DropFields) > 0:
+			mapping.Transform = dropContentFields(mappingData.DropFields...)
+		}
+		mappings[name] = mapping
+	}
+
+	return mappings, nil
+}
+
+// mustLoadLegacyEras and mustLoadLegacyContentMappings panic on an invalid
+// embedded data table, which TestMigrationData rules out.
+func mustLoadLegacyEras() map[string]legacyEra {
+	eras, err := loadLegacyEras(migrationDataFiles)
+	if err != nil {
+		panic(err)
+	}
+
+	return eras
+}
+
+func mustLoadLegacyContentMappings() map[string]legacyContentMapping {
+	mappings, err := loadLegacyContentMappings(migrationDataFiles)
+	if err != nil {
+		panic(err)
+	}
+
+	return mappings
+}
+
+// MigrateShowDataCmd returns the migrate show-data command printing an
+// embedded data table.
+func MigrateShowDataCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show-data [name]",
+		Short: "Print a data table embedded in the migration and its SHA-256",
+		Long: fmt.Sprintf(`Print the JSON file of a data table embedded in the migration to STDOUT and its
+SHA-256, which the migrate manifest records, to STDERR, so it can be compared
+with the audited file. Without a name list the tables: %s.`, strings.Join(migrationDataNames(), ", ")),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				assets := append([]migrationDataAsset{}, migrationData...)
+				sort.Slice(assets, func(i, j int) bool { return assets[i].Name < assets[j].Name })
+				for _, asset := range assets {
+					cmd.Printf("%s  %s\n  %s\n", asset.SHA256, asset.Name, asset.Description)
+				}
+				return nil
+			}
+
+			bz, err := readMigrationData(migrationDataFiles, args[0])
+			if err != nil {
+				return err
+			}
+
+			if _, err := cmd.OutOrStdout().Write(bz); err != nil {
+				return err
+			}
+			sum := sha256.Sum256(bz)
+			cmd.PrintErrf("sha256 %s\n", hex.EncodeToString(sum[:]))
+
+			return nil
+		},
+	}
+}
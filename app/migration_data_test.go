@@ -0,0 +1,142 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+func TestMigrationData(t *testing.T) {
+	require.NoError(t, verifyMigrationData(migrationDataFiles))
+
+	for _, asset := range migrationData {
+		bz, err := ioutil.ReadFile(filepath.Join("data", asset.Name+".json"))
+		require.NoError(t, err)
+		sum := sha256.Sum256(bz)
+		require.Equal(t, hex.EncodeToString(sum[:]), asset.SHA256, "update the SHA-256 of %s once it is reviewed", asset.Name)
+	}
+
+	// the tables are those of the Go source they replaced
+	require.Equal(t, map[string]legacyEra{
+		"cosmoshub-2": {
+			Renames: []legacyRename{
+				{"consensus_params.block_size", "consensus_params.block"},
+				{"app_state.staking.pool.loose_tokens", "app_state.staking.pool.not_bonded_tokens"},
+			},
+			Defaults:   []legacyDefault{{"consensus_params.block.time_iota_ms", `"1000"`}},
+			Migrations: []string{"v0.36"},
+		},
+	}, legacyEras)
+
+	types := make(map[string]string, len(legacyContentMappings))
+	transforms := make(map[string]bool, len(legacyContentMappings))
+	for name, mapping := range legacyContentMappings {
+		types[name] = mapping.Type
+		transforms[name] = mapping.Transform != nil
+	}
+	require.Equal(t, map[string]string{
+		"cosmos-sdk/TextProposal":                  "cosmos-sdk/TextProposal",
+		"cosmos-sdk/CommunityPoolSpendProposal":    "cosmos-sdk/CommunityPoolSpendProposal",
+		"cosmos-sdk/ParameterChangeProposal":       "cosmos-sdk/ParameterChangeProposal",
+		"cosmos-sdk/SoftwareUpgradeProposal":       "cosmos-sdk/SoftwareUpgradeProposal",
+		"cosmos-sdk/CancelSoftwareUpgradeProposal": "cosmos-sdk/CancelSoftwareUpgradeProposal",
+		"gov/TextProposal":                         "cosmos-sdk/TextProposal",
+		"params/ParameterChangeProposal":           "cosmos-sdk/ParameterChangeProposal",
+		"distribution/CommunityPoolSpendProposal":  "cosmos-sdk/CommunityPoolSpendProposal",
+		"upgrade/SoftwareUpgradeProposal":          "cosmos-sdk/SoftwareUpgradeProposal",
+		"upgrade/CancelSoftwareUpgradeProposal":    "cosmos-sdk/CancelSoftwareUpgradeProposal",
+	}, types)
+	require.Equal(t, map[string]bool{
+		"cosmos-sdk/TextProposal":                  false,
+		"cosmos-sdk/CommunityPoolSpendProposal":    false,
+		"cosmos-sdk/ParameterChangeProposal":       true,
+		"cosmos-sdk/SoftwareUpgradeProposal":       true,
+		"cosmos-sdk/CancelSoftwareUpgradeProposal": false,
+		"gov/TextProposal":                         true,
+		"params/ParameterChangeProposal":           true,
+		"distribution/CommunityPoolSpendProposal":  false,
+		"upgrade/SoftwareUpgradeProposal":          true,
+		"upgrade/CancelSoftwareUpgradeProposal":    false,
+	}, transforms)
+}
+
+func TestVerifyMigrationData(t *testing.T) {
+	files := fstest.MapFS{}
+	for _, asset := range migrationData {
+		bz, err := migrationDataFiles.ReadFile("data/" + asset.Name + ".json")
+		require.NoError(t, err)
+		files["data/"+asset.Name+".json"] = &fstest.MapFile{Data: bz}
+	}
+	require.NoError(t, verifyMigrationData(files))
+
+	// a table changed without updating its hash
+	eras := files["data/legacy-eras.json"]
+	files["data/legacy-eras.json"] = &fstest.MapFile{Data: bytes.Replace(eras.Data, []byte(`"v0.36"`), []byte(`"v0.38"`), 1)}
+	require.Regexp(t, `^migration data legacy-eras has SHA-256 [0-9a-f]{64} instead of `+migrationData[1].SHA256+`$`, verifyMigrationData(files).Error())
+
+	// the decoding is strict
+	files["data/legacy-eras.json"] = &fstest.MapFile{Data: []byte(`{"cosmoshub-2": {"defualts": []}}`)}
+	_, err := loadLegacyEras(files)
+	require.EqualError(t, err, `invalid migration data legacy-eras: json: unknown field "defualts"`)
+
+	files["data/gov-content-mappings.json"] = &fstest.MapFile{Data: []byte(`{"a/TextProposal": {"type": "cosmos-sdk/TextProposal", "transform": "upgrade"}}`)}
+	_, err = loadLegacyContentMappings(files)
+	require.EqualError(t, err, "gov content mapping a/TextProposal has the unknown transform upgrade")
+
+	delete(files, "data/gov-content-mappings.json")
+	require.Error(t, verifyMigrationData(files))
+	_, err = readMigrationData(files, "prop29")
+	require.EqualError(t, err, "unknown migration data prop29, one of gov-content-mappings, legacy-eras")
+}
+
+func TestMigrateShowData(t *testing.T) {
+	run := func(args ...string) (string, string, error) {
+		cmd := MigrateGenesisCmd()
+		var out, stderr bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(append([]string{"show-data"}, args...))
+
+		err := cmd.Execute()
+		return out.String(), stderr.String(), err
+	}
+
+	out, stderr, err := run("legacy-eras")
+	require.NoError(t, err)
+	bz, err := ioutil.ReadFile(filepath.Join("data", "legacy-eras.json"))
+	require.NoError(t, err)
+	require.Equal(t, string(bz), out)
+	require.Equal(t, "sha256 "+migrationData[1].SHA256+"\n", stderr)
+
+	out, _, err = run()
+	require.NoError(t, err)
+	require.Contains(t, out, migrationData[0].SHA256+"  gov-content-mappings\n")
+	require.Contains(t, out, migrationData[1].SHA256+"  legacy-eras\n")
+
+	_, _, err = run("prop29")
+	require.Error(t, err)
+}
+
+func TestMigrateManifestData(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	_, err := executeMigrate(t, filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json"), "--chain-id", "cosmoshub-4", "--manifest", manifestPath)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var manifest migrationManifest
+	require.NoError(t, json.Unmarshal(bz, &manifest))
+	require.Equal(t, []genesis.MigrationData{
+		{Name: "gov-content-mappings", SHA256: migrationData[0].SHA256},
+		{Name: "legacy-eras", SHA256: migrationData[1].SHA256},
+	}, manifest.MigrationData)
+}
@@ -0,0 +1,58 @@
+package gaia
+
+import (
+	"encoding/json"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// migrationInfoKey is the app state key of the migration info written by
+// migrate --embed-migration-info.
+const migrationInfoKey = genesis.MigrationInfoKey
+
+// migrationInfo records which tool and which migrations produced a genesis.
+type migrationInfo = genesis.MigrationInfo
+
+func newMigrationInfo(target string, steps []string, sourceSHA256 string) migrationInfo {
+	info := version.NewInfo()
+
+	return migrationInfo{
+		GaiaVersion:         info.Version,
+		CosmosSDKVersion:    info.CosmosSdkVersion,
+		MigrationTarget:     target,
+		Steps:               steps,
+		SourceGenesisSHA256: sourceSHA256,
+	}
+}
+
+// embedMigrationInfo sets the migration info of the app state of genDoc.
+func embedMigrationInfo(genDoc *tmtypes.GenesisDoc, info migrationInfo) error {
+	var state types.AppMap
+	if err := json.Unmarshal(genDoc.AppState, &state); err != nil {
+		return errors.Wrap(err, "failed to JSON unmarshal app state")
+	}
+
+	bz, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrap(err, "failed to JSON marshal migration info")
+	}
+	state[migrationInfoKey] = bz
+
+	genDoc.AppState, err = json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "failed to JSON marshal app state")
+	}
+
+	return nil
+}
+
+// readMigrationInfo returns the migration info of an app state, nil if it
+// has none.
+func readMigrationInfo(state types.AppMap) (*migrationInfo, error) {
+	return genesis.ReadMigrationInfo(state)
+}
@@ -0,0 +1,94 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/stretchr/testify/require"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestEmbedMigrationInfo(t *testing.T) {
+	source, err := ioutil.ReadFile("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+	sum := sha256.Sum256(source)
+
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	out, err := executeMigrate(t, args...)
+	require.NoError(t, err)
+	require.Nil(t, migrationInfoOf(t, out))
+
+	out, err = executeMigrate(t, append(args, "--embed-migration-info", "--shift-all-times", "--genesis-time", "2021-07-01T00:00:00Z")...)
+	require.NoError(t, err)
+
+	info := migrationInfoOf(t, out)
+	require.NotNil(t, info)
+	require.Equal(t, "v0.40", info.MigrationTarget)
+	require.Equal(t, []string{"v0.36", "v0.38", "v0.39", "v0.40", flagShiftAllTimes, "normalize-order"}, info.Steps)
+	require.Equal(t, hex.EncodeToString(sum[:]), info.SourceGenesisSHA256)
+}
+
+func TestMigrationInfoInitChain(t *testing.T) {
+	genDoc, err := testGenesisBuilder().Build()
+	require.NoError(t, err)
+	require.NoError(t, embedMigrationInfo(genDoc, newMigrationInfo("v0.40", []string{"v0.38", "v0.39", "v0.40"}, "00")))
+
+	require.NoError(t, SmokeTestGenesis(genDoc))
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	out, err := executeGenesisValidate(t, bz)
+	require.NoError(t, err)
+	require.Contains(t, out, "migrated by gaia")
+	require.Contains(t, out, "steps [v0.38 v0.39 v0.40]")
+	require.Contains(t, out, "is a valid genesis file")
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+	state[migrationInfoKey] = json.RawMessage(`{"steps":[]}`)
+	genDoc.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+
+	bz, err = tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	_, err = executeGenesisValidate(t, bz)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid app_state.migration_info")
+}
+
+func migrationInfoOf(t *testing.T, genesis []byte) *migrationInfo {
+	genDoc, err := tmtypes.GenesisDocFromJSON(genesis)
+	require.NoError(t, err)
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	info, err := readMigrationInfo(state)
+	require.NoError(t, err)
+
+	return info
+}
+
+func executeGenesisValidate(t *testing.T, genesis []byte, args ...string) (string, error) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, genesis, 0644))
+
+	cmd := GenesisValidateCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(ioutil.Discard)
+	cmd.SetArgs(append([]string{path}, args...))
+
+	err := cmd.Execute()
+	return out.String(), err
+}
@@ -0,0 +1,96 @@
+package gaia
+
+import (
+	"fmt"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+)
+
+// mintYear is the year length the default mint blocks_per_year assumes,
+// 6311520 blocks of 5s.
+const mintYear = 8766 * time.Hour
+
+// mintBlocksPerYearTolerance is the relative deviation between the configured
+// and the block time implied blocks_per_year above which migrate warns.
+var mintBlocksPerYearTolerance = sdk.NewDecWithPrec(1, 2)
+
+// mintOverrides are the mint values set from the migrate flags, nil fields are
+// left untouched.
+type mintOverrides struct {
+	BlocksPerYear *uint64
+	Inflation     *sdk.Dec
+}
+
+// blocksPerYear returns the number of blocks produced in a year at the given
+// block time.
+func blocksPerYear(blockTime time.Duration) uint64 {
+	return uint64(mintYear / blockTime)
+}
+
+// impliedAnnualInflation returns the inflation actually minted over a year
+// when blocks come every blockTime while the minter divides the annual
+// provisions by the configured blocks_per_year.
+func impliedAnnualInflation(params mint.Params, inflation sdk.Dec, blockTime time.Duration) sdk.Dec {
+	return inflation.MulInt64(int64(blocksPerYear(blockTime))).QuoInt64(int64(params.BlocksPerYear))
+}
+
+// applyMintOverrides applies the overrides to the mint genesis and validates
+// the result with the mint module's validators.
+func applyMintOverrides(mintGenesis *mint.GenesisState, overrides mintOverrides) error {
+	if overrides.BlocksPerYear != nil {
+		mintGenesis.Params.BlocksPerYear = *overrides.BlocksPerYear
+	}
+
+	if overrides.Inflation != nil {
+		mintGenesis.Minter.Inflation = *overrides.Inflation
+	}
+
+	if err := mintGenesis.Params.Validate(); err != nil {
+		return fmt.Errorf("invalid mint params: %w", err)
+	}
+
+	if err := mint.ValidateMinter(mintGenesis.Minter); err != nil {
+		return fmt.Errorf("invalid mint minter: %w", err)
+	}
+
+	if overrides.Inflation != nil && !inflationInBounds(mintGenesis) {
+		return fmt.Errorf("mint inflation %s is outside [%s, %s]",
+			mintGenesis.Minter.Inflation, mintGenesis.Params.InflationMin, mintGenesis.Params.InflationMax)
+	}
+
+	return nil
+}
+
+func inflationInBounds(mintGenesis *mint.GenesisState) bool {
+	inflation := mintGenesis.Minter.Inflation
+	return inflation.GTE(mintGenesis.Params.InflationMin) && inflation.LTE(mintGenesis.Params.InflationMax)
+}
+
+// checkMintParams registers a warning for every inconsistency of the mint
+// genesis. The blocks_per_year check is skipped for a zero blockTime.
+func checkMintParams(mintGenesis mint.GenesisState, blockTime time.Duration, warnings *warningCollector) {
+	params := mintGenesis.Params
+	inflation := mintGenesis.Minter.Inflation
+
+	if !inflationInBounds(&mintGenesis) {
+		warnings.Add(warnMintInflationBounds, severityMedium, mint.ModuleName,
+			"inflation %s is outside [%s, %s]", inflation, params.InflationMin, params.InflationMax)
+	}
+
+	if !params.GoalBonded.IsPositive() || params.GoalBonded.GT(sdk.OneDec()) {
+		warnings.Add(warnMintGoalBonded, severityMedium, mint.ModuleName, "goal_bonded %s is outside (0, 1]", params.GoalBonded)
+	}
+
+	if blockTime > 0 && params.BlocksPerYear > 0 {
+		expected := blocksPerYear(blockTime)
+		deviation := sdk.NewDec(int64(params.BlocksPerYear) - int64(expected)).Abs().QuoInt64(int64(expected))
+
+		if deviation.GT(mintBlocksPerYearTolerance) {
+			warnings.Add(warnMintBlocksPerYear, severityHigh, mint.ModuleName,
+				"blocks_per_year %d does not match %d blocks of %s per year, the chain would mint %s annual inflation instead of %s",
+				params.BlocksPerYear, expected, blockTime, impliedAnnualInflation(params, inflation, blockTime), inflation)
+		}
+	}
+}
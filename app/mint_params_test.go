@@ -0,0 +1,67 @@
+package gaia
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocksPerYear(t *testing.T) {
+	require.Equal(t, mint.DefaultParams().BlocksPerYear, blocksPerYear(5*time.Second))
+	require.Equal(t, uint64(4508228), blocksPerYear(7*time.Second))
+
+	// blocks_per_year tuned for 5s blocks on a 7s chain mints 5/7 of the
+	// intended inflation
+	params := mint.DefaultParams()
+	inflation := sdk.NewDecWithPrec(14, 2)
+	require.Equal(t, "0.099999987324764874", impliedAnnualInflation(params, inflation, 7*time.Second).String())
+	require.Equal(t, inflation, impliedAnnualInflation(params, inflation, 5*time.Second))
+}
+
+func TestCheckMintParams(t *testing.T) {
+	mintGenesis := *mint.DefaultGenesisState()
+	mintGenesis.Minter.Inflation = sdk.NewDecWithPrec(10, 2)
+
+	for _, blockTime := range []time.Duration{0, 5 * time.Second, 5020 * time.Millisecond} {
+		var warnings warningCollector
+		checkMintParams(mintGenesis, blockTime, &warnings)
+		require.Empty(t, warnings.Warnings(), blockTime)
+	}
+
+	var warnings warningCollector
+	checkMintParams(mintGenesis, 7*time.Second, &warnings)
+	require.Len(t, warnings.Warnings(), 1)
+	require.Equal(t, warnMintBlocksPerYear, warnings.Warnings()[0].Code)
+	require.Contains(t, warnings.Warnings()[0].Message, "blocks_per_year 6311520 does not match 4508228 blocks of 7s per year")
+
+	mintGenesis.Minter.Inflation = sdk.NewDecWithPrec(25, 2)
+	mintGenesis.Params.GoalBonded = sdk.ZeroDec()
+	warnings = warningCollector{}
+	checkMintParams(mintGenesis, 0, &warnings)
+	require.Len(t, warnings.Warnings(), 2)
+	require.Equal(t, warnMintInflationBounds, warnings.Warnings()[0].Code)
+	require.Contains(t, warnings.Warnings()[0].Message, "is outside [0.070000000000000000, 0.200000000000000000]")
+	require.Equal(t, warnMintGoalBonded, warnings.Warnings()[1].Code)
+}
+
+func TestApplyMintOverrides(t *testing.T) {
+	blocks := blocksPerYear(7 * time.Second)
+	inflation := sdk.NewDecWithPrec(15, 2)
+
+	mintGenesis := mint.DefaultGenesisState()
+	require.NoError(t, applyMintOverrides(mintGenesis, mintOverrides{BlocksPerYear: &blocks, Inflation: &inflation}))
+	require.Equal(t, blocks, mintGenesis.Params.BlocksPerYear)
+	require.Equal(t, inflation, mintGenesis.Minter.Inflation)
+	var warnings warningCollector
+	checkMintParams(*mintGenesis, 7*time.Second, &warnings)
+	require.Empty(t, warnings.Warnings())
+
+	zero := uint64(0)
+	require.Error(t, applyMintOverrides(mint.DefaultGenesisState(), mintOverrides{BlocksPerYear: &zero}))
+
+	tooHigh := sdk.NewDecWithPrec(30, 2)
+	require.Error(t, applyMintOverrides(mint.DefaultGenesisState(), mintOverrides{Inflation: &tooHigh}))
+}
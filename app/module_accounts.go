@@ -0,0 +1,329 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	liquiditytypes "github.com/gravity-devs/liquidity/x/liquidity/types"
+	"github.com/pkg/errors"
+)
+
+// moduleAccountBalance compares the bank balance of a module account with the
+// balance the genesis of its module accounts for, as the module invariants
+// do. Surplus and Deficit are what the balance holds above and below it per
+// denom, Swept the part of the surplus --sweep-module-dust moved away and
+// Funded what --fund-community-pool moved in from FundedFrom, balance and
+// expected balance included.
+type moduleAccountBalance struct {
+	Name       string    `json:"name"`
+	Address    string    `json:"address"`
+	Expected   sdk.Coins `json:"expected"`
+	Balance    sdk.Coins `json:"balance"`
+	Surplus    sdk.Coins `json:"surplus"`
+	Deficit    sdk.Coins `json:"deficit"`
+	Swept      sdk.Coins `json:"swept"`
+	Funded     sdk.Coins `json:"funded"`
+	FundedFrom string    `json:"funded_from,omitempty"`
+}
+
+// Balanced tells whether the balance is what the module genesis accounts for
+// once the swept surplus is gone.
+func (b moduleAccountBalance) Balanced() bool {
+	return b.Deficit.IsZero() && b.Surplus.IsEqual(b.Swept)
+}
+
+// auditModuleAccounts returns the balance audit of every gaia module account,
+// sorted by name. The expected balances are those at genesis: the truncated
+// community pool and outstanding rewards for distribution, the deposits for
+// gov, the tokens of the bonded validators for the bonded pool, those of the
+// other validators and the unbonding delegations for the not bonded pool,
+// the batch escrows for liquidity and nothing for the others, which only pass
+// coins through within a block.
+func auditModuleAccounts(cdc codec.JSONMarshaler, state types.AppMap) ([]moduleAccountBalance, error) {
+	var bankGenesis bank.GenesisState
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return nil, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", bank.ModuleName)
+	}
+
+	expected, err := expectedModuleBalances(cdc, state)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]sdk.Coins, len(bankGenesis.Balances))
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balances[balance.Address].Add(balance.Coins...)
+	}
+
+	names := make([]string, 0, len(maccPerms))
+	for name := range maccPerms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	audit := make([]moduleAccountBalance, 0, len(names))
+	for _, name := range names {
+		addr := auth.NewModuleAddress(name).String()
+		balance := sdk.NewCoins(balances[addr]...)
+		surplus, deficit := coinsDelta(balance, expected[name])
+
+		audit = append(audit, moduleAccountBalance{
+			Name:     name,
+			Address:  addr,
+			Expected: sdk.NewCoins(expected[name]...),
+			Balance:  balance,
+			Surplus:  surplus,
+			Deficit:  deficit,
+			Swept:    sdk.NewCoins(),
+			Funded:   sdk.NewCoins(),
+		})
+	}
+
+	return audit, nil
+}
+
+// moduleAccountName returns the name of the gaia module account at addr, if
+// it is one.
+func moduleAccountName(addr sdk.AccAddress) (string, bool) {
+	for name := range maccPerms {
+		if addr.Equals(auth.NewModuleAddress(name)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// expectedModuleBalances returns the balances the module genesis states of
+// state account for, by module account name. A missing module genesis
+// accounts for nothing.
+func expectedModuleBalances(cdc codec.JSONMarshaler, state types.AppMap) (map[string]sdk.Coins, error) {
+	expected := make(map[string]sdk.Coins)
+	unmarshal := func(module string, genesis codec.ProtoMarshaler) (bool, error) {
+		bz, ok := state[module]
+		if !ok {
+			return false, nil
+		}
+		if err := cdc.UnmarshalJSON(bz, genesis); err != nil {
+			return false, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", module)
+		}
+		return true, nil
+	}
+
+	var distributionGenesis distribution.GenesisState
+	if ok, err := unmarshal(distribution.ModuleName, &distributionGenesis); err != nil {
+		return nil, err
+	} else if ok {
+		accounted := distributionGenesis.FeePool.CommunityPool
+		for _, rewards := range distributionGenesis.OutstandingRewards {
+			accounted = accounted.Add(rewards.OutstandingRewards...)
+		}
+		expected[distribution.ModuleName], _ = accounted.TruncateDecimal()
+	}
+
+	var govGenesis gov.GenesisState
+	if ok, err := unmarshal(gov.ModuleName, &govGenesis); err != nil {
+		return nil, err
+	} else if ok {
+		deposits := sdk.NewCoins()
+		for _, deposit := range govGenesis.Deposits {
+			deposits = deposits.Add(deposit.Amount...)
+		}
+		expected[gov.ModuleName] = deposits
+	}
+
+	var stakingGenesis staking.GenesisState
+	if ok, err := unmarshal(staking.ModuleName, &stakingGenesis); err != nil {
+		return nil, err
+	} else if ok {
+		bonded, notBonded := sdk.ZeroInt(), sdk.ZeroInt()
+		for _, val := range stakingGenesis.Validators {
+			if val.IsBonded() {
+				bonded = bonded.Add(val.Tokens)
+			} else {
+				notBonded = notBonded.Add(val.Tokens)
+			}
+		}
+		for _, ubd := range stakingGenesis.UnbondingDelegations {
+			for _, entry := range ubd.Entries {
+				notBonded = notBonded.Add(entry.Balance)
+			}
+		}
+
+		bondDenom := stakingGenesis.Params.BondDenom
+		expected[staking.BondedPoolName] = sdk.NewCoins(sdk.NewCoin(bondDenom, bonded))
+		expected[staking.NotBondedPoolName] = sdk.NewCoins(sdk.NewCoin(bondDenom, notBonded))
+	}
+
+	var liquidityGenesis liquiditytypes.GenesisState
+	if ok, err := unmarshal(liquiditytypes.ModuleName, &liquidityGenesis); err != nil {
+		return nil, err
+	} else if ok {
+		escrow := sdk.NewCoins()
+		add := func(coins ...sdk.Coin) {
+			for _, coin := range coins {
+				if coin.Denom != "" && coin.IsPositive() {
+					escrow = escrow.Add(coin)
+				}
+			}
+		}
+
+		for _, record := range liquidityGenesis.PoolRecords {
+			for _, msg := range record.DepositMsgStates {
+				if !msg.ToBeDeleted && msg.Msg != nil {
+					add(msg.Msg.DepositCoins...)
+				}
+			}
+			for _, msg := range record.WithdrawMsgStates {
+				if !msg.ToBeDeleted && msg.Msg != nil {
+					add(msg.Msg.PoolCoin)
+				}
+			}
+			for _, msg := range record.SwapMsgStates {
+				if !msg.ToBeDeleted {
+					add(msg.RemainingOfferCoin, msg.ReservedOfferCoinFee)
+				}
+			}
+		}
+		expected[liquiditytypes.ModuleName] = escrow
+	}
+
+	return expected, nil
+}
+
+// coinsDelta returns what balance holds above and below expected per denom.
+func coinsDelta(balance, expected sdk.Coins) (surplus, deficit sdk.Coins) {
+	surplus, deficit = sdk.NewCoins(), sdk.NewCoins()
+
+	denoms := make(map[string]bool)
+	for _, coin := range append(append(sdk.Coins{}, balance...), expected...) {
+		denoms[coin.Denom] = true
+	}
+
+	for denom := range denoms {
+		delta := balance.AmountOf(denom).Sub(expected.AmountOf(denom))
+		switch {
+		case delta.IsPositive():
+			surplus = surplus.Add(sdk.NewCoin(denom, delta))
+		case delta.IsNegative():
+			deficit = deficit.Add(sdk.NewCoin(denom, delta.Neg()))
+		}
+	}
+
+	return surplus, deficit
+}
+
+// sweepModuleDust moves the surplus of the audited module accounts to
+// destination, a bech32 account address or blockedCommunityPool, and records
+// it as swept. The total supply is unchanged. Only surpluses are swept,
+// covering a deficit would need coins no account can spare, and destination
+// cannot be a module account. Protected module accounts are not swept.
+func sweepModuleDust(cdc codec.JSONMarshaler, state types.AppMap, audit []moduleAccountBalance, destination string, protected *protectedAddresses) error {
+	toCommunityPool := destination == blockedCommunityPool
+	if toCommunityPool {
+		destination = auth.NewModuleAddress(distribution.ModuleName).String()
+	} else {
+		addr, err := sdk.AccAddressFromBech32(destination)
+		if err != nil {
+			return errors.Wrap(err, "invalid module dust destination")
+		}
+		destination = addr.String()
+
+		for _, acc := range audit {
+			if acc.Address == destination {
+				return fmt.Errorf("module dust destination %s is the %s module account", destination, acc.Name)
+			}
+		}
+	}
+
+	swept := make(map[string]sdk.Coins)
+	total := sdk.NewCoins()
+	for i := range audit {
+		if audit[i].Surplus.IsZero() {
+			continue
+		}
+
+		skip, err := protected.skip(flagSweepModuleDust, audit[i].Address)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+
+		audit[i].Swept = audit[i].Surplus
+		swept[audit[i].Address] = audit[i].Surplus
+		total = total.Add(audit[i].Surplus...)
+	}
+
+	if total.IsZero() {
+		return nil
+	}
+
+	var (
+		authGenesis         auth.GenesisState
+		bankGenesis         bank.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return err
+	}
+
+	hasDestination := false
+	var nextAccountNumber uint64
+	for _, acc := range accounts {
+		hasDestination = hasDestination || acc.GetAddress().String() == destination
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+	}
+
+	if !hasDestination {
+		destinationAddr, _ := sdk.AccAddressFromBech32(destination)
+		accounts = append(accounts, auth.NewBaseAccount(destinationAddr, nil, nextAccountNumber, 0))
+
+		authGenesis.Accounts, err = auth.PackAccounts(accounts)
+		if err != nil {
+			return err
+		}
+		state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	}
+
+	destinationCoins := total
+	balances := bankGenesis.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		if surplus, ok := swept[balance.Address]; ok {
+			balance.Coins = balance.Coins.Sub(surplus)
+		}
+
+		if balance.Address == destination {
+			destinationCoins = destinationCoins.Add(balance.Coins...)
+			continue
+		}
+
+		if !balance.Coins.IsZero() {
+			balances = append(balances, balance)
+		}
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(balances, bank.Balance{Address: destination, Coins: destinationCoins}))
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	if toCommunityPool {
+		cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+		distributionGenesis.FeePool.CommunityPool = distributionGenesis.FeePool.CommunityPool.Add(sdk.NewDecCoinsFromCoins(total...)...)
+		state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package gaia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	ibcxfertypes "github.com/cosmos/cosmos-sdk/x/ibc/applications/transfer/types"
+	mint "github.com/cosmos/cosmos-sdk/x/mint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	liquiditytypes "github.com/gravity-devs/liquidity/x/liquidity/types"
+	"github.com/stretchr/testify/require"
+)
+
+// addModuleBalance adds coins to the bank balance of the named module account
+// and to the supply.
+func addModuleBalance(t *testing.T, state types.AppMap, name string, coins sdk.Coins) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+
+	addr := auth.NewModuleAddress(name).String()
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(bankGenesis.Balances, bank.Balance{Address: addr, Coins: coins}))
+	merged := bankGenesis.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		if n := len(merged); n > 0 && merged[n-1].Address == balance.Address {
+			merged[n-1].Coins = merged[n-1].Coins.Add(balance.Coins...)
+			continue
+		}
+		merged = append(merged, balance)
+	}
+	bankGenesis.Balances = merged
+	bankGenesis.Supply = bankGenesis.Supply.Add(coins...)
+
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+}
+
+func moduleAccountAudit(t *testing.T, audit []moduleAccountBalance, name string) moduleAccountBalance {
+	for _, acc := range audit {
+		if acc.Name == name {
+			return acc
+		}
+	}
+
+	require.Failf(t, "module account not audited", "%s", name)
+	return moduleAccountBalance{}
+}
+
+func TestAuditModuleAccounts(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	atoms := func(amount int64) sdk.Coins { return sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, amount)) }
+
+	_, state := buildTestGenesis(t, testGenesisBuilder())
+	audit, err := auditModuleAccounts(cdc, state)
+	require.NoError(t, err)
+	require.Len(t, audit, len(maccPerms))
+	for _, acc := range audit {
+		require.True(t, acc.Balanced(), "%s: %+v", acc.Name, acc)
+	}
+	// bob unbonds 500000 from validator 1
+	require.Equal(t, atoms(500000), moduleAccountAudit(t, audit, staking.NotBondedPoolName).Expected)
+
+	testCases := []struct {
+		name    string
+		account string
+		edit    func(types.AppMap)
+		surplus sdk.Coins
+		deficit sdk.Coins
+	}{
+		{
+			"fee collector dust", auth.FeeCollectorName,
+			func(state types.AppMap) { addModuleBalance(t, state, auth.FeeCollectorName, atoms(7)) },
+			atoms(7), sdk.NewCoins(),
+		},
+		{
+			"mint dust", mint.ModuleName,
+			func(state types.AppMap) { addModuleBalance(t, state, mint.ModuleName, atoms(1)) },
+			atoms(1), sdk.NewCoins(),
+		},
+		{
+			"transfer dust", ibcxfertypes.ModuleName,
+			func(state types.AppMap) {
+				addModuleBalance(t, state, ibcxfertypes.ModuleName, sdk.NewCoins(sdk.NewInt64Coin("ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", 3)))
+			},
+			sdk.NewCoins(sdk.NewInt64Coin("ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", 3)), sdk.NewCoins(),
+		},
+		{
+			"distribution truncation", distribution.ModuleName,
+			func(state types.AppMap) {
+				// 10.5 + 3.7 accounts for 14 whole coins
+				var distributionGenesis distribution.GenesisState
+				cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+				distributionGenesis.FeePool.CommunityPool = sdk.NewDecCoins(sdk.NewDecCoinFromDec(genesisbuilder.BondDenom, sdk.NewDecWithPrec(105, 1)))
+				distributionGenesis.OutstandingRewards = append(distributionGenesis.OutstandingRewards, distribution.ValidatorOutstandingRewardsRecord{
+					ValidatorAddress:   sdk.ValAddress(auth.NewModuleAddress("validator")).String(),
+					OutstandingRewards: sdk.NewDecCoins(sdk.NewDecCoinFromDec(genesisbuilder.BondDenom, sdk.NewDecWithPrec(37, 1))),
+				})
+				state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+				addModuleBalance(t, state, distribution.ModuleName, atoms(15))
+			},
+			atoms(1), sdk.NewCoins(),
+		},
+		{
+			"distribution community pool deficit", distribution.ModuleName,
+			func(state types.AppMap) {
+				var distributionGenesis distribution.GenesisState
+				cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+				distributionGenesis.FeePool.CommunityPool = sdk.NewDecCoins(sdk.NewInt64DecCoin(genesisbuilder.BondDenom, 20))
+				state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+			},
+			sdk.NewCoins(), atoms(20),
+		},
+		{
+			"gov deposit dropped", gov.ModuleName,
+			func(state types.AppMap) {
+				var govGenesis gov.GenesisState
+				cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+				require.Equal(t, atoms(10000000), govGenesis.Deposits[0].Amount)
+				govGenesis.Deposits = govGenesis.Deposits[1:]
+				state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+			},
+			atoms(10000000), sdk.NewCoins(),
+		},
+		{
+			"bonded pool deficit", staking.BondedPoolName,
+			func(state types.AppMap) {
+				var stakingGenesis staking.GenesisState
+				cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+				stakingGenesis.Validators[0].Tokens = stakingGenesis.Validators[0].Tokens.AddRaw(3)
+				state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+			},
+			sdk.NewCoins(), atoms(3),
+		},
+		{
+			"not bonded pool dust", staking.NotBondedPoolName,
+			func(state types.AppMap) { addModuleBalance(t, state, staking.NotBondedPoolName, atoms(2)) },
+			atoms(2), sdk.NewCoins(),
+		},
+		{
+			"liquidity escrow deficit", liquiditytypes.ModuleName,
+			func(state types.AppMap) {
+				var liquidityGenesis liquiditytypes.GenesisState
+				cdc.MustUnmarshalJSON(state[liquiditytypes.ModuleName], &liquidityGenesis)
+				liquidityGenesis.PoolRecords = append(liquidityGenesis.PoolRecords, liquiditytypes.PoolRecord{
+					SwapMsgStates: []liquiditytypes.SwapMsgState{
+						{RemainingOfferCoin: sdk.NewInt64Coin(genesisbuilder.BondDenom, 100), ReservedOfferCoinFee: sdk.NewInt64Coin(genesisbuilder.BondDenom, 1)},
+						{RemainingOfferCoin: sdk.NewInt64Coin(genesisbuilder.BondDenom, 50), ToBeDeleted: true},
+					},
+				})
+				state[liquiditytypes.ModuleName] = cdc.MustMarshalJSON(&liquidityGenesis)
+
+				addModuleBalance(t, state, liquiditytypes.ModuleName, atoms(100))
+			},
+			sdk.NewCoins(), atoms(1),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, state := buildTestGenesis(t, testGenesisBuilder())
+			tc.edit(state)
+
+			audit, err := auditModuleAccounts(cdc, state)
+			require.NoError(t, err)
+
+			for _, acc := range audit {
+				if acc.Name != tc.account {
+					require.True(t, acc.Balanced(), "%s: %+v", acc.Name, acc)
+					continue
+				}
+
+				require.Equal(t, tc.surplus, acc.Surplus)
+				require.Equal(t, tc.deficit, acc.Deficit)
+				require.False(t, acc.Balanced())
+			}
+		})
+	}
+}
+
+func TestSweepModuleDust(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	atoms := func(amount int64) sdk.Coins { return sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, amount)) }
+
+	dustyState := func() (*genesisbuilder.Builder, types.AppMap) {
+		b := testGenesisBuilder()
+		_, state := buildTestGenesis(t, b)
+		addModuleBalance(t, state, auth.FeeCollectorName, atoms(7))
+		addModuleBalance(t, state, staking.NotBondedPoolName, atoms(2))
+		addModuleBalance(t, state, distribution.ModuleName, atoms(4))
+
+		var stakingGenesis staking.GenesisState
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		stakingGenesis.Validators[0].Tokens = stakingGenesis.Validators[0].Tokens.AddRaw(3)
+		state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+		return b, state
+	}
+
+	supplyOf := func(state types.AppMap) sdk.Coins {
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		return bankGenesis.Supply
+	}
+
+	t.Run("account", func(t *testing.T) {
+		b, state := dustyState()
+		supply := supplyOf(state)
+
+		audit, err := auditModuleAccounts(cdc, state)
+		require.NoError(t, err)
+		require.NoError(t, sweepModuleDust(cdc, state, audit, b.Address("alice").String(), nil))
+		require.Equal(t, atoms(7), moduleAccountAudit(t, audit, auth.FeeCollectorName).Swept)
+		require.True(t, moduleAccountAudit(t, audit, auth.FeeCollectorName).Balanced())
+		// the bonded pool deficit cannot be swept
+		require.False(t, moduleAccountAudit(t, audit, staking.BondedPoolName).Balanced())
+
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		require.NoError(t, bankGenesis.Validate())
+		require.Equal(t, supply, bankGenesis.Supply)
+
+		alice := b.Address("alice").String()
+		for _, balance := range bankGenesis.Balances {
+			if balance.Address == alice {
+				require.Equal(t, atoms(5000000+13), balance.Coins)
+			}
+		}
+
+		audit, err = auditModuleAccounts(cdc, state)
+		require.NoError(t, err)
+		for _, acc := range audit {
+			require.Equal(t, acc.Name != staking.BondedPoolName, acc.Balanced(), acc.Name)
+		}
+	})
+
+	t.Run("community pool", func(t *testing.T) {
+		_, state := dustyState()
+		supply := supplyOf(state)
+
+		audit, err := auditModuleAccounts(cdc, state)
+		require.NoError(t, err)
+		require.NoError(t, sweepModuleDust(cdc, state, audit, blockedCommunityPool, nil))
+		require.Equal(t, supply, supplyOf(state))
+
+		var distributionGenesis distribution.GenesisState
+		cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+		require.Equal(t, sdk.NewDecCoinsFromCoins(atoms(13)...), distributionGenesis.FeePool.CommunityPool)
+
+		audit, err = auditModuleAccounts(cdc, state)
+		require.NoError(t, err)
+		require.True(t, moduleAccountAudit(t, audit, distribution.ModuleName).Balanced())
+		require.True(t, moduleAccountAudit(t, audit, auth.FeeCollectorName).Balanced())
+	})
+
+	t.Run("module account destination", func(t *testing.T) {
+		_, state := dustyState()
+		audit, err := auditModuleAccounts(cdc, state)
+		require.NoError(t, err)
+
+		err = sweepModuleDust(cdc, state, audit, auth.NewModuleAddress(gov.ModuleName).String(), nil)
+		require.EqualError(t, err, "module dust destination "+auth.NewModuleAddress(gov.ModuleName).String()+" is the gov module account")
+	})
+}
+
+func TestMigrateModuleAccounts(t *testing.T) {
+	args := []string{"--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--strict-module-accounts"}
+
+	reportPath := filepath.Join(t.TempDir(), "module-accounts.json")
+	_, err := executeMigrate(t, append([]string{"testdata/cosmoshub-2-genesis.json", "--module-accounts-report", reportPath}, args...)...)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var report []moduleAccountBalance
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Len(t, report, len(maccPerms))
+	for _, acc := range report {
+		require.True(t, acc.Balanced(), acc.Name)
+	}
+	require.Equal(t, "1000000000uatom", moduleAccountAudit(t, report, staking.BondedPoolName).Balance.String())
+
+	// the v0.36 migration hands the collected fees to the fee collector
+	source := writeSourceGenesis(t, func(genesis map[string]interface{}) {
+		auth := genesis["app_state"].(map[string]interface{})["auth"].(map[string]interface{})
+		auth["collected_fees"] = []interface{}{map[string]interface{}{"denom": "uatom", "amount": "5"}}
+	})
+
+	_, err = executeMigrate(t, append([]string{source}, args...)...)
+	require.EqualError(t, err, "the balances of the module accounts fee_collector do not match their module genesis")
+
+	out, err := executeMigrate(t, append([]string{source, "--sweep-module-dust", blockedCommunityPool}, args...)...)
+	require.NoError(t, err)
+
+	var genesis struct {
+		AppState types.AppMap `json:"app_state"`
+	}
+	require.NoError(t, json.Unmarshal(out, &genesis))
+
+	var distributionGenesis distribution.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(genesis.AppState[distribution.ModuleName], &distributionGenesis)
+	require.Equal(t, "5.000000000000000000uatom", distributionGenesis.FeePool.CommunityPool.String())
+}
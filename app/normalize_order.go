@@ -0,0 +1,177 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// normalizeGenesisOrder sorts the tendermint validators by address and the
+// well-known app state arrays into the order the SDK exports them in: auth
+// accounts by account number, bank balances by address, the staking records
+// and slashing signing infos by the addresses keying them in the store. The
+// state of other modules is passed through untouched.
+func normalizeGenesisOrder(cdc codec.JSONMarshaler, genDoc *tmtypes.GenesisDoc) error {
+	sort.SliceStable(genDoc.Validators, func(i, j int) bool {
+		return bytes.Compare(genDoc.Validators[i].Address, genDoc.Validators[j].Address) < 0
+	})
+
+	var state types.AppMap
+	if err := json.Unmarshal(genDoc.AppState, &state); err != nil {
+		return errors.Wrap(err, "failed to JSON unmarshal app state")
+	}
+
+	if bz, ok := state[auth.ModuleName]; ok {
+		var authGenesis auth.GenesisState
+		cdc.MustUnmarshalJSON(bz, &authGenesis)
+
+		accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+		if err != nil {
+			return err
+		}
+
+		if authGenesis.Accounts, err = auth.PackAccounts(auth.SanitizeGenesisAccounts(accounts)); err != nil {
+			return err
+		}
+
+		state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	}
+
+	if bz, ok := state[bank.ModuleName]; ok {
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(bz, &bankGenesis)
+
+		bankGenesis.Balances = bank.SanitizeGenesisBalances(bankGenesis.Balances)
+		state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	}
+
+	if bz, ok := state[staking.ModuleName]; ok {
+		var stakingGenesis staking.GenesisState
+		cdc.MustUnmarshalJSON(bz, &stakingGenesis)
+
+		if err := sortStakingGenesis(&stakingGenesis); err != nil {
+			return errors.Wrap(err, "failed to sort staking genesis")
+		}
+
+		state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	}
+
+	if bz, ok := state[slashing.ModuleName]; ok {
+		var slashingGenesis slashing.GenesisState
+		cdc.MustUnmarshalJSON(bz, &slashingGenesis)
+
+		if err := sortSlashingGenesis(&slashingGenesis); err != nil {
+			return errors.Wrap(err, "failed to sort slashing genesis")
+		}
+
+		state[slashing.ModuleName] = cdc.MustMarshalJSON(&slashingGenesis)
+	}
+
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "failed to JSON marshal app state")
+	}
+	genDoc.AppState = bz
+
+	return nil
+}
+
+func sortStakingGenesis(stakingGenesis *staking.GenesisState) error {
+	validators := stakingGenesis.Validators
+	if err := sortByAddresses(len(validators), func(i int) []string {
+		return []string{validators[i].OperatorAddress}
+	}, func(i, j int) { validators[i], validators[j] = validators[j], validators[i] }); err != nil {
+		return err
+	}
+
+	powers := stakingGenesis.LastValidatorPowers
+	if err := sortByAddresses(len(powers), func(i int) []string {
+		return []string{powers[i].Address}
+	}, func(i, j int) { powers[i], powers[j] = powers[j], powers[i] }); err != nil {
+		return err
+	}
+
+	delegations := stakingGenesis.Delegations
+	if err := sortByAddresses(len(delegations), func(i int) []string {
+		return []string{delegations[i].DelegatorAddress, delegations[i].ValidatorAddress}
+	}, func(i, j int) { delegations[i], delegations[j] = delegations[j], delegations[i] }); err != nil {
+		return err
+	}
+
+	unbondings := stakingGenesis.UnbondingDelegations
+	if err := sortByAddresses(len(unbondings), func(i int) []string {
+		return []string{unbondings[i].DelegatorAddress, unbondings[i].ValidatorAddress}
+	}, func(i, j int) { unbondings[i], unbondings[j] = unbondings[j], unbondings[i] }); err != nil {
+		return err
+	}
+
+	redelegations := stakingGenesis.Redelegations
+	return sortByAddresses(len(redelegations), func(i int) []string {
+		return []string{redelegations[i].DelegatorAddress, redelegations[i].ValidatorSrcAddress, redelegations[i].ValidatorDstAddress}
+	}, func(i, j int) { redelegations[i], redelegations[j] = redelegations[j], redelegations[i] })
+}
+
+func sortSlashingGenesis(slashingGenesis *slashing.GenesisState) error {
+	infos := slashingGenesis.SigningInfos
+	if err := sortByAddresses(len(infos), func(i int) []string {
+		return []string{infos[i].Address}
+	}, func(i, j int) { infos[i], infos[j] = infos[j], infos[i] }); err != nil {
+		return err
+	}
+
+	missed := slashingGenesis.MissedBlocks
+	return sortByAddresses(len(missed), func(i int) []string {
+		return []string{missed[i].Address}
+	}, func(i, j int) { missed[i], missed[j] = missed[j], missed[i] })
+}
+
+// sortByAddresses stably sorts n elements by the bytes of the bech32
+// addresses returned by keys, compared in order. Bech32 strings do not sort
+// like the bytes they encode, which key the records in the store.
+func sortByAddresses(n int, keys func(i int) []string, swap func(i, j int)) error {
+	sorter := addressSorter{keys: make([][][]byte, n), swap: swap}
+	for i := 0; i < n; i++ {
+		for _, addr := range keys(i) {
+			_, bz, err := bech32.DecodeAndConvert(addr)
+			if err != nil {
+				return errors.Wrapf(err, "invalid address %s", addr)
+			}
+			sorter.keys[i] = append(sorter.keys[i], bz)
+		}
+	}
+
+	sort.Stable(sorter)
+	return nil
+}
+
+type addressSorter struct {
+	keys [][][]byte
+	swap func(i, j int)
+}
+
+func (s addressSorter) Len() int { return len(s.keys) }
+
+func (s addressSorter) Less(i, j int) bool {
+	for k := range s.keys[i] {
+		if c := bytes.Compare(s.keys[i][k], s.keys[j][k]); c != 0 {
+			return c < 0
+		}
+	}
+
+	return false
+}
+
+func (s addressSorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
@@ -0,0 +1,219 @@
+package gaia
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden files of the tests")
+
+const canonicalOrderGolden = "testdata/canonical-order.golden"
+
+func TestNormalizeGenesisOrder(t *testing.T) {
+	b := testGenesisBuilder().
+		WithDelegation("bob", 2, 4000).
+		WithDelegation("carol", 0, 100).
+		WithDelegation("alice", 1, 5).
+		WithUnbondingDelegation("alice", 2, 100, 24*time.Hour)
+	cdc := MakeEncodingConfig().Marshaler
+
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	exported, _ := exportTestGenesis(t, builtDoc)
+	reversed := reverseGenesisOrder(t, exported)
+
+	require.NoError(t, normalizeGenesisOrder(cdc, exported))
+	require.NoError(t, normalizeGenesisOrder(cdc, reversed))
+	require.Equal(t, exported.Validators, reversed.Validators)
+	require.JSONEq(t, string(exported.AppState), string(reversed.AppState))
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(reversed.AppState, &state))
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+	got := describeGenesisOrder(t, b, reversed)
+	if *updateGolden {
+		require.NoError(t, ioutil.WriteFile(canonicalOrderGolden, []byte(got), 0644))
+	}
+
+	golden, err := ioutil.ReadFile(canonicalOrderGolden)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), got)
+}
+
+// reverseGenesisOrder returns a copy of genDoc with the validators and the
+// normalized app state arrays reversed.
+func reverseGenesisOrder(t *testing.T, genDoc *tmtypes.GenesisDoc) *tmtypes.GenesisDoc {
+	cdc := MakeEncodingConfig().Marshaler
+
+	reversed := *genDoc
+	reversed.Validators = append([]tmtypes.GenesisValidator(nil), genDoc.Validators...)
+	for i, j := 0, len(reversed.Validators)-1; i < j; i, j = i+1, j-1 {
+		reversed.Validators[i], reversed.Validators[j] = reversed.Validators[j], reversed.Validators[i]
+	}
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	reverse := func(n int, swap func(i, j int)) {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	reverse(len(authGenesis.Accounts), func(i, j int) {
+		authGenesis.Accounts[i], authGenesis.Accounts[j] = authGenesis.Accounts[j], authGenesis.Accounts[i]
+	})
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	reverse(len(bankGenesis.Balances), func(i, j int) {
+		bankGenesis.Balances[i], bankGenesis.Balances[j] = bankGenesis.Balances[j], bankGenesis.Balances[i]
+	})
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	reverse(len(stakingGenesis.Validators), func(i, j int) {
+		stakingGenesis.Validators[i], stakingGenesis.Validators[j] = stakingGenesis.Validators[j], stakingGenesis.Validators[i]
+	})
+	reverse(len(stakingGenesis.LastValidatorPowers), func(i, j int) {
+		stakingGenesis.LastValidatorPowers[i], stakingGenesis.LastValidatorPowers[j] = stakingGenesis.LastValidatorPowers[j], stakingGenesis.LastValidatorPowers[i]
+	})
+	reverse(len(stakingGenesis.Delegations), func(i, j int) {
+		stakingGenesis.Delegations[i], stakingGenesis.Delegations[j] = stakingGenesis.Delegations[j], stakingGenesis.Delegations[i]
+	})
+	reverse(len(stakingGenesis.UnbondingDelegations), func(i, j int) {
+		stakingGenesis.UnbondingDelegations[i], stakingGenesis.UnbondingDelegations[j] = stakingGenesis.UnbondingDelegations[j], stakingGenesis.UnbondingDelegations[i]
+	})
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	var slashingGenesis slashing.GenesisState
+	cdc.MustUnmarshalJSON(state[slashing.ModuleName], &slashingGenesis)
+	reverse(len(slashingGenesis.SigningInfos), func(i, j int) {
+		slashingGenesis.SigningInfos[i], slashingGenesis.SigningInfos[j] = slashingGenesis.SigningInfos[j], slashingGenesis.SigningInfos[i]
+	})
+	reverse(len(slashingGenesis.MissedBlocks), func(i, j int) {
+		slashingGenesis.MissedBlocks[i], slashingGenesis.MissedBlocks[j] = slashingGenesis.MissedBlocks[j], slashingGenesis.MissedBlocks[i]
+	})
+	state[slashing.ModuleName] = cdc.MustMarshalJSON(&slashingGenesis)
+
+	bz, err := json.Marshal(state)
+	require.NoError(t, err)
+	reversed.AppState = bz
+
+	return &reversed
+}
+
+// describeGenesisOrder lists the order of the normalized arrays of genDoc,
+// naming the addresses of the builder b.
+func describeGenesisOrder(t *testing.T, b *genesisbuilder.Builder, genDoc *tmtypes.GenesisDoc) string {
+	cdc := MakeEncodingConfig().Marshaler
+
+	names := make(map[string]string)
+	for _, name := range []string{"alice", "bob", "carol", "dave", "depositor"} {
+		names[b.Address(name).String()] = name
+	}
+	for i := 0; i < 3; i++ {
+		names[b.Address(genesisbuilder.ValidatorName(i)).String()] = genesisbuilder.ValidatorName(i)
+		names[b.ValidatorAddress(i).String()] = genesisbuilder.ValidatorName(i)
+		names[b.ValidatorConsAddress(i).String()] = genesisbuilder.ValidatorName(i)
+	}
+	for name := range maccPerms {
+		names[auth.NewModuleAddress(name).String()] = "module:" + name
+	}
+
+	name := func(addr string) string {
+		if n, ok := names[addr]; ok {
+			return n
+		}
+		return addr
+	}
+
+	var out strings.Builder
+	section := func(title string, lines ...string) {
+		fmt.Fprintf(&out, "%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(&out, "  %s\n", line)
+		}
+	}
+
+	var lines []string
+	for _, val := range genDoc.Validators {
+		lines = append(lines, fmt.Sprintf("%s %s", val.Address, name(sdk.ConsAddress(val.Address).String())))
+	}
+	section("validators", lines...)
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	require.NoError(t, err)
+	lines = nil
+	for _, acc := range accounts {
+		lines = append(lines, fmt.Sprintf("%d %s", acc.GetAccountNumber(), name(acc.GetAddress().String())))
+	}
+	section("auth.accounts", lines...)
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	lines = nil
+	for _, balance := range bankGenesis.Balances {
+		lines = append(lines, name(balance.Address))
+	}
+	section("bank.balances", lines...)
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	lines = nil
+	for _, val := range stakingGenesis.Validators {
+		lines = append(lines, name(val.OperatorAddress))
+	}
+	section("staking.validators", lines...)
+	lines = nil
+	for _, power := range stakingGenesis.LastValidatorPowers {
+		lines = append(lines, name(power.Address))
+	}
+	section("staking.last_validator_powers", lines...)
+	lines = nil
+	for _, del := range stakingGenesis.Delegations {
+		lines = append(lines, name(del.DelegatorAddress)+" "+name(del.ValidatorAddress))
+	}
+	section("staking.delegations", lines...)
+	lines = nil
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		lines = append(lines, name(ubd.DelegatorAddress)+" "+name(ubd.ValidatorAddress))
+	}
+	section("staking.unbonding_delegations", lines...)
+
+	var slashingGenesis slashing.GenesisState
+	cdc.MustUnmarshalJSON(state[slashing.ModuleName], &slashingGenesis)
+	lines = nil
+	for _, info := range slashingGenesis.SigningInfos {
+		lines = append(lines, name(info.Address))
+	}
+	section("slashing.signing_infos", lines...)
+
+	return out.String()
+}
@@ -0,0 +1,133 @@
+package gaia
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagFileMode   = "file-mode"
+	flagCreateDirs = "create-dirs"
+)
+
+const (
+	// outputFileMode is the default mode of the genesis files and reports
+	// written.
+	outputFileMode os.FileMode = 0644
+	// keyReportFileMode is the default mode of the reports referencing the
+	// consensus or account keys of the chain, which operators of a shared
+	// machine are not to read.
+	keyReportFileMode os.FileMode = 0600
+)
+
+// outputFiles is how a command writes its output files: with the --file-mode
+// of the command if set, creating their missing parent directories with
+// --create-dirs, and never through a symlink.
+type outputFiles struct {
+	// mode is the --file-mode of every file, zero for the default of each.
+	mode       os.FileMode
+	createDirs bool
+}
+
+// addOutputFileFlags adds the flags of outputFilesFromFlags to cmd.
+func addOutputFileFlags(cmd *cobra.Command) {
+	cmd.Flags().String(flagFileMode, "", fmt.Sprintf("Octal permissions of the files written, e.g. 0640, whatever the umask (default %04o, %04o for the reports referencing keys)", outputFileMode, keyReportFileMode))
+	cmd.Flags().Bool(flagCreateDirs, false, "Create the missing parent directories of the files written")
+}
+
+// outputFilesFromFlags returns the outputFiles of the --file-mode and
+// --create-dirs flags.
+func outputFilesFromFlags(flags *pflag.FlagSet) (outputFiles, error) {
+	createDirs, _ := flags.GetBool(flagCreateDirs)
+	fileMode, _ := flags.GetString(flagFileMode)
+	return newOutputFiles(fileMode, createDirs)
+}
+
+// newOutputFiles returns the outputFiles of a --file-mode, the default mode
+// of each file if empty, and of --create-dirs.
+func newOutputFiles(fileMode string, createDirs bool) (outputFiles, error) {
+	files := outputFiles{createDirs: createDirs}
+	if fileMode == "" {
+		return files, nil
+	}
+
+	mode, err := strconv.ParseUint(fileMode, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return files, fmt.Errorf("invalid --%s %s, must be octal permissions such as 0640", flagFileMode, fileMode)
+	}
+	files.mode = os.FileMode(mode)
+
+	return files, nil
+}
+
+// fileMode returns the mode of a file whose default is def.
+func (o outputFiles) fileMode(def os.FileMode) os.FileMode {
+	if o.mode != 0 {
+		return o.mode
+	}
+	return def
+}
+
+// prepare refuses path if it is a symlink and creates its parent directories
+// with --create-dirs.
+func (o outputFiles) prepare(path string) error {
+	if err := refuseSymlink(path); err != nil {
+		return err
+	}
+
+	if o.createDirs {
+		return os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	return nil
+}
+
+// prepareDir refuses the output directory dir if it is a symlink and creates
+// it, with its parent directories.
+func (o outputFiles) prepareDir(dir string) error {
+	if err := refuseSymlink(dir); err != nil {
+		return err
+	}
+
+	return os.MkdirAll(dir, 0755)
+}
+
+// Write writes the file at path with write as writeOutputFile does, with the
+// mode of the file whose default is def.
+func (o outputFiles) Write(path string, def os.FileMode, write func(io.Writer) error, commit func() error) error {
+	if err := o.prepare(path); err != nil {
+		return err
+	}
+
+	return writeOutputFile(path, o.fileMode(def), write, commit)
+}
+
+// WriteFile writes bz to the file at path, with the mode of the file whose
+// default is def.
+func (o outputFiles) WriteFile(path string, bz []byte, def os.FileMode) error {
+	return o.Write(path, def, func(w io.Writer) error {
+		_, err := w.Write(bz)
+		return err
+	}, nil)
+}
+
+// refuseSymlink returns an error if path is a symlink. Only the last element
+// of path is checked, a symlinked parent directory is followed.
+func refuseSymlink(path string) error {
+	fi, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case fi.Mode()&os.ModeSymlink != 0:
+		return fmt.Errorf("refusing to write %s: it is a symlink", path)
+	default:
+		return nil
+	}
+}
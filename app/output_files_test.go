@@ -0,0 +1,163 @@
+package gaia
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFilesFromFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		expected outputFiles
+		err      string
+	}{
+		{nil, outputFiles{}, ""},
+		{[]string{"--file-mode", "0640", "--create-dirs"}, outputFiles{mode: 0640, createDirs: true}, ""},
+		{[]string{"--file-mode", "600"}, outputFiles{mode: 0600}, ""},
+		{[]string{"--file-mode", "0644x"}, outputFiles{}, "invalid --file-mode 0644x, must be octal permissions such as 0640"},
+		{[]string{"--file-mode", "0"}, outputFiles{}, "invalid --file-mode 0, must be octal permissions such as 0640"},
+		{[]string{"--file-mode", "1755"}, outputFiles{}, "invalid --file-mode 1755, must be octal permissions such as 0640"},
+	} {
+		cmd := &cobra.Command{}
+		addOutputFileFlags(cmd)
+		require.NoError(t, cmd.ParseFlags(tc.args))
+
+		files, err := outputFilesFromFlags(cmd.Flags())
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err, tc.args)
+			continue
+		}
+		require.NoError(t, err, tc.args)
+		require.Equal(t, tc.expected, files, tc.args)
+	}
+}
+
+func TestOutputFilesWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	mode := func(path string) os.FileMode {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return info.Mode().Perm()
+	}
+
+	// the defaults, or --file-mode for every file
+	genesisPath, reportPath := filepath.Join(dir, "genesis.json"), filepath.Join(dir, "report.json")
+	require.NoError(t, outputFiles{}.WriteFile(genesisPath, []byte("{}"), outputFileMode))
+	require.NoError(t, outputFiles{}.WriteFile(reportPath, []byte("[]"), keyReportFileMode))
+	require.Equal(t, os.FileMode(0644), mode(genesisPath))
+	require.Equal(t, os.FileMode(0600), mode(reportPath))
+
+	files := outputFiles{mode: 0640}
+	require.NoError(t, files.WriteFile(genesisPath, []byte("{}"), outputFileMode))
+	require.NoError(t, files.WriteFile(reportPath, []byte("[]"), keyReportFileMode))
+	require.Equal(t, os.FileMode(0640), mode(genesisPath))
+	require.Equal(t, os.FileMode(0640), mode(reportPath))
+
+	// the parent directories are only created with --create-dirs
+	nested := filepath.Join(dir, "launch", "reports", "report.json")
+	require.Error(t, files.WriteFile(nested, []byte("[]"), outputFileMode))
+	_, err := os.Stat(filepath.Join(dir, "launch"))
+	require.True(t, os.IsNotExist(err))
+
+	files.createDirs = true
+	require.NoError(t, files.WriteFile(nested, []byte("[]"), outputFileMode))
+	require.Equal(t, os.FileMode(0640), mode(nested))
+
+	// the temporary file is created and renamed in the destination directory,
+	// which keeps no trace of it
+	entries, err := ioutil.ReadDir(filepath.Dir(nested))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "report.json", entries[0].Name())
+}
+
+func TestOutputFilesRefuseSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "authorized_keys")
+	require.NoError(t, ioutil.WriteFile(target, []byte("ssh-ed25519 AAAA\n"), 0600))
+
+	link := filepath.Join(dir, "genesis.json")
+	require.NoError(t, os.Symlink(target, link))
+	require.EqualError(t, outputFiles{}.WriteFile(link, []byte("{}"), outputFileMode), "refusing to write "+link+": it is a symlink")
+
+	// a dangling symlink is refused too, the file it names is not created
+	dangling := filepath.Join(dir, "report.json")
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing.json"), dangling))
+	require.Error(t, outputFiles{createDirs: true}.WriteFile(dangling, []byte("[]"), outputFileMode))
+	_, err := os.Stat(filepath.Join(dir, "missing.json"))
+	require.True(t, os.IsNotExist(err))
+
+	bz, err := ioutil.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "ssh-ed25519 AAAA\n", string(bz))
+
+	linkedDir := filepath.Join(dir, "split")
+	require.NoError(t, os.Symlink(t.TempDir(), linkedDir))
+	require.EqualError(t, outputFiles{}.prepareDir(linkedDir), "refusing to write "+linkedDir+": it is a symlink")
+
+	_, err = newMigrationBundle(link, outputFiles{})
+	require.EqualError(t, err, "bundle directory "+link+" already exists")
+}
+
+func TestMigrationBundleFileModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "launch", "bundle")
+
+	_, err := newMigrationBundle(dir, outputFiles{})
+	require.Error(t, err)
+
+	bundle, err := newMigrationBundle(dir, outputFiles{createDirs: true})
+	require.NoError(t, err)
+	defer bundle.Remove()
+
+	require.NoError(t, bundle.WriteJSON(bundleWarningsFile, []migrationWarning{}))
+	require.NoError(t, bundle.WriteJSON(bundleReplacementFile, []string{}))
+	require.NoError(t, bundle.Commit(nil))
+
+	for name, expected := range map[string]os.FileMode{
+		bundleWarningsFile:    0644,
+		bundleReplacementFile: 0600,
+		bundleChecksumsFile:   0644,
+	} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, expected, info.Mode().Perm(), name)
+	}
+}
+
+func TestMigrateFileMode(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "launch", "genesis.json")
+	warningsPath := filepath.Join(dir, "launch", "reports", "warnings.json")
+	args := []string{"testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--output", output, "--warnings-report", warningsPath}
+
+	_, err := executeMigrate(t, args...)
+	require.Error(t, err)
+
+	_, err = executeMigrate(t, append(args, "--create-dirs", "--file-mode", "0640")...)
+	require.NoError(t, err)
+	for _, path := range []string{output, warningsPath} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0640), info.Mode().Perm(), path)
+	}
+
+	// an output symlink is refused, the file it links to is left as is
+	target := filepath.Join(dir, "target.json")
+	require.NoError(t, ioutil.WriteFile(target, nil, 0600))
+	link := filepath.Join(dir, "link.json")
+	require.NoError(t, os.Symlink(target, link))
+
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--output", link)
+	require.EqualError(t, err, "refusing to write "+link+": it is a symlink")
+
+	bz, err := ioutil.ReadFile(target)
+	require.NoError(t, err)
+	require.Empty(t, bz)
+}
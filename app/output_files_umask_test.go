@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package gaia
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFilesUmask(t *testing.T) {
+	dir := t.TempDir()
+
+	// the mode is the one asked for, neither masked by a strict umask nor
+	// widened by a loose one
+	for _, umask := range []int{0077, 0} {
+		old := syscall.Umask(umask)
+		path := filepath.Join(dir, "genesis.json")
+		err := outputFiles{}.WriteFile(path, []byte("{}"), outputFileMode)
+		reportErr := outputFiles{}.WriteFile(filepath.Join(dir, "report.json"), []byte("[]"), keyReportFileMode)
+		syscall.Umask(old)
+		require.NoError(t, err)
+		require.NoError(t, reportErr)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0644), info.Mode().Perm(), "umask %04o", umask)
+		info, err = os.Stat(filepath.Join(dir, "report.json"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm(), "umask %04o", umask)
+	}
+}
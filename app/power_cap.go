@@ -0,0 +1,364 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagCapValidatorPower = "cap-validator-power"
+	flagPowerCapReport    = "power-cap-report"
+)
+
+// maxPowerCapRounds bounds the rounds of capValidatorPower. A round caps the
+// validators above the level meeting the cap, only the rounding of their
+// tokens and the validators jailed in it leave work for the next one.
+const maxPowerCapRounds = 100
+
+// validatorPowerCap records how capValidatorPower brought a validator under
+// the cap: the tokens its reduced delegations returned to their delegators,
+// or why it was jailed instead.
+type validatorPowerCap struct {
+	OperatorAddress string  `json:"operator_address"`
+	Moniker         string  `json:"moniker"`
+	PowerBefore     int64   `json:"power_before"`
+	PowerAfter      int64   `json:"power_after"`
+	Moved           sdk.Int `json:"moved"`
+	Delegations     int     `json:"delegations"`
+	Jailed          bool    `json:"jailed,omitempty"`
+	Reason          string  `json:"reason,omitempty"`
+}
+
+// powerCapReport summarizes what capValidatorPower changed, the validators
+// in operator address order.
+type powerCapReport struct {
+	Cap        sdk.Dec             `json:"cap"`
+	Rounds     int                 `json:"rounds"`
+	Moved      sdk.Int             `json:"moved"`
+	Validators []validatorPowerCap `json:"validators"`
+}
+
+// parsePowerCap parses the --cap-validator-power percent into a ratio.
+func parsePowerCap(percent string) (sdk.Dec, error) {
+	d, err := sdk.NewDecFromStr(percent)
+	if err != nil {
+		return sdk.Dec{}, errors.Wrapf(err, "invalid --%s %s", flagCapValidatorPower, percent)
+	}
+	if !d.IsPositive() || d.GTE(sdk.NewDec(100)) {
+		return sdk.Dec{}, fmt.Errorf("--%s %s is not a percent between 0 and 100 exclusive", flagCapValidatorPower, percent)
+	}
+
+	return d.QuoInt64(100), nil
+}
+
+// powerCapLevel returns the highest power the validators of powers, sorted
+// by decreasing power, can keep for none to exceed the ratio capRatio of the
+// total once those above it are brought down to it, and how many are above.
+// It returns 0 validators above when none exceeds the cap as they are.
+func powerCapLevel(powers []int64, capRatio sdk.Dec) (int64, int, error) {
+	var total int64
+	for _, power := range powers {
+		total += power
+	}
+	if len(powers) == 0 || sdk.NewDec(powers[0]).LTE(capRatio.MulInt64(total)) {
+		return 0, 0, nil
+	}
+
+	// with k validators at the level L, L = cap * (rest + k*L)
+	rest := total
+	for k := 1; k < len(powers); k++ {
+		rest -= powers[k-1]
+		denom := sdk.OneDec().Sub(capRatio.MulInt64(int64(k)))
+		if !denom.IsPositive() {
+			break
+		}
+
+		level := capRatio.MulInt64(rest).Quo(denom)
+		if sdk.NewDec(powers[k]).LTE(level) {
+			if level.TruncateInt64() < 1 {
+				return 0, 0, fmt.Errorf("a cap of %s leaves the %d largest validators no power", capRatio, k)
+			}
+			return level.TruncateInt64(), k, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("%d bonded validators cannot each hold at most %s of the power", len(powers), capRatio)
+}
+
+// capValidatorPower brings every bonded validator down to at most capRatio of
+// the bonded power. The delegations of a validator above it are reduced in
+// proportion, their tokens returned to the delegators' balances out of the
+// bonded pool, and their starting infos scaled with them. A validator whose
+// self-delegation would fall below its min_self_delegation is jailed and
+// moved to unbonding at height and genesisTime instead, as capBondedValidators
+// demotes them. The delegations of protected delegators are left as they are.
+// The rounds repeat until every validator meets the cap. The supply is
+// unchanged and the tendermint genesis validators must be updated by the
+// caller.
+func capValidatorPower(cdc codec.JSONMarshaler, state types.AppMap, capRatio sdk.Dec, protected *protectedAddresses, height int64, genesisTime time.Time) (powerCapReport, error) {
+	report := powerCapReport{Cap: capRatio, Moved: sdk.ZeroInt()}
+
+	var (
+		stakingGenesis      staking.GenesisState
+		bankGenesis         bank.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", staking.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", bank.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[distribution.ModuleName], &distributionGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", distribution.ModuleName)
+	}
+
+	delegations := make(map[string][]int)
+	for i, del := range stakingGenesis.Delegations {
+		delegations[del.ValidatorAddress] = append(delegations[del.ValidatorAddress], i)
+	}
+
+	startingInfos := make(map[startingInfoKey]int, len(distributionGenesis.DelegatorStartingInfos))
+	for i, info := range distributionGenesis.DelegatorStartingInfos {
+		startingInfos[startingInfoKey{info.DelegatorAddress, info.ValidatorAddress}] = i
+	}
+
+	// the delegations of protected delegators are never reduced, each of
+	// them is looked up once so that their skips are recorded once
+	protectedDelegators := make(map[string]bool)
+	isProtected := func(delegator string) (bool, error) {
+		if skip, ok := protectedDelegators[delegator]; ok {
+			return skip, nil
+		}
+
+		skip, err := protected.skip(flagCapValidatorPower, delegator)
+		if err != nil {
+			return false, err
+		}
+		protectedDelegators[delegator] = skip
+		return skip, nil
+	}
+
+	capped := make(map[string]*validatorPowerCap)
+	returned := make(map[string]sdk.Int)
+	jailedTokens := sdk.ZeroInt()
+	unbondingTime := genesisTime.Add(stakingGenesis.Params.UnbondingTime)
+
+	for {
+		var bonded []int
+		for i, val := range stakingGenesis.Validators {
+			if val.IsBonded() {
+				bonded = append(bonded, i)
+			}
+		}
+		sort.SliceStable(bonded, func(i, j int) bool {
+			vi, vj := stakingGenesis.Validators[bonded[i]], stakingGenesis.Validators[bonded[j]]
+			if vi.ConsensusPower() != vj.ConsensusPower() {
+				return vi.ConsensusPower() > vj.ConsensusPower()
+			}
+			return vi.OperatorAddress < vj.OperatorAddress
+		})
+
+		powers := make([]int64, len(bonded))
+		for i, idx := range bonded {
+			powers[i] = stakingGenesis.Validators[idx].ConsensusPower()
+		}
+
+		level, above, err := powerCapLevel(powers, capRatio)
+		if err != nil {
+			return report, err
+		}
+		if above == 0 {
+			break
+		}
+
+		if report.Rounds++; report.Rounds > maxPowerCapRounds {
+			return report, fmt.Errorf("the validator power did not meet the cap of %s in %d rounds", capRatio, maxPowerCapRounds)
+		}
+
+		target := sdk.TokensFromConsensusPower(level)
+		for _, idx := range bonded[:above] {
+			val := stakingGenesis.Validators[idx]
+			record, ok := capped[val.OperatorAddress]
+			if !ok {
+				record = &validatorPowerCap{
+					OperatorAddress: val.OperatorAddress,
+					Moniker:         val.GetMoniker(),
+					PowerBefore:     val.ConsensusPower(),
+					Moved:           sdk.ZeroInt(),
+				}
+				capped[val.OperatorAddress] = record
+			}
+
+			valAddr, err := sdk.ValAddressFromBech32(val.OperatorAddress)
+			if err != nil {
+				return report, err
+			}
+			self := sdk.AccAddress(valAddr).String()
+
+			// the share of every delegation but the protected ones the
+			// validator gives up
+			reducible, selfTokens := sdk.ZeroDec(), sdk.ZeroDec()
+			selfReduced := false
+			for _, i := range delegations[val.OperatorAddress] {
+				del := stakingGenesis.Delegations[i]
+				skip, err := isProtected(del.DelegatorAddress)
+				if err != nil {
+					return report, err
+				}
+
+				tokens := val.TokensFromShares(del.Shares)
+				if !skip {
+					reducible = reducible.Add(tokens)
+				}
+				if del.DelegatorAddress == self {
+					selfTokens, selfReduced = tokens, !skip
+				}
+			}
+
+			excess := val.Tokens.Sub(target).ToDec()
+			if excess.GT(reducible) {
+				return report, fmt.Errorf("validator %s cannot meet the cap of %s without reducing protected delegations", val.OperatorAddress, capRatio)
+			}
+			fraction := excess.Quo(reducible)
+
+			remaining := selfTokens.TruncateInt()
+			if selfReduced {
+				remaining = selfTokens.Mul(sdk.OneDec().Sub(fraction)).TruncateInt()
+			}
+			if selfTokens.TruncateInt().GTE(val.MinSelfDelegation) && remaining.LT(val.MinSelfDelegation) {
+				record.Jailed = true
+				record.Reason = fmt.Sprintf("its self-delegation of %s would fall below its min_self_delegation of %s", remaining, val.MinSelfDelegation)
+
+				val.Jailed = true
+				val = val.UpdateStatus(staking.Unbonding)
+				val.UnbondingHeight = height
+				val.UnbondingTime = unbondingTime
+				stakingGenesis.Validators[idx] = val
+
+				jailedTokens = jailedTokens.Add(val.Tokens)
+				continue
+			}
+
+			for _, i := range delegations[val.OperatorAddress] {
+				del := stakingGenesis.Delegations[i]
+				if protectedDelegators[del.DelegatorAddress] {
+					continue
+				}
+
+				removed := del.Shares.Mul(fraction)
+				if removed.IsZero() {
+					continue
+				}
+
+				var tokens sdk.Int
+				val, tokens = val.RemoveDelShares(removed)
+				shares := del.Shares.Sub(removed)
+
+				if j, ok := startingInfos[startingInfoKey{del.DelegatorAddress, del.ValidatorAddress}]; ok {
+					info := &distributionGenesis.DelegatorStartingInfos[j].StartingInfo
+					info.Stake = info.Stake.Mul(shares).Quo(del.Shares)
+				}
+				stakingGenesis.Delegations[i].Shares = shares
+
+				if _, ok := returned[del.DelegatorAddress]; !ok {
+					returned[del.DelegatorAddress] = sdk.ZeroInt()
+				}
+				returned[del.DelegatorAddress] = returned[del.DelegatorAddress].Add(tokens)
+				record.Moved = record.Moved.Add(tokens)
+				report.Moved = report.Moved.Add(tokens)
+				record.Delegations++
+			}
+			stakingGenesis.Validators[idx] = val
+		}
+	}
+
+	if len(capped) == 0 {
+		return report, nil
+	}
+
+	validators := make(map[string]staking.Validator, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		validators[val.OperatorAddress] = val
+	}
+
+	for _, record := range capped {
+		if val := validators[record.OperatorAddress]; val.IsBonded() {
+			record.PowerAfter = val.ConsensusPower()
+		}
+		report.Validators = append(report.Validators, *record)
+	}
+	sort.Slice(report.Validators, func(i, j int) bool {
+		return report.Validators[i].OperatorAddress < report.Validators[j].OperatorAddress
+	})
+
+	lastTotalPower := sdk.ZeroInt()
+	powers := stakingGenesis.LastValidatorPowers[:0]
+	for _, lv := range stakingGenesis.LastValidatorPowers {
+		if record, ok := capped[lv.Address]; ok {
+			if record.Jailed {
+				continue
+			}
+			lv.Power = record.PowerAfter
+		}
+
+		powers = append(powers, lv)
+		lastTotalPower = lastTotalPower.AddRaw(lv.Power)
+	}
+	stakingGenesis.LastValidatorPowers = powers
+	stakingGenesis.LastTotalPower = lastTotalPower
+
+	bondDenom := stakingGenesis.Params.BondDenom
+	bondedPool := auth.NewModuleAddress(staking.BondedPoolName).String()
+	notBondedPool := auth.NewModuleAddress(staking.NotBondedPoolName).String()
+	unbonded := sdk.NewCoins(sdk.NewCoin(bondDenom, report.Moved.Add(jailedTokens)))
+	jailedCoins := sdk.NewCoins(sdk.NewCoin(bondDenom, jailedTokens))
+
+	hasNotBondedPool := false
+	seen := make(map[string]bool, len(returned))
+	for i, balance := range bankGenesis.Balances {
+		switch balance.Address {
+		case bondedPool:
+			coins, hasNeg := balance.Coins.SafeSub(unbonded)
+			if hasNeg {
+				return report, fmt.Errorf("bonded pool holds %s, less than the %s the capped validators unbond", balance.Coins, unbonded)
+			}
+			bankGenesis.Balances[i].Coins = coins
+
+		case notBondedPool:
+			bankGenesis.Balances[i].Coins = balance.Coins.Add(jailedCoins...)
+			hasNotBondedPool = true
+		}
+
+		if tokens, ok := returned[balance.Address]; ok {
+			bankGenesis.Balances[i].Coins = bankGenesis.Balances[i].Coins.Add(sdk.NewCoin(bondDenom, tokens))
+			seen[balance.Address] = true
+		}
+	}
+
+	if !hasNotBondedPool && !jailedCoins.IsZero() {
+		bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{Address: notBondedPool, Coins: jailedCoins})
+	}
+	for delegator, tokens := range returned {
+		if !seen[delegator] {
+			bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{Address: delegator, Coins: sdk.NewCoins(sdk.NewCoin(bondDenom, tokens))})
+		}
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(bankGenesis.Balances)
+
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+	return report, nil
+}
@@ -0,0 +1,247 @@
+package gaia
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerCapLevel(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		powers []int64
+		cap    string
+		level  int64
+		above  int
+		err    bool
+	}{
+		{"under cap", []int64{30, 30, 40}, "0.4", 0, 0, false},
+		{"one above", []int64{60, 20, 20}, "0.4", 26, 1, false},
+		// capping the first alone leaves the second above the level
+		{"two above", []int64{50, 40, 5, 5}, "0.3", 7, 2, false},
+		{"impossible", []int64{50, 50}, "0.4", 0, 0, true},
+		{"no power left", []int64{100, 1}, "0.3", 0, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			level, above, err := powerCapLevel(tc.powers, sdk.MustNewDecFromStr(tc.cap))
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.level, level)
+			require.Equal(t, tc.above, above)
+		})
+	}
+}
+
+func TestParsePowerCap(t *testing.T) {
+	capRatio, err := parsePowerCap("12.5")
+	require.NoError(t, err)
+	require.Equal(t, sdk.MustNewDecFromStr("0.125"), capRatio)
+
+	for _, percent := range []string{"0", "-1", "100", "ten"} {
+		_, err := parsePowerCap(percent)
+		require.Error(t, err, percent)
+	}
+}
+
+// checkPowerCap checks every bonded validator of state is at most capRatio of
+// the bonded power and the pools and supply match the staking genesis.
+func checkPowerCap(t *testing.T, state types.AppMap, capRatio sdk.Dec) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	var (
+		stakingGenesis staking.GenesisState
+		bankGenesis    bank.GenesisState
+	)
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+
+	var totalPower int64
+	bondedTokens, notBondedTokens := sdk.ZeroInt(), sdk.ZeroInt()
+	for _, val := range stakingGenesis.Validators {
+		if val.IsBonded() {
+			totalPower += val.ConsensusPower()
+			bondedTokens = bondedTokens.Add(val.Tokens)
+		} else {
+			notBondedTokens = notBondedTokens.Add(val.Tokens)
+		}
+	}
+	for _, val := range stakingGenesis.Validators {
+		if val.IsBonded() {
+			require.True(t, sdk.NewDec(val.ConsensusPower()).LTE(capRatio.MulInt64(totalPower)), "validator %s at %d of %d", val.OperatorAddress, val.ConsensusPower(), totalPower)
+		}
+	}
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		for _, entry := range ubd.Entries {
+			notBondedTokens = notBondedTokens.Add(entry.Balance)
+		}
+	}
+	require.Equal(t, sdk.NewInt(totalPower), stakingGenesis.LastTotalPower)
+
+	balances := make(map[string]sdk.Coins)
+	total := sdk.NewCoins()
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+		total = total.Add(balance.Coins...)
+	}
+	require.Equal(t, bankGenesis.Supply, total)
+	require.Equal(t, bondedTokens, balances[auth.NewModuleAddress(staking.BondedPoolName).String()].AmountOf(genesisbuilder.BondDenom))
+	require.Equal(t, notBondedTokens, balances[auth.NewModuleAddress(staking.NotBondedPoolName).String()].AmountOf(genesisbuilder.BondDenom))
+}
+
+func TestCapValidatorPower(t *testing.T) {
+	b := genesisbuilder.New().
+		WithValidatorPowers(1000, 300, 100, 50, 50).
+		WithAccount("alice", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000))).
+		WithDelegation("alice", 0, 400000000).
+		WithDelegation("bob", 0, 123456789).
+		WithDelegation("carol", 1, 77777777)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	cdc := MakeEncodingConfig().Marshaler
+	capRatio := sdk.MustNewDecFromStr("0.3")
+
+	t.Run("skewed", func(t *testing.T) {
+		genDoc, state := exportTestGenesis(t, builtDoc)
+
+		var bankBefore bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+
+		report, err := capValidatorPower(cdc, state, capRatio, nil, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, report.Rounds, 1)
+		require.Len(t, report.Validators, 2)
+		checkPowerCap(t, state, capRatio)
+
+		moved := sdk.ZeroInt()
+		for _, val := range report.Validators {
+			require.False(t, val.Jailed)
+			require.Less(t, val.PowerAfter, val.PowerBefore)
+			moved = moved.Add(val.Moved)
+		}
+		require.Equal(t, report.Moved, moved)
+
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		require.Equal(t, bankBefore.Supply, bankGenesis.Supply)
+
+		// the delegators got the tokens of their reduced delegations
+		balances := make(map[string]sdk.Coins)
+		for _, balance := range bankGenesis.Balances {
+			balances[balance.Address] = balance.Coins
+		}
+		for _, name := range []string{"alice", "bob", "carol"} {
+			require.True(t, balances[b.Address(name).String()].AmountOf(genesisbuilder.BondDenom).GT(sdk.NewInt(1000)), name)
+		}
+
+		// the same state is capped the same
+		_, again := exportTestGenesis(t, builtDoc)
+		_, err = capValidatorPower(cdc, again, capRatio, nil, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.NoError(t, err)
+		require.Equal(t, state, again)
+
+		var stakingGenesis staking.GenesisState
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		genDoc.Validators, err = tmValidatorsFromStaking(stakingGenesis)
+		require.NoError(t, err)
+		genDoc.AppState, err = json.Marshal(state)
+		require.NoError(t, err)
+		require.NoError(t, SmokeTestGenesis(genDoc))
+	})
+
+	t.Run("min self delegation", func(t *testing.T) {
+		genDoc, state := exportTestGenesis(t, builtDoc)
+
+		var stakingGenesis staking.GenesisState
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		for i, val := range stakingGenesis.Validators {
+			if val.OperatorAddress == b.ValidatorAddress(0).String() {
+				stakingGenesis.Validators[i].MinSelfDelegation = sdk.TokensFromConsensusPower(1000)
+			}
+		}
+		state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+		report, err := capValidatorPower(cdc, state, capRatio, nil, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.NoError(t, err)
+		checkPowerCap(t, state, capRatio)
+
+		jailed := make(map[string]validatorPowerCap)
+		for _, val := range report.Validators {
+			if val.Jailed {
+				jailed[val.OperatorAddress] = val
+			}
+		}
+		require.Len(t, jailed, 1)
+		record := jailed[b.ValidatorAddress(0).String()]
+		require.Zero(t, record.PowerAfter)
+		require.True(t, record.Moved.IsZero())
+		require.Contains(t, record.Reason, "min_self_delegation")
+
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		for _, val := range stakingGenesis.Validators {
+			if val.OperatorAddress == record.OperatorAddress {
+				require.True(t, val.Jailed)
+				require.Equal(t, staking.Unbonding, val.Status)
+				require.Equal(t, genDoc.GenesisTime.Add(stakingGenesis.Params.UnbondingTime), val.UnbondingTime)
+			}
+		}
+
+		genDoc.Validators, err = tmValidatorsFromStaking(stakingGenesis)
+		require.NoError(t, err)
+		genDoc.AppState, err = json.Marshal(state)
+		require.NoError(t, err)
+		require.NoError(t, SmokeTestGenesis(genDoc))
+	})
+
+	t.Run("protected", func(t *testing.T) {
+		genDoc, state := exportTestGenesis(t, builtDoc)
+
+		protected := &protectedAddresses{reasons: map[string]string{b.Address("bob").String(): "listed"}}
+		_, err := capValidatorPower(cdc, state, capRatio, protected, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.NoError(t, err)
+		checkPowerCap(t, state, capRatio)
+		require.Len(t, protected.Skips, 1)
+
+		var stakingGenesis staking.GenesisState
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		for _, del := range stakingGenesis.Delegations {
+			if del.DelegatorAddress == b.Address("bob").String() {
+				require.Equal(t, sdk.NewDec(123456789), del.Shares)
+			}
+		}
+
+		// without alice's delegation, too little is left to reduce
+		protected.reasons[b.Address("alice").String()] = "listed"
+		_, state = exportTestGenesis(t, builtDoc)
+		_, err = capValidatorPower(cdc, state, capRatio, protected, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "protected delegations")
+
+		delete(protected.reasons, b.Address("alice").String())
+		protected.Strict = true
+		_, state = exportTestGenesis(t, builtDoc)
+		_, err = capValidatorPower(cdc, state, capRatio, protected, genDoc.InitialHeight, genDoc.GenesisTime)
+		require.Error(t, err)
+	})
+}
+
+func TestMigrateCapValidatorPower(t *testing.T) {
+	source := writeMutatedGenesis(t, func(_, _ map[string]interface{}) {})
+
+	// the one validator of the source cannot meet any cap
+	_, err := executeMigrate(t, source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--"+flagCapValidatorPower, "50")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--"+flagCapValidatorPower)
+	require.Contains(t, err.Error(), "1 bonded validators cannot each hold at most 0.5")
+}
@@ -0,0 +1,260 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/cosmos/cosmos-sdk/version"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagFormat = "format"
+	formatText = "text"
+)
+
+// GenesisPowerReportCmd returns a command reporting how the voting power of
+// the bonded validators of a genesis file is distributed.
+func GenesisPowerReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "power-report [genesis-file]",
+		Short: "Report the voting power distribution of a genesis file",
+		Long: fmt.Sprintf(`Report the voting power of the bonded, unjailed validators of the staking genesis:
+the share and cumulative share of every validator, the number of validators
+controlling more than 1/3 and 2/3 of the power, the self-bond of every validator
+and its largest delegator. The staking genesis of the source and the migrated
+versions are both supported. Pass - as the genesis file to read it from STDIN.
+
+Example:
+$ %s genesis power-report genesis.json
+$ %s genesis power-report genesis.json --format json
+`, version.AppName, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString(flagFormat)
+			if format != formatText && format != formatJSON {
+				return fmt.Errorf("unknown format %q, expected %s or %s", format, formatText, formatJSON)
+			}
+
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			report, err := readPowerReport(input)
+			if err != nil {
+				return err
+			}
+
+			if format == formatJSON {
+				bz, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to JSON marshal power report")
+				}
+				cmd.Println(string(bz))
+				return nil
+			}
+
+			return report.writeTable(cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().String(flagFormat, formatText, "Format of the report, text or json")
+
+	return cmd
+}
+
+// powerReport is the voting power distribution of the bonded validators of a
+// genesis file, sorted by decreasing power.
+type powerReport struct {
+	TotalPower int64 `json:"total_power"`
+	// OneThirdValidators and TwoThirdsValidators are the smallest numbers of
+	// validators controlling more than 1/3 and 2/3 of the total power.
+	OneThirdValidators  int              `json:"one_third_validators"`
+	TwoThirdsValidators int              `json:"two_thirds_validators"`
+	Validators          []validatorPower `json:"validators"`
+}
+
+type validatorPower struct {
+	OperatorAddress   string  `json:"operator_address"`
+	Moniker           string  `json:"moniker"`
+	Power             int64   `json:"power"`
+	Share             sdk.Dec `json:"share"`
+	CumulativeShare   sdk.Dec `json:"cumulative_share"`
+	Tokens            sdk.Int `json:"tokens"`
+	SelfBond          sdk.Int `json:"self_bond"`
+	SelfBondRatio     sdk.Dec `json:"self_bond_ratio"`
+	LargestDelegator  string  `json:"largest_delegator"`
+	LargestDelegation sdk.Int `json:"largest_delegation"`
+}
+
+// powerReportGenesis holds the parts of the staking genesis the report reads,
+// whose JSON is the same from v0.36 to v0.40 but for the validator status.
+type powerReportGenesis struct {
+	AppState struct {
+		Staking *struct {
+			Validators []struct {
+				OperatorAddress string `json:"operator_address"`
+				Description     struct {
+					Moniker string `json:"moniker"`
+				} `json:"description"`
+				Jailed          bool            `json:"jailed"`
+				Status          json.RawMessage `json:"status"`
+				Tokens          sdk.Int         `json:"tokens"`
+				DelegatorShares sdk.Dec         `json:"delegator_shares"`
+			} `json:"validators"`
+			Delegations []struct {
+				DelegatorAddress string  `json:"delegator_address"`
+				ValidatorAddress string  `json:"validator_address"`
+				Shares           sdk.Dec `json:"shares"`
+			} `json:"delegations"`
+		} `json:"staking"`
+	} `json:"app_state"`
+}
+
+// isBondedStatus tells whether status is the bonded status of a validator,
+// the BOND_STATUS_BONDED enum since v0.40 and the number 2 before.
+func isBondedStatus(status json.RawMessage) bool {
+	var name string
+	if err := json.Unmarshal(status, &name); err == nil {
+		return name == staking.Bonded.String()
+	}
+
+	var number int
+	if err := json.Unmarshal(status, &number); err == nil {
+		return number == 2
+	}
+
+	return false
+}
+
+func readPowerReport(r io.Reader) (*powerReport, error) {
+	var genesis powerReportGenesis
+	if err := json.NewDecoder(r).Decode(&genesis); err != nil {
+		return nil, errors.Wrap(err, "failed to JSON unmarshal genesis file")
+	}
+
+	stakingGenesis := genesis.AppState.Staking
+	if stakingGenesis == nil {
+		return nil, fmt.Errorf("genesis file has no %s genesis", staking.ModuleName)
+	}
+
+	report := &powerReport{Validators: []validatorPower{}}
+	// validators indexes the bonded validators by the bytes of their operator
+	// addresses, which are the bytes of the self-delegating accounts.
+	validators := make(map[string]int)
+	var shares []sdk.Dec
+	for _, val := range stakingGenesis.Validators {
+		if val.Jailed || !isBondedStatus(val.Status) || val.Tokens.IsNil() || val.DelegatorShares.IsNil() {
+			continue
+		}
+
+		_, bz, err := bech32.DecodeAndConvert(val.OperatorAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid validator address %s", val.OperatorAddress)
+		}
+
+		power := sdk.TokensToConsensusPower(val.Tokens)
+		if power <= 0 {
+			continue
+		}
+
+		validators[string(bz)] = len(report.Validators)
+		shares = append(shares, val.DelegatorShares)
+		report.Validators = append(report.Validators, validatorPower{
+			OperatorAddress:   val.OperatorAddress,
+			Moniker:           val.Description.Moniker,
+			Power:             power,
+			Tokens:            val.Tokens,
+			SelfBond:          sdk.ZeroInt(),
+			LargestDelegation: sdk.ZeroInt(),
+		})
+		report.TotalPower += power
+	}
+
+	for _, del := range stakingGenesis.Delegations {
+		_, valBz, err := bech32.DecodeAndConvert(del.ValidatorAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid validator address %s", del.ValidatorAddress)
+		}
+		i, ok := validators[string(valBz)]
+		if !ok {
+			continue
+		}
+
+		_, delBz, err := bech32.DecodeAndConvert(del.DelegatorAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid delegator address %s", del.DelegatorAddress)
+		}
+
+		if del.Shares.IsNil() || shares[i].IsZero() {
+			continue
+		}
+		val := &report.Validators[i]
+		tokens := del.Shares.MulInt(val.Tokens).Quo(shares[i]).TruncateInt()
+
+		if bytes.Equal(delBz, valBz) {
+			val.SelfBond = val.SelfBond.Add(tokens)
+		}
+		if tokens.GT(val.LargestDelegation) {
+			val.LargestDelegator = del.DelegatorAddress
+			val.LargestDelegation = tokens
+		}
+	}
+
+	sort.SliceStable(report.Validators, func(i, j int) bool {
+		return report.Validators[i].Power > report.Validators[j].Power
+	})
+
+	var cumulative int64
+	for i := range report.Validators {
+		val := &report.Validators[i]
+		cumulative += val.Power
+
+		val.Share = sdk.NewDec(val.Power).QuoInt64(report.TotalPower)
+		val.CumulativeShare = sdk.NewDec(cumulative).QuoInt64(report.TotalPower)
+		val.SelfBondRatio = val.SelfBond.ToDec().QuoInt(val.Tokens)
+
+		if report.OneThirdValidators == 0 && 3*cumulative > report.TotalPower {
+			report.OneThirdValidators = i + 1
+		}
+		if report.TwoThirdsValidators == 0 && 3*cumulative > 2*report.TotalPower {
+			report.TwoThirdsValidators = i + 1
+		}
+	}
+
+	return report, nil
+}
+
+func (r *powerReport) writeTable(out io.Writer) error {
+	fmt.Fprintf(out, "total power %d of %d bonded validators\n", r.TotalPower, len(r.Validators))
+	fmt.Fprintf(out, "validators controlling more than 1/3 of the power: %d\n", r.OneThirdValidators)
+	fmt.Fprintf(out, "validators controlling more than 2/3 of the power: %d\n\n", r.TwoThirdsValidators)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tVALIDATOR\tMONIKER\tPOWER\tSHARE\tCUMULATIVE\tSELF-BOND\tSELF-BOND RATIO\tLARGEST DELEGATOR\tLARGEST DELEGATION")
+	for i, val := range r.Validators {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			i+1, val.OperatorAddress, val.Moniker, val.Power,
+			formatPercent(val.Share), formatPercent(val.CumulativeShare), val.SelfBond, formatPercent(val.SelfBondRatio),
+			val.LargestDelegator, val.LargestDelegation)
+	}
+
+	return w.Flush()
+}
+
+// formatPercent formats the ratio d as a percentage with two decimals,
+// truncating the others.
+func formatPercent(d sdk.Dec) string {
+	basisPoints := d.MulInt64(10000).TruncateInt64()
+	return fmt.Sprintf("%d.%02d%%", basisPoints/100, basisPoints%100)
+}
@@ -0,0 +1,104 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowerReport(t *testing.T) {
+	b := testGenesisBuilder().
+		WithValidatorPowers(40).
+		WithDelegation("bob", 3, 60000000)
+	genDoc, _ := buildTestGenesis(t, b)
+
+	bz, err := json.Marshal(genDoc)
+	require.NoError(t, err)
+
+	report, err := readPowerReport(bytes.NewReader(bz))
+	require.NoError(t, err)
+
+	// validator 3 holds 100 of 165 alone, 133 with validator 2
+	require.Equal(t, int64(165), report.TotalPower)
+	require.Equal(t, 1, report.OneThirdValidators)
+	require.Equal(t, 2, report.TwoThirdsValidators)
+
+	require.Len(t, report.Validators, 4)
+	var powers []int64
+	for _, val := range report.Validators {
+		powers = append(powers, val.Power)
+	}
+	require.Equal(t, []int64{100, 33, 20, 12}, powers)
+
+	top := report.Validators[0]
+	require.Equal(t, b.ValidatorAddress(3).String(), top.OperatorAddress)
+	require.Equal(t, sdk.NewInt(40000000), top.SelfBond)
+	require.Equal(t, sdk.NewDecWithPrec(4, 1), top.SelfBondRatio)
+	require.Equal(t, b.Address("bob").String(), top.LargestDelegator)
+	require.Equal(t, sdk.NewInt(60000000), top.LargestDelegation)
+	require.Equal(t, "60.60%", formatPercent(top.Share))
+
+	carol := report.Validators[1]
+	require.Equal(t, b.ValidatorAddress(2).String(), carol.OperatorAddress)
+	require.Equal(t, sdk.NewInt(30000000), carol.SelfBond)
+	require.Equal(t, b.Address(genesisbuilder.ValidatorName(2)).String(), carol.LargestDelegator)
+	require.Equal(t, "80.60%", formatPercent(carol.CumulativeShare))
+	require.Equal(t, sdk.OneDec(), report.Validators[3].CumulativeShare)
+}
+
+func TestPowerReportSourceGenesis(t *testing.T) {
+	input, err := os.Open("testdata/cosmoshub-2-genesis.json")
+	require.NoError(t, err)
+	defer input.Close()
+
+	report, err := readPowerReport(input)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1000), report.TotalPower)
+	require.Equal(t, 1, report.OneThirdValidators)
+	require.Equal(t, 1, report.TwoThirdsValidators)
+	require.Len(t, report.Validators, 1)
+	require.Equal(t, "validator", report.Validators[0].Moniker)
+	require.Equal(t, sdk.OneDec(), report.Validators[0].SelfBondRatio)
+	require.Equal(t, "cosmos1tlykl6rqlns2wv2nd5claxvdrnq2scsumduudu", report.Validators[0].LargestDelegator)
+}
+
+func TestGenesisPowerReportCmd(t *testing.T) {
+	genDoc, _ := buildTestGenesis(t, testGenesisBuilder())
+	genesisFile := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, genDoc.SaveAs(genesisFile))
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := GenesisPowerReportCmd()
+		cmd.SetOut(&out)
+		cmd.SetErr(ioutil.Discard)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run(genesisFile)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(out, "total power 65 of 3 bonded validators\n"))
+	require.Contains(t, out, "more than 1/3 of the power: 1\n")
+	require.Contains(t, out, "more than 2/3 of the power: 2\n")
+
+	out, err = run(genesisFile, "--format", "json")
+	require.NoError(t, err)
+	var report powerReport
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	require.Equal(t, int64(65), report.TotalPower)
+	require.Equal(t, 2, report.TwoThirdsValidators)
+
+	_, err = run(genesisFile, "--format", "yaml")
+	require.EqualError(t, err, `unknown format "yaml", expected text or json`)
+}
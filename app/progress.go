@@ -0,0 +1,161 @@
+package gaia
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between two non-TTY progress lines
+// within a stage.
+const progressInterval = 5 * time.Second
+
+// stageObserver is notified by a stageTracker as the migration advances.
+type stageObserver interface {
+	StageStarted(index, total int, name string)
+	StageProgress(done, total int64)
+	StageFinished(name string, elapsed time.Duration)
+}
+
+// stageTracker instruments the migration pipeline stages. --verbose and
+// --progress are both observers of it so they report the same stages.
+type stageTracker struct {
+	stages    []string
+	observers []stageObserver
+
+	mtx     sync.Mutex
+	current int
+	started time.Time
+	now     func() time.Time
+}
+
+func newStageTracker(stages []string, observers ...stageObserver) *stageTracker {
+	return &stageTracker{stages: stages, observers: observers, current: -1, now: time.Now}
+}
+
+// Start finishes the current stage, if any, and starts the named one.
+func (t *stageTracker) Start(name string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.finish()
+
+	t.current = len(t.stages)
+	for i, stage := range t.stages {
+		if stage == name {
+			t.current = i
+			break
+		}
+	}
+	t.started = t.now()
+
+	for _, o := range t.observers {
+		o.StageStarted(t.current, len(t.stages), name)
+	}
+}
+
+// Progress reports done out of total bytes processed in the current stage.
+func (t *stageTracker) Progress(done, total int64) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, o := range t.observers {
+		o.StageProgress(done, total)
+	}
+}
+
+// Done finishes the current stage.
+func (t *stageTracker) Done() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.finish()
+	t.current = -1
+}
+
+func (t *stageTracker) finish() {
+	if t.current < 0 {
+		return
+	}
+
+	name := fmt.Sprint(t.current)
+	if t.current < len(t.stages) {
+		name = t.stages[t.current]
+	}
+
+	for _, o := range t.observers {
+		o.StageFinished(name, t.now().Sub(t.started))
+	}
+}
+
+// verboseObserver logs every finished stage with its duration.
+type verboseObserver struct {
+	w io.Writer
+}
+
+func (o verboseObserver) StageStarted(int, int, string) {}
+
+func (o verboseObserver) StageProgress(int64, int64) {}
+
+func (o verboseObserver) StageFinished(name string, elapsed time.Duration) {
+	fmt.Fprintf(o.w, "stage %s finished in %s\n", name, elapsed.Round(time.Millisecond))
+}
+
+// progressObserver renders the migration progress. On a TTY it redraws a
+// single status line, otherwise it prints a line per stage and at most one
+// progress line every progressInterval.
+type progressObserver struct {
+	w   io.Writer
+	tty bool
+	now func() time.Time
+
+	line      string
+	lastPrint time.Time
+}
+
+func newProgressObserver(w io.Writer) *progressObserver {
+	tty := false
+	if f, ok := w.(*os.File); ok {
+		if info, err := f.Stat(); err == nil {
+			tty = info.Mode()&os.ModeCharDevice != 0
+		}
+	}
+
+	return &progressObserver{w: w, tty: tty, now: time.Now}
+}
+
+func (o *progressObserver) StageStarted(index, total int, name string) {
+	o.line = fmt.Sprintf("[%d/%d] %s", index+1, total, name)
+	o.print(o.line, true)
+}
+
+func (o *progressObserver) StageProgress(done, total int64) {
+	if total <= 0 {
+		return
+	}
+
+	o.print(fmt.Sprintf("%s %d/%d bytes (%d%%)", o.line, done, total, done*100/total), done == total)
+}
+
+func (o *progressObserver) StageFinished(string, time.Duration) {
+	if o.tty {
+		// leave the terminal on a clean line for whatever comes next
+		fmt.Fprint(o.w, "\r\033[K")
+	}
+}
+
+func (o *progressObserver) print(line string, force bool) {
+	if o.tty {
+		fmt.Fprintf(o.w, "\r\033[K%s", line)
+		return
+	}
+
+	if !force && o.now().Sub(o.lastPrint) < progressInterval {
+		return
+	}
+
+	o.lastPrint = o.now()
+	fmt.Fprintf(o.w, "progress: %s\n", line)
+}
@@ -0,0 +1,56 @@
+package gaia
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressObserverLines(t *testing.T) {
+	var buf bytes.Buffer
+	progress := newProgressObserver(&buf)
+	require.False(t, progress.tty)
+
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	progress.now = clock
+
+	var verbose bytes.Buffer
+	stages := newStageTracker([]string{"read", "modules", "output"}, progress, verboseObserver{&verbose})
+	stages.now = clock
+
+	stages.Start("read")
+	now = now.Add(1500 * time.Millisecond)
+
+	stages.Start("modules")
+	stages.Progress(100, 400)
+
+	// throttled until progressInterval passed
+	now = now.Add(time.Second)
+	stages.Progress(200, 400)
+
+	now = now.Add(progressInterval)
+	stages.Progress(300, 400)
+
+	// a finished stage is always reported
+	stages.Progress(400, 400)
+
+	now = now.Add(time.Second)
+	stages.Start("output")
+	stages.Done()
+	stages.Done()
+
+	require.Equal(t, `progress: [1/3] read
+progress: [2/3] modules
+progress: [2/3] modules 300/400 bytes (75%)
+progress: [2/3] modules 400/400 bytes (100%)
+progress: [3/3] output
+`, buf.String())
+
+	require.Equal(t, `stage read finished in 1.5s
+stage modules finished in 7s
+stage output finished in 0s
+`, verbose.String())
+}
@@ -0,0 +1,159 @@
+package gaia
+
+// This file implements the fund recovery approved by cosmoshub proposal 29. The
+// recovery entries are supplied as a JSON file and applied to the migrated bank
+// genesis as transfers between accounts, so the total supply is unchanged.
+// Entries with a vesting schedule then make the destination a vesting account
+// of the recovered amount, see applyRecoveryVesting. Entries of an invalid
+// destination can be diverted to a claims account, see prop29_claims.go.
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/pkg/errors"
+)
+
+// recoveryEntry moves Amount from the From account to the To account, vesting
+// on the schedule Vesting if set.
+type recoveryEntry struct {
+	From    string           `json:"from" desc:"Bech32 account address the amount is recovered from"`
+	To      string           `json:"to" desc:"Bech32 account address receiving the amount"`
+	Amount  sdk.Coins        `json:"amount" desc:"Recovered coins"`
+	Vesting *recoveryVesting `json:"vesting,omitempty" desc:"Vesting schedule of the recovered amount, released at once if absent"`
+	// diversion is set when To is the claims account the amount is diverted
+	// to, the destination of the entry being invalid.
+	diversion *divertedRecovery
+}
+
+// recoveryReport records the applied recovery entries and their totals by
+// denom, split into the amounts delivered to their destinations and those
+// diverted to the claims account.
+type recoveryReport struct {
+	Entries   []recoveryEntry `json:"entries"`
+	Totals    sdk.Coins       `json:"totals"`
+	Delivered sdk.Coins       `json:"delivered"`
+	Diverted  sdk.Coins       `json:"diverted"`
+}
+
+// loadRecoveryEntries reads the recovery entries of the JSON file path. An
+// entry of an invalid destination fails unless claims, the address of the
+// claims account, is set: the entry then moves its amount to claims instead,
+// without vesting it.
+func loadRecoveryEntries(path, claims string) ([]recoveryEntry, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read prop29 data from file %s", path)
+	}
+
+	entries, err := parseRecoveryEntries(bz, claims)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid prop29 data in file %s", path)
+	}
+
+	return entries, nil
+}
+
+// parseRecoveryEntries parses and validates the prop29 data bz, returning
+// an entry for every element of the JSON array. Unknown fields fail rather
+// than being ignored, a misspelt vesting would release the amount at once.
+func parseRecoveryEntries(bz []byte, claims string) ([]recoveryEntry, error) {
+	var entries []recoveryEntry
+	if err := decodeStrictJSON(bz, &entries); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal prop29 data")
+	}
+
+	for i, entry := range entries {
+		if _, err := sdk.AccAddressFromBech32(entry.From); err != nil {
+			return nil, errors.Wrapf(err, "invalid from address in prop29 entry %d", i)
+		}
+
+		_, toErr := sdk.AccAddressFromBech32(entry.To)
+		if toErr != nil && claims == "" {
+			return nil, errors.Wrapf(toErr, "invalid to address in prop29 entry %d", i)
+		}
+
+		entries[i].Amount = entry.Amount.Sort()
+		if err := validateCoins(entries[i].Amount); err != nil {
+			return nil, errors.Wrapf(err, "invalid amount in prop29 entry %d", i)
+		}
+
+		if entries[i].Amount.IsZero() {
+			return nil, fmt.Errorf("prop29 entry %d has an empty amount", i)
+		}
+
+		if entry.Vesting != nil {
+			if err := entry.Vesting.Validate(entries[i].Amount); err != nil {
+				return nil, errors.Wrapf(err, "invalid vesting in prop29 entry %d", i)
+			}
+		}
+
+		if toErr != nil {
+			entries[i].diversion = &divertedRecovery{
+				Entry:      i,
+				From:       entry.From,
+				IntendedTo: entry.To,
+				Amount:     entries[i].Amount,
+				Vesting:    entry.Vesting,
+				Reason:     toErr.Error(),
+			}
+			entries[i].To, entries[i].Vesting = claims, nil
+		}
+	}
+
+	return entries, nil
+}
+
+// applyRecoveries applies the recovery entries to the bank genesis in order.
+// Every denom moved must be part of the bank supply and the source account
+// must hold the full amount; destinations without a balance get a new entry.
+func applyRecoveries(bankGenesis *bank.GenesisState, entries []recoveryEntry) (recoveryReport, error) {
+	report := recoveryReport{Entries: entries, Totals: sdk.NewCoins(), Delivered: sdk.NewCoins(), Diverted: sdk.NewCoins()}
+
+	balanceIdx := make(map[string]int, len(bankGenesis.Balances))
+	for i, balance := range bankGenesis.Balances {
+		balanceIdx[balance.Address] = i
+	}
+
+	for i, entry := range entries {
+		for _, coin := range entry.Amount {
+			if !bankGenesis.Supply.AmountOf(coin.Denom).IsPositive() {
+				return recoveryReport{}, fmt.Errorf("prop29 entry %d moves denom %s which is not in the bank supply", i, coin.Denom)
+			}
+		}
+
+		fromIdx, ok := balanceIdx[entry.From]
+		if !ok {
+			return recoveryReport{}, fmt.Errorf("prop29 entry %d: account %s has no balance", i, entry.From)
+		}
+
+		remaining, negative := bankGenesis.Balances[fromIdx].Coins.SafeSub(entry.Amount)
+		if negative {
+			return recoveryReport{}, fmt.Errorf("prop29 entry %d: account %s balance %s is less than %s",
+				i, entry.From, bankGenesis.Balances[fromIdx].Coins, entry.Amount)
+		}
+
+		bankGenesis.Balances[fromIdx].Coins = remaining
+
+		toIdx, ok := balanceIdx[entry.To]
+		if !ok {
+			bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{Address: entry.To, Coins: sdk.NewCoins()})
+			toIdx = len(bankGenesis.Balances) - 1
+			balanceIdx[entry.To] = toIdx
+		}
+
+		bankGenesis.Balances[toIdx].Coins = bankGenesis.Balances[toIdx].Coins.Add(entry.Amount...)
+		report.Totals = report.Totals.Add(entry.Amount...)
+		if entry.diversion != nil {
+			report.Diverted = report.Diverted.Add(entry.Amount...)
+		} else {
+			report.Delivered = report.Delivered.Add(entry.Amount...)
+		}
+	}
+
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(bankGenesis.Balances)
+
+	return report, nil
+}
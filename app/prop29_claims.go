@@ -0,0 +1,123 @@
+package gaia
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagProp29Claims       = "prop-29-claims-account"
+	flagProp29ClaimsReport = "prop-29-claims-report"
+)
+
+// prop29ClaimsModule is the --prop-29-claims-account diverting the amounts
+// of the prop29 entries of invalid destinations to the module account
+// prop29ClaimsModuleName, which nobody can spend from until governance moves
+// them.
+const (
+	prop29ClaimsModule     = "module"
+	prop29ClaimsModuleName = "prop29_claims"
+)
+
+// divertedRecovery is a prop29 recovery entry, entry Entry of the prop29
+// data, whose destination IntendedTo is invalid for Reason. Its amount went
+// to the claims account instead, to be claimed for the destination meant.
+type divertedRecovery struct {
+	Entry      int              `json:"entry"`
+	From       string           `json:"from"`
+	IntendedTo string           `json:"intended_to"`
+	Amount     sdk.Coins        `json:"amount"`
+	Vesting    *recoveryVesting `json:"vesting,omitempty"`
+	Reason     string           `json:"reason"`
+}
+
+// prop29Claims is the record of the diverted prop29 entries seeding a claims
+// process: the claims account holding their amounts, its module account name
+// if it is one, and the entries with their totals by denom.
+type prop29Claims struct {
+	ClaimsAccount string             `json:"claims_account"`
+	ModuleAccount string             `json:"module_account,omitempty"`
+	Entries       []divertedRecovery `json:"entries"`
+	Totals        sdk.Coins          `json:"totals"`
+}
+
+// prop29ClaimsAddress returns the address of the --prop-29-claims-account
+// claims, prop29ClaimsModule or a bech32 account address.
+func prop29ClaimsAddress(claims string) (string, error) {
+	if claims == prop29ClaimsModule {
+		return auth.NewModuleAddress(prop29ClaimsModuleName).String(), nil
+	}
+
+	addr, err := sdk.AccAddressFromBech32(claims)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid --%s", flagProp29Claims)
+	}
+
+	return addr.String(), nil
+}
+
+// newProp29Claims returns the claims record of the diverted entries of
+// entries, moved to the claims account of the --prop-29-claims-account claims.
+func newProp29Claims(claims string, entries []recoveryEntry) prop29Claims {
+	record := prop29Claims{Entries: []divertedRecovery{}, Totals: sdk.NewCoins()}
+	record.ClaimsAccount, _ = prop29ClaimsAddress(claims)
+	if claims == prop29ClaimsModule {
+		record.ModuleAccount = prop29ClaimsModuleName
+	}
+
+	for _, entry := range entries {
+		if entry.diversion != nil {
+			record.Entries = append(record.Entries, *entry.diversion)
+			record.Totals = record.Totals.Add(entry.Amount...)
+		}
+	}
+
+	return record
+}
+
+// addClaimsModuleAccount adds the claims module account to the auth genesis
+// of state. An account at its address already must be that module account.
+func addClaimsModuleAccount(cdc codec.JSONMarshaler, state types.AppMap) error {
+	var authGenesis auth.GenesisState
+	if err := cdc.UnmarshalJSON(state[auth.ModuleName], &authGenesis); err != nil {
+		return errors.Wrapf(err, "failed to JSON unmarshal %s genesis", auth.ModuleName)
+	}
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return err
+	}
+
+	addr := auth.NewModuleAddress(prop29ClaimsModuleName)
+	var nextAccountNumber uint64
+	for _, acc := range accounts {
+		if acc.GetAddress().Equals(addr) {
+			if macc, ok := acc.(auth.ModuleAccountI); ok && macc.GetName() == prop29ClaimsModuleName {
+				return nil
+			}
+			return fmt.Errorf("account %s at the address of the %s module account is not that module account", addr, prop29ClaimsModuleName)
+		}
+
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+	}
+
+	macc := auth.NewEmptyModuleAccount(prop29ClaimsModuleName)
+	if err := macc.SetAccountNumber(nextAccountNumber); err != nil {
+		return err
+	}
+
+	authGenesis.Accounts, err = auth.PackAccounts(append(accounts, macc))
+	if err != nil {
+		return err
+	}
+
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	return nil
+}
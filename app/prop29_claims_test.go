@@ -0,0 +1,117 @@
+package gaia
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProp29Claims(t *testing.T) {
+	b, bankGenesis := recoveryBankGenesis(t)
+	testAddr := func(name string) string { return b.Address(name).String() }
+
+	// the address of holder with its last character changed
+	holder := testAddr("holder")
+	badChecksum := holder[:len(holder)-1] + "q"
+	if badChecksum == holder {
+		badChecksum = holder[:len(holder)-1] + "p"
+	}
+
+	path := filepath.Join(t.TempDir(), "prop29.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[
+		{"from": "`+testAddr("fundraiser1")+`", "to": "`+holder+`", "amount": [{"denom": "uatom", "amount": "400"}]},
+		{"from": "`+testAddr("fundraiser1")+`", "to": "cosmos1notvalid!", "amount": [{"denom": "uatom", "amount": "100"}, {"denom": "`+ibcDenom+`", "amount": "20"}],
+		 "vesting": {"type": "delayed", "end": "720h"}},
+		{"from": "`+testAddr("fundraiser2")+`", "to": "`+badChecksum+`", "amount": [{"denom": "uatom", "amount": "300"}]}
+	]`), 0600))
+
+	_, err := loadRecoveryEntries(path, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid to address in prop29 entry 1")
+
+	claimsAddr, err := prop29ClaimsAddress(prop29ClaimsModule)
+	require.NoError(t, err)
+	require.Equal(t, auth.NewModuleAddress(prop29ClaimsModuleName).String(), claimsAddr)
+
+	entries, err := loadRecoveryEntries(path, claimsAddr)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Nil(t, entries[0].diversion)
+	require.Equal(t, holder, entries[0].To)
+	for _, i := range []int{1, 2} {
+		require.NotNil(t, entries[i].diversion)
+		require.Equal(t, i, entries[i].diversion.Entry)
+		require.Equal(t, claimsAddr, entries[i].To)
+		require.Nil(t, entries[i].Vesting)
+	}
+	require.Equal(t, "cosmos1notvalid!", entries[1].diversion.IntendedTo)
+	require.NotNil(t, entries[1].diversion.Vesting)
+	require.Contains(t, entries[1].diversion.Reason, "invalid character")
+	require.Equal(t, badChecksum, entries[2].diversion.IntendedTo)
+	require.Contains(t, entries[2].diversion.Reason, "checksum")
+
+	report, err := applyRecoveries(&bankGenesis, entries)
+	require.NoError(t, err)
+	require.NoError(t, bankGenesis.Validate())
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 400)), report.Delivered)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 400), sdk.NewInt64Coin(ibcDenom, 20)), report.Diverted)
+	require.Equal(t, report.Delivered.Add(report.Diverted...), report.Totals)
+
+	balances := make(map[string]sdk.Coins)
+	total := sdk.NewCoins()
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+		total = total.Add(balance.Coins...)
+	}
+	require.Equal(t, bankGenesis.Supply, total)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 405)), balances[holder])
+	require.Equal(t, report.Diverted, balances[claimsAddr])
+
+	claims := newProp29Claims(prop29ClaimsModule, entries)
+	require.Equal(t, claimsAddr, claims.ClaimsAccount)
+	require.Equal(t, prop29ClaimsModuleName, claims.ModuleAccount)
+	require.Equal(t, []divertedRecovery{*entries[1].diversion, *entries[2].diversion}, claims.Entries)
+	require.Equal(t, report.Diverted, claims.Totals)
+
+	// a regular claims account is no module account
+	claims = newProp29Claims(testAddr("claims"), entries)
+	require.Equal(t, testAddr("claims"), claims.ClaimsAccount)
+	require.Empty(t, claims.ModuleAccount)
+
+	_, err = prop29ClaimsAddress("cosmos1bad")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--"+flagProp29Claims)
+}
+
+func TestAddClaimsModuleAccount(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	_, state := buildTestGenesis(t, genesisbuilder.New().WithValidators(1))
+
+	accounts := func() []auth.GenesisAccount {
+		var authGenesis auth.GenesisState
+		cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+		accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+		require.NoError(t, err)
+		return accounts
+	}
+	before := len(accounts())
+
+	require.NoError(t, addClaimsModuleAccount(cdc, state))
+	// the module account is added once
+	require.NoError(t, addClaimsModuleAccount(cdc, state))
+
+	after := accounts()
+	require.Len(t, after, before+1)
+	macc, ok := after[before].(auth.ModuleAccountI)
+	require.True(t, ok)
+	require.Equal(t, prop29ClaimsModuleName, macc.GetName())
+	require.Equal(t, auth.NewModuleAddress(prop29ClaimsModuleName), macc.GetAddress())
+	for _, acc := range after[:before] {
+		require.NotEqual(t, acc.GetAccountNumber(), macc.GetAccountNumber())
+	}
+}
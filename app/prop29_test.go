@@ -0,0 +1,130 @@
+package gaia
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+const ibcDenom = "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"
+
+func recoveryBankGenesis(t *testing.T) (*genesisbuilder.Builder, bank.GenesisState) {
+	b := genesisbuilder.New().
+		WithAccount("fundraiser1", sdk.NewCoins(sdk.NewInt64Coin("uatom", 1000), sdk.NewInt64Coin(ibcDenom, 50))).
+		WithAccount("fundraiser2", sdk.NewCoins(sdk.NewInt64Coin("uatom", 300))).
+		WithAccount("holder", sdk.NewCoins(sdk.NewInt64Coin("uatom", 5)))
+	_, state := buildTestGenesis(t, b)
+
+	var bankGenesis bank.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+
+	return b, bankGenesis
+}
+
+func TestApplyRecoveries(t *testing.T) {
+	b, bankGenesis := recoveryBankGenesis(t)
+	testAddr := func(name string) string { return b.Address(name).String() }
+
+	entries := []recoveryEntry{
+		{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 400), sdk.NewInt64Coin(ibcDenom, 20))},
+		{From: testAddr("fundraiser2"), To: testAddr("newaccount"), Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 300))},
+		{From: testAddr("fundraiser1"), To: testAddr("newaccount"), Amount: sdk.NewCoins(sdk.NewInt64Coin(ibcDenom, 30))},
+	}
+
+	report, err := applyRecoveries(&bankGenesis, entries)
+	require.NoError(t, err)
+	require.NoError(t, bankGenesis.Validate())
+
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 700), sdk.NewInt64Coin(ibcDenom, 50)), report.Totals)
+
+	balances := make(map[string]sdk.Coins)
+	total := sdk.NewCoins()
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+		total = total.Add(balance.Coins...)
+	}
+
+	require.Equal(t, bankGenesis.Supply, total)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 600)), balances[testAddr("fundraiser1")])
+	require.True(t, balances[testAddr("fundraiser2")].IsZero())
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 405), sdk.NewInt64Coin(ibcDenom, 20)), balances[testAddr("holder")])
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 300), sdk.NewInt64Coin(ibcDenom, 30)), balances[testAddr("newaccount")])
+}
+
+func TestApplyRecoveriesErrors(t *testing.T) {
+	b, _ := recoveryBankGenesis(t)
+	testAddr := func(name string) string { return b.Address(name).String() }
+
+	testCases := []struct {
+		name   string
+		entry  recoveryEntry
+		expErr string
+	}{
+		{
+			"denom not in supply",
+			recoveryEntry{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: sdk.NewCoins(sdk.NewInt64Coin("stake", 1))},
+			"not in the bank supply",
+		},
+		{
+			"insufficient balance",
+			recoveryEntry{From: testAddr("fundraiser2"), To: testAddr("holder"), Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 301))},
+			"is less than",
+		},
+		{
+			"unknown source",
+			recoveryEntry{From: testAddr("nobody"), To: testAddr("holder"), Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 1))},
+			"has no balance",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, bankGenesis := recoveryBankGenesis(t)
+
+			_, err := applyRecoveries(&bankGenesis, []recoveryEntry{tc.entry})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expErr)
+		})
+	}
+}
+
+func TestLoadRecoveryEntries(t *testing.T) {
+	b := genesisbuilder.New()
+	testAddr := func(name string) string { return b.Address(name).String() }
+
+	path := filepath.Join(t.TempDir(), "prop29.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{
+		"from": "`+testAddr("fundraiser1")+`",
+		"to": "`+testAddr("holder")+`",
+		"amount": [{"denom": "uatom", "amount": "10"}, {"denom": "`+ibcDenom+`", "amount": "5"}]
+	}]`), 0600))
+
+	entries, err := loadRecoveryEntries(path, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 10), sdk.NewInt64Coin(ibcDenom, 5)), entries[0].Amount)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"from": "cosmos1bad", "to": "`+testAddr("holder")+`", "amount": []}]`), 0600))
+	_, err = loadRecoveryEntries(path, "")
+	require.Error(t, err)
+	for data, expErr := range map[string]string{
+		// a misspelt field would release the amount at once
+		`"amount": [{"denom": "uatom", "amount": "10"}], "vestng": {"type": "delayed", "end": "720h"}`: `unknown field "vestng"`,
+		`"amount": [{"denom": "uatom"}]`: "coin uatom has no amount",
+		`"amount": [{"denom": "uatom", "amount": "10"}], "vesting": {"type": "periodic", "periods": [{"length": "1h", "amount": [{"denom": "ufoo", "amount": "10"}]}]}`: "not the entry amount",
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(`[{"from": "`+testAddr("fundraiser1")+`", "to": "`+testAddr("holder")+`", `+data+`}]`), 0600))
+		_, err = loadRecoveryEntries(path, "")
+		require.Error(t, err, data)
+		require.Contains(t, err.Error(), expErr)
+	}
+
+	_, err = parseRecoveryEntries([]byte(`[] []`), "")
+	require.Error(t, err)
+}
@@ -0,0 +1,301 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vesting "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	recoveryVestingDelayed  = "delayed"
+	recoveryVestingPeriodic = "periodic"
+)
+
+// recoveryVesting releases the amount of a recovery entry on a schedule
+// relative to the genesis time of the migrated chain instead of at once. The
+// offsets and period lengths are durations such as "720h", in whole seconds.
+type recoveryVesting struct {
+	Type string `json:"type" enum:"delayed,periodic" desc:"Kind of schedule"`
+	// End is when a delayed schedule releases the whole amount.
+	End string `json:"end,omitempty" desc:"Offset from the genesis time at which a delayed schedule releases the whole amount, e.g. 720h"`
+	// Start is when the first period of a periodic schedule starts, the
+	// genesis time if empty. The period amounts sum to the entry amount.
+	Start   string           `json:"start,omitempty" desc:"Offset from the genesis time at which the first period of a periodic schedule starts, 0s if absent"`
+	Periods []recoveryPeriod `json:"periods,omitempty" desc:"Periods of a periodic schedule, whose amounts sum to the entry amount"`
+}
+
+type recoveryPeriod struct {
+	Length string    `json:"length" desc:"Length of the period, e.g. 720h"`
+	Amount sdk.Coins `json:"amount" desc:"Coins released at the end of the period"`
+}
+
+// parseVestingOffset parses a duration of whole seconds, which may not be
+// negative.
+func parseVestingOffset(s string) (int64, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if d < 0 || d%time.Second != 0 {
+		return 0, fmt.Errorf("%s is not a non-negative number of seconds", s)
+	}
+
+	return int64(d / time.Second), nil
+}
+
+// Validate checks the schedule releases exactly amount.
+func (v recoveryVesting) Validate(amount sdk.Coins) error {
+	switch v.Type {
+	case recoveryVestingDelayed:
+		if v.Start != "" || len(v.Periods) > 0 {
+			return fmt.Errorf("a %s vesting schedule has only an end", recoveryVestingDelayed)
+		}
+
+		end, err := parseVestingOffset(v.End)
+		if err != nil {
+			return errors.Wrap(err, "invalid vesting end")
+		}
+		if end == 0 {
+			return fmt.Errorf("a %s vesting schedule must end after the genesis time", recoveryVestingDelayed)
+		}
+
+	case recoveryVestingPeriodic:
+		if v.End != "" {
+			return fmt.Errorf("a %s vesting schedule ends with its last period", recoveryVestingPeriodic)
+		}
+
+		if v.Start != "" {
+			if _, err := parseVestingOffset(v.Start); err != nil {
+				return errors.Wrap(err, "invalid vesting start")
+			}
+		}
+
+		if len(v.Periods) == 0 {
+			return fmt.Errorf("a %s vesting schedule needs periods", recoveryVestingPeriodic)
+		}
+
+		total := sdk.NewCoins()
+		for i, p := range v.Periods {
+			length, err := parseVestingOffset(p.Length)
+			if err != nil {
+				return errors.Wrapf(err, "invalid length of vesting period %d", i)
+			}
+			if length == 0 {
+				return fmt.Errorf("vesting period %d is empty", i)
+			}
+
+			if err := validateCoins(p.Amount); err != nil {
+				return errors.Wrapf(err, "invalid amount of vesting period %d", i)
+			}
+			total = total.Add(p.Amount...)
+		}
+
+		// IsEqual panics on coins of different denoms
+		if !total.IsAllGTE(amount) || !amount.IsAllGTE(total) {
+			return fmt.Errorf("vesting periods release %s, not the entry amount %s", total, amount)
+		}
+
+	default:
+		return fmt.Errorf("unknown vesting type %q, expected %s or %s", v.Type, recoveryVestingDelayed, recoveryVestingPeriodic)
+	}
+
+	return nil
+}
+
+// schedule returns the unix start and end times and the periods of the
+// schedule for a chain starting at genesisTime. Delayed schedules have no
+// periods and start at the genesis time.
+func (v recoveryVesting) schedule(genesisTime time.Time) (start, end int64, periods vestingtypes.Periods) {
+	start = genesisTime.Unix()
+
+	if v.Type == recoveryVestingDelayed {
+		offset, _ := parseVestingOffset(v.End)
+		return start, start + offset, nil
+	}
+
+	if v.Start != "" {
+		offset, _ := parseVestingOffset(v.Start)
+		start += offset
+	}
+
+	end = start
+	for _, p := range v.Periods {
+		length, _ := parseVestingOffset(p.Length)
+		periods = append(periods, vestingtypes.Period{Length: length, Amount: p.Amount})
+		end += length
+	}
+
+	return start, end, periods
+}
+
+// hasRecoveryVesting tells whether any of the recovery entries vests.
+func hasRecoveryVesting(entries []recoveryEntry) bool {
+	for _, entry := range entries {
+		if entry.Vesting != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyRecoveryVesting turns the destinations of the recovery entries with a
+// vesting schedule into vesting accounts of the recovered amounts, after
+// applyRecoveries moved the balances. A base account is converted and keeps
+// its existing balance spendable, a missing account is created. The schedule
+// is merged into an existing vesting account of the same type when the
+// result vests each amount when it would have vested alone: the periods of a
+// periodic account are merged at their end times, a delayed account must end
+// at the same time. Any other existing account fails the migration.
+func applyRecoveryVesting(cdc codec.JSONMarshaler, state types.AppMap, entries []recoveryEntry, genesisTime time.Time) error {
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return err
+	}
+
+	accountIdx := make(map[string]int, len(accounts))
+	var nextAccountNumber uint64
+	for i, acc := range accounts {
+		accountIdx[acc.GetAddress().String()] = i
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.Vesting == nil {
+			continue
+		}
+
+		idx, ok := accountIdx[entry.To]
+		if !ok {
+			addr, err := sdk.AccAddressFromBech32(entry.To)
+			if err != nil {
+				return errors.Wrapf(err, "invalid to address in prop29 entry %d", i)
+			}
+
+			accounts = append(accounts, auth.NewBaseAccount(addr, nil, nextAccountNumber, 0))
+			nextAccountNumber++
+			idx = len(accounts) - 1
+			accountIdx[entry.To] = idx
+		}
+
+		acc, err := addRecoveryVesting(accounts[idx], entry.Amount, *entry.Vesting, genesisTime)
+		if err != nil {
+			return errors.Wrapf(err, "prop29 entry %d: account %s", i, entry.To)
+		}
+		accounts[idx] = acc
+	}
+
+	authGenesis.Accounts, err = auth.PackAccounts(accounts)
+	if err != nil {
+		return err
+	}
+
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+
+	return nil
+}
+
+// addRecoveryVesting returns acc vesting amount on the schedule v in addition
+// to what it vests already.
+func addRecoveryVesting(acc auth.GenesisAccount, amount sdk.Coins, v recoveryVesting, genesisTime time.Time) (auth.GenesisAccount, error) {
+	start, end, periods := v.schedule(genesisTime)
+
+	switch acc := acc.(type) {
+	case auth.ModuleAccountI:
+		return nil, fmt.Errorf("is a module account")
+
+	case *vestingtypes.DelayedVestingAccount:
+		if v.Type != recoveryVestingDelayed {
+			return nil, fmt.Errorf("is a delayed vesting account, the entry vests on a %s schedule", v.Type)
+		}
+		if acc.EndTime != end {
+			return nil, fmt.Errorf("is a delayed vesting account ending at %s, the entry ends at %s",
+				time.Unix(acc.EndTime, 0).UTC(), time.Unix(end, 0).UTC())
+		}
+
+		acc.OriginalVesting = acc.OriginalVesting.Add(amount...)
+		return acc, nil
+
+	case *vestingtypes.PeriodicVestingAccount:
+		if v.Type != recoveryVestingPeriodic {
+			return nil, fmt.Errorf("is a periodic vesting account, the entry vests on a %s schedule", v.Type)
+		}
+
+		acc.StartTime, acc.VestingPeriods = mergeVestingPeriods(acc.StartTime, acc.VestingPeriods, start, periods)
+		acc.EndTime = acc.StartTime
+		for _, p := range acc.VestingPeriods {
+			acc.EndTime += p.Length
+		}
+		acc.OriginalVesting = acc.OriginalVesting.Add(amount...)
+		return acc, nil
+
+	case vesting.VestingAccount:
+		return nil, fmt.Errorf("is a %T, only delayed and periodic vesting accounts can vest recovered funds", acc)
+
+	case *auth.BaseAccount:
+		if v.Type == recoveryVestingDelayed {
+			return vestingtypes.NewDelayedVestingAccount(acc, amount, end), nil
+		}
+		return vestingtypes.NewPeriodicVestingAccount(acc, amount, start, periods), nil
+
+	default:
+		return nil, fmt.Errorf("is a %T, which cannot be converted to a vesting account", acc)
+	}
+}
+
+// mergeVestingPeriods merges two periodic schedules into one starting at the
+// earlier start, releasing the amounts of both at the end times they were
+// released at, amounts ending at the same time in a single period.
+func mergeVestingPeriods(startA int64, periodsA vestingtypes.Periods, startB int64, periodsB vestingtypes.Periods) (int64, vestingtypes.Periods) {
+	type release struct {
+		time   int64
+		amount sdk.Coins
+	}
+
+	var releases []release
+	for _, schedule := range []struct {
+		start   int64
+		periods vestingtypes.Periods
+	}{{startA, periodsA}, {startB, periodsB}} {
+		t := schedule.start
+		for _, p := range schedule.periods {
+			t += p.Length
+			releases = append(releases, release{t, p.Amount})
+		}
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool { return releases[i].time < releases[j].time })
+
+	start := startA
+	if startB < start {
+		start = startB
+	}
+
+	var merged vestingtypes.Periods
+	t := start
+	for _, r := range releases {
+		if len(merged) > 0 && r.time == t {
+			merged[len(merged)-1].Amount = merged[len(merged)-1].Amount.Add(r.amount...)
+			continue
+		}
+
+		merged = append(merged, vestingtypes.Period{Length: r.time - t, Amount: r.amount})
+		t = r.time
+	}
+
+	return start, merged
+}
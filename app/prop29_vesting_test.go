@@ -0,0 +1,201 @@
+package gaia
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+// applyTestRecoveries applies the recovery entries to the state of the
+// recovery test genesis and returns its accounts by address.
+func applyTestRecoveries(t *testing.T, b *genesisbuilder.Builder, entries []recoveryEntry) (map[string]auth.GenesisAccount, error) {
+	_, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	_, err := applyRecoveries(&bankGenesis, entries)
+	require.NoError(t, err)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	if err := applyRecoveryVesting(cdc, state, entries, genesisbuilder.GenesisTime); err != nil {
+		return nil, err
+	}
+
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	require.NoError(t, auth.ValidateGenesis(authGenesis))
+	require.NoError(t, bankGenesis.Validate())
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	require.NoError(t, err)
+
+	byAddress := make(map[string]auth.GenesisAccount, len(accounts))
+	for _, acc := range accounts {
+		byAddress[acc.GetAddress().String()] = acc
+	}
+
+	return byAddress, nil
+}
+
+func uatom(amount int64) sdk.Coins {
+	return sdk.NewCoins(sdk.NewInt64Coin("uatom", amount))
+}
+
+func TestRecoveryVestingDelayed(t *testing.T) {
+	b, _ := recoveryBankGenesis(t)
+	testAddr := func(name string) string { return b.Address(name).String() }
+	delayed := &recoveryVesting{Type: recoveryVestingDelayed, End: "8760h"}
+
+	accounts, err := applyTestRecoveries(t, b, []recoveryEntry{
+		{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: uatom(400), Vesting: delayed},
+		{From: testAddr("fundraiser2"), To: testAddr("newaccount"), Amount: uatom(100), Vesting: delayed},
+		{From: testAddr("fundraiser2"), To: testAddr("newaccount"), Amount: uatom(200), Vesting: delayed},
+	})
+	require.NoError(t, err)
+
+	end := genesisbuilder.GenesisTime.Add(365 * 24 * time.Hour)
+
+	holder, ok := accounts[testAddr("holder")].(*vestingtypes.DelayedVestingAccount)
+	require.True(t, ok)
+	require.Equal(t, uatom(400), holder.OriginalVesting)
+	require.Equal(t, end.Unix(), holder.EndTime)
+	// the balance held before the recovery stays spendable
+	require.Equal(t, uatom(400), holder.LockedCoins(genesisbuilder.GenesisTime))
+	require.True(t, holder.LockedCoins(end).IsZero())
+
+	created, ok := accounts[testAddr("newaccount")].(*vestingtypes.DelayedVestingAccount)
+	require.True(t, ok)
+	require.Equal(t, uatom(300), created.OriginalVesting)
+	require.Equal(t, uint64(len(accounts)-1), created.AccountNumber)
+}
+
+func TestRecoveryVestingPeriodic(t *testing.T) {
+	b, _ := recoveryBankGenesis(t)
+	testAddr := func(name string) string { return b.Address(name).String() }
+
+	accounts, err := applyTestRecoveries(t, b, []recoveryEntry{
+		{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: uatom(300), Vesting: &recoveryVesting{
+			Type: recoveryVestingPeriodic,
+			Periods: []recoveryPeriod{
+				{Length: "720h", Amount: uatom(100)},
+				{Length: "720h", Amount: uatom(200)},
+			},
+		}},
+		// merged with the first schedule, releasing at days 30, 40 and 60
+		{From: testAddr("fundraiser2"), To: testAddr("holder"), Amount: uatom(60), Vesting: &recoveryVesting{
+			Type:  recoveryVestingPeriodic,
+			Start: "240h",
+			Periods: []recoveryPeriod{
+				{Length: "720h", Amount: uatom(50)},
+				{Length: "480h", Amount: uatom(10)},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	day := int64(24 * 60 * 60)
+	holder, ok := accounts[testAddr("holder")].(*vestingtypes.PeriodicVestingAccount)
+	require.True(t, ok)
+	require.Equal(t, uatom(360), holder.OriginalVesting)
+	require.Equal(t, genesisbuilder.GenesisTime.Unix(), holder.StartTime)
+	require.Equal(t, genesisbuilder.GenesisTime.Unix()+60*day, holder.EndTime)
+	require.Equal(t, []vestingtypes.Period{
+		{Length: 30 * day, Amount: uatom(100)},
+		{Length: 10 * day, Amount: uatom(50)},
+		{Length: 20 * day, Amount: uatom(210)},
+	}, holder.VestingPeriods)
+	require.Equal(t, uatom(150), holder.GetVestedCoins(genesisbuilder.GenesisTime.Add(45*24*time.Hour)))
+}
+
+func TestRecoveryVestingConflicts(t *testing.T) {
+	b, _ := recoveryBankGenesis(t)
+	b = b.WithVestingAccount("dave", uatom(100), -24*time.Hour, 365*24*time.Hour)
+	testAddr := func(name string) string { return b.Address(name).String() }
+
+	delayed := func(end string) *recoveryVesting {
+		return &recoveryVesting{Type: recoveryVestingDelayed, End: end}
+	}
+	periodic := &recoveryVesting{Type: recoveryVestingPeriodic, Periods: []recoveryPeriod{{Length: "720h", Amount: uatom(10)}}}
+
+	testCases := []struct {
+		name    string
+		entries []recoveryEntry
+		err     string
+	}{
+		{
+			"delayed end times differ",
+			[]recoveryEntry{
+				{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: uatom(10), Vesting: delayed("720h")},
+				{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: uatom(10), Vesting: delayed("1440h")},
+			},
+			"prop29 entry 1: account " + testAddr("holder") + ": is a delayed vesting account ending at 2021-07-31 00:00:00 +0000 UTC, the entry ends at 2021-08-30 00:00:00 +0000 UTC",
+		},
+		{
+			"periodic onto delayed",
+			[]recoveryEntry{
+				{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: uatom(10), Vesting: delayed("720h")},
+				{From: testAddr("fundraiser1"), To: testAddr("holder"), Amount: uatom(10), Vesting: periodic},
+			},
+			"is a delayed vesting account, the entry vests on a periodic schedule",
+		},
+		{
+			"continuous vesting account",
+			[]recoveryEntry{
+				{From: testAddr("fundraiser1"), To: testAddr("dave"), Amount: uatom(10), Vesting: periodic},
+			},
+			"is a *types.ContinuousVestingAccount, only delayed and periodic vesting accounts can vest recovered funds",
+		},
+		{
+			"module account",
+			[]recoveryEntry{
+				{From: testAddr("fundraiser1"), To: auth.NewModuleAddress(distribution.ModuleName).String(), Amount: uatom(10), Vesting: periodic},
+			},
+			"is a module account",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := applyTestRecoveries(t, b, tc.entries)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestRecoveryVestingValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		vesting recoveryVesting
+		err     string
+	}{
+		{"unknown type", recoveryVesting{Type: "continuous"}, `unknown vesting type "continuous", expected delayed or periodic`},
+		{"delayed without end", recoveryVesting{Type: recoveryVestingDelayed}, "invalid vesting end"},
+		{"delayed at genesis", recoveryVesting{Type: recoveryVestingDelayed, End: "0s"}, "a delayed vesting schedule must end after the genesis time"},
+		{"delayed with periods", recoveryVesting{Type: recoveryVestingDelayed, End: "1h", Periods: []recoveryPeriod{{Length: "1h", Amount: uatom(10)}}}, "a delayed vesting schedule has only an end"},
+		{"periodic with end", recoveryVesting{Type: recoveryVestingPeriodic, End: "1h"}, "a periodic vesting schedule ends with its last period"},
+		{"periodic without periods", recoveryVesting{Type: recoveryVestingPeriodic}, "a periodic vesting schedule needs periods"},
+		{"fractional seconds", recoveryVesting{Type: recoveryVestingPeriodic, Periods: []recoveryPeriod{{Length: "1.5s", Amount: uatom(10)}}}, "1.5s is not a non-negative number of seconds"},
+		{"negative start", recoveryVesting{Type: recoveryVestingPeriodic, Start: "-1h", Periods: []recoveryPeriod{{Length: "1h", Amount: uatom(10)}}}, "invalid vesting start"},
+		{"amount mismatch", recoveryVesting{Type: recoveryVestingPeriodic, Periods: []recoveryPeriod{{Length: "1h", Amount: uatom(9)}}}, "vesting periods release 9uatom, not the entry amount 10uatom"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.vesting.Validate(uatom(10))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	require.NoError(t, recoveryVesting{Type: recoveryVestingDelayed, End: "720h"}.Validate(uatom(10)))
+}
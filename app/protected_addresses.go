@@ -0,0 +1,147 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/pkg/errors"
+)
+
+// protectedAddresses are the addresses no state-altering migrate option may
+// modify: those listed in the --protected-addresses file, such as exchange
+// cold wallets and multisigs, and every module account. An option that would
+// modify one skips it and records the skip, or fails when Strict is set. The
+// destinations the options move funds to are chosen explicitly and not
+// checked. A nil protectedAddresses protects nothing.
+type protectedAddresses struct {
+	Source string
+	Strict bool
+	Skips  []protectedSkip
+
+	// reasons maps the protected addresses to why they are protected.
+	reasons map[string]string
+	listed  int
+}
+
+// protectedSkip records an address an option left untouched.
+type protectedSkip struct {
+	Option  string `json:"option"`
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+}
+
+// loadProtectedAddresses reads a JSON array of bech32 account addresses and
+// protects them along with the module accounts.
+func loadProtectedAddresses(path string, strict bool) (*protectedAddresses, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read protected addresses file")
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(bz, &addresses); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal protected addresses")
+	}
+
+	return newProtectedAddresses(path, addresses, strict)
+}
+
+func newProtectedAddresses(source string, addresses []string, strict bool) (*protectedAddresses, error) {
+	p := &protectedAddresses{Source: source, Strict: strict, reasons: make(map[string]string)}
+
+	modules := make([]string, 0, len(maccPerms))
+	for name := range maccPerms {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+	for _, name := range modules {
+		p.reasons[auth.NewModuleAddress(name).String()] = fmt.Sprintf("the %s module account", name)
+	}
+
+	for _, addr := range addresses {
+		accAddr, err := sdk.AccAddressFromBech32(addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid protected address %s", addr)
+		}
+
+		if _, ok := p.reasons[accAddr.String()]; !ok {
+			p.reasons[accAddr.String()] = "listed in " + source
+			p.listed++
+		}
+	}
+
+	return p, nil
+}
+
+// skip tells whether option must leave addr untouched and records the skip.
+// In strict mode a protected address fails the option instead.
+func (p *protectedAddresses) skip(option, addr string) (bool, error) {
+	if p == nil {
+		return false, nil
+	}
+
+	reason, ok := p.reasons[addr]
+	if !ok {
+		return false, nil
+	}
+
+	if p.Strict {
+		return false, fmt.Errorf("--%s would modify the protected address %s, %s", option, addr, reason)
+	}
+
+	p.Skips = append(p.Skips, protectedSkip{Option: option, Address: addr, Reason: reason})
+	return true, nil
+}
+
+// filter returns the addresses option may modify.
+func (p *protectedAddresses) filter(option string, addresses []string) ([]string, error) {
+	var kept []string
+	for _, addr := range addresses {
+		skip, err := p.skip(option, addr)
+		if err != nil {
+			return nil, err
+		}
+		if !skip {
+			kept = append(kept, addr)
+		}
+	}
+
+	return kept, nil
+}
+
+// filterRecoveries returns the prop29 recovery entries moving funds neither
+// from nor to a protected address.
+func (p *protectedAddresses) filterRecoveries(entries []recoveryEntry) ([]recoveryEntry, error) {
+	var kept []recoveryEntry
+	for _, entry := range entries {
+		skipFrom, err := p.skip(flagProp29Data, entry.From)
+		if err != nil {
+			return nil, err
+		}
+		skipTo, err := p.skip(flagProp29Data, entry.To)
+		if err != nil {
+			return nil, err
+		}
+
+		if !skipFrom && !skipTo {
+			kept = append(kept, entry)
+		}
+	}
+
+	return kept, nil
+}
+
+// warn registers a warning for every recorded skip.
+func (p *protectedAddresses) warn(warnings *warningCollector) {
+	if p == nil {
+		return
+	}
+
+	for _, skip := range p.Skips {
+		warnings.Add(warnAuthProtectedSkipped, severityLow, auth.ModuleName, "--%s left the protected address %s untouched, %s", skip.Option, skip.Address, skip.Reason)
+	}
+}
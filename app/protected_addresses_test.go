@@ -0,0 +1,126 @@
+package gaia
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneProtectedAccounts(t *testing.T) {
+	b := testGenesisBuilder().WithDelegation("bob", 2, 4000)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+	cdc := MakeEncodingConfig().Marshaler
+	bob := b.Address("bob").String()
+
+	protected, err := newProtectedAddresses("protected.json", []string{bob}, false)
+	require.NoError(t, err)
+
+	_, state := exportTestGenesis(t, builtDoc)
+	report, err := pruneAccounts(cdc, state, pruneOptions{
+		Below:     &sdk.Coin{Denom: genesisbuilder.BondDenom, Amount: sdk.NewInt(2000000)},
+		Sink:      b.Address("sink"),
+		Protected: protected,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Accounts)
+	require.Equal(t, []protectedSkip{{Option: flagPruneBelow, Address: bob, Reason: "listed in protected.json"}}, protected.Skips)
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+	// the small account survives with its balance, depositor is pruned
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	require.NoError(t, err)
+	addresses := make(map[string]bool)
+	for _, acc := range accounts {
+		addresses[acc.GetAddress().String()] = true
+	}
+	require.True(t, addresses[bob])
+	require.False(t, addresses[b.Address("depositor").String()])
+
+	var bankGenesis bank.GenesisState
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	balances := make(map[string]sdk.Coins)
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+	}
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000)), balances[bob])
+
+	warnings := &warningCollector{}
+	protected.warn(warnings)
+	require.Len(t, warnings.Warnings(), 1)
+	require.Equal(t, warnAuthProtectedSkipped, warnings.Warnings()[0].Code)
+
+	// strict protection fails the pruning instead
+	protected, err = newProtectedAddresses("protected.json", []string{bob}, true)
+	require.NoError(t, err)
+	_, state = exportTestGenesis(t, builtDoc)
+	_, err = pruneAccounts(cdc, state, pruneOptions{KeepTop: 1, Sink: b.Address("sink"), Protected: protected})
+	require.EqualError(t, err, "--prune-accounts-below would modify the protected address "+bob+", listed in protected.json")
+}
+
+func TestProtectedAddressesOptions(t *testing.T) {
+	b := genesisbuilder.New()
+	dir := t.TempDir()
+	protectedFile := filepath.Join(dir, "protected.json")
+	require.NoError(t, ioutil.WriteFile(protectedFile, []byte(`["`+b.Address("cold-wallet").String()+`"]`), 0644))
+	blocked := filepath.Join(dir, "blocked.json")
+	require.NoError(t, ioutil.WriteFile(blocked, []byte(`["`+b.Address("cold-wallet").String()+`","`+b.Address("thief").String()+`"]`), 0644))
+
+	parse := func(args ...string) (stateChangeOptions, error) {
+		return stateChangeOptionsOf(parseMigrateOptions(t, args...))
+	}
+
+	// the protected blocked address is skipped
+	opts, err := parse("--"+flagProtectedAddrs, protectedFile, "--"+flagBlockedAddresses, blocked)
+	require.NoError(t, err)
+	require.Equal(t, []string{b.Address("thief").String()}, opts.Blocked)
+	require.Equal(t, []string{
+		"--blocked-addresses: move the funds of the addresses listed in " + blocked + " (1) to community-pool and remove their accounts",
+		"--protected-addresses: leave the 1 addresses listed in " + protectedFile + " and the module accounts untouched, skipping conflicts",
+	}, opts.Summary())
+
+	// module accounts are protected without being listed
+	skip, err := opts.Protected.skip(flagSweepModuleDust, auth.NewModuleAddress(gov.ModuleName).String())
+	require.NoError(t, err)
+	require.True(t, skip)
+	require.Equal(t, "the gov module account", opts.Protected.Skips[len(opts.Protected.Skips)-1].Reason)
+
+	_, err = parse("--"+flagProtectedAddrs, protectedFile, "--"+flagBlockedAddresses, blocked, "--"+flagFailOnProtected)
+	require.EqualError(t, err, "--blocked-addresses would modify the protected address "+b.Address("cold-wallet").String()+", listed in "+protectedFile)
+
+	_, err = parse("--" + flagFailOnProtected)
+	require.EqualError(t, err, "--fail-on-protected-conflict needs --protected-addresses")
+
+	// without other state changes there is nothing to confirm
+	opts, err = parse("--"+flagProtectedAddrs, protectedFile)
+	require.NoError(t, err)
+	require.Empty(t, opts.Summary())
+}
+
+func TestAirdropProtectedAccounts(t *testing.T) {
+	b := testGenesisBuilder()
+	_, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+	alice := b.Address("alice").String()
+
+	protected, err := newProtectedAddresses("protected.json", []string{alice}, false)
+	require.NoError(t, err)
+
+	amount := sdk.NewInt(5)
+	report, err := applyAirdrop(cdc, state, airdropFormula{Denom: "udrop", Amount: &amount}, protected, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Grants)
+	for _, grant := range report.Grants {
+		require.NotEqual(t, alice, grant.Address)
+	}
+	require.Equal(t, []protectedSkip{{Option: flagAirdrop, Address: alice, Reason: "listed in protected.json"}}, protected.Skips)
+}
@@ -0,0 +1,481 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+// pruneOptions selects the accounts removed by pruneAccounts. An account is
+// pruned when it holds less than Below, or when it does not rank among the
+// KeepTop largest accounts. Everything the pruned accounts own is handed to
+// Sink. Protected accounts keep their rank but are never pruned.
+type pruneOptions struct {
+	Below     *sdk.Coin
+	KeepTop   int
+	Sink      sdk.AccAddress
+	Protected *protectedAddresses
+}
+
+// pruneReport summarizes what pruneAccounts removed. MergedDelegations counts
+// the delegations merged into another delegation of the sink to the same
+// validator. The rewards these accrued are not paid out: they stay in the
+// outstanding rewards of the validator, unless --withdraw-all-rewards paid
+// them before the pruning.
+type pruneReport struct {
+	Accounts          int       `json:"accounts"`
+	Balances          sdk.Coins `json:"balances"`
+	Stake             sdk.Int   `json:"stake"`
+	Unbonding         sdk.Int   `json:"unbonding"`
+	Deposits          sdk.Coins `json:"deposits"`
+	Votes             int       `json:"votes"`
+	MergedDelegations int       `json:"merged_delegations"`
+}
+
+// pruneAccounts removes small accounts from the app state while keeping it
+// valid: their balances and gov deposits are moved to the sink account, their
+// delegations, unbonding and redelegations are transferred to it, and their
+// votes are dropped. Module accounts, validator operator accounts and the sink
+// itself are never pruned. The total supply and every validator's power are
+// left unchanged.
+func pruneAccounts(cdc codec.JSONMarshaler, state types.AppMap, opts pruneOptions) (pruneReport, error) {
+	report := pruneReport{Balances: sdk.NewCoins(), Stake: sdk.ZeroInt(), Unbonding: sdk.ZeroInt(), Deposits: sdk.NewCoins()}
+
+	if opts.Sink.Empty() {
+		return report, fmt.Errorf("a sink account is required to prune accounts")
+	}
+
+	var (
+		authGenesis         auth.GenesisState
+		bankGenesis         bank.GenesisState
+		stakingGenesis      staking.GenesisState
+		distributionGenesis distribution.GenesisState
+		govGenesis          gov.GenesisState
+	)
+
+	if err := cdc.UnmarshalJSON(state[auth.ModuleName], &authGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", auth.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", bank.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", staking.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[distribution.ModuleName], &distributionGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", distribution.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[gov.ModuleName], &govGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", gov.ModuleName)
+	}
+
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return report, err
+	}
+
+	pruned, err := selectPrunedAccounts(accounts, bankGenesis, stakingGenesis, opts)
+	if err != nil {
+		return report, err
+	}
+
+	sink := opts.Sink.String()
+	kept := accounts[:0]
+	hasSink := false
+	var nextAccountNumber uint64
+	for _, acc := range accounts {
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+
+		if pruned[acc.GetAddress().String()] {
+			continue
+		}
+
+		if acc.GetAddress().Equals(opts.Sink) {
+			if _, ok := acc.(auth.ModuleAccountI); ok {
+				return report, fmt.Errorf("pruning sink %s is a module account", sink)
+			}
+
+			hasSink = true
+		}
+
+		kept = append(kept, acc)
+	}
+
+	if !hasSink {
+		kept = append(kept, auth.NewBaseAccount(opts.Sink, nil, nextAccountNumber, 0))
+	}
+
+	report.Accounts = len(pruned)
+
+	authGenesis.Accounts, err = auth.PackAccounts(kept)
+	if err != nil {
+		return report, err
+	}
+
+	govAddress := auth.NewModuleAddress(gov.ModuleName).String()
+	for _, amount := range removeDeposits(&govGenesis, pruned) {
+		report.Deposits = report.Deposits.Add(amount...)
+	}
+
+	votes := govGenesis.Votes[:0]
+	for _, vote := range govGenesis.Votes {
+		if pruned[vote.Voter] {
+			report.Votes++
+			continue
+		}
+
+		votes = append(votes, vote)
+	}
+	govGenesis.Votes = votes
+
+	sinkCoins := report.Deposits
+	balances := bankGenesis.Balances[:0]
+	for _, balance := range bankGenesis.Balances {
+		switch {
+		case pruned[balance.Address]:
+			report.Balances = report.Balances.Add(balance.Coins...)
+			sinkCoins = sinkCoins.Add(balance.Coins...)
+			continue
+		case balance.Address == govAddress:
+			balance.Coins = balance.Coins.Sub(report.Deposits)
+		case balance.Address == sink:
+			sinkCoins = sinkCoins.Add(balance.Coins...)
+			continue
+		}
+
+		balances = append(balances, balance)
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(append(balances, bank.Balance{Address: sink, Coins: sinkCoins}))
+
+	transferStaking(&stakingGenesis, &distributionGenesis, pruned, sink, &report)
+
+	state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+
+	return report, nil
+}
+
+// selectPrunedAccounts returns the addresses of the accounts to prune. Accounts
+// are weighed by their balance in the pruning denom, plus their delegated
+// tokens when that is the bond denom.
+func selectPrunedAccounts(accounts auth.GenesisAccounts, bankGenesis bank.GenesisState, stakingGenesis staking.GenesisState, opts pruneOptions) (map[string]bool, error) {
+	denom := stakingGenesis.Params.BondDenom
+	if opts.Below != nil {
+		denom = opts.Below.Denom
+	}
+
+	weights := make(map[string]sdk.Int)
+	for _, balance := range bankGenesis.Balances {
+		weights[balance.Address] = balance.Coins.AmountOf(denom)
+	}
+
+	protected := map[string]bool{opts.Sink.String(): true}
+
+	validators := make(map[string]staking.Validator, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		valAddr, err := sdk.ValAddressFromBech32(val.OperatorAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		validators[val.OperatorAddress] = val
+		protected[sdk.AccAddress(valAddr).String()] = true
+	}
+
+	if denom == stakingGenesis.Params.BondDenom {
+		for _, del := range stakingGenesis.Delegations {
+			val, ok := validators[del.ValidatorAddress]
+			if !ok {
+				return nil, fmt.Errorf("delegation from %s to unknown validator %s", del.DelegatorAddress, del.ValidatorAddress)
+			}
+
+			weight, ok := weights[del.DelegatorAddress]
+			if !ok {
+				weight = sdk.ZeroInt()
+			}
+			weights[del.DelegatorAddress] = weight.Add(val.TokensFromShares(del.Shares).TruncateInt())
+		}
+	}
+
+	var candidates []string
+	for _, acc := range accounts {
+		addr := acc.GetAddress().String()
+		if _, ok := acc.(auth.ModuleAccountI); ok || protected[addr] {
+			continue
+		}
+
+		if _, ok := weights[addr]; !ok {
+			weights[addr] = sdk.ZeroInt()
+		}
+
+		candidates = append(candidates, addr)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		wi, wj := weights[candidates[i]], weights[candidates[j]]
+		if !wi.Equal(wj) {
+			return wi.GT(wj)
+		}
+
+		return candidates[i] < candidates[j]
+	})
+
+	pruned := make(map[string]bool)
+	for i, addr := range candidates {
+		if (opts.KeepTop > 0 && i >= opts.KeepTop) || (opts.Below != nil && weights[addr].LT(opts.Below.Amount)) {
+			skip, err := opts.Protected.skip(flagPruneBelow, addr)
+			if err != nil {
+				return nil, err
+			}
+			if !skip {
+				pruned[addr] = true
+			}
+		}
+	}
+
+	return pruned, nil
+}
+
+// transferStaking hands the delegations, unbonding delegations and
+// redelegations of the pruned accounts to the sink, merging them with the
+// sink's own, and updates the distribution records keyed by delegator.
+func transferStaking(stakingGenesis *staking.GenesisState, distributionGenesis *distribution.GenesisState, pruned map[string]bool, sink string, report *pruneReport) {
+	validators := make(map[string]staking.Validator, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		validators[val.OperatorAddress] = val
+	}
+
+	// merged delegations drop their starting info, the first delegation of
+	// the sink to a validator keeps its own
+	dropped := make(map[startingInfoKey]bool)
+	keptInfos := make(map[string]startingInfoKey)
+	merged := make(map[string]bool)
+
+	sinkDelegations := make(map[string]int)
+	delegations := stakingGenesis.Delegations[:0]
+	for _, del := range stakingGenesis.Delegations {
+		delegator := del.DelegatorAddress
+		if pruned[delegator] {
+			report.Stake = report.Stake.Add(validators[del.ValidatorAddress].TokensFromShares(del.Shares).TruncateInt())
+			del.DelegatorAddress = sink
+		}
+
+		if del.DelegatorAddress == sink {
+			if i, ok := sinkDelegations[del.ValidatorAddress]; ok {
+				delegations[i].Shares = delegations[i].Shares.Add(del.Shares)
+				dropped[startingInfoKey{delegator, del.ValidatorAddress}] = true
+				merged[del.ValidatorAddress] = true
+				report.MergedDelegations++
+				continue
+			}
+
+			sinkDelegations[del.ValidatorAddress] = len(delegations)
+			keptInfos[del.ValidatorAddress] = startingInfoKey{delegator, del.ValidatorAddress}
+		}
+
+		delegations = append(delegations, del)
+	}
+	stakingGenesis.Delegations = delegations
+
+	// the kept starting info of a merged delegation gets the merged stake
+	mergedStakes := make(map[startingInfoKey]sdk.Dec, len(merged))
+	for val := range merged {
+		shares := delegations[sinkDelegations[val]].Shares
+		mergedStakes[keptInfos[val]] = validators[val].TokensFromSharesTruncated(shares)
+	}
+	mergeStartingInfos(distributionGenesis, mergedStakes, dropped)
+
+	sinkUnbondings := make(map[string]int)
+	unbondings := stakingGenesis.UnbondingDelegations[:0]
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		if pruned[ubd.DelegatorAddress] {
+			for _, entry := range ubd.Entries {
+				report.Unbonding = report.Unbonding.Add(entry.Balance)
+			}
+			ubd.DelegatorAddress = sink
+		}
+
+		if ubd.DelegatorAddress == sink {
+			if i, ok := sinkUnbondings[ubd.ValidatorAddress]; ok {
+				unbondings[i].Entries = append(unbondings[i].Entries, ubd.Entries...)
+				continue
+			}
+
+			sinkUnbondings[ubd.ValidatorAddress] = len(unbondings)
+		}
+
+		unbondings = append(unbondings, ubd)
+	}
+	stakingGenesis.UnbondingDelegations = unbondings
+
+	type redelegationKey struct{ src, dst string }
+	sinkRedelegations := make(map[redelegationKey]int)
+	redelegations := stakingGenesis.Redelegations[:0]
+	for _, red := range stakingGenesis.Redelegations {
+		if pruned[red.DelegatorAddress] {
+			red.DelegatorAddress = sink
+		}
+
+		if red.DelegatorAddress == sink {
+			key := redelegationKey{red.ValidatorSrcAddress, red.ValidatorDstAddress}
+			if i, ok := sinkRedelegations[key]; ok {
+				redelegations[i].Entries = append(redelegations[i].Entries, red.Entries...)
+				continue
+			}
+
+			sinkRedelegations[key] = len(redelegations)
+		}
+
+		redelegations = append(redelegations, red)
+	}
+	stakingGenesis.Redelegations = redelegations
+
+	dropStartingInfos(distributionGenesis, dropped)
+	for i, info := range distributionGenesis.DelegatorStartingInfos {
+		if pruned[info.DelegatorAddress] {
+			distributionGenesis.DelegatorStartingInfos[i].DelegatorAddress = sink
+		}
+	}
+
+	dropWithdrawInfos(distributionGenesis, pruned)
+}
+
+// startingInfoKey identifies a distribution delegator starting info.
+type startingInfoKey struct{ delegator, validator string }
+
+// mergeStartingInfos sets the stake of the starting infos of stakes, kept by
+// delegations that others with the dropped starting infos were merged into.
+// Each of them moves to the latest period of the starting infos merged with
+// it, so that the rewards of the merged stake are never computed over periods
+// before part of it was delegated: the distribution keeper would pay more
+// than the validator holds. What the merged delegations accrued before that
+// period stays in the outstanding rewards of the validator.
+func mergeStartingInfos(distributionGenesis *distribution.GenesisState, stakes map[startingInfoKey]sdk.Dec, dropped map[startingInfoKey]bool) {
+	if len(stakes) == 0 {
+		return
+	}
+
+	latest := make(map[string]uint64)
+	for _, info := range distributionGenesis.DelegatorStartingInfos {
+		key := startingInfoKey{info.DelegatorAddress, info.ValidatorAddress}
+		if _, ok := stakes[key]; !ok && !dropped[key] {
+			continue
+		}
+		if info.StartingInfo.PreviousPeriod > latest[info.ValidatorAddress] {
+			latest[info.ValidatorAddress] = info.StartingInfo.PreviousPeriod
+		}
+	}
+
+	type periodKey struct {
+		validator string
+		period    uint64
+	}
+	references := make(map[periodKey]int64)
+
+	for i, info := range distributionGenesis.DelegatorStartingInfos {
+		stake, ok := stakes[startingInfoKey{info.DelegatorAddress, info.ValidatorAddress}]
+		if !ok {
+			continue
+		}
+
+		startingInfo := &distributionGenesis.DelegatorStartingInfos[i].StartingInfo
+		if period := latest[info.ValidatorAddress]; period > startingInfo.PreviousPeriod {
+			references[periodKey{info.ValidatorAddress, startingInfo.PreviousPeriod}]--
+			references[periodKey{info.ValidatorAddress, period}]++
+			startingInfo.PreviousPeriod = period
+		}
+		startingInfo.Stake = stake
+	}
+
+	for i, record := range distributionGenesis.ValidatorHistoricalRewards {
+		count := int64(record.Rewards.ReferenceCount) + references[periodKey{record.ValidatorAddress, record.Period}]
+		distributionGenesis.ValidatorHistoricalRewards[i].Rewards.ReferenceCount = uint32(count)
+	}
+}
+
+// dropStartingInfos removes the given delegator starting infos. The historical
+// rewards period each of them referenced loses a reference and is removed once
+// it has none left, as the distribution keeper does when a delegation ends.
+func dropStartingInfos(distributionGenesis *distribution.GenesisState, dropped map[startingInfoKey]bool) {
+	type periodKey struct {
+		validator string
+		period    uint64
+	}
+	released := make(map[periodKey]uint32)
+
+	startingInfos := distributionGenesis.DelegatorStartingInfos[:0]
+	for _, info := range distributionGenesis.DelegatorStartingInfos {
+		if dropped[startingInfoKey{info.DelegatorAddress, info.ValidatorAddress}] {
+			released[periodKey{info.ValidatorAddress, info.StartingInfo.PreviousPeriod}]++
+			continue
+		}
+
+		startingInfos = append(startingInfos, info)
+	}
+	distributionGenesis.DelegatorStartingInfos = startingInfos
+
+	historicalRewards := distributionGenesis.ValidatorHistoricalRewards[:0]
+	for _, record := range distributionGenesis.ValidatorHistoricalRewards {
+		record.Rewards.ReferenceCount -= released[periodKey{record.ValidatorAddress, record.Period}]
+		if record.Rewards.ReferenceCount == 0 {
+			continue
+		}
+
+		historicalRewards = append(historicalRewards, record)
+	}
+	distributionGenesis.ValidatorHistoricalRewards = historicalRewards
+}
+
+// dropWithdrawInfos removes the withdraw addresses set by or pointing to any of
+// the given accounts.
+func dropWithdrawInfos(distributionGenesis *distribution.GenesisState, accounts map[string]bool) {
+	withdrawInfos := distributionGenesis.DelegatorWithdrawInfos[:0]
+	for _, info := range distributionGenesis.DelegatorWithdrawInfos {
+		if !accounts[info.DelegatorAddress] && !accounts[info.WithdrawAddress] {
+			withdrawInfos = append(withdrawInfos, info)
+		}
+	}
+	distributionGenesis.DelegatorWithdrawInfos = withdrawInfos
+}
+
+// removeDeposits removes the gov deposits of the given depositors and lowers
+// the total deposit of their proposals accordingly. It returns the removed
+// amounts by depositor, the caller moves them out of the gov module account.
+func removeDeposits(govGenesis *gov.GenesisState, depositors map[string]bool) map[string]sdk.Coins {
+	removed := make(map[string]sdk.Coins)
+	byProposal := make(map[uint64]sdk.Coins)
+
+	deposits := govGenesis.Deposits[:0]
+	for _, deposit := range govGenesis.Deposits {
+		if !depositors[deposit.Depositor] {
+			deposits = append(deposits, deposit)
+			continue
+		}
+
+		byProposal[deposit.ProposalId] = byProposal[deposit.ProposalId].Add(deposit.Amount...)
+		removed[deposit.Depositor] = removed[deposit.Depositor].Add(deposit.Amount...)
+	}
+	govGenesis.Deposits = deposits
+
+	for i, proposal := range govGenesis.Proposals {
+		if amount, ok := byProposal[proposal.ProposalId]; ok {
+			govGenesis.Proposals[i].TotalDeposit = proposal.TotalDeposit.Sub(amount)
+		}
+	}
+
+	return removed
+}
@@ -0,0 +1,143 @@
+package gaia
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneAccounts(t *testing.T) {
+	b := testGenesisBuilder().WithDelegation("bob", 2, 4000)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	cdc := MakeEncodingConfig().Marshaler
+	sink := b.Address("sink")
+
+	testCases := []struct {
+		name     string
+		opts     pruneOptions
+		accounts int
+		merged   int
+		pruned   []string
+	}{
+		{
+			"below threshold",
+			pruneOptions{Below: &sdk.Coin{Denom: genesisbuilder.BondDenom, Amount: sdk.NewInt(2000000)}, Sink: sink},
+			2,
+			0,
+			[]string{"bob", "depositor"},
+		},
+		{
+			"keep top accounts",
+			pruneOptions{KeepTop: 2, Sink: sink},
+			3,
+			1,
+			[]string{"bob", "carol", "depositor"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			genDoc, state := exportTestGenesis(t, builtDoc)
+
+			var bankBefore bank.GenesisState
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+
+			report, err := pruneAccounts(cdc, state, tc.opts)
+			require.NoError(t, err)
+			require.Equal(t, tc.accounts, report.Accounts)
+			require.Equal(t, sdk.NewInt(10000000), report.Deposits.AmountOf(genesisbuilder.BondDenom))
+			require.Equal(t, sdk.NewInt(500000), report.Unbonding)
+			require.Equal(t, tc.merged, report.MergedDelegations)
+
+			require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+			var (
+				authGenesis         auth.GenesisState
+				bankGenesis         bank.GenesisState
+				stakingGenesis      staking.GenesisState
+				distributionGenesis distribution.GenesisState
+				govGenesis          gov.GenesisState
+			)
+			cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+			cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+			cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+			cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+			cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+
+			require.Equal(t, bankBefore.Supply, bankGenesis.Supply)
+			require.Empty(t, govGenesis.Deposits)
+
+			accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+			require.NoError(t, err)
+
+			addresses := make(map[string]bool)
+			for _, acc := range accounts {
+				addresses[acc.GetAddress().String()] = true
+			}
+
+			require.True(t, addresses[sink.String()])
+			require.True(t, addresses[b.Address("alice").String()])
+			require.True(t, addresses[b.Address(genesisbuilder.ValidatorName(0)).String()])
+			require.True(t, addresses[auth.NewModuleAddress(staking.BondedPoolName).String()])
+			for _, name := range tc.pruned {
+				require.False(t, addresses[b.Address(name).String()], name)
+			}
+
+			for _, del := range stakingGenesis.Delegations {
+				require.True(t, addresses[del.DelegatorAddress], del.DelegatorAddress)
+			}
+			for _, ubd := range stakingGenesis.UnbondingDelegations {
+				require.Equal(t, sink.String(), ubd.DelegatorAddress)
+			}
+			for _, info := range distributionGenesis.DelegatorStartingInfos {
+				require.True(t, addresses[info.DelegatorAddress], info.DelegatorAddress)
+			}
+
+			// the starting info of every delegation has its stake
+			validators := make(map[string]staking.Validator)
+			for _, val := range stakingGenesis.Validators {
+				validators[val.OperatorAddress] = val
+			}
+			stakes := make(map[string]sdk.Dec)
+			for _, info := range distributionGenesis.DelegatorStartingInfos {
+				stakes[info.DelegatorAddress+info.ValidatorAddress] = info.StartingInfo.Stake
+			}
+			require.Len(t, stakes, len(stakingGenesis.Delegations))
+			for _, del := range stakingGenesis.Delegations {
+				stake := validators[del.ValidatorAddress].TokensFromSharesTruncated(del.Shares)
+				require.Equal(t, stake, stakes[del.DelegatorAddress+del.ValidatorAddress], del.DelegatorAddress)
+			}
+
+			// the rewards of the merged delegations are computed on their stake
+			_, err = withdrawAllRewards(cdc, copyAppMap(state))
+			require.NoError(t, err)
+
+			genDoc.AppState, err = json.Marshal(state)
+			require.NoError(t, err)
+			require.NoError(t, SmokeTestGenesis(genDoc))
+		})
+	}
+}
+
+func TestPruneAccountsSink(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	_, state := buildTestGenesis(t, testGenesisBuilder())
+
+	_, err := pruneAccounts(cdc, state, pruneOptions{KeepTop: 1})
+	require.Error(t, err)
+
+	_, err = pruneAccounts(cdc, state, pruneOptions{KeepTop: 1, Sink: auth.NewModuleAddress(gov.ModuleName)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is a module account")
+}
@@ -1,19 +1,22 @@
 package gaia
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"strings"
 
 	"github.com/cosmos/cosmos-sdk/client"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdkcryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/genutil/types"
 	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/pkg/errors"
 	cryptocodec "github.com/tendermint/tendermint/crypto/encoding"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
 	tmtypes "github.com/tendermint/tendermint/types"
 )
 
@@ -30,26 +33,61 @@ func (r *replacementConfigs) isReplacedValidator(validatorAddress string) (int,
 	return -1, replacementConfig{}
 }
 
+// replacementConfig is an entry of the --replacement-cons-keys file.
 type replacementConfig struct {
-	Name             string `json:"validator_name"`
-	ValidatorAddress string `json:"validator_address"`
-	ConsensusPubkey  string `json:"stargate_consensus_public_key"`
+	Name             string `json:"validator_name,omitempty" desc:"Moniker of the validator, informative only"`
+	ValidatorAddress string `json:"validator_address" desc:"Bech32 operator address of the validator"`
+	ConsensusPubkey  string `json:"stargate_consensus_public_key" desc:"Bech32 consensus public key replacing the key of the validator"`
 }
 
-func loadKeydataFromFile(clientCtx client.Context, replacementrJSON string, genDoc *tmtypes.GenesisDoc) *tmtypes.GenesisDoc {
-	jsonReplacementBlob, err := ioutil.ReadFile(replacementrJSON)
-	if err != nil {
-		log.Fatal(errors.Wrapf(err, "failed to read replacement keys from file %s", replacementrJSON))
+// checkReplacementKeyTypes checks that the consensus params allow the type of
+// every replacement key, Tendermint refuses to start with a validator key of
+// another type.
+func checkReplacementKeyTypes(replacementKeys replacementConfigs, params *tmproto.ConsensusParams) error {
+	if params == nil {
+		params = tmtypes.DefaultConsensusParams()
 	}
 
-	var replacementKeys replacementConfigs
+	for _, replacement := range replacementKeys {
+		name := replacement.Name
+		if name == "" {
+			name = replacement.ValidatorAddress
+		}
 
-	err = json.Unmarshal(jsonReplacementBlob, &replacementKeys)
+		consPubKey, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeConsPub, replacement.ConsensusPubkey)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode replacement key of validator %s", name)
+		}
 
+		tmPubKey, err := sdkcryptocodec.ToTmPubKeyInterface(consPubKey)
+		if err != nil {
+			return errors.Wrapf(err, "replacement key of validator %s is not a consensus key", name)
+		}
+
+		if !tmtypes.IsValidPubkeyType(params.Validator, tmPubKey.Type()) {
+			return fmt.Errorf("replacement key of validator %s is a %s key, the consensus params only allow %s",
+				name, tmPubKey.Type(), strings.Join(params.Validator.PubKeyTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+func loadKeydataFromFile(clientCtx client.Context, replacementrJSON string, genDoc *tmtypes.GenesisDoc) *tmtypes.GenesisDoc {
+	replacementKeys, err := readReplacementKeys(clientCtx.JSONMarshaler, replacementrJSON, genDoc)
 	if err != nil {
-		log.Fatal("Could not unmarshal replacement keys ")
+		log.Fatal(err)
 	}
 
+	return replaceConsensusKeys(clientCtx, replacementKeys, genDoc)
+}
+
+// replaceConsensusKeys replaces the consensus keys of the validators of
+// replacementKeys in the staking and slashing genesis and the tendermint
+// genesis validators.
+func replaceConsensusKeys(clientCtx client.Context, replacementKeys replacementConfigs, genDoc *tmtypes.GenesisDoc) *tmtypes.GenesisDoc {
+	var err error
+
 	var state types.AppMap
 	if err := json.Unmarshal(genDoc.AppState, &state); err != nil {
 		log.Fatal(errors.Wrap(err, "failed to JSON unmarshal initial genesis state"))
@@ -97,7 +135,7 @@ func loadKeydataFromFile(clientCtx client.Context, replacementrJSON string, genD
 			}
 
 			for tmIdx, tmval := range genDoc.Validators {
-				if tmval.Address.String() == replaceValConsAddress.String() {
+				if bytes.Equal(tmval.Address, toReplaceValConsAddress) {
 					genDoc.Validators[tmIdx].Address = replaceValConsAddress.Bytes()
 					genDoc.Validators[tmIdx].PubKey = replaceValConsPubKey
 
@@ -119,3 +157,43 @@ func loadKeydataFromFile(clientCtx client.Context, replacementrJSON string, genD
 	return genDoc
 
 }
+
+// keyReplacement records a tendermint genesis validator whose consensus key
+// was replaced.
+type keyReplacement struct {
+	Name string `json:"name"`
+	// ValidatorAddress is the operator address of the validator.
+	ValidatorAddress string `json:"validator_address,omitempty"`
+	Power            int64  `json:"power"`
+	OldConsAddress   string `json:"old_cons_address"`
+	NewConsAddress   string `json:"new_cons_address"`
+}
+
+// replacedKeys returns the validators of before whose consensus address
+// differs in after, the same validators once the keys of replacementKeys
+// replaced theirs.
+func replacedKeys(before, after []tmtypes.GenesisValidator, replacementKeys replacementConfigs) []keyReplacement {
+	operators := make(map[string]string, len(replacementKeys))
+	for _, replacement := range replacementKeys {
+		if pk, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeConsPub, replacement.ConsensusPubkey); err == nil {
+			operators[sdk.ConsAddress(pk.Address()).String()] = replacement.ValidatorAddress
+		}
+	}
+
+	var replaced []keyReplacement
+	for i := range before {
+		if i >= len(after) || bytes.Equal(before[i].Address, after[i].Address) {
+			continue
+		}
+
+		replaced = append(replaced, keyReplacement{
+			Name:             after[i].Name,
+			ValidatorAddress: operators[sdk.ConsAddress(after[i].Address).String()],
+			Power:            after[i].Power,
+			OldConsAddress:   sdk.ConsAddress(before[i].Address).String(),
+			NewConsAddress:   sdk.ConsAddress(after[i].Address).String(),
+		})
+	}
+
+	return replaced
+}
@@ -0,0 +1,46 @@
+package gaia
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	tmsecp256k1 "github.com/tendermint/tendermint/crypto/secp256k1"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+)
+
+func TestMigrateReplacementKeyType(t *testing.T) {
+	consPubKey, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeConsPub, secp256k1.GenPrivKey().PubKey())
+	require.NoError(t, err)
+
+	// the fixture consensus params only allow ed25519
+	replacementKeys := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, ioutil.WriteFile(replacementKeys, []byte(fmt.Sprintf(`[{
+		"validator_name": "validator",
+		"validator_address": "cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0",
+		"stargate_consensus_public_key": %q
+	}]`, consPubKey)), 0644))
+
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+		"--chain-id", "cosmoshub-4", "--replacement-cons-keys", replacementKeys)
+	require.EqualError(t, err, "replacement key of validator validator is a secp256k1 key, the consensus params only allow ed25519")
+}
+
+func TestGenesisValidateValidatorKeyType(t *testing.T) {
+	genDoc, _ := buildTestGenesis(t, testGenesisBuilder())
+	require.Equal(t, []string{"ed25519"}, genDoc.ConsensusParams.Validator.PubKeyTypes)
+
+	pubKey := tmsecp256k1.GenPrivKey().PubKey()
+	genDoc.Validators[1].PubKey = pubKey
+	genDoc.Validators[1].Address = pubKey.Address()
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+
+	_, err = executeGenesisValidate(t, bz)
+	require.EqualError(t, err, fmt.Sprintf("invalid app state: validators: validator %s has a secp256k1 consensus key, the consensus params only allow ed25519", genDoc.Validators[1].Name))
+}
@@ -0,0 +1,256 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/codec/legacy"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	kmultisig "github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagNormalizePubKeys    = "normalize-pubkeys"
+	flagClearInvalidPubKeys = "clear-invalid-pubkeys"
+	flagPubKeyReport        = "pubkey-report"
+)
+
+// Encodings of the account pubkeys found by normalizePubKeys.
+const (
+	pubKeyEncodingAny       = "any"
+	pubKeyEncodingAminoJSON = "amino-json"
+	pubKeyEncodingBech32    = "bech32"
+)
+
+// accountPubKey is an account pubkey of the auth genesis that the
+// normalization re-encoded or could not parse.
+type accountPubKey struct {
+	Address string `json:"address"`
+	// Encoding is how the pubkey was found, any for the current proto Any,
+	// amino-json or bech32 for the legacy ones.
+	Encoding string `json:"encoding"`
+	// Type is the proto type URL of a parsed pubkey.
+	Type    string `json:"type,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Cleared bool   `json:"cleared,omitempty"`
+}
+
+// Invalid returns whether the pubkey failed to parse.
+func (k accountPubKey) Invalid() bool {
+	return k.Error != ""
+}
+
+// normalizePubKeys re-encodes the pubkeys of the accounts of the auth genesis
+// of state into the current proto Any form, whether they are Any, amino JSON
+// or bech32 encoded. It returns the accounts whose pubkey was re-encoded from
+// a legacy encoding or failed to parse: an unknown type, a key of the wrong
+// size or not matching the account address. With clear the failed pubkeys
+// are set to null. The accounts are otherwise left as they are, account
+// numbers and sequences included.
+func normalizePubKeys(cdc codec.JSONMarshaler, state types.AppMap, clear bool) ([]accountPubKey, error) {
+	report, err := scanAccounts(cdc, state, accountScanOptions{NormalizePubKeys: true, ClearPubKeys: clear})
+	if err != nil {
+		return nil, err
+	}
+
+	return report.PubKeys, nil
+}
+
+// normalizeAccountPubKey normalizes the pubkey of base, the base account of
+// the account of address, as normalizePubKeys does. It returns the entry of
+// the pubkey if it was re-encoded from a legacy encoding or failed to parse,
+// and whether base changed.
+func normalizeAccountPubKey(cdc codec.JSONMarshaler, base map[string]interface{}, address string, clear bool) (*accountPubKey, bool, error) {
+	pubKeyBz, err := json.Marshal(base["pub_key"])
+	if err != nil {
+		return nil, false, err
+	}
+	entry := accountPubKey{Address: address}
+
+	var pubKey cryptotypes.PubKey
+	pubKey, entry.Encoding, err = parsePubKeyJSON(cdc, pubKeyBz)
+	if err == nil {
+		err = checkAccountPubKey(pubKey, address)
+	}
+
+	var normalized interface{}
+	if err != nil {
+		entry.Error, entry.Cleared = err.Error(), clear
+	} else {
+		entry.Type = "/" + proto.MessageName(pubKey)
+		if normalized, err = pubKeyAnyJSON(cdc, pubKey); err != nil {
+			return nil, false, errors.Wrapf(err, "failed to encode the pubkey of account %s", address)
+		}
+	}
+
+	var found *accountPubKey
+	if entry.Invalid() || entry.Encoding != pubKeyEncodingAny {
+		found = &entry
+	}
+	if entry.Invalid() && !entry.Cleared {
+		return found, false, nil
+	}
+
+	base["pub_key"] = normalized
+	return found, true, nil
+}
+
+// baseAccountJSON returns the base account of the JSON of an account of any
+// of the auth and vesting account types, nil if it has none.
+func baseAccountJSON(account map[string]interface{}) map[string]interface{} {
+	for {
+		if _, ok := account["address"]; ok {
+			return account
+		}
+
+		next, ok := account["base_account"].(map[string]interface{})
+		if !ok {
+			next, ok = account["base_vesting_account"].(map[string]interface{})
+		}
+		if !ok {
+			return nil
+		}
+		account = next
+	}
+}
+
+// parsePubKeyJSON parses a pubkey encoded as a proto Any, as amino JSON or as
+// a bech32 string and returns it with its encoding.
+func parsePubKeyJSON(cdc codec.JSONMarshaler, bz []byte) (cryptotypes.PubKey, string, error) {
+	var pubKey cryptotypes.PubKey
+
+	var bech32 string
+	if err := json.Unmarshal(bz, &bech32); err == nil {
+		pubKey, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeAccPub, bech32)
+		return pubKey, pubKeyEncodingBech32, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bz, &fields); err != nil {
+		return nil, pubKeyEncodingAny, fmt.Errorf("pubkey is neither an object nor a string")
+	}
+
+	if _, ok := fields["@type"]; ok {
+		if err := cdc.UnmarshalInterfaceJSON(bz, &pubKey); err != nil {
+			return nil, pubKeyEncodingAny, err
+		}
+		return pubKey, pubKeyEncodingAny, nil
+	}
+
+	pubKey, err := aminoPubKeyJSON(bz)
+	return pubKey, pubKeyEncodingAminoJSON, err
+}
+
+// aminoMultisigJSON is the amino JSON of a legacy multisig pubkey.
+type aminoMultisigJSON struct {
+	Type  string `json:"type"`
+	Value struct {
+		Threshold uint32            `json:"threshold,string"`
+		PubKeys   []json.RawMessage `json:"pubkeys"`
+	} `json:"value"`
+}
+
+// aminoPubKeyJSON decodes an amino JSON pubkey. The multisig ones are decoded
+// here, the amino codec loses their pubkeys.
+func aminoPubKeyJSON(bz []byte) (cryptotypes.PubKey, error) {
+	var multisig aminoMultisigJSON
+	if err := json.Unmarshal(bz, &multisig); err != nil || multisig.Type != kmultisig.PubKeyAminoRoute {
+		var pubKey cryptotypes.PubKey
+		if err := legacy.Cdc.UnmarshalJSON(bz, &pubKey); err != nil {
+			return nil, err
+		}
+		return pubKey, nil
+	}
+
+	pubKey := &kmultisig.LegacyAminoPubKey{Threshold: multisig.Value.Threshold}
+	for i, pubKeyBz := range multisig.Value.PubKeys {
+		key, err := aminoPubKeyJSON(pubKeyBz)
+		if err != nil {
+			return nil, errors.Wrapf(err, "multisig pubkey %d", i)
+		}
+
+		any, err := codectypes.NewAnyWithValue(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "multisig pubkey %d", i)
+		}
+		pubKey.PubKeys = append(pubKey.PubKeys, any)
+	}
+
+	return pubKey, nil
+}
+
+// checkAccountPubKey checks the key sizes of pubKey, the threshold of a
+// multisig and that the pubKey has the address of the account.
+func checkAccountPubKey(pubKey cryptotypes.PubKey, address string) error {
+	if err := checkPubKeySize(pubKey); err != nil {
+		return err
+	}
+
+	accAddr, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return errors.Wrap(err, "invalid account address")
+	}
+	if !bytes.Equal(pubKey.Address(), accAddr) {
+		return fmt.Errorf("pubkey of address %s", sdk.AccAddress(pubKey.Address()))
+	}
+
+	return nil
+}
+
+func checkPubKeySize(pubKey cryptotypes.PubKey) error {
+	switch pk := pubKey.(type) {
+	case *secp256k1.PubKey:
+		if len(pk.Key) != secp256k1.PubKeySize {
+			return fmt.Errorf("secp256k1 pubkey of %d bytes instead of %d", len(pk.Key), secp256k1.PubKeySize)
+		}
+	case *ed25519.PubKey:
+		if len(pk.Key) != ed25519.PubKeySize {
+			return fmt.Errorf("ed25519 pubkey of %d bytes instead of %d", len(pk.Key), ed25519.PubKeySize)
+		}
+	case *kmultisig.LegacyAminoPubKey:
+		if pk.Threshold == 0 || int(pk.Threshold) > len(pk.PubKeys) {
+			return fmt.Errorf("multisig threshold %d of %d pubkeys", pk.Threshold, len(pk.PubKeys))
+		}
+		for i, key := range pk.GetPubKeys() {
+			if key == nil {
+				return fmt.Errorf("multisig pubkey %d is unknown", i)
+			}
+			if err := checkPubKeySize(key); err != nil {
+				return errors.Wrapf(err, "multisig pubkey %d", i)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported account pubkey type %T", pubKey)
+	}
+
+	return nil
+}
+
+// pubKeyAnyJSON returns the proto Any JSON of pubKey, decoded for the account
+// JSON.
+func pubKeyAnyJSON(cdc codec.JSONMarshaler, pubKey cryptotypes.PubKey) (interface{}, error) {
+	any, err := codectypes.NewAnyWithValue(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bz, err := cdc.MarshalJSON(any)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	var value interface{}
+	err = dec.Decode(&value)
+	return value, err
+}
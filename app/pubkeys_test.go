@@ -0,0 +1,167 @@
+package gaia
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/codec/legacy"
+	kmultisig "github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+// pubKeysGenesis returns a test auth genesis with a legacy amino multisig, a
+// secp256k1 proto Any, a bech32 pubkey, a corrupted pubkey, a pubkey of
+// another address and a vesting account with an amino pubkey, and their
+// addresses in that order.
+func pubKeysGenesis(t *testing.T) (types.AppMap, []string) {
+	keys := make([]cryptotypes.PubKey, 4)
+	for i := range keys {
+		keys[i] = secp256k1.GenPrivKeyFromSecret([]byte(fmt.Sprintf("pubkey %d", i))).PubKey()
+	}
+	multisig := kmultisig.NewLegacyAminoPubKey(2, keys[:3])
+
+	aminoJSON := func(pk cryptotypes.PubKey) json.RawMessage {
+		bz, err := legacy.Cdc.MarshalJSON(pk)
+		require.NoError(t, err)
+		return bz
+	}
+	bech32, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeAccPub, keys[1])
+	require.NoError(t, err)
+	bech32JSON, err := json.Marshal(bech32)
+	require.NoError(t, err)
+	anyJSON := fmt.Sprintf(`{"@type": "/cosmos.crypto.secp256k1.PubKey", "key": %q}`, base64.StdEncoding.EncodeToString(keys[0].Bytes()))
+	corruptedJSON := fmt.Sprintf(`{"@type": "/cosmos.crypto.secp256k1.PubKey", "key": %q}`, base64.StdEncoding.EncodeToString(keys[2].Bytes()[:20]))
+
+	addresses := []string{
+		sdk.AccAddress(multisig.Address()).String(),
+		sdk.AccAddress(keys[0].Address()).String(),
+		sdk.AccAddress(keys[1].Address()).String(),
+		sdk.AccAddress(keys[2].Address()).String(),
+		sdk.AccAddress(keys[3].Address()).String(),
+		sdk.AccAddress(keys[2].Address()).String(),
+	}
+	baseAccount := func(i int, pubKey string) string {
+		return fmt.Sprintf(`{"@type": "/cosmos.auth.v1beta1.BaseAccount", "address": %q, "pub_key": %s, "account_number": "%d", "sequence": "%d"}`,
+			addresses[i], pubKey, 100+i, 7*i)
+	}
+	accounts := []string{
+		baseAccount(0, string(aminoJSON(multisig))),
+		baseAccount(1, anyJSON),
+		baseAccount(2, string(bech32JSON)),
+		baseAccount(3, corruptedJSON),
+		baseAccount(4, anyJSON),
+		fmt.Sprintf(`{"@type": "/cosmos.vesting.v1beta1.ContinuousVestingAccount", "base_vesting_account": {"base_account": {"address": %q, "pub_key": %s, "account_number": "105", "sequence": "35"}, "original_vesting": [], "delegated_free": [], "delegated_vesting": [], "end_time": "2000"}, "start_time": "1000"}`,
+			addresses[5], aminoJSON(keys[2])),
+		`{"@type": "/cosmos.auth.v1beta1.BaseAccount", "address": "` + genesisbuilder.New().Address("no pubkey").String() + `", "pub_key": null, "account_number": "106", "sequence": "0"}`,
+	}
+	var accountsJSON json.RawMessage
+	for i, account := range accounts {
+		if i > 0 {
+			accountsJSON = append(accountsJSON, ',')
+		}
+		accountsJSON = append(accountsJSON, account...)
+	}
+
+	return types.AppMap{
+		auth.ModuleName: json.RawMessage(`{"params": {"max_memo_characters": "256"}, "accounts": [` + string(accountsJSON) + `]}`),
+	}, addresses
+}
+
+func TestNormalizePubKeys(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	for _, clear := range []bool{false, true} {
+		state, addresses := pubKeysGenesis(t)
+
+		pubKeys, err := normalizePubKeys(cdc, state, clear)
+		require.NoError(t, err)
+		require.Len(t, pubKeys, 5)
+		require.Equal(t, accountPubKey{Address: addresses[0], Encoding: pubKeyEncodingAminoJSON, Type: "/cosmos.crypto.multisig.LegacyAminoPubKey"}, pubKeys[0])
+		require.Equal(t, accountPubKey{Address: addresses[2], Encoding: pubKeyEncodingBech32, Type: "/cosmos.crypto.secp256k1.PubKey"}, pubKeys[1])
+		require.Equal(t, accountPubKey{Address: addresses[3], Encoding: pubKeyEncodingAny, Error: "secp256k1 pubkey of 20 bytes instead of 33", Cleared: clear}, pubKeys[2])
+		require.Equal(t, addresses[4], pubKeys[3].Address)
+		require.Regexp(t, "^pubkey of address cosmos1", pubKeys[3].Error)
+		require.Equal(t, clear, pubKeys[3].Cleared)
+		require.Equal(t, accountPubKey{Address: addresses[5], Encoding: pubKeyEncodingAminoJSON, Type: "/cosmos.crypto.secp256k1.PubKey"}, pubKeys[4])
+
+		// the normalized genesis decodes with the account numbers and
+		// sequences of the source
+		var authGenesis auth.GenesisState
+		require.NoError(t, cdc.UnmarshalJSON(state[auth.ModuleName], &authGenesis))
+		accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+		require.NoError(t, err)
+		require.Len(t, accounts, 7)
+		for i, account := range accounts {
+			require.Equal(t, uint64(100+i), account.GetAccountNumber())
+			if i < 6 {
+				require.Equal(t, addresses[i], account.GetAddress().String())
+				require.Equal(t, uint64(7*i), account.GetSequence())
+			}
+
+			switch i {
+			case 3, 4:
+				require.Equal(t, clear, account.GetPubKey() == nil, i)
+			case 6:
+				require.Nil(t, account.GetPubKey())
+			default:
+				require.Equal(t, addresses[i], sdk.AccAddress(account.GetPubKey().Address()).String())
+			}
+		}
+		multisig, ok := accounts[0].GetPubKey().(*kmultisig.LegacyAminoPubKey)
+		require.True(t, ok)
+		require.Equal(t, uint32(2), multisig.Threshold)
+		require.Len(t, multisig.PubKeys, 3)
+
+		// normalizing again finds the invalid pubkeys left only
+		pubKeys, err = normalizePubKeys(cdc, state, clear)
+		require.NoError(t, err)
+		if clear {
+			require.Empty(t, pubKeys)
+		} else {
+			require.Len(t, pubKeys, 2)
+		}
+	}
+}
+
+func TestCheckAccountPubKey(t *testing.T) {
+	keys := []cryptotypes.PubKey{
+		secp256k1.GenPrivKeyFromSecret([]byte("0")).PubKey(),
+		secp256k1.GenPrivKeyFromSecret([]byte("1")).PubKey(),
+	}
+	multisig := kmultisig.NewLegacyAminoPubKey(2, keys)
+	require.NoError(t, checkAccountPubKey(multisig, sdk.AccAddress(multisig.Address()).String()))
+
+	multisig.Threshold = 3
+	require.EqualError(t, checkAccountPubKey(multisig, sdk.AccAddress(multisig.Address()).String()), "multisig threshold 3 of 2 pubkeys")
+
+	short := kmultisig.NewLegacyAminoPubKey(1, []cryptotypes.PubKey{keys[0], &secp256k1.PubKey{Key: []byte{2, 1}}})
+	require.EqualError(t, checkAccountPubKey(short, ""), "multisig pubkey 1: secp256k1 pubkey of 2 bytes instead of 33")
+
+	require.EqualError(t, checkAccountPubKey(keys[0], sdk.AccAddress(keys[1].Address()).String()), "pubkey of address "+sdk.AccAddress(keys[0].Address()).String())
+}
+
+func TestMigrateNormalizePubKeys(t *testing.T) {
+	path := filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json")
+	expected, err := executeMigrate(t, path, "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+
+	// the migrated pubkeys of a valid source are already proto Any
+	reportPath := filepath.Join(t.TempDir(), "pubkeys.json")
+	out, err := executeMigrate(t, path, "--chain-id", "cosmoshub-4", "--"+flagNormalizePubKeys, "--"+flagPubKeyReport, reportPath)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(out))
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(bz))
+}
@@ -0,0 +1,130 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagSourceHaltHeight = "source-halt-height"
+	flagSourceHaltTime   = "source-halt-time"
+	flagUpgradeInfo      = "upgrade-info"
+)
+
+// relativeBases are the values of the source chain that the relative
+// --initial-height and --genesis-time values are resolved against.
+type relativeBases struct {
+	// HaltHeight is the --source-halt-height, 0 when not given.
+	HaltHeight int64
+	// UpgradeInfo is the path of the upgrade-info.json the upgrade module
+	// writes when it halts the chain, empty when not given.
+	UpgradeInfo string
+	// HaltTime is the --source-halt-time, zero when not given.
+	HaltTime time.Time
+	// GenesisTime is the genesis time of the source genesis.
+	GenesisTime time.Time
+}
+
+// haltHeight returns the halt height of the source chain, and where it is
+// from. The --source-halt-height and the --upgrade-info height must agree.
+func (b relativeBases) haltHeight() (int64, string, error) {
+	var height int64
+	var source string
+
+	if b.UpgradeInfo != "" {
+		bz, err := ioutil.ReadFile(b.UpgradeInfo)
+		if err != nil {
+			return 0, "", errors.Wrapf(err, "failed to read --%s", flagUpgradeInfo)
+		}
+
+		var info storetypes.UpgradeInfo
+		if err := json.Unmarshal(bz, &info); err != nil {
+			return 0, "", errors.Wrapf(err, "invalid --%s %s", flagUpgradeInfo, b.UpgradeInfo)
+		}
+		if info.Height <= 0 {
+			return 0, "", fmt.Errorf("--%s %s has no height", flagUpgradeInfo, b.UpgradeInfo)
+		}
+
+		height = info.Height
+		source = fmt.Sprintf("the halt height %d of upgrade %q in --%s %s", info.Height, info.Name, flagUpgradeInfo, b.UpgradeInfo)
+	}
+
+	if b.HaltHeight > 0 {
+		if height != 0 && height != b.HaltHeight {
+			return 0, "", fmt.Errorf("--%s %d conflicts with the height %d of --%s %s", flagSourceHaltHeight, b.HaltHeight, height, flagUpgradeInfo, b.UpgradeInfo)
+		}
+		if source == "" {
+			height = b.HaltHeight
+			source = fmt.Sprintf("the --%s %d", flagSourceHaltHeight, b.HaltHeight)
+		}
+	}
+
+	if height == 0 {
+		return 0, "", fmt.Errorf("neither --%s nor --%s gives the height it is relative to", flagSourceHaltHeight, flagUpgradeInfo)
+	}
+
+	return height, source, nil
+}
+
+// resolveInitialHeight returns the height of the --initial-height value, a
+// height or +N blocks after the halt height of the source chain. For a
+// relative value it also describes how it was resolved.
+func resolveInitialHeight(value string, bases relativeBases) (int64, string, error) {
+	if !strings.HasPrefix(value, "+") {
+		height, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || height < 0 {
+			return 0, "", fmt.Errorf("invalid --%s %s, expected a height or +N blocks after the source halt height", flagInitialHeight, value)
+		}
+		return height, "", nil
+	}
+
+	blocks, err := strconv.ParseInt(value[1:], 10, 64)
+	if err != nil || blocks < 1 {
+		return 0, "", fmt.Errorf("invalid --%s %s, expected a height or +N blocks after the source halt height", flagInitialHeight, value)
+	}
+
+	base, source, err := bases.haltHeight()
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "--%s %s is relative", flagInitialHeight, value)
+	}
+
+	height := base + blocks
+	return height, fmt.Sprintf("--%s %s resolved to %d, %d blocks after %s", flagInitialHeight, value, height, blocks, source), nil
+}
+
+// resolveGenesisTime returns the time of the --genesis-time value, an RFC 3339
+// time or +duration after the --source-halt-time, or else after the genesis
+// time of the source genesis. For a relative value it also describes how it
+// was resolved.
+func resolveGenesisTime(value string, bases relativeBases) (time.Time, string, error) {
+	if !strings.HasPrefix(value, "+") {
+		var t time.Time
+		if err := t.UnmarshalText([]byte(value)); err != nil {
+			return time.Time{}, "", errors.Wrap(err, "failed to unmarshal genesis time")
+		}
+		return t, "", nil
+	}
+
+	d, err := time.ParseDuration(value[1:])
+	if err != nil || d <= 0 {
+		return time.Time{}, "", fmt.Errorf("invalid --%s %s, expected a time or +duration after the source halt time, e.g. +45m", flagGenesisTime, value)
+	}
+
+	base, source := bases.HaltTime, fmt.Sprintf("the --%s %s", flagSourceHaltTime, bases.HaltTime.Format(time.RFC3339Nano))
+	if base.IsZero() {
+		base, source = bases.GenesisTime, fmt.Sprintf("the source genesis time %s", bases.GenesisTime.Format(time.RFC3339Nano))
+	}
+	if base.IsZero() {
+		return time.Time{}, "", fmt.Errorf("--%s %s is relative: neither --%s nor the source genesis gives the time it is relative to", flagGenesisTime, value, flagSourceHaltTime)
+	}
+
+	t := base.Add(d).UTC()
+	return t, fmt.Sprintf("--%s %s resolved to %s, %s after %s", flagGenesisTime, value, t.Format(time.RFC3339Nano), d, source), nil
+}
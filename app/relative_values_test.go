@@ -0,0 +1,112 @@
+package gaia
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestResolveInitialHeight(t *testing.T) {
+	upgradeInfo := filepath.Join(t.TempDir(), "upgrade-info.json")
+	require.NoError(t, ioutil.WriteFile(upgradeInfo, []byte(`{"name":"v5-Delta","height":6910000}`), 0600))
+
+	for _, tc := range []struct {
+		name     string
+		value    string
+		bases    relativeBases
+		height   int64
+		resolved string
+		err      string
+	}{
+		{"absolute", "5200791", relativeBases{}, 5200791, "", ""},
+		{"absolute ignores the bases", "42", relativeBases{HaltHeight: 100}, 42, "", ""},
+		{"halt height", "+1", relativeBases{HaltHeight: 6910000}, 6910001, "--initial-height +1 resolved to 6910001, 1 blocks after the --source-halt-height 6910000", ""},
+		{"upgrade info", "+1", relativeBases{UpgradeInfo: upgradeInfo}, 6910001, `--initial-height +1 resolved to 6910001, 1 blocks after the halt height 6910000 of upgrade "v5-Delta" in --upgrade-info ` + upgradeInfo, ""},
+		{"agreeing bases", "+10", relativeBases{HaltHeight: 6910000, UpgradeInfo: upgradeInfo}, 6910010, `--initial-height +10 resolved to 6910010, 10 blocks after the halt height 6910000 of upgrade "v5-Delta" in --upgrade-info ` + upgradeInfo, ""},
+		{"conflicting bases", "+1", relativeBases{HaltHeight: 6909999, UpgradeInfo: upgradeInfo}, 0, "", "--initial-height +1 is relative: --source-halt-height 6909999 conflicts with the height 6910000 of --upgrade-info " + upgradeInfo},
+		{"no base", "+1", relativeBases{}, 0, "", "--initial-height +1 is relative: neither --source-halt-height nor --upgrade-info gives the height it is relative to"},
+		{"zero blocks", "+0", relativeBases{HaltHeight: 100}, 0, "", "invalid --initial-height +0, expected a height or +N blocks after the source halt height"},
+		{"negative", "-1", relativeBases{HaltHeight: 100}, 0, "", "invalid --initial-height -1, expected a height or +N blocks after the source halt height"},
+		{"not a number", "halt+1", relativeBases{HaltHeight: 100}, 0, "", "invalid --initial-height halt+1, expected a height or +N blocks after the source halt height"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			height, resolved, err := resolveInitialHeight(tc.value, tc.bases)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.height, height)
+			require.Equal(t, tc.resolved, resolved)
+		})
+	}
+}
+
+func TestResolveGenesisTime(t *testing.T) {
+	sourceTime := time.Date(2019, 12, 11, 16, 11, 34, 0, time.UTC)
+	haltTime := time.Date(2021, 2, 18, 6, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name     string
+		value    string
+		bases    relativeBases
+		time     time.Time
+		resolved string
+		err      string
+	}{
+		{"absolute", "2021-02-18T17:00:00Z", relativeBases{HaltTime: haltTime}, time.Date(2021, 2, 18, 17, 0, 0, 0, time.UTC), "", ""},
+		{"halt time", "+60m", relativeBases{HaltTime: haltTime, GenesisTime: sourceTime}, haltTime.Add(time.Hour), "--genesis-time +60m resolved to 2021-02-18T07:00:00Z, 1h0m0s after the --source-halt-time 2021-02-18T06:00:00Z", ""},
+		{"source genesis time", "+45m", relativeBases{GenesisTime: sourceTime}, sourceTime.Add(45 * time.Minute), "--genesis-time +45m resolved to 2019-12-11T16:56:34Z, 45m0s after the source genesis time 2019-12-11T16:11:34Z", ""},
+		{"compound duration", "+1h30m", relativeBases{HaltTime: haltTime}, haltTime.Add(90 * time.Minute), "--genesis-time +1h30m resolved to 2021-02-18T07:30:00Z, 1h30m0s after the --source-halt-time 2021-02-18T06:00:00Z", ""},
+		{"no base", "+45m", relativeBases{}, time.Time{}, "", "--genesis-time +45m is relative: neither --source-halt-time nor the source genesis gives the time it is relative to"},
+		{"no unit", "+45", relativeBases{GenesisTime: sourceTime}, time.Time{}, "", "invalid --genesis-time +45, expected a time or +duration after the source halt time, e.g. +45m"},
+		{"not a time", "tomorrow", relativeBases{GenesisTime: sourceTime}, time.Time{}, "", `failed to unmarshal genesis time: parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			genesisTime, resolved, err := resolveGenesisTime(tc.value, tc.bases)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, tc.time.Equal(genesisTime), genesisTime)
+			require.Equal(t, tc.resolved, resolved)
+		})
+	}
+}
+
+func TestMigrateRelativeValues(t *testing.T) {
+	dir := t.TempDir()
+	upgradeInfo := filepath.Join(dir, "upgrade-info.json")
+	require.NoError(t, ioutil.WriteFile(upgradeInfo, []byte(`{"name":"v5-Delta","height":6910000}`), 0600))
+	manifestPath := filepath.Join(dir, "manifest.json")
+
+	var stderr bytes.Buffer
+	out, err := executeMigrateTo(t, &stderr, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4",
+		"--initial-height", "+1", "--upgrade-info", upgradeInfo,
+		"--genesis-time", "+60m", "--source-halt-time", "2021-02-18T06:00:00Z", "--manifest", manifestPath)
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "==> --genesis-time +60m resolved to 2021-02-18T07:00:00Z, 1h0m0s after the --source-halt-time 2021-02-18T06:00:00Z\n")
+	require.Contains(t, stderr.String(), "==> --initial-height +1 resolved to 6910001, 1 blocks after the halt height 6910000 of upgrade \"v5-Delta\" in --upgrade-info "+upgradeInfo+"\n")
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	require.Equal(t, int64(6910001), genDoc.InitialHeight)
+	require.Equal(t, "2021-02-18T07:00:00Z", genDoc.GenesisTime.Format(time.RFC3339))
+
+	// the manifest records the resolved values, not their bases
+	manifest, err := loadMigrationManifest(manifestPath)
+	require.NoError(t, err)
+	require.Equal(t, int64(6910001), manifest.InitialHeight)
+	require.Equal(t, []string{"--chain-id=cosmoshub-4", "--genesis-time=2021-02-18T07:00:00Z", "--initial-height=6910001", "--legacy-source=cosmoshub-2", "--no-prop-29=true"}, manifest.MigrateArgs)
+
+	_, err = executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29", "--initial-height", "+1")
+	require.EqualError(t, err, "--initial-height +1 is relative: neither --source-halt-height nor --upgrade-info gives the height it is relative to")
+}
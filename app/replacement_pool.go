@@ -0,0 +1,246 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const flagReplacementReport = "replacement-keys-report"
+
+// Selection rules of a replacementPool.
+const (
+	poolSelectTopPower  = "top-power"
+	poolSelectAllBonded = "all-bonded"
+)
+
+// What a replacementPool does when it has fewer keys than selected validators.
+const (
+	poolExhaustedStop  = "stop"
+	poolExhaustedError = "error"
+)
+
+// replacementPool is a --replacement-cons-keys file assigning a pool of
+// consensus keys to the validators selected by a rule, instead of listing the
+// key of every validator.
+type replacementPool struct {
+	// Pool are the bech32 consensus public keys, assigned in their order.
+	Pool []string `json:"pool"`
+	// Select is top-power, the validators with the most power up to the size
+	// of the pool, all-bonded, or an array of operator addresses.
+	Select json.RawMessage `json:"select"`
+	// OnExhausted is stop, leaving the keys of the validators without a pool
+	// key unchanged, or error, the default.
+	OnExhausted string `json:"on_exhausted,omitempty"`
+}
+
+// readReplacementKeys reads the --replacement-cons-keys file at path, either
+// an array of replacementConfig or a replacementPool, which is assigned to
+// the validators of the staking genesis of genDoc.
+func readReplacementKeys(cdc codec.JSONMarshaler, path string, genDoc *tmtypes.GenesisDoc) (replacementConfigs, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read replacement keys from file %s", path)
+	}
+
+	return parseReplacementKeys(cdc, bz, genDoc)
+}
+
+// parseReplacementKeys parses the --replacement-cons-keys file bz for the
+// validators of the staking genesis of genDoc. Every entry of an array must
+// replace the key of a distinct validator of the genesis with a distinct
+// key, an entry that would replace nothing fails rather than being ignored.
+func parseReplacementKeys(cdc codec.JSONMarshaler, bz []byte, genDoc *tmtypes.GenesisDoc) (replacementConfigs, error) {
+	var state types.AppMap
+	if err := json.Unmarshal(genDoc.AppState, &state); err != nil {
+		return nil, errors.Wrap(err, "failed to JSON unmarshal genesis state")
+	}
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the staking genesis")
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(bz), []byte("{")) {
+		var replacementKeys replacementConfigs
+		if err := decodeStrictJSON(bz, &replacementKeys); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal replacement keys")
+		}
+		if err := validateReplacementKeys(replacementKeys, stakingGenesis.Validators); err != nil {
+			return nil, err
+		}
+		return replacementKeys, nil
+	}
+
+	var pool replacementPool
+	if err := decodeStrictJSON(bz, &pool); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal replacement key pool")
+	}
+
+	return assignReplacementPool(pool, stakingGenesis.Validators)
+}
+
+// validateReplacementKeys checks every entry of replacementKeys replaces the
+// key of a distinct validator of validators with a distinct consensus key.
+func validateReplacementKeys(replacementKeys replacementConfigs, validators []staking.Validator) error {
+	known := make(map[string]bool, len(validators))
+	for _, val := range validators {
+		known[val.OperatorAddress] = true
+	}
+
+	replaced := make(map[string]int, len(replacementKeys))
+	keys := make(map[string]int, len(replacementKeys))
+	for i, replacement := range replacementKeys {
+		if _, err := sdk.ValAddressFromBech32(replacement.ValidatorAddress); err != nil {
+			return errors.Wrapf(err, "invalid validator address in replacement key %d", i)
+		}
+		if !known[replacement.ValidatorAddress] {
+			return fmt.Errorf("validator %s of replacement key %d is not in the staking genesis", replacement.ValidatorAddress, i)
+		}
+		if j, ok := replaced[replacement.ValidatorAddress]; ok {
+			return fmt.Errorf("validator %s has replacement keys %d and %d", replacement.ValidatorAddress, j, i)
+		}
+		replaced[replacement.ValidatorAddress] = i
+
+		pk, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeConsPub, replacement.ConsensusPubkey)
+		if err != nil {
+			return errors.Wrapf(err, "invalid consensus key in replacement key %d", i)
+		}
+		consAddr := sdk.ConsAddress(pk.Address()).String()
+		if j, ok := keys[consAddr]; ok {
+			return fmt.Errorf("replacement keys %d and %d are the same consensus key", j, i)
+		}
+		keys[consAddr] = i
+	}
+
+	return nil
+}
+
+// assignReplacementPool assigns the keys of pool to the validators it
+// selects, by descending tokens, ties by operator address.
+func assignReplacementPool(pool replacementPool, validators []staking.Validator) (replacementConfigs, error) {
+	if len(pool.Pool) == 0 {
+		return nil, fmt.Errorf("the replacement key pool has no keys")
+	}
+
+	onExhausted := pool.OnExhausted
+	if onExhausted == "" {
+		onExhausted = poolExhaustedError
+	}
+	if onExhausted != poolExhaustedStop && onExhausted != poolExhaustedError {
+		return nil, fmt.Errorf("unknown on_exhausted %q of the replacement key pool, expected %s or %s", onExhausted, poolExhaustedStop, poolExhaustedError)
+	}
+
+	// a pool key already held by a validator would give two validators the
+	// same key
+	held := make(map[string]string)
+	for _, val := range validators {
+		consAddr, err := val.GetConsAddr()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid consensus key of validator %s", val.OperatorAddress)
+		}
+		held[consAddr.String()] = val.OperatorAddress
+	}
+	pooled := make(map[string]bool)
+	for _, key := range pool.Pool {
+		pk, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeConsPub, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pool key %s", key)
+		}
+		consAddr := sdk.ConsAddress(pk.Address()).String()
+		if operator, ok := held[consAddr]; ok {
+			return nil, fmt.Errorf("pool key %s is the consensus key of validator %s", key, operator)
+		}
+		if pooled[consAddr] {
+			return nil, fmt.Errorf("pool key %s is listed twice", key)
+		}
+		pooled[consAddr] = true
+	}
+
+	selected, err := selectPoolValidators(pool, validators)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(selected) > len(pool.Pool) {
+		if onExhausted == poolExhaustedError {
+			return nil, fmt.Errorf("the replacement key pool has %d keys for %d selected validators, set on_exhausted to %s to assign them to the first %d only",
+				len(pool.Pool), len(selected), poolExhaustedStop, len(pool.Pool))
+		}
+		selected = selected[:len(pool.Pool)]
+	}
+
+	replacementKeys := make(replacementConfigs, len(selected))
+	for i, val := range selected {
+		replacementKeys[i] = replacementConfig{
+			Name:             val.Description.Moniker,
+			ValidatorAddress: val.OperatorAddress,
+			ConsensusPubkey:  pool.Pool[i],
+		}
+	}
+
+	return replacementKeys, nil
+}
+
+// selectPoolValidators returns the validators selected by the rule of pool,
+// by descending tokens, ties by operator address.
+func selectPoolValidators(pool replacementPool, validators []staking.Validator) ([]staking.Validator, error) {
+	var candidates []staking.Validator
+
+	var rule string
+	if err := json.Unmarshal(pool.Select, &rule); err == nil {
+		switch rule {
+		case poolSelectTopPower, poolSelectAllBonded:
+			for _, val := range validators {
+				if val.IsBonded() && !val.IsJailed() {
+					candidates = append(candidates, val)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown select %q of the replacement key pool, expected %s, %s or an array of operator addresses", rule, poolSelectTopPower, poolSelectAllBonded)
+		}
+	} else {
+		var operators []string
+		if err := json.Unmarshal(pool.Select, &operators); err != nil || len(operators) == 0 {
+			return nil, fmt.Errorf("the select of the replacement key pool must be %s, %s or an array of operator addresses", poolSelectTopPower, poolSelectAllBonded)
+		}
+
+		byOperator := make(map[string]staking.Validator, len(validators))
+		for _, val := range validators {
+			byOperator[val.OperatorAddress] = val
+		}
+		listed := make(map[string]bool, len(operators))
+		for _, operator := range operators {
+			val, ok := byOperator[operator]
+			if !ok {
+				return nil, fmt.Errorf("selected validator %s of the replacement key pool is not in the staking genesis", operator)
+			}
+			if listed[operator] {
+				return nil, fmt.Errorf("selected validator %s of the replacement key pool is listed twice", operator)
+			}
+			listed[operator] = true
+			candidates = append(candidates, val)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].Tokens.Equal(candidates[j].Tokens) {
+			return candidates[i].Tokens.GT(candidates[j].Tokens)
+		}
+		return candidates[i].OperatorAddress < candidates[j].OperatorAddress
+	})
+
+	if rule == poolSelectTopPower && len(candidates) > len(pool.Pool) {
+		candidates = candidates[:len(pool.Pool)]
+	}
+
+	return candidates, nil
+}
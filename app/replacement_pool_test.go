@@ -0,0 +1,197 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+// poolKey returns the bech32 consensus public key i of a replacement pool.
+func poolKey(t *testing.T, i int) string {
+	pk := ed25519.GenPrivKeyFromSecret([]byte(fmt.Sprintf("pool%d", i))).PubKey()
+	key, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeConsPub, pk)
+	require.NoError(t, err)
+
+	return key
+}
+
+// replacementPoolFixture has validators 1 and 2 tied with the most power and
+// validator 4 jailed.
+func replacementPoolFixture(t *testing.T) (*genesisbuilder.Builder, []staking.Validator) {
+	b := genesisbuilder.New().WithValidatorPowers(10, 30, 30, 20, 40)
+	_, state := buildTestGenesis(t, b)
+
+	var stakingGenesis staking.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	for i, val := range stakingGenesis.Validators {
+		if val.OperatorAddress == b.ValidatorAddress(4).String() {
+			stakingGenesis.Validators[i].Jailed = true
+		}
+	}
+
+	return b, stakingGenesis.Validators
+}
+
+func TestAssignReplacementPool(t *testing.T) {
+	b, validators := replacementPoolFixture(t)
+	valoper := func(i int) string { return b.ValidatorAddress(i).String() }
+
+	// validators 1 and 2 are tied, by operator address
+	tied := []int{1, 2}
+	if valoper(2) < valoper(1) {
+		tied = []int{2, 1}
+	}
+
+	keys := func(n int) []string {
+		var keys []string
+		for i := 0; i < n; i++ {
+			keys = append(keys, poolKey(t, i))
+		}
+		return keys
+	}
+
+	for _, tc := range []struct {
+		name     string
+		pool     replacementPool
+		assigned []int
+		err      string
+	}{
+		{"top power", replacementPool{Pool: keys(3), Select: json.RawMessage(`"top-power"`)}, append(tied, 3), ""},
+		{"top power of more keys than validators", replacementPool{Pool: keys(6), Select: json.RawMessage(`"top-power"`)}, append(tied, 3, 0), ""},
+		{"all bonded", replacementPool{Pool: keys(4), Select: json.RawMessage(`"all-bonded"`)}, append(tied, 3, 0), ""},
+		{
+			"all bonded exhausted",
+			replacementPool{Pool: keys(3), Select: json.RawMessage(`"all-bonded"`)},
+			nil,
+			"the replacement key pool has 3 keys for 4 selected validators, set on_exhausted to stop to assign them to the first 3 only",
+		},
+		{"all bonded stopped", replacementPool{Pool: keys(3), Select: json.RawMessage(`"all-bonded"`), OnExhausted: "stop"}, append(tied, 3), ""},
+		{
+			"operators by power",
+			replacementPool{Pool: keys(3), Select: json.RawMessage(fmt.Sprintf(`[%q, %q, %q]`, valoper(0), valoper(4), valoper(3)))},
+			[]int{4, 3, 0},
+			"",
+		},
+		{
+			"operators exhausted",
+			replacementPool{Pool: keys(1), Select: json.RawMessage(fmt.Sprintf(`[%q, %q]`, valoper(0), valoper(3))), OnExhausted: "error"},
+			nil,
+			"the replacement key pool has 1 keys for 2 selected validators",
+		},
+		{
+			"unknown operator",
+			replacementPool{Pool: keys(1), Select: json.RawMessage(`["cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0"]`)},
+			nil,
+			"selected validator cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0 of the replacement key pool is not in the staking genesis",
+		},
+		{
+			"listed twice",
+			replacementPool{Pool: keys(2), Select: json.RawMessage(fmt.Sprintf(`[%q, %q]`, valoper(0), valoper(0)))},
+			nil,
+			"is listed twice",
+		},
+		{"unknown rule", replacementPool{Pool: keys(1), Select: json.RawMessage(`"top"`)}, nil, `unknown select "top"`},
+		{"no rule", replacementPool{Pool: keys(1)}, nil, "the select of the replacement key pool must be"},
+		{"unknown on exhausted", replacementPool{Pool: keys(1), Select: json.RawMessage(`"top-power"`), OnExhausted: "skip"}, nil, `unknown on_exhausted "skip"`},
+		{"no keys", replacementPool{Select: json.RawMessage(`"top-power"`)}, nil, "the replacement key pool has no keys"},
+		{"duplicate key", replacementPool{Pool: append(keys(2), poolKey(t, 1)), Select: json.RawMessage(`"top-power"`)}, nil, "is listed twice"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			replacementKeys, err := assignReplacementPool(tc.pool, validators)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var assigned []string
+			var expected []string
+			for i, replacement := range replacementKeys {
+				require.Equal(t, tc.pool.Pool[i], replacement.ConsensusPubkey)
+				assigned = append(assigned, replacement.ValidatorAddress)
+			}
+			for _, i := range tc.assigned {
+				expected = append(expected, valoper(i))
+			}
+			require.Equal(t, expected, assigned)
+		})
+	}
+
+	// a pool key held by a validator
+	held, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeConsPub, genesisbuilder.ValidatorConsKey(0).PubKey())
+	require.NoError(t, err)
+	_, err = assignReplacementPool(replacementPool{Pool: []string{held}, Select: json.RawMessage(`"top-power"`)}, validators)
+	require.EqualError(t, err, fmt.Sprintf("pool key %s is the consensus key of validator %s", held, valoper(0)))
+}
+
+func TestReplacementPoolKeys(t *testing.T) {
+	b := genesisbuilder.New().WithValidatorPowers(10, 30, 20)
+	genDoc, _ := buildTestGenesis(t, b)
+
+	path := filepath.Join(t.TempDir(), "pool.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(`{"pool": [%q, %q], "select": "all-bonded", "on_exhausted": "stop"}`,
+		poolKey(t, 0), poolKey(t, 1))), 0600))
+
+	cdc := MakeEncodingConfig().Marshaler
+	replacementKeys, err := readReplacementKeys(cdc, path, genDoc)
+	require.NoError(t, err)
+	require.NoError(t, checkReplacementKeyTypes(replacementKeys, genDoc.ConsensusParams))
+
+	before := append(genDoc.Validators[:0:0], genDoc.Validators...)
+	replaced := replaceConsensusKeys(client.Context{}.WithJSONMarshaler(cdc), replacementKeys, genDoc)
+
+	report := replacedKeys(before, replaced.Validators, replacementKeys)
+	require.Len(t, report, 2)
+	byOperator := make(map[string]keyReplacement)
+	for _, replacement := range report {
+		byOperator[replacement.ValidatorAddress] = replacement
+	}
+	for i, validator := range []int{1, 2} {
+		pk, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeConsPub, poolKey(t, i))
+		require.NoError(t, err)
+
+		replacement := byOperator[b.ValidatorAddress(validator).String()]
+		require.Equal(t, b.ValidatorConsAddress(validator).String(), replacement.OldConsAddress)
+		require.Equal(t, sdk.ConsAddress(pk.Address()).String(), replacement.NewConsAddress)
+	}
+
+	require.NoError(t, SmokeTestGenesis(replaced))
+
+	// a file of explicit replacements is read as before
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(`[{"validator_address": %q, "stargate_consensus_public_key": %q}]`,
+		b.ValidatorAddress(0).String(), poolKey(t, 0))), 0600))
+	replacementKeys, err = readReplacementKeys(cdc, path, genDoc)
+	require.NoError(t, err)
+	require.Equal(t, replacementConfigs{{ValidatorAddress: b.ValidatorAddress(0).String(), ConsensusPubkey: poolKey(t, 0)}}, replacementKeys)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"pool": [], "selection": "top-power"}`), 0600))
+	_, err = readReplacementKeys(cdc, path, genDoc)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), `unknown field "selection"`), err.Error())
+	// no entry of a file of explicit replacements is ignored
+	entry := func(validator string, key int) string {
+		return fmt.Sprintf(`{"validator_address": %q, "stargate_consensus_public_key": %q}`, validator, poolKey(t, key))
+	}
+	for data, expErr := range map[string]string{
+		`[` + entry(b.ValidatorAddress(0).String(), 0) + `, ` + entry(b.ValidatorAddress(0).String(), 1) + `]`:                "has replacement keys 0 and 1",
+		`[` + entry(b.ValidatorAddress(0).String(), 0) + `, ` + entry(b.ValidatorAddress(1).String(), 0) + `]`:                "replacement keys 0 and 1 are the same consensus key",
+		`[` + entry(sdk.ValAddress("validator not listed").String(), 0) + `]`:                                                 "is not in the staking genesis",
+		fmt.Sprintf(`[{"validator_address": %q, "consensus_public_key": %q}]`, b.ValidatorAddress(0).String(), poolKey(t, 0)): `unknown field "consensus_public_key"`,
+		`[null]`: "invalid validator address in replacement key 0",
+	} {
+		_, err = parseReplacementKeys(cdc, []byte(data), genDoc)
+		require.Error(t, err, data)
+		require.Contains(t, err.Error(), expErr)
+	}
+}
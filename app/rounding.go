@@ -0,0 +1,189 @@
+package gaia
+
+import (
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagRoundingDustTo     = "rounding-dust-to"
+	flagRoundingDustReport = "rounding-dust-report"
+)
+
+// roundingDust converts the decimal amounts the migration steps mint to
+// integer amounts, always truncating, and accumulates the fractions lost by
+// step and denom. creditRoundingDust then mints the accumulated dust to a
+// destination, so the supply is what the steps meant to mint to the base
+// unit. The conversions that only rank or report, e.g. the tokens of
+// delegation shares weighing the pruned accounts, are not accounted.
+//
+// A nil *roundingDust truncates without accounting.
+type roundingDust struct {
+	steps map[string]sdk.DecCoins
+}
+
+func newRoundingDust() *roundingDust {
+	return &roundingDust{steps: make(map[string]sdk.DecCoins)}
+}
+
+// TruncateInt returns amount of denom truncated to an integer, accounting the
+// fraction as dust of step.
+func (d *roundingDust) TruncateInt(step, denom string, amount sdk.Dec) sdk.Int {
+	truncated := amount.TruncateInt()
+	if d != nil {
+		if fraction := amount.Sub(truncated.ToDec()); fraction.IsPositive() {
+			d.steps[step] = d.steps[step].Add(sdk.NewDecCoinFromDec(denom, fraction))
+		}
+	}
+
+	return truncated
+}
+
+// TruncateCoins returns coins truncated to integer amounts, accounting the
+// fractions as dust of step.
+func (d *roundingDust) TruncateCoins(step string, coins sdk.DecCoins) sdk.Coins {
+	truncated := sdk.NewCoins()
+	for _, coin := range coins {
+		truncated = truncated.Add(sdk.NewCoin(coin.Denom, d.TruncateInt(step, coin.Denom, coin.Amount)))
+	}
+
+	return truncated
+}
+
+// Total returns the dust of every step.
+func (d *roundingDust) Total() sdk.DecCoins {
+	total := sdk.NewDecCoins()
+	if d == nil {
+		return total
+	}
+	for _, dust := range d.steps {
+		total = total.Add(dust...)
+	}
+
+	return total
+}
+
+// stepDust is the dust of a step in the rounding dust report.
+type stepDust struct {
+	Step string       `json:"step"`
+	Dust sdk.DecCoins `json:"dust"`
+}
+
+// roundingDustReport lists the dust of the steps, sorted by name, and what
+// creditRoundingDust minted for it. Residue is the dust below the base unit
+// left out of a credit to an account, the community pool holds decimal
+// amounts.
+type roundingDustReport struct {
+	Steps       []stepDust   `json:"steps"`
+	Total       sdk.DecCoins `json:"total"`
+	Destination string       `json:"destination"`
+	Credited    sdk.Coins    `json:"credited"`
+	Residue     sdk.DecCoins `json:"residue,omitempty"`
+}
+
+// creditRoundingDust mints the dust accumulated by d to destination, a bech32
+// account address or blockedCommunityPool, and adds what it minted to the
+// bank supply. The community pool is credited the exact dust and the
+// distribution module account the integer amounts it then accounts for
+// beyond its balance, an account the truncated dust.
+func creditRoundingDust(cdc codec.JSONMarshaler, state types.AppMap, d *roundingDust, destination string) (roundingDustReport, error) {
+	report := roundingDustReport{Total: d.Total(), Destination: destination, Credited: sdk.NewCoins()}
+	for step, dust := range d.steps {
+		report.Steps = append(report.Steps, stepDust{Step: step, Dust: dust})
+	}
+	sort.Slice(report.Steps, func(i, j int) bool { return report.Steps[i].Step < report.Steps[j].Step })
+
+	if report.Total.IsZero() {
+		return report, nil
+	}
+
+	var (
+		authGenesis         auth.GenesisState
+		bankGenesis         bank.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", bank.ModuleName)
+	}
+
+	if destination == blockedCommunityPool {
+		if err := cdc.UnmarshalJSON(state[distribution.ModuleName], &distributionGenesis); err != nil {
+			return report, errors.Wrapf(err, "failed to unmarshal %s genesis", distribution.ModuleName)
+		}
+		accounted := distributionGenesis.FeePool.CommunityPool
+		for _, rewards := range distributionGenesis.OutstandingRewards {
+			accounted = accounted.Add(rewards.OutstandingRewards...)
+		}
+		before, _ := accounted.TruncateDecimal()
+		after, _ := accounted.Add(report.Total...).TruncateDecimal()
+
+		distributionGenesis.FeePool.CommunityPool = distributionGenesis.FeePool.CommunityPool.Add(report.Total...)
+		state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+		report.Credited = after.Sub(before)
+		destination = auth.NewModuleAddress(distribution.ModuleName).String()
+	} else {
+		addr, err := sdk.AccAddressFromBech32(destination)
+		if err != nil {
+			return report, errors.Wrap(err, "invalid rounding dust destination")
+		}
+		destination = addr.String()
+
+		var residue sdk.DecCoins
+		report.Credited, residue = report.Total.TruncateDecimal()
+		if !residue.IsZero() {
+			report.Residue = residue
+		}
+
+		if err := cdc.UnmarshalJSON(state[auth.ModuleName], &authGenesis); err != nil {
+			return report, errors.Wrapf(err, "failed to unmarshal %s genesis", auth.ModuleName)
+		}
+		accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+		if err != nil {
+			return report, err
+		}
+
+		hasDestination := false
+		var nextAccountNumber uint64
+		for _, acc := range accounts {
+			hasDestination = hasDestination || acc.GetAddress().Equals(addr)
+			if acc.GetAccountNumber() >= nextAccountNumber {
+				nextAccountNumber = acc.GetAccountNumber() + 1
+			}
+		}
+
+		if !hasDestination && !report.Credited.IsZero() {
+			accounts = append(accounts, auth.NewBaseAccount(addr, nil, nextAccountNumber, 0))
+			if authGenesis.Accounts, err = auth.PackAccounts(accounts); err != nil {
+				return report, err
+			}
+			state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+		}
+	}
+
+	if report.Credited.IsZero() {
+		return report, nil
+	}
+
+	credited := false
+	for i, balance := range bankGenesis.Balances {
+		if balance.Address == destination {
+			bankGenesis.Balances[i].Coins = balance.Coins.Add(report.Credited...)
+			credited = true
+		}
+	}
+	if !credited {
+		bankGenesis.Balances = bank.SanitizeGenesisBalances(append(bankGenesis.Balances, bank.Balance{Address: destination, Coins: report.Credited}))
+	}
+	bankGenesis.Supply = bankGenesis.Supply.Add(report.Credited...)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	return report, nil
+}
@@ -0,0 +1,146 @@
+package gaia
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundingDust(t *testing.T) {
+	dust := newRoundingDust()
+	require.Equal(t, sdk.NewInt(2), dust.TruncateInt("a", "uatom", sdk.MustNewDecFromStr("2.75")))
+	require.Equal(t, sdk.NewInt(3), dust.TruncateInt("a", "uatom", sdk.MustNewDecFromStr("3")))
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("uatom", 1), sdk.NewInt64Coin("ufork", 7)),
+		dust.TruncateCoins("b", sdk.NewDecCoins(sdk.NewDecCoinFromDec("uatom", sdk.MustNewDecFromStr("1.5")), sdk.NewDecCoinFromDec("ufork", sdk.MustNewDecFromStr("7.000000000000000001")))))
+
+	require.Equal(t, sdk.NewDecCoins(sdk.NewDecCoinFromDec("uatom", sdk.MustNewDecFromStr("1.25")), sdk.NewDecCoinFromDec("ufork", sdk.MustNewDecFromStr("0.000000000000000001"))), dust.Total())
+
+	var untracked *roundingDust
+	require.Equal(t, sdk.NewInt(2), untracked.TruncateInt("a", "uatom", sdk.MustNewDecFromStr("2.75")))
+	require.True(t, untracked.Total().IsZero())
+}
+
+func TestCreditRoundingDust(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	dust := newRoundingDust()
+	dust.TruncateInt(flagAirdrop, "ufork", sdk.MustNewDecFromStr("0.75"))
+	dust.TruncateInt(flagAirdrop, "ufork", sdk.MustNewDecFromStr("0.5"))
+	dust.TruncateInt("payout", "uatom", sdk.MustNewDecFromStr("0.5"))
+
+	b := testGenesisBuilder()
+	_, state := buildTestGenesis(t, b)
+	destination := b.Address("dust").String()
+	report, err := creditRoundingDust(cdc, state, dust, destination)
+	require.NoError(t, err)
+	require.Equal(t, []stepDust{
+		{Step: flagAirdrop, Dust: sdk.NewDecCoins(sdk.NewDecCoinFromDec("ufork", sdk.MustNewDecFromStr("1.25")))},
+		{Step: "payout", Dust: sdk.NewDecCoins(sdk.NewDecCoinFromDec("uatom", sdk.MustNewDecFromStr("0.5")))},
+	}, report.Steps)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("ufork", 1)), report.Credited)
+	require.Equal(t, sdk.NewDecCoins(sdk.NewDecCoinFromDec("uatom", sdk.MustNewDecFromStr("0.5")), sdk.NewDecCoinFromDec("ufork", sdk.MustNewDecFromStr("0.25"))), report.Residue)
+
+	// the destination account is created
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	require.NoError(t, err)
+	require.Equal(t, destination, accounts[len(accounts)-1].GetAddress().String())
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+
+	_, err = creditRoundingDust(cdc, state, dust, "cosmos1invalid")
+	require.Error(t, err)
+
+	// the summary names the destination of the airdrop dust
+	ratio := sdk.NewDecWithPrec(3, 1)
+	opts := stateChangeOptions{Airdrop: &airdropFormula{Denom: "ufork", Ratio: &ratio}, AirdropSource: "airdrop.json", RoundingDustTo: blockedCommunityPool}
+	require.Equal(t, []string{
+		"--airdrop: mint ufork 0.300000000000000000 per bond denom from airdrop.json, excluding 0 addresses",
+		"--rounding-dust-to: mint the fractions of ufork the airdrop grants round down to community-pool",
+	}, opts.Summary())
+}
+
+// TestRoundingDustConservation airdrops random ratios of random holdings and
+// checks the supply grows by exactly what the ratios meant to mint, to the
+// base unit, whichever the dust destination. A protected holder is granted
+// neither its share nor the dust of it.
+func TestRoundingDustConservation(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 40; i++ {
+		b := genesisbuilder.New().WithValidators(1 + r.Intn(3))
+		for j := 0; j < 1+r.Intn(12); j++ {
+			b.WithAccount(fmt.Sprintf("account %d", j), sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1+r.Int63n(1e12))))
+		}
+		_, state := buildTestGenesis(t, b)
+
+		ratio := sdk.NewDecWithPrec(1+r.Int63n(1e18), 18).MulInt64(1 + r.Int63n(3))
+		destination := blockedCommunityPool
+		if i%2 == 1 {
+			destination = b.Address("dust").String()
+		}
+
+		var bankBefore bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+		var distributionBefore distribution.GenesisState
+		cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionBefore)
+
+		protectedAddr := b.Address("account 0").String()
+		protected, err := newProtectedAddresses("protected.json", []string{protectedAddr}, false)
+		require.NoError(t, err)
+
+		dust := newRoundingDust()
+		airdrop, err := applyAirdrop(cdc, state, airdropFormula{Denom: "ufork", Ratio: &ratio}, protected, dust)
+		require.NoError(t, err)
+		require.Equal(t, []protectedSkip{{Option: flagAirdrop, Address: protectedAddr, Reason: "listed in protected.json"}}, protected.Skips, "case %d", i)
+		report, err := creditRoundingDust(cdc, state, dust, destination)
+		require.NoError(t, err)
+
+		exact := sdk.ZeroDec()
+		for _, grant := range airdrop.Grants {
+			require.NotEqual(t, protectedAddr, grant.Address, "case %d", i)
+			exact = exact.Add(grant.Holding.ToDec().Mul(ratio))
+		}
+		require.Equal(t, exact.Sub(airdrop.Total.Amount.ToDec()), dust.Total().AmountOf("ufork"), "case %d", i)
+
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		minted := bankGenesis.Supply.Sub(bankBefore.Supply)
+		require.Equal(t, exact.TruncateInt(), airdrop.Total.Amount.Add(report.Credited.AmountOf("ufork")), "case %d", i)
+		require.Equal(t, minted.AmountOf("ufork"), airdrop.Total.Amount.Add(report.Credited.AmountOf("ufork")), "case %d", i)
+
+		held := sdk.ZeroInt()
+		for _, balance := range bankGenesis.Balances {
+			held = held.Add(balance.Coins.AmountOf("ufork"))
+			if balance.Address == protectedAddr {
+				require.True(t, balance.Coins.AmountOf("ufork").IsZero(), "case %d", i)
+			}
+		}
+		require.Equal(t, minted.AmountOf("ufork"), held, "case %d", i)
+
+		if destination == blockedCommunityPool {
+			// the community pool holds the exact dust, backed by the
+			// distribution module account
+			var distributionGenesis distribution.GenesisState
+			cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+			require.Equal(t, exact.Sub(airdrop.Total.Amount.ToDec()), distributionGenesis.FeePool.CommunityPool.AmountOf("ufork").Sub(distributionBefore.FeePool.CommunityPool.AmountOf("ufork")), "case %d", i)
+			require.Empty(t, report.Residue)
+
+			audit, err := auditModuleAccounts(cdc, state)
+			require.NoError(t, err)
+			for _, acc := range audit {
+				require.True(t, acc.Balanced(), "case %d: %+v", i, acc)
+			}
+		} else {
+			require.Equal(t, exact.Sub(exact.TruncateInt().ToDec()), report.Residue.AmountOf("ufork"), "case %d", i)
+		}
+		require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state), "case %d", i)
+	}
+}
@@ -0,0 +1,97 @@
+package gaia
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cosmos/cosmos-sdk/x/crisis"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// smokeTestWarnSize is the app state size above which running the smoke test
+// warrants a warning, the in-memory app needs several times the size of the
+// app state.
+const smokeTestWarnSize = 256 << 20
+
+// smokeTestAppOptions skips the crisis module's genesis invariant assertion so
+// SmokeTestGenesis can run and name the invariants itself.
+type smokeTestAppOptions struct{}
+
+func (smokeTestAppOptions) Get(key string) interface{} {
+	if key == crisis.FlagSkipGenesisInvariants {
+		return true
+	}
+
+	return nil
+}
+
+// SmokeTestGenesis starts an in-memory GaiaApp from genDoc: it runs InitChain,
+// a single BeginBlock, EndBlock and Commit cycle, and then every registered
+// invariant. It returns the first failure, naming the module and invariant for
+// broken invariants.
+func SmokeTestGenesis(genDoc *tmtypes.GenesisDoc) (err error) {
+	homeDir, err := ioutil.TempDir("", "gaia-smoke-test")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(homeDir)
+
+	app := NewGaiaApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, map[int64]bool{}, homeDir, 0, MakeEncodingConfig(), smokeTestAppOptions{})
+
+	stage := "InitChain"
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("smoke test %s panicked: %v", stage, r)
+		}
+	}()
+
+	validators := make([]abci.ValidatorUpdate, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = tmtypes.TM2PB.NewValidatorUpdate(val.PubKey, val.Power)
+	}
+
+	consensusParams := genDoc.ConsensusParams
+	if consensusParams == nil {
+		consensusParams = tmtypes.DefaultConsensusParams()
+	}
+
+	initialHeight := genDoc.InitialHeight
+	if initialHeight == 0 {
+		initialHeight = 1
+	}
+
+	app.InitChain(abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(consensusParams),
+		Validators:      validators,
+		AppStateBytes:   genDoc.AppState,
+		InitialHeight:   initialHeight,
+	})
+
+	header := tmproto.Header{ChainID: genDoc.ChainID, Height: initialHeight, Time: genDoc.GenesisTime}
+
+	stage = "BeginBlock"
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+
+	stage = "EndBlock"
+	app.EndBlock(abci.RequestEndBlock{Height: initialHeight})
+
+	stage = "Commit"
+	app.Commit()
+
+	stage = "invariants"
+	ctx := app.NewContext(true, header)
+	for _, route := range app.CrisisKeeper.Routes() {
+		if res, broken := route.Invar(ctx); broken {
+			return fmt.Errorf("invariant %s/%s is broken: %s", route.ModuleName, route.Route, res)
+		}
+	}
+
+	return nil
+}
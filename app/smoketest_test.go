@@ -0,0 +1,47 @@
+package gaia
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmokeTestGenesis(t *testing.T) {
+	genDoc, err := testGenesisBuilder().Build()
+	require.NoError(t, err)
+	require.NoError(t, SmokeTestGenesis(genDoc))
+}
+
+func TestSmokeTestGenesisFailures(t *testing.T) {
+	cdc := MakeEncodingConfig().Marshaler
+
+	t.Run("broken invariant", func(t *testing.T) {
+		genDoc, state := buildTestGenesis(t, testGenesisBuilder())
+
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		bankGenesis.Supply = bankGenesis.Supply.Add(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1))
+		state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+		var err error
+		genDoc.AppState, err = json.Marshal(state)
+		require.NoError(t, err)
+
+		err = SmokeTestGenesis(genDoc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invariant bank/total-supply is broken")
+	})
+
+	t.Run("InitChain panic", func(t *testing.T) {
+		genDoc, _ := buildTestGenesis(t, testGenesisBuilder())
+		genDoc.Validators = genDoc.Validators[1:]
+
+		err := SmokeTestGenesis(genDoc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "smoke test InitChain panicked")
+	})
+}
@@ -0,0 +1,205 @@
+package gaia
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	"github.com/pkg/errors"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// stageCache stores the app state migrated by the SDK migration stages of
+// migrate in a directory, so a run migrating the same source with the same
+// gaia build resumes after the last cached stage. The later stages depend on
+// many flags and files and always run.
+type stageCache struct {
+	dir string
+}
+
+// stageCacheEntry is the file of a cached stage output, State hashing to
+// SHA256 and Chain the chain hash of its stage link. Report is what the stage
+// reported besides its output, replayed when the entry is reused.
+type stageCacheEntry struct {
+	Stage       string          `json:"stage"`
+	GaiaVersion string          `json:"gaia_version"`
+	SHA256      string          `json:"sha256"`
+	Chain       string          `json:"chain"`
+	State       json.RawMessage `json:"state"`
+	Report      json.RawMessage `json:"report,omitempty"`
+}
+
+func newStageCache(dir string) (*stageCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create cache directory")
+	}
+
+	return &stageCache{dir: dir}, nil
+}
+
+// stageCacheVersion identifies the gaia build in the cache keys and entries,
+// so a different build never reuses the outputs of another.
+func stageCacheVersion() string {
+	info := version.NewInfo()
+	return info.Version + "-" + info.GitCommit
+}
+
+// stageCacheSourceKey returns the cache key of the source genesis, the input
+// of the first stage.
+func stageCacheSourceKey(jsonBlob []byte) string {
+	sum := sha256.Sum256(jsonBlob)
+	return hex.EncodeToString(sum[:])
+}
+
+// stageCacheKey returns the cache key of the output of stage run on the input
+// keyed inputKey with options.
+func stageCacheKey(stage, inputKey string, options ...string) string {
+	hash := sha256.New()
+	for _, part := range append([]string{stageCacheVersion(), stage, inputKey}, options...) {
+		// length prefixed so the parts cannot run into each other
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *stageCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// read returns the entry keyed key, false if there is none. An entry of
+// another gaia build or not matching its hash is an error.
+func (c *stageCache) read(key string) (stageCacheEntry, bool, error) {
+	var entry stageCacheEntry
+	bz, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+
+	if err := json.Unmarshal(bz, &entry); err != nil {
+		return entry, false, errors.Wrapf(err, "invalid cache entry %s", c.path(key))
+	}
+
+	if entry.GaiaVersion != stageCacheVersion() {
+		return entry, false, fmt.Errorf("cache entry %s is of gaia %s", c.path(key), entry.GaiaVersion)
+	}
+
+	if sum := sha256.Sum256(entry.State); hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return entry, false, fmt.Errorf("cache entry %s does not match its SHA-256", c.path(key))
+	}
+
+	return entry, true, nil
+}
+
+// Get returns the cached output keyed key, false if there is none. An entry
+// of another gaia build or not matching its hash is an error.
+func (c *stageCache) Get(key string) (types.AppMap, bool, error) {
+	entry, ok, err := c.read(key)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+
+	var state types.AppMap
+	if err := json.Unmarshal(entry.State, &state); err != nil {
+		return nil, false, errors.Wrapf(err, "invalid cache entry %s", c.path(key))
+	}
+
+	return state, true, nil
+}
+
+// Report returns the report cached with the output keyed key, nil if it has
+// none.
+func (c *stageCache) Report(key string) (json.RawMessage, error) {
+	entry, ok, err := c.read(key)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	return entry.Report, nil
+}
+
+// Chain returns the stage chain of the cached outputs of stages keyed keys,
+// following source. It stops at the first output missing, invalid or whose
+// link does not chain from the previous one, returning the links before it
+// and a *genesis.StageChainError naming its stage.
+func (c *stageCache) Chain(source genesis.StageLink, stages, keys []string) ([]genesis.StageLink, error) {
+	links := []genesis.StageLink{source}
+	for i, key := range keys {
+		entry, ok, err := c.read(key)
+		switch {
+		case err != nil:
+			return links, &genesis.StageChainError{Index: len(links), Stage: stages[i], Reason: err.Error()}
+		case !ok:
+			return links, &genesis.StageChainError{Index: len(links), Stage: stages[i], Reason: "no cached output"}
+		}
+
+		link := genesis.StageLink{Stage: entry.Stage, SHA256: entry.SHA256, Chain: entry.Chain}
+		if err := genesis.VerifyStageChain(append(links, link)); err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+// Put caches the output of the stage link, the JSON app state stateBz, and
+// the JSON report of the stage, if any, keyed key. The entry is written to a
+// temporary file first so a failed run never leaves a partial entry.
+func (c *stageCache) Put(key string, link genesis.StageLink, stateBz, reportBz []byte) error {
+	bz, err := json.Marshal(stageCacheEntry{
+		Stage:       link.Stage,
+		GaiaVersion: stageCacheVersion(),
+		SHA256:      link.SHA256,
+		Chain:       link.Chain,
+		State:       stateBz,
+		Report:      reportBz,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.path(key), bz)
+}
+
+// writeFileAtomic writes bz to path through a temporary file of its
+// directory renamed to path, so readers never see a partial file.
+func writeFileAtomic(path string, bz []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(bz); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// stageLink returns the link of the stage whose output is state following
+// previous, and the JSON of state it hashes.
+func stageLink(previous genesis.StageLink, stage string, state types.AppMap) (genesis.StageLink, []byte, error) {
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return genesis.StageLink{}, nil, err
+	}
+
+	sum := sha256.Sum256(bz)
+	return genesis.NewStageLink(previous, stage, hex.EncodeToString(sum[:])), bz, nil
+}
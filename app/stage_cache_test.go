@@ -0,0 +1,143 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestMigrateStageCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	migrate := func(chainID string) (*tmtypes.GenesisDoc, string) {
+		var log bytes.Buffer
+		out, err := executeMigrateTo(t, &log, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+			"--chain-id", chainID, "--cache-dir", cacheDir, "--verbose")
+		require.NoError(t, err)
+
+		genDoc, err := tmtypes.GenesisDocFromJSON(out)
+		require.NoError(t, err)
+		return genDoc, log.String()
+	}
+
+	expensive := []string{"stage legacy finished", "stage v0.38 finished", "stage v0.39 finished", "stage v0.40 finished"}
+
+	first, log := migrate("cosmoshub-4")
+	for _, line := range expensive {
+		require.Contains(t, log, line)
+	}
+	require.NotContains(t, log, "reused the cached")
+
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	second, log := migrate("cosmoshub-5")
+	require.Contains(t, log, "reused the cached v0.40 state")
+	for _, line := range expensive {
+		require.NotContains(t, log, line)
+	}
+	require.Contains(t, log, "stage genesis finished")
+
+	require.Equal(t, "cosmoshub-5", second.ChainID)
+	second.ChainID = first.ChainID
+	require.Equal(t, first, second)
+
+	t.Run("corrupted entry", func(t *testing.T) {
+		cache := &stageCache{dir: cacheDir}
+		for _, path := range entries {
+			bz, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			var entry stageCacheEntry
+			require.NoError(t, json.Unmarshal(bz, &entry))
+			if entry.Stage != "v0.40" {
+				continue
+			}
+
+			entry.State = []byte(`{"bank":{}}`)
+			bz, err = json.Marshal(entry)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(path, bz, 0644))
+
+			_, ok, err := cache.Get(filepath.Base(path[:len(path)-len(".json")]))
+			require.False(t, ok)
+			require.EqualError(t, err, "cache entry "+path+" does not match its SHA-256")
+		}
+
+		_, log := migrate("cosmoshub-4")
+		require.Contains(t, log, "ignoring the cached v0.40 state: cache entry")
+		require.Contains(t, log, "reused the cached v0.39 state")
+		require.Contains(t, log, "stage v0.40 finished")
+		require.NotContains(t, log, "stage v0.39 finished")
+	})
+
+	t.Run("tampered entry", func(t *testing.T) {
+		manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+		_, err := executeMigrate(t, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+			"--chain-id", "cosmoshub-4", "--manifest", manifestPath)
+		require.NoError(t, err)
+		uncached, err := loadMigrationManifest(manifestPath)
+		require.NoError(t, err)
+
+		// the v0.39 state changed with its SHA-256, the entry alone is valid
+		for _, path := range entries {
+			bz, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			var entry stageCacheEntry
+			require.NoError(t, json.Unmarshal(bz, &entry))
+			if entry.Stage != "v0.39" {
+				continue
+			}
+
+			entry.State = []byte(`{"bank":{}}`)
+			sum := sha256.Sum256(entry.State)
+			entry.SHA256 = hex.EncodeToString(sum[:])
+			bz, err = json.Marshal(entry)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(path, bz, 0644))
+		}
+
+		var log bytes.Buffer
+		out, err := executeMigrateTo(t, &log, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2", "--no-prop-29",
+			"--chain-id", "cosmoshub-4", "--cache-dir", cacheDir, "--manifest", manifestPath, "--verbose")
+		require.NoError(t, err)
+		require.Contains(t, log.String(), "ignoring the cached states from the v0.39 stage on: stage chain broken at link 3, stage v0.39")
+		require.Contains(t, log.String(), "reused the cached v0.38 state")
+		require.NotContains(t, log.String(), "reused the cached v0.40 state")
+		require.Contains(t, log.String(), "stage v0.39 finished")
+
+		genDoc, err := tmtypes.GenesisDocFromJSON(out)
+		require.NoError(t, err)
+		require.Equal(t, first, genDoc)
+
+		cached, err := loadMigrationManifest(manifestPath)
+		require.NoError(t, err)
+		require.Equal(t, uncached.StageChain, cached.StageChain)
+		require.NoError(t, verifyManifestStageChain(cached))
+
+		// the stages run again are cached again
+		_, rerun := migrate("cosmoshub-4")
+		require.Contains(t, rerun, "reused the cached v0.40 state")
+		require.NotContains(t, rerun, "ignoring")
+	})
+
+	t.Run("gaia version changed", func(t *testing.T) {
+		defer func(v string) { version.Version = v }(version.Version)
+		version.Version = "v5.0.99"
+
+		_, log := migrate("cosmoshub-4")
+		require.NotContains(t, log, "reused the cached")
+		for _, line := range expensive {
+			require.Contains(t, log, line)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package gaia
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+// stakingEntriesCheck is the result of checking the unbonding and
+// redelegation entries of a staking genesis.
+type stakingEntriesCheck struct {
+	// OverLimit describes the unbonding delegations and redelegations with
+	// more entries than the max_entries staking param, their delegators
+	// cannot unbond or redelegate the same way again until entries complete.
+	OverLimit []string
+	// MaturedUnbondings and MaturedRedelegations count the entries whose
+	// completion time is not after the genesis time, which all complete in
+	// the first block.
+	MaturedUnbondings    int
+	MaturedRedelegations int
+}
+
+// checkStakingEntries checks the unbonding delegations and redelegations of
+// the staking genesis of state for a chain starting at genesisTime.
+func checkStakingEntries(cdc codec.JSONMarshaler, state types.AppMap, genesisTime time.Time) (stakingEntriesCheck, error) {
+	var check stakingEntriesCheck
+
+	var stakingGenesis staking.GenesisState
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return check, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", staking.ModuleName)
+	}
+	maxEntries := int(stakingGenesis.Params.MaxEntries)
+
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		if len(ubd.Entries) > maxEntries {
+			check.OverLimit = append(check.OverLimit, fmt.Sprintf("unbonding delegation of %s from %s has %d entries, max_entries is %d",
+				ubd.DelegatorAddress, ubd.ValidatorAddress, len(ubd.Entries), maxEntries))
+		}
+		for _, entry := range ubd.Entries {
+			if entry.IsMature(genesisTime) {
+				check.MaturedUnbondings++
+			}
+		}
+	}
+
+	for _, red := range stakingGenesis.Redelegations {
+		if len(red.Entries) > maxEntries {
+			check.OverLimit = append(check.OverLimit, fmt.Sprintf("redelegation of %s from %s to %s has %d entries, max_entries is %d",
+				red.DelegatorAddress, red.ValidatorSrcAddress, red.ValidatorDstAddress, len(red.Entries), maxEntries))
+		}
+		for _, entry := range red.Entries {
+			if entry.IsMature(genesisTime) {
+				check.MaturedRedelegations++
+			}
+		}
+	}
+
+	return check, nil
+}
+
+// maturedEntriesReport sums up what completeMaturedEntries completed.
+type maturedEntriesReport struct {
+	UnbondingEntries    int       `json:"unbonding_entries"`
+	Returned            sdk.Coins `json:"returned"`
+	Delegators          int       `json:"delegators"`
+	RedelegationEntries int       `json:"redelegation_entries"`
+}
+
+// completeMaturedEntries completes the unbonding and redelegation entries of
+// the staking genesis of state whose completion time is not after
+// genesisTime, as the staking end blocker of the first block would. The
+// balances of matured unbonding entries move from the not bonded pool to
+// their delegators, redelegation entries only stop tracking the redelegated
+// stake. Unbonding delegations and redelegations left without entries are
+// removed, the supply is unchanged.
+func completeMaturedEntries(cdc codec.JSONMarshaler, state types.AppMap, genesisTime time.Time) (maturedEntriesReport, error) {
+	report := maturedEntriesReport{Returned: sdk.NewCoins()}
+
+	var (
+		stakingGenesis staking.GenesisState
+		bankGenesis    bank.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", staking.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to JSON unmarshal %s genesis", bank.ModuleName)
+	}
+	bondDenom := stakingGenesis.Params.BondDenom
+
+	returned := make(map[string]sdk.Int)
+	ubds := stakingGenesis.UnbondingDelegations[:0]
+	for _, ubd := range stakingGenesis.UnbondingDelegations {
+		entries := ubd.Entries[:0]
+		for _, entry := range ubd.Entries {
+			if !entry.IsMature(genesisTime) {
+				entries = append(entries, entry)
+				continue
+			}
+
+			report.UnbondingEntries++
+			if entry.Balance.IsPositive() {
+				if _, ok := returned[ubd.DelegatorAddress]; !ok {
+					returned[ubd.DelegatorAddress] = sdk.ZeroInt()
+				}
+				returned[ubd.DelegatorAddress] = returned[ubd.DelegatorAddress].Add(entry.Balance)
+				report.Returned = report.Returned.Add(sdk.NewCoin(bondDenom, entry.Balance))
+			}
+		}
+
+		if len(entries) > 0 {
+			ubd.Entries = entries
+			ubds = append(ubds, ubd)
+		}
+	}
+	stakingGenesis.UnbondingDelegations = ubds
+
+	reds := stakingGenesis.Redelegations[:0]
+	for _, red := range stakingGenesis.Redelegations {
+		entries := red.Entries[:0]
+		for _, entry := range red.Entries {
+			if entry.IsMature(genesisTime) {
+				report.RedelegationEntries++
+			} else {
+				entries = append(entries, entry)
+			}
+		}
+
+		if len(entries) > 0 {
+			red.Entries = entries
+			reds = append(reds, red)
+		}
+	}
+	stakingGenesis.Redelegations = reds
+
+	if !report.Returned.IsZero() {
+		notBondedPool := auth.NewModuleAddress(staking.NotBondedPoolName).String()
+
+		pool := sdk.NewCoins()
+		balances := bankGenesis.Balances
+		for i, balance := range balances {
+			switch amount, ok := returned[balance.Address]; {
+			case balance.Address == notBondedPool:
+				pool = balance.Coins
+				balances[i].Coins, _ = balance.Coins.SafeSub(report.Returned)
+			case ok:
+				balances[i].Coins = balance.Coins.Add(sdk.NewCoin(bondDenom, amount))
+				delete(returned, balance.Address)
+				report.Delegators++
+			}
+		}
+
+		if !pool.IsAllGTE(report.Returned) {
+			return report, fmt.Errorf("not bonded pool holds %s, less than the %s of the matured unbonding entries", pool, report.Returned)
+		}
+
+		for delegator, amount := range returned {
+			balances = append(balances, bank.Balance{Address: delegator, Coins: sdk.NewCoins(sdk.NewCoin(bondDenom, amount))})
+			report.Delegators++
+		}
+		bankGenesis.Balances = bank.SanitizeGenesisBalances(balances)
+	}
+
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+
+	return report, nil
+}
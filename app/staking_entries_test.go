@@ -0,0 +1,84 @@
+package gaia
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStakingEntries(t *testing.T) {
+	b := genesisbuilder.New().WithValidators(3).
+		WithUnbondingDelegation("alice", 0, 100, -time.Hour).
+		WithUnbondingDelegation("alice", 0, 200, time.Hour).
+		WithUnbondingDelegation("bob", 1, 300, 0).
+		WithUnbondingDelegation("dave", 2, 400, 24*time.Hour).
+		WithRedelegation("carol", 0, 1, 500, -2*time.Hour).
+		WithRedelegation("carol", 0, 1, 600, 2*time.Hour)
+	genDoc, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	stakingGenesis.Params.MaxEntries = 1
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	check, err := checkStakingEntries(cdc, state, genDoc.GenesisTime)
+	require.NoError(t, err)
+	require.Equal(t, stakingEntriesCheck{
+		OverLimit: []string{
+			"unbonding delegation of " + b.Address("alice").String() + " from " + b.ValidatorAddress(0).String() + " has 2 entries, max_entries is 1",
+			"redelegation of " + b.Address("carol").String() + " from " + b.ValidatorAddress(0).String() + " to " + b.ValidatorAddress(1).String() + " has 2 entries, max_entries is 1",
+		},
+		MaturedUnbondings:    2,
+		MaturedRedelegations: 1,
+	}, check)
+
+	balanceOf := func(name string) sdk.Int {
+		var bankGenesis bank.GenesisState
+		cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+		for _, balance := range bankGenesis.Balances {
+			if balance.Address == b.Address(name).String() {
+				return balance.Coins.AmountOf(genesisbuilder.BondDenom)
+			}
+		}
+		return sdk.ZeroInt()
+	}
+	aliceBefore, bobBefore := balanceOf("alice"), balanceOf("bob")
+
+	report, err := completeMaturedEntries(cdc, state, genDoc.GenesisTime)
+	require.NoError(t, err)
+	require.Equal(t, maturedEntriesReport{
+		UnbondingEntries:    2,
+		Returned:            sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 400)),
+		Delegators:          2,
+		RedelegationEntries: 1,
+	}, report)
+	require.Equal(t, aliceBefore.AddRaw(100), balanceOf("alice"))
+	require.Equal(t, bobBefore.AddRaw(300), balanceOf("bob"))
+
+	check, err = checkStakingEntries(cdc, state, genDoc.GenesisTime)
+	require.NoError(t, err)
+	require.Equal(t, stakingEntriesCheck{}, check)
+
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	require.Len(t, stakingGenesis.UnbondingDelegations, 2)
+	require.Len(t, stakingGenesis.Redelegations, 1)
+	require.Len(t, stakingGenesis.Redelegations[0].Entries, 1)
+
+	audit, err := auditModuleAccounts(cdc, state)
+	require.NoError(t, err)
+	for _, acc := range audit {
+		require.True(t, acc.Balanced(), acc.Name)
+	}
+
+	// the supply and the staking and bank invariants still hold
+	genDoc.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+	require.NoError(t, SmokeTestGenesis(genDoc))
+}
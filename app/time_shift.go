@@ -0,0 +1,111 @@
+package gaia
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/x/evidence/exported"
+	evtypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// timeShiftExcluded documents the time fields --shift-all-times leaves alone.
+const timeShiftExcluded = "IBC client and consensus state timestamps are not shifted, they are verified against the counterparty chain"
+
+// shiftGenesisTimes moves every known timestamp of the staking, gov, slashing
+// and evidence genesis by delta, so they keep their distance to a changed
+// genesis time. Unset timestamps and the tombstone jail time are left as is.
+// It returns the number of shifted timestamps per module.
+func shiftGenesisTimes(cdc codec.JSONMarshaler, state types.AppMap, delta time.Duration) (map[string]int, error) {
+	shifted := make(map[string]int)
+
+	shift := func(module string, t *time.Time) {
+		if t.IsZero() || t.Equal(time.Unix(0, 0)) || t.Equal(evtypes.DoubleSignJailEndTime) {
+			return
+		}
+
+		*t = t.Add(delta)
+		shifted[module]++
+	}
+
+	if bz, ok := state[staking.ModuleName]; ok {
+		var stakingGenesis staking.GenesisState
+		if err := cdc.UnmarshalJSON(bz, &stakingGenesis); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal staking genesis: %w", err)
+		}
+
+		for i := range stakingGenesis.Validators {
+			shift(staking.ModuleName, &stakingGenesis.Validators[i].UnbondingTime)
+			shift(staking.ModuleName, &stakingGenesis.Validators[i].Commission.UpdateTime)
+		}
+		for i := range stakingGenesis.UnbondingDelegations {
+			for j := range stakingGenesis.UnbondingDelegations[i].Entries {
+				shift(staking.ModuleName, &stakingGenesis.UnbondingDelegations[i].Entries[j].CompletionTime)
+			}
+		}
+		for i := range stakingGenesis.Redelegations {
+			for j := range stakingGenesis.Redelegations[i].Entries {
+				shift(staking.ModuleName, &stakingGenesis.Redelegations[i].Entries[j].CompletionTime)
+			}
+		}
+
+		state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+	}
+
+	if bz, ok := state[gov.ModuleName]; ok {
+		var govGenesis gov.GenesisState
+		if err := cdc.UnmarshalJSON(bz, &govGenesis); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal gov genesis: %w", err)
+		}
+
+		for i := range govGenesis.Proposals {
+			shift(gov.ModuleName, &govGenesis.Proposals[i].SubmitTime)
+			shift(gov.ModuleName, &govGenesis.Proposals[i].DepositEndTime)
+			shift(gov.ModuleName, &govGenesis.Proposals[i].VotingStartTime)
+			shift(gov.ModuleName, &govGenesis.Proposals[i].VotingEndTime)
+		}
+
+		state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+	}
+
+	if bz, ok := state[slashing.ModuleName]; ok {
+		var slashingGenesis slashing.GenesisState
+		if err := cdc.UnmarshalJSON(bz, &slashingGenesis); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal slashing genesis: %w", err)
+		}
+
+		for i := range slashingGenesis.SigningInfos {
+			shift(slashing.ModuleName, &slashingGenesis.SigningInfos[i].ValidatorSigningInfo.JailedUntil)
+		}
+
+		state[slashing.ModuleName] = cdc.MustMarshalJSON(&slashingGenesis)
+	}
+
+	if bz, ok := state[evtypes.ModuleName]; ok {
+		var evGenesis evtypes.GenesisState
+		if err := cdc.UnmarshalJSON(bz, &evGenesis); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal evidence genesis: %w", err)
+		}
+
+		evidence := make([]exported.Evidence, len(evGenesis.Evidence))
+		for i, any := range evGenesis.Evidence {
+			ev, ok := any.GetCachedValue().(exported.Evidence)
+			if !ok {
+				return nil, fmt.Errorf("unexpected evidence type %s", any.TypeUrl)
+			}
+
+			if equivocation, ok := ev.(*evtypes.Equivocation); ok {
+				shift(evtypes.ModuleName, &equivocation.Time)
+			}
+			evidence[i] = ev
+		}
+
+		state[evtypes.ModuleName] = cdc.MustMarshalJSON(evtypes.NewGenesisState(evidence))
+	}
+
+	return shifted, nil
+}
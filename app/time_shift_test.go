@@ -0,0 +1,106 @@
+package gaia
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/evidence/exported"
+	evtypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	host "github.com/cosmos/cosmos-sdk/x/ibc/core/24-host"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShiftGenesisTimes(t *testing.T) {
+	b := testGenesisBuilder()
+	_, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+
+	jailedUntil := genesisbuilder.GenesisTime.Add(time.Hour)
+	unbondingTime := genesisbuilder.GenesisTime.Add(2 * time.Hour)
+	redelegationTime := genesisbuilder.GenesisTime.Add(3 * time.Hour)
+	evidenceTime := genesisbuilder.GenesisTime.Add(-time.Hour)
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	stakingGenesis.Validators[0].UnbondingTime = unbondingTime
+	stakingGenesis.Redelegations = []staking.Redelegation{staking.NewRedelegation(
+		b.Address("alice"), b.ValidatorAddress(0), b.ValidatorAddress(1), 1, redelegationTime, sdk.NewInt(1), sdk.OneDec())}
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	slashingGenesis := slashing.DefaultGenesisState()
+	for i, jailed := range []time.Time{jailedUntil, time.Unix(0, 0), evtypes.DoubleSignJailEndTime} {
+		consAddr := b.ValidatorConsAddress(i)
+		slashingGenesis.SigningInfos = append(slashingGenesis.SigningInfos, slashing.SigningInfo{
+			Address:              consAddr.String(),
+			ValidatorSigningInfo: slashing.NewValidatorSigningInfo(consAddr, 1, 0, jailed, i == 2, 0),
+		})
+	}
+	state[slashing.ModuleName] = cdc.MustMarshalJSON(slashingGenesis)
+
+	state[evtypes.ModuleName] = cdc.MustMarshalJSON(evtypes.NewGenesisState([]exported.Evidence{
+		&evtypes.Equivocation{Height: 10, Time: evidenceTime, Power: 10, ConsensusAddress: b.ValidatorConsAddress(2).String()},
+	}))
+
+	var govBefore gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govBefore)
+
+	ibcBefore := state[host.ModuleName]
+
+	delta := 90 * time.Minute
+	shifted, err := shiftGenesisTimes(cdc, state, delta)
+	require.NoError(t, err)
+
+	// 3 commission update times, 1 unbonding validator, 1 unbonding delegation
+	// entry and 1 redelegation entry
+	require.Equal(t, 6, shifted[staking.ModuleName])
+	// submit, deposit end, voting start and voting end of both proposals
+	require.Equal(t, 8, shifted[gov.ModuleName])
+	// only the jailed, not tombstoned validator
+	require.Equal(t, 1, shifted[slashing.ModuleName])
+	require.Equal(t, 1, shifted[evtypes.ModuleName])
+
+	t.Run("staking", func(t *testing.T) {
+		cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+		require.Equal(t, unbondingTime.Add(delta), stakingGenesis.Validators[0].UnbondingTime)
+		require.True(t, stakingGenesis.Validators[1].UnbondingTime.Equal(time.Unix(0, 0)) || stakingGenesis.Validators[1].UnbondingTime.IsZero())
+		require.Equal(t, genesisbuilder.GenesisTime.Add(-24*time.Hour+delta), stakingGenesis.Validators[1].Commission.UpdateTime)
+		require.Equal(t, genesisbuilder.GenesisTime.Add(7*24*time.Hour+delta), stakingGenesis.UnbondingDelegations[0].Entries[0].CompletionTime)
+		require.Equal(t, redelegationTime.Add(delta), stakingGenesis.Redelegations[0].Entries[0].CompletionTime)
+	})
+
+	t.Run("gov", func(t *testing.T) {
+		var govGenesis gov.GenesisState
+		cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+		for i, proposal := range govGenesis.Proposals {
+			require.Equal(t, govBefore.Proposals[i].SubmitTime.Add(delta), proposal.SubmitTime)
+			require.Equal(t, govBefore.Proposals[i].DepositEndTime.Add(delta), proposal.DepositEndTime)
+			require.Equal(t, govBefore.Proposals[i].VotingStartTime.Add(delta), proposal.VotingStartTime)
+			require.Equal(t, govBefore.Proposals[i].VotingEndTime.Add(delta), proposal.VotingEndTime)
+		}
+	})
+
+	t.Run("slashing", func(t *testing.T) {
+		cdc.MustUnmarshalJSON(state[slashing.ModuleName], slashingGenesis)
+		require.Equal(t, jailedUntil.Add(delta), slashingGenesis.SigningInfos[0].ValidatorSigningInfo.JailedUntil)
+		require.True(t, slashingGenesis.SigningInfos[1].ValidatorSigningInfo.JailedUntil.Equal(time.Unix(0, 0)))
+		require.True(t, slashingGenesis.SigningInfos[2].ValidatorSigningInfo.JailedUntil.Equal(evtypes.DoubleSignJailEndTime))
+	})
+
+	t.Run("evidence", func(t *testing.T) {
+		var evGenesis evtypes.GenesisState
+		cdc.MustUnmarshalJSON(state[evtypes.ModuleName], &evGenesis)
+		require.Len(t, evGenesis.Evidence, 1)
+		require.Equal(t, evidenceTime.Add(delta), evGenesis.Evidence[0].GetCachedValue().(*evtypes.Equivocation).Time)
+	})
+
+	t.Run("ibc is excluded", func(t *testing.T) {
+		require.Equal(t, ibcBefore, state[host.ModuleName])
+	})
+
+	require.NoError(t, ModuleBasics.ValidateGenesis(cdc, MakeEncodingConfig().TxConfig, state))
+}
@@ -0,0 +1,115 @@
+package gaia
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	flagNormalizeUnicode = "normalize-unicode-nfc"
+	flagUnicodeReport    = "unicode-report"
+)
+
+// nonNFCString is a string of the migrated state not in Unicode
+// normalization form C. It reads the same as its NFC form but is other bytes,
+// so a tool normalizing it on one machine and not on another produces
+// genesis files of different hashes.
+type nonNFCString struct {
+	Module string `json:"module"`
+	// Record is the operator address of a validator or the id of a proposal.
+	Record     string `json:"record"`
+	Field      string `json:"field"`
+	Value      string `json:"value"`
+	NFC        string `json:"nfc"`
+	Normalized bool   `json:"normalized"`
+}
+
+// checkUnicodeNFC returns the validator description fields and proposal
+// titles and descriptions of state that are not in NFC, by module, record and
+// field. With normalize they are normalized, those of the proposals past
+// voting included. Strings that are not valid UTF-8 have no normal form and
+// are left out.
+func checkUnicodeNFC(cdc codec.JSONMarshaler, state types.AppMap, normalize bool) ([]nonNFCString, error) {
+	var (
+		found          []nonNFCString
+		stakingGenesis staking.GenesisState
+		govGenesis     gov.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the staking genesis")
+	}
+	if err := cdc.UnmarshalJSON(state[gov.ModuleName], &govGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the gov genesis")
+	}
+
+	check := func(module, record, field string, value *string) bool {
+		if !utf8.ValidString(*value) || norm.NFC.IsNormalString(*value) {
+			return false
+		}
+
+		nfc := norm.NFC.String(*value)
+		found = append(found, nonNFCString{Module: module, Record: record, Field: field, Value: *value, NFC: nfc, Normalized: normalize})
+		if normalize {
+			*value = nfc
+		}
+		return true
+	}
+
+	for i, val := range stakingGenesis.Validators {
+		description := &stakingGenesis.Validators[i].Description
+		for _, field := range []struct {
+			name  string
+			value *string
+		}{
+			{"moniker", &description.Moniker},
+			{"identity", &description.Identity},
+			{"website", &description.Website},
+			{"security_contact", &description.SecurityContact},
+			{"details", &description.Details},
+		} {
+			check(staking.ModuleName, val.OperatorAddress, field.name, field.value)
+		}
+	}
+
+	for i, proposal := range govGenesis.Proposals {
+		content := proposal.GetContent()
+		if content == nil {
+			continue
+		}
+
+		record := fmt.Sprint(proposal.ProposalId)
+		title, description := content.GetTitle(), content.GetDescription()
+		changedTitle := check(gov.ModuleName, record, "title", &title)
+		changedDescription := check(gov.ModuleName, record, "description", &description)
+		if !normalize || (!changedTitle && !changedDescription) {
+			continue
+		}
+
+		any, err := truncatedContent(content, title, description)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to normalize the content of proposal %d", proposal.ProposalId)
+		}
+		govGenesis.Proposals[i].Content = any
+	}
+
+	if !normalize {
+		return found, nil
+	}
+
+	var err error
+	if state[staking.ModuleName], err = cdc.MarshalJSON(&stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the staking genesis")
+	}
+	if state[gov.ModuleName], err = cdc.MarshalJSON(&govGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the gov genesis")
+	}
+
+	return found, nil
+}
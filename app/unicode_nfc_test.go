@@ -0,0 +1,143 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const (
+	// nfdMoniker is Frederic with its accents as combining marks, NFD, and
+	// nfcMoniker the same name with precomposed accented letters, NFC.
+	nfdMoniker = "Fre\u0301de\u0301ric"
+	nfcMoniker = "Fr\u00e9d\u00e9ric"
+)
+
+func TestCheckUnicodeNFC(t *testing.T) {
+	b := genesisbuilder.New().
+		WithValidators(2).
+		WithProposal(gov.StatusVotingPeriod, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10000000))).
+		WithProposal(gov.StatusPassed, sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 10000000)))
+	_, state := buildTestGenesis(t, b)
+	cdc := MakeEncodingConfig().Marshaler
+	operator := b.ValidatorAddress(1).String()
+
+	var stakingGenesis staking.GenesisState
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	for i, val := range stakingGenesis.Validators {
+		switch val.OperatorAddress {
+		case operator:
+			stakingGenesis.Validators[i].Description.Moniker = nfdMoniker
+			stakingGenesis.Validators[i].Description.Details = "cafe\u0301"
+		default:
+			// precomposed and ASCII strings are NFC already
+			stakingGenesis.Validators[i].Description.Moniker = nfcMoniker
+		}
+	}
+	state[staking.ModuleName] = cdc.MustMarshalJSON(&stakingGenesis)
+
+	var govGenesis gov.GenesisState
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	for i, proposal := range govGenesis.Proposals {
+		content := gov.NewTextProposal("A\u030angstr\u00f6m", "unchanged")
+		if proposal.Status == gov.StatusPassed {
+			content = gov.NewTextProposal("passed", "\u1100\u1161 Hangul jamo")
+		}
+		any, err := truncatedContent(content, content.GetTitle(), content.GetDescription())
+		require.NoError(t, err)
+		govGenesis.Proposals[i].Content = any
+	}
+	state[gov.ModuleName] = cdc.MustMarshalJSON(&govGenesis)
+	voting := strconv.FormatUint(govGenesis.Proposals[0].ProposalId, 10)
+	passed := strconv.FormatUint(govGenesis.Proposals[1].ProposalId, 10)
+
+	expected := func(normalize bool) []nonNFCString {
+		return []nonNFCString{
+			{Module: staking.ModuleName, Record: operator, Field: "moniker", Value: nfdMoniker, NFC: nfcMoniker, Normalized: normalize},
+			{Module: staking.ModuleName, Record: operator, Field: "details", Value: "cafe\u0301", NFC: "caf\u00e9", Normalized: normalize},
+			{Module: gov.ModuleName, Record: voting, Field: "title", Value: "A\u030angstr\u00f6m", NFC: "\u00c5ngstr\u00f6m", Normalized: normalize},
+			{Module: gov.ModuleName, Record: passed, Field: "description", Value: "\u1100\u1161 Hangul jamo", NFC: "\uac00 Hangul jamo", Normalized: normalize},
+		}
+	}
+
+	before := make(map[string]json.RawMessage, len(state))
+	for module, bz := range state {
+		before[module] = bz
+	}
+	found, err := checkUnicodeNFC(cdc, state, false)
+	require.NoError(t, err)
+	require.Equal(t, expected(false), found)
+	require.Equal(t, before, map[string]json.RawMessage(state))
+
+	found, err = checkUnicodeNFC(cdc, state, true)
+	require.NoError(t, err)
+	require.Equal(t, expected(true), found)
+
+	cdc.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+	for _, val := range stakingGenesis.Validators {
+		require.Equal(t, nfcMoniker, val.Description.Moniker)
+	}
+	cdc.MustUnmarshalJSON(state[gov.ModuleName], &govGenesis)
+	require.Equal(t, "\u00c5ngstr\u00f6m", govGenesis.Proposals[0].GetContent().GetTitle())
+	require.Equal(t, "unchanged", govGenesis.Proposals[0].GetContent().GetDescription())
+	require.Equal(t, "\uac00 Hangul jamo", govGenesis.Proposals[1].GetContent().GetDescription())
+
+	found, err = checkUnicodeNFC(cdc, state, true)
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
+
+func TestMigrateUnicodeNFC(t *testing.T) {
+	bz, err := ioutil.ReadFile(filepath.Join(compatCorpus, "cosmoshub-3", "no-ibc-state", "genesis.json"))
+	require.NoError(t, err)
+	var genesis map[string]interface{}
+	require.NoError(t, json.Unmarshal(bz, &genesis))
+	stakingState := genesis["app_state"].(map[string]interface{})["staking"].(map[string]interface{})
+	validator := stakingState["validators"].([]interface{})[0].(map[string]interface{})
+	validator["description"].(map[string]interface{})["moniker"] = nfdMoniker
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "genesis.json")
+	bz, err = json.Marshal(genesis)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	var stderr bytes.Buffer
+	_, err = executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4")
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), `W-STAKING-006 [medium] the moniker of validator cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0, "Fre\u0301de\u0301ric", is not in Unicode NFC, "Fr\u00e9d\u00e9ric", use --normalize-unicode-nfc to normalize it`)
+
+	reportPath := filepath.Join(dir, "unicode.json")
+	stderr.Reset()
+	out, err := executeMigrateTo(t, &stderr, path, "--chain-id", "cosmoshub-4", "--"+flagNormalizeUnicode, "--"+flagUnicodeReport, reportPath)
+	require.NoError(t, err)
+	require.NotContains(t, stderr.String(), "W-STAKING-006")
+	require.Contains(t, stderr.String(), "normalized 1 validator description and proposal content strings to NFC\n")
+
+	bz, err = ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report []nonNFCString
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Equal(t, []nonNFCString{{
+		Module: staking.ModuleName, Record: "cosmosvaloper1tlykl6rqlns2wv2nd5claxvdrnq2scsu7egfp0", Field: "moniker",
+		Value: nfdMoniker, NFC: nfcMoniker, Normalized: true,
+	}}, report)
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	var appState map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &appState))
+	var stakingGenesis staking.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(appState[staking.ModuleName], &stakingGenesis)
+	require.Equal(t, nfcMoniker, stakingGenesis.Validators[0].Description.Moniker)
+}
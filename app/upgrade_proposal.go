@@ -0,0 +1,83 @@
+package gaia
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	upgrade "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/pkg/errors"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// loadUpgradePlan returns the plan of a software upgrade proposal. The
+// proposal is either a gov proposal ID, queried from the node configured on
+// the client context, or the path to a JSON file holding the proposal content.
+func loadUpgradePlan(clientCtx client.Context, proposal string) (upgrade.Plan, error) {
+	if proposalID, err := strconv.ParseUint(proposal, 10, 64); err == nil {
+		return queryUpgradePlan(clientCtx, proposalID)
+	}
+
+	bz, err := ioutil.ReadFile(proposal)
+	if err != nil {
+		return upgrade.Plan{}, errors.Wrapf(err, "failed to read upgrade proposal from file %s", proposal)
+	}
+
+	var content upgrade.SoftwareUpgradeProposal
+	if err := clientCtx.JSONMarshaler.UnmarshalJSON(bz, &content); err != nil {
+		return upgrade.Plan{}, errors.Wrapf(err, "failed to unmarshal software upgrade proposal from file %s", proposal)
+	}
+
+	return content.Plan, nil
+}
+
+func queryUpgradePlan(clientCtx client.Context, proposalID uint64) (upgrade.Plan, error) {
+	if clientCtx.Client == nil {
+		return upgrade.Plan{}, fmt.Errorf("querying upgrade proposal %d requires a node, set one with --node", proposalID)
+	}
+
+	res, err := gov.NewQueryClient(clientCtx).Proposal(context.Background(), &gov.QueryProposalRequest{ProposalId: proposalID})
+	if err != nil {
+		return upgrade.Plan{}, errors.Wrapf(err, "failed to query proposal %d", proposalID)
+	}
+
+	content, ok := res.Proposal.GetContent().(*upgrade.SoftwareUpgradeProposal)
+	if !ok {
+		return upgrade.Plan{}, fmt.Errorf("proposal %d is not a software upgrade proposal", proposalID)
+	}
+
+	return content.Plan, nil
+}
+
+// applyUpgradePlan sets the initial height of genDoc to the height after the
+// upgrade plan halts the source chain, or the genesis time to the plan time for
+// time based plans. heightSet and timeSet report whether the genDoc values were
+// explicitly provided, in which case they must agree with the plan.
+func applyUpgradePlan(genDoc *tmtypes.GenesisDoc, plan upgrade.Plan, heightSet, timeSet bool) error {
+	if plan.Height <= 0 && plan.Time.IsZero() {
+		return fmt.Errorf("upgrade plan %q has neither a height nor a time", plan.Name)
+	}
+
+	if plan.Height > 0 {
+		initialHeight := plan.Height + 1
+		if heightSet && genDoc.InitialHeight != initialHeight {
+			return fmt.Errorf("--%s %d conflicts with height %d derived from upgrade plan %q", flagInitialHeight, genDoc.InitialHeight, initialHeight, plan.Name)
+		}
+
+		genDoc.InitialHeight = initialHeight
+	}
+
+	if !plan.Time.IsZero() {
+		if timeSet && !genDoc.GenesisTime.Equal(plan.Time) {
+			return fmt.Errorf("--%s %s conflicts with time %s from upgrade plan %q", flagGenesisTime, genDoc.GenesisTime.Format(time.RFC3339Nano), plan.Time.Format(time.RFC3339Nano), plan.Name)
+		}
+
+		genDoc.GenesisTime = plan.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package gaia
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func writeUpgradeProposal(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "proposal.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+func TestApplyUpgradePlanFromFile(t *testing.T) {
+	clientCtx := client.Context{}.WithJSONMarshaler(MakeEncodingConfig().Marshaler)
+
+	heightProposal := writeUpgradeProposal(t, `{
+		"title": "Vega",
+		"description": "upgrade",
+		"plan": {"name": "vega", "height": "7368386"}
+	}`)
+	timeProposal := writeUpgradeProposal(t, `{
+		"title": "Vega",
+		"description": "upgrade",
+		"plan": {"name": "vega", "time": "2021-07-12T14:00:00Z"}
+	}`)
+	planTime := time.Date(2021, 7, 12, 14, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		proposal      string
+		initialHeight int64
+		heightSet     bool
+		genesisTime   time.Time
+		timeSet       bool
+		expHeight     int64
+		expTime       time.Time
+		expErr        string
+	}{
+		{"height derived", heightProposal, 0, false, time.Time{}, false, 7368387, time.Time{}, ""},
+		{"matching explicit height", heightProposal, 7368387, true, time.Time{}, false, 7368387, time.Time{}, ""},
+		{"conflicting explicit height", heightProposal, 5000, true, time.Time{}, false, 0, time.Time{}, "conflicts with height 7368387"},
+		{"time derived", timeProposal, 5000, true, time.Time{}, false, 5000, planTime, ""},
+		{"conflicting explicit time", timeProposal, 5000, true, planTime.Add(time.Hour), true, 0, time.Time{}, "conflicts with time"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := loadUpgradePlan(clientCtx, tc.proposal)
+			require.NoError(t, err)
+
+			genDoc := &tmtypes.GenesisDoc{InitialHeight: tc.initialHeight, GenesisTime: tc.genesisTime}
+
+			err = applyUpgradePlan(genDoc, plan, tc.heightSet, tc.timeSet)
+			if tc.expErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expHeight, genDoc.InitialHeight)
+			require.True(t, tc.expTime.Equal(genDoc.GenesisTime))
+		})
+	}
+}
+
+func TestLoadUpgradePlanByIDRequiresNode(t *testing.T) {
+	_, err := loadUpgradePlan(client.Context{}, "29")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--node")
+}
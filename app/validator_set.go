@@ -0,0 +1,196 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+	cryptocodec "github.com/tendermint/tendermint/crypto/encoding"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// tmValidatorsFromStaking computes the tendermint genesis validator set that
+// the staking module will produce at InitChain: every bonded validator, in
+// staking genesis order, with its tokens converted to consensus power.
+func tmValidatorsFromStaking(stakingGenesis staking.GenesisState) ([]tmtypes.GenesisValidator, error) {
+	var validators []tmtypes.GenesisValidator
+
+	for _, val := range stakingGenesis.Validators {
+		if !val.IsBonded() {
+			continue
+		}
+
+		protoPubKey, err := val.TmConsPublicKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get consensus key of validator %s", val.OperatorAddress)
+		}
+
+		pubKey, err := cryptocodec.PubKeyFromProto(protoPubKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode consensus key of validator %s", val.OperatorAddress)
+		}
+
+		validators = append(validators, tmtypes.GenesisValidator{
+			Address: pubKey.Address(),
+			PubKey:  pubKey,
+			Power:   val.ConsensusPower(),
+			Name:    val.GetMoniker(),
+		})
+	}
+
+	return validators, nil
+}
+
+// tmValidatorsFromAppState decodes the staking genesis from the app state and
+// returns the tendermint validator set derived from it.
+func tmValidatorsFromAppState(clientCtx client.Context, appState json.RawMessage) ([]tmtypes.GenesisValidator, error) {
+	var state types.AppMap
+	if err := json.Unmarshal(appState, &state); err != nil {
+		return nil, errors.Wrap(err, "failed to JSON unmarshal genesis state")
+	}
+
+	var stakingGenesis staking.GenesisState
+	if err := clientCtx.JSONMarshaler.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal staking genesis")
+	}
+
+	return tmValidatorsFromStaking(stakingGenesis)
+}
+
+// validatorSetDiscrepancies compares the tendermint genesis validators against
+// the expected set and describes every membership, power and ordering mismatch.
+// An empty result means InitChain will accept the validator set.
+func validatorSetDiscrepancies(expected, actual []tmtypes.GenesisValidator) []string {
+	var discrepancies []string
+
+	expectedByAddr := make(map[string]tmtypes.GenesisValidator, len(expected))
+	for _, val := range expected {
+		expectedByAddr[val.Address.String()] = val
+	}
+
+	actualByAddr := make(map[string]tmtypes.GenesisValidator, len(actual))
+	for _, val := range actual {
+		actualByAddr[val.Address.String()] = val
+	}
+
+	for _, val := range expected {
+		if _, ok := actualByAddr[val.Address.String()]; !ok {
+			discrepancies = append(discrepancies, fmt.Sprintf(
+				"validator %s (%s) is bonded in staking but missing from the tendermint validator set", val.Address, val.Name))
+		}
+	}
+
+	for _, val := range actual {
+		exp, ok := expectedByAddr[val.Address.String()]
+		if !ok {
+			discrepancies = append(discrepancies, fmt.Sprintf(
+				"validator %s (%s) is in the tendermint validator set but not bonded in staking", val.Address, val.Name))
+			continue
+		}
+
+		if exp.Power != val.Power {
+			discrepancies = append(discrepancies, fmt.Sprintf(
+				"validator %s (%s) has tendermint power %d but staking power %d", val.Address, val.Name, val.Power, exp.Power))
+		}
+	}
+
+	// Only compare the relative order of validators present in both sets,
+	// membership differences are already reported above.
+	var expectedOrder, actualOrder []string
+	for _, val := range expected {
+		if _, ok := actualByAddr[val.Address.String()]; ok {
+			expectedOrder = append(expectedOrder, val.Address.String())
+		}
+	}
+	for _, val := range actual {
+		if _, ok := expectedByAddr[val.Address.String()]; ok {
+			actualOrder = append(actualOrder, val.Address.String())
+		}
+	}
+
+	for i := range expectedOrder {
+		if expectedOrder[i] != actualOrder[i] {
+			discrepancies = append(discrepancies, fmt.Sprintf(
+				"tendermint validator set is out of order: position %d is %s, expected %s", i, actualOrder[i], expectedOrder[i]))
+			break
+		}
+	}
+
+	return discrepancies
+}
+
+// genesisValidatorProblems checks the tendermint genesis validators against
+// the limits of Tendermint and describes every violation, with the index and
+// field of the validator at fault: a positive power, a total power of at most
+// MaxTotalVotingPower, at most MaxVotesCount validators, addresses matching
+// the public keys and unique, and monikers of at most the staking length.
+func genesisValidatorProblems(validators []tmtypes.GenesisValidator) []string {
+	var problems []string
+
+	if len(validators) > tmtypes.MaxVotesCount {
+		problems = append(problems, fmt.Sprintf("%d tendermint genesis validators, at most %d can vote", len(validators), tmtypes.MaxVotesCount))
+	}
+
+	var total int64
+	byAddr := make(map[string]int, len(validators))
+	for i, val := range validators {
+		if val.Power <= 0 {
+			problems = append(problems, fmt.Sprintf("validator %d (%s): power %d, it must be positive", i, val.Name, val.Power))
+		} else if total > tmtypes.MaxTotalVotingPower-val.Power {
+			problems = append(problems, fmt.Sprintf("validator %d (%s): power %d takes the total power above the maximum %d", i, val.Name, val.Power, tmtypes.MaxTotalVotingPower))
+		} else {
+			total += val.Power
+		}
+
+		if val.PubKey != nil && !bytes.Equal(val.Address, val.PubKey.Address()) {
+			problems = append(problems, fmt.Sprintf("validator %d (%s): address %s is not the address of its public key %s", i, val.Name, val.Address, val.PubKey.Address()))
+		}
+
+		if j, ok := byAddr[val.Address.String()]; ok {
+			problems = append(problems, fmt.Sprintf("validator %d (%s): address %s is also the address of validator %d", i, val.Name, val.Address, j))
+		} else {
+			byAddr[val.Address.String()] = i
+		}
+
+		if len(val.Name) > staking.MaxMonikerLength {
+			problems = append(problems, fmt.Sprintf("validator %d: name is %d characters long, at most %d are allowed", i, len(val.Name), staking.MaxMonikerLength))
+		}
+	}
+
+	return problems
+}
+
+// firstProposer returns the index of the genesis validator tendermint selects
+// as proposer of the first block, or -1 for an empty set. Genesis validators
+// carry no proposer priorities, tendermint recomputes them from the set, so the
+// result only depends on the validators' powers and addresses, the latter
+// breaking ties between equal powers.
+func firstProposer(validators []tmtypes.GenesisValidator) (int, error) {
+	if len(validators) == 0 {
+		return -1, nil
+	}
+
+	changes := make([]*tmtypes.Validator, len(validators))
+	for i, val := range validators {
+		changes[i] = tmtypes.NewValidator(val.PubKey, val.Power)
+	}
+
+	valSet := &tmtypes.ValidatorSet{}
+	if err := valSet.UpdateWithChangeSet(changes); err != nil {
+		return -1, errors.Wrap(err, "invalid tendermint validator set")
+	}
+	valSet.IncrementProposerPriority(1)
+
+	proposer := valSet.GetProposer()
+	for i, val := range validators {
+		if bytes.Equal(val.Address, proposer.Address) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("proposer %s is not a genesis validator", proposer.Address)
+}
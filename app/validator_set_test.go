@@ -0,0 +1,227 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func TestValidatorSetDiscrepancies(t *testing.T) {
+	genDoc, state := buildTestGenesis(t, genesisbuilder.New().WithValidators(4))
+
+	var stakingGenesis staking.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(state[staking.ModuleName], &stakingGenesis)
+
+	all, err := tmValidatorsFromStaking(stakingGenesis)
+	require.NoError(t, err)
+	require.Equal(t, genDoc.Validators, all)
+	require.Empty(t, validatorSetDiscrepancies(all, genDoc.Validators))
+
+	expected := all[:3]
+	require.Equal(t, int64(20), expected[1].Power)
+
+	t.Run("power mismatch", func(t *testing.T) {
+		actual := append(expected[:0:0], expected...)
+		actual[1].Power = 21
+
+		discrepancies := validatorSetDiscrepancies(expected, actual)
+		require.Len(t, discrepancies, 1)
+		require.Contains(t, discrepancies[0], "has tendermint power 21 but staking power 20")
+	})
+
+	t.Run("missing validator", func(t *testing.T) {
+		actual := append(expected[:0:0], expected[0], expected[2])
+
+		discrepancies := validatorSetDiscrepancies(expected, actual)
+		require.Len(t, discrepancies, 1)
+		require.Contains(t, discrepancies[0], "missing from the tendermint validator set")
+	})
+
+	t.Run("extra validator", func(t *testing.T) {
+		discrepancies := validatorSetDiscrepancies(expected, all)
+		require.Len(t, discrepancies, 1)
+		require.Contains(t, discrepancies[0], "not bonded in staking")
+	})
+
+	t.Run("ordering", func(t *testing.T) {
+		actual := append(expected[:0:0], expected[1], expected[0], expected[2])
+
+		discrepancies := validatorSetDiscrepancies(expected, actual)
+		require.Len(t, discrepancies, 1)
+		require.Contains(t, discrepancies[0], "out of order")
+	})
+
+	t.Run("unbonded validators are excluded", func(t *testing.T) {
+		stakingGenesis.Validators[3].Status = staking.Unbonded
+
+		vals, err := tmValidatorsFromStaking(stakingGenesis)
+		require.NoError(t, err)
+		require.Empty(t, validatorSetDiscrepancies(expected, vals))
+	})
+}
+
+func TestFirstProposer(t *testing.T) {
+	b := genesisbuilder.New().WithValidatorPowers(10, 10, 10, 10)
+
+	genDoc, _ := buildTestGenesis(t, b)
+	again, _ := buildTestGenesis(t, b)
+
+	proposer, err := firstProposer(genDoc.Validators)
+	require.NoError(t, err)
+
+	proposerAgain, err := firstProposer(again.Validators)
+	require.NoError(t, err)
+	require.Equal(t, proposer, proposerAgain)
+
+	requireTendermintProposer := func(validators []tmtypes.GenesisValidator, idx int) {
+		vals := make([]*tmtypes.Validator, len(validators))
+		for i, val := range validators {
+			vals[i] = tmtypes.NewValidator(val.PubKey, val.Power)
+		}
+
+		require.Equal(t, validators[idx].Address, tmtypes.NewValidatorSet(vals).GetProposer().Address)
+	}
+	requireTendermintProposer(genDoc.Validators, proposer)
+
+	// replace the key of every validator but the current proposer, the
+	// result must be the same on every run
+	var replacements replacementConfigs
+	for i := range genDoc.Validators {
+		if i == proposer {
+			continue
+		}
+
+		pk := ed25519.GenPrivKeyFromSecret([]byte(fmt.Sprintf("replacement%d", i))).PubKey()
+		bech32PubKey, err := sdk.Bech32ifyPubKey(sdk.Bech32PubKeyTypeConsPub, pk)
+		require.NoError(t, err)
+
+		replacements = append(replacements, replacementConfig{
+			Name:             genesisbuilder.ValidatorName(i),
+			ValidatorAddress: b.ValidatorAddress(i).String(),
+			ConsensusPubkey:  bech32PubKey,
+		})
+	}
+
+	bz, err := json.Marshal(replacements)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "replacement.json")
+	require.NoError(t, ioutil.WriteFile(path, bz, 0600))
+
+	clientCtx := client.Context{}.WithJSONMarshaler(MakeEncodingConfig().Marshaler)
+
+	replaced := loadKeydataFromFile(clientCtx, path, genDoc)
+	replacedAgain := loadKeydataFromFile(clientCtx, path, again)
+	require.Equal(t, replaced.Validators, replacedAgain.Validators)
+
+	stakingValidators, err := tmValidatorsFromAppState(clientCtx, replaced.AppState)
+	require.NoError(t, err)
+	require.Empty(t, validatorSetDiscrepancies(stakingValidators, replaced.Validators))
+
+	replacedProposer, err := firstProposer(replaced.Validators)
+	require.NoError(t, err)
+
+	replacedProposerAgain, err := firstProposer(replacedAgain.Validators)
+	require.NoError(t, err)
+	require.Equal(t, replacedProposer, replacedProposerAgain)
+	requireTendermintProposer(replaced.Validators, replacedProposer)
+
+	proposer, err = firstProposer(nil)
+	require.NoError(t, err)
+	require.Equal(t, -1, proposer)
+}
+
+func TestGenesisValidatorProblems(t *testing.T) {
+	genDoc, _ := buildTestGenesis(t, genesisbuilder.New().WithValidators(3))
+	require.Empty(t, genesisValidatorProblems(genDoc.Validators))
+
+	edited := func(edit func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator) []string {
+		return genesisValidatorProblems(edit(append(genDoc.Validators[:0:0], genDoc.Validators...)))
+	}
+
+	testCases := []struct {
+		name     string
+		edit     func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator
+		problems []string
+	}{
+		{"zero power", func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator {
+			vals[1].Power = 0
+			return vals
+		}, []string{fmt.Sprintf("validator 1 (%s): power 0, it must be positive", genDoc.Validators[1].Name)}},
+		{"total power", func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator {
+			vals[0].Power = tmtypes.MaxTotalVotingPower / 2
+			vals[2].Power = tmtypes.MaxTotalVotingPower / 2
+			return vals
+		}, []string{fmt.Sprintf("validator 2 (%s): power %d takes the total power above the maximum %d",
+			genDoc.Validators[2].Name, tmtypes.MaxTotalVotingPower/2, tmtypes.MaxTotalVotingPower)}},
+		{"duplicate address", func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator {
+			return append(vals, vals[0])
+		}, []string{fmt.Sprintf("validator 3 (%s): address %s is also the address of validator 0", genDoc.Validators[0].Name, genDoc.Validators[0].Address)}},
+		{"address of another key", func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator {
+			vals[2].PubKey = vals[1].PubKey
+			return vals
+		}, []string{fmt.Sprintf("validator 2 (%s): address %s is not the address of its public key %s",
+			genDoc.Validators[2].Name, genDoc.Validators[2].Address, genDoc.Validators[1].Address)}},
+		{"long name", func(vals []tmtypes.GenesisValidator) []tmtypes.GenesisValidator {
+			vals[0].Name = strings.Repeat("v", staking.MaxMonikerLength+1)
+			return vals
+		}, []string{fmt.Sprintf("validator 0: name is %d characters long, at most %d are allowed", staking.MaxMonikerLength+1, staking.MaxMonikerLength)}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.problems, edited(tc.edit))
+		})
+	}
+
+	t.Run("validator count", func(t *testing.T) {
+		vals := make([]tmtypes.GenesisValidator, tmtypes.MaxVotesCount+1)
+		for i := range vals {
+			pubKey := ed25519.GenPrivKeyFromSecret([]byte(fmt.Sprint(i))).PubKey()
+			tmPubKey, err := cryptocodec.ToTmPubKeyInterface(pubKey)
+			require.NoError(t, err)
+			vals[i] = tmtypes.GenesisValidator{Address: tmPubKey.Address(), PubKey: tmPubKey, Power: 1, Name: fmt.Sprint(i)}
+		}
+
+		require.Equal(t, []string{fmt.Sprintf("%d tendermint genesis validators, at most %d can vote", tmtypes.MaxVotesCount+1, tmtypes.MaxVotesCount)},
+			genesisValidatorProblems(vals))
+	})
+}
+
+func TestMigrateSyncValidatorPowers(t *testing.T) {
+	// the source tendermint power disagrees with the staking tokens
+	source := writeSourceGenesis(t, func(genesis map[string]interface{}) {
+		genesis["validators"].([]interface{})[0].(map[string]interface{})["power"] = "1"
+	})
+	args := []string{source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4"}
+
+	_, err := executeMigrate(t, args...)
+	require.EqualError(t, err, "tendermint genesis validators do not match the staking bonded set (1 discrepancies), use --sync-tm-validators to regenerate them from staking")
+
+	out, err := executeMigrate(t, append(args, "--sync-tm-validators")...)
+	require.NoError(t, err)
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), genDoc.Validators[0].Power)
+
+	// staking tokens below one unit of power give a validator no power
+	source = writeSourceGenesis(t, func(genesis map[string]interface{}) {
+		appState := genesis["app_state"].(map[string]interface{})
+		appState["staking"].(map[string]interface{})["validators"].([]interface{})[0].(map[string]interface{})["tokens"] = "100"
+	})
+	_, err = executeMigrate(t, source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4", "--sync-tm-validators")
+	require.EqualError(t, err, "tendermint genesis validators exceed the limits of Tendermint (1 problems)")
+}
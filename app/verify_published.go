@@ -0,0 +1,111 @@
+package gaia
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagSHA256   = "sha256"
+	flagSize     = "size"
+	flagManifest = "manifest"
+)
+
+// VerifyPublishedGenesisCmd returns a command checking a downloaded genesis
+// file against its published SHA-256 and size.
+func VerifyPublishedGenesisCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-published [genesis-file]",
+		Short: "Verify a genesis file against its published SHA-256 and size",
+		Long: fmt.Sprintf(`Stream the genesis file, compute its SHA-256 and compare it, and its size, with
+the published values given by --sha256 and --size or read from the manifest
+written by migrate --manifest, a file or an http(s) URL. Flags override the
+manifest values. Pass - as the genesis file to read it from STDIN.
+
+Example:
+$ %s genesis verify-published genesis.json --sha256 <hex> --size 104857600
+$ %s genesis verify-published genesis.json --manifest https://example.com/manifest.json
+`, version.AppName, version.AppName),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var expected migrationManifest
+			expected.GenesisSize = -1
+
+			if source, _ := cmd.Flags().GetString(flagManifest); source != "" {
+				manifest, err := loadMigrationManifest(source)
+				if err != nil {
+					return errors.Wrap(err, "failed to load manifest")
+				}
+				expected = manifest
+			}
+
+			if sum, _ := cmd.Flags().GetString(flagSHA256); sum != "" {
+				expected.GenesisSHA256 = sum
+			}
+
+			if cmd.Flags().Changed(flagSize) {
+				expected.GenesisSize, _ = cmd.Flags().GetInt64(flagSize)
+			}
+
+			if expected.GenesisSHA256 == "" {
+				return fmt.Errorf("either --%s or --%s is required", flagSHA256, flagManifest)
+			}
+
+			expected.GenesisSHA256 = strings.ToLower(expected.GenesisSHA256)
+			if bz, err := hex.DecodeString(expected.GenesisSHA256); err != nil || len(bz) != 32 {
+				return fmt.Errorf("invalid SHA-256 %q, expected 64 hex characters", expected.GenesisSHA256)
+			}
+
+			input, err := openGenesisInput(args[0], cmd.InOrStdin())
+			if err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+			defer input.Close()
+
+			digest := newDigestWriter()
+			if _, err := io.Copy(digest, input); err != nil {
+				return errors.Wrap(err, "failed to read genesis file")
+			}
+
+			mismatches := verifyPublishedGenesis(expected, digest)
+			if len(mismatches) > 0 {
+				for _, mismatch := range mismatches {
+					cmd.PrintErrln(mismatch)
+				}
+
+				return fmt.Errorf("%s does not match the published genesis", args[0])
+			}
+
+			cmd.Printf("%s matches the published genesis: sha256 %s, %d bytes\n", args[0], digest.Sum(), digest.Size())
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagSHA256, "", "Published hex encoded SHA-256 of the genesis file")
+	cmd.Flags().Int64(flagSize, 0, "Published size of the genesis file in bytes")
+	cmd.Flags().String(flagManifest, "", "Migration manifest file or http(s) URL to read the published SHA-256 and size from")
+
+	return cmd
+}
+
+// verifyPublishedGenesis returns an expected vs actual line for the size and
+// SHA-256 that do not match. A negative expected size is not checked.
+func verifyPublishedGenesis(expected migrationManifest, actual *digestWriter) []string {
+	var mismatches []string
+
+	if expected.GenesisSize >= 0 && expected.GenesisSize != actual.Size() {
+		mismatches = append(mismatches, fmt.Sprintf("size:   expected %d, actual %d", expected.GenesisSize, actual.Size()))
+	}
+
+	if expected.GenesisSHA256 != actual.Sum() {
+		mismatches = append(mismatches, fmt.Sprintf("sha256: expected %s, actual %s", expected.GenesisSHA256, actual.Sum()))
+	}
+
+	return mismatches
+}
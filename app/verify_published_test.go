@@ -0,0 +1,109 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func runVerifyPublished(t *testing.T, args ...string) (string, error) {
+	cmd := VerifyPublishedGenesisCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestVerifyPublishedGenesis(t *testing.T) {
+	genesis := []byte("{\"chain_id\":\"cosmoshub-4\"}\n")
+	sum := sha256.Sum256(genesis)
+	hexSum := hex.EncodeToString(sum[:])
+	otherSum := sha256.Sum256([]byte("other"))
+	size := fmt.Sprint(len(genesis))
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, ioutil.WriteFile(path, genesis, 0600))
+
+	t.Run("match", func(t *testing.T) {
+		out, err := runVerifyPublished(t, path, "--sha256", hexSum, "--size", size)
+		require.NoError(t, err)
+		require.Contains(t, out, "matches the published genesis")
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		out, err := runVerifyPublished(t, path, "--sha256", hex.EncodeToString(otherSum[:]), "--size", size)
+		require.Error(t, err)
+		require.Contains(t, out, fmt.Sprintf("sha256: expected %x, actual %s", otherSum, hexSum))
+		require.NotContains(t, out, "size:")
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		out, err := runVerifyPublished(t, path, "--sha256", hexSum, "--size", "1")
+		require.Error(t, err)
+		require.Contains(t, out, fmt.Sprintf("size:   expected 1, actual %d", len(genesis)))
+		require.NotContains(t, out, "sha256:")
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		_, err := runVerifyPublished(t, path, "--sha256", "abc")
+		require.Error(t, err)
+
+		_, err = runVerifyPublished(t, path)
+		require.Error(t, err)
+	})
+
+	digest := newDigestWriter()
+	_, err := digest.Write(genesis)
+	require.NoError(t, err)
+
+	manifest := newMigrationManifest(&tmtypes.GenesisDoc{ChainID: "cosmoshub-4", GenesisTime: time.Unix(0, 0).UTC(), InitialHeight: 2}, digest, newDigestWriter(), nil)
+	require.Equal(t, hexSum, manifest.GenesisSHA256)
+	require.Equal(t, int64(len(genesis)), manifest.GenesisSize)
+
+	manifestBz, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	t.Run("manifest file", func(t *testing.T) {
+		manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+		require.NoError(t, ioutil.WriteFile(manifestPath, manifestBz, 0600))
+
+		_, err := runVerifyPublished(t, path, "--manifest", manifestPath)
+		require.NoError(t, err)
+
+		// flags override the manifest
+		out, err := runVerifyPublished(t, path, "--manifest", manifestPath, "--size", "1")
+		require.Error(t, err)
+		require.Contains(t, out, "size:   expected 1")
+	})
+
+	t.Run("manifest url", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/manifest.json" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(manifestBz)
+		}))
+		defer server.Close()
+
+		_, err := runVerifyPublished(t, path, "--manifest", server.URL+"/manifest.json")
+		require.NoError(t, err)
+
+		_, err = runVerifyPublished(t, path, "--manifest", server.URL+"/missing.json")
+		require.Error(t, err)
+	})
+}
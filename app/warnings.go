@@ -0,0 +1,205 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+// warningSeverity classifies how likely a migration warning is to break the
+// migrated chain.
+type warningSeverity = genesis.Severity
+
+const (
+	severityLow    = genesis.SeverityLow
+	severityMedium = genesis.SeverityMedium
+	severityHigh   = genesis.SeverityHigh
+)
+
+// Stable codes of the migration warnings, matched by --warnings-as-errors.
+const (
+	warnAuthBlockedNotFound  = "W-AUTH-001"
+	warnAuthProtectedSkipped = "W-AUTH-002"
+	warnAuthInvalidPubKey    = "W-AUTH-003"
+	warnAuthSeqDecreased     = "W-AUTH-004"
+	warnAuthPubKeyChanged    = "W-AUTH-005"
+	warnBankModuleAccount    = "W-BANK-001"
+	warnCrisisFeeDenom       = "W-CRISIS-001"
+	warnEvidenceUnknown      = "W-EVIDENCE-001"
+	warnEvidenceExpired      = "W-EVIDENCE-002"
+	warnGenesisSmokeTestSize = "W-GENESIS-001"
+	warnGenesisModuleSize    = "W-GENESIS-002"
+	warnGovStaleVote         = "W-GOV-001"
+	warnGovTallyOutcome      = "W-GOV-002"
+	warnGovLongContent       = "W-GOV-003"
+	warnGovDepositsBalance   = "W-GOV-004"
+	warnGovNonNFC            = "W-GOV-005"
+	warnIBCClientExpired     = "W-IBC-001"
+	warnIBCClientProofSpecs  = "W-IBC-002"
+	warnIBCClientUnmapped    = "W-IBC-003"
+	warnIBCClientRevision    = "W-IBC-004"
+	warnIBCChannelSequences  = "W-IBC-005"
+	warnIBCChannelState      = "W-IBC-006"
+	warnMintInflationBounds  = "W-MINT-001"
+	warnMintGoalBonded       = "W-MINT-002"
+	warnMintBlocksPerYear    = "W-MINT-003"
+	warnStakingProposer      = "W-STAKING-001"
+	warnStakingDemoted       = "W-STAKING-002"
+	warnStakingMaxEntries    = "W-STAKING-003"
+	warnStakingMatured       = "W-STAKING-004"
+	warnStakingLongString    = "W-STAKING-005"
+	warnStakingNonNFC        = "W-STAKING-006"
+)
+
+// warningCheck is the check reporting the warnings of a code, Repair the
+// migrate flag repairing what it finds if any.
+type warningCheck struct {
+	Code        string          `json:"code"`
+	Severity    warningSeverity `json:"severity"`
+	Description string          `json:"description"`
+	Repair      string          `json:"repair,omitempty"`
+}
+
+// warningChecks are the checks of every warning code, in code order. A code
+// is always reported with the severity listed here.
+var warningChecks = []warningCheck{
+	{warnAuthBlockedNotFound, severityLow, "A --blocked-addresses address has no account in the source", ""},
+	{warnAuthProtectedSkipped, severityLow, "A state change left a --protected-addresses address untouched", ""},
+	{warnAuthInvalidPubKey, severityHigh, "An account pubkey fails to parse or does not match its address", flagClearInvalidPubKeys},
+	{warnAuthSeqDecreased, severityHigh, "An account sequence is lower than in the source", ""},
+	{warnAuthPubKeyChanged, severityHigh, "An account pubkey differs from the source", ""},
+	{warnBankModuleAccount, severityHigh, "A module account balance differs from what its module genesis accounts for", flagSweepModuleDust},
+	{warnCrisisFeeDenom, severityMedium, "The crisis constant fee is in a denom missing from the bank supply", flagCrisisConstantFee},
+	{warnEvidenceUnknown, severityMedium, "An equivocation names no validator", flagDropStaleEvidence},
+	{warnEvidenceExpired, severityLow, "An equivocation is older than the evidence max age at genesis", ""},
+	{warnGenesisSmokeTestSize, severityLow, "The app state is large for --smoke-test", ""},
+	{warnGenesisModuleSize, severityMedium, "A module genesis is a large part of --max-output-size", ""},
+	{warnGovStaleVote, severityLow, "A vote carries no voting power", flagDropStaleVotes},
+	{warnGovTallyOutcome, severityMedium, "The projected outcome of an active proposal changed", ""},
+	{warnGovLongContent, severityLow, "The content of an active proposal is longer than the gov limits", flagTruncateLongStrings},
+	{warnGovDepositsBalance, severityHigh, "The gov module account balance differs from the deposits of the active proposals", flagTopUpGovAccount},
+	{warnGovNonNFC, severityMedium, "A proposal title or description is not in Unicode NFC", flagNormalizeUnicode},
+	{warnIBCClientExpired, severityHigh, "An IBC client expired before the genesis time", ""},
+	{warnIBCClientProofSpecs, severityHigh, "An IBC client lacks the standard proof specs and upgrade path", flagFixProofSpecs},
+	{warnIBCClientUnmapped, severityMedium, "An IBC client counterparty chain ID is not in the --remap-counterparty-chain-ids mapping", ""},
+	{warnIBCClientRevision, severityMedium, "A remapped IBC client keeps heights of another revision", ""},
+	{warnIBCChannelSequences, severityHigh, "An IBC channel next sequence is not above the sequences of its packets", flagRepairChannelSeqs},
+	{warnIBCChannelState, severityHigh, "An IBC channel keeps commitments of acknowledged packets or a state its connection does not allow", ""},
+	{warnMintInflationBounds, severityMedium, "The mint inflation is outside its bounds", flagMintInflation},
+	{warnMintGoalBonded, severityMedium, "The mint goal_bonded is outside (0, 1]", ""},
+	{warnMintBlocksPerYear, severityHigh, "The mint blocks_per_year does not match the block time", flagMintBlocksPerYear},
+	{warnStakingProposer, severityLow, "The replacement keys changed the first proposer", ""},
+	{warnStakingDemoted, severityMedium, "A validator was demoted or jailed by max_validators or --cap-validator-power", ""},
+	{warnStakingMaxEntries, severityMedium, "An unbonding or redelegation exceeds the max_entries of the staking params", ""},
+	{warnStakingMatured, severityMedium, "Unbonding or redelegation entries completed before the genesis time", flagCompleteMatured},
+	{warnStakingLongString, severityLow, "A validator description is longer than the staking limits", flagTruncateLongStrings},
+	{warnStakingNonNFC, severityMedium, "A validator description is not in Unicode NFC", flagNormalizeUnicode},
+}
+
+// migrationWarning is a finding of a migration check.
+type migrationWarning = genesis.Finding
+
+// warningCollector gathers the warnings of every migration check so they can
+// be reported together and, per code, turned into errors.
+type warningCollector struct {
+	warnings []migrationWarning
+	// observe, if set, is called with every warning as it is added
+	observe func(migrationWarning)
+}
+
+// Add registers a warning.
+func (c *warningCollector) Add(code string, severity warningSeverity, module, format string, args ...interface{}) {
+	warning := migrationWarning{
+		Code:     code,
+		Severity: severity,
+		Module:   module,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	c.warnings = append(c.warnings, warning)
+
+	if c.observe != nil {
+		c.observe(warning)
+	}
+}
+
+// Warnings returns the registered warnings in registration order.
+func (c *warningCollector) Warnings() []migrationWarning {
+	return c.warnings
+}
+
+// Print writes the warnings to w grouped by module, modules in alphabetical
+// order. Within a module the codes are in registration order and the warnings
+// of a code are sorted by message. Only the first maxExamples warnings of a
+// code are written, followed by the count of the others, all are written when
+// maxExamples is 0.
+func (c *warningCollector) Print(w io.Writer, maxExamples int) {
+	byModule := make(map[string]map[string][]migrationWarning)
+	codes := make(map[string][]string)
+	var modules []string
+	for _, warning := range c.warnings {
+		if _, ok := byModule[warning.Module]; !ok {
+			modules = append(modules, warning.Module)
+			byModule[warning.Module] = make(map[string][]migrationWarning)
+		}
+		if _, ok := byModule[warning.Module][warning.Code]; !ok {
+			codes[warning.Module] = append(codes[warning.Module], warning.Code)
+		}
+		byModule[warning.Module][warning.Code] = append(byModule[warning.Module][warning.Code], warning)
+	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		fmt.Fprintf(w, "%s:\n", module)
+		for _, code := range codes[module] {
+			warnings := byModule[module][code]
+			sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Message < warnings[j].Message })
+
+			examples := warnings
+			if maxExamples > 0 && len(examples) > maxExamples {
+				examples = examples[:maxExamples]
+			}
+			for _, warning := range examples {
+				fmt.Fprintf(w, "  %s [%s] %s\n", warning.Code, warning.Severity, warning.Message)
+			}
+			if more := len(warnings) - len(examples); more > 0 {
+				fmt.Fprintf(w, "  %s and %d more, %d in total, --%s lists all\n", code, more, len(warnings), flagWarningsReport)
+			}
+		}
+	}
+}
+
+// writeWarningsReport writes every warning as a JSON array to path.
+func writeWarningsReport(files outputFiles, path string, warnings []migrationWarning) error {
+	bz, err := json.MarshalIndent(append([]migrationWarning{}, warnings...), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return files.WriteFile(path, bz, outputFileMode)
+}
+
+// Matching returns the warnings whose code matches any of the patterns, which
+// use path.Match syntax, e.g. W-IBC-*.
+func (c *warningCollector) Matching(patterns []string) ([]migrationWarning, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid warning code pattern %q: %w", pattern, err)
+		}
+	}
+
+	var matching []migrationWarning
+	for _, warning := range c.warnings {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, warning.Code); ok {
+				matching = append(matching, warning)
+				break
+			}
+		}
+	}
+
+	return matching, nil
+}
@@ -0,0 +1,136 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testWarnings() *warningCollector {
+	warnings := &warningCollector{}
+	warnings.Add(warnMintBlocksPerYear, severityHigh, "mint", "blocks_per_year is off")
+	warnings.Add("W-IBC-001", severityMedium, "ibc", "client %s expired", "07-tendermint-0")
+	warnings.Add(warnMintGoalBonded, severityMedium, "mint", "goal_bonded is off")
+	warnings.Add("W-IBC-010", severityLow, "ibc", "escrow mismatch")
+
+	return warnings
+}
+
+func TestWarningCollectorMatching(t *testing.T) {
+	warnings := testWarnings()
+
+	codes := func(patterns ...string) []string {
+		matching, err := warnings.Matching(patterns)
+		require.NoError(t, err)
+
+		var codes []string
+		for _, warning := range matching {
+			codes = append(codes, warning.Code)
+		}
+		return codes
+	}
+
+	require.Equal(t, []string{warnMintBlocksPerYear, "W-IBC-001", warnMintGoalBonded, "W-IBC-010"}, codes("*"))
+	require.Equal(t, []string{"W-IBC-001", "W-IBC-010"}, codes("W-IBC-*"))
+	require.Equal(t, []string{"W-IBC-001"}, codes("W-IBC-00?"))
+	require.Equal(t, []string{warnMintGoalBonded}, codes(warnMintGoalBonded))
+	require.Equal(t, []string{warnMintBlocksPerYear, warnMintGoalBonded, "W-IBC-010"}, codes("W-MINT-*", "W-IBC-010"))
+	require.Equal(t, []string{"W-IBC-001"}, codes("W-IBC-001", "W-IBC-001"))
+	require.Empty(t, codes("W-SUPPLY-*"))
+	require.Empty(t, codes("W-IBC"))
+	require.Empty(t, codes())
+
+	_, err := warnings.Matching([]string{"W-["})
+	require.Error(t, err)
+}
+
+func TestWarningCollectorPrint(t *testing.T) {
+	var buf bytes.Buffer
+	testWarnings().Print(&buf, 10)
+
+	require.Equal(t, `ibc:
+  W-IBC-001 [medium] client 07-tendermint-0 expired
+  W-IBC-010 [low] escrow mismatch
+mint:
+  W-MINT-003 [high] blocks_per_year is off
+  W-MINT-002 [medium] goal_bonded is off
+`, buf.String())
+}
+
+func TestWarningCollectorPrintExamples(t *testing.T) {
+	const findings = 12000
+
+	warnings := &warningCollector{}
+	for i := findings; i > 0; i-- {
+		warnings.Add(warnBankModuleAccount, severityHigh, "bank", "balance %05d holds no coins", i)
+	}
+	warnings.Add(warnMintGoalBonded, severityMedium, "mint", "goal_bonded is off")
+
+	var buf bytes.Buffer
+	warnings.Print(&buf, 3)
+	require.Equal(t, `bank:
+  W-BANK-001 [high] balance 00001 holds no coins
+  W-BANK-001 [high] balance 00002 holds no coins
+  W-BANK-001 [high] balance 00003 holds no coins
+  W-BANK-001 and 11997 more, 12000 in total, --warnings-report lists all
+mint:
+  W-MINT-002 [medium] goal_bonded is off
+`, buf.String())
+
+	buf.Reset()
+	warnings.Print(&buf, 0)
+	require.Equal(t, findings+3, strings.Count(buf.String(), "\n"))
+
+	path := filepath.Join(t.TempDir(), "warnings.json")
+	require.NoError(t, writeWarningsReport(outputFiles{}, path, warnings.Warnings()))
+	bz, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var report []migrationWarning
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Len(t, report, findings+1)
+	require.Equal(t, warnings.Warnings(), report)
+}
+
+func TestMigrateWarningsReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warnings.json")
+
+	var stderr bytes.Buffer
+	_, err := executeMigrateTo(t, &stderr, "testdata/cosmoshub-2-genesis.json", "--legacy-source", "cosmoshub-2",
+		"--no-prop-29", "--chain-id", "cosmoshub-4", "--"+flagMaxWarnExamples, "1", "--"+flagWarningsReport, path)
+	require.NoError(t, err)
+
+	bz, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var report []migrationWarning
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.NotEmpty(t, report)
+	for _, warning := range report {
+		require.Contains(t, stderr.String(), warning.Code)
+	}
+}
+
+func TestWarningsAsErrorsFlag(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		patterns []string
+	}{
+		{nil, []string{}},
+		{[]string{"--warnings-as-errors"}, []string{"*"}},
+		{[]string{"--warnings-as-errors=W-IBC-*"}, []string{"W-IBC-*"}},
+		{[]string{"--warnings-as-errors=W-IBC-*,W-MINT-001"}, []string{"W-IBC-*", "W-MINT-001"}},
+	}
+
+	for _, tc := range testCases {
+		cmd := MigrateGenesisCmd()
+		require.NoError(t, cmd.Flags().Parse(tc.args))
+
+		patterns, err := cmd.Flags().GetStringSlice(flagWarningsAsErrors)
+		require.NoError(t, err)
+		require.Equal(t, tc.patterns, patterns, tc.args)
+	}
+}
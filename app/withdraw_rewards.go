@@ -0,0 +1,375 @@
+package gaia
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	flagWithdrawAllRewards = "withdraw-all-rewards"
+	flagRewardsReport      = "withdraw-rewards-report"
+)
+
+// validatorRewardsWithdrawal is what withdrawAllRewards paid out of the
+// outstanding rewards of a validator: the rewards of its delegations, its
+// commission and the dust left to the community pool.
+type validatorRewardsWithdrawal struct {
+	OperatorAddress string       `json:"operator_address"`
+	Delegations     int          `json:"delegations"`
+	Rewards         sdk.Coins    `json:"rewards"`
+	Commission      sdk.Coins    `json:"commission"`
+	Dust            sdk.DecCoins `json:"dust"`
+}
+
+// rewardsWithdrawalReport sums up what withdrawAllRewards paid, the
+// validators in the order of the staking genesis.
+type rewardsWithdrawalReport struct {
+	Rewards    sdk.Coins                    `json:"rewards"`
+	Commission sdk.Coins                    `json:"commission"`
+	Dust       sdk.DecCoins                 `json:"dust"`
+	Recipients int                          `json:"recipients"`
+	Validators []validatorRewardsWithdrawal `json:"validators"`
+}
+
+// withdrawAllRewards pays out every pending delegation reward and validator
+// commission of the distribution genesis of state to the withdraw addresses,
+// as withdrawing them all at the end of the last block would. The rewards of
+// a delegation are computed like the distribution keeper does, over the
+// reward periods and slash events since its starting info, the commission is
+// truncated. What the outstanding rewards of a validator hold beyond the
+// coins paid, the fractions of the base unit included, goes to the community
+// pool.
+//
+// Every validator then starts over with empty outstanding rewards and
+// commission, a single historical rewards period referenced by its current
+// rewards and delegations and no slash events. The coins paid move from the
+// distribution module account to the recipients, the supply is unchanged.
+func withdrawAllRewards(cdc codec.JSONMarshaler, state types.AppMap) (rewardsWithdrawalReport, error) {
+	report := rewardsWithdrawalReport{Rewards: sdk.NewCoins(), Commission: sdk.NewCoins(), Dust: sdk.NewDecCoins()}
+
+	var (
+		authGenesis         auth.GenesisState
+		bankGenesis         bank.GenesisState
+		stakingGenesis      staking.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	if err := cdc.UnmarshalJSON(state[auth.ModuleName], &authGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", auth.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[bank.ModuleName], &bankGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", bank.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[staking.ModuleName], &stakingGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", staking.ModuleName)
+	}
+	if err := cdc.UnmarshalJSON(state[distribution.ModuleName], &distributionGenesis); err != nil {
+		return report, errors.Wrapf(err, "failed to unmarshal %s genesis", distribution.ModuleName)
+	}
+
+	withdrawAddrs := make(map[string]string, len(distributionGenesis.DelegatorWithdrawInfos))
+	for _, info := range distributionGenesis.DelegatorWithdrawInfos {
+		withdrawAddrs[info.DelegatorAddress] = info.WithdrawAddress
+	}
+	withdrawAddr := func(delegator string) string {
+		if addr, ok := withdrawAddrs[delegator]; ok {
+			return addr
+		}
+		return delegator
+	}
+
+	historical := make(map[string]map[uint64]sdk.DecCoins)
+	for _, record := range distributionGenesis.ValidatorHistoricalRewards {
+		if historical[record.ValidatorAddress] == nil {
+			historical[record.ValidatorAddress] = make(map[uint64]sdk.DecCoins)
+		}
+		historical[record.ValidatorAddress][record.Period] = record.Rewards.CumulativeRewardRatio
+	}
+
+	current := make(map[string]distribution.ValidatorCurrentRewards, len(distributionGenesis.ValidatorCurrentRewards))
+	for _, record := range distributionGenesis.ValidatorCurrentRewards {
+		current[record.ValidatorAddress] = record.Rewards
+	}
+
+	outstanding := make(map[string]sdk.DecCoins, len(distributionGenesis.OutstandingRewards))
+	for _, record := range distributionGenesis.OutstandingRewards {
+		outstanding[record.ValidatorAddress] = record.OutstandingRewards
+	}
+
+	commissions := make(map[string]sdk.DecCoins, len(distributionGenesis.ValidatorAccumulatedCommissions))
+	for _, record := range distributionGenesis.ValidatorAccumulatedCommissions {
+		commissions[record.ValidatorAddress] = record.Accumulated.Commission
+	}
+
+	// the slash events of each validator, in the order they happened
+	slashes := make(map[string][]distribution.ValidatorSlashEventRecord)
+	for _, record := range distributionGenesis.ValidatorSlashEvents {
+		slashes[record.ValidatorAddress] = append(slashes[record.ValidatorAddress], record)
+	}
+	for _, events := range slashes {
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].Height != events[j].Height {
+				return events[i].Height < events[j].Height
+			}
+			return events[i].ValidatorSlashEvent.ValidatorPeriod < events[j].ValidatorSlashEvent.ValidatorPeriod
+		})
+	}
+
+	startingInfos := make(map[startingInfoKey]distribution.DelegatorStartingInfo, len(distributionGenesis.DelegatorStartingInfos))
+	for _, record := range distributionGenesis.DelegatorStartingInfos {
+		startingInfos[startingInfoKey{record.DelegatorAddress, record.ValidatorAddress}] = record.StartingInfo
+	}
+
+	delegations := make(map[string][]staking.Delegation)
+	for _, del := range stakingGenesis.Delegations {
+		delegations[del.ValidatorAddress] = append(delegations[del.ValidatorAddress], del)
+	}
+
+	paid := make(map[string]sdk.Coins)
+	pay := func(recipient string, coins sdk.Coins) {
+		if !coins.IsZero() {
+			paid[recipient] = paid[recipient].Add(coins...)
+		}
+	}
+	communityPool := distributionGenesis.FeePool.CommunityPool
+
+	var (
+		historicalRecords  []distribution.ValidatorHistoricalRewardsRecord
+		currentRecords     []distribution.ValidatorCurrentRewardsRecord
+		outstandingRecords []distribution.ValidatorOutstandingRewardsRecord
+		commissionRecords  []distribution.ValidatorAccumulatedCommissionRecord
+		startingRecords    []distribution.DelegatorStartingInfoRecord
+	)
+
+	for _, val := range stakingGenesis.Validators {
+		operator := val.OperatorAddress
+		valAddr, err := sdk.ValAddressFromBech32(operator)
+		if err != nil {
+			return report, err
+		}
+
+		cur, ok := current[operator]
+		if !ok {
+			return report, fmt.Errorf("validator %s has no current rewards", operator)
+		}
+		previous, ok := historical[operator][cur.Period-1]
+		if !ok {
+			return report, fmt.Errorf("validator %s has no historical rewards of period %d", operator, cur.Period-1)
+		}
+
+		// the current period ends, as the keeper increments it on a
+		// withdrawal, rewards of a validator without tokens are not shared
+		ending := previous
+		if !val.Tokens.IsZero() {
+			ending = previous.Add(cur.Rewards.QuoDecTruncate(val.Tokens.ToDec())...)
+		}
+		ratio := func(period uint64) (sdk.DecCoins, error) {
+			if period == cur.Period {
+				return ending, nil
+			}
+			r, ok := historical[operator][period]
+			if !ok {
+				return nil, fmt.Errorf("validator %s has no historical rewards of period %d", operator, period)
+			}
+			return r, nil
+		}
+		between := func(start, end uint64, stake sdk.Dec) (sdk.DecCoins, error) {
+			startRatio, err := ratio(start)
+			if err != nil {
+				return nil, err
+			}
+			endRatio, err := ratio(end)
+			if err != nil {
+				return nil, err
+			}
+			if start > end || endRatio.IsAnyNegative() || startRatio.IsAnyNegative() {
+				return nil, fmt.Errorf("validator %s has invalid historical rewards between periods %d and %d", operator, start, end)
+			}
+			return endRatio.Sub(startRatio).MulDecTruncate(stake), nil
+		}
+
+		withdrawal := validatorRewardsWithdrawal{OperatorAddress: operator, Rewards: sdk.NewCoins(), Commission: sdk.NewCoins()}
+		remaining := outstanding[operator]
+
+		// the commission first, as the zero height export withdraws it
+		commission, _ := commissions[operator].Intersect(remaining).TruncateDecimal()
+		remaining = remaining.Sub(sdk.NewDecCoinsFromCoins(commission...))
+		withdrawal.Commission = commission
+		pay(withdrawAddr(sdk.AccAddress(valAddr).String()), commission)
+
+		for _, del := range delegations[operator] {
+			info, ok := startingInfos[startingInfoKey{del.DelegatorAddress, operator}]
+			if !ok {
+				return report, fmt.Errorf("delegation of %s to %s has no starting info", del.DelegatorAddress, operator)
+			}
+
+			rewards := sdk.NewDecCoins()
+			start, stake := info.PreviousPeriod, info.Stake
+			for _, event := range slashes[operator] {
+				if event.Height < info.Height {
+					continue
+				}
+				if end := event.ValidatorSlashEvent.ValidatorPeriod; end > start {
+					earned, err := between(start, end, stake)
+					if err != nil {
+						return report, err
+					}
+					rewards = rewards.Add(earned...)
+					stake = stake.MulTruncate(sdk.OneDec().Sub(event.ValidatorSlashEvent.Fraction))
+					start = end
+				}
+			}
+
+			// the stake may exceed the current one by the rounding of the
+			// slashes, as the keeper tolerates
+			if currentStake := val.TokensFromShares(del.Shares); stake.GT(currentStake) {
+				marginOfErr := sdk.SmallestDec().MulInt64(3)
+				if stake.GT(currentStake.Add(marginOfErr)) {
+					return report, fmt.Errorf("delegation of %s to %s has a stake of %s above its current stake %s", del.DelegatorAddress, operator, stake, currentStake)
+				}
+				stake = currentStake
+			}
+
+			last, err := between(start, cur.Period, stake)
+			if err != nil {
+				return report, err
+			}
+			rewards = rewards.Add(last...)
+
+			coins, _ := rewards.Intersect(remaining).TruncateDecimal()
+			remaining = remaining.Sub(sdk.NewDecCoinsFromCoins(coins...))
+			withdrawal.Rewards = withdrawal.Rewards.Add(coins...)
+			pay(withdrawAddr(del.DelegatorAddress), coins)
+			withdrawal.Delegations++
+
+			startingRecords = append(startingRecords, distribution.DelegatorStartingInfoRecord{
+				DelegatorAddress: del.DelegatorAddress,
+				ValidatorAddress: operator,
+				StartingInfo:     distribution.NewDelegatorStartingInfo(0, val.TokensFromSharesTruncated(del.Shares), info.Height),
+			})
+		}
+
+		// what is left of the outstanding rewards is dust
+		withdrawal.Dust = remaining
+		communityPool = communityPool.Add(remaining...)
+
+		historicalRecords = append(historicalRecords, distribution.ValidatorHistoricalRewardsRecord{
+			ValidatorAddress: operator,
+			Period:           0,
+			Rewards:          distribution.NewValidatorHistoricalRewards(sdk.DecCoins{}, uint32(1+withdrawal.Delegations)),
+		})
+		currentRecords = append(currentRecords, distribution.ValidatorCurrentRewardsRecord{
+			ValidatorAddress: operator,
+			Rewards:          distribution.NewValidatorCurrentRewards(sdk.DecCoins{}, 1),
+		})
+		outstandingRecords = append(outstandingRecords, distribution.ValidatorOutstandingRewardsRecord{
+			ValidatorAddress:   operator,
+			OutstandingRewards: sdk.DecCoins{},
+		})
+		commissionRecords = append(commissionRecords, distribution.ValidatorAccumulatedCommissionRecord{
+			ValidatorAddress: operator,
+			Accumulated:      distribution.InitialValidatorAccumulatedCommission(),
+		})
+
+		report.Rewards = report.Rewards.Add(withdrawal.Rewards...)
+		report.Commission = report.Commission.Add(withdrawal.Commission...)
+		report.Dust = report.Dust.Add(withdrawal.Dust...)
+		report.Validators = append(report.Validators, withdrawal)
+	}
+
+	distributionGenesis.FeePool.CommunityPool = communityPool
+	distributionGenesis.ValidatorHistoricalRewards = historicalRecords
+	distributionGenesis.ValidatorCurrentRewards = currentRecords
+	distributionGenesis.OutstandingRewards = outstandingRecords
+	distributionGenesis.ValidatorAccumulatedCommissions = commissionRecords
+	distributionGenesis.DelegatorStartingInfos = startingRecords
+	distributionGenesis.ValidatorSlashEvents = []distribution.ValidatorSlashEventRecord{}
+	if err := distribution.ValidateGenesis(&distributionGenesis); err != nil {
+		return report, errors.Wrapf(err, "invalid %s genesis after withdrawing the rewards", distribution.ModuleName)
+	}
+
+	// the recipients without an account get one, as the bank keeper would
+	// create it on the transfer
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return report, err
+	}
+	hasAccount := make(map[string]bool, len(accounts))
+	var nextAccountNumber uint64
+	for _, acc := range accounts {
+		hasAccount[acc.GetAddress().String()] = true
+		if acc.GetAccountNumber() >= nextAccountNumber {
+			nextAccountNumber = acc.GetAccountNumber() + 1
+		}
+	}
+
+	recipients := make([]string, 0, len(paid))
+	for recipient := range paid {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+	report.Recipients = len(recipients)
+
+	created := false
+	for _, recipient := range recipients {
+		if hasAccount[recipient] {
+			continue
+		}
+
+		addr, err := sdk.AccAddressFromBech32(recipient)
+		if err != nil {
+			return report, errors.Wrapf(err, "invalid rewards recipient %s", recipient)
+		}
+		accounts = append(accounts, auth.NewBaseAccount(addr, nil, nextAccountNumber, 0))
+		nextAccountNumber++
+		created = true
+	}
+	if created {
+		if authGenesis.Accounts, err = auth.PackAccounts(accounts); err != nil {
+			return report, err
+		}
+		state[auth.ModuleName] = cdc.MustMarshalJSON(&authGenesis)
+	}
+
+	total := report.Rewards.Add(report.Commission...)
+	distributionAddr := auth.NewModuleAddress(distribution.ModuleName).String()
+	credited := make(map[string]bool, len(paid))
+	debited := total.IsZero()
+	for i, balance := range bankGenesis.Balances {
+		if balance.Address == distributionAddr {
+			debited = true
+			coins, hasNeg := balance.Coins.SafeSub(total)
+			if hasNeg {
+				return report, fmt.Errorf("distribution module account holds %s, less than the %s of rewards and commission withdrawn", balance.Coins, total)
+			}
+			bankGenesis.Balances[i].Coins = coins
+		}
+
+		if coins, ok := paid[balance.Address]; ok {
+			bankGenesis.Balances[i].Coins = bankGenesis.Balances[i].Coins.Add(coins...)
+			credited[balance.Address] = true
+		}
+	}
+	if !debited {
+		return report, fmt.Errorf("distribution module account holds nothing of the %s of rewards and commission withdrawn", total)
+	}
+	for _, recipient := range recipients {
+		if !credited[recipient] {
+			bankGenesis.Balances = append(bankGenesis.Balances, bank.Balance{Address: recipient, Coins: paid[recipient]})
+		}
+	}
+	bankGenesis.Balances = bank.SanitizeGenesisBalances(bankGenesis.Balances)
+
+	state[bank.ModuleName] = cdc.MustMarshalJSON(&bankGenesis)
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+	return report, nil
+}
@@ -0,0 +1,271 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distribution "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/cosmos/cosmos-sdk/x/genutil/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// exportRewardsGenesis runs genDoc for a block allocating rewards to every
+// validator over several periods, ended by withdrawals, a slash and a new
+// delegation, and exports it. It returns the export with the balances
+// withdrawing every commission and delegation reward with the keepers would
+// leave.
+func exportRewardsGenesis(t *testing.T, b *genesisbuilder.Builder, genDoc *tmtypes.GenesisDoc) (*tmtypes.GenesisDoc, types.AppMap, map[string]sdk.Coins) {
+	app := NewGaiaApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, map[int64]bool{}, t.TempDir(), 0, MakeEncodingConfig(), simapp.EmptyAppOptions{})
+
+	validators := make([]abci.ValidatorUpdate, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = tmtypes.TM2PB.NewValidatorUpdate(val.PubKey, val.Power)
+	}
+	app.InitChain(abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(genDoc.ConsensusParams),
+		Validators:      validators,
+		AppStateBytes:   genDoc.AppState,
+		InitialHeight:   genDoc.InitialHeight,
+	})
+
+	header := tmproto.Header{ChainID: genDoc.ChainID, Height: genDoc.InitialHeight, Time: genDoc.GenesisTime}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	ctx := app.BaseApp.NewContext(false, header)
+
+	allocate := func(amount int64) {
+		rewards := sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, amount), sdk.NewInt64Coin("ufoo", amount/3))
+		require.NoError(t, app.BankKeeper.MintCoins(ctx, minttypes.ModuleName, rewards))
+		require.NoError(t, app.BankKeeper.SendCoinsFromModuleToModule(ctx, minttypes.ModuleName, distribution.ModuleName, rewards))
+
+		// split unevenly between the validators, leaving fractions behind,
+		// the last getting the rest
+		vals := app.StakingKeeper.GetAllValidators(ctx)
+		left := sdk.NewDecCoinsFromCoins(rewards...)
+		for i, val := range vals {
+			share := left
+			if i < len(vals)-1 {
+				share = sdk.NewDecCoinsFromCoins(rewards...).QuoDec(sdk.NewDec(int64(i + 3)))
+			}
+			left = left.Sub(share)
+			app.DistrKeeper.AllocateTokensToValidator(ctx, val, share)
+		}
+	}
+
+	allocate(1000003)
+
+	// alice withdraws, ending a period of validator 0
+	_, err := app.DistrKeeper.WithdrawDelegationRewards(ctx, b.Address("alice"), b.ValidatorAddress(0))
+	require.NoError(t, err)
+	allocate(777777)
+
+	// validator 1 is slashed, its delegations earning less from then on
+	val, found := app.StakingKeeper.GetValidator(ctx, b.ValidatorAddress(1))
+	require.True(t, found)
+	consAddr, err := val.GetConsAddr()
+	require.NoError(t, err)
+	app.StakingKeeper.Slash(ctx, consAddr, ctx.BlockHeight(), val.ConsensusPower(), sdk.NewDecWithPrec(5, 2))
+	allocate(555555)
+
+	// bob delegates more to validator 0, with a withdraw address
+	require.NoError(t, app.DistrKeeper.SetWithdrawAddr(ctx, b.Address("bob"), b.Address("dave")))
+	val, found = app.StakingKeeper.GetValidator(ctx, b.ValidatorAddress(0))
+	require.True(t, found)
+	_, err = app.StakingKeeper.Delegate(ctx, b.Address("bob"), sdk.NewInt(1000), staking.Unbonded, val, true)
+	require.NoError(t, err)
+	allocate(333331)
+
+	app.EndBlock(abci.RequestEndBlock{Height: header.Height})
+	app.Commit()
+
+	exported, err := app.ExportAppStateAndValidators(false, nil)
+	require.NoError(t, err)
+	exportedDoc := &tmtypes.GenesisDoc{
+		GenesisTime:     genDoc.GenesisTime,
+		ChainID:         genDoc.ChainID,
+		InitialHeight:   exported.Height + 1,
+		ConsensusParams: genDoc.ConsensusParams,
+		Validators:      exported.Validators,
+		AppState:        exported.AppState,
+	}
+	require.NoError(t, exportedDoc.ValidateAndComplete())
+
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(exportedDoc.AppState, &state))
+
+	// withdraw everything with the keepers on top of the committed state, at
+	// the next height as the delegations of this one earn nothing before
+	header.Height++
+	ctx, _ = app.BaseApp.NewContext(true, header).CacheContext()
+	for _, val := range app.StakingKeeper.GetAllValidators(ctx) {
+		_, _ = app.DistrKeeper.WithdrawValidatorCommission(ctx, val.GetOperator())
+		for _, del := range app.StakingKeeper.GetValidatorDelegations(ctx, val.GetOperator()) {
+			_, err := app.DistrKeeper.WithdrawDelegationRewards(ctx, del.GetDelegatorAddr(), val.GetOperator())
+			require.NoError(t, err)
+		}
+	}
+
+	balances := make(map[string]sdk.Coins)
+	app.BankKeeper.IterateAllBalances(ctx, func(addr sdk.AccAddress, coin sdk.Coin) bool {
+		balances[addr.String()] = balances[addr.String()].Add(coin)
+		return false
+	})
+
+	return exportedDoc, state, balances
+}
+
+func TestWithdrawAllRewards(t *testing.T) {
+	b := genesisbuilder.New().
+		WithValidatorPowers(100, 50, 30).
+		WithAccount("alice", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 1000))).
+		WithAccount("bob", sdk.NewCoins(sdk.NewInt64Coin(genesisbuilder.BondDenom, 5000))).
+		WithDelegation("alice", 0, 40000000).
+		WithDelegation("bob", 0, 12345678).
+		WithDelegation("bob", 1, 7777777).
+		WithDelegation("carol", 1, 3333333).
+		WithDelegation("carol", 2, 1111111)
+	builtDoc, err := b.Build()
+	require.NoError(t, err)
+
+	genDoc, state, expected := exportRewardsGenesis(t, b, builtDoc)
+	cdc := MakeEncodingConfig().Marshaler
+
+	var (
+		bankBefore         bank.GenesisState
+		distributionBefore distribution.GenesisState
+	)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankBefore)
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionBefore)
+	require.NotEmpty(t, distributionBefore.ValidatorSlashEvents)
+	require.Greater(t, len(distributionBefore.ValidatorHistoricalRewards), len(distributionBefore.ValidatorCurrentRewards))
+
+	report, err := withdrawAllRewards(cdc, state)
+	require.NoError(t, err)
+	require.Len(t, report.Validators, 3)
+	require.False(t, report.Rewards.IsZero())
+	require.False(t, report.Commission.IsZero())
+
+	var (
+		bankGenesis         bank.GenesisState
+		distributionGenesis distribution.GenesisState
+	)
+	cdc.MustUnmarshalJSON(state[bank.ModuleName], &bankGenesis)
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+	require.NoError(t, distribution.ValidateGenesis(&distributionGenesis))
+	require.Equal(t, bankBefore.Supply, bankGenesis.Supply)
+
+	// the recipients got what the keepers would have paid them
+	balances := make(map[string]sdk.Coins)
+	total := sdk.NewCoins()
+	for _, balance := range bankGenesis.Balances {
+		balances[balance.Address] = balance.Coins
+		total = total.Add(balance.Coins...)
+	}
+	require.Equal(t, bankGenesis.Supply, total)
+	distributionAddr := auth.NewModuleAddress(distribution.ModuleName).String()
+	for addr, coins := range expected {
+		if addr != distributionAddr {
+			require.Equal(t, coins.String(), balances[addr].String(), addr)
+		}
+	}
+	require.False(t, balances[b.Address("dave").String()].IsZero())
+
+	// only the community pool is left, with the dust
+	require.Equal(t, distributionBefore.FeePool.CommunityPool.Add(report.Dust...), distributionGenesis.FeePool.CommunityPool)
+	pool, _ := distributionGenesis.FeePool.CommunityPool.TruncateDecimal()
+	require.Equal(t, pool, balances[distributionAddr])
+	require.Empty(t, distributionGenesis.ValidatorSlashEvents)
+	for _, record := range distributionGenesis.OutstandingRewards {
+		require.True(t, record.OutstandingRewards.IsZero())
+	}
+	for _, record := range distributionGenesis.ValidatorAccumulatedCommissions {
+		require.True(t, record.Accumulated.Commission.IsZero())
+	}
+	for _, record := range distributionGenesis.ValidatorHistoricalRewards {
+		require.Zero(t, record.Period)
+	}
+
+	// the accounts of the new recipients are created
+	var authGenesis auth.GenesisState
+	cdc.MustUnmarshalJSON(state[auth.ModuleName], &authGenesis)
+	accounts, err := auth.UnpackAccounts(authGenesis.Accounts)
+	require.NoError(t, err)
+	hasAccount := make(map[string]bool)
+	for _, acc := range accounts {
+		hasAccount[acc.GetAddress().String()] = true
+	}
+	require.True(t, hasAccount[b.Address("dave").String()])
+
+	genDoc.AppState, err = json.Marshal(state)
+	require.NoError(t, err)
+	require.NoError(t, SmokeTestGenesis(genDoc))
+
+	// withdrawing again pays nothing
+	again, err := withdrawAllRewards(cdc, state)
+	require.NoError(t, err)
+	require.True(t, again.Rewards.IsZero())
+	require.True(t, again.Commission.IsZero())
+	require.True(t, again.Dust.IsZero())
+}
+
+func TestWithdrawAllRewardsUnderfunded(t *testing.T) {
+	builtDoc, err := genesisbuilder.New().WithValidatorPowers(10).Build()
+	require.NoError(t, err)
+	_, state := exportTestGenesis(t, builtDoc)
+
+	cdc := MakeEncodingConfig().Marshaler
+	var distributionGenesis distribution.GenesisState
+	cdc.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+	distributionGenesis.ValidatorAccumulatedCommissions[0].Accumulated.Commission = sdk.NewDecCoins(sdk.NewInt64DecCoin(genesisbuilder.BondDenom, 10))
+	distributionGenesis.OutstandingRewards[0].OutstandingRewards = sdk.NewDecCoins(sdk.NewInt64DecCoin(genesisbuilder.BondDenom, 10))
+	state[distribution.ModuleName] = cdc.MustMarshalJSON(&distributionGenesis)
+
+	_, err = withdrawAllRewards(cdc, state)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "distribution module account")
+}
+
+func TestMigrateWithdrawAllRewards(t *testing.T) {
+	source := writeMutatedGenesis(t, func(_, _ map[string]interface{}) {})
+	reportPath := filepath.Join(t.TempDir(), "rewards.json")
+
+	var log bytes.Buffer
+	out, err := executeMigrateTo(t, &log, source, "--legacy-source", "cosmoshub-2", "--no-prop-29", "--chain-id", "cosmoshub-4",
+		"--"+flagWithdrawAllRewards, "--"+flagRewardsReport, reportPath)
+	require.NoError(t, err)
+	require.Contains(t, log.String(), "of dust to the community pool")
+
+	bz, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var report rewardsWithdrawalReport
+	require.NoError(t, json.Unmarshal(bz, &report))
+	require.Len(t, report.Validators, 1)
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(out)
+	require.NoError(t, err)
+	var state types.AppMap
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &state))
+
+	var distributionGenesis distribution.GenesisState
+	MakeEncodingConfig().Marshaler.MustUnmarshalJSON(state[distribution.ModuleName], &distributionGenesis)
+	require.Empty(t, distributionGenesis.ValidatorSlashEvents)
+	for _, record := range distributionGenesis.OutstandingRewards {
+		require.True(t, record.OutstandingRewards.IsZero())
+	}
+}
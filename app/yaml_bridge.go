@@ -0,0 +1,238 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Genesis file formats accepted by --input-format and --output-format.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// jsonToYAML converts a JSON document to YAML keeping object key order and
+// the literal text of every number, so yamlToJSON restores the same JSON.
+func jsonToYAML(bz []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+
+	node, err := jsonToYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON document")
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func jsonToYAMLNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := jsonToYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)}, value)
+			}
+
+			_, err := dec.Token()
+			return node, err
+
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				value, err := jsonToYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				node.Content = append(node.Content, value)
+			}
+
+			_, err := dec.Token()
+			return node, err
+		}
+
+		return nil, fmt.Errorf("unexpected JSON delimiter %s", v)
+
+	case string:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+		if strings.Contains(v, "\n") {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		return node, nil
+
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}, nil
+
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// yamlToJSON converts a YAML document to JSON. Integers keep their exact
+// value, numbers a float64 cannot hold without losing precision are converted
+// to strings so no consumer silently rounds them.
+func yamlToJSON(bz []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bz, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, fmt.Errorf("expected a single YAML document")
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAMLNodeJSON(&buf, doc.Content[0]); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeYAMLNodeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeYAMLNodeJSON(buf, node.Alias)
+
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: only scalar mapping keys can be converted to JSON", key.Line)
+			}
+
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, key.Value)
+			buf.WriteByte(':')
+
+			if err := writeYAMLNodeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, value := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeYAMLNodeJSON(buf, value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case yaml.ScalarNode:
+		return writeYAMLScalarJSON(buf, node)
+	}
+
+	return fmt.Errorf("line %d: unexpected YAML node", node.Line)
+}
+
+func writeYAMLScalarJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return err
+		}
+		buf.WriteString(strconv.FormatBool(b))
+
+	case "!!int":
+		i, ok := new(big.Int).SetString(strings.ReplaceAll(node.Value, "_", ""), 0)
+		if !ok {
+			return fmt.Errorf("line %d: invalid integer %s", node.Line, node.Value)
+		}
+		buf.WriteString(i.String())
+
+	case "!!float":
+		r, ok := new(big.Rat).SetString(node.Value)
+		if !ok {
+			return fmt.Errorf("line %d: float %s cannot be converted to JSON", node.Line, node.Value)
+		}
+
+		// a float is kept as a number when float64 parsing does not change
+		// its value beyond the shortest representation
+		f, _ := r.Float64()
+		shortest, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+		if shortest == nil || shortest.Cmp(r) != 0 {
+			writeJSONString(buf, node.Value)
+			return nil
+		}
+
+		if json.Valid([]byte(node.Value)) {
+			buf.WriteString(node.Value)
+		} else {
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+
+	default:
+		writeJSONString(buf, node.Value)
+	}
+
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	bz, _ := json.Marshal(s)
+	buf.Write(bz)
+}
@@ -0,0 +1,73 @@
+package gaia
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+)
+
+func sortJSON(t *testing.T, bz []byte) []byte {
+	sorted, err := canonicalJSON(bz, true)
+	require.NoError(t, err)
+
+	return sorted
+}
+
+func requireYAMLRoundTrip(t *testing.T, bz []byte) []byte {
+	sorted := sortJSON(t, bz)
+
+	yamlBz, err := jsonToYAML(sorted)
+	require.NoError(t, err)
+
+	jsonBz, err := yamlToJSON(yamlBz)
+	require.NoError(t, err)
+
+	require.Equal(t, string(sorted), string(sortJSON(t, jsonBz)))
+
+	return yamlBz
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	genDoc, err := testGenesisBuilder().Build()
+	require.NoError(t, err)
+
+	bz, err := tmjson.Marshal(genDoc)
+	require.NoError(t, err)
+	requireYAMLRoundTrip(t, bz)
+
+	yamlBz := requireYAMLRoundTrip(t, []byte(`{
+		"dec": "0.100000000000000000",
+		"negative_dec": "-123456789012345678.123456789012345678",
+		"uint64_max": 18446744073709551615,
+		"uint64_max_string": "18446744073709551615",
+		"int64_min": -9223372036854775808,
+		"float": 0.5,
+		"exponent": 1e-7,
+		"lookalikes": ["true", "null", "1e3", "0x10", "012", "~", "", "yes", " padded "],
+		"multiline": "line 1\nline 2",
+		"unicode": "⚛ atom <&>",
+		"empty": {"object": {}, "array": []},
+		"nested": [[1, 2], {"a": null, "b": false}]
+	}`))
+
+	require.Contains(t, string(yamlBz), `uint64_max: 18446744073709551615`)
+	require.Contains(t, string(yamlBz), `uint64_max_string: "18446744073709551615"`)
+	require.Contains(t, string(yamlBz), `dec: "0.100000000000000000"`)
+}
+
+func TestYAMLToJSONPrecision(t *testing.T) {
+	bz, err := yamlToJSON([]byte(`
+big: 123456789012345678901234567890
+hex: 0x10
+underscored: 1_000_000
+exact: 0.25
+inexact: 0.1000000000000000000001
+quoted: "42"
+`))
+	require.NoError(t, err)
+	require.Equal(t, `{"big":"123456789012345678901234567890","hex":16,"underscored":1000000,"exact":0.25,"inexact":"0.1000000000000000000001","quoted":"42"}`, string(bz))
+
+	_, err = yamlToJSON([]byte("? [a, b]\n: c\n"))
+	require.Error(t, err)
+}
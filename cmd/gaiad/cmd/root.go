@@ -23,6 +23,7 @@ import (
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"github.com/cosmos/cosmos-sdk/x/crisis"
 	genutilcli "github.com/cosmos/cosmos-sdk/x/genutil/client/cli"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 	tmcli "github.com/tendermint/tendermint/libs/cli"
@@ -31,6 +32,7 @@ import (
 
 	gaia "github.com/cosmos/gaia/v5/app"
 	"github.com/cosmos/gaia/v5/app/params"
+	rotationcli "github.com/cosmos/gaia/v5/x/rotation/client/cli"
 )
 
 // NewRootCmd creates a new root command for simd. It is called once in the
@@ -73,6 +75,7 @@ func initRootCmd(rootCmd *cobra.Command, encodingConfig params.EncodingConfig) {
 		genutilcli.InitCmd(gaia.ModuleBasics, gaia.DefaultNodeHome),
 		genutilcli.CollectGenTxsCmd(banktypes.GenesisBalancesIterator{}, gaia.DefaultNodeHome),
 		gaia.MigrateGenesisCmd(),
+		genesisCommand(),
 		genutilcli.GenTxCmd(gaia.ModuleBasics, encodingConfig.TxConfig, banktypes.GenesisBalancesIterator{}, gaia.DefaultNodeHome),
 		genutilcli.ValidateGenesisCmd(gaia.ModuleBasics),
 		AddGenesisAccountCmd(gaia.DefaultNodeHome),
@@ -119,6 +122,40 @@ func queryCommand() *cobra.Command {
 	return cmd
 }
 
+func genesisCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                        "genesis",
+		Short:                      "Genesis file subcommands",
+		DisableFlagParsing:         true,
+		SuggestionsMinimumDistance: 2,
+		RunE:                       client.ValidateCmd,
+	}
+
+	cmd.AddCommand(
+		gaia.GenesisValidateCmd(),
+		gaia.VerifyPublishedGenesisCmd(),
+		gaia.GenesisVerifyManifestCmd(),
+		gaia.GenesisPowerReportCmd(),
+		gaia.GenesisWatchlistDiffCmd(),
+		gaia.GenesisBisectCmd(),
+		gaia.GenesisSplitCmd(),
+		gaia.GenesisJoinCmd(),
+		gaia.GenesisSchemaCmd(),
+		gaia.GenesisReadinessCmd(),
+		gaia.GenesisLinkChainsCmd(),
+		gaia.GenesisCollectGenTxsOntoCmd(),
+		gaia.GenesisReproduceCmd(),
+		gaia.GenesisUpgradeInfoCmd(),
+		gaia.GenesisServeVerificationCmd(),
+		gaia.GenesisVerifyAgainstCmd(),
+		gaia.GenesisCounterpartyInstructionsCmd(),
+		gaia.GenesisGetCmd(),
+		gaia.GenesisSetCmd(),
+	)
+
+	return cmd
+}
+
 func txCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:                        "tx",
@@ -141,11 +178,22 @@ func txCommand() *cobra.Command {
 	)
 
 	gaia.ModuleBasics.AddTxCommands(cmd)
+	addStakingTxCommands(cmd)
 	cmd.PersistentFlags().String(flags.FlagChainID, "", "The network chain ID")
 
 	return cmd
 }
 
+// addStakingTxCommands adds the staking transactions of the gaia modules to
+// the staking tx command.
+func addStakingTxCommands(txCmd *cobra.Command) {
+	for _, cmd := range txCmd.Commands() {
+		if cmd.Name() == stakingtypes.ModuleName {
+			cmd.AddCommand(rotationcli.NewRotateConsKeyCmd())
+		}
+	}
+}
+
 // newApp is an AppCreator
 func newApp(logger log.Logger, db dbm.DB, traceStore io.Writer, appOpts servertypes.AppOptions) servertypes.Application {
 	var cache sdk.MultiStorePersistentCache
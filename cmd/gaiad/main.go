@@ -18,6 +18,9 @@ func main() {
 		case server.ErrorCode:
 			os.Exit(e.Code)
 
+		case *app.MigrationTimeoutError:
+			os.Exit(app.MigrationTimeoutExitCode)
+
 		default:
 			os.Exit(1)
 		}
@@ -3,16 +3,27 @@ module github.com/cosmos/gaia/v5
 go 1.16
 
 require (
+	github.com/confio/ics23/go v0.6.6
 	github.com/cosmos/cosmos-sdk v0.42.6
+	github.com/gogo/protobuf v1.3.3
 	github.com/gorilla/mux v1.8.0
 	github.com/gravity-devs/liquidity v1.2.9
+	github.com/grpc-ecosystem/grpc-gateway v1.16.0
+	github.com/pelletier/go-toml v1.8.1
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.10.0
 	github.com/rakyll/statik v0.1.7
+	github.com/regen-network/cosmos-proto v0.3.1
 	github.com/spf13/cast v1.3.1
 	github.com/spf13/cobra v1.1.3
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
 	github.com/tendermint/tendermint v0.34.11
 	github.com/tendermint/tm-db v0.6.4
+	golang.org/x/text v0.3.3
+	google.golang.org/grpc v1.37.0
+	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
 
 replace google.golang.org/grpc => google.golang.org/grpc v1.33.2
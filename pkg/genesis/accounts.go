@@ -0,0 +1,100 @@
+package genesis
+
+import (
+	"fmt"
+	"sort"
+
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+
+	"github.com/cosmos/gaia/v5/internal/modules"
+)
+
+// poolNames are the names of the gaia module accounts not named after a
+// module.
+var poolNames = []string{authtypes.FeeCollectorName, stakingtypes.BondedPoolName, stakingtypes.NotBondedPoolName}
+
+// ModuleAccountAddresses returns a finding for every module account of the
+// auth state whose address is not the one derived from its name, as tools
+// re-encoding or rewriting addresses may leave it, and for every other
+// account at the address of a module, which the module cannot use. The
+// balances left at such addresses are named, no module can spend them.
+func (d *Document) ModuleAccountAddresses() []Finding {
+	var authGenesis authtypes.GenesisState
+	if _, ok := d.state[authtypes.ModuleName]; !ok {
+		return nil
+	}
+	if err := d.Module(authtypes.ModuleName, &authGenesis); err != nil {
+		return nil
+	}
+
+	accounts, err := authtypes.UnpackAccounts(authGenesis.Accounts)
+	if err != nil {
+		return nil
+	}
+
+	balances := make(map[string]string)
+	var bankGenesis banktypes.GenesisState
+	if _, ok := d.state[banktypes.ModuleName]; ok && d.Module(banktypes.ModuleName, &bankGenesis) == nil {
+		for _, balance := range bankGenesis.Balances {
+			if !balance.Coins.IsZero() {
+				balances[balance.Address] = balance.Coins.String()
+			}
+		}
+	}
+	orphaned := func(addr string) string {
+		if coins, ok := balances[addr]; ok {
+			return fmt.Sprintf(", its balance of %s is orphaned", coins)
+		}
+		return ""
+	}
+
+	// moduleAddrs maps the addresses derived from the names of the module
+	// accounts, of the modules and of the pools to those names
+	moduleAddrs := make(map[string]string)
+	for name := range modules.Basics {
+		moduleAddrs[authtypes.NewModuleAddress(name).String()] = name
+	}
+	for _, name := range poolNames {
+		moduleAddrs[authtypes.NewModuleAddress(name).String()] = name
+	}
+
+	var findings []Finding
+	for _, acc := range accounts {
+		macc, ok := acc.(authtypes.ModuleAccountI)
+		if !ok {
+			continue
+		}
+
+		derived := authtypes.NewModuleAddress(macc.GetName()).String()
+		moduleAddrs[derived] = macc.GetName()
+		if addr := macc.GetAddress().String(); addr != derived {
+			findings = append(findings, Finding{
+				Code:     CodeModuleAccountAddress,
+				Severity: SeverityError,
+				Module:   authtypes.ModuleName,
+				Message:  fmt.Sprintf("module account %s has address %s, its name derives %s%s", macc.GetName(), addr, derived, orphaned(addr)),
+			})
+		}
+	}
+
+	for _, acc := range accounts {
+		if _, ok := acc.(authtypes.ModuleAccountI); ok {
+			continue
+		}
+
+		addr := acc.GetAddress().String()
+		if name, ok := moduleAddrs[addr]; ok {
+			findings = append(findings, Finding{
+				Code:     CodeModuleAddressAccount,
+				Severity: SeverityError,
+				Module:   authtypes.ModuleName,
+				Message:  fmt.Sprintf("account %s at the address of the %s module is not a module account%s", addr, name, orphaned(addr)),
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Message < findings[j].Message })
+	return findings
+}
@@ -0,0 +1,139 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+// bech32Suffixes are the suffixes of the main prefix of a chain giving the
+// prefixes of its validator, consensus and public key bech32 strings.
+var bech32Suffixes = []string{
+	"",
+	sdk.PrefixValidator + sdk.PrefixOperator,
+	sdk.PrefixValidator + sdk.PrefixConsensus,
+	sdk.PrefixPublic,
+	sdk.PrefixValidator + sdk.PrefixOperator + sdk.PrefixPublic,
+	sdk.PrefixValidator + sdk.PrefixConsensus + sdk.PrefixPublic,
+}
+
+// bech32Pattern matches the JSON strings of bech32 strings and
+// bech32WordPattern the bech32 strings of a message.
+var (
+	bech32Pattern     = regexp.MustCompile(`"[a-z]+1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]{6,}"`)
+	bech32WordPattern = regexp.MustCompile(`\b[a-z]+1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]{6,}\b`)
+)
+
+// WithBech32Prefix returns the document validated as the genesis of a chain
+// whose account addresses have the bech32 prefix instead of the one of the
+// codec, e.g. osmo, and whose validator, consensus and public key prefixes
+// derive from it as those of gaia do. Validate reports the bech32 strings of
+// the codec prefixes, which such a chain does not parse, and those of other
+// prefixes fail the module validations as they do for gaia.
+func (d *Document) WithBech32Prefix(prefix string) *Document {
+	doc := *d
+	doc.bech32Prefix = prefix
+	return &doc
+}
+
+// bech32Translation maps the prefixes of a chain to those of the codec and
+// back.
+type bech32Translation struct {
+	toCodec map[string]string
+	// translated maps the translated bech32 strings to the original ones.
+	translated map[string]string
+}
+
+// newBech32Translation returns the translation of the prefixes derived from
+// prefix to the codec ones, nil when they are the same.
+func newBech32Translation(prefix string) *bech32Translation {
+	config := sdk.GetConfig()
+	if prefix == "" || prefix == config.GetBech32AccountAddrPrefix() {
+		return nil
+	}
+
+	codecPrefixes := []string{
+		config.GetBech32AccountAddrPrefix(),
+		config.GetBech32ValidatorAddrPrefix(),
+		config.GetBech32ConsensusAddrPrefix(),
+		config.GetBech32AccountPubPrefix(),
+		config.GetBech32ValidatorPubPrefix(),
+		config.GetBech32ConsensusPubPrefix(),
+	}
+
+	t := &bech32Translation{toCodec: make(map[string]string), translated: make(map[string]string)}
+	for i, suffix := range bech32Suffixes {
+		t.toCodec[prefix+suffix] = codecPrefixes[i]
+	}
+
+	return t
+}
+
+// translateState returns state with the bech32 strings of the chain prefixes
+// re-encoded with the codec ones, and a finding for every module with bech32
+// strings of the codec prefixes.
+func (t *bech32Translation) translateState(state map[string]json.RawMessage) (map[string]json.RawMessage, []Finding) {
+	codecPrefixes := make(map[string]bool, len(t.toCodec))
+	for _, codecPrefix := range t.toCodec {
+		codecPrefixes[codecPrefix] = true
+	}
+
+	translated := make(map[string]json.RawMessage, len(state))
+	var findings []Finding
+	for name, bz := range state {
+		var mixed []string
+		translated[name] = bech32Pattern.ReplaceAllFunc(bz, func(match []byte) []byte {
+			s := string(match[1 : len(match)-1])
+			hrp, data, err := bech32.DecodeAndConvert(s)
+			if err != nil {
+				return match
+			}
+
+			if codecPrefixes[hrp] {
+				mixed = append(mixed, s)
+				return match
+			}
+
+			codecPrefix, ok := t.toCodec[hrp]
+			if !ok {
+				return match
+			}
+
+			codecString, err := bech32.ConvertAndEncode(codecPrefix, data)
+			if err != nil {
+				return match
+			}
+
+			t.translated[codecString] = s
+			return []byte(`"` + codecString + `"`)
+		})
+
+		if len(mixed) > 0 {
+			findings = append(findings, Finding{
+				Code:     CodeMixedBech32Prefix,
+				Severity: SeverityError,
+				Module:   name,
+				Message: fmt.Sprintf("%s state has %d bech32 strings of the %s prefixes the chain does not parse, e.g. %s",
+					name, len(mixed), sdk.GetConfig().GetBech32AccountAddrPrefix(), mixed[0]),
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Module < findings[j].Module })
+	return translated, findings
+}
+
+// translateBack returns message with the translated bech32 strings in it
+// replaced by the original ones.
+func (t *bech32Translation) translateBack(message string) string {
+	return bech32WordPattern.ReplaceAllStringFunc(message, func(match string) string {
+		if original, ok := t.translated[match]; ok {
+			return original
+		}
+		return match
+	})
+}
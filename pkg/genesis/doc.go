@@ -0,0 +1,27 @@
+/*
+Package genesis loads and validates gaia genesis files for tools that parse
+them outside of gaiad, such as explorers and wallets.
+
+Load decodes a genesis file into a Document, Document.Module unmarshals the
+state of one module into its genesis type and Document.Validate checks the
+state of every gaia module, returning Findings instead of stopping at the
+first error. The genesis subcommands of gaiad are built on this package.
+
+The exported API of this package follows semantic versioning with the gaia
+module: it only changes in a backwards compatible way within a major version.
+The genesis types it unmarshals into are those of the modules of that major
+version.
+
+Example:
+
+	doc, err := genesis.Load(f)
+	if err != nil {
+		return err
+	}
+
+	var bankGenesis banktypes.GenesisState
+	if err := doc.Module(banktypes.ModuleName, &bankGenesis); err != nil {
+		return err
+	}
+*/
+package genesis
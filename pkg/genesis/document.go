@@ -0,0 +1,98 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/internal/modules"
+)
+
+// Document is a loaded genesis file: its genesis doc and the state of every
+// app state module.
+type Document struct {
+	genDoc   *tmtypes.GenesisDoc
+	state    map[string]json.RawMessage
+	cdc      codec.JSONMarshaler
+	txConfig client.TxEncodingConfig
+	// bech32Prefix is the account address prefix Validate expects, the
+	// codec one when empty.
+	bech32Prefix string
+}
+
+// Load reads a genesis file from r and decodes its genesis doc and app state.
+// The genesis doc is validated as Tendermint does, the module states are only
+// decoded by Module and checked by Validate.
+func Load(r io.Reader) (*Document, error) {
+	bz, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read genesis")
+	}
+
+	genDoc, err := tmtypes.GenesisDocFromJSON(bz)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid genesis doc")
+	}
+
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(genDoc.AppState, &state); err != nil {
+		return nil, errors.Wrap(err, "invalid app state")
+	}
+
+	encodingConfig := modules.MakeEncodingConfig()
+
+	return &Document{
+		genDoc:   genDoc,
+		state:    state,
+		cdc:      encodingConfig.Marshaler,
+		txConfig: encodingConfig.TxConfig,
+	}, nil
+}
+
+// GenesisDoc returns the genesis doc of the document.
+func (d *Document) GenesisDoc() *tmtypes.GenesisDoc {
+	return d.genDoc
+}
+
+// Modules returns the sorted app state keys of the document, which may
+// include keys naming no gaia module.
+func (d *Document) Modules() []string {
+	names := make([]string, 0, len(d.state))
+	for name := range d.state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// RawModule returns the JSON state of the module name and whether the
+// document has one.
+func (d *Document) RawModule(name string) (json.RawMessage, bool) {
+	bz, ok := d.state[name]
+	return bz, ok
+}
+
+// Module unmarshals the state of the module name into out, the genesis state
+// type of the module, e.g. a *banktypes.GenesisState for "bank". Accounts and
+// other interface types are resolved against the gaia modules.
+func (d *Document) Module(name string, out proto.Message) error {
+	bz, ok := d.state[name]
+	if !ok {
+		return fmt.Errorf("genesis has no %s state", name)
+	}
+
+	if err := d.cdc.UnmarshalJSON(bz, out); err != nil {
+		return errors.Wrapf(err, "failed to JSON unmarshal %s genesis", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package genesis_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+
+	"github.com/cosmos/gaia/v5/pkg/genesis"
+)
+
+func ExampleLoad() {
+	f, err := os.Open("testdata/genesis.json")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	doc, err := genesis.Load(f)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(doc.GenesisDoc().ChainID)
+	fmt.Println(doc.Modules()[:3])
+	// Output:
+	// cosmoshub-4
+	// [auth bank capability]
+}
+
+func ExampleDocument_Module() {
+	f, err := os.Open("testdata/genesis.json")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	doc, err := genesis.Load(f)
+	if err != nil {
+		panic(err)
+	}
+
+	var bankGenesis banktypes.GenesisState
+	if err := doc.Module(banktypes.ModuleName, &bankGenesis); err != nil {
+		panic(err)
+	}
+	fmt.Println(bankGenesis.Supply)
+
+	var stakingGenesis stakingtypes.GenesisState
+	if err := doc.Module(stakingtypes.ModuleName, &stakingGenesis); err != nil {
+		panic(err)
+	}
+	fmt.Println(len(stakingGenesis.Validators), stakingGenesis.Params.BondDenom)
+
+	fmt.Println(doc.Module("wasm", &bankGenesis))
+	// Output:
+	// 10000000uatom
+	// 1 uatom
+	// genesis has no wasm state
+}
+
+func ExampleDocument_Validate() {
+	bz, err := ioutil.ReadFile("testdata/genesis.json")
+	if err != nil {
+		panic(err)
+	}
+
+	doc, err := genesis.Load(strings.NewReader(string(bz)))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(doc.Validate()))
+
+	// an empty bond denom and an app state key naming no module
+	edited := strings.Replace(string(bz), `"bond_denom": "uatom"`, `"bond_denom": ""`, 1)
+	edited = strings.Replace(edited, `"app_state": {`, `"app_state": {"wasm": {},`, 1)
+
+	doc, err = genesis.Load(strings.NewReader(edited))
+	if err != nil {
+		panic(err)
+	}
+	for _, finding := range doc.Validate() {
+		fmt.Printf("%s [%s] %s: %s\n", finding.Code, finding.Severity, finding.Module, finding.Message)
+	}
+	fmt.Println(len(genesis.Errors(doc.Validate())))
+	// Output:
+	// 0
+	// W-GENESIS-002 [low] wasm: app state key "wasm" names no gaia module and is ignored by InitChain
+	// E-GENESIS-001 [error] staking: bond denom cannot be blank
+	// 1
+}
+
+func ExampleParseMigrateResult() {
+	result, err := genesis.ParseMigrateResult(`migrate-result status=error stage=validators code=W-IBC-003 warnings=1 duration=2.5s error="1 warnings are treated as errors by --warnings-as-errors"`)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(result.OK(), result.Stage, result.Code, result.Duration)
+	// Output:
+	// false validators W-IBC-003 2.5s
+}
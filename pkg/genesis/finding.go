@@ -0,0 +1,157 @@
+package genesis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/gaia/v5/internal/modules"
+)
+
+// Severity classifies how likely a finding is to break the chain started
+// from a genesis.
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+	// SeverityError is the severity of the findings making InitChain fail.
+	SeverityError Severity = "error"
+)
+
+// Stable codes of the findings of Validate.
+const (
+	CodeInvalidModuleState   = "E-GENESIS-001"
+	CodeInvalidMigrationInfo = "E-GENESIS-002"
+	CodeValidatorKeyType     = "E-GENESIS-003"
+	CodeModuleAccountAddress = "E-GENESIS-004"
+	CodeModuleAddressAccount = "E-GENESIS-005"
+	CodeMixedBech32Prefix    = "E-GENESIS-006"
+	CodeUnknownAppStateKey   = "W-GENESIS-002"
+)
+
+// Finding is a finding of a genesis check, of Validate or of the checks of
+// gaiad migrate.
+type Finding struct {
+	Code     string   `json:"code" pattern:"^[EW]-[A-Z0-9]+-[0-9]{3}$" desc:"Stable code of the finding"`
+	Severity Severity `json:"severity" enum:"low,medium,high,error" desc:"How likely the finding is to break the chain"`
+	Module   string   `json:"module" desc:"Module or app state key the finding is about"`
+	Message  string   `json:"message" desc:"Human readable description of the finding"`
+}
+
+// Validate checks the consensus key types of the genesis validators, the
+// module account addresses, the migration info and the state of every gaia
+// module of the document, modules in alphabetical order, and reports app
+// state keys naming no module, which InitChain ignores. The document is valid
+// if no finding has SeverityError. A document WithBech32Prefix is validated
+// with its bech32 strings re-encoded with the codec prefixes, the findings
+// name the original ones.
+func (d *Document) Validate() []Finding {
+	t := newBech32Translation(d.bech32Prefix)
+	if t == nil {
+		return d.validate()
+	}
+
+	doc := *d
+	var findings []Finding
+	doc.state, findings = t.translateState(d.state)
+	doc.bech32Prefix = ""
+
+	for _, finding := range doc.validate() {
+		finding.Message = t.translateBack(finding.Message)
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// validate runs the checks of Validate on the document as it is.
+func (d *Document) validate() []Finding {
+	findings := append(ValidatorKeyTypes(d.genDoc), d.ModuleAccountAddresses()...)
+
+	if _, err := d.MigrationInfo(); err != nil {
+		findings = append(findings, Finding{
+			Code:     CodeInvalidMigrationInfo,
+			Severity: SeverityError,
+			Module:   MigrationInfoKey,
+			Message:  err.Error(),
+		})
+	}
+
+	for _, name := range d.Modules() {
+		if _, ok := modules.Basics[name]; !ok && name != MigrationInfoKey {
+			findings = append(findings, Finding{
+				Code:     CodeUnknownAppStateKey,
+				Severity: SeverityLow,
+				Module:   name,
+				Message:  fmt.Sprintf("app state key %q names no gaia module and is ignored by InitChain", name),
+			})
+		}
+	}
+
+	names := make([]string, 0, len(modules.Basics))
+	for name := range modules.Basics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := modules.Basics[name].ValidateGenesis(d.cdc, d.txConfig, d.state[name]); err != nil {
+			findings = append(findings, Finding{
+				Code:     CodeInvalidModuleState,
+				Severity: SeverityError,
+				Module:   name,
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	return findings
+}
+
+// ValidatorKeyTypes returns a finding for every tendermint genesis validator
+// of genDoc with a consensus key type its consensus params do not allow,
+// which Tendermint refuses to start with.
+func ValidatorKeyTypes(genDoc *tmtypes.GenesisDoc) []Finding {
+	params := genDoc.ConsensusParams
+	if params == nil {
+		params = tmtypes.DefaultConsensusParams()
+	}
+
+	var findings []Finding
+	for _, val := range genDoc.Validators {
+		if val.PubKey == nil || tmtypes.IsValidPubkeyType(params.Validator, val.PubKey.Type()) {
+			continue
+		}
+
+		name := val.Name
+		if name == "" {
+			name = val.Address.String()
+		}
+
+		findings = append(findings, Finding{
+			Code:     CodeValidatorKeyType,
+			Severity: SeverityError,
+			Module:   "validators",
+			Message: fmt.Sprintf("validator %s has a %s consensus key, the consensus params only allow %s",
+				name, val.PubKey.Type(), strings.Join(params.Validator.PubKeyTypes, ", ")),
+		})
+	}
+
+	return findings
+}
+
+// Errors returns the findings of SeverityError.
+func Errors(findings []Finding) []Finding {
+	var errs []Finding
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			errs = append(errs, finding)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,29 @@
+package genesis
+
+import "time"
+
+// Manifest describes a migrated genesis file so it can be verified once
+// published, as written by gaiad migrate --manifest.
+type Manifest struct {
+	ChainID       string    `json:"chain_id" desc:"Chain ID of the genesis"`
+	GenesisTime   time.Time `json:"genesis_time" desc:"Genesis time of the genesis"`
+	InitialHeight int64     `json:"initial_height" desc:"Initial height of the genesis"`
+	GenesisSHA256 string    `json:"genesis_sha256" pattern:"^[0-9a-f]{64}$" desc:"Hex encoded SHA-256 of the genesis file"`
+	GenesisSize   int64     `json:"genesis_size" desc:"Size of the genesis file in bytes"`
+
+	GaiaVersion         string          `json:"gaia_version,omitempty" desc:"Version of the gaiad binary that migrated the genesis"`
+	CosmosSDKVersion    string          `json:"cosmos_sdk_version,omitempty" desc:"Version of the cosmos-sdk the gaiad binary was built with"`
+	IBCVersion          string          `json:"ibc_version,omitempty" desc:"Version of the IBC module the gaiad binary was built with, the cosmos-sdk version while IBC is part of the SDK"`
+	GoVersion           string          `json:"go_version,omitempty" desc:"Go version the gaiad binary was built with"`
+	SourceGenesisSHA256 string          `json:"source_genesis_sha256,omitempty" pattern:"^[0-9a-f]{64}$" desc:"Hex encoded SHA-256 of the source genesis file"`
+	MigrateArgs         []string        `json:"migrate_args,omitempty" desc:"Flags of the migration that determine the genesis, re-run by genesis reproduce"`
+	MigrationData       []MigrationData `json:"migration_data,omitempty" desc:"SHA-256 of the data tables embedded in the gaiad binary, as printed by migrate show-data"`
+	MigrateResult       string          `json:"migrate_result,omitempty" desc:"Status line of the migration as written last to stderr, without the duration, parsed by ParseMigrateResult"`
+	StageChain          []StageLink     `json:"stage_chain,omitempty" desc:"Hash chain of the source genesis, the outputs of the migration stages and the migrated genesis, verified by VerifyStageChain and genesis reproduce"`
+}
+
+// MigrationData is the SHA-256 of a data table embedded in gaiad migrate.
+type MigrationData struct {
+	Name   string `json:"name" desc:"Name of the data table, printed by migrate show-data <name>"`
+	SHA256 string `json:"sha256" pattern:"^[0-9a-f]{64}$" desc:"Hex encoded SHA-256 of the JSON file of the data table"`
+}
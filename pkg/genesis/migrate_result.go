@@ -0,0 +1,167 @@
+package genesis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MigrateResultPrefix starts the status line gaiad migrate writes last to
+// stderr.
+const MigrateResultPrefix = "migrate-result"
+
+// Statuses of a MigrateResult.
+const (
+	MigrateStatusOK    = "ok"
+	MigrateStatusError = "error"
+)
+
+// MigrateResult is the outcome of a gaiad migrate run. Its String form is the
+// last line the run writes to stderr, e.g.
+//
+//	migrate-result status=ok output_sha256=7a5f…91a2 warnings=12 duration=93s
+//	migrate-result status=error stage=validators code=W-IBC-003 warnings=1 duration=2.5s error="1 warnings are treated as errors by --warnings-as-errors"
+//
+// and ParseMigrateResult parses it back.
+type MigrateResult struct {
+	Status string
+	// Stage and Module are where a failed migration stopped.
+	Stage  string
+	Module string
+	// Code lists the comma separated codes of the warnings a failed
+	// migration treated as errors.
+	Code         string
+	OutputSHA256 string
+	Warnings     int
+	// Duration is left out of the line when zero, as in the result of the
+	// manifest, which is the same for every run of a migration.
+	Duration time.Duration
+	Error    string
+}
+
+// OK returns whether the migration succeeded.
+func (r MigrateResult) OK() bool {
+	return r.Status == MigrateStatusOK
+}
+
+// String returns the status line of the result: the prefix and the
+// key=value fields in a fixed order, leaving out the empty optional ones.
+// Values with spaces, quotes or equal signs are quoted as Go strings.
+func (r MigrateResult) String() string {
+	var b strings.Builder
+	b.WriteString(MigrateResultPrefix)
+
+	field := func(key, value string) {
+		b.WriteString(" " + key + "=" + quoteResultValue(value))
+	}
+	optional := func(key, value string) {
+		if value != "" {
+			field(key, value)
+		}
+	}
+
+	field("status", r.Status)
+	optional("stage", r.Stage)
+	optional("module", r.Module)
+	optional("code", r.Code)
+	optional("output_sha256", r.OutputSHA256)
+	field("warnings", strconv.Itoa(r.Warnings))
+	if r.Duration != 0 {
+		field("duration", r.Duration.Round(time.Millisecond).String())
+	}
+	optional("error", r.Error)
+
+	return b.String()
+}
+
+// ParseMigrateResult parses a status line written by MigrateResult.String.
+// Unknown keys are ignored, so that lines of later versions still parse.
+func ParseMigrateResult(line string) (MigrateResult, error) {
+	var r MigrateResult
+
+	rest := strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(rest, MigrateResultPrefix+" ") {
+		return r, fmt.Errorf("not a %s line: %q", MigrateResultPrefix, line)
+	}
+	rest = rest[len(MigrateResultPrefix):]
+
+	seen := make(map[string]bool)
+	for rest = strings.TrimLeft(rest, " "); rest != ""; rest = strings.TrimLeft(rest, " ") {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 || strings.IndexByte(rest[:eq], ' ') >= 0 {
+			return r, fmt.Errorf("malformed field %q", rest)
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		value, n, err := unquoteResultValue(rest)
+		if err != nil {
+			return r, fmt.Errorf("malformed value of %s: %w", key, err)
+		}
+		rest = rest[n:]
+		seen[key] = true
+
+		switch key {
+		case "status":
+			r.Status = value
+		case "stage":
+			r.Stage = value
+		case "module":
+			r.Module = value
+		case "code":
+			r.Code = value
+		case "output_sha256":
+			r.OutputSHA256 = value
+		case "warnings":
+			if r.Warnings, err = strconv.Atoi(value); err != nil {
+				return r, fmt.Errorf("malformed warnings: %w", err)
+			}
+		case "duration":
+			if r.Duration, err = time.ParseDuration(value); err != nil {
+				return r, fmt.Errorf("malformed duration: %w", err)
+			}
+		case "error":
+			r.Error = value
+		}
+	}
+
+	if !seen["status"] {
+		return r, fmt.Errorf("%s line without a status: %q", MigrateResultPrefix, line)
+	}
+
+	return r, nil
+}
+
+func quoteResultValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \"=\\") || strconv.Quote(value) != `"`+value+`"` {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// unquoteResultValue returns the value at the start of s and the number of
+// bytes it takes, a quoted value up to its closing quote and any other up to
+// the next space.
+func unquoteResultValue(s string) (string, int, error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexByte(s, ' ')
+		if end < 0 {
+			end = len(s)
+		}
+		return s[:end], end, nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			return value, i + 1, err
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted value %s", s)
+}
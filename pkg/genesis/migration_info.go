@@ -0,0 +1,54 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// MigrationInfoKey is the app state key of the migration info written by
+// gaiad migrate --embed-migration-info. It names no module, so InitChain and
+// the module genesis validation skip it.
+const MigrationInfoKey = "migration_info"
+
+// MigrationInfo records which tool and which migrations produced a genesis.
+type MigrationInfo struct {
+	GaiaVersion         string   `json:"gaia_version"`
+	CosmosSDKVersion    string   `json:"cosmos_sdk_version"`
+	MigrationTarget     string   `json:"migration_target"`
+	Steps               []string `json:"steps"`
+	SourceGenesisSHA256 string   `json:"source_genesis_sha256"`
+}
+
+// String implements the fmt.Stringer interface.
+func (info MigrationInfo) String() string {
+	return fmt.Sprintf("gaia %s, cosmos-sdk %s, target %s, steps %v, source sha256 %s",
+		info.GaiaVersion, info.CosmosSDKVersion, info.MigrationTarget, info.Steps, info.SourceGenesisSHA256)
+}
+
+// ReadMigrationInfo returns the migration info of an app state, nil if it
+// has none.
+func ReadMigrationInfo(state map[string]json.RawMessage) (*MigrationInfo, error) {
+	bz, ok := state[MigrationInfoKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var info MigrationInfo
+	if err := json.Unmarshal(bz, &info); err != nil {
+		return nil, errors.Wrapf(err, "invalid app_state.%s", MigrationInfoKey)
+	}
+
+	if info.MigrationTarget == "" || info.SourceGenesisSHA256 == "" {
+		return nil, fmt.Errorf("invalid app_state.%s: missing migration target or source genesis hash", MigrationInfoKey)
+	}
+
+	return &info, nil
+}
+
+// MigrationInfo returns the migration info of the document, nil if it has
+// none.
+func (d *Document) MigrationInfo() (*MigrationInfo, error) {
+	return ReadMigrationInfo(d.state)
+}
@@ -0,0 +1,91 @@
+package genesis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// The first and last stages of the stage chain of a migration, the source
+// genesis JSON it read and the genesis file it wrote.
+const (
+	StageSource = "source"
+	StageOutput = "output"
+)
+
+// StageLink is a link of the hash chain of the stages of a migration, as
+// recorded in the manifest by gaiad migrate. The chain hash of a link hashes
+// the chain hash of the previous link with the stage and the SHA-256 of its
+// output, so changing the output of a stage breaks the chain from its link on.
+type StageLink struct {
+	Stage  string `json:"stage" desc:"Name of the migration stage, source for the source genesis JSON and output for the migrated genesis file"`
+	SHA256 string `json:"sha256" pattern:"^[0-9a-f]{64}$" desc:"Hex encoded SHA-256 of the output of the stage"`
+	Chain  string `json:"chain" pattern:"^[0-9a-f]{64}$" desc:"Hex encoded SHA-256 of the chain of the previous link, the stage and the SHA-256 of its output"`
+}
+
+// NewStageLink returns the link of stage, whose output has the hex encoded
+// SHA-256 sum, following previous. The first link follows the zero StageLink.
+func NewStageLink(previous StageLink, stage, sum string) StageLink {
+	return StageLink{Stage: stage, SHA256: sum, Chain: chainHash(previous.Chain, stage, sum)}
+}
+
+// chainHash returns the hex encoded chain hash of a link.
+func chainHash(previous, stage, sum string) string {
+	hash := sha256.New()
+	for _, part := range []string{previous, stage, sum} {
+		// length prefixed so the parts cannot run into each other
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// StageChainError is the first broken link of a stage chain, or the first
+// link of a chain differing from the one expected.
+type StageChainError struct {
+	// Index is the position of the link in the chain.
+	Index  int
+	Stage  string
+	Reason string
+}
+
+func (e *StageChainError) Error() string {
+	return fmt.Sprintf("stage chain broken at link %d, stage %s: %s", e.Index, e.Stage, e.Reason)
+}
+
+// VerifyStageChain checks every link of links hashes the previous one, the
+// first the zero StageLink, returning a *StageChainError for the first link
+// that does not.
+func VerifyStageChain(links []StageLink) error {
+	var previous StageLink
+	for i, link := range links {
+		if link.Chain != chainHash(previous.Chain, link.Stage, link.SHA256) {
+			return &StageChainError{Index: i, Stage: link.Stage, Reason: fmt.Sprintf("its chain %s does not hash the previous link and its output %s", link.Chain, link.SHA256)}
+		}
+
+		previous = link
+	}
+
+	return nil
+}
+
+// CompareStageChains compares the stage chain actual with the chain expected,
+// returning a *StageChainError for the first link that differs.
+func CompareStageChains(expected, actual []StageLink) error {
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			return &StageChainError{Index: i, Stage: expected[i].Stage, Reason: "missing"}
+		case i >= len(expected):
+			return &StageChainError{Index: i, Stage: actual[i].Stage, Reason: "not expected"}
+		case actual[i].Stage != expected[i].Stage:
+			return &StageChainError{Index: i, Stage: actual[i].Stage, Reason: fmt.Sprintf("expected stage %s", expected[i].Stage)}
+		case actual[i].SHA256 != expected[i].SHA256:
+			return &StageChainError{Index: i, Stage: actual[i].Stage, Reason: fmt.Sprintf("output %s, expected %s", actual[i].SHA256, expected[i].SHA256)}
+		case actual[i].Chain != expected[i].Chain:
+			return &StageChainError{Index: i, Stage: actual[i].Stage, Reason: fmt.Sprintf("chain %s, expected %s", actual[i].Chain, expected[i].Chain)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+#!/usr/bin/env bash
+
+set -eo pipefail
+
+# the SDK and third party proto files are read from the module cache
+go mod download github.com/cosmos/cosmos-sdk
+sdk_dir=$(go list -f '{{ .Dir }}' -m github.com/cosmos/cosmos-sdk)
+
+proto_dirs=$(find ./proto -path -prune -o -name '*.proto' -print0 | xargs -0 -n1 dirname | sort | uniq)
+for dir in $proto_dirs; do
+  buf protoc \
+    -I "proto" \
+    -I "${sdk_dir}/proto" \
+    -I "${sdk_dir}/third_party/proto" \
+    --gocosmos_out=plugins=interfacetype+grpc,\
+Mgoogle/protobuf/any.proto=github.com/cosmos/cosmos-sdk/codec/types:. \
+  $(find "${dir}" -maxdepth 1 -name '*.proto')
+done
+
+# move proto files to the right places
+cp -r github.com/cosmos/gaia/v5/* ./
+rm -rf github.com
@@ -0,0 +1,380 @@
+// Package testutil holds test harnesses running the gaia app beyond the unit
+// tests of its packages.
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/crisis"
+	"github.com/gogo/protobuf/proto"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cryptoenc "github.com/tendermint/tendermint/crypto/encoding"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	gaia "github.com/cosmos/gaia/v5/app"
+)
+
+// SoakConfig is the workload RunSoak replays. Every block delivers
+// MsgsPerBlock messages, each a bank send, a delegation or a gov vote drawn
+// by the weights, and every InvariantPeriod blocks all the invariants run.
+type SoakConfig struct {
+	Blocks          int
+	MsgsPerBlock    int
+	InvariantPeriod int
+	BlockTime       time.Duration
+	Seed            int64
+	// MaxAccounts bounds the accounts the workload draws from, the first
+	// holding the bond denom in address order.
+	MaxAccounts    int
+	SendWeight     int
+	DelegateWeight int
+	VoteWeight     int
+}
+
+// DefaultSoakConfig is the workload of the default CI run: mostly sends, 200
+// blocks of 6 seconds with the invariants every 20 blocks.
+func DefaultSoakConfig() SoakConfig {
+	return SoakConfig{
+		Blocks:          200,
+		MsgsPerBlock:    20,
+		InvariantPeriod: 20,
+		BlockTime:       6 * time.Second,
+		Seed:            1,
+		MaxAccounts:     1000,
+		SendWeight:      6,
+		DelegateWeight:  3,
+		VoteWeight:      1,
+	}
+}
+
+// SoakReport is the outcome of RunSoak. Delivered and Failed count the
+// messages by type URL, a message fails when the state rejects it, e.g. a
+// send above the balance or a vote on a closed proposal. The block times
+// exclude the invariant checks.
+type SoakReport struct {
+	StartHeight       int64
+	EndHeight         int64
+	Blocks            int
+	Delivered         map[string]int
+	Failed            map[string]int
+	Duration          time.Duration
+	MeanBlockTime     time.Duration
+	P50BlockTime      time.Duration
+	P99BlockTime      time.Duration
+	MaxBlockTime      time.Duration
+	InvariantChecks   int
+	InvariantDuration time.Duration
+	// InvariantFailures are the invariants found broken, each at the first
+	// height it was.
+	InvariantFailures []InvariantFailure
+}
+
+// InvariantFailure is an invariant broken at a height.
+type InvariantFailure struct {
+	Height  int64
+	Module  string
+	Route   string
+	Message string
+}
+
+// BlocksPerSecond is the block throughput of the run.
+func (r SoakReport) BlocksPerSecond() float64 {
+	return float64(r.Blocks) / r.Duration.Seconds()
+}
+
+// MsgsPerSecond is the message throughput of the run.
+func (r SoakReport) MsgsPerSecond() float64 {
+	total := 0
+	for _, n := range r.Delivered {
+		total += n
+	}
+	for _, n := range r.Failed {
+		total += n
+	}
+	return float64(total) / r.Duration.Seconds()
+}
+
+// String formats the report as a table of the messages followed by the
+// timings and invariant failures.
+func (r SoakReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "soak: %d blocks, heights %d to %d, in %s: %.1f blocks/s, %.1f msgs/s\n",
+		r.Blocks, r.StartHeight, r.EndHeight, r.Duration.Round(time.Millisecond), r.BlocksPerSecond(), r.MsgsPerSecond())
+	fmt.Fprintf(&b, "block time: mean %s, p50 %s, p99 %s, max %s\n", r.MeanBlockTime, r.P50BlockTime, r.P99BlockTime, r.MaxBlockTime)
+	fmt.Fprintf(&b, "invariants: %d checks in %s, %d broken\n", r.InvariantChecks, r.InvariantDuration.Round(time.Millisecond), len(r.InvariantFailures))
+
+	types := make([]string, 0, len(r.Delivered)+len(r.Failed))
+	for typeURL := range r.Delivered {
+		types = append(types, typeURL)
+	}
+	for typeURL := range r.Failed {
+		if _, ok := r.Delivered[typeURL]; !ok {
+			types = append(types, typeURL)
+		}
+	}
+	sort.Strings(types)
+
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MESSAGE\tDELIVERED\tFAILED")
+	for _, typeURL := range types {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", typeURL, r.Delivered[typeURL], r.Failed[typeURL])
+	}
+	tw.Flush() // nolint: errcheck
+
+	for _, failure := range r.InvariantFailures {
+		fmt.Fprintf(&b, "invariant %s/%s broken at height %d: %s\n", failure.Module, failure.Route, failure.Height, failure.Message)
+	}
+
+	return b.String()
+}
+
+// soakAppOptions skips the crisis module's genesis invariant assertion so
+// RunSoak reports broken invariants instead of panicking in InitChain.
+type soakAppOptions struct{}
+
+func (soakAppOptions) Get(key string) interface{} {
+	if key == crisis.FlagSkipGenesisInvariants {
+		return true
+	}
+
+	return nil
+}
+
+// soakChain is a single-node GaiaApp started from a genesis, driven block by
+// block as tendermint would with a validator set signing every block.
+type soakChain struct {
+	app        *gaia.GaiaApp
+	chainID    string
+	height     int64
+	time       time.Time
+	blockTime  time.Duration
+	validators map[string]abci.Validator
+	blocks     int
+}
+
+// RunSoak starts an in-memory GaiaApp from genDoc, typically a migrated
+// genesis, and replays the workload of cfg onto it. The messages are
+// delivered through the message service router of the app without
+// transactions: the keys of the accounts of a migrated genesis are unknown,
+// so signatures and fees are not checked. It returns an error if the chain
+// halts, e.g. a block panics; broken invariants are reported, the run goes on.
+func RunSoak(genDoc *tmtypes.GenesisDoc, cfg SoakConfig) (report *SoakReport, err error) {
+	homeDir, err := ioutil.TempDir("", "gaia-soak")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(homeDir)
+
+	app := gaia.NewGaiaApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, map[int64]bool{}, homeDir, 0, gaia.MakeEncodingConfig(), soakAppOptions{})
+	chain := &soakChain{app: app, chainID: genDoc.ChainID, time: genDoc.GenesisTime, blockTime: cfg.BlockTime, validators: make(map[string]abci.Validator)}
+
+	stage := "InitChain"
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("soak %s at height %d panicked: %v", stage, chain.height, r)
+		}
+	}()
+	if err := chain.initChain(genDoc); err != nil {
+		return nil, err
+	}
+
+	report = &SoakReport{StartHeight: chain.height + 1, Delivered: make(map[string]int), Failed: make(map[string]int)}
+	broken := make(map[string]bool)
+	checkInvariants := func(ctx sdk.Context) {
+		start := time.Now()
+		for _, route := range app.CrisisKeeper.Routes() {
+			if res, isBroken := route.Invar(ctx); isBroken && !broken[route.FullRoute()] {
+				broken[route.FullRoute()] = true
+				report.InvariantFailures = append(report.InvariantFailures, InvariantFailure{
+					Height: chain.height, Module: route.ModuleName, Route: route.Route, Message: strings.TrimSpace(res),
+				})
+			}
+		}
+		report.InvariantChecks++
+		report.InvariantDuration += time.Since(start)
+	}
+
+	// the genesis state is not committed before the first block
+	genesisCtx := app.NewContext(false, tmproto.Header{ChainID: chain.chainID, Height: chain.height, Time: chain.time})
+	stage = "invariants"
+	checkInvariants(genesisCtx)
+
+	workload := newSoakWorkload(genesisCtx, app, cfg)
+
+	blockTimes := make([]time.Duration, 0, cfg.Blocks)
+	run := time.Now()
+	for i := 0; i < cfg.Blocks; i++ {
+		start := time.Now()
+		stage = "block"
+		ctx := chain.beginBlock()
+		for j := 0; j < cfg.MsgsPerBlock; j++ {
+			msg := workload.next()
+			if msg == nil {
+				continue
+			}
+
+			typeURL := "/" + proto.MessageName(msg)
+			if workload.deliver(ctx, msg) {
+				report.Delivered[typeURL]++
+			} else {
+				report.Failed[typeURL]++
+			}
+		}
+		chain.endBlock()
+		blockTimes = append(blockTimes, time.Since(start))
+
+		if cfg.InvariantPeriod > 0 && (i+1)%cfg.InvariantPeriod == 0 {
+			stage = "invariants"
+			checkInvariants(app.NewContext(true, tmproto.Header{ChainID: chain.chainID, Height: chain.height, Time: chain.time}))
+		}
+	}
+	report.Duration = time.Since(run)
+
+	report.Blocks, report.EndHeight = len(blockTimes), chain.height
+	if len(blockTimes) > 0 {
+		var total time.Duration
+		for _, d := range blockTimes {
+			total += d
+		}
+		sort.Slice(blockTimes, func(i, j int) bool { return blockTimes[i] < blockTimes[j] })
+
+		report.MeanBlockTime = total / time.Duration(len(blockTimes))
+		report.P50BlockTime = blockTimes[len(blockTimes)/2]
+		report.P99BlockTime = blockTimes[len(blockTimes)*99/100]
+		report.MaxBlockTime = blockTimes[len(blockTimes)-1]
+	}
+
+	return report, nil
+}
+
+// initChain runs InitChain with genDoc and records the validator set it
+// starts with.
+func (c *soakChain) initChain(genDoc *tmtypes.GenesisDoc) error {
+	validators := make([]abci.ValidatorUpdate, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = tmtypes.TM2PB.NewValidatorUpdate(val.PubKey, val.Power)
+	}
+
+	consensusParams := genDoc.ConsensusParams
+	if consensusParams == nil {
+		consensusParams = tmtypes.DefaultConsensusParams()
+	}
+
+	c.height = genDoc.InitialHeight
+	if c.height == 0 {
+		c.height = 1
+	}
+
+	appState, err := withDefaultGenesis(genDoc.AppState)
+	if err != nil {
+		return err
+	}
+
+	res := c.app.InitChain(abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(consensusParams),
+		Validators:      validators,
+		AppStateBytes:   appState,
+		InitialHeight:   c.height,
+	})
+	if len(res.Validators) > 0 {
+		validators = res.Validators
+	}
+	if err := c.updateValidators(validators); err != nil {
+		return err
+	}
+	if len(c.validators) == 0 {
+		return fmt.Errorf("the genesis has no validators")
+	}
+
+	// the first block is at the initial height
+	c.height--
+	return nil
+}
+
+// withDefaultGenesis returns appState with the default genesis of the modules
+// it has none of. The migration leaves out the modules new to the chain, e.g.
+// liquidity, which the upgrade initializes, and InitChain skips them, leaving
+// their params unset for the first block to panic on.
+func withDefaultGenesis(appState json.RawMessage) (json.RawMessage, error) {
+	var state gaia.GenesisState
+	if err := json.Unmarshal(appState, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode the app state: %w", err)
+	}
+
+	for module, bz := range gaia.NewDefaultGenesisState() {
+		if _, ok := state[module]; !ok {
+			state[module] = bz
+		}
+	}
+
+	return json.Marshal(state)
+}
+
+// updateValidators applies the validator updates of InitChain or EndBlock.
+func (c *soakChain) updateValidators(updates []abci.ValidatorUpdate) error {
+	for _, update := range updates {
+		pubKey, err := cryptoenc.PubKeyFromProto(update.PubKey)
+		if err != nil {
+			return err
+		}
+
+		address := string(pubKey.Address())
+		if update.Power == 0 {
+			delete(c.validators, address)
+			continue
+		}
+		c.validators[address] = abci.Validator{Address: pubKey.Address(), Power: update.Power}
+	}
+
+	return nil
+}
+
+// beginBlock starts the next block, proposed by the validators in turn with
+// every validator signing the previous one, and returns the context to
+// deliver its messages with.
+func (c *soakChain) beginBlock() sdk.Context {
+	addresses := make([]string, 0, len(c.validators))
+	for address := range c.validators {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	// the first block is at the genesis time and has no previous one
+	var lastCommit abci.LastCommitInfo
+	if c.blocks > 0 {
+		for _, address := range addresses {
+			lastCommit.Votes = append(lastCommit.Votes, abci.VoteInfo{Validator: c.validators[address], SignedLastBlock: true})
+		}
+		c.time = c.time.Add(c.blockTime)
+	}
+	c.height++
+	proposer := c.validators[addresses[c.blocks%len(addresses)]].Address
+	c.blocks++
+
+	header := tmproto.Header{ChainID: c.chainID, Height: c.height, Time: c.time, ProposerAddress: proposer}
+	c.app.BeginBlock(abci.RequestBeginBlock{Header: header, LastCommitInfo: lastCommit})
+
+	return c.app.NewContext(false, header)
+}
+
+// endBlock ends the block and commits it.
+func (c *soakChain) endBlock() {
+	res := c.app.EndBlock(abci.RequestEndBlock{Height: c.height})
+	if err := c.updateValidators(res.ValidatorUpdates); err != nil {
+		panic(err)
+	}
+	c.app.Commit()
+}
@@ -0,0 +1,87 @@
+package testutil
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// soakFixture is the migrated genesis the soak test runs by default.
+const soakFixture = "../app/testdata/compat/cosmoshub-3/vesting-account/migrated.golden"
+
+// soakEnvInt returns the integer of the environment variable key, def if it
+// is not set.
+func soakEnvInt(t *testing.T, key string, def int) int {
+	s, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.Atoi(s)
+	require.NoError(t, err, key)
+	return n
+}
+
+// lowerMinDeposit sets the min deposit of the gov genesis of genDoc.
+func lowerMinDeposit(t *testing.T, genDoc *tmtypes.GenesisDoc, minDeposit sdk.Coins) {
+	var appState map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(genDoc.AppState, &appState))
+
+	var govGenesis map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(appState[gov.ModuleName], &govGenesis))
+	var depositParams map[string]interface{}
+	require.NoError(t, json.Unmarshal(govGenesis["deposit_params"], &depositParams))
+	depositParams["min_deposit"] = minDeposit
+
+	var err error
+	govGenesis["deposit_params"], err = json.Marshal(depositParams)
+	require.NoError(t, err)
+	appState[gov.ModuleName], err = json.Marshal(govGenesis)
+	require.NoError(t, err)
+	genDoc.AppState, err = json.Marshal(appState)
+	require.NoError(t, err)
+}
+
+// TestSoak runs the soak harness on the fixture genesis or, with
+// GAIA_SOAK_GENESIS, on a real migrated one. GAIA_SOAK_BLOCKS,
+// GAIA_SOAK_MSGS_PER_BLOCK and GAIA_SOAK_INVARIANT_PERIOD override the
+// defaults of the run.
+func TestSoak(t *testing.T) {
+	path := os.Getenv("GAIA_SOAK_GENESIS")
+	if path == "" {
+		path = soakFixture
+	}
+	genDoc, err := tmtypes.GenesisDocFromFile(path)
+	require.NoError(t, err)
+	if path == soakFixture {
+		// no fixture account holds the 512atom deposit to submit the
+		// proposal the votes need
+		lowerMinDeposit(t, genDoc, sdk.NewCoins(sdk.NewInt64Coin("uatom", 10000000)))
+	}
+
+	cfg := DefaultSoakConfig()
+	cfg.Blocks = soakEnvInt(t, "GAIA_SOAK_BLOCKS", cfg.Blocks)
+	cfg.MsgsPerBlock = soakEnvInt(t, "GAIA_SOAK_MSGS_PER_BLOCK", cfg.MsgsPerBlock)
+	cfg.InvariantPeriod = soakEnvInt(t, "GAIA_SOAK_INVARIANT_PERIOD", cfg.InvariantPeriod)
+	if testing.Short() && os.Getenv("GAIA_SOAK_BLOCKS") == "" {
+		cfg.Blocks = 20
+	}
+
+	report, err := RunSoak(genDoc, cfg)
+	require.NoError(t, err)
+	t.Log("\n" + report.String())
+
+	require.Empty(t, report.InvariantFailures)
+	require.Equal(t, cfg.Blocks, report.Blocks)
+	require.Equal(t, report.StartHeight+int64(cfg.Blocks)-1, report.EndHeight)
+	require.NotEmpty(t, report.Delivered)
+	if path == soakFixture {
+		require.Positive(t, report.Delivered["/cosmos.gov.v1beta1.MsgVote"])
+	}
+}
@@ -0,0 +1,158 @@
+package testutil
+
+import (
+	"fmt"
+	"math/rand"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	gov "github.com/cosmos/cosmos-sdk/x/gov/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/gogo/protobuf/proto"
+
+	gaia "github.com/cosmos/gaia/v5/app"
+)
+
+// soakVoteOptions are the options the votes of the workload draw from.
+var soakVoteOptions = []gov.VoteOption{gov.OptionYes, gov.OptionAbstain, gov.OptionNo, gov.OptionNoWithVeto}
+
+// soakWorkload draws the messages of a soak run from the accounts, validators
+// and voting proposals of the chain.
+type soakWorkload struct {
+	app        *gaia.GaiaApp
+	cfg        SoakConfig
+	rand       *rand.Rand
+	denom      string
+	accounts   []sdk.AccAddress
+	validators []sdk.ValAddress
+	proposals  []uint64
+	// propose is the proposal submitted first when none is voting, for the
+	// votes to have one.
+	propose sdk.Msg
+}
+
+// newSoakWorkload returns the workload of cfg on the state of ctx: the first
+// MaxAccounts accounts holding the bond denom, but the module accounts, the
+// validators not jailed and the proposals in voting period.
+func newSoakWorkload(ctx sdk.Context, app *gaia.GaiaApp, cfg SoakConfig) *soakWorkload {
+	w := &soakWorkload{app: app, cfg: cfg, rand: rand.New(rand.NewSource(cfg.Seed)), denom: app.StakingKeeper.BondDenom(ctx)}
+
+	modules := app.ModuleAccountAddrs()
+	app.BankKeeper.IterateAllBalances(ctx, func(addr sdk.AccAddress, coin sdk.Coin) bool {
+		if coin.Denom == w.denom && coin.IsPositive() && !modules[addr.String()] {
+			w.accounts = append(w.accounts, addr)
+		}
+		return cfg.MaxAccounts > 0 && len(w.accounts) >= cfg.MaxAccounts
+	})
+
+	for _, val := range app.StakingKeeper.GetAllValidators(ctx) {
+		if !val.IsJailed() {
+			w.validators = append(w.validators, val.GetOperator())
+		}
+	}
+
+	for _, proposal := range app.GovKeeper.GetProposals(ctx) {
+		if proposal.Status == gov.StatusVotingPeriod {
+			w.proposals = append(w.proposals, proposal.ProposalId)
+		}
+	}
+
+	if len(w.proposals) == 0 && cfg.VoteWeight > 0 {
+		minDeposit := app.GovKeeper.GetDepositParams(ctx).MinDeposit
+		for _, addr := range w.accounts {
+			if !app.BankKeeper.SpendableCoins(ctx, addr).IsAllGTE(minDeposit) {
+				continue
+			}
+
+			msg, err := gov.NewMsgSubmitProposal(gov.NewTextProposal("Soak test", "The proposal the soak test votes on"), minDeposit, addr)
+			if err == nil {
+				w.propose = msg
+			}
+			break
+		}
+	}
+
+	return w
+}
+
+// next returns the next message of the workload, nil if the chain has
+// nothing to draw it from.
+func (w *soakWorkload) next() sdk.Msg {
+	if w.propose != nil {
+		msg := w.propose
+		w.propose = nil
+		return msg
+	}
+
+	total := w.cfg.SendWeight + w.cfg.DelegateWeight + w.cfg.VoteWeight
+	if len(w.accounts) == 0 || total <= 0 {
+		return nil
+	}
+
+	from := w.accounts[w.rand.Intn(len(w.accounts))]
+	amount := sdk.NewInt64Coin(w.denom, 1+w.rand.Int63n(1000))
+
+	switch r := w.rand.Intn(total); {
+	case r < w.cfg.SendWeight:
+		return bank.NewMsgSend(from, w.accounts[w.rand.Intn(len(w.accounts))], sdk.NewCoins(amount))
+	case r < w.cfg.SendWeight+w.cfg.DelegateWeight:
+		if len(w.validators) == 0 {
+			return nil
+		}
+		return staking.NewMsgDelegate(from, w.validators[w.rand.Intn(len(w.validators))], amount)
+	default:
+		if len(w.proposals) == 0 {
+			return nil
+		}
+		return gov.NewMsgVote(from, w.proposals[w.rand.Intn(len(w.proposals))], soakVoteOptions[w.rand.Intn(len(soakVoteOptions))])
+	}
+}
+
+// deliver runs msg through its handler of the message service router of the
+// app, as a transaction of it would, and reports whether it succeeded. The
+// state changes of a failed message are discarded, and a handler panicking,
+// as a send above the balance does, fails it as it fails the transaction.
+func (w *soakWorkload) deliver(ctx sdk.Context, msg sdk.Msg) (ok bool) {
+	handler := w.app.MsgServiceRouter().Handler(soakServiceMethod(msg))
+	if handler == nil {
+		panic(fmt.Sprintf("no handler of %T", msg))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	cacheCtx, write := ctx.CacheContext()
+	res, err := handler(cacheCtx, msg.(sdk.MsgRequest))
+	if err != nil {
+		return false
+	}
+	write()
+
+	if _, ok := msg.(*gov.MsgSubmitProposal); ok {
+		var submitted gov.MsgSubmitProposalResponse
+		if err := proto.Unmarshal(res.Data, &submitted); err == nil {
+			w.proposals = append(w.proposals, submitted.ProposalId)
+		}
+	}
+
+	return true
+}
+
+// soakServiceMethod returns the method of the Msg service handling msg.
+func soakServiceMethod(msg sdk.Msg) string {
+	switch msg.(type) {
+	case *bank.MsgSend:
+		return "/cosmos.bank.v1beta1.Msg/Send"
+	case *staking.MsgDelegate:
+		return "/cosmos.staking.v1beta1.Msg/Delegate"
+	case *gov.MsgVote:
+		return "/cosmos.gov.v1beta1.Msg/Vote"
+	case *gov.MsgSubmitProposal:
+		return "/cosmos.gov.v1beta1.Msg/SubmitProposal"
+	default:
+		panic(fmt.Sprintf("no service method of %T", msg))
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// NewRotateConsKeyCmd returns a CLI command handler for replacing the
+// consensus pubkey of the validator operated by the --from account.
+func NewRotateConsKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-cons-key [validator-conspub]",
+		Short: "Replace the consensus pubkey of your validator",
+		Long: strings.TrimSpace(
+			fmt.Sprintf(`Replace the consensus pubkey of the validator operated by the --from account.
+The new pubkey signs blocks from two blocks after the rotation, start the node with its
+key in time. The old pubkey stays attributed to the validator: evidence of its double
+signs is still slashed. A validator rotates once per unbonding period at most, and
+never to a pubkey another validator used.
+
+The transaction can be generated offline with --generate-only and signed with the
+operator key elsewhere.
+
+Example:
+$ %s tx staking rotate-cons-key $(%s tendermint show-validator) --from mykey
+`,
+				version.AppName, version.AppName,
+			),
+		),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clientCtx, err := client.GetClientTxContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			pk, err := sdk.GetPubKeyFromBech32(sdk.Bech32PubKeyTypeConsPub, args[0])
+			if err != nil {
+				return err
+			}
+
+			msg, err := types.NewMsgRotateConsKey(sdk.ValAddress(clientCtx.GetFromAddress()), pk)
+			if err != nil {
+				return err
+			}
+
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+
+			return tx.GenerateOrBroadcastTxCLI(clientCtx, cmd.Flags(), msg)
+		},
+	}
+
+	flags.AddTxFlagsToCmd(cmd)
+
+	return cmd
+}
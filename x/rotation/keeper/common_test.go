@@ -0,0 +1,94 @@
+package keeper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	gaia "github.com/cosmos/gaia/v5/app"
+)
+
+// testChain drives a gaia app block by block.
+type testChain struct {
+	t       *testing.T
+	app     *gaia.GaiaApp
+	genDoc  *tmtypes.GenesisDoc
+	header  tmproto.Header
+	updates []abci.ValidatorUpdate
+}
+
+// newTestChain starts a gaia app from genDoc, ready to run its first block.
+func newTestChain(t *testing.T, genDoc *tmtypes.GenesisDoc) *testChain {
+	app := gaia.NewGaiaApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, map[int64]bool{}, t.TempDir(), 0, gaia.MakeEncodingConfig(), simapp.EmptyAppOptions{})
+
+	validators := make([]abci.ValidatorUpdate, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = tmtypes.TM2PB.NewValidatorUpdate(val.PubKey, val.Power)
+	}
+
+	app.InitChain(abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: tmtypes.TM2PB.ConsensusParams(genDoc.ConsensusParams),
+		Validators:      validators,
+		AppStateBytes:   genDoc.AppState,
+		InitialHeight:   genDoc.InitialHeight,
+	})
+
+	return &testChain{
+		t:      t,
+		app:    app,
+		genDoc: genDoc,
+		header: tmproto.Header{ChainID: genDoc.ChainID, Height: genDoc.InitialHeight - 1, Time: genDoc.GenesisTime},
+	}
+}
+
+// beginBlock begins the next block, d after the last one, with the evidence
+// of misbehaviour, and returns its context.
+func (c *testChain) beginBlock(d time.Duration, evidence ...abci.Evidence) sdk.Context {
+	c.header.Height++
+	c.header.Time = c.header.Time.Add(d)
+	c.app.BeginBlock(abci.RequestBeginBlock{Header: c.header, ByzantineValidators: evidence})
+
+	ctx := c.app.BaseApp.NewContext(false, c.header)
+	return ctx.WithConsensusParams(c.app.GetConsensusParams(ctx))
+}
+
+// endBlock ends and commits the current block, keeping its validator updates.
+func (c *testChain) endBlock() {
+	res := c.app.EndBlock(abci.RequestEndBlock{Height: c.header.Height})
+	c.updates = res.ValidatorUpdates
+	c.app.Commit()
+}
+
+// nextBlock runs an empty block and returns the context of the next one.
+func (c *testChain) nextBlock(d time.Duration) sdk.Context {
+	c.endBlock()
+	return c.beginBlock(d)
+}
+
+// export returns the genesis exported from the last committed block.
+func (c *testChain) export() *tmtypes.GenesisDoc {
+	exported, err := c.app.ExportAppStateAndValidators(false, nil)
+	require.NoError(c.t, err)
+
+	genDoc := &tmtypes.GenesisDoc{
+		GenesisTime:     c.genDoc.GenesisTime,
+		ChainID:         c.genDoc.ChainID,
+		InitialHeight:   exported.Height + 1,
+		ConsensusParams: c.genDoc.ConsensusParams,
+		Validators:      exported.Validators,
+		AppState:        exported.AppState,
+	}
+	require.NoError(c.t, genDoc.ValidateAndComplete())
+
+	return genDoc
+}
@@ -0,0 +1,48 @@
+package keeper
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// InitGenesis stores the rotations of the genesis and attributes their old
+// consensus pubkeys back to the validators in the staking and slashing
+// stores, which only export the current ones. The rotations are all
+// unsettled, the first block drops those that are settled. It must run after
+// the staking and slashing genesis.
+func (k Keeper) InitGenesis(ctx sdk.Context, genState types.GenesisState) {
+	for _, rotation := range genState.Rotations {
+		k.SetRotation(ctx, rotation)
+		k.SetUnsettledRotation(ctx, rotation)
+
+		validator, found := k.stakingKeeper.GetValidator(ctx, rotation.GetValidatorAddr())
+		if !found {
+			continue
+		}
+
+		oldPk, err := rotation.GetOldConsPubKey()
+		if err != nil {
+			panic(err)
+		}
+
+		validator.ConsensusPubkey = rotation.OldConsensusPubkey
+		if err := k.stakingKeeper.SetValidatorByConsAddr(ctx, validator); err != nil {
+			panic(err)
+		}
+
+		if err := k.slashingKeeper.AddPubkey(ctx, oldPk); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ExportGenesis returns the rotation genesis state.
+func (k Keeper) ExportGenesis(ctx sdk.Context) *types.GenesisState {
+	rotations := k.GetAllRotations(ctx)
+	if rotations == nil {
+		rotations = []types.Rotation{}
+	}
+
+	return types.NewGenesisState(rotations)
+}
@@ -0,0 +1,147 @@
+package keeper
+
+import (
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/libs/log"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// Keeper of the rotation store
+type Keeper struct {
+	cdc            codec.BinaryMarshaler
+	storeKey       sdk.StoreKey
+	stakingKeeper  types.StakingKeeper
+	slashingKeeper types.SlashingKeeper
+}
+
+// NewKeeper returns a rotation keeper. It replaces the consensus pubkeys of
+// validators in the staking and slashing stores, keeping their old pubkeys
+// attributed to them.
+func NewKeeper(cdc codec.BinaryMarshaler, key sdk.StoreKey, stakingKeeper types.StakingKeeper, slashingKeeper types.SlashingKeeper) Keeper {
+	return Keeper{
+		cdc:            cdc,
+		storeKey:       key,
+		stakingKeeper:  stakingKeeper,
+		slashingKeeper: slashingKeeper,
+	}
+}
+
+// Logger returns a module-specific logger.
+func (k Keeper) Logger(ctx sdk.Context) log.Logger {
+	return ctx.Logger().With("module", "x/"+types.ModuleName)
+}
+
+// SetRotation stores a rotation.
+func (k Keeper) SetRotation(ctx sdk.Context, rotation types.Rotation) {
+	store := ctx.KVStore(k.storeKey)
+	bz := types.MustMarshalRotation(k.cdc, &rotation)
+	store.Set(types.GetRotationKey(rotation.GetValidatorAddr(), rotation.Height), bz)
+}
+
+// GetRotations returns the rotations of a validator, oldest first.
+func (k Keeper) GetRotations(ctx sdk.Context, valAddr sdk.ValAddress) (rotations []types.Rotation) {
+	store := ctx.KVStore(k.storeKey)
+	iterator := sdk.KVStorePrefixIterator(store, types.GetRotationsKey(valAddr))
+	defer iterator.Close()
+
+	for ; iterator.Valid(); iterator.Next() {
+		rotations = append(rotations, types.MustUnmarshalRotation(k.cdc, iterator.Value()))
+	}
+
+	return rotations
+}
+
+// GetLastRotation returns the latest rotation of a validator.
+func (k Keeper) GetLastRotation(ctx sdk.Context, valAddr sdk.ValAddress) (rotation types.Rotation, found bool) {
+	store := ctx.KVStore(k.storeKey)
+	iterator := sdk.KVStoreReversePrefixIterator(store, types.GetRotationsKey(valAddr))
+	defer iterator.Close()
+
+	if !iterator.Valid() {
+		return rotation, false
+	}
+
+	return types.MustUnmarshalRotation(k.cdc, iterator.Value()), true
+}
+
+// IterateRotations iterates over all the rotations, by validator and height.
+func (k Keeper) IterateRotations(ctx sdk.Context, cb func(rotation types.Rotation) (stop bool)) {
+	store := ctx.KVStore(k.storeKey)
+	iterator := sdk.KVStorePrefixIterator(store, types.RotationKeyPrefix)
+	defer iterator.Close()
+
+	for ; iterator.Valid(); iterator.Next() {
+		if cb(types.MustUnmarshalRotation(k.cdc, iterator.Value())) {
+			break
+		}
+	}
+}
+
+// GetAllRotations returns all the rotations, by validator and height.
+func (k Keeper) GetAllRotations(ctx sdk.Context) (rotations []types.Rotation) {
+	k.IterateRotations(ctx, func(rotation types.Rotation) bool {
+		rotations = append(rotations, rotation)
+		return false
+	})
+
+	return rotations
+}
+
+// SetPendingRotation marks a rotation of the current block whose validator
+// updates are still to be sent to Tendermint.
+func (k Keeper) SetPendingRotation(ctx sdk.Context, rotation types.Rotation) {
+	store := ctx.KVStore(k.storeKey)
+	bz := types.MustMarshalRotation(k.cdc, &rotation)
+	store.Set(types.GetPendingRotationKey(rotation.GetValidatorAddr()), bz)
+}
+
+// GetPendingRotations returns the rotations of the current block.
+func (k Keeper) GetPendingRotations(ctx sdk.Context) (rotations []types.Rotation) {
+	store := ctx.KVStore(k.storeKey)
+	iterator := sdk.KVStorePrefixIterator(store, types.PendingRotationKeyPrefix)
+	defer iterator.Close()
+
+	for ; iterator.Valid(); iterator.Next() {
+		rotations = append(rotations, types.MustUnmarshalRotation(k.cdc, iterator.Value()))
+	}
+
+	return rotations
+}
+
+// DeletePendingRotation removes the pending mark of the rotation of a
+// validator.
+func (k Keeper) DeletePendingRotation(ctx sdk.Context, valAddr sdk.ValAddress) {
+	store := ctx.KVStore(k.storeKey)
+	store.Delete(types.GetPendingRotationKey(valAddr))
+}
+
+// SetUnsettledRotation indexes a rotation whose old consensus pubkey may
+// still be the subject of evidence.
+func (k Keeper) SetUnsettledRotation(ctx sdk.Context, rotation types.Rotation) {
+	store := ctx.KVStore(k.storeKey)
+	bz := types.MustMarshalRotation(k.cdc, &rotation)
+	store.Set(types.GetUnsettledRotationKey(rotation.Height, rotation.GetValidatorAddr()), bz)
+}
+
+// IterateUnsettledRotations iterates over the unsettled rotations, by height
+// and validator.
+func (k Keeper) IterateUnsettledRotations(ctx sdk.Context, cb func(rotation types.Rotation) (stop bool)) {
+	store := ctx.KVStore(k.storeKey)
+	iterator := sdk.KVStorePrefixIterator(store, types.UnsettledRotationKeyPrefix)
+	defer iterator.Close()
+
+	for ; iterator.Valid(); iterator.Next() {
+		if cb(types.MustUnmarshalRotation(k.cdc, iterator.Value())) {
+			break
+		}
+	}
+}
+
+// DeleteUnsettledRotation removes a rotation from the unsettled rotations,
+// the rotation itself is kept.
+func (k Keeper) DeleteUnsettledRotation(ctx sdk.Context, valAddr sdk.ValAddress, height int64) {
+	store := ctx.KVStore(k.storeKey)
+	store.Delete(types.GetUnsettledRotationKey(height, valAddr))
+}
@@ -0,0 +1,260 @@
+package keeper_test
+
+import (
+	"testing"
+	"time"
+
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	upgradetypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	gaia "github.com/cosmos/gaia/v5/app"
+	"github.com/cosmos/gaia/v5/app/helpers/genesisbuilder"
+	"github.com/cosmos/gaia/v5/x/rotation/keeper"
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+func newConsKey(name string) cryptotypes.PubKey {
+	return ed25519.GenPrivKeyFromSecret([]byte(name)).PubKey()
+}
+
+func consPubKey(t *testing.T, chain *testChain, ctx sdk.Context, valAddr sdk.ValAddress) cryptotypes.PubKey {
+	validator, found := chain.app.StakingKeeper.GetValidator(ctx, valAddr)
+	require.True(t, found)
+
+	pk, err := validator.ConsPubKey()
+	require.NoError(t, err)
+
+	return pk
+}
+
+func newTestRotationChain(t *testing.T) (*genesisbuilder.Builder, *testChain) {
+	b := genesisbuilder.New().WithValidators(3)
+	genDoc, err := b.Build()
+	require.NoError(t, err)
+
+	return b, newTestChain(t, genDoc)
+}
+
+func TestRotateConsKey(t *testing.T) {
+	b, chain := newTestRotationChain(t)
+	ctx := chain.beginBlock(0)
+	unbondingTime := chain.app.StakingKeeper.UnbondingTime(ctx)
+
+	oldPk := consPubKey(t, chain, ctx, b.ValidatorAddress(0))
+	newPk := newConsKey("new")
+
+	_, err := chain.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(0), newPk)
+	require.NoError(t, err)
+
+	for _, consAddr := range []sdk.ConsAddress{sdk.ConsAddress(oldPk.Address()), sdk.ConsAddress(newPk.Address())} {
+		validator, found := chain.app.StakingKeeper.GetValidatorByConsAddr(ctx, consAddr)
+		require.True(t, found)
+		require.Equal(t, b.ValidatorAddress(0).String(), validator.OperatorAddress)
+	}
+
+	_, err = chain.app.SlashingKeeper.GetPubkey(ctx, newPk.Address())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		validator int
+		pk        cryptotypes.PubKey
+		err       error
+	}{
+		{"unknown validator", -1, newConsKey("other"), types.ErrNoValidatorFound},
+		{"current key", 1, consPubKey(t, chain, ctx, b.ValidatorAddress(1)), types.ErrSameConsKey},
+		{"key of another validator", 1, consPubKey(t, chain, ctx, b.ValidatorAddress(2)), types.ErrConsKeyInUse},
+		{"old key of another validator", 1, oldPk, types.ErrConsKeyInUse},
+		{"unsupported key type", 1, secp256k1.GenPrivKeyFromSecret([]byte("secp")).PubKey(), types.ErrPubKeyTypeNotSupported},
+		{"rotation in the same block", 0, newConsKey("other"), types.ErrRotationTooSoon},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			valAddr := sdk.ValAddress("unknown-validator")
+			if tc.validator >= 0 {
+				valAddr = b.ValidatorAddress(tc.validator)
+			}
+
+			_, err := chain.app.RotationKeeper.RotateConsKey(ctx, valAddr, tc.pk)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	ctx = chain.nextBlock(unbondingTime - time.Second)
+	_, err = chain.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(0), newConsKey("other"))
+	require.ErrorIs(t, err, types.ErrRotationTooSoon)
+
+	ctx = chain.nextBlock(time.Second)
+	_, err = chain.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(0), newConsKey("other"))
+	require.NoError(t, err)
+	require.Len(t, chain.app.RotationKeeper.GetRotations(ctx, b.ValidatorAddress(0)), 2)
+
+	chain.app.StakingKeeper.Jail(ctx, sdk.ConsAddress(consPubKey(t, chain, ctx, b.ValidatorAddress(1)).Address()))
+	_, err = chain.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(1), newConsKey("jailed"))
+	require.ErrorIs(t, err, types.ErrValidatorJailed)
+}
+
+func TestRotationValidatorUpdates(t *testing.T) {
+	b, chain := newTestRotationChain(t)
+	ctx := chain.beginBlock(0)
+
+	oldPk := consPubKey(t, chain, ctx, b.ValidatorAddress(0))
+	newPk := newConsKey("new")
+	power := chain.app.StakingKeeper.GetLastValidatorPower(ctx, b.ValidatorAddress(0))
+
+	msg, err := types.NewMsgRotateConsKey(b.ValidatorAddress(0), newPk)
+	require.NoError(t, err)
+	require.NoError(t, msg.ValidateBasic())
+	require.Equal(t, []sdk.AccAddress{b.Address("validator0")}, msg.GetSigners())
+
+	_, err = keeper.NewMsgServerImpl(chain.app.RotationKeeper).RotateConsKey(sdk.WrapSDKContext(ctx), msg)
+	require.NoError(t, err)
+	chain.endBlock()
+
+	oldTmPk, err := cryptocodec.ToTmProtoPublicKey(oldPk)
+	require.NoError(t, err)
+	newTmPk, err := cryptocodec.ToTmProtoPublicKey(newPk)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []abci.ValidatorUpdate{{PubKey: oldTmPk, Power: 0}, {PubKey: newTmPk, Power: power}}, chain.updates)
+
+	ctx = chain.beginBlock(5 * time.Second)
+	require.Empty(t, chain.app.RotationKeeper.GetPendingRotations(ctx))
+	chain.endBlock()
+	require.Empty(t, chain.updates)
+}
+
+// TestDoubleSignAcrossRotation checks that a validator stays accountable for
+// the double signs of its old consensus pubkey once rotated: the evidence is
+// slashed, and the tombstone holds against its new consensus pubkey.
+func TestDoubleSignAcrossRotation(t *testing.T) {
+	b, chain := newTestRotationChain(t)
+	ctx := chain.beginBlock(0)
+	valAddr := b.ValidatorAddress(0)
+
+	oldPk := consPubKey(t, chain, ctx, b.ValidatorAddress(0))
+	newPk := newConsKey("new")
+	infractionTime := ctx.BlockTime()
+	infractionHeight := ctx.BlockHeight()
+
+	_, err := chain.app.RotationKeeper.RotateConsKey(ctx, valAddr, newPk)
+	require.NoError(t, err)
+	ctx = chain.nextBlock(5 * time.Second)
+
+	before, _ := chain.app.StakingKeeper.GetValidator(ctx, valAddr)
+	chain.endBlock()
+
+	ctx = chain.beginBlock(5*time.Second, abci.Evidence{
+		Type:             abci.EvidenceType_DUPLICATE_VOTE,
+		Validator:        abci.Validator{Address: oldPk.Address(), Power: before.ConsensusPower()},
+		Height:           infractionHeight,
+		Time:             infractionTime,
+		TotalVotingPower: 60,
+	})
+
+	after, _ := chain.app.StakingKeeper.GetValidator(ctx, valAddr)
+	require.True(t, after.IsJailed())
+	require.True(t, after.Tokens.LT(before.Tokens))
+
+	for _, consAddr := range []sdk.ConsAddress{sdk.ConsAddress(oldPk.Address()), sdk.ConsAddress(newPk.Address())} {
+		info, found := chain.app.SlashingKeeper.GetValidatorSigningInfo(ctx, consAddr)
+		require.True(t, found)
+		require.True(t, info.Tombstoned, consAddr.String())
+	}
+
+	ctx = chain.nextBlock(slashingtypes.DefaultDowntimeJailDuration)
+	require.ErrorIs(t, chain.app.SlashingKeeper.Unjail(ctx, valAddr), slashingtypes.ErrValidatorJailed)
+}
+
+func TestRotationGenesis(t *testing.T) {
+	b, chain := newTestRotationChain(t)
+	ctx := chain.beginBlock(0)
+
+	oldPk := consPubKey(t, chain, ctx, b.ValidatorAddress(0))
+	newPk := newConsKey("new")
+
+	_, err := chain.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(0), newPk)
+	require.NoError(t, err)
+	chain.nextBlock(5 * time.Second)
+	chain.endBlock()
+
+	imported := newTestChain(t, chain.export())
+	ctx = imported.beginBlock(5 * time.Second)
+
+	rotations := imported.app.RotationKeeper.GetAllRotations(ctx)
+	require.Len(t, rotations, 1)
+	require.Equal(t, b.ValidatorAddress(0).String(), rotations[0].ValidatorAddress)
+
+	validator, found := imported.app.StakingKeeper.GetValidatorByConsAddr(ctx, sdk.ConsAddress(oldPk.Address()))
+	require.True(t, found)
+	require.Equal(t, b.ValidatorAddress(0).String(), validator.OperatorAddress)
+
+	_, err = imported.app.SlashingKeeper.GetPubkey(ctx, oldPk.Address())
+	require.NoError(t, err)
+
+	_, err = imported.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(1), oldPk)
+	require.ErrorIs(t, err, types.ErrConsKeyInUse)
+}
+
+// TestSettledRotations checks that the signing infos are only synced for the
+// rotations whose old consensus pubkey may still be the subject of evidence,
+// while the rotations themselves are kept.
+func TestSettledRotations(t *testing.T) {
+	b := genesisbuilder.New().WithValidators(3)
+	genDoc, err := b.Build()
+	require.NoError(t, err)
+	genDoc.ConsensusParams.Evidence.MaxAgeNumBlocks = 3
+	genDoc.ConsensusParams.Evidence.MaxAgeDuration = time.Hour
+	chain := newTestChain(t, genDoc)
+
+	unsettled := func(app *gaia.GaiaApp, ctx sdk.Context) (rotations []types.Rotation) {
+		app.RotationKeeper.IterateUnsettledRotations(ctx, func(rotation types.Rotation) bool {
+			rotations = append(rotations, rotation)
+			return false
+		})
+		return rotations
+	}
+
+	ctx := chain.beginBlock(0)
+	unbondingTime := chain.app.StakingKeeper.UnbondingTime(ctx)
+	_, err = chain.app.RotationKeeper.RotateConsKey(ctx, b.ValidatorAddress(0), newConsKey("new"))
+	require.NoError(t, err)
+	require.Len(t, unsettled(chain.app, ctx), 1)
+
+	// past the unbonding time and the evidence max age, but within the max
+	// age in blocks
+	ctx = chain.nextBlock(unbondingTime + time.Hour + time.Second)
+	require.Len(t, unsettled(chain.app, ctx), 1)
+	for i := 0; i < 4; i++ {
+		ctx = chain.nextBlock(5 * time.Second)
+	}
+	require.Empty(t, unsettled(chain.app, ctx))
+	require.Len(t, chain.app.RotationKeeper.GetAllRotations(ctx), 1)
+
+	// the exported rotations are unsettled again until the first block
+	chain.endBlock()
+	exported := chain.export()
+	exported.GenesisTime = chain.header.Time
+	imported := newTestChain(t, exported)
+	ctx = imported.beginBlock(5 * time.Second)
+	require.Empty(t, unsettled(imported.app, ctx))
+	require.Len(t, imported.app.RotationKeeper.GetAllRotations(ctx), 1)
+}
+
+func TestRotationUpgrade(t *testing.T) {
+	_, chain := newTestRotationChain(t)
+	ctx := chain.beginBlock(0)
+
+	require.True(t, chain.app.UpgradeKeeper.HasHandler(gaia.RotationUpgradeName))
+	chain.app.UpgradeKeeper.ApplyUpgrade(ctx, upgradetypes.Plan{Name: gaia.RotationUpgradeName, Height: ctx.BlockHeight()})
+	require.Equal(t, ctx.BlockHeight(), chain.app.UpgradeKeeper.GetDoneHeight(ctx, gaia.RotationUpgradeName))
+	require.Empty(t, chain.app.RotationKeeper.GetAllRotations(ctx))
+}
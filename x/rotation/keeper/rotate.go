@@ -0,0 +1,196 @@
+package keeper
+
+import (
+	"bytes"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	tmstrings "github.com/tendermint/tendermint/libs/strings"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// RotateConsKey replaces the consensus pubkey of a validator with newPk.
+//
+// The old pubkey keeps pointing at the validator in the staking store, so
+// evidence of a double sign made with it is still slashed, and its signing
+// info is carried over to the new pubkey. A validator rotates at most once
+// per unbonding period, when not jailed, to a pubkey no validator ever used.
+func (k Keeper) RotateConsKey(ctx sdk.Context, valAddr sdk.ValAddress, newPk cryptotypes.PubKey) (types.Rotation, error) {
+	validator, found := k.stakingKeeper.GetValidator(ctx, valAddr)
+	if !found {
+		return types.Rotation{}, types.ErrNoValidatorFound
+	}
+
+	if validator.IsJailed() {
+		return types.Rotation{}, types.ErrValidatorJailed
+	}
+
+	oldPk, err := validator.ConsPubKey()
+	if err != nil {
+		return types.Rotation{}, err
+	}
+
+	if bytes.Equal(oldPk.Bytes(), newPk.Bytes()) {
+		return types.Rotation{}, types.ErrSameConsKey
+	}
+
+	cp := ctx.ConsensusParams()
+	if cp != nil && cp.Validator != nil {
+		if !tmstrings.StringInSlice(newPk.Type(), cp.Validator.PubKeyTypes) {
+			return types.Rotation{}, sdkerrors.Wrapf(
+				types.ErrPubKeyTypeNotSupported,
+				"got: %s, expected: %s", newPk.Type(), cp.Validator.PubKeyTypes,
+			)
+		}
+	}
+
+	newConsAddr := sdk.ConsAddress(newPk.Address())
+	if _, found := k.stakingKeeper.GetValidatorByConsAddr(ctx, newConsAddr); found {
+		return types.Rotation{}, types.ErrConsKeyInUse
+	}
+
+	if _, found := k.slashingKeeper.GetValidatorSigningInfo(ctx, newConsAddr); found {
+		return types.Rotation{}, types.ErrConsKeyInUse
+	}
+
+	if last, found := k.GetLastRotation(ctx, valAddr); found {
+		if last.Height >= ctx.BlockHeight() || last.Time.Add(k.stakingKeeper.UnbondingTime(ctx)).After(ctx.BlockTime()) {
+			return types.Rotation{}, sdkerrors.Wrapf(types.ErrRotationTooSoon, "last rotation at height %d", last.Height)
+		}
+	}
+
+	rotation, err := types.NewRotation(valAddr, oldPk, ctx.BlockHeight(), ctx.BlockTime())
+	if err != nil {
+		return types.Rotation{}, err
+	}
+
+	if validator.ConsensusPubkey, err = codectypes.NewAnyWithValue(newPk); err != nil {
+		return types.Rotation{}, err
+	}
+
+	k.stakingKeeper.SetValidator(ctx, validator)
+	if err := k.stakingKeeper.SetValidatorByConsAddr(ctx, validator); err != nil {
+		return types.Rotation{}, err
+	}
+
+	if err := k.slashingKeeper.AddPubkey(ctx, newPk); err != nil {
+		return types.Rotation{}, err
+	}
+
+	oldConsAddr := sdk.ConsAddress(oldPk.Address())
+	if info, found := k.slashingKeeper.GetValidatorSigningInfo(ctx, oldConsAddr); found {
+		info.Address = newConsAddr.String()
+		k.slashingKeeper.SetValidatorSigningInfo(ctx, newConsAddr, info)
+
+		k.slashingKeeper.IterateValidatorMissedBlockBitArray(ctx, oldConsAddr, func(index int64, missed bool) bool {
+			k.slashingKeeper.SetValidatorMissedBlockBitArray(ctx, newConsAddr, index, missed)
+			return false
+		})
+	}
+
+	k.SetRotation(ctx, rotation)
+	k.SetPendingRotation(ctx, rotation)
+	k.SetUnsettledRotation(ctx, rotation)
+
+	k.Logger(ctx).Info("rotated consensus pubkey", "validator", valAddr.String(), "old", oldConsAddr.String(), "new", newConsAddr.String())
+
+	return rotation, nil
+}
+
+// SyncSigningInfos shares the tombstone and the jail time between the old
+// and current signing infos of the validators of the unsettled rotations, so
+// the misbehaviour of one consensus pubkey is held against the validator
+// under the other. A rotation is synced a last time and dropped from the
+// unsettled ones once it is settled, or when its validator is gone.
+func (k Keeper) SyncSigningInfos(ctx sdk.Context) {
+	var done []types.Rotation
+	k.IterateUnsettledRotations(ctx, func(rotation types.Rotation) bool {
+		if !k.syncSigningInfos(ctx, rotation) || k.isSettled(ctx, rotation) {
+			done = append(done, rotation)
+		}
+		return false
+	})
+
+	for _, rotation := range done {
+		k.DeleteUnsettledRotation(ctx, rotation.GetValidatorAddr(), rotation.Height)
+	}
+}
+
+// isSettled reports whether evidence of the old consensus pubkey of a
+// rotation is too old to be handled. The old pubkey signs until Tendermint
+// applies the rotation two blocks later, at a time the rotation does not
+// record, so the evidence max age is only counted once the unbonding time
+// passed. A validator rotates at most once per unbonding time, which bounds
+// the unsettled rotations to a few per validator.
+func (k Keeper) isSettled(ctx sdk.Context, rotation types.Rotation) bool {
+	maxAge, maxAgeBlocks := time.Duration(0), int64(0)
+	if cp := ctx.ConsensusParams(); cp != nil && cp.Evidence != nil {
+		maxAge, maxAgeBlocks = cp.Evidence.MaxAgeDuration, cp.Evidence.MaxAgeNumBlocks
+	}
+
+	settledAt := rotation.Time.Add(k.stakingKeeper.UnbondingTime(ctx) + maxAge)
+	return ctx.BlockTime().After(settledAt) && ctx.BlockHeight() > rotation.Height+validatorUpdateDelay+maxAgeBlocks
+}
+
+// validatorUpdateDelay is the number of blocks after which Tendermint applies
+// the validator updates of a block.
+const validatorUpdateDelay = 2
+
+// syncSigningInfos shares the tombstone and the jail time between the old and
+// current signing infos of the validator of a rotation. It returns false if
+// the rotation has nothing left to sync: its validator is gone or one of its
+// consensus pubkeys cannot be decoded.
+func (k Keeper) syncSigningInfos(ctx sdk.Context, rotation types.Rotation) bool {
+	validator, found := k.stakingKeeper.GetValidator(ctx, rotation.GetValidatorAddr())
+	if !found {
+		return false
+	}
+
+	currentConsAddr, err := validator.GetConsAddr()
+	if err != nil {
+		k.Logger(ctx).Error("invalid consensus pubkey of rotated validator", "validator", rotation.ValidatorAddress, "err", err)
+		return false
+	}
+
+	oldConsAddr, err := rotation.GetOldConsAddr()
+	if err != nil {
+		k.Logger(ctx).Error("invalid old consensus pubkey of rotation", "validator", rotation.ValidatorAddress, "height", rotation.Height, "err", err)
+		return false
+	}
+
+	oldInfo, found := k.slashingKeeper.GetValidatorSigningInfo(ctx, oldConsAddr)
+	if !found {
+		return true
+	}
+
+	currentInfo, found := k.slashingKeeper.GetValidatorSigningInfo(ctx, currentConsAddr)
+	if !found {
+		return true
+	}
+
+	tombstoned := oldInfo.Tombstoned || currentInfo.Tombstoned
+	jailedUntil := oldInfo.JailedUntil
+	if currentInfo.JailedUntil.After(jailedUntil) {
+		jailedUntil = currentInfo.JailedUntil
+	}
+
+	for _, info := range []struct {
+		addr sdk.ConsAddress
+		info slashingtypes.ValidatorSigningInfo
+	}{{oldConsAddr, oldInfo}, {currentConsAddr, currentInfo}} {
+		if info.info.Tombstoned == tombstoned && info.info.JailedUntil.Equal(jailedUntil) {
+			continue
+		}
+
+		info.info.Tombstoned = tombstoned
+		info.info.JailedUntil = jailedUntil
+		k.slashingKeeper.SetValidatorSigningInfo(ctx, info.addr, info.info)
+	}
+
+	return true
+}
@@ -0,0 +1,79 @@
+package keeper
+
+import (
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+// ApplyRotationUpdates runs the staking end blocker and amends the validator
+// updates it returns for the rotations of the block: Tendermint is told to
+// drop the old consensus pubkey of a bonded rotated validator and to give
+// its power to the new one.
+func (k Keeper) ApplyRotationUpdates(ctx sdk.Context, endBlock func() []abci.ValidatorUpdate) []abci.ValidatorUpdate {
+	rotations := k.GetPendingRotations(ctx)
+
+	// the last powers are those Tendermint knows, before the end blocker
+	// replaces them with the new ones
+	wasBonded := make([]bool, len(rotations))
+	for i, rotation := range rotations {
+		wasBonded[i] = k.stakingKeeper.GetLastValidatorPower(ctx, rotation.GetValidatorAddr()) > 0
+	}
+
+	updates := endBlock()
+
+	for i, rotation := range rotations {
+		valAddr := rotation.GetValidatorAddr()
+		k.DeletePendingRotation(ctx, valAddr)
+
+		if !wasBonded[i] {
+			continue
+		}
+
+		validator, found := k.stakingKeeper.GetValidator(ctx, valAddr)
+		if !found {
+			continue
+		}
+
+		newPk, err := validator.TmConsPublicKey()
+		if err != nil {
+			panic(err)
+		}
+
+		oldSdkPk, err := rotation.GetOldConsPubKey()
+		if err != nil {
+			panic(err)
+		}
+
+		oldPk, err := cryptocodec.ToTmProtoPublicKey(oldSdkPk)
+		if err != nil {
+			panic(err)
+		}
+
+		index := -1
+		for j, update := range updates {
+			if update.PubKey.Equal(newPk) {
+				index = j
+				break
+			}
+		}
+
+		switch {
+		case index < 0:
+			power := k.stakingKeeper.GetLastValidatorPower(ctx, valAddr)
+			updates = append(updates,
+				abci.ValidatorUpdate{PubKey: oldPk, Power: 0},
+				abci.ValidatorUpdate{PubKey: newPk, Power: power},
+			)
+
+		case updates[index].Power == 0:
+			// unbonded in the block, Tendermint only knows the old pubkey
+			updates[index].PubKey = oldPk
+
+		default:
+			updates = append(updates, abci.ValidatorUpdate{PubKey: oldPk, Power: 0})
+		}
+	}
+
+	return updates
+}
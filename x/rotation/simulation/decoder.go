@@ -0,0 +1,29 @@
+package simulation
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/types/kv"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// NewDecodeStore returns a decoder function closure that unmarshals the KVPair's
+// Value to the corresponding rotation type.
+func NewDecodeStore(cdc codec.Marshaler) func(kvA, kvB kv.Pair) string {
+	return func(kvA, kvB kv.Pair) string {
+		switch {
+		case bytes.Equal(kvA.Key[:1], types.RotationKeyPrefix),
+			bytes.Equal(kvA.Key[:1], types.PendingRotationKeyPrefix),
+			bytes.Equal(kvA.Key[:1], types.UnsettledRotationKeyPrefix):
+			rotationA := types.MustUnmarshalRotation(cdc, kvA.Value)
+			rotationB := types.MustUnmarshalRotation(cdc, kvB.Value)
+			return fmt.Sprintf("%v\n%v", rotationA, rotationB)
+
+		default:
+			panic(fmt.Sprintf("invalid rotation key prefix %X", kvA.Key[:1]))
+		}
+	}
+}
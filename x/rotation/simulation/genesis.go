@@ -0,0 +1,13 @@
+package simulation
+
+import (
+	"github.com/cosmos/cosmos-sdk/types/module"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// RandomizedGenState generates the rotation genesis of a simulation, which
+// starts without rotations as the validators are generated with it.
+func RandomizedGenState(simState *module.SimulationState) {
+	simState.GenState[types.ModuleName] = simState.Cdc.MustMarshalJSON(types.DefaultGenesisState())
+}
@@ -0,0 +1,114 @@
+package simulation
+
+import (
+	"math/rand"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	"github.com/cosmos/cosmos-sdk/simapp/helpers"
+	simappparams "github.com/cosmos/cosmos-sdk/simapp/params"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	simtypes "github.com/cosmos/cosmos-sdk/types/simulation"
+	"github.com/cosmos/cosmos-sdk/x/simulation"
+	stakingkeeper "github.com/cosmos/cosmos-sdk/x/staking/keeper"
+
+	"github.com/cosmos/gaia/v5/x/rotation/keeper"
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+// Simulation operation weights constants
+const (
+	OpWeightMsgRotateConsKey = "op_weight_msg_rotate_cons_key"
+
+	// DefaultWeightMsgRotateConsKey is lower than the weights of the staking
+	// messages, a validator only rotates once per unbonding time.
+	DefaultWeightMsgRotateConsKey int = 20
+)
+
+// WeightedOperations returns all the operations from the module with their respective weights
+func WeightedOperations(
+	appParams simtypes.AppParams, cdc codec.JSONMarshaler, ak types.AccountKeeper,
+	bk types.BankKeeper, k keeper.Keeper, sk stakingkeeper.Keeper,
+) simulation.WeightedOperations {
+
+	var weightMsgRotateConsKey int
+	appParams.GetOrGenerate(cdc, OpWeightMsgRotateConsKey, &weightMsgRotateConsKey, nil,
+		func(_ *rand.Rand) {
+			weightMsgRotateConsKey = DefaultWeightMsgRotateConsKey
+		},
+	)
+
+	return simulation.WeightedOperations{
+		simulation.NewWeightedOperation(
+			weightMsgRotateConsKey,
+			SimulateMsgRotateConsKey(ak, bk, k, sk),
+		),
+	}
+}
+
+// SimulateMsgRotateConsKey generates a MsgRotateConsKey of a random validator
+// to a new random ed25519 consensus pubkey.
+func SimulateMsgRotateConsKey(ak types.AccountKeeper, bk types.BankKeeper, k keeper.Keeper, sk stakingkeeper.Keeper) simtypes.Operation {
+	return func(
+		r *rand.Rand, app *baseapp.BaseApp, ctx sdk.Context,
+		accs []simtypes.Account, chainID string,
+	) (simtypes.OperationMsg, []simtypes.FutureOperation, error) {
+
+		validator, ok := stakingkeeper.RandomValidator(r, sk, ctx)
+		if !ok {
+			return simtypes.NoOpMsg(types.ModuleName, types.TypeMsgRotateConsKey, "validator is not ok"), nil, nil // skip
+		}
+
+		if validator.IsJailed() {
+			return simtypes.NoOpMsg(types.ModuleName, types.TypeMsgRotateConsKey, "validator is jailed"), nil, nil // skip
+		}
+
+		if last, found := k.GetLastRotation(ctx, validator.GetOperator()); found {
+			if last.Height >= ctx.BlockHeight() || last.Time.Add(sk.UnbondingTime(ctx)).After(ctx.BlockTime()) {
+				return simtypes.NoOpMsg(types.ModuleName, types.TypeMsgRotateConsKey, "validator rotated within the unbonding time"), nil, nil // skip
+			}
+		}
+
+		simAccount, found := simtypes.FindAccount(accs, sdk.AccAddress(validator.GetOperator()))
+		if !found {
+			return simtypes.NoOpMsg(types.ModuleName, types.TypeMsgRotateConsKey, "unable to find account"), nil, nil // skip
+		}
+
+		account := ak.GetAccount(ctx, simAccount.Address)
+		spendable := bk.SpendableCoins(ctx, account.GetAddress())
+
+		fees, err := simtypes.RandomFees(r, ctx, spendable)
+		if err != nil {
+			return simtypes.NoOpMsg(types.ModuleName, types.TypeMsgRotateConsKey, "unable to generate fees"), nil, err
+		}
+
+		newPk := ed25519.GenPrivKeyFromSecret([]byte(simtypes.RandStringOfLength(r, 32))).PubKey()
+		msg, err := types.NewMsgRotateConsKey(validator.GetOperator(), newPk)
+		if err != nil {
+			return simtypes.NoOpMsg(types.ModuleName, types.TypeMsgRotateConsKey, "unable to create message"), nil, err
+		}
+
+		txGen := simappparams.MakeTestEncodingConfig().TxConfig
+		tx, err := helpers.GenTx(
+			txGen,
+			[]sdk.Msg{msg},
+			fees,
+			helpers.DefaultGenTxGas,
+			chainID,
+			[]uint64{account.GetAccountNumber()},
+			[]uint64{account.GetSequence()},
+			simAccount.PrivKey,
+		)
+		if err != nil {
+			return simtypes.NoOpMsg(types.ModuleName, msg.Type(), "unable to generate mock tx"), nil, err
+		}
+
+		_, _, err = app.Deliver(txGen.TxEncoder(), tx)
+		if err != nil {
+			return simtypes.NoOpMsg(types.ModuleName, msg.Type(), "unable to deliver tx"), nil, err
+		}
+
+		return simtypes.NewOperationMsg(msg, true, ""), nil, nil
+	}
+}
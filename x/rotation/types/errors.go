@@ -0,0 +1,15 @@
+package types
+
+import (
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// x/rotation module sentinel errors
+var (
+	ErrNoValidatorFound       = sdkerrors.Register(ModuleName, 2, "validator does not exist")
+	ErrConsKeyInUse           = sdkerrors.Register(ModuleName, 3, "consensus pubkey is already used by a validator")
+	ErrRotationTooSoon        = sdkerrors.Register(ModuleName, 4, "consensus pubkey was rotated less than an unbonding period ago")
+	ErrValidatorJailed        = sdkerrors.Register(ModuleName, 5, "validator is jailed")
+	ErrPubKeyTypeNotSupported = sdkerrors.Register(ModuleName, 6, "consensus pubkey type is not supported")
+	ErrSameConsKey            = sdkerrors.Register(ModuleName, 7, "new consensus pubkey is the current one")
+)
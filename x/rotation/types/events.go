@@ -0,0 +1,11 @@
+package types
+
+// rotation module event types
+const (
+	EventTypeRotateConsKey = "rotate_cons_key"
+
+	AttributeKeyValidator      = "validator"
+	AttributeKeyOldConsAddress = "old_cons_address"
+	AttributeKeyNewConsAddress = "new_cons_address"
+	AttributeValueCategory     = ModuleName
+)
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"time"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// StakingKeeper defines the staking functionality the rotation module needs.
+type StakingKeeper interface {
+	GetValidator(ctx sdk.Context, addr sdk.ValAddress) (stakingtypes.Validator, bool)
+	GetValidatorByConsAddr(ctx sdk.Context, consAddr sdk.ConsAddress) (stakingtypes.Validator, bool)
+	SetValidator(ctx sdk.Context, validator stakingtypes.Validator)
+	SetValidatorByConsAddr(ctx sdk.Context, validator stakingtypes.Validator) error
+	GetLastValidatorPower(ctx sdk.Context, operator sdk.ValAddress) int64
+	UnbondingTime(ctx sdk.Context) time.Duration
+}
+
+// SlashingKeeper defines the slashing functionality the rotation module needs.
+type SlashingKeeper interface {
+	AddPubkey(ctx sdk.Context, pubkey cryptotypes.PubKey) error
+	GetValidatorSigningInfo(ctx sdk.Context, address sdk.ConsAddress) (slashingtypes.ValidatorSigningInfo, bool)
+	SetValidatorSigningInfo(ctx sdk.Context, address sdk.ConsAddress, info slashingtypes.ValidatorSigningInfo)
+	IterateValidatorMissedBlockBitArray(ctx sdk.Context, address sdk.ConsAddress, handler func(index int64, missed bool) (stop bool))
+	SetValidatorMissedBlockBitArray(ctx sdk.Context, address sdk.ConsAddress, index int64, missed bool)
+}
+
+// AccountKeeper defines the account functionality the rotation simulation
+// needs.
+type AccountKeeper interface {
+	GetAccount(ctx sdk.Context, addr sdk.AccAddress) authtypes.AccountI
+}
+
+// BankKeeper defines the bank functionality the rotation simulation needs.
+type BankKeeper interface {
+	SpendableCoins(ctx sdk.Context, addr sdk.AccAddress) sdk.Coins
+}
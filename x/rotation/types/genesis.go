@@ -0,0 +1,59 @@
+package types
+
+import (
+	"fmt"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var _ codectypes.UnpackInterfacesMessage = (*GenesisState)(nil)
+
+// NewGenesisState creates a new GenesisState instance.
+func NewGenesisState(rotations []Rotation) *GenesisState {
+	return &GenesisState{Rotations: rotations}
+}
+
+// DefaultGenesisState returns the default genesis state of the rotation module.
+func DefaultGenesisState() *GenesisState {
+	return NewGenesisState([]Rotation{})
+}
+
+// Validate performs basic validation of the rotation genesis: every rotation
+// names a valid validator and old consensus pubkey, and no validator rotated
+// twice at the same height.
+func (gs GenesisState) Validate() error {
+	seen := make(map[string]bool)
+	for _, r := range gs.Rotations {
+		if _, err := sdk.ValAddressFromBech32(r.ValidatorAddress); err != nil {
+			return fmt.Errorf("invalid rotation validator address %s: %w", r.ValidatorAddress, err)
+		}
+
+		if _, err := r.GetOldConsPubKey(); err != nil {
+			return fmt.Errorf("invalid rotation of %s: %w", r.ValidatorAddress, err)
+		}
+
+		if r.Height < 0 {
+			return fmt.Errorf("invalid rotation of %s: negative height %d", r.ValidatorAddress, r.Height)
+		}
+
+		key := fmt.Sprintf("%s/%d", r.ValidatorAddress, r.Height)
+		if seen[key] {
+			return fmt.Errorf("duplicate rotation of %s at height %d", r.ValidatorAddress, r.Height)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// UnpackInterfaces implements UnpackInterfacesMessage.UnpackInterfaces
+func (gs GenesisState) UnpackInterfaces(unpacker codectypes.AnyUnpacker) error {
+	for _, r := range gs.Rotations {
+		if err := r.UnpackInterfaces(unpacker); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/gaia/v5/x/rotation/types"
+)
+
+func TestGenesisStateValidate(t *testing.T) {
+	pk := ed25519.GenPrivKeyFromSecret([]byte("old")).PubKey()
+	valAddr := sdk.ValAddress(pk.Address())
+
+	rotation := func(height int64) types.Rotation {
+		r, err := types.NewRotation(valAddr, pk, height, time.Unix(0, 0).UTC())
+		require.NoError(t, err)
+		return r
+	}
+
+	testCases := []struct {
+		name      string
+		rotations []types.Rotation
+		valid     bool
+	}{
+		{"default", nil, true},
+		{"rotations", []types.Rotation{rotation(1), rotation(2)}, true},
+		{"invalid address", []types.Rotation{{ValidatorAddress: "invalid", OldConsensusPubkey: rotation(1).OldConsensusPubkey}}, false},
+		{"no pubkey", []types.Rotation{{ValidatorAddress: valAddr.String()}}, false},
+		{"negative height", []types.Rotation{rotation(-1)}, false},
+		{"duplicate", []types.Rotation{rotation(1), rotation(1)}, false},
+	}
+
+	for _, tc := range testCases {
+		err := types.NewGenesisState(tc.rotations).Validate()
+		if tc.valid {
+			require.NoError(t, err, tc.name)
+		} else {
+			require.Error(t, err, tc.name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	// ModuleName is the name of the rotation module
+	ModuleName = "rotation"
+
+	// StoreKey is the string store representation
+	StoreKey = ModuleName
+
+	// RouterKey is the msg router key for the rotation module
+	RouterKey = ModuleName
+)
+
+var (
+	// RotationKeyPrefix prefixes the rotations, keyed by validator and height
+	RotationKeyPrefix = []byte{0x01}
+
+	// PendingRotationKeyPrefix prefixes the rotations of the current block
+	// whose validator updates are still to be sent to Tendermint
+	PendingRotationKeyPrefix = []byte{0x02}
+
+	// UnsettledRotationKeyPrefix prefixes the rotations whose old consensus
+	// pubkey may still be the subject of evidence, keyed by height and
+	// validator
+	UnsettledRotationKeyPrefix = []byte{0x03}
+)
+
+// GetRotationsKey returns the key prefix of the rotations of a validator.
+func GetRotationsKey(valAddr sdk.ValAddress) []byte {
+	return append(RotationKeyPrefix, valAddr.Bytes()...)
+}
+
+// GetRotationKey returns the key of the rotation of a validator at a height.
+func GetRotationKey(valAddr sdk.ValAddress, height int64) []byte {
+	return append(GetRotationsKey(valAddr), sdk.Uint64ToBigEndian(uint64(height))...)
+}
+
+// GetPendingRotationKey returns the key of the pending rotation of a
+// validator.
+func GetPendingRotationKey(valAddr sdk.ValAddress) []byte {
+	return append(PendingRotationKeyPrefix, valAddr.Bytes()...)
+}
+
+// GetUnsettledRotationKey returns the key of the unsettled rotation of a
+// validator at a height.
+func GetUnsettledRotationKey(height int64, valAddr sdk.ValAddress) []byte {
+	return append(append(UnsettledRotationKeyPrefix, sdk.Uint64ToBigEndian(uint64(height))...), valAddr.Bytes()...)
+}
@@ -0,0 +1,91 @@
+package types
+
+import (
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// rotation message types
+const (
+	TypeMsgRotateConsKey = "rotate_cons_key"
+)
+
+var (
+	_ sdk.Msg                            = &MsgRotateConsKey{}
+	_ codectypes.UnpackInterfacesMessage = (*MsgRotateConsKey)(nil)
+)
+
+// NewMsgRotateConsKey creates a new MsgRotateConsKey instance.
+func NewMsgRotateConsKey(valAddr sdk.ValAddress, pubKey cryptotypes.PubKey) (*MsgRotateConsKey, error) { //nolint:interfacer
+	pkAny, err := codectypes.NewAnyWithValue(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MsgRotateConsKey{
+		ValidatorAddress: valAddr.String(),
+		NewPubkey:        pkAny,
+	}, nil
+}
+
+// Route implements the sdk.Msg interface.
+func (msg MsgRotateConsKey) Route() string { return RouterKey }
+
+// Type implements the sdk.Msg interface.
+func (msg MsgRotateConsKey) Type() string { return TypeMsgRotateConsKey }
+
+// GetSigners implements the sdk.Msg interface. The operator of the
+// validator signs the rotation.
+func (msg MsgRotateConsKey) GetSigners() []sdk.AccAddress {
+	valAddr, err := sdk.ValAddressFromBech32(msg.ValidatorAddress)
+	if err != nil {
+		panic(err)
+	}
+
+	return []sdk.AccAddress{sdk.AccAddress(valAddr)}
+}
+
+// GetSignBytes returns the message bytes to sign over.
+func (msg MsgRotateConsKey) GetSignBytes() []byte {
+	bz := ModuleCdc.MustMarshalJSON(&msg)
+	return sdk.MustSortJSON(bz)
+}
+
+// ValidateBasic implements the sdk.Msg interface.
+func (msg MsgRotateConsKey) ValidateBasic() error {
+	if msg.ValidatorAddress == "" {
+		return sdkerrors.Wrap(sdkerrors.ErrInvalidAddress, "empty validator address")
+	}
+
+	if _, err := sdk.ValAddressFromBech32(msg.ValidatorAddress); err != nil {
+		return sdkerrors.Wrap(sdkerrors.ErrInvalidAddress, err.Error())
+	}
+
+	if msg.NewPubkey == nil {
+		return sdkerrors.Wrap(sdkerrors.ErrInvalidPubKey, "empty consensus pubkey")
+	}
+
+	return nil
+}
+
+// UnpackInterfaces implements UnpackInterfacesMessage.UnpackInterfaces
+func (msg MsgRotateConsKey) UnpackInterfaces(unpacker codectypes.AnyUnpacker) error {
+	var pubKey cryptotypes.PubKey
+	return unpacker.UnpackAny(msg.NewPubkey, &pubKey)
+}
+
+// GetNewPubKey returns the new consensus pubkey of the message.
+func (msg MsgRotateConsKey) GetNewPubKey() (cryptotypes.PubKey, error) {
+	if msg.NewPubkey == nil {
+		return nil, sdkerrors.Wrap(sdkerrors.ErrInvalidPubKey, "empty consensus pubkey")
+	}
+
+	pk, ok := msg.NewPubkey.GetCachedValue().(cryptotypes.PubKey)
+	if !ok {
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrInvalidType, "expecting cryptotypes.PubKey, got %T", msg.NewPubkey.GetCachedValue())
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+var _ codectypes.UnpackInterfacesMessage = (*Rotation)(nil)
+
+// NewRotation creates a new Rotation instance.
+func NewRotation(valAddr sdk.ValAddress, oldPubKey cryptotypes.PubKey, height int64, t time.Time) (Rotation, error) { //nolint:interfacer
+	pkAny, err := codectypes.NewAnyWithValue(oldPubKey)
+	if err != nil {
+		return Rotation{}, err
+	}
+
+	return Rotation{
+		ValidatorAddress:   valAddr.String(),
+		OldConsensusPubkey: pkAny,
+		Height:             height,
+		Time:               t,
+	}, nil
+}
+
+// GetValidatorAddr returns the operator address of the rotated validator.
+func (r Rotation) GetValidatorAddr() sdk.ValAddress {
+	addr, err := sdk.ValAddressFromBech32(r.ValidatorAddress)
+	if err != nil {
+		panic(err)
+	}
+
+	return addr
+}
+
+// GetOldConsPubKey returns the consensus pubkey the rotation replaced.
+func (r Rotation) GetOldConsPubKey() (cryptotypes.PubKey, error) {
+	if r.OldConsensusPubkey == nil {
+		return nil, sdkerrors.Wrap(sdkerrors.ErrInvalidPubKey, "empty consensus pubkey")
+	}
+
+	pk, ok := r.OldConsensusPubkey.GetCachedValue().(cryptotypes.PubKey)
+	if !ok {
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrInvalidType, "expecting cryptotypes.PubKey, got %T", r.OldConsensusPubkey.GetCachedValue())
+	}
+
+	return pk, nil
+}
+
+// GetOldConsAddr returns the consensus address the rotation replaced.
+func (r Rotation) GetOldConsAddr() (sdk.ConsAddress, error) {
+	pk, err := r.GetOldConsPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return sdk.ConsAddress(pk.Address()), nil
+}
+
+// UnpackInterfaces implements UnpackInterfacesMessage.UnpackInterfaces
+func (r Rotation) UnpackInterfaces(unpacker codectypes.AnyUnpacker) error {
+	var pk cryptotypes.PubKey
+	return unpacker.UnpackAny(r.OldConsensusPubkey, &pk)
+}
+
+// MustMarshalRotation returns the binary encoding of a rotation.
+func MustMarshalRotation(cdc codec.BinaryMarshaler, r *Rotation) []byte {
+	return cdc.MustMarshalBinaryBare(r)
+}
+
+// MustUnmarshalRotation decodes a rotation from its binary encoding.
+func MustUnmarshalRotation(cdc codec.BinaryMarshaler, bz []byte) Rotation {
+	var r Rotation
+	cdc.MustUnmarshalBinaryBare(bz, &r)
+	return r
+}
@@ -0,0 +1,458 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: gaia/rotation/v1beta1/rotation.proto
+
+package types
+
+import (
+	fmt "fmt"
+	types "github.com/cosmos/cosmos-sdk/codec/types"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/gogo/protobuf/proto"
+	github_com_gogo_protobuf_types "github.com/gogo/protobuf/types"
+	_ "github.com/regen-network/cosmos-proto"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+	time "time"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+var _ = time.Kitchen
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// Rotation records the replacement of the consensus pubkey of a validator.
+// The old pubkey stays attributed to the validator so evidence of its
+// misbehaviour before the rotation can still be handled.
+type Rotation struct {
+	ValidatorAddress   string     `protobuf:"bytes,1,opt,name=validator_address,json=validatorAddress,proto3" json:"validator_address,omitempty" yaml:"validator_address"`
+	OldConsensusPubkey *types.Any `protobuf:"bytes,2,opt,name=old_consensus_pubkey,json=oldConsensusPubkey,proto3" json:"old_consensus_pubkey,omitempty" yaml:"old_consensus_pubkey"`
+	Height             int64      `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Time               time.Time  `protobuf:"bytes,4,opt,name=time,proto3,stdtime" json:"time"`
+}
+
+func (m *Rotation) Reset()         { *m = Rotation{} }
+func (m *Rotation) String() string { return proto.CompactTextString(m) }
+func (*Rotation) ProtoMessage()    {}
+func (*Rotation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_16d31ed6caaff936, []int{0}
+}
+func (m *Rotation) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Rotation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Rotation.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Rotation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Rotation.Merge(m, src)
+}
+func (m *Rotation) XXX_Size() int {
+	return m.Size()
+}
+func (m *Rotation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Rotation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Rotation proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*Rotation)(nil), "gaia.rotation.v1beta1.Rotation")
+}
+
+func init() {
+	proto.RegisterFile("gaia/rotation/v1beta1/rotation.proto", fileDescriptor_16d31ed6caaff936)
+}
+
+var fileDescriptor_16d31ed6caaff936 = []byte{
+	// 384 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x92, 0x41, 0x4b, 0xe3, 0x50,
+	0x10, 0xc7, 0xf3, 0xda, 0x52, 0xba, 0xd9, 0xcb, 0x6e, 0xc8, 0x2e, 0x69, 0x77, 0x49, 0x4a, 0xf0,
+	0x50, 0x04, 0x13, 0xaa, 0x88, 0xd2, 0x5b, 0xa3, 0x17, 0xf1, 0x52, 0x82, 0x27, 0x2f, 0xe1, 0x25,
+	0x79, 0xa6, 0xc1, 0x24, 0x13, 0xf2, 0x5e, 0x8a, 0x39, 0x79, 0xf5, 0xd8, 0x8f, 0xd0, 0xcf, 0x20,
+	0x7e, 0x88, 0xe2, 0xa9, 0x47, 0x4f, 0x55, 0xda, 0x8b, 0xe7, 0x7e, 0x02, 0x69, 0x92, 0x56, 0xb0,
+	0xde, 0x66, 0xfe, 0xf3, 0x1b, 0xe6, 0x3f, 0xc3, 0xf0, 0x7b, 0x1e, 0xf6, 0xb1, 0x9e, 0x00, 0xc3,
+	0xcc, 0x87, 0x48, 0x1f, 0x75, 0x6d, 0xc2, 0x70, 0x77, 0x2b, 0x68, 0x71, 0x02, 0x0c, 0x84, 0x3f,
+	0x6b, 0x4a, 0xdb, 0x8a, 0x25, 0xd5, 0x6a, 0x7a, 0x00, 0x5e, 0x40, 0xf4, 0x1c, 0xb2, 0xd3, 0x1b,
+	0x1d, 0x47, 0x59, 0xd1, 0xd1, 0x52, 0xbe, 0x96, 0x98, 0x1f, 0x12, 0xca, 0x70, 0x18, 0x97, 0x80,
+	0xe8, 0x81, 0x07, 0x79, 0xa8, 0xaf, 0xa3, 0x52, 0x6d, 0x3a, 0x40, 0x43, 0xa0, 0x56, 0x51, 0x28,
+	0x92, 0xa2, 0xa4, 0x3e, 0x56, 0xf8, 0x86, 0x59, 0x3a, 0x10, 0x2e, 0xf8, 0xdf, 0x23, 0x1c, 0xf8,
+	0x2e, 0x66, 0x90, 0x58, 0xd8, 0x75, 0x13, 0x42, 0xa9, 0x84, 0xda, 0xa8, 0xf3, 0xc3, 0xf8, 0xbf,
+	0x9a, 0x2b, 0x52, 0x86, 0xc3, 0xa0, 0xa7, 0xee, 0x20, 0xaa, 0xf9, 0x6b, 0xab, 0xf5, 0x0b, 0x49,
+	0xb8, 0xe7, 0x45, 0x08, 0x5c, 0xcb, 0x81, 0x88, 0x92, 0x88, 0xa6, 0xd4, 0x8a, 0x53, 0xfb, 0x96,
+	0x64, 0x52, 0xa5, 0x8d, 0x3a, 0x3f, 0x0f, 0x45, 0xad, 0x58, 0x44, 0xdb, 0x2c, 0xa2, 0xf5, 0xa3,
+	0xcc, 0x38, 0x59, 0xcd, 0x95, 0x7f, 0xc5, 0x8c, 0xef, 0x7a, 0xd5, 0xe7, 0xa7, 0x03, 0xb1, 0x34,
+	0xef, 0x24, 0x59, 0xcc, 0x40, 0x1b, 0xa4, 0xf6, 0x25, 0xc9, 0x4c, 0x01, 0x02, 0xf7, 0x6c, 0x43,
+	0x0f, 0x72, 0x58, 0xf8, 0xcb, 0xd7, 0x87, 0xc4, 0xf7, 0x86, 0x4c, 0xaa, 0xb6, 0x51, 0xa7, 0x6a,
+	0x96, 0x99, 0x70, 0xca, 0xd7, 0xd6, 0x47, 0x93, 0x6a, 0xb9, 0x91, 0xd6, 0x8e, 0x91, 0xab, 0xcd,
+	0x45, 0x8d, 0xc6, 0x74, 0xae, 0x70, 0xe3, 0x57, 0x05, 0x99, 0x79, 0x47, 0xaf, 0xf1, 0x30, 0x51,
+	0xb8, 0xf7, 0x89, 0xc2, 0x19, 0xe7, 0xd3, 0x85, 0x8c, 0x66, 0x0b, 0x19, 0xbd, 0x2d, 0x64, 0x34,
+	0x5e, 0xca, 0xdc, 0x6c, 0x29, 0x73, 0x2f, 0x4b, 0x99, 0xbb, 0xde, 0xf7, 0x7c, 0x36, 0x4c, 0x6d,
+	0xcd, 0x81, 0xb0, 0xbc, 0xb3, 0x9e, 0xbf, 0xc2, 0xe8, 0x58, 0xbf, 0xfb, 0xfc, 0x07, 0x96, 0xc5,
+	0x84, 0xda, 0xf5, 0x7c, 0xe6, 0xd1, 0xc7, 0x00, 0x36, 0xd3, 0xee, 0x94, 0x2d, 0x02, 0x00, 0x00,
+}
+
+func (m *Rotation) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Rotation) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Rotation) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	n1, err1 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Time):])
+	if err1 != nil {
+		return 0, err1
+	}
+	i -= n1
+	i = encodeVarintRotation(dAtA, i, uint64(n1))
+	i--
+	dAtA[i] = 0x22
+	if m.Height != 0 {
+		i = encodeVarintRotation(dAtA, i, uint64(m.Height))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.OldConsensusPubkey != nil {
+		{
+			size, err := m.OldConsensusPubkey.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRotation(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.ValidatorAddress) > 0 {
+		i -= len(m.ValidatorAddress)
+		copy(dAtA[i:], m.ValidatorAddress)
+		i = encodeVarintRotation(dAtA, i, uint64(len(m.ValidatorAddress)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintRotation(dAtA []byte, offset int, v uint64) int {
+	offset -= sovRotation(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *Rotation) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.ValidatorAddress)
+	if l > 0 {
+		n += 1 + l + sovRotation(uint64(l))
+	}
+	if m.OldConsensusPubkey != nil {
+		l = m.OldConsensusPubkey.Size()
+		n += 1 + l + sovRotation(uint64(l))
+	}
+	if m.Height != 0 {
+		n += 1 + sovRotation(uint64(m.Height))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.Time)
+	n += 1 + l + sovRotation(uint64(l))
+	return n
+}
+
+func sovRotation(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozRotation(x uint64) (n int) {
+	return sovRotation(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Rotation) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRotation
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Rotation: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Rotation: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValidatorAddress", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRotation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRotation
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRotation
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ValidatorAddress = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OldConsensusPubkey", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRotation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRotation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRotation
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.OldConsensusPubkey == nil {
+				m.OldConsensusPubkey = &types.Any{}
+			}
+			if err := m.OldConsensusPubkey.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+			}
+			m.Height = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRotation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Height |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRotation
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRotation
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRotation
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.Time, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRotation(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRotation
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipRotation(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowRotation
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowRotation
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowRotation
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthRotation
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupRotation
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthRotation
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthRotation        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowRotation          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupRotation = fmt.Errorf("proto: unexpected end of group")
+)
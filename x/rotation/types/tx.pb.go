@@ -0,0 +1,587 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: gaia/rotation/v1beta1/tx.proto
+
+package types
+
+import (
+	context "context"
+	fmt "fmt"
+	types "github.com/cosmos/cosmos-sdk/codec/types"
+	_ "github.com/gogo/protobuf/gogoproto"
+	grpc1 "github.com/gogo/protobuf/grpc"
+	proto "github.com/gogo/protobuf/proto"
+	_ "github.com/regen-network/cosmos-proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// MsgRotateConsKey defines a SDK message for replacing the consensus pubkey of
+// a validator, signed by its operator.
+type MsgRotateConsKey struct {
+	ValidatorAddress string     `protobuf:"bytes,1,opt,name=validator_address,json=validatorAddress,proto3" json:"validator_address,omitempty" yaml:"validator_address"`
+	NewPubkey        *types.Any `protobuf:"bytes,2,opt,name=new_pubkey,json=newPubkey,proto3" json:"new_pubkey,omitempty" yaml:"new_pubkey"`
+}
+
+func (m *MsgRotateConsKey) Reset()         { *m = MsgRotateConsKey{} }
+func (m *MsgRotateConsKey) String() string { return proto.CompactTextString(m) }
+func (*MsgRotateConsKey) ProtoMessage()    {}
+func (*MsgRotateConsKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7c30b4e3e95a59e5, []int{0}
+}
+func (m *MsgRotateConsKey) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgRotateConsKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgRotateConsKey.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgRotateConsKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgRotateConsKey.Merge(m, src)
+}
+func (m *MsgRotateConsKey) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgRotateConsKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgRotateConsKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgRotateConsKey proto.InternalMessageInfo
+
+// MsgRotateConsKeyResponse defines the Msg/RotateConsKey response type.
+type MsgRotateConsKeyResponse struct {
+}
+
+func (m *MsgRotateConsKeyResponse) Reset()         { *m = MsgRotateConsKeyResponse{} }
+func (m *MsgRotateConsKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgRotateConsKeyResponse) ProtoMessage()    {}
+func (*MsgRotateConsKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7c30b4e3e95a59e5, []int{1}
+}
+func (m *MsgRotateConsKeyResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgRotateConsKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgRotateConsKeyResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgRotateConsKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgRotateConsKeyResponse.Merge(m, src)
+}
+func (m *MsgRotateConsKeyResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgRotateConsKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgRotateConsKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgRotateConsKeyResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*MsgRotateConsKey)(nil), "gaia.rotation.v1beta1.MsgRotateConsKey")
+	proto.RegisterType((*MsgRotateConsKeyResponse)(nil), "gaia.rotation.v1beta1.MsgRotateConsKeyResponse")
+}
+
+func init() { proto.RegisterFile("gaia/rotation/v1beta1/tx.proto", fileDescriptor_7c30b4e3e95a59e5) }
+
+var fileDescriptor_7c30b4e3e95a59e5 = []byte{
+	// 366 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0xb1, 0x6e, 0xe2, 0x40,
+	0x10, 0xb5, 0xef, 0xa4, 0xd3, 0xb1, 0xa7, 0x93, 0xc0, 0xe2, 0x24, 0x63, 0x9d, 0x6c, 0xe4, 0xe6,
+	0xd0, 0x49, 0xec, 0x0a, 0x4e, 0xd7, 0xd0, 0x41, 0xd2, 0x44, 0x08, 0x09, 0xb9, 0x4c, 0x83, 0xd6,
+	0xb0, 0xd9, 0x58, 0x01, 0x8f, 0xe5, 0x5d, 0x03, 0xfb, 0x07, 0x29, 0xf3, 0x09, 0x7c, 0x44, 0x3e,
+	0x22, 0x4a, 0x1a, 0xca, 0x54, 0x28, 0x82, 0x26, 0x35, 0x5f, 0x10, 0x61, 0x9b, 0xa0, 0x90, 0x14,
+	0xe9, 0x66, 0xdf, 0x7b, 0x33, 0xb3, 0x6f, 0x66, 0x90, 0xcd, 0x69, 0x40, 0x49, 0x0c, 0x92, 0xca,
+	0x00, 0x42, 0x32, 0x6d, 0xf8, 0x4c, 0xd2, 0x06, 0x91, 0x73, 0x1c, 0xc5, 0x20, 0xc1, 0xf8, 0xb5,
+	0xe3, 0xf1, 0x9e, 0xc7, 0x39, 0x6f, 0x55, 0x38, 0x00, 0x1f, 0x33, 0x92, 0x8a, 0xfc, 0xe4, 0x82,
+	0xd0, 0x50, 0x65, 0x19, 0x56, 0x99, 0x03, 0x87, 0x34, 0x24, 0xbb, 0x28, 0x47, 0x2b, 0x43, 0x10,
+	0x13, 0x10, 0x83, 0x8c, 0xc8, 0x1e, 0x19, 0xe5, 0x3e, 0xe8, 0xa8, 0xd8, 0x13, 0xdc, 0xdb, 0xf5,
+	0x60, 0x27, 0x10, 0x8a, 0x2e, 0x53, 0xc6, 0x19, 0x2a, 0x4d, 0xe9, 0x38, 0x18, 0x51, 0x09, 0xf1,
+	0x80, 0x8e, 0x46, 0x31, 0x13, 0xc2, 0xd4, 0xab, 0x7a, 0xad, 0xd0, 0xf9, 0xbd, 0x5d, 0x39, 0xa6,
+	0xa2, 0x93, 0x71, 0xcb, 0x7d, 0x27, 0x71, 0xbd, 0xe2, 0x2b, 0xd6, 0xce, 0x20, 0xc3, 0x47, 0x28,
+	0x64, 0xb3, 0x41, 0x94, 0xf8, 0x57, 0x4c, 0x99, 0x5f, 0xaa, 0x7a, 0xed, 0x47, 0xb3, 0x8c, 0x33,
+	0x03, 0x78, 0x6f, 0x00, 0xb7, 0x43, 0xd5, 0xa9, 0x6f, 0x57, 0x4e, 0x29, 0xab, 0x7c, 0xc8, 0x70,
+	0xef, 0x6f, 0xeb, 0xe5, 0xfc, 0xc3, 0xc3, 0x58, 0x45, 0x12, 0x70, 0x3f, 0xf1, 0xbb, 0x4c, 0x79,
+	0x85, 0x90, 0xcd, 0xfa, 0xa9, 0xa6, 0xf5, 0xfd, 0x7a, 0xe1, 0x68, 0xcf, 0x0b, 0x47, 0x73, 0x2d,
+	0x64, 0x1e, 0x9b, 0xf1, 0x98, 0x88, 0x20, 0x14, 0xac, 0x19, 0xa1, 0xaf, 0x3d, 0xc1, 0x8d, 0x00,
+	0xfd, 0x7c, 0x6b, 0xf6, 0x0f, 0xfe, 0x70, 0xca, 0xf8, 0xb8, 0x90, 0x45, 0x3e, 0x29, 0xdc, 0x77,
+	0xec, 0x9c, 0xde, 0xad, 0x6d, 0x7d, 0xb9, 0xb6, 0xf5, 0xa7, 0xb5, 0xad, 0xdf, 0x6c, 0x6c, 0x6d,
+	0xb9, 0xb1, 0xb5, 0xc7, 0x8d, 0xad, 0x9d, 0xff, 0xe5, 0x81, 0xbc, 0x4c, 0x7c, 0x3c, 0x84, 0x49,
+	0xbe, 0x0e, 0x92, 0x9e, 0xc2, 0xf4, 0x3f, 0x99, 0x1f, 0xee, 0x41, 0xaa, 0x88, 0x09, 0xff, 0x5b,
+	0x3a, 0xa5, 0x7f, 0x2f, 0x03, 0x00, 0x7e, 0x17, 0x2a, 0x44, 0x2d, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MsgClient is the client API for Msg service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MsgClient interface {
+	// RotateConsKey defines a method for replacing the consensus pubkey of a
+	// validator.
+	RotateConsKey(ctx context.Context, in *MsgRotateConsKey, opts ...grpc.CallOption) (*MsgRotateConsKeyResponse, error)
+}
+
+type msgClient struct {
+	cc grpc1.ClientConn
+}
+
+func NewMsgClient(cc grpc1.ClientConn) MsgClient {
+	return &msgClient{cc}
+}
+
+func (c *msgClient) RotateConsKey(ctx context.Context, in *MsgRotateConsKey, opts ...grpc.CallOption) (*MsgRotateConsKeyResponse, error) {
+	out := new(MsgRotateConsKeyResponse)
+	err := c.cc.Invoke(ctx, "/gaia.rotation.v1beta1.Msg/RotateConsKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MsgServer is the server API for Msg service.
+type MsgServer interface {
+	// RotateConsKey defines a method for replacing the consensus pubkey of a
+	// validator.
+	RotateConsKey(context.Context, *MsgRotateConsKey) (*MsgRotateConsKeyResponse, error)
+}
+
+// UnimplementedMsgServer can be embedded to have forward compatible implementations.
+type UnimplementedMsgServer struct {
+}
+
+func (*UnimplementedMsgServer) RotateConsKey(ctx context.Context, req *MsgRotateConsKey) (*MsgRotateConsKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateConsKey not implemented")
+}
+
+func RegisterMsgServer(s grpc1.Server, srv MsgServer) {
+	s.RegisterService(&_Msg_serviceDesc, srv)
+}
+
+func _Msg_RotateConsKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgRotateConsKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).RotateConsKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gaia.rotation.v1beta1.Msg/RotateConsKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).RotateConsKey(ctx, req.(*MsgRotateConsKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Msg_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gaia.rotation.v1beta1.Msg",
+	HandlerType: (*MsgServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RotateConsKey",
+			Handler:    _Msg_RotateConsKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gaia/rotation/v1beta1/tx.proto",
+}
+
+func (m *MsgRotateConsKey) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgRotateConsKey) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgRotateConsKey) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.NewPubkey != nil {
+		{
+			size, err := m.NewPubkey.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTx(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.ValidatorAddress) > 0 {
+		i -= len(m.ValidatorAddress)
+		copy(dAtA[i:], m.ValidatorAddress)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.ValidatorAddress)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgRotateConsKeyResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgRotateConsKeyResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgRotateConsKeyResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintTx(dAtA []byte, offset int, v uint64) int {
+	offset -= sovTx(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *MsgRotateConsKey) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.ValidatorAddress)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	if m.NewPubkey != nil {
+		l = m.NewPubkey.Size()
+		n += 1 + l + sovTx(uint64(l))
+	}
+	return n
+}
+
+func (m *MsgRotateConsKeyResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func sovTx(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozTx(x uint64) (n int) {
+	return sovTx(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *MsgRotateConsKey) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgRotateConsKey: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgRotateConsKey: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValidatorAddress", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ValidatorAddress = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NewPubkey", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.NewPubkey == nil {
+				m.NewPubkey = &types.Any{}
+			}
+			if err := m.NewPubkey.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgRotateConsKeyResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgRotateConsKeyResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgRotateConsKeyResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipTx(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthTx
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupTx
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthTx
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthTx        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowTx          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupTx = fmt.Errorf("proto: unexpected end of group")
+)